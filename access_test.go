@@ -0,0 +1,98 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantKey struct{}
+
+func TestAuthorizer(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var calls []string
+	repo.provider.SetAuthorizer(func(ctx context.Context, op AccessOp, key string) error {
+		calls = append(calls, string(op)+" "+key)
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		if !strings.HasPrefix(key, "tenant:"+tenant+":") {
+			return errors.New("cross-tenant access")
+		}
+		return nil
+	})
+	defer repo.provider.SetAuthorizer(nil)
+
+	tenantA := context.WithValue(ctx, tenantKey{}, "a")
+	require.NoError(t, repo.Set(tenantA, "tenant:a:1", &TestValue{ID: "1"}))
+	_, err := repo.Get(tenantA, "tenant:a:1")
+	require.NoError(t, err)
+
+	err = repo.Set(tenantA, "tenant:b:1", &TestValue{ID: "x"})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission), "unexpected error: %v", err)
+
+	tenantB := context.WithValue(ctx, tenantKey{}, "b")
+	_, err = repo.Get(tenantB, "tenant:a:1")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+	_, err = repo.MGet(tenantB, []string{"tenant:b:1", "tenant:a:1"})
+	assert.Error(t, err)
+	_, err = repo.MDelete(tenantB, []string{"tenant:a:1"})
+	assert.Error(t, err)
+	_, err = repo.Keys(tenantB, "*")
+	assert.Error(t, err)
+	keys, err := repo.Keys(tenantA, "tenant:a:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant:a:1"}, keys)
+
+	assert.Contains(t, calls, "write tenant:a:1")
+	assert.Contains(t, calls, "scan tenant:a:*")
+
+	// Repository authorizers override the provider's; GPA errors pass through unchanged
+	readOnly := NewRepository[TestValue](repo.provider, repo.client, "", WithAuthorizer(
+		func(ctx context.Context, op AccessOp, key string) error {
+			if op != AccessRead {
+				return gpa.NewError(ErrorTypeReadOnly, "repository is read-only")
+			}
+			return nil
+		}))
+	_, err = readOnly.Get(ctx, "tenant:a:1")
+	require.NoError(t, err)
+	err = readOnly.DeleteKey(ctx, "tenant:a:1")
+	assert.True(t, IsReadOnlyError(err))
+
+	// Queries authorize the candidates they load, whether found through an index or a scan
+	users := NewRepository[indexedUser](repo.provider, repo.client, "tenant:a:user:", AllowFullScan())
+	require.NoError(t, users.Set(tenantA, "1", &indexedUser{ID: "1", Email: "q@x.io", Status: "active"}))
+	defer users.DeleteKey(tenantA, "1")
+	found, err := users.Query(tenantA, gpa.Where("status", gpa.OpEqual, "active"))
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+	_, err = users.Query(tenantB, gpa.Where("status", gpa.OpEqual, "active"))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+	_, err = users.Count(tenantB, gpa.Where("email", gpa.OpEqual, "q@x.io"), gpa.Where("id", gpa.OpEqual, "1"))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+
+	// So do index lookups, aggregations and exports
+	keys, err = users.KeysByIndex(tenantA, "status", "active")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, keys)
+	_, err = users.KeysByIndex(tenantB, "status", "active")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+	_, _, err = users.KeyByUnique(tenantB, "email", "q@x.io")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+	_, _, err = users.FilterKeys(tenantB, IndexEq("status", "active"), FilterOptions{})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+	_, err = users.CountBy(tenantB, "status")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+	_, err = users.CountBy(tenantA, "status")
+	require.NoError(t, err)
+	_, err = users.Export(tenantB, io.Discard, ExportOptions{})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+}
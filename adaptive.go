@@ -0,0 +1,169 @@
+package gparedis
+
+import (
+	"sync"
+	"time"
+)
+
+// =====================================
+// Adaptive Batch Sizing
+// =====================================
+
+// AdaptiveOptions bounds an AdaptiveController
+type AdaptiveOptions struct {
+	// MinBatch and MaxBatch bound the batch size (defaults 10 and 1000)
+	MinBatch int64
+	MaxBatch int64
+	// TargetRTT is the round-trip time a batch should take (default 20ms)
+	TargetRTT time.Duration
+	// MinInterval and MaxInterval bound the flush interval (defaults 100ms and 10s)
+	MinInterval time.Duration
+	MaxInterval time.Duration
+}
+
+// withDefaults fills in unset fields
+func (o AdaptiveOptions) withDefaults() AdaptiveOptions {
+	if o.MinBatch <= 0 {
+		o.MinBatch = 10
+	}
+	if o.MaxBatch < o.MinBatch {
+		o.MaxBatch = 1000
+		if o.MaxBatch < o.MinBatch {
+			o.MaxBatch = o.MinBatch
+		}
+	}
+	if o.TargetRTT <= 0 {
+		o.TargetRTT = 20 * time.Millisecond
+	}
+	if o.MinInterval <= 0 {
+		o.MinInterval = 100 * time.Millisecond
+	}
+	if o.MaxInterval < o.MinInterval {
+		o.MaxInterval = 10 * time.Second
+		if o.MaxInterval < o.MinInterval {
+			o.MaxInterval = o.MinInterval
+		}
+	}
+	return o
+}
+
+// AdaptiveController tunes the batch size and flush interval of a batching loop from
+// observed round trips, in the manner of TCP congestion control: batches grow additively
+// while they complete within TargetRTT, shrink proportionally when they run slower, and
+// are halved on errors, which also back off the flush interval. Safe for concurrent use.
+type AdaptiveController struct {
+	opts AdaptiveOptions
+
+	mu       sync.Mutex
+	batch    int64
+	interval time.Duration
+	rtt      time.Duration
+	errors   int64
+	observed int64
+}
+
+// NewAdaptiveController creates a controller starting at the smallest batch and a flush
+// interval halfway between the bounds
+// Example: ctrl := gparedis.NewAdaptiveController(gparedis.AdaptiveOptions{TargetRTT: 10 * time.Millisecond})
+func NewAdaptiveController(opts AdaptiveOptions) *AdaptiveController {
+	opts = opts.withDefaults()
+	return &AdaptiveController{
+		opts:     opts,
+		batch:    opts.MinBatch,
+		interval: (opts.MinInterval + opts.MaxInterval) / 2,
+	}
+}
+
+// BatchSize returns the batch size to use for the next round trip
+func (c *AdaptiveController) BatchSize() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.batch
+}
+
+// Interval returns the flush interval to wait before the next poll
+func (c *AdaptiveController) Interval() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.interval
+}
+
+// RTT returns the smoothed round-trip time of observed batches
+func (c *AdaptiveController) RTT() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rtt
+}
+
+// Observe feeds back one round trip that carried n items, took rtt and failed with err
+func (c *AdaptiveController) Observe(n int64, rtt time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observed++
+	full := n >= c.batch
+
+	if err != nil {
+		c.errors++
+		c.batch = c.clampBatch(c.batch / 2)
+		c.interval = c.clampInterval(c.interval * 2)
+		return
+	}
+
+	// Exponentially weighted moving average, so one slow batch doesn't collapse the size
+	if c.rtt == 0 {
+		c.rtt = rtt
+	} else {
+		c.rtt = (c.rtt*7 + rtt) / 8
+	}
+
+	switch {
+	case c.rtt > c.opts.TargetRTT:
+		c.batch = c.clampBatch(int64(float64(c.batch) * float64(c.opts.TargetRTT) / float64(c.rtt)))
+	case full:
+		// Only grow when the batch was full; partial batches say nothing about capacity
+		step := c.batch / 10
+		if step < 1 {
+			step = 1
+		}
+		c.batch = c.clampBatch(c.batch + step)
+	}
+
+	// Full batches mean work is queuing up: poll sooner. Partial ones: poll less often.
+	if full {
+		c.interval = c.clampInterval(c.interval * 3 / 4)
+	} else {
+		c.interval = c.clampInterval(c.interval * 5 / 4)
+	}
+}
+
+// ErrorRate returns the fraction of observed round trips that failed
+func (c *AdaptiveController) ErrorRate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.observed == 0 {
+		return 0
+	}
+	return float64(c.errors) / float64(c.observed)
+}
+
+// clampBatch keeps a batch size within the bounds
+func (c *AdaptiveController) clampBatch(n int64) int64 {
+	if n < c.opts.MinBatch {
+		return c.opts.MinBatch
+	}
+	if n > c.opts.MaxBatch {
+		return c.opts.MaxBatch
+	}
+	return n
+}
+
+// clampInterval keeps a flush interval within the bounds
+func (c *AdaptiveController) clampInterval(d time.Duration) time.Duration {
+	if d < c.opts.MinInterval {
+		return c.opts.MinInterval
+	}
+	if d > c.opts.MaxInterval {
+		return c.opts.MaxInterval
+	}
+	return d
+}
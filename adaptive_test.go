@@ -0,0 +1,84 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveController(t *testing.T) {
+	c := NewAdaptiveController(AdaptiveOptions{MinBatch: 10, MaxBatch: 100, TargetRTT: 10 * time.Millisecond,
+		MinInterval: 100 * time.Millisecond, MaxInterval: time.Second})
+	assert.Equal(t, int64(10), c.BatchSize())
+	assert.Equal(t, 550*time.Millisecond, c.Interval())
+
+	// Fast, full batches grow the batch and shorten the interval
+	for i := 0; i < 50; i++ {
+		c.Observe(c.BatchSize(), time.Millisecond, nil)
+	}
+	assert.Equal(t, int64(100), c.BatchSize())
+	assert.Equal(t, 100*time.Millisecond, c.Interval())
+
+	// Slow batches shrink it proportionally
+	for i := 0; i < 20; i++ {
+		c.Observe(c.BatchSize(), 40*time.Millisecond, nil)
+	}
+	assert.Less(t, c.BatchSize(), int64(50))
+	assert.Greater(t, c.RTT(), 10*time.Millisecond)
+
+	// Errors halve the batch and back off the interval
+	before := c.BatchSize()
+	c.Observe(before, time.Millisecond, errors.New("timeout"))
+	assert.Equal(t, max(before/2, 10), c.BatchSize())
+	assert.Equal(t, 200*time.Millisecond, c.Interval())
+	assert.InDelta(t, 1.0/71, c.ErrorRate(), 0.0001)
+
+	// Partial batches don't grow the size and lengthen the interval
+	small := NewAdaptiveController(AdaptiveOptions{})
+	small.Observe(1, time.Millisecond, nil)
+	assert.Equal(t, int64(10), small.BatchSize())
+	assert.Greater(t, small.Interval(), (100*time.Millisecond+10*time.Second)/2)
+}
+
+func TestAdaptiveRekey(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		require.NoError(t, repo.client.Set(ctx, fmt.Sprintf("adaptive:old:%d", i), i, 0).Err())
+	}
+	ctrl := NewAdaptiveController(AdaptiveOptions{MinBatch: 5, TargetRTT: time.Second})
+	progress, err := repo.provider.RekeyPrefix(ctx, "adaptive:old:", "adaptive:new:", RekeyOptions{Adaptive: ctrl})
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), progress.Moved)
+	assert.Greater(t, ctrl.RTT(), time.Duration(0))
+
+	keys, err := repo.client.Keys(ctx, "adaptive:new:*").Result()
+	require.NoError(t, err)
+	repo.client.Del(ctx, keys...)
+}
+
+func TestAdaptiveDebouncer(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ctrl := NewAdaptiveController(AdaptiveOptions{MinBatch: 2, MaxBatch: 4, TargetRTT: time.Second})
+	d := NewDebouncer(repo.provider, "adaptive:debounce", DebouncerOptions{Window: time.Millisecond, Adaptive: ctrl})
+	defer repo.client.Del(ctx, "adaptive:debounce")
+	for i := 0; i < 9; i++ {
+		require.NoError(t, d.Trigger(ctx, fmt.Sprint(i)))
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	handled, err := d.Flush(ctx, func(ctx context.Context, id string) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 9, handled)
+	assert.Equal(t, int64(4), ctrl.BatchSize(), "full claims grow the batch")
+}
@@ -0,0 +1,196 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Administrative Commands
+// =====================================
+
+// AdminAccess gates the administrative commands a provider may send
+type AdminAccess int32
+
+const (
+	// AdminDisabled rejects every administrative command (the default)
+	AdminDisabled AdminAccess = iota
+	// AdminInspect allows the commands that only read server state: ServerConfig, Clients and SlowLog
+	AdminInspect
+	// AdminFull also allows SetServerConfig and KillClient
+	AdminFull
+)
+
+// protectedConfigParams can't be changed through SetServerConfig even with AdminFull, as
+// they expose the filesystem or credentials
+var protectedConfigParams = map[string]bool{
+	"dir": true, "dbfilename": true, "logfile": true, "aclfile": true, "unixsocket": true,
+	"requirepass": true, "masterauth": true, "masteruser": true,
+	"tls-cert-file": true, "tls-key-file": true, "tls-ca-cert-file": true, "tls-ca-cert-dir": true,
+	"enable-debug-command": true, "enable-module-command": true, "enable-protected-configs": true,
+}
+
+// ClientInfo is one connection reported by CLIENT LIST
+type ClientInfo struct {
+	ID      int64
+	Addr    string
+	Name    string
+	Age     time.Duration
+	Idle    time.Duration
+	DB      int
+	Command string
+	Flags   string
+	// Fields holds every field as reported, including those without a typed counterpart
+	Fields map[string]string
+}
+
+// SlowLogEntry is one command recorded in the slow log
+type SlowLogEntry struct {
+	ID         int64
+	Time       time.Time
+	Duration   time.Duration
+	Args       []string
+	ClientAddr string
+	ClientName string
+}
+
+// SetAdminAccess chooses which administrative commands the provider may send, so ops
+// tooling built on gparedis doesn't need raw client access and application code can't
+// reconfigure the server by accident
+// Example: provider.SetAdminAccess(gparedis.AdminInspect)
+func (p *Provider) SetAdminAccess(access AdminAccess) {
+	p.adminAccess.Store(int32(access))
+}
+
+// AdminAccess returns the administrative access level
+func (p *Provider) AdminAccess() AdminAccess {
+	return AdminAccess(p.adminAccess.Load())
+}
+
+// requireAdmin fails unless the provider allows administrative commands at level
+func (p *Provider) requireAdmin(level AdminAccess, command string) error {
+	if p.AdminAccess() < level {
+		return gpa.NewError(gpa.ErrorTypePermission, "administrative command "+command+" not allowed; see SetAdminAccess")
+	}
+	return nil
+}
+
+// ServerConfig returns the server configuration parameters matching pattern (CONFIG GET)
+// Example: cfg, err := provider.ServerConfig(ctx, "maxmemory*")
+func (p *Provider) ServerConfig(ctx context.Context, pattern string) (map[string]string, error) {
+	if err := p.requireAdmin(AdminInspect, "CONFIG GET"); err != nil {
+		return nil, err
+	}
+	values, err := p.client.ConfigGet(ctx, pattern).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	config := make(map[string]string, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		config[fmt.Sprint(values[i])] = fmt.Sprint(values[i+1])
+	}
+	return config, nil
+}
+
+// SetServerConfig changes a server configuration parameter (CONFIG SET). Parameters exposing
+// the filesystem or credentials are refused.
+func (p *Provider) SetServerConfig(ctx context.Context, param, value string) error {
+	if err := p.requireAdmin(AdminFull, "CONFIG SET"); err != nil {
+		return err
+	}
+	if protectedConfigParams[strings.ToLower(param)] {
+		return gpa.NewError(gpa.ErrorTypePermission, "config parameter "+param+" is protected")
+	}
+	return convertRedisError(p.client.ConfigSet(ctx, param, value).Err())
+}
+
+// Clients lists the connections to the server (CLIENT LIST)
+func (p *Provider) Clients(ctx context.Context) ([]ClientInfo, error) {
+	if err := p.requireAdmin(AdminInspect, "CLIENT LIST"); err != nil {
+		return nil, err
+	}
+	list, err := p.client.ClientList(ctx).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	var clients []ClientInfo
+	for _, line := range strings.Split(list, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			clients = append(clients, parseClientInfo(line))
+		}
+	}
+	return clients, nil
+}
+
+// parseClientInfo parses one CLIENT LIST line of space-separated name=value fields
+func parseClientInfo(line string) ClientInfo {
+	info := ClientInfo{Fields: make(map[string]string)}
+	for _, field := range strings.Fields(line) {
+		name, value, _ := strings.Cut(field, "=")
+		info.Fields[name] = value
+		switch name {
+		case "id":
+			info.ID, _ = strconv.ParseInt(value, 10, 64)
+		case "addr":
+			info.Addr = value
+		case "name":
+			info.Name = value
+		case "age":
+			seconds, _ := strconv.ParseInt(value, 10, 64)
+			info.Age = time.Duration(seconds) * time.Second
+		case "idle":
+			seconds, _ := strconv.ParseInt(value, 10, 64)
+			info.Idle = time.Duration(seconds) * time.Second
+		case "db":
+			info.DB, _ = strconv.Atoi(value)
+		case "cmd":
+			info.Command = value
+		case "flags":
+			info.Flags = value
+		}
+	}
+	return info
+}
+
+// KillClient closes the connection with the given ID (CLIENT KILL ID)
+func (p *Provider) KillClient(ctx context.Context, id int64) error {
+	if err := p.requireAdmin(AdminFull, "CLIENT KILL"); err != nil {
+		return err
+	}
+	killed, err := p.client.ClientKillByFilter(ctx, "ID", strconv.FormatInt(id, 10)).Result()
+	if err != nil {
+		return convertRedisError(err)
+	}
+	if killed == 0 {
+		return gpa.NewError(gpa.ErrorTypeNotFound, "client "+strconv.FormatInt(id, 10)+" not found")
+	}
+	return nil
+}
+
+// SlowLog returns the n most recent slow log entries (SLOWLOG GET)
+func (p *Provider) SlowLog(ctx context.Context, n int64) ([]SlowLogEntry, error) {
+	if err := p.requireAdmin(AdminInspect, "SLOWLOG GET"); err != nil {
+		return nil, err
+	}
+	logs, err := p.client.SlowLogGet(ctx, n).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	entries := make([]SlowLogEntry, len(logs))
+	for i, log := range logs {
+		entries[i] = SlowLogEntry{
+			ID:         log.ID,
+			Time:       log.Time,
+			Duration:   log.Duration,
+			Args:       log.Args,
+			ClientAddr: log.ClientAddr,
+			ClientName: log.ClientName,
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,75 @@
+package gparedis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAccessGating(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	p := repo.provider
+	assert.Equal(t, AdminDisabled, p.AdminAccess())
+
+	_, err := p.ServerConfig(ctx, "*")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+	_, err = p.Clients(ctx)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+
+	p.SetAdminAccess(AdminInspect)
+	err = p.SetServerConfig(ctx, "maxmemory", "0")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+	err = p.KillClient(ctx, 1)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+
+	p.SetAdminAccess(AdminFull)
+	err = p.SetServerConfig(ctx, "DIR", "/tmp")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission))
+}
+
+func TestAdminCommands(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	p := repo.provider
+	p.SetAdminAccess(AdminFull)
+
+	unsupported := func(err error) bool {
+		return err != nil && strings.Contains(err.Error(), "unknown")
+	}
+	if clients, err := p.Clients(ctx); !unsupported(err) {
+		require.NoError(t, err)
+		require.NotEmpty(t, clients)
+		assert.Greater(t, clients[0].ID, int64(0))
+		assert.NotEmpty(t, clients[0].Addr)
+	}
+
+	if _, err := p.SlowLog(ctx, 10); !unsupported(err) {
+		assert.NoError(t, err)
+	}
+	if _, err := p.ServerConfig(ctx, "maxmemory"); !unsupported(err) {
+		assert.NoError(t, err)
+	}
+}
+
+func TestParseClientInfo(t *testing.T) {
+	info := parseClientInfo("id=7 addr=127.0.0.1:51234 laddr=127.0.0.1:6379 fd=8 name=worker age=120 idle=3 flags=N db=2 cmd=client|list")
+	assert.Equal(t, int64(7), info.ID)
+	assert.Equal(t, "127.0.0.1:51234", info.Addr)
+	assert.Equal(t, "worker", info.Name)
+	assert.Equal(t, 2*time.Minute, info.Age)
+	assert.Equal(t, 3*time.Second, info.Idle)
+	assert.Equal(t, 2, info.DB)
+	assert.Equal(t, "client|list", info.Command)
+	assert.Equal(t, "N", info.Flags)
+	assert.Equal(t, "8", info.Fields["fd"])
+}
@@ -0,0 +1,161 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Index Aggregations
+// =====================================
+
+// CountBy returns the number of keys holding each value of a tag index, read from the sizes
+// of its tag sets. Composite values join the field values with ":"; text indexes count the
+// keys containing each term.
+// Example: byStatus, err := orders.CountBy(ctx, "status") // {"open": 12, "shipped": 40}
+func (r *Repository[T]) CountBy(ctx context.Context, index string) (map[string]int64, error) {
+	def, err := r.findIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	if def.ranged {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "index "+index+" is a range index; use SumBy")
+	}
+	if err := r.authorize(ctx, AccessScan, r.buildKey("*")); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64)
+	setPrefix := r.tagSetKey(index, "")
+	err = scanKeys(ctx, r.client, EscapeGlob(setPrefix)+"*", func(sets []string) error {
+		sizes := make([]*redis.IntCmd, len(sets))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, set := range sets {
+				sizes[i] = pipe.SCard(ctx, set)
+			}
+			return nil
+		})
+		if err != nil {
+			return convertRedisError(err)
+		}
+		for i, set := range sets {
+			if n := sizes[i].Val(); n > 0 {
+				counts[strings.TrimPrefix(set, setPrefix)] = n
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// SumBy returns the sum of a range index's values over all indexed keys, and the number of
+// keys summed. Scores are read with ZSCAN, so large indexes don't block the server; times
+// sum as Unix milliseconds.
+// Example: total, n, err := orders.SumBy(ctx, "amount")
+func (r *Repository[T]) SumBy(ctx context.Context, index string) (float64, int64, error) {
+	def, err := r.findIndex(index)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !def.ranged {
+		return 0, 0, gpa.NewError(gpa.ErrorTypeInvalidArgument, "index "+index+" is not a range index")
+	}
+	if err := r.authorize(ctx, AccessScan, r.buildKey("*")); err != nil {
+		return 0, 0, err
+	}
+
+	var sum float64
+	var count int64
+	set := r.rangeSetKey(index)
+	var cursor uint64
+	for {
+		pairs, next, err := r.client.ZScan(ctx, set, cursor, "", scanBatchSize).Result()
+		if err != nil {
+			return 0, 0, convertRedisError(err)
+		}
+		// ZSCAN returns members and scores interleaved
+		for i := 1; i < len(pairs); i += 2 {
+			score, err := strconv.ParseFloat(pairs[i], 64)
+			if err != nil {
+				return 0, 0, gpa.NewErrorWithCause(gpa.ErrorTypeDatabase, "invalid range index score", err)
+			}
+			sum += score
+			count++
+		}
+		if cursor = next; cursor == 0 {
+			return sum, count, nil
+		}
+	}
+}
+
+// groupCountScript counts the members of a filter result in each tag set.
+// KEYS[1]: filter result (sorted set), KEYS[2..]: tag sets. Returns one count per tag set.
+var groupCountScript = readOnlyScript(redis.NewScript(`
+local counts = {}
+for i = 2, #KEYS do
+	local n = 0
+	for _, member in ipairs(redis.call('SMEMBERS', KEYS[i])) do
+		if redis.call('ZSCORE', KEYS[1], member) then
+			n = n + 1
+		end
+	end
+	counts[#counts + 1] = n
+end
+return counts
+`))
+
+// GroupCount returns the number of keys matching filter for each value of a tag index, like
+// SQL's SELECT index, COUNT(*) WHERE filter GROUP BY index. The filter is resolved as in
+// FilterKeys, and its result is cached for 30s. Values without matches are omitted.
+// Example: byRegion, err := users.GroupCount(ctx, "region", gparedis.IndexEq("status", "active"))
+func (r *Repository[T]) GroupCount(ctx context.Context, index string, filter IndexFilter) (map[string]int64, error) {
+	def, err := r.findIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	if def.ranged {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "index "+index+" is a range index; use SumBy")
+	}
+	if err := r.authorize(ctx, AccessScan, r.buildKey("*")); err != nil {
+		return nil, err
+	}
+	if filter.op == filterEq {
+		// Leaves are plain sets; materialize them as sorted sets
+		filter = AnyOf(filter)
+	}
+	result, ok, err := r.resolveFilter(ctx, filter, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64)
+	if !ok {
+		return counts, nil
+	}
+
+	setPrefix := r.tagSetKey(index, "")
+	err = scanKeys(ctx, r.client, EscapeGlob(setPrefix)+"*", func(sets []string) error {
+		keys := append([]string{result}, sets...)
+		found, err := groupCountScript.Run(ctx, r.client, keys).Int64Slice()
+		if err != nil {
+			return convertRedisError(err)
+		}
+		for i, set := range sets {
+			if i < len(found) && found[i] > 0 {
+				counts[strings.TrimPrefix(set, setPrefix)] = found[i]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
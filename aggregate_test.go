@@ -0,0 +1,53 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type aggregatedOrder struct {
+	ID     string  `json:"id"`
+	Status string  `json:"status" gpaindex:"status"`
+	Region string  `json:"region" gpaindex:"region"`
+	Amount float64 `json:"amount" gpaindex:"amount,range"`
+}
+
+func TestIndexAggregations(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[aggregatedOrder](base.provider, base.client, "order:")
+	require.NoError(t, repo.MSet(ctx, map[string]*aggregatedOrder{
+		"1": {ID: "1", Status: "open", Region: "EU", Amount: 10},
+		"2": {ID: "2", Status: "open", Region: "US", Amount: 20.5},
+		"3": {ID: "3", Status: "shipped", Region: "EU", Amount: 5},
+		"4": {ID: "4", Status: "open", Region: "EU", Amount: 1.5},
+	}))
+
+	byStatus, err := repo.CountBy(ctx, "status")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"open": 3, "shipped": 1}, byStatus)
+
+	sum, n, err := repo.SumBy(ctx, "amount")
+	require.NoError(t, err)
+	assert.Equal(t, 37.0, sum)
+	assert.Equal(t, int64(4), n)
+
+	openByRegion, err := repo.GroupCount(ctx, "region", IndexEq("status", "open"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"EU": 2, "US": 1}, openByRegion)
+
+	none, err := repo.GroupCount(ctx, "region", IndexEq("status", "cancelled"))
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	_, err = repo.CountBy(ctx, "amount")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+	_, _, err = repo.SumBy(ctx, "status")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
@@ -0,0 +1,187 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Access Pattern Analysis
+// =====================================
+
+// batchSuggestions maps single-key commands to their batched alternative
+var batchSuggestions = map[string]string{
+	"get":       "MGET (Repository.MGet)",
+	"set":       "MSET or a pipeline (Repository.MSet)",
+	"exists":    "EXISTS with several keys",
+	"del":       "DEL with several keys (Repository.MDelete)",
+	"unlink":    "UNLINK with several keys",
+	"hget":      "HMGET",
+	"sismember": "SMISMEMBER",
+	"ttl":       "a pipeline",
+	"pttl":      "a pipeline",
+	"incrby":    "a pipeline",
+}
+
+// AccessRecord is one command observed during a traced request
+type AccessRecord struct {
+	Command   string
+	Key       string
+	Pipelined bool
+	Time      time.Time
+}
+
+// AccessFinding flags a run of single-key commands that could have been one batched call
+type AccessFinding struct {
+	Command    string
+	Keys       []string
+	Suggestion string
+}
+
+// String describes the finding
+func (f AccessFinding) String() string {
+	return fmt.Sprintf("%d sequential %s calls (%s, ...) could use %s",
+		len(f.Keys), strings.ToUpper(f.Command), f.Keys[0], f.Suggestion)
+}
+
+// AccessReport is the analysis of one traced request
+type AccessReport struct {
+	Request  string
+	Accesses []AccessRecord
+	Findings []AccessFinding
+}
+
+// AnalyzerOptions configures an AccessAnalyzer
+type AnalyzerOptions struct {
+	// Threshold is the run length of sequential single-key commands reported (default 3)
+	Threshold int
+	// OnReport receives the report of every request with at least one finding
+	OnReport func(AccessReport)
+	// ReportAll passes reports without findings to OnReport as well
+	ReportAll bool
+}
+
+// AccessAnalyzer records per-request key access sequences and flags repeated single-key
+// commands that could be batched (N+1 patterns). Meant for development and tests.
+type AccessAnalyzer struct {
+	opts AnalyzerOptions
+}
+
+// NewAccessAnalyzer creates an analyzer
+// Example: analyzer := gparedis.NewAccessAnalyzer(gparedis.AnalyzerOptions{OnReport: func(r gparedis.AccessReport) { log.Println(r.Findings) }})
+func NewAccessAnalyzer(opts AnalyzerOptions) *AccessAnalyzer {
+	if opts.Threshold < 2 {
+		opts.Threshold = 3
+	}
+	return &AccessAnalyzer{opts: opts}
+}
+
+// accessTrace collects the commands of one request
+type accessTrace struct {
+	mu       sync.Mutex
+	accesses []AccessRecord
+}
+
+// accessTraceKey stores the request trace in a context
+type accessTraceKey struct{}
+
+// Begin starts tracing the Redis commands issued with the returned context. Calling finish
+// analyzes the trace, passes the report to OnReport and returns it.
+// Example: ctx, finish := analyzer.Begin(r.Context(), r.URL.Path); defer finish()
+func (a *AccessAnalyzer) Begin(ctx context.Context, request string) (context.Context, func() AccessReport) {
+	trace := &accessTrace{}
+	ctx = context.WithValue(ctx, accessTraceKey{}, trace)
+	return ctx, func() AccessReport {
+		trace.mu.Lock()
+		accesses := append([]AccessRecord(nil), trace.accesses...)
+		trace.mu.Unlock()
+
+		report := AccessReport{Request: request, Accesses: accesses, Findings: a.analyze(accesses)}
+		if a.opts.OnReport != nil && (len(report.Findings) > 0 || a.opts.ReportAll) {
+			a.opts.OnReport(report)
+		}
+		return report
+	}
+}
+
+// analyze finds runs of sequential, non-pipelined single-key commands of the same kind.
+// HGET runs must also target the same hash.
+func (a *AccessAnalyzer) analyze(accesses []AccessRecord) []AccessFinding {
+	var findings []AccessFinding
+	var run []AccessRecord
+
+	flush := func() {
+		if len(run) >= a.opts.Threshold {
+			keys := make([]string, len(run))
+			for i, rec := range run {
+				keys[i] = rec.Key
+			}
+			findings = append(findings, AccessFinding{
+				Command:    run[0].Command,
+				Keys:       keys,
+				Suggestion: batchSuggestions[run[0].Command],
+			})
+		}
+		run = nil
+	}
+
+	for _, rec := range accesses {
+		if rec.Pipelined || batchSuggestions[rec.Command] == "" {
+			flush()
+			continue
+		}
+		if len(run) > 0 {
+			last := run[len(run)-1]
+			if last.Command != rec.Command || (rec.Command == "hget" && last.Key != rec.Key) {
+				flush()
+			}
+		}
+		run = append(run, rec)
+	}
+	flush()
+	return findings
+}
+
+// recordAccess appends commands to the trace in ctx
+func recordAccess(ctx context.Context, pipelined bool, cmds ...redis.Cmder) {
+	trace, ok := ctx.Value(accessTraceKey{}).(*accessTrace)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	for _, cmd := range cmds {
+		rec := AccessRecord{Command: strings.ToLower(cmd.Name()), Pipelined: pipelined, Time: now}
+		if args := cmd.Args(); len(args) > 1 {
+			rec.Key = fmt.Sprint(args[1])
+		}
+		trace.accesses = append(trace.accesses, rec)
+	}
+}
+
+// accessTraceHook records commands issued with a traced context
+type accessTraceHook struct{}
+
+func (accessTraceHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	recordAccess(ctx, false, cmd)
+	return ctx, nil
+}
+
+func (accessTraceHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (accessTraceHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	recordAccess(ctx, true, cmds...)
+	return ctx, nil
+}
+
+func (accessTraceHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessAnalyzerFlagsSequentialGets(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, repo.Set(context.Background(), fmt.Sprint("user:", i), &TestValue{ID: fmt.Sprint(i)}))
+	}
+
+	var reports []AccessReport
+	analyzer := NewAccessAnalyzer(AnalyzerOptions{OnReport: func(r AccessReport) { reports = append(reports, r) }})
+
+	ctx, finish := analyzer.Begin(context.Background(), "GET /users")
+	for i := 0; i < 4; i++ {
+		_, err := repo.Get(ctx, fmt.Sprint("user:", i))
+		require.NoError(t, err)
+	}
+	report := finish()
+
+	require.Len(t, report.Findings, 1)
+	finding := report.Findings[0]
+	assert.Equal(t, "get", finding.Command)
+	assert.Equal(t, []string{"user:0", "user:1", "user:2", "user:3"}, finding.Keys)
+	assert.Contains(t, finding.String(), "4 sequential GET calls")
+	require.Len(t, reports, 1)
+	assert.Equal(t, "GET /users", reports[0].Request)
+
+	// The batched equivalent is clean
+	ctx, finish = analyzer.Begin(context.Background(), "GET /users?batched")
+	_, err := repo.MGet(ctx, []string{"user:0", "user:1", "user:2", "user:3"})
+	require.NoError(t, err)
+	report = finish()
+	assert.Empty(t, report.Findings)
+	assert.Len(t, report.Accesses, 1)
+	assert.Len(t, reports, 1)
+}
+
+func TestAccessAnalyzerRuns(t *testing.T) {
+	a := NewAccessAnalyzer(AnalyzerOptions{Threshold: 2})
+	findings := a.analyze([]AccessRecord{
+		{Command: "get", Key: "a"},
+		{Command: "set", Key: "x"},
+		{Command: "get", Key: "b"},
+		{Command: "hget", Key: "h1"},
+		{Command: "hget", Key: "h2"},
+		{Command: "get", Key: "c", Pipelined: true},
+		{Command: "get", Key: "d", Pipelined: true},
+		{Command: "hget", Key: "h3"},
+		{Command: "hget", Key: "h3"},
+	})
+	require.Len(t, findings, 1)
+	assert.Equal(t, "hget", findings[0].Command)
+	assert.Equal(t, []string{"h3", "h3"}, findings[0].Keys)
+}
@@ -0,0 +1,183 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Audit Log
+// =====================================
+
+// principalKey stores the acting principal in a context
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the principal (user, service account, ...) performing operations
+// Example: ctx = gparedis.WithPrincipal(ctx, "user:42")
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set with WithPrincipal
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+	return principal, ok && principal != ""
+}
+
+// AuditEntry records who modified which keys
+type AuditEntry struct {
+	ID        string    `json:"-"`
+	Principal string    `json:"principal"`
+	Op        string    `json:"op"`
+	Prefix    string    `json:"prefix,omitempty"`
+	Keys      []string  `json:"keys"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AuditOptions configures an AuditLog
+type AuditOptions struct {
+	// Retention drops entries older than this on append (0 keeps everything)
+	Retention time.Duration
+	// PrincipalFunc extracts the principal from a context; defaults to PrincipalFromContext
+	PrincipalFunc func(ctx context.Context) string
+	// Anonymous is recorded when no principal is found (default "anonymous")
+	Anonymous string
+}
+
+// AuditFilter selects audit entries in Query. Zero values match everything.
+type AuditFilter struct {
+	Principal string
+	Key       string
+	Op        string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// AuditLog is an append-only audit trail stored in a Redis stream
+type AuditLog struct {
+	stream *Stream[AuditEntry]
+	opts   AuditOptions
+}
+
+// NewAuditLog creates an audit log stored in the stream at streamKey
+// Example: audit := gparedis.NewAuditLog(provider, "audit:log", gparedis.AuditOptions{Retention: 90 * 24 * time.Hour})
+func NewAuditLog(provider *Provider, streamKey string, opts AuditOptions) *AuditLog {
+	if opts.PrincipalFunc == nil {
+		opts.PrincipalFunc = func(ctx context.Context) string {
+			principal, _ := PrincipalFromContext(ctx)
+			return principal
+		}
+	}
+	if opts.Anonymous == "" {
+		opts.Anonymous = "anonymous"
+	}
+	return &AuditLog{stream: NewStream[AuditEntry](provider, streamKey, StreamOptions{}), opts: opts}
+}
+
+// Audited records every write made through the repository in log: Set, SetWithTTL, MSet,
+// MSetNX, MCompareAndSwap, SetIfUnchanged, MGetOrLoad, DeleteKey, MDelete, DeleteByCondition,
+// Increment, Expire, SetTTL, RemoveTTL, imports (including ImportTTLs) and the SetTx,
+// SetWithTTLTx, DeleteKeyTx and IncrementTx writes of a committed MultiRepo. When the audit
+// record can't be written the operation returns an ErrorTypeInternal error even though the
+// write itself was applied.
+func Audited(log *AuditLog) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.audit = log
+	}
+}
+
+// Record appends an entry for op on keys, attributed to the principal found in ctx
+func (a *AuditLog) Record(ctx context.Context, op, prefix string, keys []string) error {
+	principal := a.opts.PrincipalFunc(ctx)
+	if principal == "" {
+		principal = a.opts.Anonymous
+	}
+	args, err := a.stream.addArgs(ctx, &AuditEntry{
+		Principal: principal,
+		Op:        op,
+		Prefix:    prefix,
+		Keys:      keys,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if a.opts.Retention > 0 {
+		args.MinID = strconv.FormatInt(time.Now().Add(-a.opts.Retention).UnixMilli(), 10)
+		args.Approx = true
+	}
+	if err := a.stream.client.XAdd(ctx, args).Err(); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to write audit record", err)
+	}
+	return nil
+}
+
+// Query returns entries matching filter in chronological order
+func (a *AuditLog) Query(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	start, end := "-", "+"
+	if !filter.Since.IsZero() {
+		start = strconv.FormatInt(filter.Since.UnixMilli(), 10)
+	}
+	if !filter.Until.IsZero() {
+		end = strconv.FormatInt(filter.Until.UnixMilli(), 10)
+	}
+
+	var entries []AuditEntry
+	for {
+		batch, err := a.stream.Range(ctx, start, end, scanBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range batch {
+			if msg.Value == nil || !filter.matches(msg.Value) {
+				continue
+			}
+			entry := *msg.Value
+			entry.ID = msg.ID
+			entries = append(entries, entry)
+			if filter.Limit > 0 && len(entries) >= filter.Limit {
+				return entries, nil
+			}
+		}
+		if len(batch) < scanBatchSize {
+			return entries, nil
+		}
+		start = "(" + batch[len(batch)-1].ID
+	}
+}
+
+// matches reports whether entry satisfies the filter
+func (f AuditFilter) matches(entry *AuditEntry) bool {
+	if f.Principal != "" && entry.Principal != f.Principal {
+		return false
+	}
+	if f.Op != "" && entry.Op != f.Op {
+		return false
+	}
+	if f.Key != "" {
+		for _, key := range entry.Keys {
+			if key == f.Key {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Stream returns the underlying stream for consumers that want to tail the audit log
+func (a *AuditLog) Stream() *Stream[AuditEntry] {
+	return a.stream
+}
+
+// recordWrite appends an audit entry for a successful repository write when auditing is enabled
+func (r *Repository[T]) recordWrite(ctx context.Context, op string, keys ...string) error {
+	if r.opts.audit == nil || len(keys) == 0 {
+		return nil
+	}
+	return r.opts.audit.Record(ctx, op, r.keyPrefix, keys)
+}
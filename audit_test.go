@@ -0,0 +1,118 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrincipalContext(t *testing.T) {
+	_, ok := PrincipalFromContext(context.Background())
+	assert.False(t, ok)
+
+	principal, ok := PrincipalFromContext(WithPrincipal(context.Background(), "user:42"))
+	assert.True(t, ok)
+	assert.Equal(t, "user:42", principal)
+}
+
+func TestAuditedRepository(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	audit := NewAuditLog(base.provider, "audit:log", AuditOptions{Retention: time.Hour})
+	repo := NewRepository[TestValue](base.provider, base.client, "user:", Audited(audit))
+
+	alice := WithPrincipal(context.Background(), "alice")
+	bob := WithPrincipal(context.Background(), "bob")
+
+	require.NoError(t, repo.Set(alice, "1", &TestValue{ID: "1"}))
+	require.NoError(t, repo.MSet(bob, map[string]*TestValue{"2": {ID: "2"}, "3": {ID: "3"}}))
+	_, err := repo.Increment(alice, "visits", 1)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteKey(bob, "1"))
+	_, err = repo.MDelete(context.Background(), []string{"2"})
+	require.NoError(t, err)
+
+	entries, err := audit.Query(context.Background(), AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+	assert.Equal(t, "alice", entries[0].Principal)
+	assert.Equal(t, "set", entries[0].Op)
+	assert.Equal(t, "user:", entries[0].Prefix)
+	assert.Equal(t, []string{"2", "3"}, entries[1].Keys)
+	assert.Equal(t, "anonymous", entries[4].Principal)
+	assert.NotEmpty(t, entries[0].ID)
+
+	byBob, err := audit.Query(context.Background(), AuditFilter{Principal: "bob"})
+	require.NoError(t, err)
+	require.Len(t, byBob, 2)
+	assert.Equal(t, "delete", byBob[1].Op)
+
+	touching1, err := audit.Query(context.Background(), AuditFilter{Key: "1"})
+	require.NoError(t, err)
+	assert.Len(t, touching1, 2)
+
+	future, err := audit.Query(context.Background(), AuditFilter{Since: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, future)
+}
+
+func TestAuditedWritePaths(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := WithPrincipal(context.Background(), "carol")
+	audit := NewAuditLog(base.provider, "audit:paths", AuditOptions{Retention: time.Hour})
+	repo := NewRepository[TestValue](base.provider, base.client, "audited:", Audited(audit))
+	defer repo.MDelete(ctx, []string{"a", "b", "c", "d"})
+
+	_, err := repo.MSetNX(ctx, map[string]*TestValue{"a": {ID: "a"}})
+	require.NoError(t, err)
+	_, err = repo.MCompareAndSwap(ctx, map[string]*TestValue{"a": {ID: "a"}}, map[string]*TestValue{"a": {ID: "a", Age: 1}}, 0)
+	require.NoError(t, err)
+	_, err = repo.MGetOrLoad(ctx, []string{"b"}, func(missing []string) (map[string]*TestValue, error) {
+		return map[string]*TestValue{"b": {ID: "b"}}, nil
+	}, 0)
+	require.NoError(t, err)
+	require.NoError(t, repo.Expire(ctx, "a", time.Hour))
+	require.NoError(t, repo.SetTTL(ctx, "a", time.Hour))
+	require.NoError(t, repo.RemoveTTL(ctx, "a"))
+	require.NoError(t, base.provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		if err := repo.SetTx(tx, "c", &TestValue{ID: "c"}); err != nil {
+			return err
+		}
+		return repo.DeleteKeyTx(tx, "b")
+	}))
+
+	entries, err := audit.Query(context.Background(), AuditFilter{})
+	require.NoError(t, err)
+	ops := make([]string, len(entries))
+	for i, entry := range entries {
+		ops[i] = entry.Op
+		assert.Equal(t, "carol", entry.Principal)
+	}
+	assert.Equal(t, []string{"msetnx", "compare_and_swap", "load", "expire", "expire", "persist", "set", "delete"}, ops)
+}
+
+func TestAuditQueryPaging(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := WithPrincipal(context.Background(), "batch")
+	audit := NewAuditLog(repo.provider, "audit:log", AuditOptions{})
+	for i := 0; i < scanBatchSize+5; i++ {
+		require.NoError(t, audit.Record(ctx, "set", "", []string{fmt.Sprint(i)}))
+	}
+
+	entries, err := audit.Query(ctx, AuditFilter{})
+	require.NoError(t, err)
+	assert.Len(t, entries, scanBatchSize+5)
+
+	limited, err := audit.Query(ctx, AuditFilter{Limit: 3})
+	require.NoError(t, err)
+	assert.Len(t, limited, 3)
+}
@@ -0,0 +1,170 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Cross-Repository Batch Fetching
+// =====================================
+
+// BatchFetcher collects Gets from repositories of different entity types that share a
+// provider and reads them together in one MGET (one per slot in cluster mode).
+type BatchFetcher struct {
+	ctx      context.Context
+	provider *Provider
+	keys     []string
+	seen     map[string]bool
+	reads    []batchRead
+	// separate holds reads that need their own round trip, such as idle-policy GETEX reads
+	separate []func(ctx context.Context)
+}
+
+// batchRead resolves one queued Get from the value fetched for its full key
+type batchRead struct {
+	fullKey string
+	resolve func(value interface{}, err error)
+}
+
+// Pending is the result of a Get queued on a BatchFetcher, available once FetchBatch returns
+type Pending[T any] struct {
+	key   string
+	value *T
+	err   error
+	done  bool
+}
+
+// Result returns the fetched value, an ErrorTypeNotFound error for a missing key, or the
+// error that prevented the read
+func (p *Pending[T]) Result() (*T, error) {
+	if !p.done {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "batch has not been fetched: "+p.key)
+	}
+	return p.value, p.err
+}
+
+// FetchBatch runs fn and then reads every Get queued on the batch in a single round trip,
+// decoding each value with its repository's codec. If fn returns an error nothing is read.
+// Read errors are reported through each Pending rather than by FetchBatch, so repositories
+// with a near cache can still serve stale copies.
+// Example:
+//
+//	var user *gparedis.Pending[User]
+//	var cart *gparedis.Pending[Cart]
+//	err := provider.FetchBatch(ctx, func(b *gparedis.BatchFetcher) error {
+//		user = users.GetBatch(b, id)
+//		cart = carts.GetBatch(b, id)
+//		return nil
+//	})
+func (p *Provider) FetchBatch(ctx context.Context, fn func(b *BatchFetcher) error) error {
+	b := &BatchFetcher{ctx: ctx, provider: p, seen: make(map[string]bool)}
+	if err := fn(b); err != nil {
+		return err
+	}
+
+	if len(b.keys) > 0 {
+		var values []interface{}
+		var err error
+		if p.clusterMode && !SameSlot(b.keys...) {
+			values, err = mgetGroups(ctx, p.client, b.keys)
+		} else {
+			values, err = p.client.MGet(ctx, b.keys...).Result()
+			err = convertRedisError(err)
+		}
+
+		byKey := make(map[string]interface{}, len(b.keys))
+		for i, value := range values {
+			byKey[b.keys[i]] = value
+		}
+		for _, read := range b.reads {
+			read.resolve(byKey[read.fullKey], err)
+		}
+	}
+	for _, read := range b.separate {
+		read(ctx)
+	}
+	return nil
+}
+
+// Len returns the number of Gets queued so far
+func (b *BatchFetcher) Len() int {
+	return len(b.reads) + len(b.separate)
+}
+
+// queue adds a read of fullKey to the batch, fetching each key once
+func (b *BatchFetcher) queue(fullKey string, resolve func(value interface{}, err error)) {
+	if !b.seen[fullKey] {
+		b.seen[fullKey] = true
+		b.keys = append(b.keys, fullKey)
+	}
+	b.reads = append(b.reads, batchRead{fullKey: fullKey, resolve: resolve})
+}
+
+// GetBatch queues a Get of key on b. Fresh near cache copies are resolved without a read;
+// repositories with WithTimeToIdle are read with their own GETEX round trip.
+func (r *Repository[T]) GetBatch(b *BatchFetcher, key string) *Pending[T] {
+	pending := &Pending[T]{key: key}
+	if b == nil || b.provider == nil {
+		pending.err, pending.done = gpa.NewError(gpa.ErrorTypeInvalidArgument, "batch is not initialized"), true
+		return pending
+	}
+	if r.client != b.provider.client {
+		pending.err = gpa.NewError(gpa.ErrorTypeInvalidArgument, "repository does not share the batch's provider connection")
+		pending.done = true
+		return pending
+	}
+	fullKey := r.buildKey(key)
+	if err := r.authorize(b.ctx, AccessRead, fullKey); err != nil {
+		pending.err, pending.done = err, true
+		return pending
+	}
+
+	resolve := func(value interface{}, err error) {
+		pending.done = true
+		if err != nil {
+			data, ok := r.serveStale(key, err)
+			if !ok {
+				pending.err = err
+				return
+			}
+			value = string(data)
+		} else if r.near != nil {
+			if data, ok := value.(string); ok {
+				r.near.store(key, []byte(data))
+			} else {
+				r.near.Invalidate(key)
+			}
+		}
+
+		data, ok := value.(string)
+		if !ok {
+			pending.err = gpa.GPAError{Type: gpa.ErrorTypeNotFound, Message: fmt.Sprintf("key not found: %s", key)}
+			return
+		}
+		pending.value, pending.err = r.decode([]byte(data))
+	}
+
+	if r.near != nil {
+		if data, ok := r.near.fresh(key); ok {
+			pending.done = true
+			pending.value, pending.err = r.decode(data)
+			return pending
+		}
+	}
+	if r.opts.idle != nil {
+		b.separate = append(b.separate, func(ctx context.Context) {
+			values, err := r.touchIdle(ctx, []string{fullKey})
+			if err != nil {
+				resolve(nil, err)
+				return
+			}
+			resolve(values[0], nil)
+		})
+		return pending
+	}
+	b.queue(fullKey, resolve)
+	return pending
+}
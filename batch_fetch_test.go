@@ -0,0 +1,92 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type batchOrder struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+func TestFetchBatch(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	users := NewRepository[TestValue](repo.provider, repo.client, "batch:user:")
+	orders := NewRepository[batchOrder](repo.provider, repo.client, "batch:order:")
+	require.NoError(t, users.Set(ctx, "1", &TestValue{ID: "1", Name: "Ann"}))
+	require.NoError(t, orders.Set(ctx, "9", &batchOrder{ID: "9", Total: 42}))
+	defer repo.client.Del(ctx, "batch:user:1", "batch:order:9")
+
+	metrics := repo.provider.EnableMetrics(MetricsOptions{})
+	defer repo.provider.DisableMetrics()
+
+	var user, again, missing *Pending[TestValue]
+	var order *Pending[batchOrder]
+	err := repo.provider.FetchBatch(ctx, func(b *BatchFetcher) error {
+		user = users.GetBatch(b, "1")
+		again = users.GetBatch(b, "1")
+		missing = users.GetBatch(b, "2")
+		order = orders.GetBatch(b, "9")
+		assert.Equal(t, 4, b.Len())
+		_, err := user.Result()
+		assert.Error(t, err, "results aren't available before the fetch")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), metrics.Commands(), "one MGET for every repository")
+
+	u, err := user.Result()
+	require.NoError(t, err)
+	assert.Equal(t, "Ann", u.Name)
+	u2, err := again.Result()
+	require.NoError(t, err)
+	assert.Equal(t, "1", u2.ID)
+	o, err := order.Result()
+	require.NoError(t, err)
+	assert.Equal(t, 42, o.Total)
+	_, err = missing.Result()
+	gpaErr, ok := err.(gpa.GPAError)
+	require.True(t, ok)
+	assert.Equal(t, gpa.ErrorTypeNotFound, gpaErr.Type)
+}
+
+func TestFetchBatchUsesNearCache(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	users := NewRepository[TestValue](repo.provider, repo.client, "batchnear:user:",
+		WithNearCache(NearCacheOptions{TTL: time.Minute}))
+	require.NoError(t, users.Set(ctx, "1", &TestValue{ID: "1"}))
+	defer repo.client.Del(ctx, "batchnear:user:1")
+
+	for i := 0; i < 2; i++ {
+		var user *Pending[TestValue]
+		require.NoError(t, repo.provider.FetchBatch(ctx, func(b *BatchFetcher) error {
+			user = users.GetBatch(b, "1")
+			return nil
+		}))
+		_, err := user.Result()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, uint64(1), users.NearCache().Stats().Hits, "the second batch is served from the near cache")
+
+	other := NewRepository[TestValue](nil, repo.client, "")
+	other.client = nil
+	var bad *Pending[TestValue]
+	require.NoError(t, repo.provider.FetchBatch(ctx, func(b *BatchFetcher) error {
+		bad = other.GetBatch(b, "x")
+		return nil
+	}))
+	_, err := bad.Result()
+	assert.Error(t, err)
+}
@@ -0,0 +1,207 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Blocking Commands
+// =====================================
+
+// blockingDeadlineMargin is left between a server-side timeout and the context deadline, so
+// the server's empty reply arrives before the connection's read deadline
+const blockingDeadlineMargin = 25 * time.Millisecond
+
+// BlockingOptions configures a BlockingClient
+type BlockingOptions struct {
+	// PoolSize is the number of dedicated connections, i.e. how many blocking commands may
+	// wait at once (default 10)
+	PoolSize int
+	// MaxBlock caps each server-side wait. Longer waits are re-issued every MaxBlock, which
+	// bounds how long a cancellation takes to be noticed (default 1s)
+	MaxBlock time.Duration
+}
+
+// BlockingClient runs blocking commands (BLPOP, BRPOP, BLMOVE, BZPOPMIN, XREAD BLOCK) on its
+// own connections, so waiting consumers can't exhaust the provider's shared pool. Waits end
+// with the context: server-side timeouts are aligned to its deadline, so connections aren't
+// dropped on read timeouts, and cancellation is noticed within MaxBlock. Requires Redis 6
+// or later for sub-second timeouts.
+type BlockingClient struct {
+	client *redis.Client
+	opts   BlockingOptions
+}
+
+// NewBlockingClient creates a blocking client connecting to the provider's server with the
+// provider's hooks (metrics, read-only mode, ...). Close it when done.
+// Example: blocking := provider.NewBlockingClient(gparedis.BlockingOptions{PoolSize: 4})
+func (p *Provider) NewBlockingClient(opts BlockingOptions) *BlockingClient {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	if opts.MaxBlock <= 0 {
+		opts.MaxBlock = time.Second
+	}
+	redisOpts := *p.client.Options()
+	redisOpts.PoolSize = opts.PoolSize
+	redisOpts.MinIdleConns = 0
+	redisOpts.ReadTimeout = opts.MaxBlock + 5*time.Second
+	client := redis.NewClient(&redisOpts)
+	p.addClientHooks(client)
+	return &BlockingClient{client: client, opts: opts}
+}
+
+// Close closes the dedicated connections
+func (b *BlockingClient) Close() error {
+	return b.client.Close()
+}
+
+// BLPop waits for an element at the head of the first non-empty list and removes it
+// Example: key, job, err := blocking.BLPop(ctx, "jobs:high", "jobs:low")
+func (b *BlockingClient) BLPop(ctx context.Context, keys ...string) (key, value string, err error) {
+	return b.listPop(ctx, "blpop", keys)
+}
+
+// BRPop waits for an element at the tail of the first non-empty list and removes it
+func (b *BlockingClient) BRPop(ctx context.Context, keys ...string) (key, value string, err error) {
+	return b.listPop(ctx, "brpop", keys)
+}
+
+// listPop runs BLPOP or BRPOP
+func (b *BlockingClient) listPop(ctx context.Context, name string, keys []string) (string, string, error) {
+	values, err := b.do(ctx, name, keys).StringSlice()
+	if gpaErr, ok := err.(gpa.GPAError); ok {
+		return "", "", gpaErr
+	}
+	if err != nil || len(values) != 2 {
+		return "", "", gpa.NewErrorWithCause(gpa.ErrorTypeDatabase, "unexpected "+strings.ToUpper(name)+" reply", err)
+	}
+	return values[0], values[1], nil
+}
+
+// BLMove waits for an element of source and moves it to destination; from and to are
+// "LEFT" or "RIGHT"
+// Example: job, err := blocking.BLMove(ctx, "jobs", "jobs:processing", "LEFT", "RIGHT")
+func (b *BlockingClient) BLMove(ctx context.Context, source, destination, from, to string) (string, error) {
+	return b.do(ctx, "blmove", []string{source, destination, from, to}).Text()
+}
+
+// BZPopMin waits for the member with the lowest score of the first non-empty sorted set and removes it
+func (b *BlockingClient) BZPopMin(ctx context.Context, keys ...string) (*redis.ZWithKey, error) {
+	return b.zsetPop(ctx, "bzpopmin", keys)
+}
+
+// BZPopMax waits for the member with the highest score of the first non-empty sorted set and removes it
+func (b *BlockingClient) BZPopMax(ctx context.Context, keys ...string) (*redis.ZWithKey, error) {
+	return b.zsetPop(ctx, "bzpopmax", keys)
+}
+
+// zsetPop runs BZPOPMIN or BZPOPMAX
+func (b *BlockingClient) zsetPop(ctx context.Context, name string, keys []string) (*redis.ZWithKey, error) {
+	values, err := b.do(ctx, name, keys).StringSlice()
+	if gpaErr, ok := err.(gpa.GPAError); ok {
+		return nil, gpaErr
+	}
+	if err != nil || len(values) != 3 {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeDatabase, "unexpected "+strings.ToUpper(name)+" reply", err)
+	}
+	score, err := strconv.ParseFloat(values[2], 64)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeDatabase, "invalid "+strings.ToUpper(name)+" score", err)
+	}
+	return &redis.ZWithKey{Key: values[0], Z: redis.Z{Member: values[1], Score: score}}, nil
+}
+
+// XRead waits for entries after the given IDs (stream key to ID; "$" means entries added
+// from now on) and returns up to count per stream (0 for no limit)
+// Example: streams, err := blocking.XRead(ctx, map[string]string{"events": "$"}, 100)
+func (b *BlockingClient) XRead(ctx context.Context, streams map[string]string, count int64) ([]redis.XStream, error) {
+	keys := sortedKeys(streams)
+	args := make([]string, 0, 2*len(keys))
+	args = append(args, keys...)
+	for _, key := range keys {
+		id := streams[key]
+		if id == "$" {
+			// Pin "$" to the current last entry so no entry is missed between re-issued waits
+			last, err := b.client.XRevRangeN(ctx, key, "+", "-", 1).Result()
+			if err != nil {
+				return nil, convertRedisError(err)
+			}
+			id = "0-0"
+			if len(last) > 0 {
+				id = last[0].ID
+			}
+		}
+		args = append(args, id)
+	}
+
+	var result []redis.XStream
+	err := b.wait(ctx, "xread", func(timeout time.Duration) error {
+		var err error
+		result, err = b.client.XRead(ctx, &redis.XReadArgs{Streams: args, Count: count, Block: timeout}).Result()
+		return err
+	})
+	return result, err
+}
+
+// do runs a blocking command whose last argument is the timeout in seconds. Errors ending
+// the wait are reported as GPA errors by the returned command.
+func (b *BlockingClient) do(ctx context.Context, name string, args []string) *redis.Cmd {
+	var reply *redis.Cmd
+	err := b.wait(ctx, name, func(timeout time.Duration) error {
+		cmdArgs := make([]interface{}, 0, len(args)+2)
+		cmdArgs = append(cmdArgs, name)
+		for _, arg := range args {
+			cmdArgs = append(cmdArgs, arg)
+		}
+		cmdArgs = append(cmdArgs, strconv.FormatFloat(timeout.Seconds(), 'f', 3, 64))
+		reply = b.client.Do(ctx, cmdArgs...)
+		return reply.Err()
+	})
+	if err != nil {
+		reply = redis.NewCmd(ctx)
+		reply.SetErr(err)
+	}
+	return reply
+}
+
+// wait issues a blocking command with a server-side timeout bounded by MaxBlock and the
+// context deadline, re-issuing it until it yields a reply or the context ends
+func (b *BlockingClient) wait(ctx context.Context, name string, issue func(timeout time.Duration) error) error {
+	for {
+		timeout := b.opts.MaxBlock
+		if deadline, ok := ctx.Deadline(); ok {
+			if remaining := time.Until(deadline) - blockingDeadlineMargin; remaining < timeout {
+				timeout = remaining
+			}
+		}
+		if ctx.Err() != nil || timeout < time.Millisecond {
+			return b.interrupted(ctx, name)
+		}
+		err := issue(timeout)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return b.interrupted(ctx, name)
+		}
+		if err != redis.Nil {
+			return convertRedisError(err)
+		}
+	}
+}
+
+// interrupted builds the error returned when the context ends before a reply
+func (b *BlockingClient) interrupted(ctx context.Context, name string) error {
+	cause := ctx.Err()
+	if cause == nil {
+		cause = context.DeadlineExceeded
+	}
+	return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, strings.ToUpper(name)+" ended without a reply", cause)
+}
@@ -0,0 +1,107 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockingClientPops(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	blocking := repo.provider.NewBlockingClient(BlockingOptions{PoolSize: 2, MaxBlock: 100 * time.Millisecond})
+	defer blocking.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Elements pushed while waiting are picked up across re-issued waits
+	go func() {
+		time.Sleep(250 * time.Millisecond)
+		repo.client.RPush(context.Background(), "blocking:jobs", "a", "b")
+	}()
+	key, value, err := blocking.BLPop(ctx, "blocking:empty", "blocking:jobs")
+	require.NoError(t, err)
+	assert.Equal(t, "blocking:jobs", key)
+	assert.Equal(t, "a", value)
+
+	_, value, err = blocking.BRPop(ctx, "blocking:jobs")
+	require.NoError(t, err)
+	assert.Equal(t, "b", value)
+
+	require.NoError(t, repo.client.RPush(ctx, "blocking:jobs", "c").Err())
+	moved, err := blocking.BLMove(ctx, "blocking:jobs", "blocking:processing", "LEFT", "RIGHT")
+	require.NoError(t, err)
+	assert.Equal(t, "c", moved)
+	assert.Equal(t, []string{"c"}, repo.client.LRange(ctx, "blocking:processing", 0, -1).Val())
+
+	require.NoError(t, repo.client.ZAdd(ctx, "blocking:tasks", &redis.Z{Member: "low", Score: 1}, &redis.Z{Member: "high", Score: 9}).Err())
+	popped, err := blocking.BZPopMin(ctx, "blocking:tasks")
+	require.NoError(t, err)
+	assert.Equal(t, "low", popped.Member)
+	assert.Equal(t, 1.0, popped.Score)
+	popped, err = blocking.BZPopMax(ctx, "blocking:tasks")
+	require.NoError(t, err)
+	assert.Equal(t, "high", popped.Member)
+}
+
+func TestBlockingClientContext(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	blocking := repo.provider.NewBlockingClient(BlockingOptions{MaxBlock: 100 * time.Millisecond})
+	defer blocking.Close()
+
+	// The wait ends with the deadline rather than the next whole second
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := blocking.BLPop(ctx, "blocking:none")
+	require.Error(t, err)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeTimeout))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// Cancellation is noticed within MaxBlock
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start = time.Now()
+	_, err = blocking.BZPopMin(ctx, "blocking:none")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// The connection is still usable afterwards
+	require.NoError(t, repo.client.RPush(context.Background(), "blocking:after", "x").Err())
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, value, err := blocking.BLPop(ctx, "blocking:after")
+	require.NoError(t, err)
+	assert.Equal(t, "x", value)
+}
+
+func TestBlockingClientXRead(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	blocking := repo.provider.NewBlockingClient(BlockingOptions{MaxBlock: 100 * time.Millisecond})
+	defer blocking.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	require.NoError(t, repo.client.XAdd(ctx, &redis.XAddArgs{Stream: "blocking:events", Values: map[string]interface{}{"n": "old"}}).Err())
+	go func() {
+		time.Sleep(250 * time.Millisecond)
+		repo.client.XAdd(context.Background(), &redis.XAddArgs{Stream: "blocking:events", Values: map[string]interface{}{"n": "new"}})
+	}()
+	streams, err := blocking.XRead(ctx, map[string]string{"blocking:events": "$"}, 10)
+	require.NoError(t, err)
+	require.Len(t, streams, 1)
+	require.Len(t, streams[0].Messages, 1)
+	assert.Equal(t, "new", streams[0].Messages[0].Values["n"])
+}
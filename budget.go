@@ -0,0 +1,128 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Request-Scoped Operation Budgets
+// =====================================
+
+// BudgetLimits caps the Redis work done on behalf of one request
+type BudgetLimits struct {
+	// MaxCommands caps the commands sent; pipelines count one per command (0 disables)
+	MaxCommands int64
+	// MaxTime caps the total time spent waiting on Redis (0 disables)
+	MaxTime time.Duration
+}
+
+// Budget tracks the Redis usage of one request against its limits
+type Budget struct {
+	limits   BudgetLimits
+	commands atomic.Int64
+	elapsed  atomic.Int64
+}
+
+// budgetKey stores the request budget in a context
+type budgetKey struct{}
+
+// WithBudget returns a context whose Redis commands are counted against limits. Once a limit
+// is reached, further commands fail fast with ErrorTypeBudgetExceeded, which surfaces N+1
+// cache access patterns in tests and development.
+// Example: ctx, budget := gparedis.WithBudget(r.Context(), gparedis.BudgetLimits{MaxCommands: 20})
+func WithBudget(ctx context.Context, limits BudgetLimits) (context.Context, *Budget) {
+	budget := &Budget{limits: limits}
+	return context.WithValue(ctx, budgetKey{}, budget), budget
+}
+
+// BudgetFromContext returns the budget attached by WithBudget
+func BudgetFromContext(ctx context.Context) (*Budget, bool) {
+	budget, ok := ctx.Value(budgetKey{}).(*Budget)
+	return budget, ok
+}
+
+// Commands returns the number of commands charged so far
+func (b *Budget) Commands() int64 {
+	return b.commands.Load()
+}
+
+// Elapsed returns the total time spent in Redis so far
+func (b *Budget) Elapsed() time.Duration {
+	return time.Duration(b.elapsed.Load())
+}
+
+// Limits returns the budget's limits
+func (b *Budget) Limits() BudgetLimits {
+	return b.limits
+}
+
+// charge reserves n commands, failing if a limit is already exhausted
+func (b *Budget) charge(n int64) error {
+	if b.limits.MaxTime > 0 && b.Elapsed() >= b.limits.MaxTime {
+		return gpa.NewError(ErrorTypeBudgetExceeded,
+			fmt.Sprintf("request Redis time budget of %s exhausted (%s used)", b.limits.MaxTime, b.Elapsed()))
+	}
+	if b.limits.MaxCommands > 0 {
+		if used := b.commands.Add(n); used > b.limits.MaxCommands {
+			b.commands.Add(-n)
+			return gpa.NewError(ErrorTypeBudgetExceeded,
+				fmt.Sprintf("request command budget of %d exhausted", b.limits.MaxCommands))
+		}
+		return nil
+	}
+	b.commands.Add(n)
+	return nil
+}
+
+// budgetHook charges commands to the budget in their context
+type budgetHook struct{}
+
+// budgetStartKey stores the time a budgeted command was sent
+type budgetStartKey struct{}
+
+func (budgetHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return budgetBefore(ctx, 1)
+}
+
+func (budgetHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	budgetAfter(ctx)
+	return nil
+}
+
+func (budgetHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return budgetBefore(ctx, int64(len(cmds)))
+}
+
+func (budgetHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	budgetAfter(ctx)
+	return nil
+}
+
+// budgetBefore charges n commands and records the start time
+func budgetBefore(ctx context.Context, n int64) (context.Context, error) {
+	budget, ok := BudgetFromContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	if err := budget.charge(n); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, budgetStartKey{}, time.Now()), nil
+}
+
+// budgetAfter adds the command's duration to the budget
+func budgetAfter(ctx context.Context) {
+	start, ok := ctx.Value(budgetStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	if budget, ok := BudgetFromContext(ctx); ok {
+		budget.elapsed.Add(int64(time.Since(start)))
+	}
+}
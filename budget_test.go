@@ -0,0 +1,57 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandBudget(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.Set(context.Background(), fmt.Sprint(i), &TestValue{ID: fmt.Sprint(i)}))
+	}
+
+	ctx, budget := WithBudget(context.Background(), BudgetLimits{MaxCommands: 3})
+	for i := 0; i < 3; i++ {
+		_, err := repo.Get(ctx, fmt.Sprint(i))
+		require.NoError(t, err)
+	}
+	_, err := repo.Get(ctx, "3")
+	assert.True(t, IsBudgetExceededError(err), "unexpected error: %v", err)
+	assert.Equal(t, int64(3), budget.Commands())
+	assert.Greater(t, budget.Elapsed(), time.Duration(0))
+
+	// Pipelines are charged per command
+	_, err = repo.provider.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "0")
+		return nil
+	})
+	assert.True(t, IsBudgetExceededError(err))
+
+	// Batching stays within budget where single-key access wouldn't
+	ctx, budget = WithBudget(context.Background(), BudgetLimits{MaxCommands: 1})
+	found, err := repo.MGet(ctx, []string{"0", "1", "2", "3", "4"})
+	require.NoError(t, err)
+	assert.Len(t, found, 5)
+	assert.Equal(t, int64(1), budget.Commands())
+
+	// Contexts without a budget are unaffected
+	_, err = repo.Get(context.Background(), "4")
+	require.NoError(t, err)
+}
+
+func TestTimeBudget(t *testing.T) {
+	b := &Budget{limits: BudgetLimits{MaxTime: time.Millisecond}}
+	require.NoError(t, b.charge(1))
+	b.elapsed.Add(int64(2 * time.Millisecond))
+	assert.True(t, IsBudgetExceededError(b.charge(1)))
+	assert.Equal(t, BudgetLimits{MaxTime: time.Millisecond}, b.Limits())
+}
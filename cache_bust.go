@@ -0,0 +1,164 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Fleet-Wide Cache Busting
+// =====================================
+
+// CacheBustChannel is the Pub/Sub channel carrying cache bust commands
+const CacheBustChannel = "gparedis:cache-bust"
+
+// CacheBustOp is the kind of cache bust
+type CacheBustOp string
+
+const (
+	// CacheBustKeys drops the copies of specific keys
+	CacheBustKeys CacheBustOp = "keys"
+	// CacheBustPrefix drops the copies of keys starting with a key prefix
+	CacheBustPrefix CacheBustOp = "prefix"
+	// CacheBustNamespace drops every copy of a repository, or of all repositories
+	CacheBustNamespace CacheBustOp = "namespace"
+)
+
+// CacheBust is a command dropping near cache copies on every provider instance
+type CacheBust struct {
+	Op CacheBustOp `json:"op"`
+	// Namespace is the repository prefix whose near cache is busted; empty busts every repository
+	Namespace string `json:"namespace,omitempty"`
+	// Keys are the keys (relative to the repository prefix) dropped by CacheBustKeys
+	Keys []string `json:"keys,omitempty"`
+	// KeyPrefix selects the keys (relative to the repository prefix) dropped by CacheBustPrefix
+	KeyPrefix string `json:"keyPrefix,omitempty"`
+}
+
+// InvalidateKeys drops the near cache copies of keys in the repository at namespace, on this
+// instance and every instance running ListenCacheBusts
+// Example: err := provider.InvalidateKeys(ctx, "product:", "42", "43")
+func (p *Provider) InvalidateKeys(ctx context.Context, namespace string, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return p.publishCacheBust(ctx, CacheBust{Op: CacheBustKeys, Namespace: namespace, Keys: keys})
+}
+
+// InvalidatePrefix drops the near cache copies of the keys starting with keyPrefix in the
+// repository at namespace, fleet-wide
+// Example: err := provider.InvalidatePrefix(ctx, "product:", "eu:")
+func (p *Provider) InvalidatePrefix(ctx context.Context, namespace, keyPrefix string) error {
+	return p.publishCacheBust(ctx, CacheBust{Op: CacheBustPrefix, Namespace: namespace, KeyPrefix: keyPrefix})
+}
+
+// FlushNamespace drops every near cache copy of the repository at namespace, or of all
+// repositories when namespace is empty, fleet-wide
+func (p *Provider) FlushNamespace(ctx context.Context, namespace string) error {
+	return p.publishCacheBust(ctx, CacheBust{Op: CacheBustNamespace, Namespace: namespace})
+}
+
+// publishCacheBust applies bust locally and publishes it to the other instances
+func (p *Provider) publishCacheBust(ctx context.Context, bust CacheBust) error {
+	p.applyCacheBust(bust)
+	payload, err := json.Marshal(bust)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to encode cache bust", err)
+	}
+	return convertRedisError(p.client.Publish(ctx, CacheBustChannel, payload).Err())
+}
+
+// ListenCacheBusts applies the cache busts published by any instance to this provider's near
+// caches until ctx is cancelled, calling onBust (when not nil) after each one. Busts
+// published while the subscription reconnects are lost, so near cache TTLs remain the
+// backstop for stale copies.
+// Example: go provider.ListenCacheBusts(ctx, nil)
+func (p *Provider) ListenCacheBusts(ctx context.Context, onBust func(CacheBust)) error {
+	pubsub := p.client.Subscribe(ctx, CacheBustChannel)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to subscribe to "+CacheBustChannel, err)
+	}
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return ctx.Err()
+			}
+			var bust CacheBust
+			if err := json.Unmarshal([]byte(msg.Payload), &bust); err != nil {
+				continue
+			}
+			p.applyCacheBust(bust)
+			if onBust != nil {
+				onBust(bust)
+			}
+		}
+	}
+}
+
+// applyCacheBust drops the copies selected by bust from the registered near caches
+func (p *Provider) applyCacheBust(bust CacheBust) {
+	p.nearCachesMu.Lock()
+	var caches []*NearCache
+	for prefix, c := range p.nearCaches {
+		if bust.Namespace == "" || prefix == bust.Namespace {
+			caches = append(caches, c)
+		}
+	}
+	p.nearCachesMu.Unlock()
+
+	for _, c := range caches {
+		switch bust.Op {
+		case CacheBustKeys:
+			c.Invalidate(bust.Keys...)
+		case CacheBustPrefix:
+			c.InvalidatePrefix(bust.KeyPrefix)
+		case CacheBustNamespace:
+			c.Purge()
+		}
+	}
+}
+
+// InvalidatePrefix drops the copies of keys starting with keyPrefix
+func (c *NearCache) InvalidatePrefix(keyPrefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.entries {
+		if strings.HasPrefix(key, keyPrefix) {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// RunCacheBustCommand runs a cache bust given as command line arguments, for wiring into
+// admin CLIs:
+//
+//	keys <namespace> <key>...
+//	prefix <namespace> <key prefix>
+//	flush [<namespace>]
+func RunCacheBustCommand(ctx context.Context, p *Provider, args []string) error {
+	if len(args) == 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "usage: keys <namespace> <key>... | prefix <namespace> <key prefix> | flush [<namespace>]")
+	}
+	switch {
+	case args[0] == "keys" && len(args) >= 3:
+		return p.InvalidateKeys(ctx, args[1], args[2:]...)
+	case args[0] == "prefix" && len(args) == 3:
+		return p.InvalidatePrefix(ctx, args[1], args[2])
+	case args[0] == "flush" && len(args) <= 2:
+		namespace := ""
+		if len(args) == 2 {
+			namespace = args[1]
+		}
+		return p.FlushNamespace(ctx, namespace)
+	}
+	return gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid cache bust command: "+strings.Join(args, " "))
+}
@@ -0,0 +1,66 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheBust(t *testing.T) {
+	admin, cleanup := setupTestRepository(t)
+	defer cleanup()
+	instance, cleanupInstance := setupTestRepository(t)
+	defer cleanupInstance()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	products := NewRepository[TestValue](instance.provider, instance.client, "bust:product:",
+		WithNearCache(NearCacheOptions{TTL: time.Hour}))
+	orders := NewRepository[TestValue](instance.provider, instance.client, "bust:order:",
+		WithNearCache(NearCacheOptions{TTL: time.Hour}))
+	for _, key := range []string{"eu:1", "eu:2", "us:1"} {
+		require.NoError(t, products.Set(ctx, key, &TestValue{ID: key}))
+		_, err := products.Get(ctx, key)
+		require.NoError(t, err)
+	}
+	require.NoError(t, orders.Set(ctx, "1", &TestValue{ID: "1"}))
+	_, err := orders.Get(ctx, "1")
+	require.NoError(t, err)
+	require.Equal(t, 3, products.NearCache().Len())
+
+	busts := make(chan CacheBust, 4)
+	go func() { _ = instance.provider.ListenCacheBusts(ctx, func(b CacheBust) { busts <- b }) }()
+	// Wait until the listener is subscribed
+	require.Eventually(t, func() bool {
+		n, err := admin.client.PubSubNumSub(ctx, CacheBustChannel).Result()
+		return err == nil && n[CacheBustChannel] > 0
+	}, 2*time.Second, 10*time.Millisecond)
+
+	receive := func() CacheBust {
+		select {
+		case b := <-busts:
+			return b
+		case <-time.After(2 * time.Second):
+			t.Fatal("cache bust not received")
+			return CacheBust{}
+		}
+	}
+
+	require.NoError(t, admin.provider.InvalidateKeys(ctx, "bust:product:", "us:1"))
+	assert.Equal(t, []string{"us:1"}, receive().Keys)
+	assert.Equal(t, 2, products.NearCache().Len())
+
+	require.NoError(t, RunCacheBustCommand(ctx, admin.provider, []string{"prefix", "bust:product:", "eu:"}))
+	assert.Equal(t, CacheBustPrefix, receive().Op)
+	assert.Equal(t, 0, products.NearCache().Len())
+	assert.Equal(t, 1, orders.NearCache().Len())
+
+	require.NoError(t, admin.provider.FlushNamespace(ctx, ""))
+	receive()
+	assert.Equal(t, 0, orders.NearCache().Len())
+
+	assert.Error(t, RunCacheBustCommand(ctx, admin.provider, []string{"prefix", "bust:product:"}))
+}
@@ -0,0 +1,135 @@
+package gparedis
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Canonical JSON Encoding
+// =====================================
+
+// WithCanonicalJSON stores values as canonical JSON: object keys sorted, no insignificant
+// whitespace, no HTML escaping, integers as written and other numbers in their shortest
+// round-trip form (1.50 and 1.5e0 both become 1.5). Equal values then always encode to
+// equal bytes, which MCompareAndSwap, hash-based writes and deduplication rely on. Values
+// written without the option keep their old bytes until rewritten.
+func WithCanonicalJSON() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.canonicalJSON = true
+	}
+}
+
+// CanonicalJSON marshals v the way WithCanonicalJSON stores it, for computing hashes or
+// expected values outside a repository
+// Example: data, err := gparedis.CanonicalJSON(order)
+func CanonicalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize value", err)
+	}
+	return canonicalizeJSON(buf.Bytes())
+}
+
+// canonicalizeJSON rewrites a JSON document in canonical form
+func canonicalizeJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to canonicalize value", err)
+	}
+	var b bytes.Buffer
+	if err := writeCanonicalJSON(&b, v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// writeCanonicalJSON appends the canonical encoding of a decoded JSON value
+func writeCanonicalJSON(b *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeCanonicalString(b, k)
+			b.WriteByte(':')
+			if err := writeCanonicalJSON(b, v[k]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case []interface{}:
+		b.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case string:
+		writeCanonicalString(b, v)
+	case json.Number:
+		return writeCanonicalNumber(b, v)
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case nil:
+		b.WriteString("null")
+	}
+	return nil
+}
+
+// writeCanonicalString appends a JSON string without HTML escaping
+func writeCanonicalString(b *bytes.Buffer, s string) {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	// Encode terminates the value with a newline
+	b.Truncate(b.Len() - 1)
+}
+
+// writeCanonicalNumber keeps integers as written, so int64 values beyond float64 precision
+// survive, and prints every other number in its shortest round-trip form
+func writeCanonicalNumber(b *bytes.Buffer, n json.Number) error {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if s == "-0" {
+			s = "0"
+		}
+		b.WriteString(s)
+		return nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to canonicalize number "+s, err)
+	}
+	// encoding/json formats float64 like ECMAScript: shortest form, exponent only outside
+	// [1e-6, 1e21)
+	out, err := json.Marshal(f)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to canonicalize number "+s, err)
+	}
+	if string(out) == "-0" {
+		out = []byte("0")
+	}
+	b.Write(out)
+	return nil
+}
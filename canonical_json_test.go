@@ -0,0 +1,70 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalizeJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"b": 1, "a": {"d": [1, 2], "c": null}}`:  `{"a":{"c":null,"d":[1,2]},"b":1}`,
+		`[1.50, 1.5e0, 1E2, 0.000001, 1e-7, 1e21]`: `[1.5,1.5,100,0.000001,1e-7,1e+21]`,
+		`9223372036854775807`:                      `9223372036854775807`,
+		`[-0, -0.0]`:                               `[0,0]`,
+		`"<a & b>"`:                                `"<a & b>"`,
+		`"line\nbreak é"`:                          `"line\nbreak é"`,
+		`true`:                                     `true`,
+	}
+	for in, want := range tests {
+		got, err := canonicalizeJSON([]byte(in))
+		require.NoError(t, err, in)
+		assert.Equal(t, want, string(got), in)
+	}
+
+	_, err := canonicalizeJSON([]byte(`{"a":`))
+	assert.Error(t, err)
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	a, err := CanonicalJSON(map[string]interface{}{"z": 1.0, "a": "x<y"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"x<y","z":1}`, string(a))
+
+	// The same document decoded and re-encoded yields the same bytes
+	var decoded interface{}
+	require.NoError(t, json.Unmarshal(a, &decoded))
+	b, err := CanonicalJSON(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+}
+
+func TestWithCanonicalJSON(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	type doc struct {
+		Name  string             `json:"name"`
+		Tags  map[string]float64 `json:"tags"`
+		Notes string             `json:"notes"`
+	}
+	repo := NewRepository[doc](base.provider, base.client, "canon:", WithCanonicalJSON())
+	value := &doc{Name: "a", Tags: map[string]float64{"y": 2.50, "x": 1}, Notes: "<b>"}
+	require.NoError(t, repo.Set(ctx, "1", value))
+
+	raw, err := base.client.Get(ctx, "canon:1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"a","notes":"<b>","tags":{"x":1,"y":2.5}}`, raw)
+
+	got, err := repo.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, value, got)
+
+	swapped, err := repo.MCompareAndSwap(ctx, map[string]*doc{"1": got}, map[string]*doc{"1": {Name: "b"}}, 0)
+	require.NoError(t, err)
+	assert.True(t, swapped, "a read value compares equal to the stored bytes")
+}
@@ -0,0 +1,154 @@
+package gparedis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Change Data Capture
+// =====================================
+
+// ChangeOp is the kind of write recorded in a change event
+type ChangeOp string
+
+const (
+	// ChangeOpSet records a create or update
+	ChangeOpSet ChangeOp = "set"
+	// ChangeOpDelete records a deletion
+	ChangeOpDelete ChangeOp = "delete"
+)
+
+// ChangeEvent is appended to the change stream for every captured write.
+// Version increases by one for every change of a key and survives deletes.
+type ChangeEvent struct {
+	Key       string    `json:"key"`
+	Op        ChangeOp  `json:"op"`
+	Version   int64     `json:"version"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CaptureChanges makes every write of the repository (Set, SetWithTTL, MSet, MSetNX,
+// MCompareAndSwap, SetIfUnchanged, Increment, DeleteKey, MDelete, loader write-backs, imports
+// and the SetTx, DeleteKeyTx and IncrementTx of MultiRepo) append a ChangeEvent to the stream
+// at streamKey in the same atomic step as the write. Increments are recorded as sets. Per-key
+// versions are kept in the hash streamKey+":versions". maxLen approximately caps the stream (0 for unbounded).
+// Example: repo := gparedis.NewRepository[User](provider, client, "user:", gparedis.CaptureChanges("cdc:user", 1_000_000))
+func CaptureChanges(streamKey string, maxLen int64) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.changeStream = streamKey
+		o.changeStreamMaxLen = maxLen
+	}
+}
+
+// captureEventLua defines capture(stream, versions, key, op, maxlen, ts), which bumps the
+// version of the relative key and appends its change event. Scripts that write keys under
+// change capture are prefixed with it.
+const captureEventLua = `
+local function capture(stream, versions, key, op, maxlen, ts)
+	local version = redis.call('HINCRBY', versions, key, 1)
+	local event = '{"key":' .. cjson.encode(key) .. ',"op":"' .. op .. '","version":' .. version .. ',"timestamp":"' .. ts .. '"}'
+	if maxlen > 0 then
+		redis.call('XADD', stream, 'MAXLEN', '~', maxlen, '*', 'data', event)
+	else
+		redis.call('XADD', stream, '*', 'data', event)
+	end
+end
+`
+
+// captureScript applies a set or delete to KEYS[3..] and records one change event per affected key.
+// KEYS: stream, versions hash, value keys. ARGV: op, maxlen, timestamp, then (key, data, ttl ms) triples.
+var captureScript = redis.NewScript(captureEventLua + `
+local op = ARGV[1]
+local maxlen = tonumber(ARGV[2])
+local ts = ARGV[3]
+local changed = 0
+for i = 3, #KEYS do
+	local base = 4 + (i - 3) * 3
+	local applied = true
+	if op == 'set' then
+		local data = ARGV[base + 1]
+		local ttl = tonumber(ARGV[base + 2])
+		if ttl > 0 then
+			redis.call('SET', KEYS[i], data, 'PX', ttl)
+		else
+			redis.call('SET', KEYS[i], data)
+		end
+	else
+		applied = redis.call('DEL', KEYS[i]) == 1
+	end
+	if applied then
+		changed = changed + 1
+		capture(KEYS[1], KEYS[2], ARGV[base], op, maxlen, ts)
+	end
+end
+return changed
+`)
+
+// incrementScript increments KEYS[1] by ARGV[1] and records a set event on the change stream
+// KEYS[2] and its versions hash KEYS[3]. ARGV[2..4] are the relative key, maxlen and timestamp.
+// Returns the new value.
+var incrementScript = redis.NewScript(captureEventLua + `
+local value = redis.call('INCRBY', KEYS[1], ARGV[1])
+capture(KEYS[2], KEYS[3], ARGV[2], 'set', tonumber(ARGV[3]), ARGV[4])
+return value
+`)
+
+// Changes returns the change stream of the repository, or nil when capture is disabled
+func (r *Repository[T]) Changes() *Stream[ChangeEvent] {
+	if r.opts.changeStream == "" {
+		return nil
+	}
+	return &Stream[ChangeEvent]{provider: r.provider, client: r.client, key: r.opts.changeStream}
+}
+
+// captureWrite runs a captured set (payloads non-nil) or delete of keys and returns the number of keys changed.
+// ttls holds the expiration of each key for sets (nil for no expiration).
+func (r *Repository[T]) captureWrite(ctx context.Context, op ChangeOp, keys []string, payloads [][]byte, ttls []time.Duration) (int64, error) {
+	fullKeys, args := r.captureArgs(op, keys, payloads, ttls)
+	changed, err := captureScript.Run(ctx, r.client, fullKeys, args...).Int64()
+	if err != nil {
+		return 0, convertRedisError(err)
+	}
+	return changed, nil
+}
+
+// captureArgs builds the KEYS and ARGV of captureScript
+func (r *Repository[T]) captureArgs(op ChangeOp, keys []string, payloads [][]byte, ttls []time.Duration) ([]string, []interface{}) {
+	fullKeys := make([]string, 0, len(keys)+2)
+	fullKeys = append(fullKeys, r.changeKeys()...)
+	args := make([]interface{}, 0, len(keys)*3+3)
+	args = append(args, string(op), r.opts.changeStreamMaxLen, changeTimestamp())
+
+	for i, key := range keys {
+		fullKeys = append(fullKeys, r.buildKey(key))
+		var data []byte
+		if payloads != nil {
+			data = payloads[i]
+		}
+		var ttl time.Duration
+		if ttls != nil {
+			ttl = ttls[i]
+		}
+		args = append(args, key, data, ttl.Milliseconds())
+	}
+	return fullKeys, args
+}
+
+// changeKeys returns the change stream and its versions hash
+func (r *Repository[T]) changeKeys() []string {
+	return []string{r.opts.changeStream, r.opts.changeStream + ":versions"}
+}
+
+// changeTimestamp formats the timestamp of change events written now
+func changeTimestamp() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// incrementArgs builds the KEYS and ARGV of incrementScript
+func (r *Repository[T]) incrementArgs(key string, delta int64) ([]string, []interface{}) {
+	fullKeys := append([]string{r.buildKey(key)}, r.changeKeys()...)
+	return fullKeys, []interface{}{delta, key, r.opts.changeStreamMaxLen, changeTimestamp()}
+}
@@ -0,0 +1,120 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureChanges(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[TestValue](base.provider, base.client, "user:", CaptureChanges("cdc:user", 0))
+	assert.Nil(t, base.Changes())
+
+	require.NoError(t, repo.Set(ctx, "1", &TestValue{ID: "1", Name: "Alice"}))
+	require.NoError(t, repo.SetWithTTL(ctx, "1", &TestValue{ID: "1", Name: "Alicia"}, time.Minute))
+	require.NoError(t, repo.MSet(ctx, map[string]*TestValue{"2": {ID: "2"}, "3": {ID: "3"}}))
+	require.NoError(t, repo.DeleteKey(ctx, "1"))
+	deleted, err := repo.MDelete(ctx, []string{"2", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	// Writes still land where they should
+	value, err := repo.Get(ctx, "3")
+	require.NoError(t, err)
+	assert.Equal(t, "3", value.ID)
+	exists, err := repo.Exists(ctx, KeyPattern("1"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	events, err := repo.Changes().Range(ctx, "-", "+", 0)
+	require.NoError(t, err)
+	require.Len(t, events, 6)
+
+	type change struct {
+		key     string
+		op      ChangeOp
+		version int64
+	}
+	var got []change
+	for _, e := range events {
+		got = append(got, change{e.Value.Key, e.Value.Op, e.Value.Version})
+		assert.WithinDuration(t, time.Now(), e.Value.Timestamp, time.Minute)
+	}
+	assert.Equal(t, []change{
+		{"1", ChangeOpSet, 1},
+		{"1", ChangeOpSet, 2},
+		{"2", ChangeOpSet, 1},
+		{"3", ChangeOpSet, 1},
+		{"1", ChangeOpDelete, 3},
+		{"2", ChangeOpDelete, 2},
+	}, got)
+}
+
+func TestCaptureChangesOnEveryWritePath(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[TestValue](base.provider, base.client, "user:", CaptureChanges("cdc:paths", 0))
+
+	written, err := repo.MSetNX(ctx, map[string]*TestValue{"1": {ID: "1"}})
+	require.NoError(t, err)
+	require.True(t, written)
+	written, err = repo.MSetNX(ctx, map[string]*TestValue{"1": {ID: "1"}})
+	require.NoError(t, err)
+	require.False(t, written)
+
+	swapped, err := repo.MCompareAndSwap(ctx, map[string]*TestValue{"1": {ID: "1"}}, map[string]*TestValue{"1": {ID: "1", Name: "Alice"}}, 0)
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	_, err = repo.MGetOrLoad(ctx, []string{"2"}, func(missing []string) (map[string]*TestValue, error) {
+		return map[string]*TestValue{"2": {ID: "2"}}, nil
+	}, time.Minute)
+	require.NoError(t, err)
+
+	n, err := repo.Increment(ctx, "count", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	require.NoError(t, base.provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		if err := repo.SetTx(tx, "3", &TestValue{ID: "3"}); err != nil {
+			return err
+		}
+		if err := repo.IncrementTx(tx, "count", 1); err != nil {
+			return err
+		}
+		return repo.DeleteKeyTx(tx, "2")
+	}))
+	value, err := base.client.Get(ctx, "user:count").Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), value)
+
+	events, err := repo.Changes().Range(ctx, "-", "+", 0)
+	require.NoError(t, err)
+	type change struct {
+		key     string
+		op      ChangeOp
+		version int64
+	}
+	var got []change
+	for _, e := range events {
+		got = append(got, change{e.Value.Key, e.Value.Op, e.Value.Version})
+	}
+	assert.Equal(t, []change{
+		{"1", ChangeOpSet, 1},
+		{"1", ChangeOpSet, 2},
+		{"2", ChangeOpSet, 1},
+		{"count", ChangeOpSet, 1},
+		{"3", ChangeOpSet, 1},
+		{"count", ChangeOpSet, 2},
+		{"2", ChangeOpDelete, 2},
+	}, got)
+}
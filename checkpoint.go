@@ -0,0 +1,196 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Batch Job Checkpoints
+// =====================================
+
+// CheckpointOptions configures a Checkpoint
+type CheckpointOptions struct {
+	// LeaseTTL is how long ownership lasts without a Save or Renew (default 30s)
+	LeaseTTL time.Duration
+}
+
+// Checkpoint persists the position of a bulk job (a SCAN cursor, an offset, the last key
+// processed) at key, so a job interrupted by a crash, a deploy or an error resumes where it
+// left off. One process at a time owns the job through a lease at key+":lease", taken by
+// Acquire and renewed by every Save; a process whose lease expired can no longer move the
+// position, so a stalled worker can't overwrite the progress of the one that took over.
+type Checkpoint struct {
+	client   *redis.Client
+	key      string
+	opts     CheckpointOptions
+	token    string
+	position string
+}
+
+// NewCheckpoint creates the checkpoint of the job stored at key
+// Example: cp := gparedis.NewCheckpoint(provider, "jobs:export-users", gparedis.CheckpointOptions{})
+func NewCheckpoint(provider *Provider, key string, opts CheckpointOptions) *Checkpoint {
+	return newCheckpoint(provider.client, key, opts)
+}
+
+// newCheckpoint creates a checkpoint on client, for repositories without a provider
+func newCheckpoint(client *redis.Client, key string, opts CheckpointOptions) *Checkpoint {
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = 30 * time.Second
+	}
+	return &Checkpoint{client: client, key: key, opts: opts}
+}
+
+// Key returns the key holding the position
+func (c *Checkpoint) Key() string {
+	return c.key
+}
+
+// leaseKey holds the token of the owning process
+func (c *Checkpoint) leaseKey() string {
+	return c.key + ":lease"
+}
+
+// Acquire takes ownership of the job and returns the saved position, "" for a job that
+// hasn't saved one. Returns ErrorTypeConflict while another process holds the lease.
+func (c *Checkpoint) Acquire(ctx context.Context) (string, error) {
+	token, err := newRandomID()
+	if err != nil {
+		return "", gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to generate lease token", err)
+	}
+	acquired, err := c.client.SetNX(ctx, c.leaseKey(), token, c.opts.LeaseTTL).Result()
+	if err != nil {
+		return "", convertRedisError(err)
+	}
+	if !acquired {
+		return "", gpa.NewError(ErrorTypeConflict, "job "+c.key+" is owned by another process")
+	}
+	position, err := c.client.Get(ctx, c.key).Result()
+	if err != nil && err != redis.Nil {
+		releaseLockScript.Run(context.Background(), c.client, []string{c.leaseKey()}, token)
+		return "", convertRedisError(err)
+	}
+	c.token, c.position = token, position
+	return position, nil
+}
+
+// checkpointScript updates a checkpoint while the caller owns its lease.
+// KEYS[1]: position, KEYS[2]: lease. ARGV[1]: token, ARGV[2]: lease TTL (ms),
+// ARGV[3]: "save", "reset", "renew" or "complete", ARGV[4]: position.
+// Returns 1, or 0 when the lease was lost.
+var checkpointScript = redis.NewScript(`
+if redis.call('GET', KEYS[2]) ~= ARGV[1] then
+	return 0
+end
+if ARGV[3] == 'complete' then
+	redis.call('DEL', KEYS[1], KEYS[2])
+	return 1
+end
+if ARGV[3] == 'save' then
+	redis.call('SET', KEYS[1], ARGV[4])
+elseif ARGV[3] == 'reset' then
+	redis.call('DEL', KEYS[1])
+end
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+return 1
+`)
+
+// update runs checkpointScript, returning ErrorTypeConflict when the lease was lost
+func (c *Checkpoint) update(ctx context.Context, mode, position string) error {
+	if c.token == "" {
+		return gpa.NewError(ErrorTypeConflict, "job "+c.key+" was not acquired")
+	}
+	ok, err := checkpointScript.Run(ctx, c.client, []string{c.key, c.leaseKey()},
+		c.token, c.opts.LeaseTTL.Milliseconds(), mode, position).Int()
+	if err != nil {
+		return convertRedisError(err)
+	}
+	if ok == 0 {
+		c.token = ""
+		return gpa.NewError(ErrorTypeConflict, "lease of job "+c.key+" was lost")
+	}
+	return nil
+}
+
+// Save records the position reached and renews the lease
+func (c *Checkpoint) Save(ctx context.Context, position string) error {
+	if err := c.update(ctx, "save", position); err != nil {
+		return err
+	}
+	c.position = position
+	return nil
+}
+
+// Renew extends the lease without moving the position, for batches slower than LeaseTTL
+func (c *Checkpoint) Renew(ctx context.Context) error {
+	return c.update(ctx, "renew", "")
+}
+
+// Reset forgets the saved position, so the job starts over
+func (c *Checkpoint) Reset(ctx context.Context) error {
+	if err := c.update(ctx, "reset", ""); err != nil {
+		return err
+	}
+	c.position = ""
+	return nil
+}
+
+// Complete removes the position and the lease once the job is done
+func (c *Checkpoint) Complete(ctx context.Context) error {
+	if err := c.update(ctx, "complete", ""); err != nil {
+		return err
+	}
+	c.token, c.position = "", ""
+	return nil
+}
+
+// Release gives up ownership and keeps the position, so the next Acquire resumes from it
+func (c *Checkpoint) Release(ctx context.Context) error {
+	if c.token == "" {
+		return nil
+	}
+	token := c.token
+	c.token = ""
+	return convertRedisError(releaseLockScript.Run(ctx, c.client, []string{c.leaseKey()}, token).Err())
+}
+
+// Scan walks the keys matching pattern with SCAN from the saved cursor, calling fn for every
+// batch and saving the cursor after it; the checkpoint is completed once the scan ends. The
+// checkpoint must be acquired. A batch interrupted midway is passed to fn again on resume, so
+// fn must be idempotent.
+// Example: err := cp.Scan(ctx, "user:*", 500, func(keys []string) error { return export(keys) })
+func (c *Checkpoint) Scan(ctx context.Context, pattern string, count int64, fn func(keys []string) error) error {
+	if c.token == "" {
+		return gpa.NewError(ErrorTypeConflict, "job "+c.key+" was not acquired")
+	}
+	if count <= 0 {
+		count = scanBatchSize
+	}
+	var cursor uint64
+	if c.position != "" {
+		var err error
+		if cursor, err = strconv.ParseUint(c.position, 10, 64); err != nil {
+			return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "invalid checkpoint cursor "+c.position, err)
+		}
+	}
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, pattern, count).Result()
+		if err != nil {
+			return convertRedisError(err)
+		}
+		if err := fn(keys); err != nil {
+			return err
+		}
+		if cursor = next; cursor == 0 {
+			return c.Complete(ctx)
+		}
+		if err := c.Save(ctx, strconv.FormatUint(cursor, 10)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointLease(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	first := NewCheckpoint(repo.provider, "jobs:migrate", CheckpointOptions{LeaseTTL: 100 * time.Millisecond})
+	second := NewCheckpoint(repo.provider, "jobs:migrate", CheckpointOptions{LeaseTTL: 100 * time.Millisecond})
+
+	position, err := first.Acquire(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, position)
+	require.NoError(t, first.Save(ctx, "offset=10"))
+	_, err = second.Acquire(ctx)
+	assert.True(t, IsConflictError(err), "the lease is held")
+
+	// A stalled owner loses the job and can't move the position any more
+	time.Sleep(150 * time.Millisecond)
+	position, err = second.Acquire(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "offset=10", position)
+	assert.True(t, IsConflictError(first.Save(ctx, "offset=20")))
+	assert.True(t, IsConflictError(first.Renew(ctx)), "the lost lease stays lost")
+
+	require.NoError(t, second.Save(ctx, "offset=30"))
+	require.NoError(t, second.Release(ctx))
+	position, err = first.Acquire(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "offset=30", position, "a released job resumes from its position")
+	require.NoError(t, first.Complete(ctx))
+	exists, err := repo.client.Exists(ctx, "jobs:migrate", "jobs:migrate:lease").Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists)
+}
+
+func TestCheckpointScanResumes(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		require.NoError(t, repo.client.Set(ctx, fmt.Sprintf("item:%d", i), i, 0).Err())
+	}
+
+	seen := map[string]int{}
+	boom := errors.New("boom")
+	batches := 0
+	cp := NewCheckpoint(repo.provider, "jobs:scan", CheckpointOptions{})
+	_, err := cp.Acquire(ctx)
+	require.NoError(t, err)
+	err = cp.Scan(ctx, "item:*", 5, func(keys []string) error {
+		if batches++; batches == 3 {
+			return boom
+		}
+		for _, key := range keys {
+			seen[key]++
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, boom)
+	require.NoError(t, cp.Release(ctx))
+	saved, err := repo.client.Get(ctx, "jobs:scan").Result()
+	require.NoError(t, err)
+	assert.NotEqual(t, "0", saved)
+
+	resumed := NewCheckpoint(repo.provider, "jobs:scan", CheckpointOptions{})
+	position, err := resumed.Acquire(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, saved, position)
+	require.NoError(t, resumed.Scan(ctx, "item:*", 5, func(keys []string) error {
+		for _, key := range keys {
+			seen[key]++
+		}
+		return nil
+	}))
+	assert.Len(t, seen, 50, "every key is visited across both runs")
+	exists, err := repo.client.Exists(ctx, "jobs:scan").Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists, "the checkpoint is removed once the scan ends")
+
+	assert.True(t, IsConflictError(NewCheckpoint(repo.provider, "jobs:other", CheckpointOptions{}).Scan(ctx, "*", 0, nil)))
+}
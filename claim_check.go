@@ -0,0 +1,139 @@
+package gparedis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Claim Checks
+// =====================================
+
+// claimPayloadScript returns the stored payload of KEYS[1] and counts the read, deleting the
+// key once its last reader claimed it (readers 0 keeps the key until it expires)
+var claimPayloadScript = redis.NewScript(`
+local body = redis.call('HGET', KEYS[1], 'body')
+if not body then
+	return false
+end
+if tonumber(redis.call('HGET', KEYS[1], 'readers')) > 0 and redis.call('HINCRBY', KEYS[1], 'readers', -1) == 0 then
+	redis.call('DEL', KEYS[1])
+end
+return body
+`)
+
+// ClaimCheckOptions configures a ClaimCheck
+type ClaimCheckOptions struct {
+	// Threshold is the payload size in bytes above which payloads are stored; smaller ones
+	// pass inline (default 64KiB)
+	Threshold int
+	// TTL bounds how long a stored payload waits for its readers (default 1h)
+	TTL time.Duration
+	// Readers is how many claims consume a stored payload before it is deleted, such as the
+	// number of consumer groups of a stream (default 1)
+	Readers int
+	// KeepUntilExpiry keeps stored payloads until TTL regardless of claims, for Pub/Sub
+	// fan-out where the number of subscribers is unknown
+	KeepUntilExpiry bool
+	// Prefix is the key prefix of stored payloads (default "gparedis:claim:")
+	Prefix string
+}
+
+// ClaimCheck stores large payloads in TTL'd keys and passes only a reference through queues,
+// streams and Pub/Sub, keeping messages small. Consumers resolve references with Claim (or
+// transparently through Router, Stream and DecodePayload); the stored payload is deleted
+// after its readers claimed it, or when the TTL runs out.
+type ClaimCheck struct {
+	provider *Provider
+	opts     ClaimCheckOptions
+}
+
+// NewClaimCheck creates a claim check storing payloads through provider
+// Example: checks := gparedis.NewClaimCheck(provider, gparedis.ClaimCheckOptions{Threshold: 256 << 10})
+func NewClaimCheck(provider *Provider, opts ClaimCheckOptions) *ClaimCheck {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 64 << 10
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = time.Hour
+	}
+	if opts.Readers <= 0 {
+		opts.Readers = 1
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = claimCheckKeyPrefix
+	}
+	return &ClaimCheck{provider: provider, opts: opts}
+}
+
+// Check returns the payload to send in place of payload: payload itself when it is below
+// the threshold, otherwise a reference to a stored copy
+// Example: msg, err := checks.Check(ctx, report); queue.Push(ctx, msg)
+func (c *ClaimCheck) Check(ctx context.Context, payload []byte) ([]byte, error) {
+	if len(payload) <= c.opts.Threshold {
+		return payload, nil
+	}
+	return c.store(ctx, payload)
+}
+
+// store saves payload under a new key and returns the reference to it
+func (c *ClaimCheck) store(ctx context.Context, payload []byte) ([]byte, error) {
+	id, err := newRandomID()
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to generate claim check key", err)
+	}
+	readers := c.opts.Readers
+	if c.opts.KeepUntilExpiry {
+		readers = 0
+	}
+	key := c.opts.Prefix + id
+	_, err = c.provider.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "body", payload, "readers", readers)
+		pipe.PExpire(ctx, key, c.opts.TTL)
+		return nil
+	})
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	return []byte(payloadClaimMarker + key), nil
+}
+
+// Claim returns the original payload of a message produced by Check, counting a read of
+// stored payloads. Inline payloads are returned unchanged; a reference whose payload was
+// consumed or expired fails with ErrorTypeNotFound.
+func (c *ClaimCheck) Claim(ctx context.Context, payload []byte) ([]byte, error) {
+	return c.provider.DecodePayload(ctx, payload)
+}
+
+// Release deletes the stored payload of a reference before its readers claimed it, such as
+// when the message carrying it was dropped. Inline payloads are ignored.
+func (c *ClaimCheck) Release(ctx context.Context, payload []byte) error {
+	key, ok := claimCheckKey(payload)
+	if !ok {
+		return nil
+	}
+	return convertRedisError(c.provider.client.Del(ctx, key).Err())
+}
+
+// claimCheckKey returns the key referenced by a claim check payload
+func claimCheckKey(payload []byte) (string, bool) {
+	if len(payload) <= len(payloadClaimMarker) || string(payload[:len(payloadClaimMarker)]) != payloadClaimMarker {
+		return "", false
+	}
+	return string(payload[len(payloadClaimMarker):]), true
+}
+
+// claimPayload loads a stored payload and counts the read
+func (p *Provider) claimPayload(ctx context.Context, key string) ([]byte, error) {
+	body, err := claimPayloadScript.Run(ctx, p.client, []string{key}).Text()
+	if err == redis.Nil {
+		return nil, gpa.NewError(gpa.ErrorTypeNotFound, "claim-checked payload "+key+" was consumed or expired")
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	return []byte(body), nil
+}
@@ -0,0 +1,85 @@
+package gparedis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimCheck(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	checks := NewClaimCheck(repo.provider, ClaimCheckOptions{Threshold: 100, Prefix: "test:claim:"})
+	body := []byte(strings.Repeat("x", 1000))
+
+	// Small payloads pass inline
+	msg, err := checks.Check(ctx, []byte("small"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("small"), msg)
+	claimed, err := checks.Claim(ctx, msg)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("small"), claimed)
+
+	// Large payloads travel as a reference through a queue and are deleted once claimed
+	msg, err = checks.Check(ctx, body)
+	require.NoError(t, err)
+	assert.Less(t, len(msg), 100)
+	require.NoError(t, repo.client.RPush(ctx, "test:claim:queue", msg).Err())
+	defer repo.client.Del(ctx, "test:claim:queue")
+	popped, err := repo.client.LPop(ctx, "test:claim:queue").Bytes()
+	require.NoError(t, err)
+	claimed, err = checks.Claim(ctx, popped)
+	require.NoError(t, err)
+	assert.Equal(t, body, claimed)
+	keys, err := repo.client.Keys(ctx, "test:claim:*").Result()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	_, err = checks.Claim(ctx, popped)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+
+	// Released payloads are gone
+	msg, err = checks.Check(ctx, body)
+	require.NoError(t, err)
+	require.NoError(t, checks.Release(ctx, msg))
+	_, err = checks.Claim(ctx, msg)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+}
+
+func TestClaimCheckReaders(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	body := []byte(strings.Repeat("y", 1000))
+
+	// One claim per consumer group
+	checks := NewClaimCheck(repo.provider, ClaimCheckOptions{Threshold: 100, Readers: 2})
+	msg, err := checks.Check(ctx, body)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		claimed, err := checks.Claim(ctx, msg)
+		require.NoError(t, err)
+		assert.Equal(t, body, claimed)
+	}
+	_, err = checks.Claim(ctx, msg)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+
+	// Fan-out payloads stay until they expire
+	checks = NewClaimCheck(repo.provider, ClaimCheckOptions{Threshold: 100, KeepUntilExpiry: true})
+	msg, err = checks.Check(ctx, body)
+	require.NoError(t, err)
+	defer checks.Release(ctx, msg)
+	for i := 0; i < 3; i++ {
+		_, err := repo.provider.DecodePayload(ctx, msg)
+		require.NoError(t, err)
+	}
+	key, ok := claimCheckKey(msg)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), repo.client.Exists(ctx, key).Val())
+}
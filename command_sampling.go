@@ -0,0 +1,216 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Command Audit Sampling
+// =====================================
+
+// callerKey stores the caller attributed to sampled commands in a context
+type callerKey struct{}
+
+// WithCaller returns a context attributing the commands issued with it to caller (a handler,
+// job or component name) in command samples
+// Example: ctx = gparedis.WithCaller(ctx, "checkout.ReserveStock")
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// callerFromContext returns the caller set with WithCaller, falling back to the principal
+func callerFromContext(ctx context.Context) string {
+	if caller, ok := ctx.Value(callerKey{}).(string); ok && caller != "" {
+		return caller
+	}
+	principal, _ := PrincipalFromContext(ctx)
+	return principal
+}
+
+// CommandSamplingOptions configures command sampling
+type CommandSamplingOptions struct {
+	// Rate is the fraction of commands (or pipelines) sampled, 0 to 1 (default 0.01)
+	Rate float64
+	// Capacity is the number of samples kept; older ones are overwritten (default 1000)
+	Capacity int
+	// MaxArgLen truncates long arguments such as values (default 256 bytes)
+	MaxArgLen int
+}
+
+// CommandSample is one sampled command
+type CommandSample struct {
+	// Command is the full command, name first, with long arguments truncated
+	Command  []string
+	Duration time.Duration
+	// Err is the command's error, empty on success (including nil replies)
+	Err string
+	// Caller is the WithCaller value, or the principal, of the issuing context
+	Caller    string
+	Pipelined bool
+	At        time.Time
+}
+
+// Diagnostics is a snapshot of the provider's debugging state
+type Diagnostics struct {
+	// CommandSamples holds the sampled commands, oldest first (empty unless sampling is enabled)
+	CommandSamples []CommandSample
+	// CommandsSeen and CommandsSampled count the commands observed and recorded by sampling
+	CommandsSeen    uint64
+	CommandsSampled uint64
+}
+
+// commandSampler keeps sampled commands in a ring buffer
+type commandSampler struct {
+	opts CommandSamplingOptions
+
+	mu      sync.Mutex
+	ring    []CommandSample
+	next    int
+	seen    uint64
+	sampled uint64
+}
+
+// EnableCommandSampling records a random fraction of commands with their full arguments,
+// duration and caller into a ring buffer read through Diagnostics, for tracking down where
+// unexpected keys or commands come from. Replaces any running sampler.
+// Example: provider.EnableCommandSampling(gparedis.CommandSamplingOptions{Rate: 0.01})
+func (p *Provider) EnableCommandSampling(opts CommandSamplingOptions) {
+	if opts.Rate <= 0 {
+		opts.Rate = 0.01
+	}
+	if opts.Capacity <= 0 {
+		opts.Capacity = 1000
+	}
+	if opts.MaxArgLen <= 0 {
+		opts.MaxArgLen = 256
+	}
+	p.sampler.Store(&commandSampler{opts: opts, ring: make([]CommandSample, 0, opts.Capacity)})
+}
+
+// DisableCommandSampling stops sampling and drops the recorded samples
+func (p *Provider) DisableCommandSampling() {
+	p.sampler.Store(nil)
+}
+
+// Diagnostics returns a snapshot of the provider's debugging state
+func (p *Provider) Diagnostics() Diagnostics {
+	var d Diagnostics
+	if s := p.sampler.Load(); s != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		d.CommandSamples = make([]CommandSample, 0, len(s.ring))
+		if len(s.ring) == s.opts.Capacity {
+			d.CommandSamples = append(d.CommandSamples, s.ring[s.next:]...)
+		}
+		d.CommandSamples = append(d.CommandSamples, s.ring[:s.next]...)
+		d.CommandsSeen, d.CommandsSampled = s.seen, s.sampled
+	}
+	return d
+}
+
+// record stores the samples of one command or pipeline
+func (s *commandSampler) record(ctx context.Context, cmds []redis.Cmder, elapsed time.Duration, pipelined bool) {
+	caller := callerFromContext(ctx)
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cmd := range cmds {
+		sample := CommandSample{
+			Command:   s.commandArgs(cmd),
+			Duration:  elapsed,
+			Caller:    caller,
+			Pipelined: pipelined,
+			At:        now,
+		}
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			sample.Err = err.Error()
+		}
+		if len(s.ring) < s.opts.Capacity {
+			s.ring = append(s.ring, sample)
+		} else {
+			s.ring[s.next] = sample
+		}
+		s.next = (s.next + 1) % s.opts.Capacity
+		s.sampled++
+	}
+}
+
+// commandArgs renders a command's arguments, truncating long ones
+func (s *commandSampler) commandArgs(cmd redis.Cmder) []string {
+	args := cmd.Args()
+	rendered := make([]string, len(args))
+	for i, arg := range args {
+		var str string
+		switch v := arg.(type) {
+		case string:
+			str = v
+		case []byte:
+			str = string(v)
+		default:
+			str = fmt.Sprint(v)
+		}
+		if len(str) > s.opts.MaxArgLen {
+			str = str[:s.opts.MaxArgLen] + fmt.Sprintf("...(%d bytes)", len(str))
+		}
+		rendered[i] = str
+	}
+	return rendered
+}
+
+// sampleStartKey stores the time a sampled command was sent
+type sampleStartKey struct{}
+
+// samplingHook picks commands for the provider's command sampler
+type samplingHook struct {
+	provider *Provider
+}
+
+func (h *samplingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return h.before(ctx, 1), nil
+}
+
+func (h *samplingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.after(ctx, []redis.Cmder{cmd}, false)
+	return nil
+}
+
+func (h *samplingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return h.before(ctx, len(cmds)), nil
+}
+
+func (h *samplingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	h.after(ctx, cmds, true)
+	return nil
+}
+
+// before counts the commands and marks sampled ones with their start time
+func (h *samplingHook) before(ctx context.Context, n int) context.Context {
+	s := h.provider.sampler.Load()
+	if s == nil {
+		return ctx
+	}
+	s.mu.Lock()
+	s.seen += uint64(n)
+	s.mu.Unlock()
+	if rand.Float64() >= s.opts.Rate {
+		return ctx
+	}
+	return context.WithValue(ctx, sampleStartKey{}, time.Now())
+}
+
+// after records sampled commands
+func (h *samplingHook) after(ctx context.Context, cmds []redis.Cmder, pipelined bool) {
+	start, ok := ctx.Value(sampleStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	if s := h.provider.sampler.Load(); s != nil {
+		s.record(ctx, cmds, time.Since(start), pipelined)
+	}
+}
@@ -0,0 +1,57 @@
+package gparedis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandSampling(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	assert.Empty(t, repo.provider.Diagnostics().CommandSamples)
+
+	repo.provider.EnableCommandSampling(CommandSamplingOptions{Rate: 1, Capacity: 3, MaxArgLen: 16})
+	defer repo.provider.DisableCommandSampling()
+
+	ctx := WithCaller(context.Background(), "importer")
+	users := NewRepository[TestValue](repo.provider, repo.client, "sampled:")
+	require.NoError(t, users.Set(ctx, "1", &TestValue{ID: "1", Name: "a long enough name"}))
+	defer repo.client.Del(context.Background(), "sampled:1")
+	_, err := users.Get(WithPrincipal(context.Background(), "user:42"), "1")
+	require.NoError(t, err)
+	_, err = users.Get(ctx, "missing")
+	assert.Error(t, err)
+	_, err = repo.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "sampled:1")
+		pipe.Exists(ctx, "sampled:1")
+		return nil
+	})
+	require.NoError(t, err)
+
+	d := repo.provider.Diagnostics()
+	require.Len(t, d.CommandSamples, 3, "the ring keeps the latest samples")
+	assert.Equal(t, d.CommandsSeen, d.CommandsSampled)
+	assert.Equal(t, []string{"get", "sampled:missing"}, d.CommandSamples[0].Command)
+	assert.Empty(t, d.CommandSamples[0].Err, "nil replies aren't errors")
+	assert.Equal(t, "importer", d.CommandSamples[0].Caller)
+	assert.True(t, d.CommandSamples[2].Pipelined)
+	assert.Equal(t, "exists", d.CommandSamples[2].Command[0])
+
+	repo.provider.EnableCommandSampling(CommandSamplingOptions{Rate: 1, MaxArgLen: 16})
+	require.NoError(t, users.Set(ctx, "1", &TestValue{ID: "1", Name: "a long enough name"}))
+	_, err = users.Get(WithPrincipal(context.Background(), "user:42"), "1")
+	require.NoError(t, err)
+	d = repo.provider.Diagnostics()
+	require.Len(t, d.CommandSamples, 2)
+	assert.True(t, strings.HasSuffix(d.CommandSamples[0].Command[2], "bytes)"), "long values are truncated")
+	assert.Equal(t, "user:42", d.CommandSamples[1].Caller, "the principal is the fallback caller")
+
+	repo.provider.DisableCommandSampling()
+	assert.Empty(t, repo.provider.Diagnostics().CommandSamples)
+}
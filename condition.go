@@ -0,0 +1,360 @@
+package gparedis
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// In-Memory Condition Evaluation
+// =====================================
+
+// evaluateCondition reports whether entity satisfies cond.
+// Field names are resolved against json tags first and Go field names second
+// (case-insensitive); dotted paths walk nested structs and maps.
+func evaluateCondition(entity interface{}, cond gpa.Condition) (bool, error) {
+	switch c := cond.(type) {
+	case gpa.CompositeCondition:
+		return evaluateComposite(entity, c)
+	case *gpa.CompositeCondition:
+		return evaluateComposite(entity, *c)
+	case gpa.SubQueryCondition, *gpa.SubQueryCondition:
+		return false, gpa.NewError(gpa.ErrorTypeUnsupported, "subquery conditions are not supported by Redis key-value store")
+	}
+
+	value, found := lookupField(reflect.ValueOf(entity), cond.Field())
+	if !found && cond.Operator() != gpa.OpIsNull && cond.Operator() != gpa.OpNotExists {
+		return false, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unknown field in condition: "+cond.Field())
+	}
+	return applyOperator(value, found, cond.Operator(), cond.Value())
+}
+
+// evaluateComposite evaluates AND/OR/NOT groups
+func evaluateComposite(entity interface{}, c gpa.CompositeCondition) (bool, error) {
+	switch c.Logic {
+	case gpa.LogicOr:
+		for _, sub := range c.Conditions {
+			ok, err := evaluateCondition(entity, sub)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case gpa.LogicNot:
+		for _, sub := range c.Conditions {
+			ok, err := evaluateCondition(entity, sub)
+			if err != nil || ok {
+				return false, err
+			}
+		}
+		return true, nil
+	default:
+		for _, sub := range c.Conditions {
+			ok, err := evaluateCondition(entity, sub)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// matchesAll reports whether entity satisfies every condition
+func matchesAll(entity interface{}, conds []gpa.Condition) (bool, error) {
+	for _, cond := range conds {
+		ok, err := evaluateCondition(entity, cond)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// lookupField resolves a (possibly dotted) field path on v
+func lookupField(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, part := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			field, ok := structField(v, part)
+			if !ok {
+				return reflect.Value{}, false
+			}
+			v = field
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, false
+			}
+			item := v.MapIndex(reflect.ValueOf(part).Convert(v.Type().Key()))
+			if !item.IsValid() {
+				return reflect.Value{}, false
+			}
+			v = item
+		default:
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+// structField finds a struct field by json tag or (case-insensitive) Go name
+func structField(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == name {
+			return v.Field(i), true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() && strings.EqualFold(f.Name, name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// applyOperator compares a field value against the condition operand
+func applyOperator(field reflect.Value, found bool, op gpa.Operator, operand interface{}) (bool, error) {
+	isNull := !found || isNilValue(field)
+
+	switch op {
+	case gpa.OpIsNull, gpa.OpNotExists:
+		return isNull, nil
+	case gpa.OpIsNotNull, gpa.OpExists:
+		return !isNull, nil
+	}
+	if isNull {
+		return false, nil
+	}
+
+	actual := indirect(field).Interface()
+	switch op {
+	case gpa.OpEqual:
+		return valuesEqual(actual, operand), nil
+	case gpa.OpNotEqual:
+		return !valuesEqual(actual, operand), nil
+	case gpa.OpGreaterThan, gpa.OpGreaterThanOrEqual, gpa.OpLessThan, gpa.OpLessThanOrEqual:
+		cmp, err := compareValues(actual, operand)
+		if err != nil {
+			return false, err
+		}
+		switch op {
+		case gpa.OpGreaterThan:
+			return cmp > 0, nil
+		case gpa.OpGreaterThanOrEqual:
+			return cmp >= 0, nil
+		case gpa.OpLessThan:
+			return cmp < 0, nil
+		default:
+			return cmp <= 0, nil
+		}
+	case gpa.OpIn, gpa.OpNotIn:
+		items, err := operandList(operand)
+		if err != nil {
+			return false, err
+		}
+		in := false
+		for _, item := range items {
+			if valuesEqual(actual, item) {
+				in = true
+				break
+			}
+		}
+		return in == (op == gpa.OpIn), nil
+	case gpa.OpBetween, gpa.OpNotBetween:
+		bounds, err := operandList(operand)
+		if err != nil || len(bounds) != 2 {
+			return false, gpa.NewError(gpa.ErrorTypeInvalidArgument, "BETWEEN requires exactly two bounds")
+		}
+		lo, err := compareValues(actual, bounds[0])
+		if err != nil {
+			return false, err
+		}
+		hi, err := compareValues(actual, bounds[1])
+		if err != nil {
+			return false, err
+		}
+		between := lo >= 0 && hi <= 0
+		return between == (op == gpa.OpBetween), nil
+	case gpa.OpLike, gpa.OpNotLike:
+		pattern, ok := operand.(string)
+		if !ok {
+			return false, gpa.NewError(gpa.ErrorTypeInvalidArgument, "LIKE requires a string pattern")
+		}
+		re, err := regexp.Compile(likeToRegexp(pattern))
+		if err != nil {
+			return false, gpa.NewErrorWithCause(gpa.ErrorTypeInvalidArgument, "invalid LIKE pattern", err)
+		}
+		return re.MatchString(fmt.Sprint(actual)) == (op == gpa.OpLike), nil
+	case gpa.OpContains:
+		return containsValue(indirect(field), operand), nil
+	case gpa.OpStartsWith:
+		return strings.HasPrefix(fmt.Sprint(actual), fmt.Sprint(operand)), nil
+	case gpa.OpEndsWith:
+		return strings.HasSuffix(fmt.Sprint(actual), fmt.Sprint(operand)), nil
+	case gpa.OpRegex:
+		re, err := regexp.Compile(fmt.Sprint(operand))
+		if err != nil {
+			return false, gpa.NewErrorWithCause(gpa.ErrorTypeInvalidArgument, "invalid regular expression", err)
+		}
+		return re.MatchString(fmt.Sprint(actual)), nil
+	default:
+		return false, gpa.NewError(gpa.ErrorTypeUnsupported, fmt.Sprintf("operator %s is not supported for in-memory filtering", op))
+	}
+}
+
+// isNilValue reports whether v holds a nil pointer, interface, map or slice
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// indirect dereferences pointers and interfaces
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// toFloat converts numeric values to float64
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// valuesEqual compares values, treating all numeric types as comparable
+func valuesEqual(a, b interface{}) bool {
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			return af == bf
+		}
+	}
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := b.(time.Time); ok {
+			return at.Equal(bt)
+		}
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		if as, ok := stringValue(a); ok {
+			if bs, ok := stringValue(b); ok {
+				return as == bs
+			}
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// stringValue returns the underlying string of string-kinded values
+func stringValue(v interface{}) (string, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.String {
+		return rv.String(), true
+	}
+	return "", false
+}
+
+// compareValues orders two numeric, string or time values
+func compareValues(a, b interface{}) (int, error) {
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			switch {
+			case af < bf:
+				return -1, nil
+			case af > bf:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := b.(time.Time); ok {
+			return at.Compare(bt), nil
+		}
+	}
+	if as, ok := stringValue(a); ok {
+		if bs, ok := stringValue(b); ok {
+			return strings.Compare(as, bs), nil
+		}
+	}
+	return 0, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("cannot compare %T with %T", a, b))
+}
+
+// operandList converts a slice operand to []interface{}
+func operandList(operand interface{}) ([]interface{}, error) {
+	rv := reflect.ValueOf(operand)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "operator requires a list operand")
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// containsValue checks substring containment for strings and membership for slices and maps
+func containsValue(v reflect.Value, operand interface{}) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.Contains(v.String(), fmt.Sprint(operand))
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if valuesEqual(v.Index(i).Interface(), operand) {
+				return true
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			if valuesEqual(key.Interface(), operand) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// likeToRegexp converts a SQL LIKE pattern (% and _) to an anchored regular expression
+func likeToRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type conditionTestEntity struct {
+	Name    string            `json:"name"`
+	Age     int               `json:"age"`
+	Email   *string           `json:"email"`
+	Tags    []string          `json:"tags"`
+	Created time.Time         `json:"created"`
+	Meta    map[string]string `json:"meta"`
+	Address struct {
+		City string `json:"city"`
+	} `json:"address"`
+}
+
+func TestEvaluateCondition(t *testing.T) {
+	now := time.Now()
+	entity := &conditionTestEntity{Name: "Alice", Age: 30, Tags: []string{"admin", "beta"}, Created: now,
+		Meta: map[string]string{"plan": "pro"}}
+	entity.Address.City = "Berlin"
+
+	cases := []struct {
+		cond gpa.Condition
+		want bool
+	}{
+		{gpa.WhereCondition("name", gpa.OpEqual, "Alice"), true},
+		{gpa.WhereCondition("Name", gpa.OpNotEqual, "Alice"), false},
+		{gpa.WhereCondition("age", gpa.OpGreaterThan, int64(29)), true},
+		{gpa.WhereCondition("age", gpa.OpLessThanOrEqual, 29.5), false},
+		{gpa.WhereCondition("age", gpa.OpBetween, []int{18, 30}), true},
+		{gpa.WhereCondition("age", gpa.OpNotBetween, []int{18, 30}), false},
+		{gpa.WhereCondition("age", gpa.OpIn, []interface{}{10, 30}), true},
+		{gpa.WhereCondition("name", gpa.OpNotIn, []string{"Bob"}), true},
+		{gpa.WhereCondition("name", gpa.OpLike, "Al%"), true},
+		{gpa.WhereCondition("name", gpa.OpLike, "A_ice"), true},
+		{gpa.WhereCondition("name", gpa.OpNotLike, "B%"), true},
+		{gpa.WhereCondition("tags", gpa.OpContains, "beta"), true},
+		{gpa.WhereCondition("name", gpa.OpStartsWith, "Ali"), true},
+		{gpa.WhereCondition("name", gpa.OpEndsWith, "ce"), true},
+		{gpa.WhereCondition("name", gpa.OpRegex, "^A.*e$"), true},
+		{gpa.WhereCondition("email", gpa.OpIsNull, nil), true},
+		{gpa.WhereCondition("email", gpa.OpIsNotNull, nil), false},
+		{gpa.WhereCondition("created", gpa.OpLessThan, now.Add(time.Second)), true},
+		{gpa.WhereCondition("address.city", gpa.OpEqual, "Berlin"), true},
+		{gpa.WhereCondition("meta.plan", gpa.OpEqual, "pro"), true},
+		{gpa.CompositeCondition{Logic: gpa.LogicOr, Conditions: []gpa.Condition{
+			gpa.WhereCondition("name", gpa.OpEqual, "Bob"),
+			gpa.WhereCondition("age", gpa.OpEqual, 30),
+		}}, true},
+		{gpa.CompositeCondition{Logic: gpa.LogicAnd, Conditions: []gpa.Condition{
+			gpa.WhereCondition("name", gpa.OpEqual, "Alice"),
+			gpa.WhereCondition("age", gpa.OpEqual, 31),
+		}}, false},
+		{gpa.CompositeCondition{Logic: gpa.LogicNot, Conditions: []gpa.Condition{
+			gpa.WhereCondition("name", gpa.OpEqual, "Bob"),
+		}}, true},
+	}
+
+	for _, tc := range cases {
+		got, err := evaluateCondition(entity, tc.cond)
+		require.NoError(t, err, tc.cond.String())
+		assert.Equal(t, tc.want, got, tc.cond.String())
+	}
+
+	_, err := evaluateCondition(entity, gpa.WhereCondition("missing", gpa.OpEqual, 1))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+
+	_, err = evaluateCondition(entity, gpa.WhereCondition("name", gpa.OpGreaterThan, 1))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestRepositoryQueryWithConditions(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		require.NoError(t, repo.Set(ctx, key, &TestValue{ID: key, Name: "User", Age: 20 + i}))
+	}
+	_, err := repo.Increment(ctx, "counter", 1)
+	require.NoError(t, err)
+
+	// Conditions require an explicit opt-in
+	_, err = repo.Query(ctx, gpa.Where("age", gpa.OpGreaterThan, 25))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeUnsupported))
+	err = repo.DeleteByCondition(ctx, gpa.WhereCondition("age", gpa.OpGreaterThan, 25))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeUnsupported))
+
+	scanRepo := NewRepository[TestValue](repo.provider, repo.client, "", AllowFullScan())
+
+	older, err := scanRepo.Query(ctx, gpa.Where("age", gpa.OpGreaterThan, 25), gpa.Limit(2))
+	require.NoError(t, err)
+	require.Len(t, older, 2)
+	assert.Equal(t, "user:6", older[0].ID)
+	assert.Equal(t, "user:7", older[1].ID)
+
+	count, err := scanRepo.Count(ctx, gpa.Where("age", gpa.OpGreaterThan, 25))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	require.NoError(t, scanRepo.DeleteByCondition(ctx, gpa.WhereCondition("age", gpa.OpGreaterThan, 25)))
+	remaining, err := scanRepo.Count(ctx, KeyPattern("user:*"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), remaining)
+}
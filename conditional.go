@@ -0,0 +1,201 @@
+package gparedis
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Conditional Batch Writes
+// =====================================
+
+// compareAndSwapScript atomically verifies the current value of every key and only then
+// writes all new values. KEYS are the value keys, followed by the change stream and its
+// versions hash under change capture. ARGV[1] is the TTL in milliseconds (0 for none) and
+// ARGV[2] the number of value keys, followed by one (expect_present, expected_value,
+// new_value, relative key) quadruple per key, then the maxlen and timestamp of change events.
+var compareAndSwapScript = redis.NewScript(captureEventLua + `
+local ttl = tonumber(ARGV[1])
+local n = tonumber(ARGV[2])
+for i = 1, n do
+	local base = (i - 1) * 4 + 2
+	local current = redis.call('GET', KEYS[i])
+	if ARGV[base + 1] == '1' then
+		if current ~= ARGV[base + 2] then
+			return 0
+		end
+	elseif current then
+		return 0
+	end
+end
+for i = 1, n do
+	local base = (i - 1) * 4 + 2
+	if ttl > 0 then
+		redis.call('SET', KEYS[i], ARGV[base + 3], 'PX', ttl)
+	else
+		redis.call('SET', KEYS[i], ARGV[base + 3])
+	end
+	if #KEYS > n then
+		capture(KEYS[n + 1], KEYS[n + 2], ARGV[base + 4], 'set', tonumber(ARGV[n * 4 + 3]), ARGV[n * 4 + 4])
+	end
+end
+return 1
+`)
+
+// msetNXScript writes every key only if none of the value keys exist, applying each key's
+// TTL in the same step. KEYS are the value keys, followed by the change stream and its
+// versions hash under change capture. ARGV[1] is the number of value keys, followed by one
+// (value, ttl ms, relative key) triple per key with 0 for no expiration, then the maxlen and
+// timestamp of change events. Returns 1 when the keys were written and 0 otherwise.
+var msetNXScript = redis.NewScript(captureEventLua + `
+local n = tonumber(ARGV[1])
+for i = 1, n do
+	if redis.call('EXISTS', KEYS[i]) == 1 then
+		return 0
+	end
+end
+for i = 1, n do
+	local base = (i - 1) * 3 + 2
+	local ttl = tonumber(ARGV[base + 1])
+	if ttl > 0 then
+		redis.call('SET', KEYS[i], ARGV[base], 'PX', ttl)
+	else
+		redis.call('SET', KEYS[i], ARGV[base])
+	end
+	if #KEYS > n then
+		capture(KEYS[n + 1], KEYS[n + 2], ARGV[base + 2], 'set', tonumber(ARGV[n * 3 + 2]), ARGV[n * 3 + 3])
+	end
+end
+return 1
+`)
+
+// MSetNX stores all pairs only if none of the keys exist (all-or-nothing).
+// Returns false without writing anything when at least one key is already present. The check
+// and writes, including retention TTLs, run atomically in a Lua script.
+// Example: written, err := repo.MSetNX(ctx, map[string]*User{"user:1": u1, "user:2": u2})
+func (r *Repository[T]) MSetNX(ctx context.Context, pairs map[string]*T) (bool, error) {
+	if len(pairs) == 0 {
+		return true, nil
+	}
+	keys := sortedKeys(pairs)
+	if err := r.authorizeKeys(ctx, AccessWrite, keys...); err != nil {
+		return false, err
+	}
+	if err := r.checkQuota(ctx); err != nil {
+		return false, err
+	}
+	fullKeys := r.buildKeys(keys)
+	args := make([]interface{}, 0, len(keys)*3+3)
+	args = append(args, len(keys))
+	for _, key := range keys {
+		data, err := r.encode(pairs[key])
+		if err != nil {
+			return false, err
+		}
+		args = append(args, data, r.retentionTTL(key, 0).Milliseconds(), key)
+	}
+	if r.opts.changeStream != "" {
+		fullKeys = append(fullKeys, r.changeKeys()...)
+		args = append(args, r.opts.changeStreamMaxLen, changeTimestamp())
+	}
+
+	if err := r.claimUnique(ctx, pairs); err != nil {
+		return false, err
+	}
+	written, err := msetNXScript.Run(ctx, r.client, fullKeys, args...).Int()
+	if err != nil {
+		return false, r.abandonClaims(ctx, keys, convertRedisError(err))
+	}
+	if written == 0 {
+		return false, r.releaseClaims(ctx, keys)
+	}
+	if err := r.afterSet(ctx, pairs, 0); err != nil {
+		return true, err
+	}
+	if err := r.recordWrite(ctx, "msetnx", keys...); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// MCompareAndSwap writes all values only if every key currently holds its expected value.
+// A key missing from expected (or mapped to nil) must not exist. Values are compared by their
+// serialized bytes, so expected values must be encoded the same way they were stored.
+// The check and writes run atomically in a Lua script. Returns false if any comparison failed.
+func (r *Repository[T]) MCompareAndSwap(ctx context.Context, expected map[string]*T, values map[string]*T, ttl time.Duration) (bool, error) {
+	if len(values) == 0 {
+		return true, nil
+	}
+
+	keys := sortedKeys(values)
+	if err := r.authorizeKeys(ctx, AccessWrite, keys...); err != nil {
+		return false, err
+	}
+	if err := r.checkQuota(ctx); err != nil {
+		return false, err
+	}
+	expiry := ttl
+	for _, key := range keys {
+		// The script applies one TTL to all keys, so use the strictest retention cap
+		if capped := r.retentionTTL(key, ttl); capped != ttl && (expiry == ttl || capped < expiry) {
+			expiry = capped
+		}
+	}
+	fullKeys := make([]string, len(keys))
+	args := make([]interface{}, 0, len(keys)*4+4)
+	args = append(args, expiry.Milliseconds(), len(keys))
+
+	for i, key := range keys {
+		fullKeys[i] = r.buildKey(key)
+
+		present, previous := "0", []byte{}
+		if exp := expected[key]; exp != nil {
+			data, err := r.encode(exp)
+			if err != nil {
+				return false, err
+			}
+			present, previous = "1", data
+		}
+
+		next, err := r.encode(values[key])
+		if err != nil {
+			return false, err
+		}
+		args = append(args, present, previous, next, key)
+	}
+	if r.opts.changeStream != "" {
+		fullKeys = append(fullKeys, r.changeKeys()...)
+		args = append(args, r.opts.changeStreamMaxLen, changeTimestamp())
+	}
+
+	if err := r.claimUnique(ctx, values); err != nil {
+		return false, err
+	}
+	swapped, err := compareAndSwapScript.Run(ctx, r.client, fullKeys, args...).Int()
+	if err != nil {
+		return false, r.abandonClaims(ctx, keys, convertRedisError(err))
+	}
+	if swapped == 0 {
+		return false, r.releaseClaims(ctx, keys)
+	}
+	if err := r.afterSet(ctx, values, ttl); err != nil {
+		return true, err
+	}
+	if err := r.recordWrite(ctx, "compare_and_swap", keys...); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// sortedKeys returns the keys of m in a deterministic order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
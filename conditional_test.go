@@ -0,0 +1,100 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryMSetNX(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	written, err := repo.MSetNX(ctx, map[string]*TestValue{
+		"user:1": {ID: "user:1", Name: "Alice"},
+		"user:2": {ID: "user:2", Name: "Bob"},
+	})
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	// One existing key blocks the whole batch
+	written, err = repo.MSetNX(ctx, map[string]*TestValue{
+		"user:2": {ID: "user:2", Name: "Bobby"},
+		"user:3": {ID: "user:3", Name: "Charlie"},
+	})
+	require.NoError(t, err)
+	assert.False(t, written)
+
+	exists, err := repo.KeyExists(ctx, "user:3")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	bob, err := repo.Get(ctx, "user:2")
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", bob.Name)
+}
+
+func TestMSetNXAppliesRetention(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, base.provider.SetRetentionPolicy("nx:", RetentionPolicy{MaxTTL: time.Hour}))
+	defer base.provider.RemoveRetentionPolicy("nx:")
+	repo := NewRepository[TestValue](base.provider, base.client, "nx:")
+	defer base.client.Del(ctx, "nx:a", "nx:b")
+
+	written, err := repo.MSetNX(ctx, map[string]*TestValue{"a": {ID: "a"}, "b": {ID: "b"}})
+	require.NoError(t, err)
+	require.True(t, written)
+	for _, key := range []string{"a", "b"} {
+		ttl, err := repo.TTL(ctx, key)
+		require.NoError(t, err)
+		assert.InDelta(t, float64(time.Hour), float64(ttl), float64(time.Minute), "keys are written with their TTL")
+	}
+}
+
+func TestRepositoryMCompareAndSwap(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	alice := &TestValue{ID: "user:1", Name: "Alice", Age: 30}
+	require.NoError(t, repo.Set(ctx, "user:1", alice))
+
+	// Stale expectation: nothing is written
+	swapped, err := repo.MCompareAndSwap(ctx,
+		map[string]*TestValue{"user:1": {ID: "user:1", Name: "Alice", Age: 29}},
+		map[string]*TestValue{
+			"user:1": {ID: "user:1", Name: "Alice", Age: 31},
+			"user:2": {ID: "user:2", Name: "Bob"},
+		}, 0)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+
+	exists, err := repo.KeyExists(ctx, "user:2")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Matching expectation plus an absent key
+	swapped, err = repo.MCompareAndSwap(ctx,
+		map[string]*TestValue{"user:1": alice},
+		map[string]*TestValue{
+			"user:1": {ID: "user:1", Name: "Alice", Age: 31},
+			"user:2": {ID: "user:2", Name: "Bob"},
+		}, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+
+	updated, err := repo.Get(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, 31, updated.Age)
+
+	ttl, err := repo.GetTTL(ctx, "user:2")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+}
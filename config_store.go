@@ -0,0 +1,137 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Live-Reloading Config Store
+// =====================================
+
+// ConfigStoreOptions configures a ConfigStore
+type ConfigStoreOptions[T any] struct {
+	// Default is served while the key doesn't exist (nil serves nil)
+	Default *T
+	// Subscribe tunes the key subscription used by Run
+	Subscribe SubscribeOptions
+	// OnError is called with values that fail to decode; the previous value is kept
+	OnError func(error)
+}
+
+// ConfigStore keeps a local copy of one configuration value and refreshes it whenever the
+// key changes. Reads are lock-free, so Get can sit on hot paths.
+type ConfigStore[T any] struct {
+	repo *Repository[T]
+	key  string
+	opts ConfigStoreOptions[T]
+
+	current atomic.Pointer[T]
+
+	mu        sync.Mutex
+	callbacks map[int]func(old, new *T)
+	nextID    int
+}
+
+// NewConfigStore creates a store for the value at key in repo. Call Load for the initial
+// value and Run to keep it current.
+// Example:
+//
+//	flags := gparedis.NewConfigStore(configRepo, "flags", gparedis.ConfigStoreOptions[Flags]{Default: &Flags{}})
+//	if _, err := flags.Load(ctx); err != nil { ... }
+//	go flags.Run(ctx)
+func NewConfigStore[T any](repo *Repository[T], key string, opts ConfigStoreOptions[T]) *ConfigStore[T] {
+	s := &ConfigStore[T]{repo: repo, key: key, opts: opts, callbacks: make(map[int]func(old, new *T))}
+	s.current.Store(opts.Default)
+	return s
+}
+
+// Load reads the value once, replacing the local copy. A missing key loads the default.
+func (s *ConfigStore[T]) Load(ctx context.Context) (*T, error) {
+	value, err := s.repo.Get(ctx, s.key)
+	if err != nil {
+		if !gpa.IsErrorType(err, gpa.ErrorTypeNotFound) {
+			return nil, err
+		}
+		value = s.opts.Default
+	}
+	s.apply(value)
+	return value, nil
+}
+
+// Get returns the local copy without contacting Redis
+func (s *ConfigStore[T]) Get() *T {
+	return s.current.Load()
+}
+
+// Set writes a new value; the local copy is updated right away, and on every other
+// instance when their Run loop sees the change
+func (s *ConfigStore[T]) Set(ctx context.Context, value *T) error {
+	if err := s.repo.Set(ctx, s.key, value); err != nil {
+		return err
+	}
+	s.apply(value)
+	return nil
+}
+
+// OnChange registers fn to be called with the previous and new value after each change.
+// Callbacks run synchronously in the goroutine that applied the change.
+func (s *ConfigStore[T]) OnChange(fn func(old, new *T)) (remove func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.callbacks[id] = fn
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.callbacks, id)
+	}
+}
+
+// Run subscribes to the key and applies every change until ctx is cancelled
+func (s *ConfigStore[T]) Run(ctx context.Context) error {
+	changes, err := s.repo.SubscribeWith(ctx, s.key, s.opts.Subscribe)
+	if err != nil {
+		return err
+	}
+	for change := range changes {
+		if change.Err != nil {
+			if s.opts.OnError != nil {
+				s.opts.OnError(change.Err)
+			}
+			continue
+		}
+		value := change.Value
+		if change.Deleted {
+			value = s.opts.Default
+		}
+		s.apply(value)
+	}
+	return ctx.Err()
+}
+
+// apply replaces the local copy and notifies callbacks when it changed
+func (s *ConfigStore[T]) apply(value *T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.current.Swap(value)
+	if old == value || (old != nil && value != nil && s.sameValue(old, value)) {
+		return
+	}
+	for _, fn := range s.callbacks {
+		fn(old, value)
+	}
+}
+
+// sameValue reports whether two values encode identically, so re-reading an unchanged
+// value (as Run does when it starts) doesn't notify callbacks
+func (s *ConfigStore[T]) sameValue(a, b *T) bool {
+	encodedA, errA := s.repo.encode(a)
+	encodedB, errB := s.repo.encode(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
@@ -0,0 +1,60 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigStore(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	configs := NewRepository[TestValue](repo.provider, repo.client, "cfg:")
+	defaults := &TestValue{ID: "default"}
+	store := NewConfigStore(configs, "flags", ConfigStoreOptions[TestValue]{
+		Default:   defaults,
+		Subscribe: SubscribeOptions{ResyncInterval: 20 * time.Millisecond},
+	})
+	assert.Equal(t, defaults, store.Get())
+
+	value, err := store.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "default", value.ID)
+
+	seen := make(chan string, 10)
+	remove := store.OnChange(func(old, new *TestValue) { seen <- old.ID + "->" + new.ID })
+	done := make(chan error)
+	go func() { done <- store.Run(ctx) }()
+
+	// Another instance writes: picked up by the subscription
+	other := NewConfigStore(configs, "flags", ConfigStoreOptions[TestValue]{})
+	require.NoError(t, other.Set(ctx, &TestValue{ID: "v1"}))
+	select {
+	case change := <-seen:
+		assert.Equal(t, "default->v1", change)
+	case <-time.After(time.Second):
+		t.Fatal("change not applied")
+	}
+	assert.Equal(t, "v1", store.Get().ID)
+
+	// Re-reading an unchanged value doesn't notify
+	_, err = store.Load(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, seen)
+
+	// Deleting the key falls back to the default
+	remove()
+	_, err = configs.MDelete(ctx, []string{"flags"})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return store.Get() == defaults }, time.Second, 10*time.Millisecond)
+	assert.Empty(t, seen)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,146 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Dedicated Connection Lease
+// =====================================
+
+// pushModeCommands make the server reply out of band (or not at all), which a pooled
+// connection can't recover from
+var pushModeCommands = map[string]bool{
+	"subscribe": true, "psubscribe": true, "ssubscribe": true, "monitor": true,
+}
+
+// Conn is a single server connection leased by WithConn. Commands sent through it run in
+// order on the same connection, as WATCH/MULTI/EXEC, SELECT and CLIENT SETNAME require.
+type Conn struct {
+	conn *redis.Conn
+	db   int
+
+	selected int
+	multi    bool
+	watching bool
+	named    bool
+	tracking bool
+}
+
+// WithConn leases one connection from the provider's pool for the duration of fn, then
+// undoes the session state fn left behind (an open MULTI, WATCHed keys, a SELECTed
+// database, a connection name, client tracking) and returns the connection to the pool.
+// Commands that switch the connection to out-of-band replies (SUBSCRIBE, MONITOR,
+// CLIENT REPLY OFF/SKIP) are rejected; use a subscription for Pub/Sub instead.
+// Example: err := provider.WithConn(ctx, func(conn *gparedis.Conn) error { _, err := conn.Do(ctx, "WATCH", "balance"); ... })
+func (p *Provider) WithConn(ctx context.Context, fn func(conn *Conn) error) error {
+	db := p.client.Options().DB
+	c := &Conn{conn: p.client.Conn(ctx), db: db, selected: db}
+	defer c.conn.Close()
+
+	err := fn(c)
+	// Restore the session even when ctx was cancelled, so the pool gets a clean connection
+	if resetErr := c.reset(context.WithoutCancel(ctx)); resetErr != nil && err == nil {
+		err = resetErr
+	}
+	return err
+}
+
+// Do sends a command and returns its reply: strings, int64s, nil for null replies (such as
+// the EXEC of a transaction aborted by WATCH) and []interface{} for arrays
+func (c *Conn) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "empty command")
+	}
+	name := strings.ToLower(fmt.Sprint(args[0]))
+	sub := ""
+	if len(args) > 1 {
+		sub = strings.ToLower(fmt.Sprint(args[1]))
+	}
+	if pushModeCommands[name] || (name == "client" && sub == "reply" && len(args) > 2 && !strings.EqualFold(fmt.Sprint(args[2]), "on")) {
+		return nil, gpa.NewError(gpa.ErrorTypeUnsupported, strings.ToUpper(name)+" can't be sent on a leased connection")
+	}
+
+	reply, err := c.send(ctx, args...).Result()
+	if err == redis.Nil {
+		reply, err = nil, nil
+	}
+	if err != nil {
+		if name == "exec" || name == "discard" {
+			c.multi, c.watching = false, false
+		}
+		return nil, convertRedisError(err)
+	}
+	c.track(name, sub, args)
+	return reply, nil
+}
+
+// send runs a raw command on the connection
+func (c *Conn) send(ctx context.Context, args ...interface{}) *redis.Cmd {
+	cmd := redis.NewCmd(ctx, args...)
+	_ = c.conn.Process(ctx, cmd)
+	return cmd
+}
+
+// track records the session state changed by a successful command
+func (c *Conn) track(name, sub string, args []interface{}) {
+	switch {
+	case c.multi && name != "exec" && name != "discard":
+		// Queued; takes effect at EXEC
+	case name == "multi":
+		c.multi = true
+	case name == "exec" || name == "discard":
+		c.multi, c.watching = false, false
+	case name == "watch":
+		c.watching = true
+	case name == "unwatch":
+		c.watching = false
+	case name == "select" && len(args) > 1:
+		if db, err := strconv.Atoi(fmt.Sprint(args[1])); err == nil {
+			c.selected = db
+		}
+	case name == "client" && sub == "setname":
+		c.named = true
+	case name == "client" && sub == "tracking" && len(args) > 2:
+		c.tracking = strings.EqualFold(fmt.Sprint(args[2]), "on")
+	}
+}
+
+// ID returns the server's ID of the connection (CLIENT ID)
+func (c *Conn) ID(ctx context.Context) (int64, error) {
+	id, err := c.conn.ClientID(ctx).Result()
+	return id, convertRedisError(err)
+}
+
+// reset undoes the session state left by the lease
+func (c *Conn) reset(ctx context.Context) error {
+	var cmds [][]interface{}
+	if c.multi {
+		cmds = append(cmds, []interface{}{"discard"})
+	}
+	if c.watching {
+		cmds = append(cmds, []interface{}{"unwatch"})
+	}
+	if c.selected != c.db {
+		cmds = append(cmds, []interface{}{"select", c.db})
+	}
+	if c.named {
+		cmds = append(cmds, []interface{}{"client", "setname", ""})
+	}
+	if c.tracking {
+		cmds = append(cmds, []interface{}{"client", "tracking", "off"})
+	}
+	for _, args := range cmds {
+		if err := c.send(ctx, args...).Err(); err != nil {
+			return gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to reset leased connection", err)
+		}
+	}
+	c.multi, c.watching, c.named, c.tracking, c.selected = false, false, false, false, c.db
+	return nil
+}
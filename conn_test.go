@@ -0,0 +1,94 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithConnTransaction(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, repo.client.Set(ctx, "conn:balance", "10", 0).Err())
+
+	err := repo.provider.WithConn(ctx, func(conn *Conn) error {
+		_, err := conn.Do(ctx, "WATCH", "conn:balance")
+		require.NoError(t, err)
+		// A concurrent write aborts the transaction
+		require.NoError(t, repo.client.Set(ctx, "conn:balance", "20", 0).Err())
+		_, err = conn.Do(ctx, "MULTI")
+		require.NoError(t, err)
+		queued, err := conn.Do(ctx, "SET", "conn:balance", "5")
+		require.NoError(t, err)
+		assert.Equal(t, "QUEUED", queued)
+		reply, err := conn.Do(ctx, "EXEC")
+		require.NoError(t, err)
+		assert.Nil(t, reply)
+
+		_, err = conn.Do(ctx, "MULTI")
+		require.NoError(t, err)
+		_, err = conn.Do(ctx, "INCRBY", "conn:balance", "5")
+		require.NoError(t, err)
+		reply, err = conn.Do(ctx, "EXEC")
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{int64(25)}, reply)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestWithConnRestoresSession(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	failure := errors.New("handler failed")
+	err := repo.provider.WithConn(ctx, func(conn *Conn) error {
+		_, err := conn.Do(ctx, "SELECT", 1)
+		require.NoError(t, err)
+		_, err = conn.Do(ctx, "SET", "conn:elsewhere", "x")
+		require.NoError(t, err)
+		_, err = conn.Do(ctx, "MULTI")
+		require.NoError(t, err)
+		_, err = conn.Do(ctx, "SET", "conn:never", "x")
+		require.NoError(t, err)
+		return failure
+	})
+	assert.Equal(t, failure, err)
+
+	// The connection went back to the pool with database 0 selected and no open transaction
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, redis.Nil, repo.client.Get(ctx, "conn:elsewhere").Err())
+		assert.Equal(t, redis.Nil, repo.client.Get(ctx, "conn:never").Err())
+	}
+	require.NoError(t, repo.provider.WithConn(ctx, func(conn *Conn) error {
+		_, err := conn.Do(ctx, "SELECT", 1)
+		require.NoError(t, err)
+		_, err = conn.Do(ctx, "DEL", "conn:elsewhere")
+		return err
+	}))
+}
+
+func TestWithConnRejectsPushMode(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, repo.provider.WithConn(ctx, func(conn *Conn) error {
+		_, err := conn.Do(ctx, "SUBSCRIBE", "news")
+		assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeUnsupported))
+		_, err = conn.Do(ctx, "CLIENT", "REPLY", "OFF")
+		assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeUnsupported))
+		reply, err := conn.Do(ctx, "GET", "conn:missing")
+		assert.NoError(t, err)
+		assert.Nil(t, reply)
+		return nil
+	}))
+}
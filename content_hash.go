@@ -0,0 +1,121 @@
+package gparedis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Content-Hash Conditional Writes
+// =====================================
+
+// setIfUnchangedScript writes ARGV[2] only while the SHA-1 of the stored bytes equals
+// ARGV[1], where an empty ARGV[1] means the key must not exist. ARGV[3] is the TTL in
+// milliseconds (0 for none). With change capture, KEYS[2] and KEYS[3] are the change stream
+// and its versions hash, and ARGV[4..6] the relative key, maxlen and timestamp of the event.
+// Returns the current hash (empty when missing) on mismatch and nothing on success.
+var setIfUnchangedScript = dryRunReply(redis.NewScript(captureEventLua + `
+local current = redis.call('GET', KEYS[1])
+local hash = ''
+if current then
+	hash = redis.sha1hex(current)
+end
+if hash ~= ARGV[1] then
+	return {hash}
+end
+local ttl = tonumber(ARGV[3])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+if #KEYS == 3 then
+	capture(KEYS[2], KEYS[3], ARGV[4], 'set', tonumber(ARGV[5]), ARGV[6])
+end
+return {}
+`), []interface{}{})
+
+// ContentHash returns the digest GetWithHash and SetIfUnchanged use for stored bytes: the
+// hex SHA-1, which Lua scripts can compute server-side
+func ContentHash(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// GetWithHash returns a value with the hash of its stored bytes, to hand out as an ETag
+// and pass back to SetIfUnchanged. Returns ErrorTypeNotFound for missing keys.
+// Example: order, etag, err := repo.GetWithHash(ctx, "order:1")
+func (r *Repository[T]) GetWithHash(ctx context.Context, key string) (*T, string, error) {
+	fullKey := r.buildKey(key)
+	if err := r.authorize(ctx, AccessRead, fullKey); err != nil {
+		return nil, "", err
+	}
+	// Read the primary: the hash must match what SetIfUnchanged will compare against
+	data, err := r.client.Get(ctx, fullKey).Bytes()
+	if err == redis.Nil {
+		return nil, "", gpa.NewError(gpa.ErrorTypeNotFound, "key not found: "+key)
+	}
+	if err != nil {
+		return nil, "", convertRedisError(err)
+	}
+	value, err := r.decode(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return value, ContentHash(data), nil
+}
+
+// SetIfUnchanged stores value only if the key's stored bytes still hash to expectedHash,
+// or, with an empty expectedHash, only if the key doesn't exist. The check and write are
+// atomic. Returns the new hash, or ErrorTypeConflict when the key changed since the hash
+// was taken (HTTP 412 for If-Match). Like Set, the write clears any TTL unless a retention
+// policy applies. Pair with WithCanonicalJSON when hashes are compared across services.
+// Example: etag, err = repo.SetIfUnchanged(ctx, "order:1", order, r.Header.Get("If-Match"))
+func (r *Repository[T]) SetIfUnchanged(ctx context.Context, key string, value *T, expectedHash string) (string, error) {
+	if err := r.authorizeKeys(ctx, AccessWrite, key); err != nil {
+		return "", err
+	}
+	if err := r.checkQuota(ctx); err != nil {
+		return "", err
+	}
+	data, err := r.encode(value)
+	if err != nil {
+		return "", err
+	}
+	ttl := r.retentionTTL(key, 0)
+	if err := r.claimUnique(ctx, map[string]*T{key: value}); err != nil {
+		return "", err
+	}
+
+	keys := []string{r.buildKey(key)}
+	args := []interface{}{expectedHash, data, ttl.Milliseconds()}
+	if r.opts.changeStream != "" {
+		keys = append(keys, r.changeKeys()...)
+		args = append(args, key, r.opts.changeStreamMaxLen, changeTimestamp())
+	}
+	res, err := setIfUnchangedScript.Run(ctx, r.client, keys, args...).StringSlice()
+	if err != nil {
+		return "", r.abandonClaims(ctx, []string{key}, convertRedisError(err))
+	}
+	if len(res) > 0 {
+		if err := r.releaseClaims(ctx, []string{key}); err != nil {
+			return "", err
+		}
+		if res[0] == "" {
+			return "", gpa.NewError(ErrorTypeConflict, "key "+key+" doesn't exist")
+		}
+		return "", gpa.NewError(ErrorTypeConflict, "key "+key+" was modified (hash "+res[0]+")")
+	}
+
+	if err := r.afterSet(ctx, map[string]*T{key: value}, 0); err != nil {
+		return "", err
+	}
+	if err := r.recordWrite(ctx, "set", key); err != nil {
+		return "", err
+	}
+	return ContentHash(data), nil
+}
@@ -0,0 +1,72 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentHash(t *testing.T) {
+	assert.Equal(t, "da39a3ee5e6b4b0d3255bfef95601890afd80709", ContentHash(nil))
+}
+
+func TestSetIfUnchanged(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, _, err := repo.GetWithHash(ctx, "etag")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+
+	// An empty hash creates the key only if it's missing
+	hash, err := repo.SetIfUnchanged(ctx, "etag", &TestValue{ID: "1", Name: "Ann"}, "")
+	require.NoError(t, err)
+	_, err = repo.SetIfUnchanged(ctx, "etag", &TestValue{ID: "1", Name: "Bob"}, "")
+	assert.True(t, IsConflictError(err))
+
+	value, got, err := repo.GetWithHash(ctx, "etag")
+	require.NoError(t, err)
+	assert.Equal(t, hash, got, "the returned hash matches the server-side digest")
+	assert.Equal(t, "Ann", value.Name)
+
+	next, err := repo.SetIfUnchanged(ctx, "etag", &TestValue{ID: "1", Name: "Bob"}, hash)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, next)
+
+	// The old hash is stale now
+	_, err = repo.SetIfUnchanged(ctx, "etag", &TestValue{ID: "1", Name: "Cat"}, hash)
+	assert.True(t, IsConflictError(err))
+	value, err = repo.Get(ctx, "etag")
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", value.Name)
+
+	_, err = repo.SetIfUnchanged(ctx, "missing", &TestValue{ID: "2"}, hash)
+	assert.True(t, IsConflictError(err), "a hash never matches a missing key")
+}
+
+func TestSetIfUnchangedCapturesChanges(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+	repo := NewRepository[TestValue](base.provider, base.client, "user:", CaptureChanges("cdc:etag", 0))
+	defer base.client.Del(ctx, "cdc:etag", "cdc:etag:versions")
+
+	hash, err := repo.SetIfUnchanged(ctx, "etag", &TestValue{ID: "1"}, "")
+	require.NoError(t, err)
+	_, err = repo.SetIfUnchanged(ctx, "etag", &TestValue{ID: "1"}, "")
+	assert.True(t, IsConflictError(err))
+	_, err = repo.SetIfUnchanged(ctx, "etag", &TestValue{ID: "1", Name: "Bob"}, hash)
+	require.NoError(t, err)
+
+	events, err := repo.Changes().Range(ctx, "-", "+", 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2, "rejected writes aren't captured")
+	for i, e := range events {
+		assert.Equal(t, "etag", e.Value.Key)
+		assert.Equal(t, ChangeOpSet, e.Value.Op)
+		assert.Equal(t, int64(i+1), e.Value.Version)
+	}
+}
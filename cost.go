@@ -0,0 +1,160 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Cost Attribution
+// =====================================
+
+// costLabelKey stores the cost label in a context
+type costLabelKey struct{}
+
+// WithCostLabel returns a context attributing the commands issued with it to label (a team,
+// cost center or tenant), so shared Redis infrastructure can be charged back per label
+// Example: ctx = gparedis.WithCostLabel(ctx, "team:payments")
+func WithCostLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, costLabelKey{}, label)
+}
+
+// CostLabelFromContext returns the label set with WithCostLabel
+func CostLabelFromContext(ctx context.Context) (string, bool) {
+	label, ok := ctx.Value(costLabelKey{}).(string)
+	return label, ok && label != ""
+}
+
+// CostUsage is the Redis traffic attributed to one cost label
+type CostUsage struct {
+	Label    string
+	Commands uint64
+	// BytesOut counts the command arguments sent
+	BytesOut uint64
+	// BytesIn counts the reply payloads received (strings and numbers, not protocol framing)
+	BytesIn uint64
+}
+
+// observeCost attributes commands to the cost label of ctx, if any
+func (m *Metrics) observeCost(ctx context.Context, cmds []redis.Cmder) {
+	label, ok := CostLabelFromContext(ctx)
+	if !ok {
+		return
+	}
+	var out, in uint64
+	for _, cmd := range cmds {
+		for _, arg := range cmd.Args() {
+			out += valueSize(arg)
+		}
+		in += replySize(cmd)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	usage, ok := m.costs[label]
+	if !ok {
+		usage = &CostUsage{Label: label}
+		m.costs[label] = usage
+	}
+	usage.Commands += uint64(len(cmds))
+	usage.BytesOut += out
+	usage.BytesIn += in
+}
+
+// CostUsage returns the traffic of every cost label, sorted by label. Commands issued
+// without a label are not included.
+func (m *Metrics) CostUsage() []CostUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	usages := make([]CostUsage, 0, len(m.costs))
+	for _, usage := range m.costs {
+		usages = append(usages, *usage)
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Label < usages[j].Label })
+	return usages
+}
+
+// writeCostPrometheus appends the per-label traffic to a Prometheus exposition
+func (m *Metrics) writeCostPrometheus(b *strings.Builder) {
+	usages := m.CostUsage()
+	b.WriteString("# HELP gparedis_cost_commands_total Commands sent by cost label.\n")
+	b.WriteString("# TYPE gparedis_cost_commands_total counter\n")
+	for _, usage := range usages {
+		fmt.Fprintf(b, "gparedis_cost_commands_total{label=\"%s\"} %d\n", promLabel(usage.Label), usage.Commands)
+	}
+	b.WriteString("# HELP gparedis_cost_bytes_total Payload bytes by cost label and direction.\n")
+	b.WriteString("# TYPE gparedis_cost_bytes_total counter\n")
+	for _, usage := range usages {
+		fmt.Fprintf(b, "gparedis_cost_bytes_total{label=\"%s\",direction=\"out\"} %d\n", promLabel(usage.Label), usage.BytesOut)
+		fmt.Fprintf(b, "gparedis_cost_bytes_total{label=\"%s\",direction=\"in\"} %d\n", promLabel(usage.Label), usage.BytesIn)
+	}
+}
+
+// replySize estimates the payload size of a command's reply
+func replySize(cmd redis.Cmder) uint64 {
+	if cmd.Err() != nil {
+		return 0
+	}
+	switch c := cmd.(type) {
+	case *redis.StringCmd:
+		return uint64(len(c.Val()))
+	case *redis.Cmd:
+		return valueSize(c.Val())
+	case *redis.SliceCmd:
+		return valueSize(c.Val())
+	case *redis.StringSliceCmd:
+		var n uint64
+		for _, s := range c.Val() {
+			n += uint64(len(s))
+		}
+		return n
+	case *redis.StringStringMapCmd:
+		var n uint64
+		for k, v := range c.Val() {
+			n += uint64(len(k) + len(v))
+		}
+		return n
+	case *redis.ZSliceCmd:
+		var n uint64
+		for _, z := range c.Val() {
+			n += valueSize(z.Member) + 8
+		}
+		return n
+	case *redis.XMessageSliceCmd:
+		var n uint64
+		for _, msg := range c.Val() {
+			n += uint64(len(msg.ID))
+			for k, v := range msg.Values {
+				n += uint64(len(k)) + valueSize(v)
+			}
+		}
+		return n
+	case *redis.IntCmd, *redis.BoolCmd, *redis.FloatCmd, *redis.DurationCmd:
+		return 8
+	}
+	return 0
+}
+
+// valueSize estimates the wire size of a command argument or reply value
+func valueSize(v interface{}) uint64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return uint64(len(v))
+	case []byte:
+		return uint64(len(v))
+	case []interface{}:
+		var n uint64
+		for _, item := range v {
+			n += valueSize(item)
+		}
+		return n
+	case int64, int, uint64, float64:
+		return 8
+	}
+	return uint64(len(fmt.Sprint(v)))
+}
@@ -0,0 +1,54 @@
+package gparedis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostAttribution(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	metrics := repo.provider.EnableMetrics(MetricsOptions{})
+	defer repo.provider.DisableMetrics()
+
+	ctx := context.Background()
+	payments := WithCostLabel(ctx, "team:payments")
+	search := WithCostLabel(ctx, "team:search")
+
+	label, ok := CostLabelFromContext(payments)
+	assert.True(t, ok)
+	assert.Equal(t, "team:payments", label)
+	_, ok = CostLabelFromContext(ctx)
+	assert.False(t, ok)
+
+	value := &TestValue{ID: "1", Name: strings.Repeat("x", 500)}
+	require.NoError(t, repo.Set(payments, "order:1", value))
+	_, err := repo.Get(payments, "order:1")
+	require.NoError(t, err)
+	_, err = repo.MGet(search, []string{"order:1", "order:2"})
+	require.NoError(t, err)
+	require.NoError(t, repo.Set(ctx, "order:3", value))
+
+	usages := metrics.CostUsage()
+	require.Len(t, usages, 2)
+	assert.Equal(t, "team:payments", usages[0].Label)
+	assert.Equal(t, uint64(2), usages[0].Commands)
+	assert.Greater(t, usages[0].BytesOut, uint64(500))
+	assert.Greater(t, usages[0].BytesIn, uint64(500))
+	assert.Equal(t, "team:search", usages[1].Label)
+	assert.Equal(t, uint64(1), usages[1].Commands)
+	assert.Greater(t, usages[1].BytesIn, uint64(500))
+
+	var b strings.Builder
+	require.NoError(t, metrics.WritePrometheus(&b))
+	assert.Contains(t, b.String(), `gparedis_cost_commands_total{label="team:payments"} 2`)
+	assert.Contains(t, b.String(), `gparedis_cost_bytes_total{label="team:search",direction="in"}`)
+
+	metrics.Reset()
+	assert.Empty(t, metrics.CostUsage())
+}
@@ -0,0 +1,173 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Debouncer
+// =====================================
+
+// DebounceHandler is called once per closed window with the coalesced ID
+type DebounceHandler func(ctx context.Context, id string) error
+
+// DebouncerOptions configures a Debouncer
+type DebouncerOptions struct {
+	// Window is how long triggers for the same ID are coalesced (required)
+	Window time.Duration
+	// Extend moves the deadline on every trigger (classic debounce: fire after Window of quiet).
+	// By default the first trigger fixes the deadline, so IDs fire at most once per Window.
+	Extend bool
+	// Interval is how often Run looks for closed windows (default 1s)
+	Interval time.Duration
+	// BatchSize caps the IDs claimed per poll (default 100)
+	BatchSize int64
+	// OnError is called when the handler fails; the ID is triggered again for a retry
+	OnError func(id string, err error)
+	// Adaptive, when set, replaces BatchSize and Interval with values tuned from each
+	// claim's round trip and how full the batches are
+	Adaptive *AdaptiveController
+}
+
+// Debouncer coalesces repeated triggers for the same ID within a window and emits a single
+// event when the window closes. Deadlines live in a sorted set (score = unix milliseconds)
+// shared by all processes; each closed window is claimed by exactly one of them.
+type Debouncer struct {
+	provider *Provider
+	client   *redis.Client
+	key      string
+	opts     DebouncerOptions
+}
+
+// NewDebouncer creates a debouncer tracking deadlines in the sorted set at key
+// Example: reindex := gparedis.NewDebouncer(provider, "debounce:search", gparedis.DebouncerOptions{Window: time.Minute})
+func NewDebouncer(provider *Provider, key string, opts DebouncerOptions) *Debouncer {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = scanBatchSize
+	}
+	return &Debouncer{provider: provider, client: provider.client, key: key, opts: opts}
+}
+
+// Trigger records an event for id. The first trigger opens a window; later triggers inside
+// it are coalesced (and push the deadline back when Extend is set).
+func (d *Debouncer) Trigger(ctx context.Context, id string) error {
+	if d.opts.Window <= 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "debounce window must be positive")
+	}
+	z := &redis.Z{Score: float64(time.Now().Add(d.opts.Window).UnixMilli()), Member: id}
+	if d.opts.Extend {
+		return convertRedisError(d.client.ZAdd(ctx, d.key, z).Err())
+	}
+	return convertRedisError(d.client.ZAddNX(ctx, d.key, z).Err())
+}
+
+// Cancel drops a pending window without emitting it
+func (d *Debouncer) Cancel(ctx context.Context, id string) error {
+	return convertRedisError(d.client.ZRem(ctx, d.key, id).Err())
+}
+
+// Pending returns the number of open windows
+func (d *Debouncer) Pending(ctx context.Context) (int64, error) {
+	n, err := d.client.ZCard(ctx, d.key).Result()
+	return n, convertRedisError(err)
+}
+
+// claimDueScript removes and returns up to ARGV[2] members scored at or below ARGV[1]
+var claimDueScript = dryRunReply(redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+if #due > 0 then
+	redis.call('ZREM', KEYS[1], unpack(due))
+end
+return due
+`), []interface{}{})
+
+// Due claims the IDs whose window has closed. Claimed IDs are removed, so the caller owns them;
+// a trigger arriving afterwards opens a new window.
+func (d *Debouncer) Due(ctx context.Context) ([]string, error) {
+	return d.due(ctx, d.batchSize())
+}
+
+// due claims up to limit closed windows
+func (d *Debouncer) due(ctx context.Context, limit int64) ([]string, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	start := time.Now()
+	ids, err := claimDueScript.Run(ctx, d.client, []string{d.key}, now, limit).StringSlice()
+	if err == redis.Nil {
+		err = nil
+	}
+	if d.opts.Adaptive != nil {
+		d.opts.Adaptive.Observe(int64(len(ids)), time.Since(start), err)
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	return ids, nil
+}
+
+// batchSize returns the number of IDs to claim per poll
+func (d *Debouncer) batchSize() int64 {
+	if d.opts.Adaptive != nil {
+		return d.opts.Adaptive.BatchSize()
+	}
+	return d.opts.BatchSize
+}
+
+// Flush claims every closed window and calls handler once per ID, returning how many were handled
+func (d *Debouncer) Flush(ctx context.Context, handler DebounceHandler) (int, error) {
+	handled := 0
+	for {
+		limit := d.batchSize()
+		ids, err := d.due(ctx, limit)
+		if err != nil {
+			return handled, err
+		}
+		for _, id := range ids {
+			if err := handler(ctx, id); err != nil {
+				// Re-open the window so the event is retried once it closes again
+				d.client.ZAddNX(ctx, d.key, &redis.Z{Score: float64(time.Now().Add(d.opts.Window).UnixMilli()), Member: id})
+				if d.opts.OnError != nil {
+					d.opts.OnError(id, err)
+				}
+				continue
+			}
+			handled++
+			d.provider.recordProcessed(queueKindDebouncer, d.key, "", 1)
+		}
+		if int64(len(ids)) < limit {
+			return handled, nil
+		}
+	}
+}
+
+// Run calls Flush every Interval (or the adaptive interval) until ctx is cancelled
+func (d *Debouncer) Run(ctx context.Context, handler DebounceHandler) error {
+	timer := time.NewTimer(d.interval())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			if _, err := d.Flush(ctx, handler); err != nil && d.opts.OnError != nil {
+				d.opts.OnError("", err)
+			}
+			timer.Reset(d.interval())
+		}
+	}
+}
+
+// interval returns the time to wait before the next poll
+func (d *Debouncer) interval() time.Duration {
+	if d.opts.Adaptive != nil {
+		return d.opts.Adaptive.Interval()
+	}
+	return d.opts.Interval
+}
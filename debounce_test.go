@@ -0,0 +1,81 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebouncerCoalescesTriggers(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	d := NewDebouncer(repo.provider, "debounce:test", DebouncerOptions{Window: 50 * time.Millisecond})
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, d.Trigger(ctx, "entity:1"))
+	}
+	require.NoError(t, d.Trigger(ctx, "entity:2"))
+	require.NoError(t, d.Trigger(ctx, "entity:3"))
+	require.NoError(t, d.Cancel(ctx, "entity:3"))
+
+	pending, err := d.Pending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pending)
+
+	ids, err := d.Due(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ids, "windows are still open")
+
+	time.Sleep(70 * time.Millisecond)
+	var fired []string
+	handled, err := d.Flush(ctx, func(ctx context.Context, id string) error {
+		fired = append(fired, id)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, handled)
+	assert.ElementsMatch(t, []string{"entity:1", "entity:2"}, fired)
+
+	pending, err = d.Pending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pending)
+}
+
+func TestDebouncerExtendAndRetry(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var failed []string
+	d := NewDebouncer(repo.provider, "debounce:test", DebouncerOptions{
+		Window:  40 * time.Millisecond,
+		Extend:  true,
+		OnError: func(id string, err error) { failed = append(failed, id) },
+	})
+
+	require.NoError(t, d.Trigger(ctx, "a"))
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, d.Trigger(ctx, "a"))
+	time.Sleep(20 * time.Millisecond)
+
+	ids, err := d.Due(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ids, "extended window is still open")
+
+	time.Sleep(40 * time.Millisecond)
+	_, err = d.Flush(ctx, func(ctx context.Context, id string) error { return errors.New("boom") })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, failed)
+
+	pending, err := d.Pending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending, "failed IDs are re-triggered")
+
+	assert.Error(t, NewDebouncer(repo.provider, "x", DebouncerOptions{}).Trigger(ctx, "a"))
+}
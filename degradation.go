@@ -0,0 +1,333 @@
+package gparedis
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Graceful Degradation Policies
+// =====================================
+
+// OperationClass groups repository operations that share a degradation policy
+type OperationClass string
+
+const (
+	// OperationRead covers Get and MGet
+	OperationRead OperationClass = "read"
+	// OperationWrite covers Set, SetWithTTL and MSet
+	OperationWrite OperationClass = "write"
+	// OperationDelete covers DeleteKey and MDelete
+	OperationDelete OperationClass = "delete"
+)
+
+// DegradeMode is what a repository does when Redis can't serve an operation
+type DegradeMode string
+
+const (
+	// DegradeFail returns the error to the caller (the default for every class)
+	DegradeFail DegradeMode = "fail"
+	// DegradeServeStale answers reads from the near cache (reads only)
+	DegradeServeStale DegradeMode = "serve_stale"
+	// DegradeSkipWrite drops the write, reports it to OnDegraded and returns success
+	// (writes and deletes only)
+	DegradeSkipWrite DegradeMode = "skip_write"
+	// DegradeEnqueue keeps the write in process and returns success; ReplayDegraded applies
+	// it once Redis is back (writes and deletes only)
+	DegradeEnqueue DegradeMode = "enqueue"
+)
+
+// DegradedOperation describes an operation handled by the degradation policy
+type DegradedOperation struct {
+	Class OperationClass
+	// Mode is the mode applied; DegradeFail when an enqueued write was given up
+	Mode DegradeMode
+	// Keys are relative to the repository prefix
+	Keys []string
+	// Err is the error Redis returned
+	Err error
+}
+
+// DegradationPolicy declares how a repository behaves when Redis is unavailable, per
+// operation class. Only connection failures, timeouts, maintenance windows and overload
+// rejections are degraded; misses, validation, permission and conflict errors are always
+// returned. A mode that doesn't apply to a class (serve_stale for writes, enqueue for
+// reads) is treated as fail.
+type DegradationPolicy struct {
+	// Modes maps operation classes to modes; missing classes fail
+	Modes map[OperationClass]DegradeMode
+	// MaxPending caps the keys waiting for replay under DegradeEnqueue; writes past the cap
+	// fail (default 1000)
+	MaxPending int
+	// StaleGrace is how long past the near cache TTL a copy may still be served, when the
+	// near cache doesn't set its own (default 5m)
+	StaleGrace time.Duration
+	// OnDegraded is called (outside any lock) for every operation the policy absorbed, and
+	// for enqueued writes given up on
+	OnDegraded func(op DegradedOperation)
+}
+
+// WithDegradation applies policy to the repository's operations. Reads served stale need
+// copies to fall back to: the near cache is enabled with ServeStale, and when none was
+// configured one with a zero TTL is created, so every read still asks Redis first.
+// Enqueued writes are coalesced per key (the last write wins) and kept in process only,
+// so they are lost if the process exits before ReplayDegraded or RunDegradedReplay runs.
+// Example: sessions := gparedis.NewRepository[Session](provider, client, "session:", gparedis.WithDegradation(gparedis.DegradationPolicy{Modes: map[gparedis.OperationClass]gparedis.DegradeMode{gparedis.OperationRead: gparedis.DegradeServeStale, gparedis.OperationWrite: gparedis.DegradeEnqueue, gparedis.OperationDelete: gparedis.DegradeEnqueue}}))
+func WithDegradation(policy DegradationPolicy) RepositoryOption {
+	return func(o *repositoryOptions) {
+		modes := make(map[OperationClass]DegradeMode, len(policy.Modes))
+		for class, mode := range policy.Modes {
+			modes[class] = mode
+		}
+		policy.Modes = modes
+		if policy.MaxPending <= 0 {
+			policy.MaxPending = 1000
+		}
+		o.degradation = &policy
+	}
+}
+
+// mode returns the mode applied to class
+func (p *DegradationPolicy) mode(class OperationClass) DegradeMode {
+	mode := p.Modes[class]
+	switch {
+	case class == OperationRead && mode == DegradeServeStale:
+		return mode
+	case class != OperationRead && (mode == DegradeSkipWrite || mode == DegradeEnqueue):
+		return mode
+	}
+	return DegradeFail
+}
+
+// report passes a degraded operation to OnDegraded
+func (p *DegradationPolicy) report(class OperationClass, mode DegradeMode, keys []string, err error) {
+	if p.OnDegraded != nil {
+		p.OnDegraded(DegradedOperation{Class: class, Mode: mode, Keys: keys, Err: err})
+	}
+}
+
+// staleReads returns near cache options serving stale copies, based on configured
+func (p *DegradationPolicy) staleReads(configured *NearCacheOptions) *NearCacheOptions {
+	opts := NearCacheOptions{}
+	if configured != nil {
+		opts = *configured
+	}
+	opts.ServeStale = true
+	if opts.StaleGrace <= 0 {
+		opts.StaleGrace = p.StaleGrace
+	}
+	onStale := opts.OnStale
+	opts.OnStale = func(key string, age time.Duration, cause error) {
+		if onStale != nil {
+			onStale(key, age, cause)
+		}
+		p.report(OperationRead, DegradeServeStale, []string{key}, cause)
+	}
+	return &opts
+}
+
+// unavailableError reports whether err means Redis couldn't take the operation right now,
+// so retrying the same operation later may succeed
+func unavailableError(err error) bool {
+	gpaErr, ok := err.(gpa.GPAError)
+	if !ok {
+		return err != nil && (isNetworkError(err) || isFailoverError(err))
+	}
+	switch gpaErr.Type {
+	case gpa.ErrorTypeConnection, gpa.ErrorTypeTimeout, ErrorTypeMaintenance, ErrorTypeOverloaded:
+		return true
+	case gpa.ErrorTypeDatabase:
+		cause := gpaErr.Cause
+		return cause != nil && (isNetworkError(cause) || isFailoverError(cause) || cause == context.DeadlineExceeded)
+	}
+	return false
+}
+
+// pendingWrite is a write waiting for replay
+type pendingWrite[T any] struct {
+	key   string
+	del   bool
+	value *T
+	ttl   time.Duration
+	seq   uint64
+}
+
+// pendingSets builds the pending writes of pairs, copying the values so later changes by
+// the caller aren't replayed
+func pendingSets[T any](pairs map[string]*T, ttl time.Duration) map[string]pendingWrite[T] {
+	writes := make(map[string]pendingWrite[T], len(pairs))
+	for key, value := range pairs {
+		w := pendingWrite[T]{key: key, ttl: ttl}
+		if value != nil {
+			v := *value
+			w.value = &v
+		}
+		writes[key] = w
+	}
+	return writes
+}
+
+// pendingDeletes builds the pending deletes of keys
+func pendingDeletes[T any](keys []string) map[string]pendingWrite[T] {
+	writes := make(map[string]pendingWrite[T], len(keys))
+	for _, key := range keys {
+		writes[key] = pendingWrite[T]{key: key, del: true}
+	}
+	return writes
+}
+
+// degradedWrites holds the writes enqueued by DegradeEnqueue, one per key
+type degradedWrites[T any] struct {
+	mu     sync.Mutex
+	seq    uint64
+	writes map[string]pendingWrite[T]
+}
+
+// add enqueues writes, replacing older writes to the same keys. Returns false, enqueuing
+// nothing, when the new keys would exceed max.
+func (d *degradedWrites[T]) add(writes map[string]pendingWrite[T], max int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	added := 0
+	for key := range writes {
+		if _, ok := d.writes[key]; !ok {
+			added++
+		}
+	}
+	if len(d.writes)+added > max {
+		return false
+	}
+	for _, key := range sortedKeys(writes) {
+		d.seq++
+		w := writes[key]
+		w.seq = d.seq
+		d.writes[key] = w
+	}
+	return true
+}
+
+// drop forgets the writes to keys, superseded by a write that reached Redis
+func (d *degradedWrites[T]) drop(keys []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, key := range keys {
+		delete(d.writes, key)
+	}
+}
+
+// remove forgets the write to key if it wasn't replaced since seq
+func (d *degradedWrites[T]) remove(key string, seq uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if w, ok := d.writes[key]; ok && w.seq == seq {
+		delete(d.writes, key)
+	}
+}
+
+// snapshot returns the pending writes in the order they were enqueued
+func (d *degradedWrites[T]) snapshot() []pendingWrite[T] {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	writes := make([]pendingWrite[T], 0, len(d.writes))
+	for _, w := range d.writes {
+		writes = append(writes, w)
+	}
+	sort.Slice(writes, func(i, j int) bool { return writes[i].seq < writes[j].seq })
+	return writes
+}
+
+// degradeWrite runs do and applies the policy of class when Redis is unavailable. pending
+// builds the writes to enqueue, only when needed.
+func (r *Repository[T]) degradeWrite(ctx context.Context, class OperationClass, keys []string, do func() error, pending func() map[string]pendingWrite[T]) error {
+	err := do()
+	policy := r.opts.degradation
+	if policy == nil {
+		return err
+	}
+	if err == nil {
+		r.degraded.drop(keys)
+		return nil
+	}
+	if !unavailableError(err) {
+		return err
+	}
+	switch mode := policy.mode(class); mode {
+	case DegradeSkipWrite:
+		policy.report(class, mode, keys, err)
+		return nil
+	case DegradeEnqueue:
+		if !r.degraded.add(pending(), policy.MaxPending) {
+			policy.report(class, DegradeFail, keys, err)
+			return err
+		}
+		policy.report(class, mode, keys, err)
+		return nil
+	}
+	return err
+}
+
+// PendingDegraded returns the number of keys with a write waiting for ReplayDegraded
+func (r *Repository[T]) PendingDegraded() int {
+	if r.degraded == nil {
+		return 0
+	}
+	r.degraded.mu.Lock()
+	defer r.degraded.mu.Unlock()
+	return len(r.degraded.writes)
+}
+
+// ReplayDegraded applies the writes enqueued while Redis was unavailable, oldest first, and
+// returns how many were applied. It stops at the first write Redis still can't take,
+// keeping it and the rest for the next call. A write rejected for another reason is
+// dropped and reported to OnDegraded with DegradeFail. TTLs count from the replay.
+func (r *Repository[T]) ReplayDegraded(ctx context.Context) (int, error) {
+	if r.degraded == nil {
+		return 0, nil
+	}
+	replayed := 0
+	for _, w := range r.degraded.snapshot() {
+		class := OperationWrite
+		var err error
+		if w.del {
+			class = OperationDelete
+			err = r.deleteKey(ctx, w.key)
+		} else {
+			err = r.setWithTTL(ctx, w.key, w.value, w.ttl)
+		}
+		if unavailableError(err) {
+			return replayed, err
+		}
+		r.degraded.remove(w.key, w.seq)
+		if err != nil {
+			r.opts.degradation.report(class, DegradeFail, []string{w.key}, err)
+			continue
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+// RunDegradedReplay calls ReplayDegraded every interval until ctx is cancelled
+// Example: go users.RunDegradedReplay(ctx, 5*time.Second)
+func (r *Repository[T]) RunDegradedReplay(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if r.PendingDegraded() > 0 {
+				// Writes Redis still can't take stay pending until the next tick
+				_, _ = r.ReplayDegraded(ctx)
+			}
+		}
+	}
+}
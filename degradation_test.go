@@ -0,0 +1,105 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDegradationEnqueueAndReplay(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var degraded []DegradedOperation
+	users := NewRepository[TestValue](repo.provider, repo.client, "degraded:user:", WithDegradation(DegradationPolicy{
+		Modes:      map[OperationClass]DegradeMode{OperationWrite: DegradeEnqueue, OperationDelete: DegradeSkipWrite},
+		MaxPending: 2,
+		OnDegraded: func(op DegradedOperation) { degraded = append(degraded, op) },
+	}))
+	defer repo.client.Del(ctx, "degraded:user:a", "degraded:user:b", "degraded:user:c")
+	require.NoError(t, users.Set(ctx, "c", &TestValue{ID: "c"}))
+
+	gate := NewMaintenanceGate(repo.provider, MaintenanceOptions{})
+	require.NoError(t, gate.Enable(ctx, MaintenanceWindow{Reason: "failover"}))
+	value := &TestValue{ID: "a", Age: 1}
+	require.NoError(t, users.Set(ctx, "a", value))
+	value.Age = 2
+	require.NoError(t, users.Set(ctx, "a", value), "writes to the same key are coalesced")
+	value.Age = 3
+	require.NoError(t, users.MSet(ctx, map[string]*TestValue{"b": {ID: "b"}}))
+	err := users.Set(ctx, "d", &TestValue{ID: "d"})
+	assert.True(t, IsMaintenanceError(err), "writes past MaxPending fail")
+	assert.Equal(t, 2, users.PendingDegraded())
+	deleted, err := users.MDelete(ctx, []string{"c"})
+	require.NoError(t, err, "deletes are skipped")
+	assert.Zero(t, deleted)
+	_, err = users.DeleteCascade(ctx, "c")
+	assert.True(t, IsMaintenanceError(err), "cascades are never skipped")
+
+	replayed, err := users.ReplayDegraded(ctx)
+	assert.True(t, IsMaintenanceError(err))
+	assert.Zero(t, replayed)
+	require.NoError(t, gate.Disable(ctx))
+	replayed, err = users.ReplayDegraded(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.Zero(t, users.PendingDegraded())
+
+	a, err := users.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, a.Age, "the value is copied when enqueued")
+	_, err = users.Get(ctx, "b")
+	assert.NoError(t, err)
+	_, err = users.Get(ctx, "c")
+	assert.NoError(t, err, "the skipped delete never reached Redis")
+
+	require.Len(t, degraded, 5)
+	assert.Equal(t, DegradedOperation{Class: OperationWrite, Mode: DegradeEnqueue, Keys: []string{"a"}, Err: degraded[0].Err}, degraded[0])
+	assert.Equal(t, DegradeFail, degraded[3].Mode)
+	assert.Equal(t, OperationDelete, degraded[4].Class)
+	assert.Equal(t, DegradeSkipWrite, degraded[4].Mode)
+}
+
+func TestDegradationServeStale(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: repo.client.Options().Addr})
+	var degraded []DegradedOperation
+	products := NewRepository[TestValue](repo.provider, client, "degraded:product:", WithDegradation(DegradationPolicy{
+		Modes:      map[OperationClass]DegradeMode{OperationRead: DegradeServeStale, OperationWrite: DegradeServeStale},
+		OnDegraded: func(op DegradedOperation) { degraded = append(degraded, op) },
+	}))
+	require.NotNil(t, products.NearCache(), "serving stale reads enables the near cache")
+	require.NoError(t, products.Set(ctx, "a", &TestValue{ID: "a"}))
+	defer repo.client.Del(ctx, "degraded:product:a")
+	_, err := products.Get(ctx, "a")
+	require.NoError(t, err)
+
+	// Simulate an outage
+	require.NoError(t, client.Close())
+	value, err := products.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", value.ID)
+	assert.Error(t, products.Set(ctx, "a", &TestValue{ID: "a"}), "serve_stale doesn't apply to writes")
+	require.Len(t, degraded, 1)
+	assert.Equal(t, OperationRead, degraded[0].Class)
+	assert.Equal(t, []string{"a"}, degraded[0].Keys)
+}
+
+func TestUnavailableError(t *testing.T) {
+	assert.True(t, unavailableError(convertRedisError(redis.ErrClosed)))
+	assert.True(t, unavailableError(gpa.NewError(ErrorTypeMaintenance, "maintenance")))
+	assert.True(t, unavailableError(convertRedisError(errors.New("LOADING Redis is loading the dataset in memory"))))
+	assert.False(t, unavailableError(nil))
+	assert.False(t, unavailableError(convertRedisError(redis.Nil)))
+	assert.False(t, unavailableError(convertRedisError(errors.New("WRONGTYPE Operation against a key holding the wrong kind of value"))))
+	assert.False(t, unavailableError(gpa.NewError(ErrorTypeConflict, "conflict")))
+}
@@ -0,0 +1,371 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Delta-Encoded Values
+// =====================================
+
+// DeltaOptions configures a DeltaStore
+type DeltaOptions struct {
+	// MaxDeltas is how many patches accumulate on a snapshot before the next write stores
+	// a new snapshot instead (default 50)
+	MaxDeltas int
+	// CacheSize caps the documents remembered locally to diff writes against (default 1000)
+	CacheSize int
+}
+
+// DeltaStats counts the writes of a DeltaStore
+type DeltaStats struct {
+	// Snapshots counts writes that stored the full value
+	Snapshots uint64
+	// Deltas counts writes that stored a patch
+	Deltas uint64
+	// BytesSaved is the full-value bytes not sent thanks to patches
+	BytesSaved uint64
+}
+
+// DeltaStore stores large, frequently updated values as a snapshot plus a capped list of
+// JSON Patch (RFC 6902) deltas. A write sends only the difference to the version this
+// instance last read or wrote, so small changes to multi-KB documents cost a few bytes;
+// once MaxDeltas patches pile up, the next write stores a fresh snapshot (compaction).
+// A write whose base version is stale, because another instance wrote in between, falls
+// back to a full snapshot, so writes are last-writer-wins like Set.
+type DeltaStore[T any] struct {
+	provider *Provider
+	client   *redis.Client
+	prefix   string
+	opts     DeltaOptions
+
+	mu    sync.Mutex
+	known map[string]deltaDoc
+
+	snapshots  atomic.Uint64
+	deltas     atomic.Uint64
+	bytesSaved atomic.Uint64
+}
+
+// deltaDoc is a document version remembered to diff the next write against
+type deltaDoc struct {
+	version int64
+	doc     interface{}
+}
+
+// deltaAppendScript appends the patch ARGV[2] if the stored version is still ARGV[1] and
+// fewer than ARGV[3] patches are stored. KEYS[1] is the document hash (base, version),
+// KEYS[2] the patch list. Returns the new version, -1 when stale or -2 when full.
+var deltaAppendScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'version') ~= ARGV[1] then
+	return -1
+end
+if redis.call('LLEN', KEYS[2]) >= tonumber(ARGV[3]) then
+	return -2
+end
+redis.call('RPUSH', KEYS[2], ARGV[2])
+return redis.call('HINCRBY', KEYS[1], 'version', 1)
+`)
+
+// deltaSnapshotScript replaces the base with ARGV[1] and drops the patches
+var deltaSnapshotScript = redis.NewScript(`
+redis.call('DEL', KEYS[2])
+redis.call('HSET', KEYS[1], 'base', ARGV[1])
+return redis.call('HINCRBY', KEYS[1], 'version', 1)
+`)
+
+// NewDeltaStore creates a delta store keeping documents under prefix
+// Example: docs := gparedis.NewDeltaStore[Document](provider, "doc:", gparedis.DeltaOptions{MaxDeltas: 100})
+func NewDeltaStore[T any](provider *Provider, prefix string, opts DeltaOptions) *DeltaStore[T] {
+	if opts.MaxDeltas <= 0 {
+		opts.MaxDeltas = 50
+	}
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = 1000
+	}
+	return &DeltaStore[T]{provider: provider, client: provider.client, prefix: prefix, opts: opts, known: make(map[string]deltaDoc)}
+}
+
+// Get returns the value with its patches applied, or ErrorTypeNotFound
+func (s *DeltaStore[T]) Get(ctx context.Context, id string) (*T, error) {
+	doc, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := CanonicalJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize value", err)
+	}
+	return &value, nil
+}
+
+// Set stores value, as a patch against the last version this instance saw when possible
+// and as a full snapshot otherwise
+// Example: err := docs.Set(ctx, "report-42", report)
+func (s *DeltaStore[T]) Set(ctx context.Context, id string, value *T) error {
+	data, err := CanonicalJSON(value)
+	if err != nil {
+		return err
+	}
+	doc, err := decodeJSONDocument(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	prev, ok := s.known[id]
+	s.mu.Unlock()
+	if ok {
+		ops := diffJSON("", prev.doc, doc, nil)
+		if len(ops) == 0 {
+			return nil
+		}
+		patch, err := json.Marshal(ops)
+		if err != nil {
+			return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize patch", err)
+		}
+		// A patch as large as the value saves nothing
+		if len(patch) < len(data) {
+			version, err := deltaAppendScript.Run(ctx, s.client, s.keys(id), prev.version, patch, s.opts.MaxDeltas).Int64()
+			if err != nil {
+				return convertRedisError(err)
+			}
+			if version > 0 {
+				s.deltas.Add(1)
+				s.bytesSaved.Add(uint64(len(data) - len(patch)))
+				s.remember(id, version, doc)
+				return nil
+			}
+		}
+	}
+	return s.snapshot(ctx, id, data, doc)
+}
+
+// Compact folds the stored patches into a new snapshot
+func (s *DeltaStore[T]) Compact(ctx context.Context, id string) error {
+	doc, err := s.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	data, err := CanonicalJSON(doc)
+	if err != nil {
+		return err
+	}
+	return s.snapshot(ctx, id, data, doc)
+}
+
+// Delete removes a value and its patches
+func (s *DeltaStore[T]) Delete(ctx context.Context, id string) error {
+	s.forget(id)
+	return convertRedisError(s.client.Del(ctx, s.keys(id)...).Err())
+}
+
+// Stats returns the write counters of this instance
+func (s *DeltaStore[T]) Stats() DeltaStats {
+	return DeltaStats{Snapshots: s.snapshots.Load(), Deltas: s.deltas.Load(), BytesSaved: s.bytesSaved.Load()}
+}
+
+// snapshot stores a full value
+func (s *DeltaStore[T]) snapshot(ctx context.Context, id string, data []byte, doc interface{}) error {
+	version, err := deltaSnapshotScript.Run(ctx, s.client, s.keys(id), data).Int64()
+	if err != nil {
+		s.forget(id)
+		return convertRedisError(err)
+	}
+	s.snapshots.Add(1)
+	s.remember(id, version, doc)
+	return nil
+}
+
+// load reads the snapshot and patches in one transaction and applies them
+func (s *DeltaStore[T]) load(ctx context.Context, id string) (interface{}, error) {
+	keys := s.keys(id)
+	pipe := s.client.TxPipeline()
+	fields := pipe.HMGet(ctx, keys[0], "base", "version")
+	patches := pipe.LRange(ctx, keys[1], 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, convertRedisError(err)
+	}
+	base, _ := fields.Val()[0].(string)
+	versionStr, _ := fields.Val()[1].(string)
+	if base == "" {
+		s.forget(id)
+		return nil, gpa.NewError(gpa.ErrorTypeNotFound, "value not found: "+id)
+	}
+	version, _ := strconv.ParseInt(versionStr, 10, 64)
+
+	doc, err := decodeJSONDocument([]byte(base))
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range patches.Val() {
+		var ops []jsonPatchOp
+		dec := json.NewDecoder(strings.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&ops); err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid patch stored for "+id, err)
+		}
+		if doc, err = applyJSONPatch(doc, ops); err != nil {
+			return nil, err
+		}
+	}
+	// Round-trip through canonical JSON so the remembered document compares like a
+	// freshly encoded one
+	data, err := CanonicalJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc, err = decodeJSONDocument(data); err != nil {
+		return nil, err
+	}
+	s.remember(id, version, doc)
+	return doc, nil
+}
+
+// remember records the version this instance last saw, evicting an arbitrary entry when
+// the cache is full
+func (s *DeltaStore[T]) remember(id string, version int64, doc interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.known[id]; !ok && len(s.known) >= s.opts.CacheSize {
+		for evict := range s.known {
+			delete(s.known, evict)
+			break
+		}
+	}
+	s.known[id] = deltaDoc{version: version, doc: doc}
+}
+
+// forget drops the remembered version of a document
+func (s *DeltaStore[T]) forget(id string) {
+	s.mu.Lock()
+	delete(s.known, id)
+	s.mu.Unlock()
+}
+
+// keys returns the document hash and patch list, in one hash slot
+func (s *DeltaStore[T]) keys(id string) []string {
+	key := s.prefix + HashTag(id)
+	return []string{key, key + ":deltas"}
+}
+
+// =====================================
+// JSON Patch
+// =====================================
+
+// jsonPatchOp is one RFC 6902 operation; only add, remove and replace are produced
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// decodeJSONDocument decodes JSON keeping numbers exact
+func decodeJSONDocument(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize value", err)
+	}
+	return doc, nil
+}
+
+// diffJSON appends the operations turning a into b. Objects are diffed member by member;
+// anything else that changed, arrays included, is replaced whole.
+func diffJSON(path string, a, b interface{}, ops []jsonPatchOp) []jsonPatchOp {
+	objA, okA := a.(map[string]interface{})
+	objB, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		if !reflect.DeepEqual(a, b) {
+			ops = append(ops, jsonPatchOp{Op: "replace", Path: path, Value: b})
+		}
+		return ops
+	}
+	keys := make([]string, 0, len(objA)+len(objB))
+	for k := range objA {
+		keys = append(keys, k)
+	}
+	for k := range objB {
+		if _, ok := objA[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		member := path + "/" + escapeJSONPointer(k)
+		va, inA := objA[k]
+		vb, inB := objB[k]
+		switch {
+		case !inB:
+			ops = append(ops, jsonPatchOp{Op: "remove", Path: member})
+		case !inA:
+			ops = append(ops, jsonPatchOp{Op: "add", Path: member, Value: vb})
+		default:
+			ops = diffJSON(member, va, vb, ops)
+		}
+	}
+	return ops
+}
+
+// applyJSONPatch applies add, remove and replace operations on object members
+func applyJSONPatch(doc interface{}, ops []jsonPatchOp) (interface{}, error) {
+	for _, op := range ops {
+		if op.Path == "" {
+			if op.Op == "remove" {
+				doc = nil
+			} else {
+				doc = op.Value
+			}
+			continue
+		}
+		tokens := strings.Split(op.Path[1:], "/")
+		parent := doc
+		for _, token := range tokens[:len(tokens)-1] {
+			obj, ok := parent.(map[string]interface{})
+			if !ok {
+				return nil, gpa.NewError(gpa.ErrorTypeSerialization, "patch path "+op.Path+" doesn't exist")
+			}
+			parent = obj[unescapeJSONPointer(token)]
+		}
+		obj, ok := parent.(map[string]interface{})
+		if !ok {
+			return nil, gpa.NewError(gpa.ErrorTypeSerialization, "patch path "+op.Path+" doesn't exist")
+		}
+		name := unescapeJSONPointer(tokens[len(tokens)-1])
+		switch op.Op {
+		case "add", "replace":
+			obj[name] = op.Value
+		case "remove":
+			delete(obj, name)
+		default:
+			return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "unsupported patch operation "+op.Op)
+		}
+	}
+	return doc, nil
+}
+
+// escapeJSONPointer escapes a member name for a JSON Pointer (RFC 6901)
+func escapeJSONPointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// unescapeJSONPointer reverses escapeJSONPointer
+func unescapeJSONPointer(s string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+}
@@ -0,0 +1,122 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deltaTestDoc struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Views int64             `json:"views"`
+	Tags  []string          `json:"tags"`
+	Meta  map[string]string `json:"meta,omitempty"`
+}
+
+func TestDeltaStore(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	docs := NewDeltaStore[deltaTestDoc](base.provider, "delta:", DeltaOptions{MaxDeltas: 3})
+	doc := &deltaTestDoc{Title: "report", Body: strings.Repeat("x", 4096), Views: 1, Tags: []string{"a"}}
+	require.NoError(t, docs.Set(ctx, "1", doc))
+	assert.Equal(t, DeltaStats{Snapshots: 1}, docs.Stats())
+
+	doc.Views = 2
+	doc.Meta = map[string]string{"a/b": "c"}
+	require.NoError(t, docs.Set(ctx, "1", doc))
+	doc.Views = 9007199254740993
+	doc.Tags = append(doc.Tags, "b")
+	require.NoError(t, docs.Set(ctx, "1", doc))
+	doc.Meta = nil
+	require.NoError(t, docs.Set(ctx, "1", doc))
+	stats := docs.Stats()
+	assert.Equal(t, uint64(3), stats.Deltas, "small changes are written as patches")
+	assert.Greater(t, stats.BytesSaved, uint64(3*4000))
+	n, err := base.client.LLen(ctx, "delta:{1}:deltas").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	// Another instance reads by replaying the patches
+	other := NewDeltaStore[deltaTestDoc](base.provider, "delta:", DeltaOptions{})
+	got, err := other.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, doc, got)
+
+	// A full patch list forces a snapshot
+	doc.Title = "final"
+	require.NoError(t, docs.Set(ctx, "1", doc))
+	assert.Equal(t, uint64(2), docs.Stats().Snapshots)
+	n, err = base.client.LLen(ctx, "delta:{1}:deltas").Result()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	// The other instance's base is stale now, so its write falls back to a snapshot
+	got.Title = "from other"
+	require.NoError(t, other.Set(ctx, "1", got))
+	assert.Equal(t, uint64(1), other.Stats().Snapshots)
+	assert.Zero(t, other.Stats().Deltas)
+	got, err = docs.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "from other", got.Title)
+
+	// Unchanged values write nothing
+	require.NoError(t, docs.Set(ctx, "1", got))
+	assert.Equal(t, uint64(3), docs.Stats().Deltas)
+
+	require.NoError(t, docs.Delete(ctx, "1"))
+	_, err = docs.Get(ctx, "1")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+}
+
+func TestDeltaStoreCompact(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	docs := NewDeltaStore[deltaTestDoc](base.provider, "delta:", DeltaOptions{})
+	doc := &deltaTestDoc{Title: "a", Body: strings.Repeat("y", 512)}
+	require.NoError(t, docs.Set(ctx, "2", doc))
+	doc.Title = "b"
+	require.NoError(t, docs.Set(ctx, "2", doc))
+
+	require.NoError(t, docs.Compact(ctx, "2"))
+	n, err := base.client.LLen(ctx, "delta:{2}:deltas").Result()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	got, err := docs.Get(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, doc, got)
+
+	assert.True(t, gpa.IsErrorType(docs.Compact(ctx, "missing"), gpa.ErrorTypeNotFound))
+}
+
+func TestDiffAndApplyJSONPatch(t *testing.T) {
+	a, err := decodeJSONDocument([]byte(`{"a":1,"b":{"c":[1,2],"d":"x"},"e/f":true}`))
+	require.NoError(t, err)
+	b, err := decodeJSONDocument([]byte(`{"a":1,"b":{"c":[1,3],"g":null},"e/f":false,"h":{}}`))
+	require.NoError(t, err)
+
+	ops := diffJSON("", a, b, nil)
+	assert.Equal(t, []jsonPatchOp{
+		{Op: "replace", Path: "/b/c", Value: []interface{}{json.Number("1"), json.Number("3")}},
+		{Op: "remove", Path: "/b/d"},
+		{Op: "add", Path: "/b/g"},
+		{Op: "replace", Path: "/e~1f", Value: false},
+		{Op: "add", Path: "/h", Value: map[string]interface{}{}},
+	}, ops)
+
+	got, err := applyJSONPatch(a, ops)
+	require.NoError(t, err)
+	assert.Equal(t, b, got)
+
+	_, err = applyJSONPatch(b, []jsonPatchOp{{Op: "add", Path: "/x/y", Value: 1}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,181 @@
+package gparedis
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Dataset Diff
+// =====================================
+
+// DiffSide is one side of a Diff: a provider and the key prefix compared on it
+type DiffSide struct {
+	Provider *Provider
+	Prefix   string
+}
+
+// DiffOptions configures Diff
+type DiffOptions struct {
+	// Pattern restricts the comparison to matching keys (relative to the prefixes, default "*")
+	Pattern string
+	// MaxReported caps the keys listed per category; counts are always complete (default 1000)
+	MaxReported int
+}
+
+// DiffReport is the result of a Diff. Keys are relative to the prefixes.
+type DiffReport struct {
+	// Compared counts keys present on both sides
+	Compared int
+	Equal    int
+	// Missing lists keys found in the source but not the target
+	Missing      []string
+	MissingCount int
+	// Extra lists keys found in the target but not the source
+	Extra      []string
+	ExtraCount int
+	// Different lists keys whose values differ
+	Different      []string
+	DifferentCount int
+}
+
+// Consistent reports whether both sides hold the same keys and values
+func (d *DiffReport) Consistent() bool {
+	return d.MissingCount == 0 && d.ExtraCount == 0 && d.DifferentCount == 0
+}
+
+// valueHashScript returns the SHA-1 of a string value, the type name of other values and
+// an empty string for missing keys, so values are compared without transferring them
+var valueHashScript = readOnlyScript(redis.NewScript(`
+local t = redis.call('TYPE', KEYS[1])
+if type(t) == 'table' then
+	t = t['ok']
+end
+if t == 'none' then
+	return ''
+end
+if t == 'string' then
+	return redis.sha1hex(redis.call('GET', KEYS[1]))
+end
+return t
+`))
+
+// Diff compares the keys and values under two prefixes, on two providers or one, to
+// validate migrations and mirrors. String values are compared by hashes computed on each
+// server; other types only by type. The sides are scanned while live, so keys written
+// during the comparison may be reported as differences.
+// Example: report, err := gparedis.Diff(ctx, gparedis.DiffSide{Provider: oldRedis, Prefix: "user:"}, gparedis.DiffSide{Provider: newRedis, Prefix: "user:"}, gparedis.DiffOptions{})
+func Diff(ctx context.Context, source, target DiffSide, opts DiffOptions) (*DiffReport, error) {
+	if opts.Pattern == "" {
+		opts.Pattern = "*"
+	}
+	if opts.MaxReported <= 0 {
+		opts.MaxReported = 1000
+	}
+	for _, side := range []DiffSide{source, target} {
+		if err := valueHashScript.Load(ctx, side.Provider.client).Err(); err != nil {
+			return nil, convertRedisError(err)
+		}
+	}
+	report := &DiffReport{}
+	add := func(list *[]string, count *int, key string) {
+		*count++
+		if len(*list) < opts.MaxReported {
+			*list = append(*list, key)
+		}
+	}
+
+	// Source keys are missing, equal or different
+	err := diffScan(ctx, source, opts.Pattern, func(keys []string) error {
+		sourceHashes, err := diffHashes(ctx, source, keys)
+		if err != nil {
+			return err
+		}
+		targetHashes, err := diffHashes(ctx, target, keys)
+		if err != nil {
+			return err
+		}
+		for i, key := range keys {
+			switch {
+			case sourceHashes[i] == "":
+				// Deleted since SCAN
+			case targetHashes[i] == "":
+				add(&report.Missing, &report.MissingCount, key)
+			case sourceHashes[i] != targetHashes[i]:
+				report.Compared++
+				add(&report.Different, &report.DifferentCount, key)
+			default:
+				report.Compared++
+				report.Equal++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Target keys absent from the source are extra
+	err = diffScan(ctx, target, opts.Pattern, func(keys []string) error {
+		cmds := make([]*redis.IntCmd, len(keys))
+		_, err := source.Provider.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = pipe.Exists(ctx, source.Prefix+key)
+			}
+			return nil
+		})
+		if err != nil {
+			return convertRedisError(err)
+		}
+		for i, key := range keys {
+			if cmds[i].Val() == 0 {
+				add(&report.Extra, &report.ExtraCount, key)
+			}
+		}
+		return nil
+	})
+	return report, err
+}
+
+// diffScan walks the keys of one side and calls fn with batches of keys relative to its prefix
+func diffScan(ctx context.Context, side DiffSide, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := side.Provider.client.Scan(ctx, cursor, side.Prefix+pattern, scanBatchSize).Result()
+		if err != nil {
+			return convertRedisError(err)
+		}
+		if len(keys) > 0 {
+			for i, key := range keys {
+				keys[i] = strings.TrimPrefix(key, side.Prefix)
+			}
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// diffHashes returns the value hash of every key on one side, in one pipeline
+func diffHashes(ctx context.Context, side DiffSide, keys []string) ([]string, error) {
+	cmds := make([]*redis.Cmd, len(keys))
+	_, err := side.Provider.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = valueHashScript.EvalSha(ctx, pipe, []string{side.Prefix + key})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	hashes := make([]string, len(keys))
+	for i, cmd := range cmds {
+		hashes[i], _ = cmd.Text()
+	}
+	return hashes, nil
+}
@@ -0,0 +1,49 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	c := repo.client
+	require.NoError(t, c.MSet(ctx,
+		"old:1", `{"id":"1"}`, "new:1", `{"id":"1"}`,
+		"old:2", `{"id":"2"}`, "new:2", `{"id":"2","name":"changed"}`,
+		"old:3", `{"id":"3"}`,
+		"new:4", `{"id":"4"}`,
+		"old:5", `{"id":"5"}`, "new:5", `{"id":"5"}`,
+	).Err())
+	require.NoError(t, c.SAdd(ctx, "old:set", "a").Err())
+	require.NoError(t, c.SAdd(ctx, "new:set", "b").Err())
+	require.NoError(t, c.LPush(ctx, "old:typed", "a").Err())
+	require.NoError(t, c.Set(ctx, "new:typed", "a", 0).Err())
+
+	source := DiffSide{Provider: repo.provider, Prefix: "old:"}
+	target := DiffSide{Provider: repo.provider, Prefix: "new:"}
+	report, err := Diff(ctx, source, target, DiffOptions{})
+	require.NoError(t, err)
+	assert.False(t, report.Consistent())
+	assert.Equal(t, []string{"3"}, report.Missing)
+	assert.Equal(t, []string{"4"}, report.Extra)
+	assert.ElementsMatch(t, []string{"2", "typed"}, report.Different)
+	assert.Equal(t, 5, report.Compared)
+	assert.Equal(t, 3, report.Equal, "non-string values compare by type only")
+
+	report, err = Diff(ctx, source, target, DiffOptions{Pattern: "[15]"})
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.Equal(t, 2, report.Equal)
+
+	report, err = Diff(ctx, source, target, DiffOptions{MaxReported: 1})
+	require.NoError(t, err)
+	assert.Len(t, report.Different, 1)
+	assert.Equal(t, 2, report.DifferentCount)
+}
@@ -0,0 +1,214 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Dry-Run Mode
+// =====================================
+
+// PlannedCommand is a mutating command recorded instead of executed
+type PlannedCommand struct {
+	Time time.Time
+	Args []interface{}
+}
+
+// String renders the command as it would be typed in redis-cli
+func (c PlannedCommand) String() string {
+	parts := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		s := fmt.Sprint(arg)
+		if i == 0 {
+			s = strings.ToUpper(s)
+		} else if s == "" || strings.ContainsAny(s, " \t\n\"'") {
+			s = strconv.Quote(s)
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, " ")
+}
+
+// DryRunPlan collects the writes intercepted while dry-run mode is active
+type DryRunPlan struct {
+	mu       sync.Mutex
+	commands []PlannedCommand
+	log      io.Writer
+}
+
+// record appends a command to the plan and the optional log
+func (p *DryRunPlan) record(cmd redis.Cmder) {
+	planned := PlannedCommand{Time: time.Now(), Args: append([]interface{}(nil), cmd.Args()...)}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.commands = append(p.commands, planned)
+	if p.log != nil {
+		fmt.Fprintln(p.log, planned.String())
+	}
+}
+
+// Commands returns a copy of the recorded commands in issue order
+func (p *DryRunPlan) Commands() []PlannedCommand {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PlannedCommand(nil), p.commands...)
+}
+
+// Len returns the number of recorded commands
+func (p *DryRunPlan) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.commands)
+}
+
+// Reset discards the recorded commands
+func (p *DryRunPlan) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.commands = nil
+}
+
+// WriteTo writes the plan to w, one command per line
+func (p *DryRunPlan) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, cmd := range p.Commands() {
+		n, err := fmt.Fprintln(w, cmd.String())
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// StartDryRun switches the provider to dry-run mode: mutating commands are recorded in the
+// returned plan (and written to log, which may be nil) instead of being sent to Redis, and
+// callers receive simulated results. Reads still hit Redis, so they don't observe planned writes.
+// Example: plan := provider.StartDryRun(os.Stdout); runMigration(ctx); provider.StopDryRun()
+func (p *Provider) StartDryRun(log io.Writer) *DryRunPlan {
+	plan := &DryRunPlan{log: log}
+	p.dryRun.Store(plan)
+	return plan
+}
+
+// StopDryRun leaves dry-run mode and returns the plan that was active, if any
+func (p *Provider) StopDryRun() *DryRunPlan {
+	return p.dryRun.Swap(nil)
+}
+
+// DryRun returns the active dry-run plan, or nil when writes are executed
+func (p *Provider) DryRun() *DryRunPlan {
+	return p.dryRun.Load()
+}
+
+// errDryRun stops a single intercepted command from reaching Redis; the hook clears it again
+var errDryRun = errors.New("gparedis: command intercepted by dry-run mode")
+
+// scriptReplies holds the replies dry-run mode simulates for scripts whose callers can't take
+// the default int64(1), by SHA-1. Filled at init by dryRunReply.
+var scriptReplies = map[string]interface{}{}
+
+// dryRunReply makes dry-run mode answer intercepted runs of script with reply, a value or an
+// error such as redis.Nil. Returns script, so it wraps the declaration.
+func dryRunReply(script *redis.Script, reply interface{}) *redis.Script {
+	scriptReplies[script.Hash()] = reply
+	return script
+}
+
+// simulateResult gives an intercepted command an optimistic result: OK, true, the number of
+// keys for DEL/UNLINK, 1 for other counts, the registered reply (or 1) for scripts, and zero
+// values otherwise.
+func simulateResult(cmd redis.Cmder) {
+	cmd.SetErr(nil)
+	switch c := cmd.(type) {
+	case *redis.StatusCmd:
+		c.SetVal("OK")
+	case *redis.BoolCmd:
+		c.SetVal(true)
+	case *redis.IntCmd:
+		switch strings.ToLower(cmd.Name()) {
+		case "del", "unlink":
+			c.SetVal(int64(len(cmd.Args()) - 1))
+		default:
+			c.SetVal(1)
+		}
+	case *redis.Cmd:
+		reply, ok := scriptReplies[scriptHash(cmd)]
+		if !ok {
+			reply = int64(1)
+		}
+		if err, ok := reply.(error); ok {
+			c.SetErr(err)
+		} else {
+			c.SetVal(reply)
+		}
+	case *redis.StringCmd:
+		if strings.ToLower(cmd.Name()) == "xadd" {
+			c.SetVal(strconv.FormatInt(time.Now().UnixMilli(), 10) + "-0")
+		}
+	}
+}
+
+// dryRunHook records mutating commands instead of running them while dry-run mode is active
+type dryRunHook struct {
+	provider *Provider
+}
+
+// dryRunPipelineKey stores the commands swapped out of a pipeline
+type dryRunPipelineKey struct{}
+
+func (h *dryRunHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	plan := h.provider.DryRun()
+	if plan == nil || !isWriteCommand(cmd) {
+		return ctx, nil
+	}
+	plan.record(cmd)
+	return ctx, errDryRun
+}
+
+func (h *dryRunHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if cmd.Err() == errDryRun {
+		simulateResult(cmd)
+	}
+	return nil
+}
+
+// BeforeProcessPipeline swaps intercepted writes for PINGs so the reads of the pipeline
+// (and any MULTI/EXEC around it) still run as one round trip.
+func (h *dryRunHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	plan := h.provider.DryRun()
+	if plan == nil {
+		return ctx, nil
+	}
+	swapped := make(map[int]redis.Cmder)
+	for i, cmd := range cmds {
+		if !isWriteCommand(cmd) {
+			continue
+		}
+		plan.record(cmd)
+		swapped[i] = cmd
+		cmds[i] = redis.NewStatusCmd(ctx, "ping")
+	}
+	if len(swapped) == 0 {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, dryRunPipelineKey{}, swapped), nil
+}
+
+func (h *dryRunHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	swapped, _ := ctx.Value(dryRunPipelineKey{}).(map[int]redis.Cmder)
+	for i, cmd := range swapped {
+		cmds[i] = cmd
+		simulateResult(cmd)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlannedCommandString(t *testing.T) {
+	cmd := PlannedCommand{Args: []interface{}{"set", "user:1", `{"name":"Ada Lovelace"}`, "px", 1000}}
+	assert.Equal(t, `SET user:1 "{\"name\":\"Ada Lovelace\"}" px 1000`, cmd.String())
+}
+
+func TestDryRunMode(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	provider := repo.provider
+	require.NoError(t, repo.Set(ctx, "user:1", &TestValue{ID: "1", Name: "Ada"}))
+
+	var log bytes.Buffer
+	plan := provider.StartDryRun(&log)
+	assert.Same(t, plan, provider.DryRun())
+
+	require.NoError(t, repo.SetWithTTL(ctx, "user:2", &TestValue{ID: "2"}, time.Minute))
+	deleted, err := repo.MDelete(ctx, []string{"user:1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	counter, err := repo.Increment(ctx, "counter", 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), counter)
+
+	// Mixed pipelines still run their reads
+	var get *redis.StringCmd
+	_, err = provider.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, "user:1")
+		pipe.Del(ctx, "user:1")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Contains(t, get.Val(), "Ada")
+
+	assert.Same(t, plan, provider.StopDryRun())
+	assert.Nil(t, provider.DryRun())
+
+	names := make([]string, 0, plan.Len())
+	for _, cmd := range plan.Commands() {
+		names = append(names, cmd.Args[0].(string))
+	}
+	assert.Contains(t, names, "set")
+	assert.Contains(t, names, "incrby")
+	assert.Contains(t, log.String(), "SET user:2 ")
+
+	// Nothing was written
+	_, err = repo.Get(ctx, "user:2")
+	assert.True(t, gpa.IsNotFound(err))
+	_, err = repo.Get(ctx, "user:1")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	_, err = plan.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Equal(t, log.String(), out.String())
+	plan.Reset()
+	assert.Equal(t, 0, plan.Len())
+}
+
+func TestDryRunUniqueIndex(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	provider := base.provider
+	repo := NewRepository[indexedUser](provider, base.client, "dry:user:")
+	require.NoError(t, repo.Set(ctx, "1", &indexedUser{ID: "1", Email: "ada@example.com"}))
+
+	plan := provider.StartDryRun(nil)
+	require.NoError(t, repo.Set(ctx, "2", &indexedUser{ID: "2", Email: "grace@example.com"}))
+	require.NoError(t, repo.MSet(ctx, map[string]*indexedUser{"3": {ID: "3", Email: "alan@example.com"}}))
+	_, err := repo.SetIfUnchanged(ctx, "4", &indexedUser{ID: "4", Email: "edsger@example.com"}, "")
+	require.NoError(t, err)
+	count, err := repo.GroupCount(ctx, "status", IndexEq("status", "active"))
+	require.NoError(t, err, "read-only scripts still run")
+	assert.Empty(t, count)
+	provider.StopDryRun()
+	assert.NotZero(t, plan.Len())
+
+	// Nothing was written or claimed
+	for _, key := range []string{"2", "3", "4"} {
+		_, err := repo.Get(ctx, key)
+		assert.True(t, gpa.IsNotFound(err), key)
+	}
+	_, ok, err := repo.KeyByUnique(ctx, "email", "grace@example.com")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	key, ok, err := repo.KeyByUnique(ctx, "email", "ada@example.com")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", key)
+}
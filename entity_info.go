@@ -0,0 +1,278 @@
+package gparedis
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Reflection-Based Entity Metadata
+// =====================================
+
+// indexTagName is the struct tag declaring secondary indexes.
+// Format: `gpaindex:"name[,unique][,range][,subject][,text][,ref=prefix]"`; an empty name defaults to the field's json name.
+// A field belonging to several indexes lists them separated by ";", e.g. `gpaindex:"status;tenant_status"`.
+const indexTagName = "gpaindex"
+
+// entityField describes one serialized struct field
+type entityField struct {
+	info     gpa.FieldInfo
+	jsonName string
+	index    []int
+}
+
+// indexTag is a parsed gpaindex struct tag
+type indexTag struct {
+	name    string
+	unique  bool
+	ranged  bool
+	subject bool
+	text    bool
+	// ref is the key prefix of the repository the field's value is a key of
+	ref string
+}
+
+// entityMeta is the cached reflection result for an entity type
+type entityMeta struct {
+	name       string
+	fields     []entityField
+	primaryKey []string
+	indexes    []gpa.IndexInfo
+	indexTags  map[string][]indexTag // keyed by Go field name
+}
+
+var entityMetaCache sync.Map // reflect.Type -> *entityMeta
+
+// entityMetaFor returns the (cached) metadata for type t
+func entityMetaFor(t reflect.Type) *entityMeta {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return &entityMeta{}
+	}
+	if cached, ok := entityMetaCache.Load(t); ok {
+		return cached.(*entityMeta)
+	}
+
+	meta := &entityMeta{name: t.String(), indexTags: make(map[string][]indexTag)}
+	if t.Kind() == reflect.Struct {
+		collectFields(t, nil, meta)
+		meta.primaryKey = inferPrimaryKey(meta.fields)
+		for i := range meta.fields {
+			for _, pk := range meta.primaryKey {
+				if meta.fields[i].info.Name == pk {
+					meta.fields[i].info.IsPrimaryKey = true
+				}
+			}
+		}
+		meta.indexes = buildIndexInfo(meta)
+	}
+
+	cached, _ := entityMetaCache.LoadOrStore(t, meta)
+	return cached.(*entityMeta)
+}
+
+// collectFields walks exported fields the way encoding/json does, flattening embedded structs
+func collectFields(t reflect.Type, parent []int, meta *entityMeta) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonTag := f.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		index := append(append([]int{}, parent...), i)
+
+		if f.Anonymous && jsonTag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				collectFields(ft, index, meta)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		parts := strings.Split(jsonTag, ",")
+		jsonName := parts[0]
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+		omitEmpty := false
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				omitEmpty = true
+			}
+		}
+
+		meta.fields = append(meta.fields, entityField{
+			info: gpa.FieldInfo{
+				Name:         f.Name,
+				Type:         f.Type,
+				DatabaseType: jsonType(f.Type),
+				Tag:          string(f.Tag),
+				IsNullable:   omitEmpty || isNullableKind(f.Type.Kind()),
+			},
+			jsonName: jsonName,
+			index:    index,
+		})
+
+		if raw, ok := f.Tag.Lookup(indexTagName); ok {
+			for _, spec := range strings.Split(raw, ";") {
+				tag := parseIndexTag(spec)
+				if tag.name == "" {
+					tag.name = jsonName
+				}
+				meta.indexTags[f.Name] = append(meta.indexTags[f.Name], tag)
+			}
+		}
+	}
+}
+
+// parseIndexTag parses one index of a gpaindex struct tag
+func parseIndexTag(raw string) indexTag {
+	parts := strings.Split(raw, ",")
+	tag := indexTag{name: strings.TrimSpace(parts[0])}
+	for _, opt := range parts[1:] {
+		switch strings.TrimSpace(opt) {
+		case "unique":
+			tag.unique = true
+		case "range":
+			tag.ranged = true
+		case "subject":
+			tag.subject = true
+		case "text":
+			tag.text = true
+		default:
+			if ref, ok := strings.CutPrefix(strings.TrimSpace(opt), "ref="); ok {
+				tag.ref = ref
+			}
+		}
+	}
+	return tag
+}
+
+// inferPrimaryKey picks the primary key fields from tags, falling back to a field named ID
+func inferPrimaryKey(fields []entityField) []string {
+	var pk []string
+	for _, f := range fields {
+		tag := reflect.StructTag(f.info.Tag)
+		if hasTagOption(tag.Get("gpa"), "primaryKey", "pk") ||
+			hasTagOption(tag.Get("gorm"), "primaryKey", "primary_key") ||
+			strings.Split(tag.Get("bson"), ",")[0] == "_id" {
+			pk = append(pk, f.info.Name)
+		}
+	}
+	if len(pk) > 0 {
+		return pk
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.info.Name, "ID") || f.jsonName == "id" {
+			return []string{f.info.Name}
+		}
+	}
+	return nil
+}
+
+// hasTagOption reports whether a comma or semicolon separated tag contains one of the options
+func hasTagOption(tag string, options ...string) bool {
+	for _, part := range strings.FieldsFunc(tag, func(r rune) bool { return r == ',' || r == ';' }) {
+		for _, opt := range options {
+			if strings.EqualFold(strings.TrimSpace(part), opt) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildIndexInfo groups gpaindex tags into gpa.IndexInfo entries; fields sharing a name form a composite index
+func buildIndexInfo(meta *entityMeta) []gpa.IndexInfo {
+	var indexes []gpa.IndexInfo
+	if len(meta.primaryKey) > 0 {
+		indexes = append(indexes, gpa.IndexInfo{
+			Name:     "primary",
+			Fields:   meta.primaryKey,
+			IsUnique: true,
+			Type:     gpa.IndexTypePrimary,
+		})
+	}
+
+	positions := make(map[string]int)
+	for _, f := range meta.fields {
+		for _, tag := range meta.indexTags[f.info.Name] {
+			if pos, seen := positions[tag.name]; seen {
+				idx := &indexes[pos]
+				idx.Fields = append(idx.Fields, f.info.Name)
+				idx.IsUnique = idx.IsUnique || tag.unique
+				if !idx.IsUnique {
+					idx.Type = gpa.IndexTypeComposite
+				}
+				continue
+			}
+			indexType := gpa.IndexTypeStandard
+			switch {
+			case tag.unique:
+				indexType = gpa.IndexTypeUnique
+			case tag.text:
+				indexType = gpa.IndexTypeFullText
+			}
+			positions[tag.name] = len(indexes)
+			indexes = append(indexes, gpa.IndexInfo{
+				Name:     tag.name,
+				Fields:   []string{f.info.Name},
+				IsUnique: tag.unique,
+				Type:     indexType,
+			})
+		}
+	}
+	return indexes
+}
+
+// jsonType describes how a Go type is stored in the JSON value
+func jsonType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonType(t.Elem())
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string" // []byte is base64 encoded
+		}
+		return "array"
+	case reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct, reflect.Interface:
+		return "object"
+	default:
+		return fmt.Sprintf("unsupported(%s)", t.Kind())
+	}
+}
+
+// isNullableKind reports whether values of the kind can be JSON null
+func isNullableKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return true
+	}
+	return false
+}
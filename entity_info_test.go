@@ -0,0 +1,80 @@
+package gparedis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type entityInfoBase struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type entityInfoUser struct {
+	entityInfoBase
+	UserID   string            `json:"user_id" gpa:"pk"`
+	Email    string            `json:"email" gpaindex:"email,unique"`
+	Tenant   string            `json:"tenant" gpaindex:"tenant_status"`
+	Status   string            `json:"status" gpaindex:"tenant_status"`
+	Age      int               `json:"age,omitempty" gpaindex:",range"`
+	Nickname *string           `json:"nickname"`
+	Tags     []string          `json:"tags"`
+	Meta     map[string]string `json:"meta"`
+	Secret   string            `json:"-"`
+	internal string
+}
+
+func TestEntityMetaFields(t *testing.T) {
+	repo := NewRepository[entityInfoUser](nil, nil, "user:")
+	info, err := repo.GetEntityInfo()
+	require.NoError(t, err)
+
+	assert.Equal(t, "gparedis.entityInfoUser", info.Name)
+	assert.Equal(t, "user:", info.TableName)
+	assert.Equal(t, []string{"UserID"}, info.PrimaryKey)
+
+	names := make([]string, len(info.Fields))
+	byName := make(map[string]gpa.FieldInfo)
+	for i, f := range info.Fields {
+		names[i] = f.Name
+		byName[f.Name] = f
+	}
+	assert.Equal(t, []string{"CreatedAt", "UserID", "Email", "Tenant", "Status", "Age", "Nickname", "Tags", "Meta"}, names)
+
+	assert.True(t, byName["UserID"].IsPrimaryKey)
+	assert.Equal(t, "string", byName["CreatedAt"].DatabaseType)
+	assert.Equal(t, "number", byName["Age"].DatabaseType)
+	assert.True(t, byName["Age"].IsNullable)
+	assert.True(t, byName["Nickname"].IsNullable)
+	assert.False(t, byName["Email"].IsNullable)
+	assert.Equal(t, "array", byName["Tags"].DatabaseType)
+	assert.Equal(t, "object", byName["Meta"].DatabaseType)
+	assert.Equal(t, reflect.TypeOf(""), byName["Email"].Type)
+	assert.Contains(t, byName["Email"].Tag, `gpaindex:"email,unique"`)
+}
+
+func TestEntityMetaIndexes(t *testing.T) {
+	meta := entityMetaFor(reflect.TypeOf(entityInfoUser{}))
+	require.Len(t, meta.indexes, 4)
+
+	assert.Equal(t, gpa.IndexInfo{Name: "primary", Fields: []string{"UserID"}, IsUnique: true, Type: gpa.IndexTypePrimary}, meta.indexes[0])
+	assert.Equal(t, gpa.IndexInfo{Name: "email", Fields: []string{"Email"}, IsUnique: true, Type: gpa.IndexTypeUnique}, meta.indexes[1])
+	assert.Equal(t, gpa.IndexInfo{Name: "tenant_status", Fields: []string{"Tenant", "Status"}, Type: gpa.IndexTypeComposite}, meta.indexes[2])
+	assert.Equal(t, "age", meta.indexes[3].Name)
+	assert.True(t, meta.indexTags["Age"][0].ranged)
+
+	assert.Same(t, meta, entityMetaFor(reflect.TypeOf(&entityInfoUser{})))
+}
+
+func TestEntityMetaNonStruct(t *testing.T) {
+	repo := NewRepository[string](nil, nil, "s:")
+	info, err := repo.GetEntityInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "string", info.Name)
+	assert.Equal(t, []string{"key"}, info.PrimaryKey)
+	assert.Empty(t, info.Fields)
+}
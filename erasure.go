@@ -0,0 +1,191 @@
+package gparedis
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Subject Erasure (GDPR)
+// =====================================
+
+// subjectTagPrefix prefixes the tag sets linking arbitrary keys to a data subject
+const subjectTagPrefix = "gparedis:subject:"
+
+// subjectSource is a repository with a subject index, registered on its provider
+type subjectSource struct {
+	// buildKey returns the full Redis key of an unprefixed key, as the repository stores it
+	buildKey func(key string) string
+	// keys returns the unprefixed keys belonging to the subject
+	keys func(ctx context.Context, subjectID string) ([]string, error)
+	// erase deletes unprefixed keys through the repository so indexes, CDC and audit stay consistent
+	erase func(ctx context.Context, keys []string) error
+}
+
+// ErasureReport lists everything removed for a subject. Signature is an HMAC-SHA256
+// (hex) over the report's other fields, made with the provider's erasure signing key.
+type ErasureReport struct {
+	SubjectID string    `json:"subject_id"`
+	ErasedAt  time.Time `json:"erased_at"`
+	Keys      []string  `json:"keys"`
+	Signature string    `json:"signature"`
+}
+
+// signingPayload is the canonical JSON the signature covers
+func (r *ErasureReport) signingPayload() []byte {
+	payload, _ := json.Marshal(struct {
+		SubjectID string    `json:"subject_id"`
+		ErasedAt  time.Time `json:"erased_at"`
+		Keys      []string  `json:"keys"`
+	}{r.SubjectID, r.ErasedAt, r.Keys})
+	return payload
+}
+
+// sign computes the report signature for key
+func (r *ErasureReport) sign(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(r.signingPayload())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyErasureReport checks that report was signed with key and not modified since
+func VerifyErasureReport(report *ErasureReport, key []byte) bool {
+	expected, err := hex.DecodeString(report.Signature)
+	if err != nil {
+		return false
+	}
+	actual, _ := hex.DecodeString(report.sign(key))
+	return hmac.Equal(expected, actual)
+}
+
+// SetErasureSigningKey sets the HMAC key used to sign erasure reports
+func (p *Provider) SetErasureSigningKey(key []byte) {
+	p.subjectMu.Lock()
+	defer p.subjectMu.Unlock()
+	p.erasureKey = append([]byte(nil), key...)
+}
+
+// registerSubjectSource makes a repository's subject index visible to EraseSubject
+func (p *Provider) registerSubjectSource(id string, source subjectSource) {
+	p.subjectMu.Lock()
+	defer p.subjectMu.Unlock()
+	if p.subjectSources == nil {
+		p.subjectSources = make(map[string]subjectSource)
+	}
+	p.subjectSources[id] = source
+}
+
+// TagSubject links keys that aren't covered by a repository subject index (caches, raw keys, ...)
+// to a data subject, so EraseSubject removes them as well. Keys are full Redis keys.
+func (p *Provider) TagSubject(ctx context.Context, subjectID string, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	members := make([]interface{}, len(keys))
+	for i, key := range keys {
+		members[i] = key
+	}
+	return convertRedisError(p.client.SAdd(ctx, subjectTagPrefix+subjectID, members...).Err())
+}
+
+// EraseSubject deletes every key associated with subjectID: entities found through the subject
+// indexes (`gpaindex:"name,subject"`) of this provider's repositories, and keys linked with
+// TagSubject. Returns a signed report of the removed keys; requires SetErasureSigningKey.
+// Example: report, err := provider.EraseSubject(ctx, "user-42")
+func (p *Provider) EraseSubject(ctx context.Context, subjectID string) (*ErasureReport, error) {
+	if subjectID == "" {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "subject ID cannot be empty")
+	}
+
+	p.subjectMu.RLock()
+	signingKey := p.erasureKey
+	ids := make([]string, 0, len(p.subjectSources))
+	for id := range p.subjectSources {
+		ids = append(ids, id)
+	}
+	sources := make([]subjectSource, 0, len(ids))
+	sort.Strings(ids)
+	for _, id := range ids {
+		sources = append(sources, p.subjectSources[id])
+	}
+	p.subjectMu.RUnlock()
+
+	if len(signingKey) == 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeValidation, "erasure reports must be signed; call SetErasureSigningKey first")
+	}
+
+	report := &ErasureReport{SubjectID: subjectID, Keys: []string{}}
+	for _, source := range sources {
+		keys, err := source.keys(ctx, subjectID)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		if err := source.erase(ctx, keys); err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			report.Keys = append(report.Keys, source.buildKey(key))
+		}
+	}
+
+	tagSet := subjectTagPrefix + subjectID
+	tagged, err := p.client.SMembers(ctx, tagSet).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	if len(tagged) > 0 {
+		if err := p.client.Unlink(ctx, tagged...).Err(); err != nil {
+			return nil, convertRedisError(err)
+		}
+		report.Keys = append(report.Keys, tagged...)
+	}
+	if err := p.client.Del(ctx, tagSet).Err(); err != nil {
+		return nil, convertRedisError(err)
+	}
+
+	sort.Strings(report.Keys)
+	report.ErasedAt = time.Now().UTC()
+	report.Signature = report.sign(signingKey)
+	return report, nil
+}
+
+// registerSubjectIndexes exposes the repository's subject indexes to EraseSubject
+func (r *Repository[T]) registerSubjectIndexes() {
+	if r.provider == nil {
+		return
+	}
+	for _, def := range r.indexes() {
+		if !def.subject {
+			continue
+		}
+		if len(def.fields) != 1 {
+			// Subject indexes identify a single subject; composite subject indexes are ignored
+			continue
+		}
+		index := def.name
+		r.provider.registerSubjectSource(fmt.Sprintf("%s|%s|%s", r.keyPrefix, index, reflect.TypeOf((*T)(nil)).Elem()), subjectSource{
+			buildKey: r.buildKey,
+			keys: func(ctx context.Context, subjectID string) ([]string, error) {
+				return r.KeysByIndex(ctx, index, subjectID)
+			},
+			erase: func(ctx context.Context, keys []string) error {
+				// Erasure must not be skipped or deferred by a degradation policy: the report
+				// is only signed for keys actually deleted
+				_, err := r.mdelete(ctx, keys)
+				return err
+			},
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type erasureOrder struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id" gpaindex:"user_id,subject"`
+}
+
+type erasureSession struct {
+	Token  string `json:"token"`
+	UserID int    `json:"user_id" gpaindex:"owner,subject"`
+}
+
+func TestEraseSubject(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	p := base.provider
+	orders := NewRepository[erasureOrder](p, base.client, "order:")
+	sessions := NewRepository[erasureSession](p, base.client, "session:", WithSegmentHashTag(0))
+
+	require.NoError(t, orders.Set(ctx, "1", &erasureOrder{ID: "1", UserID: "42"}))
+	require.NoError(t, orders.Set(ctx, "2", &erasureOrder{ID: "2", UserID: "7"}))
+	require.NoError(t, sessions.Set(ctx, "abc", &erasureSession{Token: "abc", UserID: 42}))
+	require.NoError(t, base.client.Set(ctx, "cache:profile:42", "{}", 0).Err())
+	require.NoError(t, p.TagSubject(ctx, "42", "cache:profile:42"))
+
+	_, err := p.EraseSubject(ctx, "42")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeValidation), "unsigned erasure is refused")
+
+	key := []byte("compliance-secret")
+	p.SetErasureSigningKey(key)
+	report, err := p.EraseSubject(ctx, "42")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cache:profile:42", "order:1", "session:{abc}"}, report.Keys, "report keys are the stored keys")
+	assert.True(t, VerifyErasureReport(report, key))
+	assert.False(t, VerifyErasureReport(report, []byte("other")))
+
+	tampered := *report
+	tampered.Keys = tampered.Keys[:1]
+	assert.False(t, VerifyErasureReport(&tampered, key))
+
+	_, err = orders.Get(ctx, "1")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+	_, err = orders.Get(ctx, "2")
+	assert.NoError(t, err)
+	exists, err := base.client.Exists(ctx, "cache:profile:42", "gparedis:subject:42").Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists)
+
+	again, err := p.EraseSubject(ctx, "42")
+	require.NoError(t, err)
+	assert.Empty(t, again.Keys)
+}
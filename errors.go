@@ -0,0 +1,67 @@
+package gparedis
+
+import "github.com/lemmego/gpa"
+
+// =====================================
+// Adapter Error Types
+// =====================================
+
+const (
+	// ErrorTypeReadOnly is returned when a provider in read-only mode is asked to write
+	ErrorTypeReadOnly gpa.ErrorType = "read_only"
+	// ErrorTypeBudgetExceeded is returned when a request exhausts its WithBudget limits
+	ErrorTypeBudgetExceeded gpa.ErrorType = "budget_exceeded"
+	// ErrorTypeOverloaded is returned when a low-priority operation is shed under load
+	ErrorTypeOverloaded gpa.ErrorType = "overloaded"
+	// ErrorTypeConflict is returned when a write conflicts with the stored state, such as an
+	// illegal state machine transition
+	ErrorTypeConflict gpa.ErrorType = "conflict"
+	// ErrorTypeQuotaExceeded is returned when a write targets a repository over its WithQuota limits
+	ErrorTypeQuotaExceeded gpa.ErrorType = "quota_exceeded"
+	// ErrorTypePayloadTooLarge is returned when an outgoing payload exceeds PayloadOptions.MaxPayload
+	ErrorTypePayloadTooLarge gpa.ErrorType = "payload_too_large"
+	// ErrorTypeRemote is returned when the handler of an RPC call failed
+	ErrorTypeRemote gpa.ErrorType = "remote"
+	// ErrorTypeMaintenance is returned when a MaintenanceGate rejects an operation during a maintenance window
+	ErrorTypeMaintenance gpa.ErrorType = "maintenance"
+)
+
+// IsReadOnlyError reports whether err was caused by read-only mode
+func IsReadOnlyError(err error) bool {
+	return gpa.IsErrorType(err, ErrorTypeReadOnly)
+}
+
+// IsBudgetExceededError reports whether err was caused by an exhausted request budget
+func IsBudgetExceededError(err error) bool {
+	return gpa.IsErrorType(err, ErrorTypeBudgetExceeded)
+}
+
+// IsOverloadedError reports whether err was caused by load shedding
+func IsOverloadedError(err error) bool {
+	return gpa.IsErrorType(err, ErrorTypeOverloaded)
+}
+
+// IsConflictError reports whether err was caused by a conflicting write
+func IsConflictError(err error) bool {
+	return gpa.IsErrorType(err, ErrorTypeConflict)
+}
+
+// IsQuotaExceededError reports whether err was caused by an exceeded repository quota
+func IsQuotaExceededError(err error) bool {
+	return gpa.IsErrorType(err, ErrorTypeQuotaExceeded)
+}
+
+// IsPayloadTooLargeError reports whether err was caused by an outgoing payload over the size limit
+func IsPayloadTooLargeError(err error) bool {
+	return gpa.IsErrorType(err, ErrorTypePayloadTooLarge)
+}
+
+// IsRemoteError reports whether err was returned by the remote handler of an RPC call
+func IsRemoteError(err error) bool {
+	return gpa.IsErrorType(err, ErrorTypeRemote)
+}
+
+// IsMaintenanceError reports whether err was caused by a maintenance window
+func IsMaintenanceError(err error) bool {
+	return gpa.IsErrorType(err, ErrorTypeMaintenance)
+}
@@ -0,0 +1,256 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Provider Event Bus
+// =====================================
+
+// EventType identifies a provider lifecycle event
+type EventType string
+
+const (
+	// EventConnect is emitted whenever a new connection to Redis is established
+	EventConnect EventType = "connect"
+	// EventDisconnect is emitted when a command fails with a network error
+	EventDisconnect EventType = "disconnect"
+	// EventFailover is emitted when Redis reports a role change (READONLY, MASTERDOWN, LOADING)
+	EventFailover EventType = "failover"
+	// EventSlowOp is emitted when a command or pipeline exceeds the slow-op threshold
+	EventSlowOp EventType = "slow_op"
+)
+
+// ProviderEvent is a structured provider lifecycle event
+type ProviderEvent struct {
+	Type     EventType
+	Time     time.Time
+	Addr     string
+	Command  string        // command name, or "pipeline(n)"; empty for connect events
+	Duration time.Duration // command duration for slow-op events
+	Err      error         // underlying error for disconnect and failover events
+}
+
+// String returns a one-line description of the event
+func (e ProviderEvent) String() string {
+	s := fmt.Sprintf("%s addr=%s", e.Type, e.Addr)
+	if e.Command != "" {
+		s += " cmd=" + e.Command
+	}
+	if e.Duration > 0 {
+		s += " duration=" + e.Duration.String()
+	}
+	if e.Err != nil {
+		s += " err=" + e.Err.Error()
+	}
+	return s
+}
+
+// EventBus fans provider events out to subscribers.
+// Callbacks run synchronously on the goroutine that issued the command and must not block.
+type EventBus struct {
+	mu      sync.RWMutex
+	nextID  int
+	subs    map[int]func(ProviderEvent)
+	dropped atomic.Uint64
+}
+
+// newEventBus creates an empty event bus
+func newEventBus() *EventBus {
+	return &EventBus{subs: make(map[int]func(ProviderEvent))}
+}
+
+// Subscribe registers a callback for all events and returns a function removing it
+func (b *EventBus) Subscribe(fn func(ProviderEvent)) (unsubscribe func()) {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = fn
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Channel returns a buffered channel receiving all events and a function closing it.
+// Events are dropped (and counted in Dropped) when the channel is full.
+func (b *EventBus) Channel(buffer int) (<-chan ProviderEvent, func()) {
+	ch := make(chan ProviderEvent, buffer)
+	var mu sync.Mutex
+	closed := false
+
+	unsubscribe := b.Subscribe(func(e ProviderEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- e:
+		default:
+			b.dropped.Add(1)
+		}
+	})
+
+	return ch, func() {
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+}
+
+// Publish delivers an event to every subscriber
+func (b *EventBus) Publish(e ProviderEvent) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.RLock()
+	subs := make([]func(ProviderEvent), 0, len(b.subs))
+	for _, fn := range b.subs {
+		subs = append(subs, fn)
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range subs {
+		fn(e)
+	}
+}
+
+// Dropped returns the number of events dropped because a channel subscriber was full
+func (b *EventBus) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// Events returns the provider's event bus
+// Example: stop := provider.Events().Subscribe(func(e gparedis.ProviderEvent) { log.Println(e) })
+func (p *Provider) Events() *EventBus {
+	p.eventsOnce.Do(func() {
+		p.events = newEventBus()
+	})
+	return p.events
+}
+
+// SetSlowOpThreshold sets the duration above which commands emit EventSlowOp (0 disables)
+func (p *Provider) SetSlowOpThreshold(threshold time.Duration) {
+	p.slowOpThreshold.Store(int64(threshold))
+}
+
+// =====================================
+// Event Hook
+// =====================================
+
+// eventStartKey stores the command start time in the hook context
+type eventStartKey struct{}
+
+// eventHook is a go-redis hook translating command outcomes into provider events
+type eventHook struct {
+	provider *Provider
+	addr     string
+	// down suppresses repeated disconnect events until the next successful connect
+	down atomic.Bool
+}
+
+// onConnect is installed as redis.Options.OnConnect
+func (h *eventHook) onConnect(ctx context.Context, cn *redis.Conn) error {
+	h.down.Store(false)
+	h.provider.Events().Publish(ProviderEvent{Type: EventConnect, Addr: h.addr})
+	return nil
+}
+
+func (h *eventHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, eventStartKey{}, time.Now()), nil
+}
+
+func (h *eventHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.observe(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (h *eventHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, eventStartKey{}, time.Now()), nil
+}
+
+func (h *eventHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var firstErr error
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			firstErr = err
+			break
+		}
+	}
+	h.observe(ctx, fmt.Sprintf("pipeline(%d)", len(cmds)), firstErr)
+	return nil
+}
+
+// observe publishes the events implied by a finished command
+func (h *eventHook) observe(ctx context.Context, name string, err error) {
+	bus := h.provider.Events()
+
+	if start, ok := ctx.Value(eventStartKey{}).(time.Time); ok {
+		threshold := time.Duration(h.provider.slowOpThreshold.Load())
+		if elapsed := time.Since(start); threshold > 0 && elapsed >= threshold {
+			bus.Publish(ProviderEvent{Type: EventSlowOp, Addr: h.addr, Command: name, Duration: elapsed})
+		}
+	}
+
+	switch {
+	case err == nil || err == redis.Nil:
+	case isFailoverError(err):
+		bus.Publish(ProviderEvent{Type: EventFailover, Addr: h.addr, Command: name, Err: err})
+	case isNetworkError(err):
+		if h.down.CompareAndSwap(false, true) {
+			bus.Publish(ProviderEvent{Type: EventDisconnect, Addr: h.addr, Command: name, Err: err})
+		}
+	}
+}
+
+// isFailoverError reports whether err signals a primary/replica role change
+func isFailoverError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, "READONLY ") || strings.HasPrefix(msg, "MASTERDOWN ") || strings.HasPrefix(msg, "LOADING ")
+}
+
+// isNetworkError reports whether err is a connection-level failure
+func isNetworkError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, redis.ErrClosed) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// installEventHook wires the event hook into client options and the client
+func (p *Provider) installEventHook(opts *redis.Options) *eventHook {
+	hook := &eventHook{provider: p, addr: opts.Addr}
+	previous := opts.OnConnect
+	opts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		if previous != nil {
+			if err := previous(ctx, cn); err != nil {
+				return err
+			}
+		}
+		return hook.onConnect(ctx, cn)
+	}
+	return hook
+}
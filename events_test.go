@@ -0,0 +1,116 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := newEventBus()
+
+	var received []ProviderEvent
+	unsubscribe := bus.Subscribe(func(e ProviderEvent) { received = append(received, e) })
+	ch, closeCh := bus.Channel(1)
+
+	bus.Publish(ProviderEvent{Type: EventConnect, Addr: "localhost:6379"})
+	bus.Publish(ProviderEvent{Type: EventSlowOp, Command: "get", Duration: time.Second})
+
+	require.Len(t, received, 2)
+	assert.False(t, received[0].Time.IsZero())
+	assert.Equal(t, EventConnect, (<-ch).Type)
+	assert.Equal(t, uint64(1), bus.Dropped())
+	assert.Equal(t, "slow_op addr= cmd=get duration=1s", received[1].String())
+
+	unsubscribe()
+	closeCh()
+	bus.Publish(ProviderEvent{Type: EventDisconnect})
+	assert.Len(t, received, 2)
+	_, open := <-ch
+	assert.False(t, open)
+}
+
+func TestEventHookClassification(t *testing.T) {
+	p := &Provider{}
+	hook := &eventHook{provider: p, addr: "primary:6379"}
+	events, stop := p.Events().Channel(10)
+	defer stop()
+
+	ctx := context.Background()
+	set := redis.NewStatusCmd(ctx, "set", "k", "v")
+	set.SetErr(errors.New("READONLY You can't write against a read only replica."))
+	require.NoError(t, hook.AfterProcess(ctx, set))
+
+	get := redis.NewStringCmd(ctx, "get", "k")
+	get.SetErr(redis.Nil)
+	require.NoError(t, hook.AfterProcess(ctx, get))
+
+	ping := redis.NewStatusCmd(ctx, "ping")
+	ping.SetErr(&netTestError{})
+	require.NoError(t, hook.AfterProcess(ctx, ping))
+	require.NoError(t, hook.AfterProcess(ctx, ping))
+
+	e := <-events
+	assert.Equal(t, EventFailover, e.Type)
+	assert.Equal(t, "set", e.Command)
+	e = <-events
+	assert.Equal(t, EventDisconnect, e.Type)
+	assert.Equal(t, "primary:6379", e.Addr)
+	assert.Empty(t, events, "repeated disconnects are suppressed until reconnect")
+
+	p.SetSlowOpThreshold(time.Nanosecond)
+	slowCtx, err := hook.BeforeProcessPipeline(ctx, nil)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	require.NoError(t, hook.AfterProcessPipeline(slowCtx, []redis.Cmder{get, get}))
+	e = <-events
+	assert.Equal(t, EventSlowOp, e.Type)
+	assert.Equal(t, "pipeline(2)", e.Command)
+	assert.GreaterOrEqual(t, e.Duration, time.Millisecond)
+}
+
+type netTestError struct{}
+
+func (netTestError) Error() string   { return "connection reset by peer" }
+func (netTestError) Timeout() bool   { return false }
+func (netTestError) Temporary() bool { return false }
+
+func TestProviderEvents(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	p := repo.provider
+	p.SetSlowOpThreshold(time.Nanosecond)
+	events, stop := p.Events().Channel(100)
+	defer stop()
+
+	ctx := context.Background()
+	// Hold the pooled connection so the next command has to dial
+	conn := p.client.Conn(ctx)
+	defer conn.Close()
+	require.NoError(t, conn.Ping(ctx).Err())
+	require.NoError(t, p.client.Ping(ctx).Err())
+
+	seen := make(map[EventType]bool)
+	for len(events) > 0 {
+		seen[(<-events).Type] = true
+	}
+	assert.True(t, seen[EventConnect])
+	assert.True(t, seen[EventSlowOp])
+}
+
+func TestDurationOption(t *testing.T) {
+	d, ok := durationOption("250ms")
+	assert.True(t, ok)
+	assert.Equal(t, 250*time.Millisecond, d)
+	d, ok = durationOption(time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, d)
+	_, ok = durationOption(42)
+	assert.False(t, ok)
+}
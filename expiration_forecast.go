@@ -0,0 +1,199 @@
+package gparedis
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Expiration Forecast
+// =====================================
+
+// ForecastOptions configures an expiration forecast
+type ForecastOptions struct {
+	// Horizon is how far ahead to look (default 30m)
+	Horizon time.Duration
+	// Bucket is the width of each forecast interval (default 1m)
+	Bucket time.Duration
+	// Pattern restricts the forecast to matching keys (relative to the prefix, default "*");
+	// ignored by the Refresher forecast
+	Pattern string
+	// SampleSize is how many keys have their TTL read; counts are scaled up from the sample
+	// when more keys match (default 1000)
+	SampleSize int
+}
+
+// ExpirationBucket is the number of keys expiring in [Start, End)
+type ExpirationBucket struct {
+	Start time.Time
+	End   time.Time
+	Keys  int64
+}
+
+// ExpirationForecast predicts how many keys under a prefix expire over the coming minutes,
+// so operators can anticipate cache-miss storms, such as a wave of entries all written
+// with the same TTL at the last deploy
+type ExpirationForecast struct {
+	Prefix string
+	At     time.Time
+	// Keys is the number of keys considered
+	Keys int64
+	// Sampled is the number of keys whose TTL was read; counts are estimates when lower than Keys
+	Sampled int
+	// Persistent estimates the keys without a TTL
+	Persistent int64
+	// Beyond estimates the keys expiring after the horizon
+	Beyond  int64
+	Buckets []ExpirationBucket
+}
+
+// Peak returns the bucket with the most expirations
+func (f *ExpirationForecast) Peak() ExpirationBucket {
+	var peak ExpirationBucket
+	for _, b := range f.Buckets {
+		if b.Keys > peak.Keys || peak.Start.IsZero() {
+			peak = b
+		}
+	}
+	return peak
+}
+
+// withDefaults fills in the default options
+func (o ForecastOptions) withDefaults() ForecastOptions {
+	if o.Horizon <= 0 {
+		o.Horizon = 30 * time.Minute
+	}
+	if o.Bucket <= 0 {
+		o.Bucket = time.Minute
+	}
+	if o.Bucket > o.Horizon {
+		o.Bucket = o.Horizon
+	}
+	if o.Pattern == "" {
+		o.Pattern = "*"
+	}
+	if o.SampleSize <= 0 {
+		o.SampleSize = 1000
+	}
+	return o
+}
+
+// newExpirationForecast creates a forecast with empty buckets covering the horizon
+func newExpirationForecast(prefix string, now time.Time, opts ForecastOptions) *ExpirationForecast {
+	f := &ExpirationForecast{Prefix: prefix, At: now}
+	for start := now; start.Before(now.Add(opts.Horizon)); start = start.Add(opts.Bucket) {
+		end := start.Add(opts.Bucket)
+		if limit := now.Add(opts.Horizon); end.After(limit) {
+			end = limit
+		}
+		f.Buckets = append(f.Buckets, ExpirationBucket{Start: start, End: end})
+	}
+	return f
+}
+
+// ExpirationForecast samples the TTLs of the keys under the prefix and estimates how many
+// expire in each Bucket over the next Horizon. Walks the keyspace with SCAN to count the
+// keys and pick the sample.
+// Example: forecast, err := sessions.ExpirationForecast(ctx, gparedis.ForecastOptions{Horizon: time.Hour, Bucket: 5 * time.Minute})
+func (r *Repository[T]) ExpirationForecast(ctx context.Context, opts ForecastOptions) (*ExpirationForecast, error) {
+	opts = opts.withDefaults()
+
+	var total int64
+	sample := make([]string, 0, opts.SampleSize)
+	err := r.scanEach(ctx, opts.Pattern, func(fullKeys []string) error {
+		for _, key := range fullKeys {
+			total++
+			if len(sample) < opts.SampleSize {
+				sample = append(sample, key)
+			} else if j := rand.Int63n(total); j < int64(opts.SampleSize) {
+				sample[j] = key
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ttls := make([]*redis.DurationCmd, len(sample))
+	_, err = r.reader(ctx).Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range sample {
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+
+	now := time.Now()
+	forecast := newExpirationForecast(r.keyPrefix, now, opts)
+	forecast.Keys = total
+	forecast.Sampled = len(sample)
+	counts := make([]int64, len(forecast.Buckets))
+	var persistent, beyond int64
+	for _, cmd := range ttls {
+		ttl := cmd.Val()
+		switch {
+		case ttl == -2:
+			// Expired since SCAN: it belongs to the first bucket
+			counts[0]++
+		case ttl < 0:
+			persistent++
+		case ttl >= opts.Horizon:
+			beyond++
+		default:
+			counts[int(ttl/opts.Bucket)]++
+		}
+	}
+
+	scale := func(n int64) int64 {
+		if forecast.Sampled == 0 || total == int64(forecast.Sampled) {
+			return n
+		}
+		return (n*total + int64(forecast.Sampled)/2) / int64(forecast.Sampled)
+	}
+	for i := range forecast.Buckets {
+		forecast.Buckets[i].Keys = scale(counts[i])
+	}
+	forecast.Persistent = scale(persistent)
+	forecast.Beyond = scale(beyond)
+	return forecast, nil
+}
+
+// ExpirationForecast counts the registered keys due to expire in each Bucket over the next
+// Horizon from the refresher's schedule. Counts are exact; keys the refresher renews in time
+// won't actually go cold.
+func (f *Refresher[T]) ExpirationForecast(ctx context.Context, opts ForecastOptions) (*ExpirationForecast, error) {
+	opts = opts.withDefaults()
+	now := time.Now()
+	forecast := newExpirationForecast(f.repo.keyPrefix, now, opts)
+	limit := now.Add(opts.Horizon)
+
+	pipe := f.repo.client.Pipeline()
+	total := pipe.ZCard(ctx, f.opts.ScheduleKey)
+	counts := make([]*redis.IntCmd, len(forecast.Buckets))
+	for i, b := range forecast.Buckets {
+		min := "(" + strconv.FormatInt(b.Start.UnixMilli(), 10)
+		if i == 0 {
+			min = "-inf"
+		}
+		counts[i] = pipe.ZCount(ctx, f.opts.ScheduleKey, min, strconv.FormatInt(b.End.UnixMilli(), 10))
+	}
+	beyond := pipe.ZCount(ctx, f.opts.ScheduleKey, "("+strconv.FormatInt(limit.UnixMilli(), 10), "+inf")
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, convertRedisError(err)
+	}
+
+	forecast.Keys = total.Val()
+	forecast.Sampled = int(total.Val())
+	for i := range forecast.Buckets {
+		forecast.Buckets[i].Keys = counts[i].Val()
+	}
+	forecast.Beyond = beyond.Val()
+	return forecast, nil
+}
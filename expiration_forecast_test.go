@@ -0,0 +1,73 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpirationForecast(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		ttl := 90 * time.Second
+		if i%3 == 0 {
+			ttl = 210 * time.Second
+		}
+		require.NoError(t, repo.SetWithTTL(ctx, fmt.Sprintf("session:%d", i), &TestValue{ID: "s"}, ttl))
+	}
+	require.NoError(t, repo.Set(ctx, "session:pinned", &TestValue{ID: "p"}))
+	require.NoError(t, repo.SetWithTTL(ctx, "session:later", &TestValue{ID: "l"}, time.Hour))
+
+	forecast, err := repo.ExpirationForecast(ctx, ForecastOptions{Pattern: "session:*", Horizon: 5 * time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, int64(32), forecast.Keys)
+	assert.Equal(t, 32, forecast.Sampled)
+	assert.Equal(t, int64(1), forecast.Persistent)
+	assert.Equal(t, int64(1), forecast.Beyond)
+	require.Len(t, forecast.Buckets, 5)
+	assert.Equal(t, int64(20), forecast.Buckets[1].Keys)
+	assert.Equal(t, int64(10), forecast.Buckets[3].Keys)
+	assert.Equal(t, forecast.Buckets[1], forecast.Peak())
+
+	// Sampled counts are scaled to the keyspace
+	forecast, err = repo.ExpirationForecast(ctx, ForecastOptions{Pattern: "session:*", Horizon: 5 * time.Minute, SampleSize: 8})
+	require.NoError(t, err)
+	assert.Equal(t, int64(32), forecast.Keys)
+	assert.Equal(t, 8, forecast.Sampled)
+	var total int64
+	for _, b := range forecast.Buckets {
+		total += b.Keys
+	}
+	assert.InDelta(t, 32, total+forecast.Persistent+forecast.Beyond, 2)
+}
+
+func TestRefresherExpirationForecast(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	loader := func(ctx context.Context, key string) (*TestValue, error) {
+		return &TestValue{ID: key}, nil
+	}
+	refresher := NewRefresher(repo, RefresherOptions{})
+	require.NoError(t, refresher.Register(ctx, "a", 30*time.Second, loader))
+	require.NoError(t, refresher.Register(ctx, "b", 30*time.Second, loader))
+	require.NoError(t, refresher.Register(ctx, "c", 150*time.Second, loader))
+	require.NoError(t, refresher.Register(ctx, "d", time.Hour, loader))
+
+	forecast, err := refresher.ExpirationForecast(ctx, ForecastOptions{Horizon: 3 * time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), forecast.Keys)
+	require.Len(t, forecast.Buckets, 3)
+	assert.Equal(t, int64(2), forecast.Buckets[0].Keys)
+	assert.Equal(t, int64(0), forecast.Buckets[1].Keys)
+	assert.Equal(t, int64(1), forecast.Buckets[2].Keys)
+	assert.Equal(t, int64(1), forecast.Beyond)
+}
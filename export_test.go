@@ -0,0 +1,82 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportWithTransformers(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, repo.SetWithTTL(ctx, "user:1", &TestValue{ID: "1", Name: "alice@example.com", Age: 30}, time.Hour))
+	require.NoError(t, repo.Set(ctx, "user:2", &TestValue{ID: "2", Name: "bob@example.com", Age: 40}))
+	require.NoError(t, repo.Set(ctx, "other:1", &TestValue{ID: "x"}))
+
+	var buf bytes.Buffer
+	n, err := repo.Export(ctx, &buf, ExportOptions{
+		Pattern:      "user:*",
+		Transformers: []Transformer{DropFields("age"), HashFields("salt", "name")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	records := map[string]ExportRecord{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record ExportRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records[record.Key] = record
+	}
+	require.Len(t, records, 2)
+	assert.Greater(t, records["user:1"].TTL, int64(59*time.Minute/time.Millisecond))
+	assert.Equal(t, int64(-1), records["user:2"].TTL)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(records["user:1"].Value, &doc))
+	assert.NotContains(t, doc, "age")
+	assert.Equal(t, "1", doc["id"])
+	assert.Len(t, doc["name"], 64)
+	assert.NotContains(t, buf.String(), "alice@example.com")
+
+	// Round trip into a clean namespace
+	require.NoError(t, repo.DeleteKey(ctx, "user:1"))
+	require.NoError(t, repo.Set(ctx, "user:2", &TestValue{ID: "2", Name: "kept"}))
+	imported, err := repo.Import(ctx, bytes.NewReader(buf.Bytes()), ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, imported)
+
+	restored, err := repo.Get(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, restored.Age)
+	assert.Len(t, restored.Name, 64)
+	ttl, err := repo.GetTTL(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute)
+
+	kept, err := repo.Get(ctx, "user:2")
+	require.NoError(t, err)
+	assert.Equal(t, "kept", kept.Name)
+}
+
+func TestFieldTransformers(t *testing.T) {
+	raw := []byte(`{"email":"a@b.c","profile":{"ssn":"123","city":"Oslo"},"count":7}`)
+	out, err := transformDocument(raw, []Transformer{
+		DropFields("profile.ssn", "missing.field"),
+		MaskFields("***", "profile.city"),
+		TransformField(func(v interface{}) interface{} { return "n/a" }, "email"),
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"email":"n/a","profile":{"city":"***"},"count":7}`, string(out))
+
+	out, err = transformDocument([]byte("42"), []Transformer{DropFields("x")})
+	require.NoError(t, err)
+	assert.Equal(t, "42", string(out))
+}
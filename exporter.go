@@ -0,0 +1,182 @@
+package gparedis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Entity Exporter
+// =====================================
+
+// ExporterOptions configures an Exporter
+type ExporterOptions[T any] struct {
+	// Pattern restricts the export to matching keys (relative to the prefix, default "*")
+	Pattern string
+	// Concurrency is the number of batches fetched in parallel (default 4)
+	Concurrency int
+	// KeysPerSecond caps the read rate to spare production traffic (0 disables)
+	KeysPerSecond float64
+	// KeyField adds the unprefixed key to every line as a member of that name (e.g. "_key");
+	// only applies to entities encoding as JSON objects
+	KeyField string
+	// Filter drops entities it returns false for
+	Filter func(key string, value *T) bool
+}
+
+// ExportResult counts the entities an export handled
+type ExportResult struct {
+	Exported int
+	// Skipped counts values that aren't entities (counters, other types) or were filtered out
+	Skipped int
+}
+
+// Exporter streams the entities under a repository prefix to JSON Lines, one deserialized
+// entity per line, for loading into data lakes and analytics tools. Batches of keys are
+// fetched concurrently, so lines come out in no particular order, and SCAN may report a
+// key twice while Redis resizes its keyspace.
+type Exporter[T any] struct {
+	repo *Repository[T]
+	opts ExporterOptions[T]
+}
+
+// NewExporter creates an exporter reading from repo
+// Example: n, err := gparedis.NewExporter(users, gparedis.ExporterOptions[User]{KeysPerSecond: 5000}).Export(ctx, file)
+func NewExporter[T any](repo *Repository[T], opts ExporterOptions[T]) *Exporter[T] {
+	if opts.Pattern == "" {
+		opts.Pattern = "*"
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	return &Exporter[T]{repo: repo, opts: opts}
+}
+
+// Export writes every entity to w and stops at the first error
+func (e *Exporter[T]) Export(ctx context.Context, w io.Writer) (ExportResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		result   ExportResult
+		firstErr error
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
+	buf := bufio.NewWriter(w)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	limiter := newThrottle(ThrottleOptions{CommandsPerSecond: e.opts.KeysPerSecond, Burst: scanBatchSize})
+	batches := make(chan []string)
+	for i := 0; i < e.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for keys := range batches {
+				if err := limiter.wait(ctx, len(keys)); err != nil {
+					fail(err)
+					continue
+				}
+				lines, skipped, err := e.fetch(ctx, keys)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				mu.Lock()
+				for _, line := range lines {
+					if _, err := buf.Write(line); err != nil && firstErr == nil {
+						firstErr = gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to write export line", err)
+					}
+				}
+				result.Exported += len(lines)
+				result.Skipped += skipped
+				mu.Unlock()
+			}
+		}()
+	}
+
+	scanErr := e.repo.scanEach(ctx, e.opts.Pattern, func(keys []string) error {
+		select {
+		case batches <- keys:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(batches)
+	wg.Wait()
+
+	if firstErr != nil {
+		return result, firstErr
+	}
+	if scanErr != nil {
+		return result, convertRedisError(scanErr)
+	}
+	if err := buf.Flush(); err != nil {
+		return result, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to flush export", err)
+	}
+	return result, nil
+}
+
+// fetch reads a batch of full keys and renders the export lines
+func (e *Exporter[T]) fetch(ctx context.Context, fullKeys []string) ([][]byte, int, error) {
+	r := e.repo
+	cmds := make([]*redis.StringCmd, len(fullKeys))
+	_, err := r.reader(ctx).Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range fullKeys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil && !isWrongTypeError(err) {
+		return nil, 0, convertRedisError(err)
+	}
+
+	lines := make([][]byte, 0, len(fullKeys))
+	skipped := 0
+	for i, fullKey := range fullKeys {
+		raw, err := cmds[i].Bytes()
+		if err != nil {
+			// Expired since SCAN or not a string value
+			skipped++
+			continue
+		}
+		value, err := r.decode(raw)
+		if err != nil {
+			skipped++
+			continue
+		}
+		key := r.trimKey(fullKey)
+		if e.opts.Filter != nil && !e.opts.Filter(key, value) {
+			skipped++
+			continue
+		}
+		line, err := json.Marshal(value)
+		if err != nil {
+			return nil, 0, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to encode "+key, err)
+		}
+		if e.opts.KeyField != "" && len(line) > 1 && line[0] == '{' {
+			member, _ := json.Marshal(map[string]string{e.opts.KeyField: key})
+			if line[1] == '}' {
+				line = member
+			} else {
+				line = append(append(member[:len(member)-1:len(member)-1], ','), line[1:]...)
+			}
+		}
+		lines = append(lines, append(line, '\n'))
+	}
+	return lines, skipped, nil
+}
@@ -0,0 +1,82 @@
+package gparedis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	pairs := make(map[string]*TestValue)
+	for i := 0; i < 250; i++ {
+		id := fmt.Sprintf("%03d", i)
+		pairs["user:"+id] = &TestValue{ID: id, Name: "user " + id, Age: i}
+	}
+	require.NoError(t, repo.MSet(ctx, pairs))
+	_, err := repo.Increment(ctx, "user:counter", 1)
+	require.NoError(t, err)
+	require.NoError(t, repo.Set(ctx, "order:1", &TestValue{ID: "o1"}))
+
+	var out bytes.Buffer
+	result, err := NewExporter(repo, ExporterOptions[TestValue]{
+		Pattern:     "user:*",
+		Concurrency: 3,
+		KeyField:    "_key",
+		Filter:      func(key string, v *TestValue) bool { return v.Age != 7 },
+	}).Export(ctx, &out)
+	require.NoError(t, err)
+	assert.Equal(t, ExportResult{Exported: 249, Skipped: 2}, result, "the counter and the filtered entity are skipped")
+
+	var keys []string
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var line struct {
+			Key string `json:"_key"`
+			TestValue
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		assert.Equal(t, "user:"+line.ID, line.Key)
+		assert.Equal(t, *pairs[line.Key], line.TestValue)
+		keys = append(keys, line.Key)
+	}
+	sort.Strings(keys)
+	assert.Len(t, keys, 249)
+	assert.Equal(t, "user:000", keys[0])
+}
+
+func TestExporterRateLimit(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	pairs := make(map[string]*TestValue)
+	for i := 0; i < 150; i++ {
+		pairs[fmt.Sprint(i)] = &TestValue{ID: fmt.Sprint(i)}
+	}
+	require.NoError(t, repo.MSet(ctx, pairs))
+
+	// 100 keys of burst, then 50 more at 500 keys/s
+	start := time.Now()
+	var out bytes.Buffer
+	result, err := NewExporter(repo, ExporterOptions[TestValue]{KeysPerSecond: 500}).Export(ctx, &out)
+	require.NoError(t, err)
+	assert.Equal(t, 150, result.Exported)
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = NewExporter(repo, ExporterOptions[TestValue]{}).Export(cancelled, &out)
+	assert.Error(t, err)
+}
@@ -0,0 +1,217 @@
+package gparedis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Faceted Index Filters
+// =====================================
+
+// Filter operators
+const (
+	filterEq  = "eq"
+	filterAll = "all"
+	filterAny = "any"
+)
+
+// IndexFilter selects keys by their secondary index values, evaluated server-side over the
+// tag sets. Build filters with IndexEq, AllOf and AnyOf.
+type IndexFilter struct {
+	op       string
+	index    string
+	values   []interface{}
+	children []IndexFilter
+}
+
+// IndexEq matches keys whose index has the given value; composite indexes take one value
+// per field in declaration order
+func IndexEq(index string, values ...interface{}) IndexFilter {
+	return IndexFilter{op: filterEq, index: index, values: values}
+}
+
+// AllOf matches keys matching every filter
+// Example: gparedis.AllOf(gparedis.IndexEq("status", "active"), gparedis.IndexEq("plan", "premium"), gparedis.IndexEq("region", "EU"))
+func AllOf(filters ...IndexFilter) IndexFilter {
+	return IndexFilter{op: filterAll, children: filters}
+}
+
+// AnyOf matches keys matching at least one filter
+func AnyOf(filters ...IndexFilter) IndexFilter {
+	return IndexFilter{op: filterAny, children: filters}
+}
+
+// FilterOptions configures one Filter call
+type FilterOptions struct {
+	// Offset is the number of matching keys skipped
+	Offset int64
+	// Limit is the number of keys returned (default 100)
+	Limit int64
+	// CacheTTL keeps computed results so following pages and identical filters reuse them
+	// (default 30s). Writes within that window may not be reflected.
+	CacheTTL time.Duration
+}
+
+// FilterPage is one page of filtered entities
+type FilterPage[T any] struct {
+	Keys  []string
+	Items []*T
+	// Total is the number of keys matching the filter
+	Total int64
+}
+
+// FilterKeys returns one page of the keys matching filter, sorted, and the total number of
+// matches. Intersections and unions are stored in temporary sorted sets with ZINTERSTORE
+// and ZUNIONSTORE (which read tag sets directly), scored equally so pages follow key order.
+// In cluster mode every tag set involved must share a slot.
+// Example: keys, total, err := users.FilterKeys(ctx, gparedis.AllOf(gparedis.IndexEq("status", "active"), gparedis.IndexEq("region", "EU")), gparedis.FilterOptions{Limit: 50})
+func (r *Repository[T]) FilterKeys(ctx context.Context, filter IndexFilter, opts FilterOptions) ([]string, int64, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 100
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = 30 * time.Second
+	}
+	if opts.Offset < 0 {
+		return nil, 0, gpa.NewError(gpa.ErrorTypeInvalidArgument, "negative filter offset")
+	}
+	if filter.op == filterEq {
+		// Leaves are plain sets; materialize them for ordered paging
+		filter = AnyOf(filter)
+	}
+	result, ok, err := r.resolveFilter(ctx, filter, opts.CacheTTL)
+	if err != nil || !ok {
+		return []string{}, 0, err
+	}
+
+	var total *redis.IntCmd
+	var keys *redis.StringSliceCmd
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		total = pipe.ZCard(ctx, result)
+		keys = pipe.ZRange(ctx, result, opts.Offset, opts.Offset+opts.Limit-1)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, convertRedisError(err)
+	}
+	if err := r.authorizeKeys(ctx, AccessRead, keys.Val()...); err != nil {
+		return nil, 0, err
+	}
+	return keys.Val(), total.Val(), nil
+}
+
+// Filter returns one page of the entities matching filter, ordered by key
+// Example: page, err := users.Filter(ctx, gparedis.AnyOf(gparedis.IndexEq("plan", "pro"), gparedis.IndexEq("plan", "team")), gparedis.FilterOptions{})
+func (r *Repository[T]) Filter(ctx context.Context, filter IndexFilter, opts FilterOptions) (*FilterPage[T], error) {
+	keys, total, err := r.FilterKeys(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadPage(ctx, keys, total)
+}
+
+// loadPage loads the entities of a page of keys, skipping keys deleted meanwhile
+func (r *Repository[T]) loadPage(ctx context.Context, keys []string, total int64) (*FilterPage[T], error) {
+	found, err := r.MGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	page := &FilterPage[T]{Keys: []string{}, Items: []*T{}, Total: total}
+	for _, key := range keys {
+		if entity, ok := found[key]; ok {
+			page.Keys = append(page.Keys, key)
+			page.Items = append(page.Items, entity)
+		}
+	}
+	return page, nil
+}
+
+// resolveFilter returns the key holding the matches of filter, storing intersections and
+// unions as needed; false when nothing can match
+func (r *Repository[T]) resolveFilter(ctx context.Context, filter IndexFilter, ttl time.Duration) (string, bool, error) {
+	if filter.op == filterEq {
+		return r.lookupTagSet(filter.index, filter.values)
+	}
+	if filter.op != filterAll && filter.op != filterAny {
+		return "", false, gpa.NewError(gpa.ErrorTypeInvalidArgument, "empty index filter")
+	}
+	if len(filter.children) == 0 {
+		return "", false, gpa.NewError(gpa.ErrorTypeInvalidArgument, "index filter without conditions")
+	}
+
+	sum := sha1.Sum([]byte(canonicalFilter(filter)))
+	result := indexKeyPrefix + r.keyPrefix + "~filter:" + hex.EncodeToString(sum[:])
+	if n, err := r.client.Exists(ctx, result).Result(); err != nil {
+		return "", false, convertRedisError(err)
+	} else if n > 0 {
+		return result, true, nil
+	}
+
+	var err error
+	var inputs []string
+	for _, child := range filter.children {
+		key, ok, err := r.resolveFilter(ctx, child, ttl)
+		if err != nil {
+			return "", false, err
+		}
+		if !ok {
+			if filter.op == filterAll {
+				return "", false, nil
+			}
+			continue
+		}
+		inputs = append(inputs, key)
+	}
+	if len(inputs) == 0 {
+		return "", false, nil
+	}
+
+	// Zero weights score every member equally, so ranges follow member order
+	weights := make([]float64, len(inputs))
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		store := &redis.ZStore{Keys: inputs, Weights: weights}
+		if filter.op == filterAll {
+			pipe.ZInterStore(ctx, result, store)
+		} else {
+			pipe.ZUnionStore(ctx, result, store)
+		}
+		pipe.PExpire(ctx, result, ttl)
+		return nil
+	})
+	if err != nil {
+		return "", false, convertRedisError(err)
+	}
+	return result, true, nil
+}
+
+// canonicalFilter renders filter so equivalent filters share cached results
+func canonicalFilter(filter IndexFilter) string {
+	if filter.op == filterEq {
+		parts := make([]string, len(filter.values))
+		for i, value := range filter.values {
+			part, ok := normalizeIndexValue(reflect.ValueOf(value))
+			if !ok {
+				part = "<nil>"
+			}
+			parts[i] = strconv.Quote(part)
+		}
+		return strconv.Quote(filter.index) + "=" + strings.Join(parts, ",")
+	}
+	children := make([]string, len(filter.children))
+	for i, child := range filter.children {
+		children[i] = canonicalFilter(child)
+	}
+	sort.Strings(children)
+	return fmt.Sprintf("%s(%s)", filter.op, strings.Join(children, ","))
+}
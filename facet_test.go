@@ -0,0 +1,105 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type facetedAccount struct {
+	ID     string `json:"id"`
+	Status string `json:"status" gpaindex:"status"`
+	Plan   string `json:"plan" gpaindex:"plan"`
+	Region string `json:"region" gpaindex:"region"`
+}
+
+func TestIndexFilters(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[facetedAccount](base.provider, base.client, "account:")
+	require.NoError(t, repo.MSet(ctx, map[string]*facetedAccount{
+		"1": {ID: "1", Status: "active", Plan: "premium", Region: "EU"},
+		"2": {ID: "2", Status: "active", Plan: "free", Region: "EU"},
+		"3": {ID: "3", Status: "active", Plan: "premium", Region: "US"},
+		"4": {ID: "4", Status: "banned", Plan: "premium", Region: "EU"},
+		"5": {ID: "5", Status: "active", Plan: "team", Region: "EU"},
+	}))
+
+	keys, total, err := repo.FilterKeys(ctx, AllOf(IndexEq("status", "active"), IndexEq("plan", "premium"), IndexEq("region", "EU")), FilterOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, keys)
+	assert.Equal(t, int64(1), total)
+
+	keys, total, err = repo.FilterKeys(ctx, AnyOf(IndexEq("plan", "free"), IndexEq("plan", "team")), FilterOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "5"}, keys)
+	assert.Equal(t, int64(2), total)
+
+	// Nested filters and single conditions
+	page, err := repo.Filter(ctx, AllOf(IndexEq("region", "EU"), AnyOf(IndexEq("plan", "premium"), IndexEq("plan", "team"))), FilterOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "4", "5"}, page.Keys)
+	require.Len(t, page.Items, 3)
+	assert.Equal(t, "4", page.Items[1].ID)
+	keys, _, err = repo.FilterKeys(ctx, IndexEq("status", "banned"), FilterOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4"}, keys)
+
+	// Pages follow key order
+	keys, total, err = repo.FilterKeys(ctx, AllOf(IndexEq("status", "active")), FilterOptions{Offset: 1, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "3"}, keys)
+	assert.Equal(t, int64(4), total)
+
+	// Conditions that can't match
+	keys, total, err = repo.FilterKeys(ctx, AllOf(IndexEq("status", "active"), IndexEq("plan", "enterprise")), FilterOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.Zero(t, total)
+	keys, _, err = repo.FilterKeys(ctx, AnyOf(IndexEq("plan", nil), IndexEq("plan", "free")), FilterOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, keys)
+
+	_, _, err = repo.FilterKeys(ctx, AllOf(IndexEq("missing", "x")), FilterOptions{})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+	_, _, err = repo.FilterKeys(ctx, AllOf(), FilterOptions{})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestIndexFilterCache(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[facetedAccount](base.provider, base.client, "account:")
+	require.NoError(t, repo.Set(ctx, "1", &facetedAccount{ID: "1", Status: "active", Plan: "pro", Region: "EU"}))
+
+	filter := AllOf(IndexEq("status", "active"), IndexEq("region", "EU"))
+	keys, _, err := repo.FilterKeys(ctx, filter, FilterOptions{CacheTTL: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, keys)
+
+	cached, err := base.client.Keys(ctx, indexKeyPrefix+"account:~filter:*").Result()
+	require.NoError(t, err)
+	require.Len(t, cached, 1)
+	ttl, err := base.client.PTTL(ctx, cached[0]).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 30*time.Second)
+
+	// Equivalent filters reuse the cached result until it expires
+	require.NoError(t, repo.Set(ctx, "2", &facetedAccount{ID: "2", Status: "active", Plan: "pro", Region: "EU"}))
+	keys, _, err = repo.FilterKeys(ctx, AllOf(IndexEq("region", "EU"), IndexEq("status", "active")), FilterOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, keys)
+
+	require.NoError(t, base.client.Del(ctx, cached[0]).Err())
+	keys, _, err = repo.FilterKeys(ctx, filter, FilterOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, keys)
+}
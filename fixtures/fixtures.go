@@ -0,0 +1,174 @@
+// Package fixtures seeds gparedis repositories from YAML or JSON files for integration
+// tests and removes the seeded keys afterwards.
+//
+// A fixture file lists entries, optionally under a key prefix and with a default TTL:
+//
+//	prefix: "team-a:"   # prepended to every key, after the repository's own prefix
+//	ttl: 1h             # default TTL of the entries (none when omitted)
+//	entries:
+//	  - key: "1"
+//	    value: {id: "1", name: Ann}
+//	  - key: "2"
+//	    ttl: 10m
+//	    value: {id: "2", name: Bob}
+//
+// Values are decoded with the entity's JSON tags, exactly like the repository stores them.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/lemmego/gparedis"
+	"gopkg.in/yaml.v3"
+)
+
+// file is the decoded form of a fixture file
+type file struct {
+	Prefix  string  `json:"prefix" yaml:"prefix"`
+	TTL     string  `json:"ttl" yaml:"ttl"`
+	Entries []entry `json:"entries" yaml:"entries"`
+}
+
+// entry is one fixture value
+type entry struct {
+	Key   string      `json:"key" yaml:"key"`
+	TTL   string      `json:"ttl" yaml:"ttl"`
+	Value interface{} `json:"value" yaml:"value"`
+}
+
+// Set is a loaded group of fixtures
+type Set[T any] struct {
+	repo *gparedis.Repository[T]
+	keys []string
+}
+
+// Load writes the entries of every file into repo. Files ending in .json are read as JSON,
+// all others as YAML. Entries already written stay in place when a later one fails; call
+// Teardown on the returned Set to remove them.
+// Example: users, err := fixtures.Load(ctx, userRepo, "testdata/users.yaml")
+func Load[T any](ctx context.Context, repo *gparedis.Repository[T], paths ...string) (*Set[T], error) {
+	set := &Set[T]{repo: repo}
+	for _, path := range paths {
+		f, err := readFile(path)
+		if err != nil {
+			return set, err
+		}
+		defaultTTL, err := parseTTL(path, f.TTL)
+		if err != nil {
+			return set, err
+		}
+		for _, e := range f.Entries {
+			if e.Key == "" {
+				return set, gpa.NewError(gpa.ErrorTypeInvalidArgument, path+": fixture entry without key")
+			}
+			ttl := defaultTTL
+			if e.TTL != "" {
+				if ttl, err = parseTTL(path, e.TTL); err != nil {
+					return set, err
+				}
+			}
+			value, err := decodeValue[T](path, e)
+			if err != nil {
+				return set, err
+			}
+			key := f.Prefix + e.Key
+			if ttl > 0 {
+				err = repo.SetWithTTL(ctx, key, value, ttl)
+			} else {
+				err = repo.Set(ctx, key, value)
+			}
+			if err != nil {
+				return set, err
+			}
+			set.keys = append(set.keys, key)
+		}
+	}
+	return set, nil
+}
+
+// LoadT loads fixtures for a test, failing it on errors and tearing the fixtures down when
+// the test finishes
+// Example: fixtures.LoadT(t, userRepo, "testdata/users.yaml")
+func LoadT[T any](t testing.TB, repo *gparedis.Repository[T], paths ...string) *Set[T] {
+	t.Helper()
+	set, err := Load(context.Background(), repo, paths...)
+	t.Cleanup(func() {
+		if err := set.Teardown(context.Background()); err != nil {
+			t.Errorf("fixtures teardown: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("fixtures: %v", err)
+	}
+	return set
+}
+
+// Keys returns the keys written, relative to the repository's prefix
+func (s *Set[T]) Keys() []string {
+	return append([]string(nil), s.keys...)
+}
+
+// Teardown deletes every key the set wrote
+func (s *Set[T]) Teardown(ctx context.Context) error {
+	if len(s.keys) == 0 {
+		return nil
+	}
+	if _, err := s.repo.MDelete(ctx, s.keys); err != nil {
+		return err
+	}
+	s.keys = nil
+	return nil
+}
+
+// readFile decodes a fixture file by its extension
+func readFile(path string) (*file, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeInvalidArgument, "failed to read fixture file "+path, err)
+	}
+	var f file
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&f)
+	} else {
+		err = yaml.Unmarshal(data, &f)
+	}
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid fixture file "+path, err)
+	}
+	return &f, nil
+}
+
+// parseTTL parses a duration such as "10m", where empty means no TTL
+func parseTTL(path, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl < 0 {
+		return 0, gpa.NewError(gpa.ErrorTypeInvalidArgument, path+": invalid fixture TTL "+s)
+	}
+	return ttl, nil
+}
+
+// decodeValue converts an entry's value to T through JSON, so the entity's JSON tags apply
+func decodeValue[T any](path string, e entry) (*T, error) {
+	data, err := json.Marshal(e.Value)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, path+": invalid fixture value for "+e.Key, err)
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, path+": fixture value for "+e.Key+" doesn't match the entity", err)
+	}
+	return &value, nil
+}
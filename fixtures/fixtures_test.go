@@ -0,0 +1,93 @@
+package fixtures
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/lemmego/gparedis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func setupRepository(t *testing.T) *gparedis.Repository[user] {
+	redisURL := os.Getenv("REDIS_TEST_URL")
+	if redisURL == "" {
+		redisURL = "redis://localhost:6379"
+	}
+	provider, err := gparedis.NewProvider(gpa.Config{Driver: "redis", ConnectionURL: redisURL})
+	if err != nil {
+		t.Skipf("Skipping Redis tests: %v", err)
+	}
+	t.Cleanup(func() { provider.Close() })
+	return gparedis.NewRepository[user](provider, provider.Client().(*redis.Client), "fixtures:user:")
+}
+
+func TestLoad(t *testing.T) {
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	set, err := Load(ctx, repo, "testdata/users.yaml", "testdata/users.json")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team-a:1", "team-a:2", "3"}, set.Keys())
+
+	ann, err := repo.Get(ctx, "team-a:1")
+	require.NoError(t, err)
+	assert.Equal(t, user{ID: "1", Name: "Ann", Age: 31}, *ann)
+	ttl, err := repo.GetTTL(ctx, "team-a:2")
+	require.NoError(t, err)
+	assert.InDelta(t, (10 * time.Minute).Seconds(), ttl.Seconds(), 5, "entry TTLs override the file's")
+	ttl, err = repo.GetTTL(ctx, "team-a:1")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), ttl.Seconds(), 5)
+	cid, err := repo.Get(ctx, "3")
+	require.NoError(t, err)
+	assert.Equal(t, 27, cid.Age)
+
+	require.NoError(t, set.Teardown(ctx))
+	for _, key := range []string{"team-a:1", "team-a:2", "3"} {
+		exists, err := repo.KeyExists(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, exists, key)
+	}
+}
+
+func TestLoadT(t *testing.T) {
+	repo := setupRepository(t)
+	t.Run("seeded", func(t *testing.T) {
+		LoadT(t, repo, "testdata/users.json")
+		exists, err := repo.KeyExists(context.Background(), "3")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+	exists, err := repo.KeyExists(context.Background(), "3")
+	require.NoError(t, err)
+	assert.False(t, exists, "fixtures are torn down when the test ends")
+}
+
+func TestLoadErrors(t *testing.T) {
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	_, err := Load(ctx, repo, "testdata/missing.yaml")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+
+	bad := t.TempDir() + "/bad.yaml"
+	require.NoError(t, os.WriteFile(bad, []byte("entries:\n  - key: x\n    ttl: soon\n    value: {}\n"), 0o644))
+	_, err = Load(ctx, repo, bad)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+
+	mismatched := t.TempDir() + "/mismatched.json"
+	require.NoError(t, os.WriteFile(mismatched, []byte(`{"entries": [{"key": "x", "value": {"age": "old"}}]}`), 0o644))
+	_, err = Load(ctx, repo, mismatched)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeSerialization))
+}
@@ -0,0 +1,130 @@
+package gparedis
+
+import (
+	"math"
+	"strings"
+)
+
+// =====================================
+// Cluster Hash Slots
+// =====================================
+
+// ClusterSlots is the number of hash slots in a Redis Cluster
+const ClusterSlots = 16384
+
+// KeySlot returns the Redis Cluster hash slot for key, honoring {hash tags}. It matches
+// CLUSTER KEYSLOT, so applications can pre-compute where a key lives.
+// Example: slot := gparedis.KeySlot("user:{42}:profile")
+func KeySlot(key string) int {
+	return int(crc16(HashTagOf(key))) % ClusterSlots
+}
+
+// HashTagOf returns the part of key that is hashed: the content of the first
+// non-empty {...} section if present, otherwise the whole key
+func HashTagOf(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+// crc16 implements CRC16-CCITT (XModem) as used by Redis Cluster
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// HashTag wraps id in braces so every key containing it maps to the same cluster slot.
+// Example: userRepo.Set(ctx, gparedis.HashTag(userID), user) and
+// sessionRepo.Set(ctx, gparedis.HashTag(userID)+":"+sessionID, session) share a slot.
+func HashTag(id string) string {
+	return "{" + id + "}"
+}
+
+// SameSlot reports whether all keys map to one hash slot, i.e. can be used together in a
+// multi-key command or transaction in cluster mode
+func SameSlot(keys ...string) bool {
+	for i := 1; i < len(keys); i++ {
+		if KeySlot(keys[i]) != KeySlot(keys[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// GroupBySlot splits keys by hash slot, keeping their order within each group
+func GroupBySlot(keys []string) map[int][]string {
+	groups := make(map[int][]string)
+	for _, key := range keys {
+		slot := KeySlot(key)
+		groups[slot] = append(groups[slot], key)
+	}
+	return groups
+}
+
+// SlotShard maps a slot to one of shards contiguous, evenly sized slot ranges, the layout
+// redis-cli --cluster create assigns. Useful for client-side sharding that stays aligned
+// with a cluster of the same size.
+func SlotShard(slot, shards int) int {
+	if shards <= 1 {
+		return 0
+	}
+	shard := slot * shards / ClusterSlots
+	if shard > 0 && shardEnd(shard-1, shards) >= slot {
+		return shard - 1
+	}
+	if shard < shards-1 && shardEnd(shard, shards) < slot {
+		return shard + 1
+	}
+	return shard
+}
+
+// shardEnd returns the last slot of shard i, rounding range boundaries like redis-cli
+func shardEnd(i, shards int) int {
+	if i >= shards-1 {
+		return ClusterSlots - 1
+	}
+	return int(math.Round(float64(i+1)*ClusterSlots/float64(shards) - 1))
+}
+
+// KeyShard returns the shard of key among shards, based on its hash slot.
+// Slot-based routing keeps keys sharing a hash tag on one shard, and changing the number
+// of shards only moves the slots at the edges of each range.
+// Example: shard := gparedis.KeyShard(gparedis.HashTag(tenantID), len(workers))
+func KeyShard(key string, shards int) int {
+	return SlotShard(KeySlot(key), shards)
+}
+
+// SlotRange is a contiguous, inclusive range of hash slots
+type SlotRange struct {
+	Start, End int
+}
+
+// ShardSlotRanges returns the slot range of each shard under SlotShard
+func ShardSlotRanges(shards int) []SlotRange {
+	if shards < 1 {
+		shards = 1
+	}
+	ranges := make([]SlotRange, shards)
+	start := 0
+	for i := range ranges {
+		ranges[i] = SlotRange{Start: start, End: shardEnd(i, shards)}
+		start = ranges[i].End + 1
+	}
+	return ranges
+}
@@ -0,0 +1,43 @@
+package gparedis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashSlot(t *testing.T) {
+	assert.Equal(t, uint16(0x31C3), crc16("123456789"))
+	assert.Equal(t, 12182, KeySlot("foo"))
+	assert.Equal(t, 5061, KeySlot("bar"))
+
+	// Keys sharing a hash tag land on the same slot
+	assert.Equal(t, KeySlot("{user1000}.following"), KeySlot("{user1000}.followers"))
+	assert.Equal(t, KeySlot("user:"+HashTag("42")), KeySlot("session:"+HashTag("42")+":web"))
+
+	// Empty or unterminated tags hash the whole key
+	assert.Equal(t, "foo{}bar", HashTagOf("foo{}bar"))
+	assert.Equal(t, "foo{bar", HashTagOf("foo{bar"))
+	assert.Equal(t, "bar", HashTagOf("foo{bar}{zap}"))
+}
+
+func TestSlotSharding(t *testing.T) {
+	assert.True(t, SameSlot("{user1000}.following", "{user1000}.followers"))
+	assert.False(t, SameSlot("foo", "bar"))
+	assert.True(t, SameSlot())
+
+	groups := GroupBySlot([]string{"foo", "{foo}:a", "bar"})
+	assert.Equal(t, []string{"foo", "{foo}:a"}, groups[12182])
+	assert.Equal(t, []string{"bar"}, groups[5061])
+
+	// Three shards split the slots like redis-cli --cluster create
+	assert.Equal(t, []SlotRange{{0, 5460}, {5461, 10922}, {10923, 16383}}, ShardSlotRanges(3))
+	for _, r := range ShardSlotRanges(3) {
+		assert.Equal(t, SlotShard(r.Start, 3), SlotShard(r.End, 3))
+	}
+	assert.Equal(t, 0, SlotShard(5460, 3))
+	assert.Equal(t, 1, SlotShard(5461, 3))
+	assert.Equal(t, 2, KeyShard("foo", 3))
+	assert.Equal(t, 0, KeyShard("foo", 1))
+	assert.Equal(t, KeyShard("{tenant}:a", 7), KeyShard("{tenant}:b", 7))
+}
@@ -0,0 +1,412 @@
+package gparedis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Bulk Importer
+// =====================================
+
+// ImportFormat selects how an Importer reads its input
+type ImportFormat string
+
+const (
+	// ImportFormatJSONLines reads one JSON entity per line
+	ImportFormatJSONLines ImportFormat = "jsonl"
+	// ImportFormatCSV reads a header row of field names (json or Go names) followed by one
+	// entity per row. Cells of string fields are taken literally, other cells as JSON
+	// (numbers, booleans, arrays, objects); empty cells leave the field unset.
+	ImportFormatCSV ImportFormat = "csv"
+)
+
+// ImportConflict decides what happens to records whose key already exists
+type ImportConflict int
+
+const (
+	// ImportSkip leaves existing keys untouched
+	ImportSkip ImportConflict = iota
+	// ImportOverwrite replaces existing keys
+	ImportOverwrite
+	// ImportFail stops the import with ErrorTypeDuplicate at the first existing key
+	ImportFail
+)
+
+// ImporterOptions configures an Importer
+type ImporterOptions[T any] struct {
+	// Format is the input format (default ImportFormatJSONLines)
+	Format ImportFormat
+	// Key returns the repository key of an entity (required)
+	Key func(value *T) string
+	// TTL applies to every imported value (0 for none)
+	TTL time.Duration
+	// BatchSize is how many records are written per pipeline (default 100)
+	BatchSize int
+	// OnConflict is the policy for existing keys (default ImportSkip)
+	OnConflict ImportConflict
+	// Validate rejects a record before it's written; record is the 1-based record number
+	Validate func(record int, value *T) error
+	// SkipInvalid counts and skips records that can't be decoded or fail validation
+	// instead of stopping the import
+	SkipInvalid bool
+	// OnProgress is called after every batch
+	OnProgress func(ImportProgress)
+}
+
+// ImportProgress counts the records an import has handled so far
+type ImportProgress struct {
+	Read    int
+	Written int
+	// Skipped counts records whose key existed under ImportSkip
+	Skipped int
+	// Invalid counts records skipped under SkipInvalid
+	Invalid int
+}
+
+// Importer streams entities from JSON Lines or CSV into a repository with pipelined
+// writes. Values go through the repository's encoding, retention, indexes and audit log;
+// BeforeCreate hooks run as part of validation.
+type Importer[T any] struct {
+	repo *Repository[T]
+	opts ImporterOptions[T]
+}
+
+// importRecord is a decoded record waiting in a batch
+type importRecord[T any] struct {
+	key   string
+	value *T
+	data  []byte
+}
+
+// NewImporter creates an importer writing into repo
+// Example:
+//
+//	importer := gparedis.NewImporter(users, gparedis.ImporterOptions[User]{
+//		Format: gparedis.ImportFormatCSV,
+//		Key:    func(u *User) string { return u.ID },
+//	})
+//	progress, err := importer.Import(ctx, file)
+func NewImporter[T any](repo *Repository[T], opts ImporterOptions[T]) *Importer[T] {
+	if opts.Format == "" {
+		opts.Format = ImportFormatJSONLines
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = scanBatchSize
+	}
+	return &Importer[T]{repo: repo, opts: opts}
+}
+
+// Import reads rd to the end and writes its records. The returned progress is accurate
+// when an error stops the import midway; records already written stay written.
+func (im *Importer[T]) Import(ctx context.Context, rd io.Reader) (ImportProgress, error) {
+	var progress ImportProgress
+	if im.opts.Key == nil {
+		return progress, gpa.NewError(gpa.ErrorTypeInvalidArgument, "importer needs a Key function")
+	}
+	next, err := im.reader(rd)
+	if err != nil {
+		return progress, err
+	}
+
+	batch := make([]importRecord[T], 0, im.opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		written, skipped, err := im.write(ctx, batch)
+		progress.Written += written
+		progress.Skipped += skipped
+		batch = batch[:0]
+		if err != nil {
+			return err
+		}
+		if im.opts.OnProgress != nil {
+			im.opts.OnProgress(progress)
+		}
+		return nil
+	}
+
+	for {
+		raw, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return progress, err
+		}
+		progress.Read++
+		record, err := im.decode(ctx, progress.Read, raw)
+		if err != nil {
+			if im.opts.SkipInvalid {
+				progress.Invalid++
+				continue
+			}
+			return progress, err
+		}
+		if batch = append(batch, record); len(batch) >= im.opts.BatchSize {
+			if err := flush(); err != nil {
+				return progress, err
+			}
+		}
+	}
+	return progress, flush()
+}
+
+// reader returns a function yielding each record's JSON object
+func (im *Importer[T]) reader(rd io.Reader) (func() ([]byte, error), error) {
+	switch im.opts.Format {
+	case ImportFormatJSONLines:
+		scanner := bufio.NewScanner(rd)
+		scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		return func() ([]byte, error) {
+			for scanner.Scan() {
+				if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+					return line, nil
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to read import input", err)
+			}
+			return nil, io.EOF
+		}, nil
+	case ImportFormatCSV:
+		cr := csv.NewReader(rd)
+		header, err := cr.Read()
+		if err == io.EOF {
+			return func() ([]byte, error) { return nil, io.EOF }, nil
+		}
+		if err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to read CSV header", err)
+		}
+		columns, err := csvColumns[T](header)
+		if err != nil {
+			return nil, err
+		}
+		return func() ([]byte, error) {
+			row, err := cr.Read()
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			if err != nil {
+				return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to read CSV row", err)
+			}
+			return csvRowJSON(columns, row), nil
+		}, nil
+	default:
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("unsupported import format: %s", im.opts.Format))
+	}
+}
+
+// decode turns a record into an entity and runs validation
+func (im *Importer[T]) decode(ctx context.Context, n int, raw []byte) (importRecord[T], error) {
+	var value T
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return importRecord[T]{}, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, fmt.Sprintf("invalid import record %d", n), err)
+	}
+	if hook, ok := any(&value).(gpa.BeforeCreateHook); ok {
+		if err := hook.BeforeCreate(ctx); err != nil {
+			return importRecord[T]{}, gpa.NewErrorWithCause(gpa.ErrorTypeValidation, fmt.Sprintf("before create hook failed on record %d", n), err)
+		}
+	}
+	if im.opts.Validate != nil {
+		if err := im.opts.Validate(n, &value); err != nil {
+			return importRecord[T]{}, gpa.NewErrorWithCause(gpa.ErrorTypeValidation, fmt.Sprintf("import record %d is invalid", n), err)
+		}
+	}
+	key := im.opts.Key(&value)
+	if key == "" {
+		return importRecord[T]{}, gpa.NewError(gpa.ErrorTypeValidation, fmt.Sprintf("import record %d has an empty key", n))
+	}
+	data, err := im.repo.encode(&value)
+	if err != nil {
+		return importRecord[T]{}, err
+	}
+	return importRecord[T]{key: key, value: &value, data: data}, nil
+}
+
+// write stores a batch in one pipeline and returns the records written and skipped
+func (im *Importer[T]) write(ctx context.Context, batch []importRecord[T]) (int, int, error) {
+	r := im.repo
+	keys := make([]string, len(batch))
+	for i, rec := range batch {
+		keys[i] = rec.key
+	}
+	if err := r.authorizeKeys(ctx, AccessWrite, keys...); err != nil {
+		return 0, 0, err
+	}
+	if err := r.checkQuota(ctx); err != nil {
+		return 0, 0, err
+	}
+	claims := make(map[string]*T, len(batch))
+	for _, rec := range batch {
+		claims[rec.key] = rec.value
+	}
+	if err := r.claimUnique(ctx, claims); err != nil {
+		return 0, 0, err
+	}
+
+	written := make(map[string]*T, len(batch))
+	if r.opts.changeStream != "" {
+		if err := im.captureBatch(ctx, batch, written); err != nil {
+			return 0, 0, r.abandonClaims(ctx, sortedKeys(claims), err)
+		}
+	} else {
+		cmds := make([]redis.Cmder, len(batch))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, rec := range batch {
+				ttl := r.retentionTTL(rec.key, im.opts.TTL)
+				if im.opts.OnConflict == ImportOverwrite {
+					cmds[i] = pipe.Set(ctx, r.buildKey(rec.key), rec.data, ttl)
+				} else {
+					cmds[i] = pipe.SetNX(ctx, r.buildKey(rec.key), rec.data, ttl)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, 0, r.abandonClaims(ctx, sortedKeys(claims), convertRedisError(err))
+		}
+		for i, rec := range batch {
+			if nx, ok := cmds[i].(*redis.BoolCmd); ok && !nx.Val() {
+				continue
+			}
+			written[rec.key] = rec.value
+		}
+	}
+
+	var unchanged []string
+	for key := range claims {
+		if _, ok := written[key]; !ok {
+			unchanged = append(unchanged, key)
+		}
+	}
+	if err := r.releaseClaims(ctx, unchanged); err != nil {
+		return 0, 0, err
+	}
+
+	skipped := len(batch) - len(written)
+	if len(written) > 0 {
+		if err := r.afterSet(ctx, written, im.opts.TTL); err != nil {
+			return len(written), skipped, err
+		}
+		if err := r.recordWrite(ctx, "import", sortedKeys(written)...); err != nil {
+			return len(written), skipped, err
+		}
+	}
+	if skipped > 0 && im.opts.OnConflict == ImportFail {
+		for _, rec := range batch {
+			if _, ok := written[rec.key]; !ok {
+				return len(written), 0, gpa.NewError(gpa.ErrorTypeDuplicate, "import key already exists: "+rec.key)
+			}
+		}
+	}
+	return len(written), skipped, nil
+}
+
+// captureBatch writes a batch through change capture; existing keys are checked first, so
+// a key created concurrently may be overwritten
+func (im *Importer[T]) captureBatch(ctx context.Context, batch []importRecord[T], written map[string]*T) error {
+	r := im.repo
+	exists := make([]*redis.IntCmd, len(batch))
+	if im.opts.OnConflict != ImportOverwrite {
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, rec := range batch {
+				exists[i] = pipe.Exists(ctx, r.buildKey(rec.key))
+			}
+			return nil
+		})
+		if err != nil {
+			return convertRedisError(err)
+		}
+	}
+	var keys []string
+	var payloads [][]byte
+	var ttls []time.Duration
+	for i, rec := range batch {
+		if exists[i] != nil && exists[i].Val() > 0 {
+			continue
+		}
+		keys = append(keys, rec.key)
+		payloads = append(payloads, rec.data)
+		ttls = append(ttls, r.retentionTTL(rec.key, im.opts.TTL))
+		written[rec.key] = rec.value
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := r.captureWrite(ctx, ChangeOpSet, keys, payloads, ttls)
+	return err
+}
+
+// csvColumn is a CSV column mapped to an entity field
+type csvColumn struct {
+	name string
+	// literal columns hold strings; the others hold JSON values
+	literal bool
+}
+
+// csvColumns maps a CSV header to the json names of T's fields
+func csvColumns[T any](header []string) ([]csvColumn, error) {
+	meta := entityMetaFor(reflect.TypeOf((*T)(nil)))
+	columns := make([]csvColumn, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if len(meta.fields) == 0 {
+			columns[i] = csvColumn{name: name, literal: true}
+			continue
+		}
+		found := false
+		for _, f := range meta.fields {
+			if f.jsonName == name || f.info.Name == name {
+				kind := f.info.Type.Kind()
+				columns[i] = csvColumn{name: f.jsonName, literal: kind == reflect.String}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unknown CSV column "+name)
+		}
+	}
+	return columns, nil
+}
+
+// csvRowJSON builds the JSON object of a CSV row; cells that aren't valid JSON are kept as
+// strings so decoding reports the mismatch
+func csvRowJSON(columns []csvColumn, row []string) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	first := true
+	for i, cell := range row {
+		if i >= len(columns) || cell == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		name, _ := json.Marshal(columns[i].name)
+		b.Write(name)
+		b.WriteByte(':')
+		if !columns[i].literal && json.Valid([]byte(cell)) {
+			b.WriteString(cell)
+		} else {
+			value, _ := json.Marshal(cell)
+			b.Write(value)
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
@@ -0,0 +1,106 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testValueKey(v *TestValue) string { return v.ID }
+
+func TestImporterJSONLines(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, repo.Set(ctx, "2", &TestValue{ID: "2", Name: "existing"}))
+	input := `{"id":"1","name":"Ann","age":31}
+
+{"id":"2","name":"Bob","age":40}
+not json
+{"id":"3","name":"Cat","age":-1}
+{"id":"4","name":"Dan","age":22}
+`
+	var reports []ImportProgress
+	importer := NewImporter(repo, ImporterOptions[TestValue]{
+		Key:         testValueKey,
+		TTL:         time.Hour,
+		BatchSize:   2,
+		SkipInvalid: true,
+		Validate: func(record int, v *TestValue) error {
+			if v.Age < 0 {
+				return errors.New("negative age")
+			}
+			return nil
+		},
+		OnProgress: func(p ImportProgress) { reports = append(reports, p) },
+	})
+	progress, err := importer.Import(ctx, strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, ImportProgress{Read: 5, Written: 2, Skipped: 1, Invalid: 2}, progress)
+	assert.Equal(t, []ImportProgress{{Read: 2, Written: 1, Skipped: 1}, {Read: 5, Written: 2, Skipped: 1, Invalid: 2}}, reports)
+
+	existing, err := repo.Get(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, "existing", existing.Name, "ImportSkip leaves existing keys alone")
+	dan, err := repo.Get(ctx, "4")
+	require.NoError(t, err)
+	assert.Equal(t, 22, dan.Age)
+	ttl, err := repo.GetTTL(ctx, "4")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), ttl.Seconds(), 5)
+
+	// Without SkipInvalid the first bad record stops the import
+	_, err = NewImporter(repo, ImporterOptions[TestValue]{Key: testValueKey}).Import(ctx, strings.NewReader("{\"id\":\"9\"}\n[1]\n"))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeSerialization))
+}
+
+func TestImporterConflicts(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, repo.Set(ctx, "1", &TestValue{ID: "1", Name: "old"}))
+	input := `{"id":"1","name":"new"}` + "\n" + `{"id":"2","name":"Bob"}`
+
+	_, err := NewImporter(repo, ImporterOptions[TestValue]{Key: testValueKey, OnConflict: ImportFail}).Import(ctx, strings.NewReader(input))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+
+	progress, err := NewImporter(repo, ImporterOptions[TestValue]{Key: testValueKey, OnConflict: ImportOverwrite}).Import(ctx, strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, 2, progress.Written)
+	got, err := repo.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "new", got.Name)
+
+	_, err = NewImporter(repo, ImporterOptions[TestValue]{}).Import(ctx, strings.NewReader(input))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestImporterCSV(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	input := "id,Name,age\n1,Ann,31\n2,\"Bob, Jr.\",\n3,42,x\n"
+	progress, err := NewImporter(repo, ImporterOptions[TestValue]{
+		Format:      ImportFormatCSV,
+		Key:         testValueKey,
+		SkipInvalid: true,
+	}).Import(ctx, strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, ImportProgress{Read: 3, Written: 2, Invalid: 1}, progress)
+
+	bob, err := repo.Get(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, TestValue{ID: "2", Name: "Bob, Jr."}, *bob)
+
+	_, err = NewImporter(repo, ImporterOptions[TestValue]{Format: ImportFormatCSV, Key: testValueKey}).Import(ctx, strings.NewReader("id,unknown\n"))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
@@ -0,0 +1,243 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Notification Inboxes
+// =====================================
+
+// inboxPushScript prepends notification ARGV[1] (encoded as ARGV[2]) and trims the inbox to
+// ARGV[3] entries, discounting trimmed unread notifications. KEYS: ids list, data hash,
+// unread set, unread counter. Returns the unread count.
+var inboxPushScript = redis.NewScript(`
+redis.call('LPUSH', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+redis.call('SADD', KEYS[3], ARGV[1])
+local count = redis.call('INCR', KEYS[4])
+local maxlen = tonumber(ARGV[3])
+local dropped = redis.call('LRANGE', KEYS[1], maxlen, -1)
+if #dropped > 0 then
+	redis.call('LTRIM', KEYS[1], 0, maxlen - 1)
+	redis.call('HDEL', KEYS[2], unpack(dropped))
+	local unread = 0
+	for _, id in ipairs(dropped) do
+		unread = unread + redis.call('SREM', KEYS[3], id)
+	end
+	if unread > 0 then
+		count = redis.call('DECRBY', KEYS[4], unread)
+	end
+end
+local ttl = tonumber(ARGV[4])
+if ttl > 0 then
+	for _, key in ipairs(KEYS) do
+		redis.call('PEXPIRE', key, ttl)
+	end
+end
+return count
+`)
+
+// inboxMarkReadScript marks the notifications in ARGV as read, decrementing the unread
+// counter once per notification that was unread. Returns the unread count.
+var inboxMarkReadScript = redis.NewScript(`
+local unread = 0
+for _, id in ipairs(ARGV) do
+	unread = unread + redis.call('SREM', KEYS[3], id)
+end
+if unread > 0 then
+	return redis.call('DECRBY', KEYS[4], unread)
+end
+return tonumber(redis.call('GET', KEYS[4]) or '0')
+`)
+
+// inboxDeleteScript removes the notifications in ARGV, discounting unread ones. Returns the
+// unread count.
+var inboxDeleteScript = redis.NewScript(`
+local unread = 0
+for _, id in ipairs(ARGV) do
+	redis.call('LREM', KEYS[1], 1, id)
+	redis.call('HDEL', KEYS[2], id)
+	unread = unread + redis.call('SREM', KEYS[3], id)
+end
+if unread > 0 then
+	return redis.call('DECRBY', KEYS[4], unread)
+end
+return tonumber(redis.call('GET', KEYS[4]) or '0')
+`)
+
+// InboxOptions configures an Inbox
+type InboxOptions struct {
+	// MaxLen is the number of notifications kept per user; older ones are dropped (default 100)
+	MaxLen int64
+	// TTL expires a user's inbox after this long without a new notification (0 keeps it forever)
+	TTL time.Duration
+}
+
+// InboxItem is a notification of an inbox
+type InboxItem[T any] struct {
+	ID        string
+	Value     *T
+	Read      bool
+	CreatedAt time.Time
+}
+
+// inboxRecord is the stored form of a notification
+type inboxRecord struct {
+	CreatedAt int64           `json:"at"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// Inbox keeps a capped list of notifications per user with an unread counter that stays
+// exact under concurrent pushes, reads and trimming: every change runs in a script that
+// adjusts the counter only for notifications whose unread state actually changed. A
+// user's keys share a hash tag, so inboxes work in cluster mode.
+type Inbox[T any] struct {
+	provider *Provider
+	client   *redis.Client
+	prefix   string
+	opts     InboxOptions
+}
+
+// NewInbox creates an inbox storing each user's notifications under prefix+"{"+user+"}:"
+// Example: notifications := gparedis.NewInbox[Notification](provider, "inbox:", gparedis.InboxOptions{MaxLen: 200})
+func NewInbox[T any](provider *Provider, prefix string, opts InboxOptions) *Inbox[T] {
+	if opts.MaxLen <= 0 {
+		opts.MaxLen = 100
+	}
+	return &Inbox[T]{provider: provider, client: provider.client, prefix: prefix, opts: opts}
+}
+
+// keys returns the ids list, data hash, unread set and unread counter of user
+func (b *Inbox[T]) keys(user string) []string {
+	base := b.prefix + "{" + user + "}:"
+	return []string{base + "ids", base + "data", base + "unread", base + "unread_count"}
+}
+
+// Push adds an unread notification for user and returns its ID and the new unread count
+// Example: id, unread, err := notifications.Push(ctx, userID, &Notification{Text: "New follower"})
+func (b *Inbox[T]) Push(ctx context.Context, user string, value *T) (string, int64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", 0, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize notification", err)
+	}
+	now := time.Now()
+	record, _ := json.Marshal(inboxRecord{CreatedAt: now.UnixMilli(), Value: data})
+	random, err := newRandomID()
+	if err != nil {
+		return "", 0, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to generate notification ID", err)
+	}
+	id := strconv.FormatInt(now.UnixMilli(), 10) + "-" + random[:8]
+	unread, err := inboxPushScript.Run(ctx, b.client, b.keys(user), id, record, b.opts.MaxLen, b.opts.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return "", 0, convertRedisError(err)
+	}
+	return id, unread, nil
+}
+
+// Unread returns the number of unread notifications of user
+func (b *Inbox[T]) Unread(ctx context.Context, user string) (int64, error) {
+	n, err := b.client.Get(ctx, b.keys(user)[3]).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return n, convertRedisError(err)
+}
+
+// MarkRead marks notifications of user as read and returns the new unread count; marking a
+// notification twice, or one that was dropped, doesn't change the count
+func (b *Inbox[T]) MarkRead(ctx context.Context, user string, ids ...string) (int64, error) {
+	if len(ids) == 0 {
+		return b.Unread(ctx, user)
+	}
+	return b.run(ctx, inboxMarkReadScript, user, ids)
+}
+
+// MarkAllRead marks every notification of user as read
+func (b *Inbox[T]) MarkAllRead(ctx context.Context, user string) error {
+	keys := b.keys(user)
+	return convertRedisError(b.client.Del(ctx, keys[2], keys[3]).Err())
+}
+
+// Delete removes notifications of user and returns the new unread count
+func (b *Inbox[T]) Delete(ctx context.Context, user string, ids ...string) (int64, error) {
+	if len(ids) == 0 {
+		return b.Unread(ctx, user)
+	}
+	return b.run(ctx, inboxDeleteScript, user, ids)
+}
+
+// run executes a script over the inbox keys of user with ids as arguments
+func (b *Inbox[T]) run(ctx context.Context, script *redis.Script, user string, ids []string) (int64, error) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	n, err := script.Run(ctx, b.client, b.keys(user), args...).Int64()
+	return n, convertRedisError(err)
+}
+
+// List returns up to limit notifications of user, newest first, skipping the offset newest.
+// Offsets shift as notifications arrive, so pages may overlap while paginating.
+// Example: page, err := notifications.List(ctx, userID, 0, 20)
+func (b *Inbox[T]) List(ctx context.Context, user string, offset, limit int64) ([]InboxItem[T], error) {
+	if limit <= 0 {
+		return []InboxItem[T]{}, nil
+	}
+	keys := b.keys(user)
+	ids, err := b.client.LRange(ctx, keys[0], offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	if len(ids) == 0 {
+		return []InboxItem[T]{}, nil
+	}
+
+	var records *redis.SliceCmd
+	unread := make([]*redis.BoolCmd, len(ids))
+	_, err = b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		records = pipe.HMGet(ctx, keys[1], ids...)
+		for i, id := range ids {
+			unread[i] = pipe.SIsMember(ctx, keys[2], id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+
+	items := make([]InboxItem[T], 0, len(ids))
+	for i, raw := range records.Val() {
+		data, ok := raw.(string)
+		if !ok {
+			// Deleted between the two reads
+			continue
+		}
+		var record inboxRecord
+		var value T
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize notification "+ids[i], err)
+		}
+		if err := json.Unmarshal(record.Value, &value); err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize notification "+ids[i], err)
+		}
+		items = append(items, InboxItem[T]{
+			ID:        ids[i],
+			Value:     &value,
+			Read:      !unread[i].Val(),
+			CreatedAt: time.UnixMilli(record.CreatedAt),
+		})
+	}
+	return items, nil
+}
+
+// Clear removes every notification of user
+func (b *Inbox[T]) Clear(ctx context.Context, user string) error {
+	return convertRedisError(b.client.Del(ctx, b.keys(user)...).Err())
+}
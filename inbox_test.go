@@ -0,0 +1,99 @@
+package gparedis
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInbox(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	inbox := NewInbox[TestValue](repo.provider, "test:inbox:", InboxOptions{MaxLen: 3})
+	defer inbox.Clear(ctx, "alice")
+
+	var ids []string
+	for _, name := range []string{"a", "b", "c"} {
+		id, unread, err := inbox.Push(ctx, "alice", &TestValue{Name: name})
+		require.NoError(t, err)
+		ids = append(ids, id)
+		assert.Equal(t, int64(len(ids)), unread)
+	}
+
+	unread, err := inbox.MarkRead(ctx, "alice", ids[0], ids[0], "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), unread)
+
+	// Pushing past MaxLen drops the oldest (read) notification, then an unread one
+	_, unread, err = inbox.Push(ctx, "alice", &TestValue{Name: "d"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), unread)
+	_, unread, err = inbox.Push(ctx, "alice", &TestValue{Name: "e"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), unread)
+
+	items, err := inbox.List(ctx, "alice", 0, 10)
+	require.NoError(t, err)
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Value.Name
+		assert.False(t, item.Read)
+		assert.False(t, item.CreatedAt.IsZero())
+	}
+	assert.Equal(t, []string{"e", "d", "c"}, names)
+
+	page, err := inbox.List(ctx, "alice", 1, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "d", page[0].Value.Name)
+
+	unread, err = inbox.Delete(ctx, "alice", items[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), unread)
+
+	require.NoError(t, inbox.MarkAllRead(ctx, "alice"))
+	unread, err = inbox.Unread(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), unread)
+	items, err = inbox.List(ctx, "alice", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.True(t, items[0].Read)
+}
+
+func TestInboxConcurrentMarkRead(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	inbox := NewInbox[TestValue](repo.provider, "test:inbox:", InboxOptions{})
+	defer inbox.Clear(ctx, "bob")
+
+	var ids []string
+	for i := 0; i < 10; i++ {
+		id, _, err := inbox.Push(ctx, "bob", &TestValue{Age: i})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	// Several devices marking the same notifications read decrement each once
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := inbox.MarkRead(ctx, "bob", ids[:6]...)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	unread, err := inbox.Unread(ctx, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), unread)
+}
@@ -0,0 +1,308 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Secondary Indexes
+// =====================================
+
+// Fields tagged with gpaindex are indexed in Redis sets ("tag sets"): every distinct value of an
+// index owns a set of the (unprefixed) keys holding that value. Fields sharing an index name form
+// a composite index whose value joins the field values with ":"; a field may belong to several
+// indexes, so composites can overlap single-field indexes. Range indexes instead keep one
+// sorted set of keys scored by the field's numeric value. A per-key reverse set remembers which
+// tag sets and range sets a key belongs to, so updates and deletes unlink stale entries without
+// reading the previous value. Index maintenance runs after the write and is not atomic with it.
+
+// indexKeyPrefix prefixes all index structures of a repository
+const indexKeyPrefix = "gparedis:idx:"
+
+// indexDef is one declared secondary index of an entity type
+type indexDef struct {
+	name    string
+	fields  []entityField
+	unique  bool
+	ranged  bool
+	subject bool
+	text    bool
+	ref     string
+}
+
+// indexDefs returns the secondary indexes declared on T, in declaration order
+func indexDefs(meta *entityMeta) []indexDef {
+	var defs []indexDef
+	positions := make(map[string]int)
+	for _, f := range meta.fields {
+		for _, tag := range meta.indexTags[f.info.Name] {
+			if pos, seen := positions[tag.name]; seen {
+				def := &defs[pos]
+				def.fields = append(def.fields, f)
+				def.unique = def.unique || tag.unique
+				def.ranged = def.ranged || tag.ranged
+				def.subject = def.subject || tag.subject
+				def.text = def.text || tag.text
+				if tag.ref != "" {
+					def.ref = tag.ref
+				}
+				continue
+			}
+			positions[tag.name] = len(defs)
+			defs = append(defs, indexDef{name: tag.name, fields: []entityField{f}, unique: tag.unique, ranged: tag.ranged, subject: tag.subject, text: tag.text, ref: tag.ref})
+		}
+	}
+	return defs
+}
+
+// indexes returns the secondary indexes of the repository's entity type
+func (r *Repository[T]) indexes() []indexDef {
+	return indexDefs(entityMetaFor(reflect.TypeOf((*T)(nil))))
+}
+
+// tagSetKey returns the set holding the keys whose index has the given value
+func (r *Repository[T]) tagSetKey(index, value string) string {
+	return indexKeyPrefix + r.keyPrefix + index + ":" + value
+}
+
+// reverseIndexKey returns the set listing the tag sets a key belongs to
+func (r *Repository[T]) reverseIndexKey(key string) string {
+	return indexKeyPrefix + r.keyPrefix + "~keys:" + key
+}
+
+// normalizeIndexValue renders a field value as it appears in tag set names.
+// Returns false for nil values, which are not indexed.
+func normalizeIndexValue(v reflect.Value) (string, bool) {
+	if !v.IsValid() {
+		return "", false
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.UTC().Format(time.RFC3339Nano), true
+	}
+	return fmt.Sprint(v.Interface()), true
+}
+
+// indexValue computes the value of def for entity
+func indexValue(entity reflect.Value, def indexDef) (string, bool) {
+	for entity.Kind() == reflect.Ptr {
+		if entity.IsNil() {
+			return "", false
+		}
+		entity = entity.Elem()
+	}
+	parts := make([]string, len(def.fields))
+	for i, f := range def.fields {
+		field, err := entity.FieldByIndexErr(f.index)
+		if err != nil {
+			return "", false
+		}
+		value, ok := normalizeIndexValue(field)
+		if !ok {
+			return "", false
+		}
+		parts[i] = value
+	}
+	return strings.Join(parts, ":"), true
+}
+
+// entityTagSets returns the tag sets entity belongs to under defs: one per index value, or
+// one per distinct term for text indexes
+func (r *Repository[T]) entityTagSets(entity reflect.Value, defs []indexDef) []string {
+	var sets []string
+	for _, def := range defs {
+		if def.ranged {
+			continue
+		}
+		value, ok := indexValue(entity, def)
+		if !ok {
+			continue
+		}
+		if !def.text {
+			sets = append(sets, r.tagSetKey(def.name, value))
+			continue
+		}
+		for _, term := range tokenize(value) {
+			sets = append(sets, r.tagSetKey(def.name, term))
+		}
+	}
+	return sets
+}
+
+// reindexScript replaces the index memberships of one key. Memberships listed in the reverse
+// set that are sorted sets (range indexes) are removed with ZREM, the others with SREM.
+// KEYS[1]: reverse set. ARGV[1]: member, ARGV[2]: number n of tag sets, ARGV[3..n+2]: tag
+// sets the member must belong to, then pairs of range set and score.
+var reindexScript = redis.NewScript(`
+for _, set in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+	if redis.call('TYPE', set).ok == 'zset' then
+		redis.call('ZREM', set, ARGV[1])
+	else
+		redis.call('SREM', set, ARGV[1])
+	end
+end
+redis.call('DEL', KEYS[1])
+local n = tonumber(ARGV[2] or '0')
+for i = 3, n + 2 do
+	redis.call('SADD', ARGV[i], ARGV[1])
+	redis.call('SADD', KEYS[1], ARGV[i])
+end
+for i = n + 3, #ARGV, 2 do
+	redis.call('ZADD', ARGV[i], ARGV[i + 1], ARGV[1])
+	redis.call('SADD', KEYS[1], ARGV[i])
+end
+return redis.call('SCARD', KEYS[1])
+`)
+
+// reindexArgs builds the reindexScript arguments giving key the memberships sets and ranges
+func reindexArgs(key string, sets []string, ranges []rangeEntry) []interface{} {
+	args := make([]interface{}, 0, 2+len(sets)+2*len(ranges))
+	args = append(args, key, len(sets))
+	for _, set := range sets {
+		args = append(args, set)
+	}
+	for _, entry := range ranges {
+		args = append(args, entry.set, strconv.FormatFloat(entry.score, 'f', -1, 64))
+	}
+	return args
+}
+
+// indexEntities updates the tag sets of freshly written values
+func (r *Repository[T]) indexEntities(ctx context.Context, values map[string]*T) error {
+	defs := r.indexes()
+	if len(defs) == 0 {
+		return nil
+	}
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range sortedKeys(values) {
+			entity := reflect.ValueOf(values[key])
+			args := reindexArgs(key, r.entityTagSets(entity, defs), r.entityRanges(entity, defs))
+			reindexScript.Eval(ctx, pipe, []string{r.reverseIndexKey(key)}, args...)
+		}
+		return nil
+	})
+	return r.indexError(err)
+}
+
+// unindexKeys removes deleted keys from all tag sets and range sets
+func (r *Repository[T]) unindexKeys(ctx context.Context, keys []string) error {
+	if len(r.indexes()) == 0 || len(keys) == 0 {
+		return nil
+	}
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			reindexScript.Eval(ctx, pipe, []string{r.reverseIndexKey(key)}, key)
+		}
+		return nil
+	})
+	return r.indexError(err)
+}
+
+// indexError wraps failures of index maintenance
+func (r *Repository[T]) indexError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to update secondary indexes", err)
+}
+
+// findIndex returns the declared index named name
+func (r *Repository[T]) findIndex(name string) (indexDef, error) {
+	for _, def := range r.indexes() {
+		if def.name == name {
+			return def, nil
+		}
+	}
+	return indexDef{}, gpa.NewError(gpa.ErrorTypeInvalidArgument, "no secondary index named "+name)
+}
+
+// lookupTagSet returns the tag set holding the keys whose index has the given values;
+// false when a value is nil, which is never indexed
+func (r *Repository[T]) lookupTagSet(index string, values []interface{}) (string, bool, error) {
+	def, err := r.findIndex(index)
+	if err != nil {
+		return "", false, err
+	}
+	if def.text {
+		return "", false, gpa.NewError(gpa.ErrorTypeInvalidArgument, "index "+index+" is a text index; use SearchKeys")
+	}
+	if def.ranged {
+		return "", false, gpa.NewError(gpa.ErrorTypeInvalidArgument, "index "+index+" is a range index; use KeysWhere")
+	}
+	if len(values) != len(def.fields) {
+		return "", false, gpa.NewError(gpa.ErrorTypeInvalidArgument,
+			fmt.Sprintf("index %s expects %d value(s), got %d", index, len(def.fields), len(values)))
+	}
+
+	parts := make([]string, len(values))
+	for i, value := range values {
+		part, ok := normalizeIndexValue(reflect.ValueOf(value))
+		if !ok {
+			return "", false, nil
+		}
+		parts[i] = part
+	}
+	return r.tagSetKey(index, strings.Join(parts, ":")), true, nil
+}
+
+// KeysByIndex returns the keys whose index has the given value, sorted. Composite indexes
+// take one value per field in declaration order.
+// Example: keys, err := repo.KeysByIndex(ctx, "status", "active")
+func (r *Repository[T]) KeysByIndex(ctx context.Context, index string, values ...interface{}) ([]string, error) {
+	set, ok, err := r.lookupTagSet(index, values)
+	if err != nil || !ok {
+		return []string{}, err
+	}
+	keys, err := r.client.SMembers(ctx, set).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	sort.Strings(keys)
+	if err := r.authorizeKeys(ctx, AccessRead, keys...); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// FindByIndex returns the entities whose index has the given value, ordered by key
+// Example: admins, err := repo.FindByIndex(ctx, "role", "admin")
+func (r *Repository[T]) FindByIndex(ctx context.Context, index string, values ...interface{}) ([]*T, error) {
+	keys, err := r.KeysByIndex(ctx, index, values...)
+	if err != nil {
+		return nil, err
+	}
+	return r.findKeys(ctx, keys)
+}
+
+// findKeys returns the entities stored at keys in order, skipping keys deleted meanwhile
+func (r *Repository[T]) findKeys(ctx context.Context, keys []string) ([]*T, error) {
+	if len(keys) == 0 {
+		return []*T{}, nil
+	}
+	found, err := r.MGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	entities := make([]*T, 0, len(found))
+	for _, key := range keys {
+		if entity, ok := found[key]; ok {
+			entities = append(entities, entity)
+		}
+	}
+	return entities, nil
+}
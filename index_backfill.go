@@ -0,0 +1,129 @@
+package gparedis
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Secondary Index Backfill
+// =====================================
+
+// IndexBuildOptions configures BuildIndexes
+type IndexBuildOptions struct {
+	// EntitiesPerSecond caps the rate keys are read and indexed (0 disables)
+	EntitiesPerSecond float64
+	// BatchSize is the SCAN count hint of each batch (default 100)
+	BatchSize int64
+	// Restart ignores the checkpoint of an interrupted build and starts over
+	Restart bool
+}
+
+// IndexBuildReport summarizes one BuildIndexes run
+type IndexBuildReport struct {
+	// Indexed counts the entities whose index entries were written
+	Indexed int
+	// Skipped counts values that aren't entities (counters, other types)
+	Skipped int
+	// Resumed reports whether the run continued from a checkpoint
+	Resumed bool
+}
+
+// indexBuildCheckpointKey returns the key holding the SCAN cursor of an interrupted build
+func (r *Repository[T]) indexBuildCheckpointKey() string {
+	return indexKeyPrefix + r.keyPrefix + "~build"
+}
+
+// BuildIndexes populates the secondary indexes from the entities already stored under the
+// prefix, for indexes declared after the data was written. Keys are scanned in batches and
+// every entity's index entries are rewritten. After each batch the SCAN cursor is saved in a
+// Checkpoint, so a build interrupted by an error or a cancelled context resumes where it
+// stopped; the checkpoint is removed once the scan completes. A batch interrupted midway is
+// indexed again on resume, which is harmless. Entities written meanwhile are indexed by the
+// writes themselves. A build already running in another process returns ErrorTypeConflict.
+// Example: report, err := users.BuildIndexes(ctx, gparedis.IndexBuildOptions{EntitiesPerSecond: 2000})
+func (r *Repository[T]) BuildIndexes(ctx context.Context, opts IndexBuildOptions) (IndexBuildReport, error) {
+	var report IndexBuildReport
+	if len(r.indexes()) == 0 {
+		return report, nil
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = scanBatchSize
+	}
+	if err := r.authorize(ctx, AccessScan, r.buildKey("*")); err != nil {
+		return report, err
+	}
+
+	checkpoint := newCheckpoint(r.client, r.indexBuildCheckpointKey(), CheckpointOptions{})
+	saved, err := checkpoint.Acquire(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer checkpoint.Release(context.Background())
+	if opts.Restart {
+		if err := checkpoint.Reset(ctx); err != nil {
+			return report, err
+		}
+	} else {
+		report.Resumed = saved != ""
+	}
+
+	limiter := newThrottle(ThrottleOptions{CommandsPerSecond: opts.EntitiesPerSecond, Burst: int(opts.BatchSize)})
+	err = checkpoint.Scan(ctx, r.buildKey("*"), opts.BatchSize, func(fullKeys []string) error {
+		fullKeys = r.ownedKeys(fullKeys)
+		if err := limiter.wait(ctx, len(fullKeys)); err != nil {
+			return err
+		}
+		if err := checkpoint.Renew(ctx); err != nil {
+			return err
+		}
+		return r.buildIndexBatch(ctx, fullKeys, &report)
+	})
+	return report, err
+}
+
+// buildIndexBatch reads one SCAN batch and rewrites the index entries of its entities
+func (r *Repository[T]) buildIndexBatch(ctx context.Context, fullKeys []string, report *IndexBuildReport) error {
+	if len(fullKeys) == 0 {
+		return nil
+	}
+	values := make([]*redis.StringCmd, len(fullKeys))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, fullKey := range fullKeys {
+			values[i] = pipe.Get(ctx, fullKey)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil && !isWrongTypeError(err) {
+		return convertRedisError(err)
+	}
+
+	entities := make(map[string]*T, len(fullKeys))
+	for i, fullKey := range fullKeys {
+		raw, err := values[i].Bytes()
+		if err == redis.Nil {
+			// Deleted since the scan
+			continue
+		}
+		if err != nil {
+			report.Skipped++
+			continue
+		}
+		entity, err := r.decode(raw)
+		if err != nil {
+			// Not an entity (counters, index structures under an empty prefix)
+			report.Skipped++
+			continue
+		}
+		entities[r.trimKey(fullKey)] = entity
+	}
+	if len(entities) == 0 {
+		return nil
+	}
+	if err := r.indexEntities(ctx, entities); err != nil {
+		return err
+	}
+	report.Indexed += len(entities)
+	return nil
+}
@@ -0,0 +1,53 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildIndexes(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// Written before the type declared its indexes
+	c := base.client
+	require.NoError(t, c.Set(ctx, "user:1", `{"id":"1","email":"a@x.io","status":"active","tenant":"acme","state":"open"}`, 0).Err())
+	require.NoError(t, c.Set(ctx, "user:2", `{"id":"2","email":"b@x.io","status":"banned","tenant":"acme","state":"open"}`, 0).Err())
+	require.NoError(t, c.Set(ctx, "user:3", `{"id":"3","email":"c@x.io","status":"active","tenant":"acme","state":"closed"}`, 0).Err())
+	require.NoError(t, c.Set(ctx, "user:visits", "12", 0).Err())
+
+	repo := NewRepository[indexedUser](base.provider, base.client, "user:")
+	active, err := repo.KeysByIndex(ctx, "status", "active")
+	require.NoError(t, err)
+	assert.Empty(t, active)
+
+	report, err := repo.BuildIndexes(ctx, IndexBuildOptions{BatchSize: 2, EntitiesPerSecond: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, IndexBuildReport{Indexed: 3, Skipped: 1}, report)
+
+	active, err = repo.KeysByIndex(ctx, "status", "active")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "3"}, active)
+	open, err := repo.KeysByIndex(ctx, "tenant_status", "acme", "open")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, open)
+	exists, err := c.Exists(ctx, repo.indexBuildCheckpointKey()).Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists)
+
+	// A leftover checkpoint resumes the scan; Restart discards it
+	require.NoError(t, c.Set(ctx, repo.indexBuildCheckpointKey(), "0", 0).Err())
+	report, err = repo.BuildIndexes(ctx, IndexBuildOptions{})
+	require.NoError(t, err)
+	assert.True(t, report.Resumed)
+	assert.Equal(t, 3, report.Indexed)
+
+	require.NoError(t, c.Set(ctx, repo.indexBuildCheckpointKey(), "0", 0).Err())
+	report, err = repo.BuildIndexes(ctx, IndexBuildOptions{Restart: true})
+	require.NoError(t, err)
+	assert.False(t, report.Resumed)
+}
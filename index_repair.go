@@ -0,0 +1,338 @@
+package gparedis
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Secondary Index Repair
+// =====================================
+
+// IndexRepairReport lists the corrections RepairIndexes made
+type IndexRepairReport struct {
+	// Scanned counts the entities checked
+	Scanned int
+	// Reindexed counts entities whose index entries were rewritten
+	Reindexed int
+	// Added counts missing tag set entries that were added
+	Added int
+	// Removed counts dangling tag set entries that were removed, including those of deleted keys
+	Removed int
+}
+
+// RepairIndexes brings the secondary indexes in line with the stored entities, fixing the
+// drift left by crashes between a write and its index update or by writes that bypassed the
+// repository. It verifies every entity's tag set and range set memberships and rewrites them
+// when they disagree, drops the index entries of keys that no longer exist, and removes
+// members not backed by their key's reverse set. Stale unique claims are pruned too; they are
+// ignored anyway, so they don't count as removed entries. Safe to run while the repository is
+// in use, though entries written concurrently may be corrected twice.
+// Example: report, err := users.RepairIndexes(ctx)
+func (r *Repository[T]) RepairIndexes(ctx context.Context) (IndexRepairReport, error) {
+	var report IndexRepairReport
+	defs := r.indexes()
+	if len(defs) == 0 {
+		return report, nil
+	}
+	if err := r.repairEntityIndexes(ctx, defs, &report); err != nil {
+		return report, err
+	}
+	if err := r.repairDeletedIndexes(ctx, &report); err != nil {
+		return report, err
+	}
+	for _, def := range defs {
+		if def.ranged {
+			if err := r.repairRangeSet(ctx, def, &report); err != nil {
+				return report, err
+			}
+			continue
+		}
+		if err := r.repairTagSets(ctx, def, &report); err != nil {
+			return report, err
+		}
+		if def.unique {
+			if err := r.repairUniqueClaims(ctx, def); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// repairEntityIndexes reindexes every entity whose reverse set or tag set memberships
+// differ from its current values
+func (r *Repository[T]) repairEntityIndexes(ctx context.Context, defs []indexDef, report *IndexRepairReport) error {
+	return r.scanEach(ctx, "*", func(fullKeys []string) error {
+		values := make([]*redis.StringCmd, len(fullKeys))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, fullKey := range fullKeys {
+				values[i] = pipe.Get(ctx, fullKey)
+			}
+			return nil
+		})
+		if err != nil && err != redis.Nil && !isWrongTypeError(err) {
+			return convertRedisError(err)
+		}
+
+		type check struct {
+			key      string
+			expected []string
+			ranges   []rangeEntry
+			recorded *redis.StringSliceCmd
+			members  []*redis.BoolCmd
+			scores   []*redis.FloatCmd
+		}
+		var checks []*check
+		for i, fullKey := range fullKeys {
+			raw, err := values[i].Bytes()
+			if err != nil {
+				continue
+			}
+			entity, err := r.decode(raw)
+			if err != nil {
+				// Not an entity (counters, index structures under an empty prefix)
+				continue
+			}
+			value := reflect.ValueOf(entity)
+			c := &check{key: r.trimKey(fullKey), expected: r.entityTagSets(value, defs), ranges: r.entityRanges(value, defs)}
+			checks = append(checks, c)
+		}
+		report.Scanned += len(checks)
+		if len(checks) == 0 {
+			return nil
+		}
+
+		_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, c := range checks {
+				c.recorded = pipe.SMembers(ctx, r.reverseIndexKey(c.key))
+				for _, set := range c.expected {
+					c.members = append(c.members, pipe.SIsMember(ctx, set, c.key))
+				}
+				for _, entry := range c.ranges {
+					c.scores = append(c.scores, pipe.ZScore(ctx, entry.set, c.key))
+				}
+			}
+			return nil
+		})
+		if err != nil && err != redis.Nil {
+			return r.indexError(err)
+		}
+
+		_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, c := range checks {
+				expected := make(map[string]bool, len(c.expected))
+				added := 0
+				for i, set := range c.expected {
+					expected[set] = true
+					if !c.members[i].Val() {
+						added++
+					}
+				}
+				for i, entry := range c.ranges {
+					expected[entry.set] = true
+					if score, err := c.scores[i].Result(); err != nil || score != entry.score {
+						added++
+					}
+				}
+				removed := 0
+				for _, set := range c.recorded.Val() {
+					if !expected[set] {
+						removed++
+					}
+				}
+				if added == 0 && removed == 0 && len(c.recorded.Val()) == len(expected) {
+					continue
+				}
+				args := reindexArgs(c.key, c.expected, c.ranges)
+				reindexScript.Eval(ctx, pipe, []string{r.reverseIndexKey(c.key)}, args...)
+				report.Reindexed++
+				report.Added += added
+				report.Removed += removed
+			}
+			return nil
+		})
+		return r.indexError(err)
+	})
+}
+
+// repairDeletedIndexes removes the index entries of keys that no longer exist
+func (r *Repository[T]) repairDeletedIndexes(ctx context.Context, report *IndexRepairReport) error {
+	reversePrefix := r.reverseIndexKey("")
+	return scanKeys(ctx, r.client, EscapeGlob(reversePrefix)+"*", func(reverseKeys []string) error {
+		exists := make([]*redis.IntCmd, len(reverseKeys))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, reverseKey := range reverseKeys {
+				exists[i] = pipe.Exists(ctx, r.buildKey(strings.TrimPrefix(reverseKey, reversePrefix)))
+			}
+			return nil
+		})
+		if err != nil {
+			return convertRedisError(err)
+		}
+
+		var stale []string
+		for i, reverseKey := range reverseKeys {
+			if exists[i].Val() == 0 {
+				stale = append(stale, reverseKey)
+			}
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+		sizes := make([]*redis.IntCmd, len(stale))
+		_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, reverseKey := range stale {
+				sizes[i] = pipe.SCard(ctx, reverseKey)
+				reindexScript.Eval(ctx, pipe, []string{reverseKey}, strings.TrimPrefix(reverseKey, reversePrefix))
+			}
+			return nil
+		})
+		if err != nil {
+			return r.indexError(err)
+		}
+		for _, size := range sizes {
+			report.Removed += int(size.Val())
+		}
+		return nil
+	})
+}
+
+// repairTagSets removes tag set members whose reverse set doesn't list the tag set
+func (r *Repository[T]) repairTagSets(ctx context.Context, def indexDef, report *IndexRepairReport) error {
+	pattern := EscapeGlob(r.tagSetKey(def.name, "")) + "*"
+	return scanKeys(ctx, r.client, pattern, func(sets []string) error {
+		for _, set := range sets {
+			var cursor uint64
+			for {
+				members, next, err := r.client.SScan(ctx, set, cursor, "", scanBatchSize).Result()
+				if err != nil {
+					return convertRedisError(err)
+				}
+				linked := make([]*redis.BoolCmd, len(members))
+				_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+					for i, member := range members {
+						linked[i] = pipe.SIsMember(ctx, r.reverseIndexKey(member), set)
+					}
+					return nil
+				})
+				if err != nil {
+					return r.indexError(err)
+				}
+				var dangling []interface{}
+				for i, member := range members {
+					if !linked[i].Val() {
+						dangling = append(dangling, member)
+					}
+				}
+				if len(dangling) > 0 {
+					if err := r.client.SRem(ctx, set, dangling...).Err(); err != nil {
+						return r.indexError(err)
+					}
+					report.Removed += len(dangling)
+				}
+				if cursor = next; cursor == 0 {
+					break
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// repairUniqueClaims drops the claims of a unique index whose key left the value's tag set
+func (r *Repository[T]) repairUniqueClaims(ctx context.Context, def indexDef) error {
+	hash := r.uniqueHashKey(def.name)
+	var cursor uint64
+	for {
+		pairs, next, err := r.client.HScan(ctx, hash, cursor, "", scanBatchSize).Result()
+		if err != nil {
+			return convertRedisError(err)
+		}
+		// HSCAN returns fields and values interleaved
+		live := make([]*redis.BoolCmd, 0, len(pairs)/2)
+		_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i := 0; i+1 < len(pairs); i += 2 {
+				live = append(live, pipe.SIsMember(ctx, r.tagSetKey(def.name, pairs[i]), pairs[i+1]))
+			}
+			return nil
+		})
+		if err != nil {
+			return r.indexError(err)
+		}
+		var stale []string
+		for i, isLive := range live {
+			if !isLive.Val() {
+				stale = append(stale, pairs[2*i])
+			}
+		}
+		if len(stale) > 0 {
+			if err := r.client.HDel(ctx, hash, stale...).Err(); err != nil {
+				return r.indexError(err)
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// repairRangeSet removes range set members whose reverse set doesn't list the range set
+func (r *Repository[T]) repairRangeSet(ctx context.Context, def indexDef, report *IndexRepairReport) error {
+	set := r.rangeSetKey(def.name)
+	var cursor uint64
+	for {
+		pairs, next, err := r.client.ZScan(ctx, set, cursor, "", scanBatchSize).Result()
+		if err != nil {
+			return convertRedisError(err)
+		}
+		// ZSCAN returns members and scores interleaved
+		linked := make([]*redis.BoolCmd, 0, len(pairs)/2)
+		_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i := 0; i+1 < len(pairs); i += 2 {
+				linked = append(linked, pipe.SIsMember(ctx, r.reverseIndexKey(pairs[i]), set))
+			}
+			return nil
+		})
+		if err != nil {
+			return r.indexError(err)
+		}
+		var dangling []interface{}
+		for i, isLinked := range linked {
+			if !isLinked.Val() {
+				dangling = append(dangling, pairs[2*i])
+			}
+		}
+		if len(dangling) > 0 {
+			if err := r.client.ZRem(ctx, set, dangling...).Err(); err != nil {
+				return r.indexError(err)
+			}
+			report.Removed += len(dangling)
+		}
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// scanKeys walks all keys matching a full pattern and calls fn with each SCAN batch
+func scanKeys(ctx context.Context, client *redis.Client, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return convertRedisError(err)
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
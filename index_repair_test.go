@@ -0,0 +1,53 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairIndexes(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	repo := NewRepository[indexedUser](base.provider, base.client, "user:")
+	require.NoError(t, repo.MSet(ctx, map[string]*indexedUser{
+		"1": {ID: "1", Email: "a@x.io", Status: "active", Tenant: "acme", State: "open"},
+		"2": {ID: "2", Email: "b@x.io", Status: "active", Tenant: "acme", State: "open"},
+		"3": {ID: "3", Email: "c@x.io", Status: "banned", Tenant: "acme", State: "open"},
+	}))
+
+	c := base.client
+	// Written behind the repository's back: three entries missing
+	require.NoError(t, c.Set(ctx, "user:4", `{"id":"4","email":"d@x.io","status":"active","tenant":"acme","state":"open"}`, 0).Err())
+	// Changed behind its back: one entry stale, one missing
+	require.NoError(t, c.Set(ctx, "user:1", `{"id":"1","email":"a@x.io","status":"banned","tenant":"acme","state":"open"}`, 0).Err())
+	// Deleted behind its back: three entries dangling
+	require.NoError(t, c.Del(ctx, "user:2").Err())
+	// A tag set member without a reverse set
+	require.NoError(t, c.SAdd(ctx, repo.tagSetKey("status", "active"), "ghost").Err())
+
+	report, err := repo.RepairIndexes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, IndexRepairReport{Scanned: 3, Reindexed: 2, Added: 4, Removed: 5}, report)
+
+	active, err := repo.KeysByIndex(ctx, "status", "active")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4"}, active)
+	banned, err := repo.KeysByIndex(ctx, "status", "banned")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "3"}, banned)
+	open, err := repo.KeysByIndex(ctx, "tenant_status", "acme", "open")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "3", "4"}, open)
+	exists, err := c.Exists(ctx, repo.reverseIndexKey("2")).Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists)
+
+	report, err = repo.RepairIndexes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, IndexRepairReport{Scanned: 3}, report, "a repaired index needs no corrections")
+}
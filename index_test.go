@@ -0,0 +1,67 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type indexedUser struct {
+	ID     string  `json:"id"`
+	Email  string  `json:"email" gpaindex:"email,unique"`
+	Status string  `json:"status" gpaindex:"status"`
+	Tenant string  `json:"tenant" gpaindex:"tenant_status"`
+	State  string  `json:"state" gpaindex:"tenant_status"`
+	Team   *string `json:"team" gpaindex:"team"`
+}
+
+func TestSecondaryIndexMaintenance(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[indexedUser](base.provider, base.client, "user:")
+
+	require.NoError(t, repo.Set(ctx, "1", &indexedUser{ID: "1", Email: "a@x.io", Status: "active", Tenant: "acme", State: "open"}))
+	require.NoError(t, repo.MSet(ctx, map[string]*indexedUser{
+		"2": {ID: "2", Email: "b@x.io", Status: "active", Tenant: "acme", State: "closed"},
+		"3": {ID: "3", Email: "c@x.io", Status: "banned", Tenant: "acme", State: "open"},
+	}))
+
+	active, err := repo.KeysByIndex(ctx, "status", "active")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, active)
+
+	openAcme, err := repo.FindByIndex(ctx, "tenant_status", "acme", "open")
+	require.NoError(t, err)
+	require.Len(t, openAcme, 2)
+	assert.Equal(t, "1", openAcme[0].ID)
+
+	// Updates move the key between tag sets
+	require.NoError(t, repo.Set(ctx, "1", &indexedUser{ID: "1", Email: "a@x.io", Status: "banned", Tenant: "acme", State: "open"}))
+	active, err = repo.KeysByIndex(ctx, "status", "active")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, active)
+
+	// Deletes unlink the key everywhere
+	require.NoError(t, repo.DeleteKey(ctx, "3"))
+	banned, err := repo.KeysByIndex(ctx, "status", "banned")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, banned)
+	byEmail, err := repo.KeysByIndex(ctx, "email", "c@x.io")
+	require.NoError(t, err)
+	assert.Empty(t, byEmail)
+
+	// Nil values are not indexed
+	noTeam, err := repo.KeysByIndex(ctx, "team", nil)
+	require.NoError(t, err)
+	assert.Empty(t, noTeam)
+
+	_, err = repo.KeysByIndex(ctx, "tenant_status", "acme")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+	_, err = repo.KeysByIndex(ctx, "missing", "x")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
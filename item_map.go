@@ -0,0 +1,189 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Item Maps
+// =====================================
+
+// itemMapUpdateScript adds ARGV[2] to the quantity of item ARGV[1], removing the item when
+// its quantity drops to zero or below. KEYS: quantities, items, prices. ARGV[3] is the TTL
+// in milliseconds. Returns the new quantity, or false when the item isn't in the map.
+var itemMapUpdateScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return false
+end
+local qty = redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2])
+if qty <= 0 then
+	for _, key in ipairs(KEYS) do
+		redis.call('HDEL', key, ARGV[1])
+	end
+	qty = 0
+end
+local ttl = tonumber(ARGV[3])
+if ttl > 0 then
+	for _, key in ipairs(KEYS) do
+		redis.call('PEXPIRE', key, ttl)
+	end
+end
+return qty
+`)
+
+// itemMapTotalScript sums quantity times unit price over the items. KEYS: quantities,
+// prices. The total is returned as a string to keep its fraction.
+var itemMapTotalScript = readOnlyScript(redis.NewScript(`
+local quantities = redis.call('HGETALL', KEYS[1])
+local total = 0
+for i = 1, #quantities, 2 do
+	local price = tonumber(redis.call('HGET', KEYS[2], quantities[i]) or '0')
+	total = total + tonumber(quantities[i + 1]) * price
+end
+return tostring(total)
+`))
+
+// ItemMapOptions configures an ItemMap
+type ItemMapOptions[T any] struct {
+	// Price returns the unit price of an item for Total (nil prices every item at 0)
+	Price func(item *T) float64
+	// TTL expires a whole map this long after its last change (0 keeps it forever)
+	TTL time.Duration
+}
+
+// MapItem is an item of an ItemMap with its quantity
+type MapItem[T any] struct {
+	ID   string
+	Item *T
+	Qty  int64
+}
+
+// ItemMap stores maps of items with quantities, such as shopping carts: each map keeps the
+// quantities, item data and unit prices in three hashes sharing a hash tag, so quantity
+// changes are atomic (HINCRBY) and Total is computed server-side.
+type ItemMap[T any] struct {
+	provider *Provider
+	client   *redis.Client
+	prefix   string
+	opts     ItemMapOptions[T]
+}
+
+// NewItemMap creates item maps stored under prefix+"{"+map ID+"}:"
+// Example: carts := gparedis.NewItemMap[Product](provider, "cart:", gparedis.ItemMapOptions[Product]{Price: func(p *Product) float64 { return p.Price }, TTL: 7 * 24 * time.Hour})
+func NewItemMap[T any](provider *Provider, prefix string, opts ItemMapOptions[T]) *ItemMap[T] {
+	return &ItemMap[T]{provider: provider, client: provider.client, prefix: prefix, opts: opts}
+}
+
+// keys returns the quantities, items and prices hashes of a map
+func (m *ItemMap[T]) keys(id string) []string {
+	base := m.prefix + "{" + id + "}:"
+	return []string{base + "qty", base + "items", base + "prices"}
+}
+
+// AddItem adds qty of item to a map, storing the item's current data and price, and
+// returns the item's new quantity
+// Example: qty, err := carts.AddItem(ctx, cartID, product.SKU, product, 2)
+func (m *ItemMap[T]) AddItem(ctx context.Context, id, itemID string, item *T, qty int64) (int64, error) {
+	if qty <= 0 {
+		return 0, gpa.NewError(gpa.ErrorTypeInvalidArgument, "quantity must be positive")
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return 0, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize item", err)
+	}
+	price := 0.0
+	if m.opts.Price != nil {
+		price = m.opts.Price(item)
+	}
+	keys := m.keys(id)
+	var total *redis.IntCmd
+	_, err = m.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		total = pipe.HIncrBy(ctx, keys[0], itemID, qty)
+		pipe.HSet(ctx, keys[1], itemID, data)
+		pipe.HSet(ctx, keys[2], itemID, price)
+		m.expire(ctx, pipe, keys)
+		return nil
+	})
+	if err != nil {
+		return 0, convertRedisError(err)
+	}
+	return total.Val(), nil
+}
+
+// UpdateQty adds delta (negative to subtract) to the quantity of an item and returns the
+// new quantity; the item is removed when it drops to zero. Items not in the map fail with
+// ErrorTypeNotFound.
+func (m *ItemMap[T]) UpdateQty(ctx context.Context, id, itemID string, delta int64) (int64, error) {
+	qty, err := itemMapUpdateScript.Run(ctx, m.client, m.keys(id), itemID, delta, m.opts.TTL.Milliseconds()).Int64()
+	if err == redis.Nil {
+		return 0, gpa.NewError(gpa.ErrorTypeNotFound, "item "+itemID+" is not in "+id)
+	}
+	return qty, convertRedisError(err)
+}
+
+// RemoveItem removes an item from a map
+func (m *ItemMap[T]) RemoveItem(ctx context.Context, id, itemID string) error {
+	keys := m.keys(id)
+	_, err := m.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.HDel(ctx, key, itemID)
+		}
+		m.expire(ctx, pipe, keys)
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// expire refreshes the TTL of a map's hashes
+func (m *ItemMap[T]) expire(ctx context.Context, pipe redis.Pipeliner, keys []string) {
+	if m.opts.TTL <= 0 {
+		return
+	}
+	for _, key := range keys {
+		pipe.PExpire(ctx, key, m.opts.TTL)
+	}
+}
+
+// Items returns the items of a map sorted by item ID
+func (m *ItemMap[T]) Items(ctx context.Context, id string) ([]MapItem[T], error) {
+	keys := m.keys(id)
+	var quantities, items *redis.StringStringMapCmd
+	_, err := m.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		quantities = pipe.HGetAll(ctx, keys[0])
+		items = pipe.HGetAll(ctx, keys[1])
+		return nil
+	})
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	result := make([]MapItem[T], 0, len(quantities.Val()))
+	for itemID, rawQty := range quantities.Val() {
+		qty, _ := strconv.ParseInt(rawQty, 10, 64)
+		var item T
+		if err := json.Unmarshal([]byte(items.Val()[itemID]), &item); err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize item "+itemID, err)
+		}
+		result = append(result, MapItem[T]{ID: itemID, Item: &item, Qty: qty})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// Total returns the sum of quantity times unit price over the items of a map
+func (m *ItemMap[T]) Total(ctx context.Context, id string) (float64, error) {
+	keys := m.keys(id)
+	total, err := itemMapTotalScript.Run(ctx, m.client, []string{keys[0], keys[2]}).Float64()
+	return total, convertRedisError(err)
+}
+
+// Clear removes a map
+func (m *ItemMap[T]) Clear(ctx context.Context, id string) error {
+	return convertRedisError(m.client.Del(ctx, m.keys(id)...).Err())
+}
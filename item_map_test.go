@@ -0,0 +1,69 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cartProduct struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+func TestItemMap(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	carts := NewItemMap[cartProduct](repo.provider, "test:cart:", ItemMapOptions[cartProduct]{
+		Price: func(p *cartProduct) float64 { return p.Price },
+		TTL:   time.Hour,
+	})
+	defer carts.Clear(ctx, "c1")
+
+	qty, err := carts.AddItem(ctx, "c1", "sku-1", &cartProduct{Name: "Mug", Price: 4.5}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), qty)
+	qty, err = carts.AddItem(ctx, "c1", "sku-1", &cartProduct{Name: "Mug", Price: 4.5}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), qty)
+	_, err = carts.AddItem(ctx, "c1", "sku-2", &cartProduct{Name: "Tea", Price: 2.25}, 2)
+	require.NoError(t, err)
+	_, err = carts.AddItem(ctx, "c1", "sku-3", &cartProduct{Name: "Pot", Price: 20}, 0)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+
+	total, err := carts.Total(ctx, "c1")
+	require.NoError(t, err)
+	assert.InDelta(t, 18.0, total, 1e-9)
+
+	qty, err = carts.UpdateQty(ctx, "c1", "sku-1", -1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), qty)
+	qty, err = carts.UpdateQty(ctx, "c1", "sku-2", -5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), qty)
+	_, err = carts.UpdateQty(ctx, "c1", "sku-2", 1)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+
+	items, err := carts.Items(ctx, "c1")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, MapItem[cartProduct]{ID: "sku-1", Item: &cartProduct{Name: "Mug", Price: 4.5}, Qty: 2}, items[0])
+
+	ttl, err := repo.client.TTL(ctx, carts.keys("c1")[2]).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute)
+
+	require.NoError(t, carts.RemoveItem(ctx, "c1", "sku-1"))
+	total, err = carts.Total(ctx, "c1")
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	items, err = carts.Items(ctx, "c1")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
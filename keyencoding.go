@@ -0,0 +1,128 @@
+package gparedis
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// =====================================
+// Binary-Safe Key Encoding
+// =====================================
+
+// KeyEncodingFormat selects how unsafe key components are encoded
+type KeyEncodingFormat string
+
+const (
+	// KeyEncodingBase64 encodes with unpadded base64url (default)
+	KeyEncodingBase64 KeyEncodingFormat = "b64"
+	// KeyEncodingHex encodes with lowercase hex
+	KeyEncodingHex KeyEncodingFormat = "hex"
+)
+
+// encodedComponentMarker starts every encoded key component, followed by the format and a dot
+const encodedComponentMarker = "~"
+
+// KeyEncoding configures reversible encoding of user-supplied key components
+type KeyEncoding struct {
+	// Format is the encoding of unsafe components (default KeyEncodingBase64)
+	Format KeyEncodingFormat
+	// MaxLength also encodes components longer than this many bytes (0 disables)
+	MaxLength int
+}
+
+// WithKeyEncoding encodes every ":"-separated key component that contains control
+// characters or invalid UTF-8 (or exceeds MaxLength) as "~b64.<data>" or "~hex.<data>", so
+// binary identifiers are safe in keys, logs and SCAN patterns. Keys returned by Keys, Scan
+// and List are decoded again. Components that already start with "~" are encoded too, so
+// decoding is unambiguous.
+// Example: files := gparedis.NewRepository[File](provider, client, "file:", gparedis.WithKeyEncoding(gparedis.KeyEncoding{}))
+func WithKeyEncoding(enc KeyEncoding) RepositoryOption {
+	return func(o *repositoryOptions) {
+		if enc.Format == "" {
+			enc.Format = KeyEncodingBase64
+		}
+		o.keyEncoding = &enc
+	}
+}
+
+// needsEncoding reports whether a key component must be encoded
+func (e KeyEncoding) needsEncoding(component string) bool {
+	if strings.HasPrefix(component, encodedComponentMarker) || !utf8.ValidString(component) {
+		return true
+	}
+	if e.MaxLength > 0 && len(component) > e.MaxLength {
+		return true
+	}
+	for _, r := range component {
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeKey encodes the unsafe components of key
+func (e KeyEncoding) encodeKey(key string) string {
+	components := strings.Split(key, keySegmentSeparator)
+	changed := false
+	for i, component := range components {
+		if !e.needsEncoding(component) {
+			continue
+		}
+		var data string
+		if e.Format == KeyEncodingHex {
+			data = hex.EncodeToString([]byte(component))
+		} else {
+			data = base64.RawURLEncoding.EncodeToString([]byte(component))
+		}
+		components[i] = encodedComponentMarker + string(e.Format) + "." + data
+		changed = true
+	}
+	if !changed {
+		return key
+	}
+	return strings.Join(components, keySegmentSeparator)
+}
+
+// decodeKey reverses encodeKey; components that don't decode are returned unchanged
+func decodeKey(key string) string {
+	if !strings.Contains(key, encodedComponentMarker) {
+		return key
+	}
+	components := strings.Split(key, keySegmentSeparator)
+	for i, component := range components {
+		if decoded, ok := decodeComponent(component); ok {
+			components[i] = decoded
+		}
+	}
+	return strings.Join(components, keySegmentSeparator)
+}
+
+// decodeComponent decodes one "~<format>.<data>" component
+func decodeComponent(component string) (string, bool) {
+	rest, ok := strings.CutPrefix(component, encodedComponentMarker)
+	if !ok {
+		return "", false
+	}
+	format, data, ok := strings.Cut(rest, ".")
+	if !ok {
+		return "", false
+	}
+	var raw []byte
+	var err error
+	switch KeyEncodingFormat(format) {
+	case KeyEncodingBase64:
+		raw, err = base64.RawURLEncoding.DecodeString(data)
+	case KeyEncodingHex:
+		raw, err = hex.DecodeString(data)
+	default:
+		return "", false
+	}
+	if err != nil {
+		return "", false
+	}
+	return string(raw), true
+}
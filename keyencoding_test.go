@@ -0,0 +1,52 @@
+package gparedis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyEncoding(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	files := NewRepository[TestValue](repo.provider, repo.client, "enc:file:", WithKeyEncoding(KeyEncoding{}))
+	binary := "tenant:a\x00b\nc"
+	require.NoError(t, files.Set(ctx, binary, &TestValue{ID: "bin"}))
+	require.NoError(t, files.Set(ctx, "tenant:plain", &TestValue{ID: "plain"}))
+	require.NoError(t, files.Set(ctx, "tenant:~tilde", &TestValue{ID: "tilde"}))
+	defer files.MDelete(ctx, []string{binary, "tenant:plain", "tenant:~tilde"})
+
+	raw, err := repo.client.Keys(ctx, "enc:file:*").Result()
+	require.NoError(t, err)
+	assert.Contains(t, raw, "enc:file:tenant:~b64.YQBiCmM")
+	assert.Contains(t, raw, "enc:file:tenant:plain")
+	for _, key := range raw {
+		assert.False(t, strings.ContainsAny(key, "\x00\n"), key)
+	}
+
+	value, err := files.Get(ctx, binary)
+	require.NoError(t, err)
+	assert.Equal(t, "bin", value.ID)
+
+	keys, err := files.Keys(ctx, "tenant:*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{binary, "tenant:plain", "tenant:~tilde"}, keys)
+}
+
+func TestKeyEncodingFormats(t *testing.T) {
+	hexEnc := KeyEncoding{Format: KeyEncodingHex, MaxLength: 8}
+	assert.Equal(t, "a:~hex.0001", hexEnc.encodeKey("a:\x00\x01"))
+	assert.Equal(t, "short:~hex.6c6f6e676964656e746966696572", hexEnc.encodeKey("short:longidentifier"))
+	assert.Equal(t, "a:\x00\x01", decodeKey("a:~hex.0001"))
+	assert.Equal(t, "short:longidentifier", decodeKey(hexEnc.encodeKey("short:longidentifier")))
+
+	b64 := KeyEncoding{Format: KeyEncodingBase64}
+	assert.Equal(t, "plain:*", b64.encodeKey("plain:*"), "patterns without unsafe components are unchanged")
+	assert.Equal(t, "\xff", decodeKey(b64.encodeKey("\xff")))
+	assert.Equal(t, "~zzz.abc", decodeKey("~zzz.abc"), "unknown formats are left alone")
+}
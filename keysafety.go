@@ -0,0 +1,81 @@
+package gparedis
+
+import (
+	"strings"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Key Validation and Separator Escaping
+// =====================================
+
+// globMetacharacters are interpreted by KEYS, SCAN MATCH and the pattern-based helpers
+const globMetacharacters = `*?[]\`
+
+// separatorEscaper percent-encodes the key separator (and the escape character itself)
+var separatorEscaper = strings.NewReplacer("%", "%25", keySegmentSeparator, "%3A")
+
+// separatorUnescaper reverses separatorEscaper
+var separatorUnescaper = strings.NewReplacer("%25", "%", "%3A", keySegmentSeparator)
+
+// WithStrictKeys rejects empty keys and keys containing glob metacharacters (* ? [ ] \) with
+// ErrorTypeInvalidArgument on every single-key and batch operation, so user input can't
+// create keys that later match unintended patterns in Keys, Scan or pattern-based deletes.
+// Patterns passed to Keys, Scan and List are not affected; build them with EscapeGlob.
+func WithStrictKeys() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.strictKeys = true
+	}
+}
+
+// WithEscapedSeparator percent-encodes ":" (as %3A, and "%" as %25) inside user keys, so a
+// key can never reach into another repository's namespace ("user:" + "a:b" colliding with
+// prefix "user:a:"). Keys, Scan and List skip keys under the prefix that contain a raw ":",
+// since they belong to a nested namespace, and return keys unescaped.
+func WithEscapedSeparator() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.escapeSeparator = true
+	}
+}
+
+// validateKeys enforces WithStrictKeys on full keys; scans are exempt
+func (r *Repository[T]) validateKeys(op AccessOp, fullKeys []string) error {
+	if !r.opts.strictKeys || op == AccessScan {
+		return nil
+	}
+	for _, fullKey := range fullKeys {
+		key := strings.TrimPrefix(fullKey, r.keyPrefix)
+		if key == "" {
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "key must not be empty")
+		}
+		if strings.ContainsAny(key, globMetacharacters) {
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument,
+				"key "+key+" contains glob metacharacters ("+globMetacharacters+")")
+		}
+	}
+	return nil
+}
+
+// ownsKey reports whether a full key returned by SCAN/KEYS belongs to the repository rather
+// than to a nested namespace under its prefix
+func (r *Repository[T]) ownsKey(fullKey string) bool {
+	if !r.opts.escapeSeparator {
+		return true
+	}
+	return !strings.Contains(strings.TrimPrefix(fullKey, r.keyPrefix), keySegmentSeparator)
+}
+
+// ownedKeys filters full keys down to those the repository owns
+func (r *Repository[T]) ownedKeys(fullKeys []string) []string {
+	if !r.opts.escapeSeparator {
+		return fullKeys
+	}
+	owned := fullKeys[:0:0]
+	for _, key := range fullKeys {
+		if r.ownsKey(key) {
+			owned = append(owned, key)
+		}
+	}
+	return owned
+}
@@ -0,0 +1,67 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictKeys(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	users := NewRepository[TestValue](repo.provider, repo.client, "strict:user:", WithStrictKeys())
+	defer users.MDelete(ctx, []string{"alice"})
+
+	require.NoError(t, users.Set(ctx, "alice", &TestValue{ID: "alice"}))
+	for _, key := range []string{"", "a*", "what?", "[ab]", `back\slash`} {
+		err := users.Set(ctx, key, &TestValue{ID: "bad"})
+		require.Error(t, err, key)
+		gpaErr, ok := err.(gpa.GPAError)
+		require.True(t, ok)
+		assert.Equal(t, gpa.ErrorTypeInvalidArgument, gpaErr.Type, key)
+	}
+	_, err := users.MGet(ctx, []string{"alice", "*"})
+	assert.Error(t, err)
+	_, err = users.MDelete(ctx, []string{"a?"})
+	assert.Error(t, err)
+
+	// Patterns are still accepted by the scanning operations
+	keys, err := users.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, keys)
+}
+
+func TestEscapedSeparator(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	users := NewRepository[TestValue](repo.provider, repo.client, "esc:user:", WithEscapedSeparator())
+	nested := NewRepository[TestValue](repo.provider, repo.client, "esc:user:admin:")
+	require.NoError(t, users.Set(ctx, "admin:1", &TestValue{ID: "colon"}))
+	require.NoError(t, users.Set(ctx, "50%", &TestValue{ID: "percent"}))
+	require.NoError(t, nested.Set(ctx, "1", &TestValue{ID: "nested"}))
+	defer users.MDelete(ctx, []string{"admin:1", "50%"})
+	defer nested.MDelete(ctx, []string{"1"})
+
+	exists, err := repo.client.Exists(ctx, "esc:user:admin%3A1", "esc:user:50%25").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), exists)
+
+	value, err := nested.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "nested", value.ID, "escaped keys can't collide with a nested prefix")
+
+	keys, err := users.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"admin:1", "50%"}, keys, "nested namespaces are skipped")
+
+	scanned, _, err := users.Scan(ctx, 0, "*", 100)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"admin:1", "50%"}, scanned)
+}
@@ -0,0 +1,201 @@
+package gparedis
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Key Schema Registry
+// =====================================
+
+// KeySchema records which entity type owns a key prefix
+type KeySchema struct {
+	Prefix     string
+	EntityType reflect.Type
+}
+
+// TypeName returns the entity type name, e.g. "main.User"
+func (s KeySchema) TypeName() string {
+	if s.EntityType == nil {
+		return "<nil>"
+	}
+	return s.EntityType.String()
+}
+
+// KeyCollision describes two overlapping prefixes declared for different entity types
+type KeyCollision struct {
+	Existing KeySchema
+	Incoming KeySchema
+}
+
+// String returns a readable description of the collision
+func (c KeyCollision) String() string {
+	return fmt.Sprintf("key prefix %q (%s) overlaps %q (%s)",
+		c.Incoming.Prefix, c.Incoming.TypeName(), c.Existing.Prefix, c.Existing.TypeName())
+}
+
+// KeySchemaRegistry tracks the key prefixes used by repositories and detects overlaps.
+// Two prefixes overlap when one is a prefix of the other, since a SCAN over the shorter one
+// would return the other type's keys. Declaring the same prefix for the same type is a no-op.
+type KeySchemaRegistry struct {
+	mu          sync.RWMutex
+	schemas     map[string]KeySchema
+	strict      bool
+	onCollision func(KeyCollision)
+}
+
+// NewKeySchemaRegistry creates an empty registry.
+// In strict mode collisions are rejected, otherwise they are recorded and reported to OnCollision.
+func NewKeySchemaRegistry(strict bool) *KeySchemaRegistry {
+	return &KeySchemaRegistry{schemas: make(map[string]KeySchema), strict: strict}
+}
+
+// SetStrict switches between rejecting and warning on collisions
+func (reg *KeySchemaRegistry) SetStrict(strict bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.strict = strict
+}
+
+// Strict reports whether collisions are rejected
+func (reg *KeySchemaRegistry) Strict() bool {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.strict
+}
+
+// OnCollision registers a callback invoked for every collision found in non-strict mode
+func (reg *KeySchemaRegistry) OnCollision(fn func(KeyCollision)) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.onCollision = fn
+}
+
+// Declare registers prefix as owned by entityType.
+// Returns ErrorTypeConstraint on collision in strict mode; in non-strict mode the
+// declaration is recorded and the collision reported to the OnCollision callback.
+func (reg *KeySchemaRegistry) Declare(prefix string, entityType reflect.Type) error {
+	if prefix == "" {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "key schema prefix cannot be empty")
+	}
+	incoming := KeySchema{Prefix: prefix, EntityType: entityType}
+
+	reg.mu.Lock()
+	if existing, ok := reg.schemas[prefix]; ok && existing.EntityType == entityType {
+		reg.mu.Unlock()
+		return nil
+	}
+	collisions := reg.collisionsLocked(incoming)
+	if len(collisions) > 0 && reg.strict {
+		reg.mu.Unlock()
+		descriptions := make([]string, len(collisions))
+		for i, c := range collisions {
+			descriptions[i] = c.String()
+		}
+		return gpa.NewError(gpa.ErrorTypeConstraint, strings.Join(descriptions, "; "))
+	}
+	if _, ok := reg.schemas[prefix]; !ok {
+		reg.schemas[prefix] = incoming
+	}
+	onCollision := reg.onCollision
+	reg.mu.Unlock()
+
+	if onCollision != nil {
+		for _, c := range collisions {
+			onCollision(c)
+		}
+	}
+	return nil
+}
+
+// collisionsLocked returns the registered schemas overlapping incoming with a different type
+func (reg *KeySchemaRegistry) collisionsLocked(incoming KeySchema) []KeyCollision {
+	var collisions []KeyCollision
+	for _, existing := range reg.sortedLocked() {
+		if existing.EntityType == incoming.EntityType {
+			continue
+		}
+		if strings.HasPrefix(existing.Prefix, incoming.Prefix) || strings.HasPrefix(incoming.Prefix, existing.Prefix) {
+			collisions = append(collisions, KeyCollision{Existing: existing, Incoming: incoming})
+		}
+	}
+	return collisions
+}
+
+// Schemas returns all declared schemas ordered by prefix
+func (reg *KeySchemaRegistry) Schemas() []KeySchema {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.sortedLocked()
+}
+
+// Lookup returns the schema owning key, using the longest matching prefix
+func (reg *KeySchemaRegistry) Lookup(key string) (KeySchema, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	var best KeySchema
+	found := false
+	for prefix, schema := range reg.schemas {
+		if strings.HasPrefix(key, prefix) && len(prefix) > len(best.Prefix) {
+			best, found = schema, true
+		}
+	}
+	return best, found
+}
+
+// Collisions returns every overlapping pair currently recorded (only possible in non-strict mode)
+func (reg *KeySchemaRegistry) Collisions() []KeyCollision {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	schemas := reg.sortedLocked()
+	var collisions []KeyCollision
+	for i := range schemas {
+		for j := i + 1; j < len(schemas); j++ {
+			if schemas[i].EntityType != schemas[j].EntityType && strings.HasPrefix(schemas[j].Prefix, schemas[i].Prefix) {
+				collisions = append(collisions, KeyCollision{Existing: schemas[i], Incoming: schemas[j]})
+			}
+		}
+	}
+	return collisions
+}
+
+// sortedLocked returns the schemas ordered by prefix
+func (reg *KeySchemaRegistry) sortedLocked() []KeySchema {
+	schemas := make([]KeySchema, 0, len(reg.schemas))
+	for _, schema := range reg.schemas {
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Prefix < schemas[j].Prefix })
+	return schemas
+}
+
+// KeySchemas returns the provider's key schema registry
+func (p *Provider) KeySchemas() *KeySchemaRegistry {
+	p.registryOnce.Do(func() {
+		if p.registry == nil {
+			p.registry = NewKeySchemaRegistry(false)
+		}
+	})
+	return p.registry
+}
+
+// DeclareRepository creates a repository after declaring its prefix in the provider's registry.
+// Unlike NewRepository it reports collisions as an error when the registry is strict.
+// Example: users, err := gparedis.DeclareRepository[User](provider, "user:")
+func DeclareRepository[T any](p *Provider, keyPrefix string, opts ...RepositoryOption) (*Repository[T], error) {
+	keyPrefix = taggedPrefix(keyPrefix, opts)
+	if keyPrefix != "" {
+		if err := p.KeySchemas().Declare(keyPrefix, reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+			return nil, err
+		}
+	}
+	return newRepository[T](p, p.client, keyPrefix, opts), nil
+}
@@ -0,0 +1,79 @@
+package gparedis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type keySchemaUser struct{ ID string }
+type keySchemaSession struct{ ID string }
+
+func TestKeySchemaRegistryDeclare(t *testing.T) {
+	reg := NewKeySchemaRegistry(false)
+	userType := reflect.TypeOf(keySchemaUser{})
+	sessionType := reflect.TypeOf(keySchemaSession{})
+
+	var reported []KeyCollision
+	reg.OnCollision(func(c KeyCollision) { reported = append(reported, c) })
+
+	require.NoError(t, reg.Declare("user:", userType))
+	require.NoError(t, reg.Declare("user:", userType))
+	require.NoError(t, reg.Declare("session:", sessionType))
+	assert.Empty(t, reported)
+
+	// Overlapping prefix for a different type is recorded and reported
+	require.NoError(t, reg.Declare("user:session:", sessionType))
+	require.Len(t, reported, 1)
+	assert.Equal(t, "user:", reported[0].Existing.Prefix)
+	assert.Contains(t, reported[0].String(), "gparedis.keySchemaSession")
+	assert.Len(t, reg.Collisions(), 1)
+
+	schemas := reg.Schemas()
+	require.Len(t, schemas, 3)
+	assert.Equal(t, "session:", schemas[0].Prefix)
+
+	owner, ok := reg.Lookup("user:session:42")
+	require.True(t, ok)
+	assert.Equal(t, sessionType, owner.EntityType)
+	owner, ok = reg.Lookup("user:42")
+	require.True(t, ok)
+	assert.Equal(t, userType, owner.EntityType)
+	_, ok = reg.Lookup("order:1")
+	assert.False(t, ok)
+
+	err := reg.Declare("", userType)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestKeySchemaRegistryStrict(t *testing.T) {
+	reg := NewKeySchemaRegistry(true)
+	require.NoError(t, reg.Declare("u:", reflect.TypeOf(keySchemaUser{})))
+
+	err := reg.Declare("u:", reflect.TypeOf(keySchemaSession{}))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeConstraint))
+	err = reg.Declare("u", reflect.TypeOf(keySchemaSession{}))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeConstraint))
+	assert.Len(t, reg.Schemas(), 1)
+}
+
+func TestProviderKeySchemas(t *testing.T) {
+	p := &Provider{}
+	applyProviderOptions(p, map[string]interface{}{"strict_key_schema": true})
+	assert.True(t, p.KeySchemas().Strict())
+
+	NewRepository[keySchemaUser](p, nil, "u:")
+	NewRepository[keySchemaUser](p, nil, "")
+	assert.Len(t, p.KeySchemas().Schemas(), 1)
+
+	_, err := DeclareRepository[keySchemaSession](p, "u:s:")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeConstraint))
+	assert.Panics(t, func() { NewRepository[keySchemaSession](p, nil, "u:") })
+
+	repo, err := DeclareRepository[keySchemaSession](p, "s:")
+	require.NoError(t, err)
+	assert.Equal(t, "s:", repo.keyPrefix)
+}
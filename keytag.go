@@ -0,0 +1,70 @@
+package gparedis
+
+import "strings"
+
+// =====================================
+// Hash-Tag Key Building
+// =====================================
+
+// keySegmentSeparator separates the segments of a key for WithSegmentHashTag
+const keySegmentSeparator = ":"
+
+// WithPrefixHashTag wraps the repository prefix in a hash tag ("user:" becomes "{user}:"),
+// so every key of the repository maps to one cluster slot and multi-key commands and
+// transactions work in cluster mode. Prefixes that already contain a hash tag are kept.
+// Example: users := gparedis.NewRepository[User](provider, client, "user:", gparedis.WithPrefixHashTag())
+func WithPrefixHashTag() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.prefixHashTag = true
+	}
+}
+
+// WithSegmentHashTag wraps one ":"-separated segment of every repository key (0-based,
+// after the prefix) in a hash tag, so all keys sharing that segment, such as a tenant ID,
+// land on the same cluster slot. Keys passed to the repository should not contain braces.
+// Example: orders := gparedis.NewRepository[Order](provider, client, "order:", gparedis.WithSegmentHashTag(0))
+// stores orders.Set(ctx, "tenant42:1001", order) at "order:{tenant42}:1001".
+func WithSegmentHashTag(segment int) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.segmentHashTag = segment >= 0
+		o.hashTagSegment = segment
+	}
+}
+
+// taggedPrefix returns the prefix a repository created with opts stores its keys under
+func taggedPrefix(keyPrefix string, opts []RepositoryOption) string {
+	var o repositoryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !o.prefixHashTag || keyPrefix == "" || HashTagOf(keyPrefix) != keyPrefix {
+		return keyPrefix
+	}
+	name := strings.TrimSuffix(keyPrefix, keySegmentSeparator)
+	return HashTag(name) + keyPrefix[len(name):]
+}
+
+// tagSegment wraps segment i of key in a hash tag. Keys with fewer segments, and segments
+// that are empty or hold glob characters (in SCAN patterns), are left unchanged.
+func tagSegment(key string, i int) string {
+	segments := strings.Split(key, keySegmentSeparator)
+	if i >= len(segments) || segments[i] == "" || strings.ContainsAny(segments[i], "*?[{}") {
+		return key
+	}
+	segments[i] = HashTag(segments[i])
+	return strings.Join(segments, keySegmentSeparator)
+}
+
+// untagSegment reverses tagSegment
+func untagSegment(key string, i int) string {
+	segments := strings.Split(key, keySegmentSeparator)
+	if i >= len(segments) {
+		return key
+	}
+	s := segments[i]
+	if len(s) < 3 || s[0] != '{' || s[len(s)-1] != '}' {
+		return key
+	}
+	segments[i] = s[1 : len(s)-1]
+	return strings.Join(segments, keySegmentSeparator)
+}
@@ -0,0 +1,82 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixHashTag(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	users := NewRepository[TestValue](repo.provider, repo.client, "tagged:user:", WithPrefixHashTag())
+	require.NoError(t, users.Set(ctx, "1", &TestValue{ID: "1"}))
+	require.NoError(t, users.Set(ctx, "2", &TestValue{ID: "2"}))
+	defer users.MDelete(ctx, []string{"1", "2"})
+
+	exists, err := repo.client.Exists(ctx, "{tagged:user}:1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), exists)
+	assert.True(t, SameSlot(users.buildKey("1"), users.buildKey("2"), users.buildKey("anything")))
+
+	keys, err := users.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, keys)
+
+	_, ok := repo.provider.KeySchemas().Lookup("{tagged:user}:1")
+	assert.True(t, ok, "the tagged prefix is declared")
+
+	// Multi-key transactions pass the cluster-mode slot check
+	repo.provider.clusterMode = true
+	defer func() { repo.provider.clusterMode = false }()
+	err = repo.provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		if err := users.SetTx(tx, "1", &TestValue{ID: "1b"}); err != nil {
+			return err
+		}
+		return users.SetTx(tx, "3", &TestValue{ID: "3"})
+	})
+	require.NoError(t, err)
+	defer users.DeleteKey(ctx, "3")
+
+	assert.Equal(t, "{already}:x:", taggedPrefix("{already}:x:", []RepositoryOption{WithPrefixHashTag()}))
+	assert.Equal(t, "{plain}", taggedPrefix("plain", []RepositoryOption{WithPrefixHashTag()}))
+	assert.Equal(t, "plain:", taggedPrefix("plain:", nil))
+}
+
+func TestSegmentHashTag(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orders := NewRepository[TestValue](repo.provider, repo.client, "tagged:order:", WithSegmentHashTag(0))
+	require.NoError(t, orders.Set(ctx, "t1:1001", &TestValue{ID: "1001"}))
+	require.NoError(t, orders.Set(ctx, "t1:1002", &TestValue{ID: "1002"}))
+	require.NoError(t, orders.Set(ctx, "t2:2001", &TestValue{ID: "2001"}))
+	defer orders.MDelete(ctx, []string{"t1:1001", "t1:1002", "t2:2001"})
+
+	exists, err := repo.client.Exists(ctx, "tagged:order:{t1}:1001").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), exists)
+	assert.True(t, SameSlot(orders.buildKey("t1:1001"), orders.buildKey("t1:1002")))
+
+	value, err := orders.Get(ctx, "t1:1001")
+	require.NoError(t, err)
+	assert.Equal(t, "1001", value.ID)
+
+	keys, err := orders.Keys(ctx, "t1:*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"t1:1001", "t1:1002"}, keys)
+	keys, err = orders.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Len(t, keys, 3)
+
+	assert.Equal(t, "a:{b}:c", tagSegment("a:b:c", 1))
+	assert.Equal(t, "a:*", tagSegment("a:*", 1))
+	assert.Equal(t, "a", tagSegment("a", 1))
+	assert.Equal(t, "a:b:c", untagSegment("a:{b}:c", 1))
+	assert.Equal(t, "a:{}:c", untagSegment("a:{}:c", 1))
+}
@@ -0,0 +1,256 @@
+// Package kvtest is a conformance suite for gpa.AdvancedKeyValueRepository implementations.
+// Decorators wrapping a gparedis repository (caches, fault injection, mirroring) run it to
+// check they keep the key-value semantics callers rely on:
+//
+//	func TestCachedRepository(t *testing.T) {
+//		kvtest.RunKVRepositoryTests(t, func(t *testing.T) gpa.AdvancedKeyValueRepository[kvtest.Entity] {
+//			inner := gparedis.NewRepository[kvtest.Entity](provider, client, kvtest.Prefix(t))
+//			return NewCached(inner)
+//		})
+//	}
+package kvtest
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Entity is the value type the suite stores
+type Entity struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Factory returns an empty repository for one subtest. Repositories handed to different
+// subtests must not see each other's keys; Prefix gives a suitable key prefix. Register
+// cleanup on t.
+type Factory func(t *testing.T) gpa.AdvancedKeyValueRepository[Entity]
+
+// Prefix returns a key prefix unique to the test, for factories isolating subtests by
+// prefix
+func Prefix(t *testing.T) string {
+	return "kvtest:" + strings.NewReplacer(" ", "_", "*", "_", "?", "_", "[", "_", "]", "_").Replace(t.Name()) + ":"
+}
+
+// RunKVRepositoryTests runs the conformance suite, one subtest per behavior, each against
+// a fresh repository from factory
+// Example: kvtest.RunKVRepositoryTests(t, newDecoratedRepository)
+func RunKVRepositoryTests(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity])
+	}{
+		{"SetGet", testSetGet},
+		{"GetMissing", testGetMissing},
+		{"SetOverwrites", testSetOverwrites},
+		{"DeleteKey", testDeleteKey},
+		{"KeyExists", testKeyExists},
+		{"MSetMGet", testMSetMGet},
+		{"MDelete", testMDelete},
+		{"SetWithTTL", testSetWithTTL},
+		{"SetTTL", testSetTTL},
+		{"RemoveTTL", testRemoveTTL},
+		{"IncrementDecrement", testIncrementDecrement},
+		{"Keys", testKeys},
+		{"Scan", testScan},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, context.Background(), factory(t))
+		})
+	}
+}
+
+func testSetGet(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	want := &Entity{ID: "1", Name: "Ann", Count: 3}
+	require.NoError(t, repo.Set(ctx, "1", want))
+	got, err := repo.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func testGetMissing(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	_, err := repo.Get(ctx, "missing")
+	assertErrorType(t, err, gpa.ErrorTypeNotFound)
+}
+
+func testSetOverwrites(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	require.NoError(t, repo.Set(ctx, "1", &Entity{ID: "1", Name: "Ann"}))
+	require.NoError(t, repo.Set(ctx, "1", &Entity{ID: "1", Name: "Bob"}))
+	got, err := repo.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", got.Name)
+}
+
+func testDeleteKey(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	require.NoError(t, repo.Set(ctx, "1", &Entity{ID: "1"}))
+	require.NoError(t, repo.DeleteKey(ctx, "1"))
+	_, err := repo.Get(ctx, "1")
+	assertErrorType(t, err, gpa.ErrorTypeNotFound)
+
+	// Deleting a missing key either succeeds or reports it as not found
+	if err := repo.DeleteKey(ctx, "1"); err != nil {
+		assertErrorType(t, err, gpa.ErrorTypeNotFound)
+	}
+}
+
+func testKeyExists(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	exists, err := repo.KeyExists(ctx, "1")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.NoError(t, repo.Set(ctx, "1", &Entity{ID: "1"}))
+	exists, err = repo.KeyExists(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func testMSetMGet(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	pairs := map[string]*Entity{
+		"1": {ID: "1", Name: "Ann"},
+		"2": {ID: "2", Name: "Bob"},
+	}
+	require.NoError(t, repo.MSet(ctx, pairs))
+
+	got, err := repo.MGet(ctx, []string{"1", "2", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, pairs, got, "missing keys are omitted")
+
+	got, err = repo.MGet(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	require.NoError(t, repo.MSet(ctx, map[string]*Entity{}))
+}
+
+func testMDelete(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	require.NoError(t, repo.MSet(ctx, map[string]*Entity{"1": {ID: "1"}, "2": {ID: "2"}}))
+	deleted, err := repo.MDelete(ctx, []string{"1", "2", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted, "only existing keys are counted")
+
+	got, err := repo.MGet(ctx, []string{"1", "2"})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	deleted, err = repo.MDelete(ctx, nil)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func testSetWithTTL(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	require.NoError(t, repo.SetWithTTL(ctx, "1", &Entity{ID: "1"}, time.Minute))
+	ttl, err := repo.GetTTL(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= time.Minute, "TTL %v out of range", ttl)
+
+	require.NoError(t, repo.Set(ctx, "2", &Entity{ID: "2"}))
+	ttl, err = repo.GetTTL(ctx, "2")
+	require.NoError(t, err)
+	assert.True(t, ttl <= 0, "keys without TTL report a non-positive TTL, got %v", ttl)
+
+	// Set replaces the TTL along with the value
+	require.NoError(t, repo.Set(ctx, "1", &Entity{ID: "1"}))
+	ttl, err = repo.GetTTL(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, ttl <= 0, "Set clears the TTL, got %v", ttl)
+}
+
+func testSetTTL(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	require.NoError(t, repo.Set(ctx, "1", &Entity{ID: "1"}))
+	require.NoError(t, repo.SetTTL(ctx, "1", time.Minute))
+	ttl, err := repo.GetTTL(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= time.Minute, "TTL %v out of range", ttl)
+
+	assertErrorType(t, repo.SetTTL(ctx, "missing", time.Minute), gpa.ErrorTypeNotFound)
+}
+
+func testRemoveTTL(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	require.NoError(t, repo.SetWithTTL(ctx, "1", &Entity{ID: "1"}, time.Minute))
+	require.NoError(t, repo.RemoveTTL(ctx, "1"))
+	ttl, err := repo.GetTTL(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, ttl <= 0, "TTL removed, got %v", ttl)
+	got, err := repo.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", got.ID)
+}
+
+func testIncrementDecrement(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	n, err := repo.Increment(ctx, "counter", 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n, "missing counters start at zero")
+	n, err = repo.Increment(ctx, "counter", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), n)
+	n, err = repo.Decrement(ctx, "counter", 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-3), n)
+	n, err = repo.Decrement(ctx, "other", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), n)
+}
+
+func testKeys(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	require.NoError(t, repo.MSet(ctx, map[string]*Entity{
+		"user:1":  {ID: "1"},
+		"user:2":  {ID: "2"},
+		"order:1": {ID: "1"},
+	}))
+	keys, err := repo.Keys(ctx, "user:*")
+	require.NoError(t, err)
+	sort.Strings(keys)
+	assert.Equal(t, []string{"user:1", "user:2"}, keys)
+
+	keys, err = repo.Keys(ctx, "nothing:*")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func testScan(t *testing.T, ctx context.Context, repo gpa.AdvancedKeyValueRepository[Entity]) {
+	pairs := make(map[string]*Entity)
+	var want []string
+	for i := 0; i < 25; i++ {
+		key := "user:" + string(rune('a'+i))
+		pairs[key] = &Entity{ID: key}
+		want = append(want, key)
+	}
+	pairs["order:1"] = &Entity{ID: "order:1"}
+	require.NoError(t, repo.MSet(ctx, pairs))
+
+	seen := make(map[string]bool)
+	var cursor uint64
+	for i := 0; ; i++ {
+		require.Less(t, i, 1000, "scan doesn't terminate")
+		keys, next, err := repo.Scan(ctx, cursor, "user:*", 5)
+		require.NoError(t, err)
+		for _, key := range keys {
+			seen[key] = true
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	got := make([]string, 0, len(seen))
+	for key := range seen {
+		got = append(got, key)
+	}
+	sort.Strings(got)
+	assert.Equal(t, want, got, "a full scan returns every matching key, unprefixed")
+}
+
+// assertErrorType checks err is a gpa error of the given type
+func assertErrorType(t *testing.T, err error, errType gpa.ErrorType) {
+	t.Helper()
+	require.Error(t, err)
+	gpaErr, ok := err.(gpa.GPAError)
+	require.True(t, ok, "expected a gpa.GPAError, got %T: %v", err, err)
+	assert.Equal(t, errType, gpaErr.Type, err.Error())
+}
@@ -0,0 +1,33 @@
+package kvtest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/lemmego/gparedis"
+)
+
+func TestRepositoryConformance(t *testing.T) {
+	redisURL := os.Getenv("REDIS_TEST_URL")
+	if redisURL == "" {
+		redisURL = "redis://localhost:6379"
+	}
+	provider, err := gparedis.NewProvider(gpa.Config{Driver: "redis", ConnectionURL: redisURL})
+	if err != nil {
+		t.Skipf("Skipping Redis tests: %v", err)
+	}
+	t.Cleanup(func() { provider.Close() })
+	client := provider.Client().(*redis.Client)
+
+	RunKVRepositoryTests(t, func(t *testing.T) gpa.AdvancedKeyValueRepository[Entity] {
+		repo := gparedis.NewRepository[Entity](provider, client, Prefix(t))
+		t.Cleanup(func() {
+			keys, _ := repo.Keys(context.Background(), "*")
+			repo.MDelete(context.Background(), keys)
+		})
+		return repo
+	})
+}
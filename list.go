@@ -0,0 +1,173 @@
+package gparedis
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Paginated Listing
+// =====================================
+
+// ListOptions configures one List call
+type ListOptions struct {
+	// Pattern restricts the listing to matching keys (relative to the prefix, default "*").
+	// Ignored when PageToken is set; the token carries the pattern of the first page.
+	Pattern string
+	// PageSize is the number of keys requested per page (default 100). SCAN batches are
+	// approximate, so a page may hold slightly more or fewer keys.
+	PageSize int64
+	// PageToken continues a previous listing; empty starts a new one
+	PageToken string
+}
+
+// ListPage is one page of a listing
+type ListPage[T any] struct {
+	Keys  []string
+	Items []*T
+	// NextPageToken continues the listing; empty on the last page
+	NextPageToken string
+}
+
+// pageToken is the signed state embedded in page tokens
+type pageToken struct {
+	Prefix  string `json:"p"`
+	Pattern string `json:"m"`
+	Cursor  uint64 `json:"c"`
+}
+
+// SetPageTokenKey sets the HMAC key signing List page tokens. Without a key, a random
+// per-provider key is generated, so tokens only work within one process; set a shared
+// key when tokens are handed out by several instances.
+func (p *Provider) SetPageTokenKey(key []byte) {
+	p.pageTokenMu.Lock()
+	defer p.pageTokenMu.Unlock()
+	p.pageTokenKey = append([]byte(nil), key...)
+}
+
+// pageTokenSigningKey returns the page token key, generating one on first use
+func (p *Provider) pageTokenSigningKey() ([]byte, error) {
+	p.pageTokenMu.Lock()
+	defer p.pageTokenMu.Unlock()
+	if len(p.pageTokenKey) == 0 {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to generate page token key", err)
+		}
+		p.pageTokenKey = key
+	}
+	return p.pageTokenKey, nil
+}
+
+// encodePageToken serializes and signs a token as base64url(json) "." base64url(hmac)
+func encodePageToken(token pageToken, key []byte) string {
+	payload, _ := json.Marshal(token)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// decodePageToken verifies and parses a token produced by encodePageToken
+func decodePageToken(s string, key []byte) (pageToken, error) {
+	var token pageToken
+	invalid := gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid page token")
+
+	encoded, signature, ok := strings.Cut(s, ".")
+	if !ok {
+		return token, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return token, invalid
+	}
+	actual, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return token, invalid
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(actual, mac.Sum(nil)) {
+		return token, invalid
+	}
+	if err := json.Unmarshal(payload, &token); err != nil {
+		return token, invalid
+	}
+	return token, nil
+}
+
+// List returns one page of values under the repository prefix. Page tokens are signed with
+// the provider's page token key and bound to this prefix, so they can be exposed to HTTP
+// clients without letting them alter the SCAN cursor, prefix or pattern.
+// Example: page, err := users.List(ctx, gparedis.ListOptions{PageSize: 50, PageToken: r.URL.Query().Get("page")})
+func (r *Repository[T]) List(ctx context.Context, opts ListOptions) (*ListPage[T], error) {
+	key, err := r.provider.pageTokenSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = scanBatchSize
+	}
+
+	state := pageToken{Prefix: r.keyPrefix, Pattern: opts.Pattern}
+	if state.Pattern == "" {
+		state.Pattern = "*"
+	}
+	if opts.PageToken != "" {
+		if state, err = decodePageToken(opts.PageToken, key); err != nil {
+			return nil, err
+		}
+		if state.Prefix != r.keyPrefix || state.Cursor == 0 {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid page token")
+		}
+	}
+
+	if err := r.authorize(ctx, AccessScan, r.buildKey(state.Pattern)); err != nil {
+		return nil, err
+	}
+
+	page := &ListPage[T]{Keys: []string{}, Items: []*T{}}
+	cursor := state.Cursor
+	var fullKeys []string
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, r.buildKey(state.Pattern), pageSize).Result()
+		if err != nil {
+			return nil, convertRedisError(err)
+		}
+		fullKeys = append(fullKeys, r.ownedKeys(keys)...)
+		cursor = next
+		if cursor == 0 || int64(len(fullKeys)) >= pageSize {
+			break
+		}
+	}
+
+	if len(fullKeys) > 0 {
+		keys := make([]string, len(fullKeys))
+		for i, fullKey := range fullKeys {
+			keys[i] = r.trimKey(fullKey)
+		}
+		found, err := r.MGet(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if value, ok := found[k]; ok {
+				page.Keys = append(page.Keys, k)
+				page.Items = append(page.Items, value)
+			}
+		}
+	}
+
+	if cursor != 0 {
+		state.Cursor = cursor
+		page.NextPageToken = encodePageToken(state, key)
+	}
+	return page, nil
+}
@@ -0,0 +1,77 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPagination(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[TestValue](base.provider, base.client, "item:")
+	other := NewRepository[TestValue](base.provider, base.client, "other:")
+	for i := 0; i < 25; i++ {
+		require.NoError(t, repo.Set(ctx, fmt.Sprintf("%02d", i), &TestValue{ID: fmt.Sprint(i)}))
+	}
+	require.NoError(t, other.Set(ctx, "x", &TestValue{ID: "x"}))
+
+	seen := map[string]bool{}
+	token := ""
+	pages := 0
+	for {
+		page, err := repo.List(ctx, ListOptions{PageSize: 10, PageToken: token})
+		require.NoError(t, err)
+		require.Len(t, page.Items, len(page.Keys))
+		for i, key := range page.Keys {
+			seen[key] = true
+			assert.Equal(t, strings.TrimLeft(key, "0"), strings.TrimLeft(page.Items[i].ID, "0"))
+		}
+		pages++
+		if page.NextPageToken == "" {
+			break
+		}
+		token = page.NextPageToken
+	}
+	assert.Len(t, seen, 25)
+	assert.GreaterOrEqual(t, pages, 1)
+
+	// Tokens are bound to the repository prefix
+	key, err := base.provider.pageTokenSigningKey()
+	require.NoError(t, err)
+	token = encodePageToken(pageToken{Prefix: "item:", Pattern: "*", Cursor: 7}, key)
+	_, err = other.List(ctx, ListOptions{PageToken: token})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestPageTokenTampering(t *testing.T) {
+	key := []byte("secret")
+	token := encodePageToken(pageToken{Prefix: "item:", Pattern: "*", Cursor: 42}, key)
+
+	decoded, err := decodePageToken(token, key)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), decoded.Cursor)
+
+	forged := encodePageToken(pageToken{Prefix: "admin:", Pattern: "*", Cursor: 42}, []byte("guess"))
+	_, err = decodePageToken(forged, key)
+	assert.Error(t, err)
+
+	payload, signature, _ := strings.Cut(token, ".")
+	_, err = decodePageToken(payload+"x."+signature, key)
+	assert.Error(t, err)
+	_, err = decodePageToken("garbage", key)
+	assert.Error(t, err)
+
+	p := &Provider{}
+	applyProviderOptions(p, map[string]interface{}{"page_token_key": "shared"})
+	signing, err := p.pageTokenSigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("shared"), signing)
+}
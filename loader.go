@@ -0,0 +1,113 @@
+package gparedis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Read-Through Loading
+// =====================================
+
+// BatchLoader loads values for keys that were not found in Redis.
+// Keys missing from the returned map are treated as absent in the source too.
+type BatchLoader[T any] func(missing []string) (map[string]*T, error)
+
+// MGetOrLoad fetches keys from Redis, calls loader once with all missing keys, writes the
+// loaded values back in a single pipeline with the given TTL (0 for no expiration),
+// and returns the merged result. Keys absent from both Redis and the loader are omitted.
+// When another key already holds a loaded unique index value, the loaded values are returned
+// without being written back.
+// Example: users, err := repo.MGetOrLoad(ctx, ids, loadUsersFromSQL, 10*time.Minute)
+func (r *Repository[T]) MGetOrLoad(ctx context.Context, keys []string, loader BatchLoader[T], ttl time.Duration) (map[string]*T, error) {
+	found, err := r.MGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]string, 0, len(keys)-len(found))
+	requested := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if _, ok := found[key]; !ok && !requested[key] {
+			missing = append(missing, key)
+		}
+		requested[key] = true
+	}
+	if len(missing) == 0 {
+		return found, nil
+	}
+
+	loaded, err := loader(missing)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "loader failed", err)
+	}
+
+	payloads := make(map[string][]byte, len(loaded))
+	for key, value := range loaded {
+		if !requested[key] || value == nil {
+			continue
+		}
+		data, err := r.encode(value)
+		if err != nil {
+			return nil, err
+		}
+		payloads[key] = data
+		found[key] = value
+	}
+
+	if len(payloads) > 0 {
+		if err := r.authorizeKeys(ctx, AccessWrite, sortedKeys(payloads)...); err != nil {
+			return nil, err
+		}
+		if err := r.checkQuota(ctx); err != nil {
+			return nil, err
+		}
+		written := make(map[string]*T, len(payloads))
+		for key := range payloads {
+			written[key] = found[key]
+		}
+		if err := r.claimUnique(ctx, written); err != nil {
+			if gpa.IsErrorType(err, gpa.ErrorTypeDuplicate) {
+				// Another key holds a loaded unique value: serve the loaded values uncached
+				return found, nil
+			}
+			return nil, err
+		}
+		if err := r.writeBack(ctx, payloads, ttl); err != nil {
+			return nil, r.abandonClaims(ctx, sortedKeys(written), err)
+		}
+		if err := r.afterSet(ctx, written, ttl); err != nil {
+			return nil, err
+		}
+		if err := r.recordWrite(ctx, "load", sortedKeys(written)...); err != nil {
+			return nil, err
+		}
+	}
+
+	return found, nil
+}
+
+// writeBack stores loaded payloads with their retention TTLs, capturing changes when enabled
+func (r *Repository[T]) writeBack(ctx context.Context, payloads map[string][]byte, ttl time.Duration) error {
+	if r.opts.changeStream != "" {
+		keys := sortedKeys(payloads)
+		data := make([][]byte, len(keys))
+		ttls := make([]time.Duration, len(keys))
+		for i, key := range keys {
+			data[i] = payloads[key]
+			ttls[i] = r.retentionTTL(key, ttl)
+		}
+		_, err := r.captureWrite(ctx, ChangeOpSet, keys, data, ttls)
+		return err
+	}
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, data := range payloads {
+			pipe.Set(ctx, r.buildKey(key), data, r.retentionTTL(key, ttl))
+		}
+		return nil
+	})
+	return convertRedisError(err)
+}
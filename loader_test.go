@@ -0,0 +1,56 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMGetOrLoad(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, repo.Set(ctx, "user:1", &TestValue{ID: "user:1", Name: "Cached"}))
+
+	calls := 0
+	loader := func(missing []string) (map[string]*TestValue, error) {
+		calls++
+		assert.ElementsMatch(t, []string{"user:2", "user:3"}, missing)
+		return map[string]*TestValue{
+			"user:2": {ID: "user:2", Name: "Loaded"},
+		}, nil
+	}
+
+	result, err := repo.MGetOrLoad(ctx, []string{"user:1", "user:2", "user:3", "user:2"}, loader, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "Cached", result["user:1"].Name)
+	assert.Equal(t, "Loaded", result["user:2"].Name)
+
+	// Loaded values were written back with the TTL
+	stored, err := repo.Get(ctx, "user:2")
+	require.NoError(t, err)
+	assert.Equal(t, "Loaded", stored.Name)
+	ttl, err := repo.GetTTL(ctx, "user:2")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+
+	// Everything cached: the loader is not called
+	_, err = repo.MGetOrLoad(ctx, []string{"user:1", "user:2"}, func([]string) (map[string]*TestValue, error) {
+		t.Fatal("loader must not be called")
+		return nil, nil
+	}, time.Minute)
+	require.NoError(t, err)
+
+	_, err = repo.MGetOrLoad(ctx, []string{"user:9"}, func([]string) (map[string]*TestValue, error) {
+		return nil, errors.New("db down")
+	}, time.Minute)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInternal))
+}
@@ -0,0 +1,184 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Load Shedding
+// =====================================
+
+// Priority ranks operations for load shedding; higher values are shed last
+type Priority int
+
+const (
+	// PriorityLow marks work that may be dropped under load (prefetching, analytics, warmups)
+	PriorityLow Priority = -10
+	// PriorityNormal is the priority of operations without an explicit priority
+	PriorityNormal Priority = 0
+	// PriorityCritical marks work that is never shed
+	PriorityCritical Priority = 10
+)
+
+// priorityKey stores the operation priority in a context
+type priorityKey struct{}
+
+// WithPriority returns a context whose Redis commands run with priority p
+// Example: _, err := cache.Get(gparedis.WithPriority(ctx, gparedis.PriorityLow), key)
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority set by WithPriority, or PriorityNormal
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// LoadShedOptions configures load shedding
+type LoadShedOptions struct {
+	// PoolTimeouts is the number of connection pool timeouts within Window that turns
+	// shedding on (required)
+	PoolTimeouts uint32
+	// Window is the evaluation period (default 10s). Shedding starts as soon as the
+	// threshold is reached and stops after a full window below it.
+	Window time.Duration
+	// ShedBelow is the priority under which operations are shed (default PriorityNormal,
+	// i.e. only operations marked below normal, such as PriorityLow, are shed)
+	ShedBelow Priority
+	// OnChange is called when shedding turns on or off
+	OnChange func(shedding bool)
+}
+
+// LoadShedStatus reports the state of load shedding
+type LoadShedStatus struct {
+	Options  LoadShedOptions
+	Shedding bool
+	// Shed counts commands rejected since load shedding was configured
+	Shed uint64
+}
+
+// loadShedder watches pool timeouts and rejects low-priority commands while they are high
+type loadShedder struct {
+	opts      LoadShedOptions
+	poolStats func() *redis.PoolStats
+
+	mu          sync.Mutex
+	started     bool
+	windowStart time.Time
+	baseline    uint32
+
+	shedding atomic.Bool
+	shed     atomic.Uint64
+}
+
+// newLoadShedder creates a shedder reading pool statistics from stats
+func newLoadShedder(opts LoadShedOptions, stats func() *redis.PoolStats) *loadShedder {
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Second
+	}
+	return &loadShedder{opts: opts, poolStats: stats}
+}
+
+// update re-evaluates the shedding state from the pool timeouts seen in the current window
+func (s *loadShedder) update(now time.Time) {
+	timeouts := s.poolStats().Timeouts
+
+	s.mu.Lock()
+	if !s.started {
+		s.started = true
+		s.windowStart = now
+		s.baseline = timeouts
+	}
+	exceeded := timeouts-s.baseline >= s.opts.PoolTimeouts
+	var changed, shedding bool
+	switch {
+	case exceeded:
+		changed = !s.shedding.Swap(true)
+		shedding = true
+	case now.Sub(s.windowStart) >= s.opts.Window:
+		changed = s.shedding.Swap(false)
+	}
+	if now.Sub(s.windowStart) >= s.opts.Window {
+		s.windowStart = now
+		s.baseline = timeouts
+	}
+	s.mu.Unlock()
+
+	if changed && s.opts.OnChange != nil {
+		s.opts.OnChange(shedding)
+	}
+}
+
+// admit rejects n commands issued with ctx when shedding and their priority is too low
+func (s *loadShedder) admit(ctx context.Context, n int) error {
+	s.update(time.Now())
+	if !s.shedding.Load() {
+		return nil
+	}
+	priority := PriorityFromContext(ctx)
+	if priority >= s.opts.ShedBelow {
+		return nil
+	}
+	s.shed.Add(uint64(n))
+	return gpa.NewError(ErrorTypeOverloaded,
+		fmt.Sprintf("operation with priority %d shed: connection pool is overloaded", priority))
+}
+
+// SetLoadShedding enables an adaptive mode that rejects operations below ShedBelow with
+// ErrorTypeOverloaded while the connection pool keeps timing out, instead of queueing every
+// caller behind the pool and hurting critical paths. Mark operations with WithPriority.
+// A zero PoolTimeouts disables load shedding.
+// Example: provider.SetLoadShedding(gparedis.LoadShedOptions{PoolTimeouts: 5, Window: 10 * time.Second})
+func (p *Provider) SetLoadShedding(opts LoadShedOptions) {
+	if opts.PoolTimeouts == 0 {
+		p.loadShed.Store(nil)
+		return
+	}
+	p.loadShed.Store(newLoadShedder(opts, func() *redis.PoolStats { return p.client.PoolStats() }))
+}
+
+// LoadShedding returns the load shedding state; ok is false when it is disabled
+func (p *Provider) LoadShedding() (status LoadShedStatus, ok bool) {
+	s := p.loadShed.Load()
+	if s == nil {
+		return LoadShedStatus{}, false
+	}
+	return LoadShedStatus{Options: s.opts, Shedding: s.shedding.Load(), Shed: s.shed.Load()}, true
+}
+
+// loadShedHook applies the provider's load shedder to every command and pipeline
+type loadShedHook struct {
+	provider *Provider
+}
+
+func (h *loadShedHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if s := h.provider.loadShed.Load(); s != nil {
+		return ctx, s.admit(ctx, 1)
+	}
+	return ctx, nil
+}
+
+func (h *loadShedHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *loadShedHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if s := h.provider.loadShed.Load(); s != nil {
+		return ctx, s.admit(ctx, len(cmds))
+	}
+	return ctx, nil
+}
+
+func (h *loadShedHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
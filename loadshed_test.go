@@ -0,0 +1,69 @@
+package gparedis
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadShedding(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, repo.Set(ctx, "shed:1", &TestValue{ID: "1"}))
+	defer repo.DeleteKey(ctx, "shed:1")
+
+	var timeouts atomic.Uint32
+	var changes []bool
+	repo.provider.loadShed.Store(newLoadShedder(LoadShedOptions{
+		PoolTimeouts: 3,
+		Window:       50 * time.Millisecond,
+		OnChange:     func(shedding bool) { changes = append(changes, shedding) },
+	}, func() *redis.PoolStats { return &redis.PoolStats{Timeouts: timeouts.Load()} }))
+	defer repo.provider.SetLoadShedding(LoadShedOptions{})
+
+	low := WithPriority(ctx, PriorityLow)
+	_, err := repo.Get(low, "shed:1")
+	require.NoError(t, err, "no pool timeouts yet")
+
+	timeouts.Store(3)
+	_, err = repo.Get(low, "shed:1")
+	assert.True(t, IsOverloadedError(err), "unexpected error: %v", err)
+	_, err = repo.MGet(low, []string{"shed:1"})
+	assert.True(t, IsOverloadedError(err))
+
+	// Normal and critical operations still run
+	_, err = repo.Get(ctx, "shed:1")
+	require.NoError(t, err)
+	_, err = repo.Get(WithPriority(ctx, PriorityCritical), "shed:1")
+	require.NoError(t, err)
+
+	status, ok := repo.provider.LoadShedding()
+	require.True(t, ok)
+	assert.True(t, status.Shedding)
+	assert.Equal(t, uint64(2), status.Shed)
+
+	// A quiet window turns shedding off: the first evaluation starts a new window with the
+	// timeouts as baseline, the next one ends it without new timeouts
+	time.Sleep(60 * time.Millisecond)
+	_, _ = repo.Get(ctx, "shed:1")
+	time.Sleep(60 * time.Millisecond)
+	_, err = repo.Get(low, "shed:1")
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, changes)
+
+	repo.provider.SetLoadShedding(LoadShedOptions{})
+	_, ok = repo.provider.LoadShedding()
+	assert.False(t, ok)
+}
+
+func TestPriorityFromContext(t *testing.T) {
+	assert.Equal(t, PriorityNormal, PriorityFromContext(context.Background()))
+	assert.Equal(t, PriorityLow, PriorityFromContext(WithPriority(context.Background(), PriorityLow)))
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -20,10 +22,82 @@ import (
 type Provider struct {
 	client *redis.Client
 	config gpa.Config
+
+	// clusterMode makes multi-key helpers enforce single-slot semantics
+	clusterMode bool
+
+	registry     *KeySchemaRegistry
+	registryOnce sync.Once
+
+	messageSchemas     *MessageSchemaRegistry
+	messageSchemasOnce sync.Once
+
+	events          *EventBus
+	eventsOnce      sync.Once
+	slowOpThreshold atomic.Int64
+
+	retentionMu      sync.RWMutex
+	retention        map[string]RetentionPolicy
+	retentionSources map[string]retentionSource
+
+	subjectMu      sync.RWMutex
+	subjectSources map[string]subjectSource
+	erasureKey     []byte
+
+	referencesMu     sync.RWMutex
+	referenceSources map[string]map[string]referenceSource
+
+	rekeyMu      sync.RWMutex
+	rekeySources map[string]rekeySource
+
+	// readOnly rejects mutating commands at the client hook level
+	readOnly atomic.Bool
+	// maintenance, when set, rejects commands during maintenance windows
+	maintenance atomic.Pointer[MaintenanceGate]
+	// adminAccess gates the administrative commands
+	adminAccess atomic.Int32
+	// dryRun, when set, records mutating commands instead of executing them
+	dryRun atomic.Pointer[DryRunPlan]
+	// throttle, when set, limits command throughput and concurrent pipelines
+	throttle atomic.Pointer[throttle]
+	// metrics, when set, collects key access statistics
+	metrics atomic.Pointer[Metrics]
+	// loadShed, when set, rejects low-priority commands while the pool is overloaded
+	loadShed atomic.Pointer[loadShedder]
+	// commandTimeouts, when set, bounds commands by class
+	commandTimeouts atomic.Pointer[CommandTimeouts]
+	// payloadOpts, when set, compresses and limits outgoing payloads
+	payloadOpts atomic.Pointer[PayloadOptions]
+	// sampler, when set, records a fraction of commands for Diagnostics
+	sampler atomic.Pointer[commandSampler]
+	// recorder, when set, writes command/reply pairs for replay
+	recorder *commandRecorder
+
+	pageTokenMu  sync.Mutex
+	pageTokenKey []byte
+
+	authorizerMu sync.RWMutex
+	authorizer   Authorizer
+
+	nodesMu      sync.RWMutex
+	replicas     []*replicaNode
+	primaryStats nodeStats
+	probeOpts    ProbeOptions
+	// readReplica is the replica selected for replica reads by the last probe
+	readReplica atomic.Pointer[redis.Client]
+
+	nearCachesMu sync.Mutex
+	nearCaches   map[string]*NearCache
 }
 
 // NewProvider creates a new Redis provider instance
 func NewProvider(config gpa.Config) (*Provider, error) {
+	return newProvider(config, nil)
+}
+
+// newProvider creates a provider, letting configure adjust the connection options (such as
+// the dialer) before the client is created
+func newProvider(config gpa.Config, configure func(*redis.Options)) (*Provider, error) {
 	provider := &Provider{config: config}
 
 	// Build Redis connection options
@@ -31,16 +105,26 @@ func NewProvider(config gpa.Config) (*Provider, error) {
 	if err != nil {
 		return nil, err
 	}
+	if configure != nil {
+		configure(opts)
+	}
 
 	// Apply Redis-specific options
 	if options, ok := config.Options["redis"]; ok {
 		if redisOptions, ok := options.(map[string]interface{}); ok {
 			applyRedisOptions(opts, redisOptions)
+			applyProviderOptions(provider, redisOptions)
+			// Class timeouts can only shorten the connection timeouts
+			raiseTimeout(&opts.ReadTimeout, provider.CommandTimeouts().Slow)
+			raiseTimeout(&opts.WriteTimeout, provider.CommandTimeouts().Slow)
 		}
 	}
 
-	// Create Redis client
+	// Create Redis client with lifecycle events
+	hook := provider.installEventHook(opts)
 	client := redis.NewClient(opts)
+	client.AddHook(hook)
+	provider.addClientHooks(client)
 
 	// Test the connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -51,9 +135,32 @@ func NewProvider(config gpa.Config) (*Provider, error) {
 	}
 
 	provider.client = client
+
+	if options, ok := config.Options["redis"].(map[string]interface{}); ok {
+		for _, addr := range stringListOption(options["replicas"]) {
+			if err := provider.AddReplica(addr); err != nil {
+				provider.Close()
+				return nil, err
+			}
+		}
+	}
 	return provider, nil
 }
 
+// addClientHooks installs the adapter's command hooks on a client of this provider
+func (p *Provider) addClientHooks(client *redis.Client) {
+	client.AddHook(accessTraceHook{})
+	client.AddHook(&readOnlyHook{provider: p})
+	client.AddHook(&maintenanceHook{provider: p})
+	client.AddHook(&dryRunHook{provider: p})
+	client.AddHook(&loadShedHook{provider: p})
+	client.AddHook(&throttleHook{provider: p})
+	client.AddHook(budgetHook{})
+	client.AddHook(&metricsHook{provider: p})
+	client.AddHook(&samplingHook{provider: p})
+	client.AddHook(&timeoutHook{provider: p})
+}
+
 // Configure applies configuration to the provider
 func (p *Provider) Configure(config gpa.Config) error {
 	p.config = config
@@ -67,9 +174,23 @@ func (p *Provider) Health() error {
 	return p.client.Ping(ctx).Err()
 }
 
-// Close closes the Redis connection
+// Close snapshots near caches configured with a SnapshotPath, closes the Redis connections
+// and finishes a command recording
 func (p *Provider) Close() error {
-	return p.client.Close()
+	snapshotErr := p.SaveNearCaches()
+	replicaErr := p.closeReplicas()
+	if err := p.client.Close(); err != nil {
+		return err
+	}
+	if replicaErr != nil {
+		return replicaErr
+	}
+	if p.recorder != nil {
+		if err := p.recorder.Close(); err != nil {
+			return err
+		}
+	}
+	return snapshotErr
 }
 
 // SupportedFeatures returns the features supported by Redis
@@ -95,8 +216,8 @@ func (p *Provider) ProviderInfo() gpa.ProviderInfo {
 
 // GetRepository returns a type-safe repository for any entity type T
 // This enables the unified provider API: userRepo := gparedis.GetRepository[User](provider)
-func GetRepository[T any](p *Provider) gpa.AdvancedKeyValueRepository[T] {
-	return NewRepository[T](p, p.client, "")
+func GetRepository[T any](p *Provider, opts ...RepositoryOption) gpa.AdvancedKeyValueRepository[T] {
+	return NewRepository[T](p, p.client, "", opts...)
 }
 
 // =====================================
@@ -296,4 +417,127 @@ func applyRedisOptions(opts *redis.Options, redisOptions map[string]interface{})
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+
+// applyProviderOptions applies adapter-level options that don't map to go-redis settings
+func applyProviderOptions(p *Provider, redisOptions map[string]interface{}) {
+	if clusterMode, ok := redisOptions["cluster_mode"]; ok {
+		if enabled, ok := clusterMode.(bool); ok {
+			p.clusterMode = enabled
+		}
+	}
+	if strict, ok := redisOptions["strict_key_schema"]; ok {
+		if enabled, ok := strict.(bool); ok {
+			p.KeySchemas().SetStrict(enabled)
+		}
+	}
+	if threshold, ok := durationOption(redisOptions["slow_op_threshold"]); ok {
+		p.SetSlowOpThreshold(threshold)
+	}
+	if key, ok := redisOptions["erasure_signing_key"].(string); ok && key != "" {
+		p.SetErasureSigningKey([]byte(key))
+	}
+	if readOnly, ok := redisOptions["read_only"].(bool); ok {
+		p.SetReadOnly(readOnly)
+	}
+	switch redisOptions["admin_access"] {
+	case "inspect":
+		p.SetAdminAccess(AdminInspect)
+	case "full":
+		p.SetAdminAccess(AdminFull)
+	}
+	if dryRun, ok := redisOptions["dry_run"].(bool); ok && dryRun {
+		p.StartDryRun(nil)
+	}
+	if metrics, ok := redisOptions["metrics"].(bool); ok && metrics {
+		p.EnableMetrics(MetricsOptions{})
+	}
+	if key, ok := redisOptions["page_token_key"].(string); ok && key != "" {
+		p.SetPageTokenKey([]byte(key))
+	}
+	if timeouts, ok := redisOptions["load_shed_pool_timeouts"].(int); ok && timeouts > 0 {
+		p.SetLoadShedding(LoadShedOptions{PoolTimeouts: uint32(timeouts)})
+	}
+	var payloadOpts PayloadOptions
+	if above, ok := redisOptions["compress_payloads_above"].(int); ok {
+		payloadOpts.CompressAbove = above
+	}
+	if max, ok := redisOptions["max_payload"].(int); ok {
+		payloadOpts.MaxPayload = max
+	}
+	if claimCheck, ok := redisOptions["payload_claim_check"].(bool); ok {
+		payloadOpts.ClaimCheck = claimCheck
+	}
+	p.SetPayloadOptions(payloadOpts)
+	var timeouts CommandTimeouts
+	if fast, ok := durationOption(redisOptions["fast_command_timeout"]); ok {
+		timeouts.Fast = fast
+	}
+	if slow, ok := durationOption(redisOptions["slow_command_timeout"]); ok {
+		timeouts.Slow = slow
+	}
+	p.SetCommandTimeouts(timeouts)
+	var throttleOpts ThrottleOptions
+	if rate, ok := numberOption(redisOptions["max_commands_per_second"]); ok {
+		throttleOpts.CommandsPerSecond = rate
+	}
+	if pipelines, ok := redisOptions["max_concurrent_pipelines"].(int); ok {
+		throttleOpts.MaxConcurrentPipelines = pipelines
+	}
+	if throttleOpts != (ThrottleOptions{}) {
+		p.SetThrottle(throttleOpts)
+	}
+}
+
+// raiseTimeout raises a go-redis read or write timeout to at least min. Zero means the
+// go-redis default of 3s and negative values disable the timeout.
+func raiseTimeout(timeout *time.Duration, min time.Duration) {
+	current := *timeout
+	if current == 0 {
+		current = 3 * time.Second
+	}
+	if current > 0 && current < min {
+		*timeout = min
+	}
+}
+
+// stringListOption accepts a []string or a []interface{} of strings
+func stringListOption(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// durationOption accepts a time.Duration or a duration string such as "250ms"
+func durationOption(value interface{}) (time.Duration, bool) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, true
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// numberOption accepts an int or float64 option value
+func numberOption(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
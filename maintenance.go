@@ -0,0 +1,268 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Maintenance Mode
+// =====================================
+
+// DefaultMaintenanceKey is the flag key shared by the gates of a fleet
+const DefaultMaintenanceKey = "gparedis:maintenance"
+
+// MaintenanceWindow describes an announced maintenance window
+type MaintenanceWindow struct {
+	// Reason is included in the errors returned to rejected operations
+	Reason string `json:"reason,omitempty"`
+	// Since is when the window was opened (set by Enable)
+	Since time.Time `json:"since"`
+	// Until is when the window closes by itself (zero: until Disable)
+	Until time.Time `json:"until,omitempty"`
+	// RejectReads rejects every command; by default only writes are rejected, for read-only windows
+	RejectReads bool `json:"reject_reads,omitempty"`
+}
+
+// active reports whether the window is open at now
+func (w *MaintenanceWindow) active(now time.Time) bool {
+	return w != nil && (w.Until.IsZero() || now.Before(w.Until))
+}
+
+// MaintenanceOptions configures a MaintenanceGate
+type MaintenanceOptions struct {
+	// Key is the flag key; its Pub/Sub channel has the same name (default DefaultMaintenanceKey)
+	Key string
+	// PollInterval is how often Run re-reads the flag, to catch announcements missed while
+	// reconnecting (default 5s)
+	PollInterval time.Duration
+	// OnChange is called when this instance sees a window open (non-nil) or close (nil)
+	OnChange func(window *MaintenanceWindow)
+	// OnError is called when Run fails to read the flag; the last known state is kept
+	OnError func(err error)
+}
+
+// MaintenanceGate rejects commands with ErrorTypeMaintenance while a maintenance window is
+// open, before they reach Redis. The window is a flag key holding a MaintenanceWindow as
+// JSON: Enable and Disable set it and announce the change on the key's Pub/Sub channel, and
+// every instance running the gate's Run loop follows along, so a whole fleet enters and
+// leaves a read-only (or fully closed) window together. Commands issued with a context from
+// WithMaintenanceBypass are always let through.
+type MaintenanceGate struct {
+	provider *Provider
+	opts     MaintenanceOptions
+
+	window   atomic.Pointer[MaintenanceWindow]
+	rejected atomic.Uint64
+}
+
+// maintenanceBypassKey marks a context whose commands ignore the maintenance gate
+type maintenanceBypassKey struct{}
+
+// WithMaintenanceBypass returns a context whose commands run during maintenance windows, for
+// the maintenance tooling itself and health checks
+func WithMaintenanceBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, maintenanceBypassKey{}, true)
+}
+
+// NewMaintenanceGate installs a maintenance gate on the provider, replacing any previous one.
+// The gate enforces no window until Refresh or Run picks up an open one.
+// Example: gate := gparedis.NewMaintenanceGate(provider, gparedis.MaintenanceOptions{}); go gate.Run(ctx)
+func NewMaintenanceGate(provider *Provider, opts MaintenanceOptions) *MaintenanceGate {
+	if opts.Key == "" {
+		opts.Key = DefaultMaintenanceKey
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	g := &MaintenanceGate{provider: provider, opts: opts}
+	provider.maintenance.Store(g)
+	return g
+}
+
+// Enable opens a maintenance window on every instance. A window with Until set closes by
+// itself, as the flag expires then.
+// Example: err := gate.Enable(ctx, gparedis.MaintenanceWindow{Reason: "Redis upgrade", Until: time.Now().Add(10 * time.Minute)})
+func (g *MaintenanceGate) Enable(ctx context.Context, window MaintenanceWindow) error {
+	window.Since = time.Now()
+	if !window.active(window.Since) {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "maintenance window ends in the past")
+	}
+	data, err := json.Marshal(window)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to encode maintenance window", err)
+	}
+	ctx = WithMaintenanceBypass(ctx)
+	_, err = g.provider.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, g.opts.Key, data, 0)
+		if !window.Until.IsZero() {
+			pipe.PExpireAt(ctx, g.opts.Key, window.Until)
+		}
+		pipe.Publish(ctx, g.opts.Key, data)
+		return nil
+	})
+	if err != nil {
+		return convertRedisError(err)
+	}
+	g.set(&window)
+	return nil
+}
+
+// Disable closes the maintenance window on every instance
+func (g *MaintenanceGate) Disable(ctx context.Context) error {
+	ctx = WithMaintenanceBypass(ctx)
+	_, err := g.provider.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, g.opts.Key)
+		pipe.Publish(ctx, g.opts.Key, "")
+		return nil
+	})
+	if err != nil {
+		return convertRedisError(err)
+	}
+	g.set(nil)
+	return nil
+}
+
+// Window returns the window this instance enforces; false when none is open
+func (g *MaintenanceGate) Window() (MaintenanceWindow, bool) {
+	w := g.window.Load()
+	if !w.active(time.Now()) {
+		return MaintenanceWindow{}, false
+	}
+	return *w, true
+}
+
+// Rejected returns the number of commands rejected by this gate
+func (g *MaintenanceGate) Rejected() uint64 {
+	return g.rejected.Load()
+}
+
+// Refresh reads the flag and updates the window this instance enforces
+func (g *MaintenanceGate) Refresh(ctx context.Context) error {
+	data, err := g.provider.client.Get(WithMaintenanceBypass(ctx), g.opts.Key).Bytes()
+	if err == redis.Nil {
+		g.set(nil)
+		return nil
+	}
+	if err != nil {
+		return convertRedisError(err)
+	}
+	var window MaintenanceWindow
+	if err := json.Unmarshal(data, &window); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid maintenance window", err)
+	}
+	g.set(&window)
+	return nil
+}
+
+// Run follows the flag until ctx is cancelled: announcements are applied as they arrive and
+// the flag is re-read every PollInterval
+func (g *MaintenanceGate) Run(ctx context.Context) error {
+	pubsub := g.provider.client.Subscribe(ctx, g.opts.Key)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to subscribe to "+g.opts.Key, err)
+	}
+	refresh := func() {
+		if err := g.Refresh(ctx); err != nil && ctx.Err() == nil && g.opts.OnError != nil {
+			g.opts.OnError(err)
+		}
+	}
+	refresh()
+
+	ticker := time.NewTicker(g.opts.PollInterval)
+	defer ticker.Stop()
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-messages:
+			if !ok {
+				return ctx.Err()
+			}
+			refresh()
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// set replaces the enforced window and reports changes to OnChange
+func (g *MaintenanceGate) set(window *MaintenanceWindow) {
+	previous := g.window.Swap(window)
+	if g.opts.OnChange == nil {
+		return
+	}
+	now := time.Now()
+	was, is := previous.active(now), window.active(now)
+	switch {
+	case is && (!was || !previous.Since.Equal(window.Since)):
+		g.opts.OnChange(window)
+	case was && !is:
+		g.opts.OnChange(nil)
+	}
+}
+
+// admit rejects cmds while a window is open, unless ctx bypasses the gate
+func (g *MaintenanceGate) admit(ctx context.Context, cmds []redis.Cmder) error {
+	w := g.window.Load()
+	if !w.active(time.Now()) {
+		return nil
+	}
+	if bypass, _ := ctx.Value(maintenanceBypassKey{}).(bool); bypass {
+		return nil
+	}
+	for _, cmd := range cmds {
+		if w.RejectReads || isWriteCommand(cmd) {
+			g.rejected.Add(1)
+			return maintenanceError(cmd.Name(), w)
+		}
+	}
+	return nil
+}
+
+// maintenanceError builds the error returned for a command rejected during window
+func maintenanceError(name string, window *MaintenanceWindow) error {
+	msg := "maintenance in progress: " + strings.ToUpper(name) + " rejected"
+	if window.Reason != "" {
+		msg += " (" + window.Reason + ")"
+	}
+	if !window.Until.IsZero() {
+		msg += ", retry after " + window.Until.Format(time.RFC3339)
+	}
+	return gpa.NewError(ErrorTypeMaintenance, msg)
+}
+
+// maintenanceHook applies the provider's maintenance gate to every command and pipeline
+type maintenanceHook struct {
+	provider *Provider
+}
+
+func (h *maintenanceHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if g := h.provider.maintenance.Load(); g != nil {
+		return ctx, g.admit(ctx, []redis.Cmder{cmd})
+	}
+	return ctx, nil
+}
+
+func (h *maintenanceHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *maintenanceHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if g := h.provider.maintenance.Load(); g != nil {
+		return ctx, g.admit(ctx, cmds)
+	}
+	return ctx, nil
+}
+
+func (h *maintenanceHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
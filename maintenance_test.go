@@ -0,0 +1,73 @@
+package gparedis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceGate(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, repo.Set(ctx, "a", &TestValue{ID: "a"}))
+	var mu sync.Mutex
+	var changes []*MaintenanceWindow
+	gate := NewMaintenanceGate(repo.provider, MaintenanceOptions{OnChange: func(w *MaintenanceWindow) {
+		mu.Lock()
+		changes = append(changes, w)
+		mu.Unlock()
+	}})
+
+	require.NoError(t, gate.Enable(ctx, MaintenanceWindow{Reason: "failover"}))
+	err := repo.Set(ctx, "b", &TestValue{ID: "b"})
+	assert.True(t, IsMaintenanceError(err))
+	assert.Contains(t, err.Error(), "failover")
+	_, err = repo.Get(ctx, "a")
+	assert.NoError(t, err, "reads pass during read-only windows")
+	assert.NoError(t, repo.Set(WithMaintenanceBypass(ctx), "b", &TestValue{ID: "b"}))
+	assert.Equal(t, uint64(1), gate.Rejected())
+
+	// Another instance picks the window up from the flag
+	other := NewMaintenanceGate(repo.provider, MaintenanceOptions{PollInterval: time.Hour})
+	_, open := other.Window()
+	assert.False(t, open)
+	require.NoError(t, other.Refresh(ctx))
+	window, open := other.Window()
+	require.True(t, open)
+	assert.Equal(t, "failover", window.Reason)
+
+	// Run follows announcements without waiting for the poll
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go other.Run(runCtx)
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, gate.Disable(ctx))
+	assert.Eventually(t, func() bool {
+		_, open := other.Window()
+		return !open
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.NoError(t, repo.Set(ctx, "c", &TestValue{ID: "c"}))
+
+	require.NoError(t, gate.Enable(ctx, MaintenanceWindow{RejectReads: true, Until: time.Now().Add(time.Hour)}))
+	assert.Eventually(t, func() bool {
+		_, err := repo.Get(ctx, "a")
+		return IsMaintenanceError(err)
+	}, 2*time.Second, 10*time.Millisecond, "closed windows reject reads too")
+	ttl, err := repo.client.PTTL(WithMaintenanceBypass(ctx), DefaultMaintenanceKey).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute, "the flag expires with the window")
+	assert.Error(t, gate.Enable(ctx, MaintenanceWindow{Until: time.Now().Add(-time.Minute)}))
+	require.NoError(t, gate.Disable(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, changes, 4)
+	assert.NotNil(t, changes[0])
+	assert.Nil(t, changes[1])
+}
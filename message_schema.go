@@ -0,0 +1,196 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Message Schema Registry
+// =====================================
+
+// MessageEnvelope wraps a published payload with the schema it was encoded with
+type MessageEnvelope struct {
+	Schema  string          `json:"schema"`
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// MessageMigration upgrades a payload from one schema version to the next
+type MessageMigration func(data json.RawMessage) (json.RawMessage, error)
+
+// messageType is the schema name and version a Go type encodes
+type messageType struct {
+	schema  string
+	version int
+}
+
+// MessageSchemaRegistry maps the versions of message schemas to Go types and the
+// migrations between them, so consumers can evolve their payload structs while older
+// publishers keep running: a consumer decoding into its version upgrades older payloads
+// one version at a time.
+type MessageSchemaRegistry struct {
+	mu         sync.RWMutex
+	types      map[reflect.Type]messageType
+	versions   map[messageType]reflect.Type
+	migrations map[messageType]MessageMigration
+}
+
+// NewMessageSchemaRegistry creates an empty registry
+func NewMessageSchemaRegistry() *MessageSchemaRegistry {
+	return &MessageSchemaRegistry{
+		types:      make(map[reflect.Type]messageType),
+		versions:   make(map[messageType]reflect.Type),
+		migrations: make(map[messageType]MessageMigration),
+	}
+}
+
+// MessageSchemas returns the provider's message schema registry
+func (p *Provider) MessageSchemas() *MessageSchemaRegistry {
+	p.messageSchemasOnce.Do(func() {
+		p.messageSchemas = NewMessageSchemaRegistry()
+	})
+	return p.messageSchemas
+}
+
+// RegisterMessageType declares T as version of the named schema. Each type encodes exactly
+// one schema version and each version has one type.
+// Example: err := gparedis.RegisterMessageType[OrderCreatedV2](provider.MessageSchemas(), "order.created", 2)
+func RegisterMessageType[T any](reg *MessageSchemaRegistry, schema string, version int) error {
+	if schema == "" || version <= 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "message schema needs a name and a positive version")
+	}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	mt := messageType{schema: schema, version: version}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if existing, ok := reg.types[t]; ok && existing != mt {
+		return gpa.NewError(gpa.ErrorTypeDuplicate, fmt.Sprintf("%s is already registered as %s v%d", t, existing.schema, existing.version))
+	}
+	if existing, ok := reg.versions[mt]; ok && existing != t {
+		return gpa.NewError(gpa.ErrorTypeDuplicate, fmt.Sprintf("%s v%d is already registered for %s", schema, version, existing))
+	}
+	reg.types[t] = mt
+	reg.versions[mt] = t
+	return nil
+}
+
+// RegisterMigration declares how payloads of the named schema are upgraded from version
+// from to from+1
+// Example: reg.RegisterMigration("order.created", 1, func(data json.RawMessage) (json.RawMessage, error) { ... })
+func (reg *MessageSchemaRegistry) RegisterMigration(schema string, from int, migrate MessageMigration) error {
+	if schema == "" || from <= 0 || migrate == nil {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "migration needs a schema name, a positive version and a function")
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.migrations[messageType{schema: schema, version: from}] = migrate
+	return nil
+}
+
+// typeOf returns the schema version registered for t
+func (reg *MessageSchemaRegistry) typeOf(t reflect.Type) (messageType, error) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	mt, ok := reg.types[t]
+	if !ok {
+		return mt, gpa.NewError(gpa.ErrorTypeInvalidArgument, t.String()+" is not a registered message type")
+	}
+	return mt, nil
+}
+
+// migrate upgrades data from version from to version to
+func (reg *MessageSchemaRegistry) migrate(schema string, data json.RawMessage, from, to int) (json.RawMessage, error) {
+	for v := from; v < to; v++ {
+		reg.mu.RLock()
+		migrate, ok := reg.migrations[messageType{schema: schema, version: v}]
+		reg.mu.RUnlock()
+		if !ok {
+			return nil, gpa.NewError(gpa.ErrorTypeUnsupported, fmt.Sprintf("no migration of %s from v%d to v%d", schema, v, v+1))
+		}
+		upgraded, err := migrate(data)
+		if err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, fmt.Sprintf("failed to migrate %s from v%d", schema, v), err)
+		}
+		data = upgraded
+	}
+	return data, nil
+}
+
+// EncodeMessage wraps value in an envelope naming its registered schema version
+func EncodeMessage[T any](reg *MessageSchemaRegistry, value *T) ([]byte, error) {
+	mt, err := reg.typeOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to encode "+mt.schema+" message", err)
+	}
+	payload, err := json.Marshal(MessageEnvelope{Schema: mt.schema, Version: mt.version, Data: data})
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to encode message envelope", err)
+	}
+	return payload, nil
+}
+
+// DecodeMessage decodes a payload into T, upgrading older schema versions through the
+// registered migrations. Payloads without an envelope, from publishers that predate it,
+// are decoded as T directly. Payloads of a newer version than T's fail with
+// ErrorTypeUnsupported: the consumer must be upgraded first.
+// Example: order, err := gparedis.DecodeMessage[OrderCreatedV2](provider.MessageSchemas(), msg.Payload)
+func DecodeMessage[T any](reg *MessageSchemaRegistry, payload []byte) (*T, error) {
+	mt, err := reg.typeOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	data := json.RawMessage(payload)
+	var envelope MessageEnvelope
+	if isEnvelope(payload, &envelope) {
+		if envelope.Schema != mt.schema {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("message has schema %s, expected %s", envelope.Schema, mt.schema))
+		}
+		if envelope.Version > mt.version {
+			return nil, gpa.NewError(gpa.ErrorTypeUnsupported, fmt.Sprintf("message is %s v%d, newer than v%d", mt.schema, envelope.Version, mt.version))
+		}
+		if data, err = reg.migrate(mt.schema, envelope.Data, envelope.Version, mt.version); err != nil {
+			return nil, err
+		}
+	}
+
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to decode "+mt.schema+" message", err)
+	}
+	return &value, nil
+}
+
+// isEnvelope reports whether payload is a message envelope, decoding it into envelope
+func isEnvelope(payload []byte, envelope *MessageEnvelope) bool {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return false
+	}
+	if err := json.Unmarshal(trimmed, envelope); err != nil {
+		return false
+	}
+	return envelope.Schema != "" && envelope.Version > 0 && len(envelope.Data) > 0
+}
+
+// PublishMessage publishes value to topic in an envelope naming its schema version from the
+// provider's registry
+// Example: n, err := gparedis.PublishMessage(ctx, provider, "orders.eu.created", &OrderCreatedV2{...})
+func PublishMessage[T any](ctx context.Context, p *Provider, topic string, value *T) (int64, error) {
+	payload, err := EncodeMessage(p.MessageSchemas(), value)
+	if err != nil {
+		return 0, err
+	}
+	return p.Publish(ctx, topic, payload)
+}
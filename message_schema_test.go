@@ -0,0 +1,98 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderCreatedV1 struct {
+	ID    string  `json:"id"`
+	Total float64 `json:"total"`
+}
+
+type orderCreatedV2 struct {
+	ID         string `json:"id"`
+	TotalCents int64  `json:"total_cents"`
+	Currency   string `json:"currency"`
+}
+
+func newOrderSchemas(t *testing.T) *MessageSchemaRegistry {
+	reg := NewMessageSchemaRegistry()
+	require.NoError(t, RegisterMessageType[orderCreatedV1](reg, "order.created", 1))
+	require.NoError(t, RegisterMessageType[orderCreatedV2](reg, "order.created", 2))
+	require.NoError(t, reg.RegisterMigration("order.created", 1, func(data json.RawMessage) (json.RawMessage, error) {
+		var v1 orderCreatedV1
+		if err := json.Unmarshal(data, &v1); err != nil {
+			return nil, err
+		}
+		return json.Marshal(orderCreatedV2{ID: v1.ID, TotalCents: int64(v1.Total * 100), Currency: "EUR"})
+	}))
+	return reg
+}
+
+func TestMessageSchemaMigration(t *testing.T) {
+	reg := newOrderSchemas(t)
+
+	payload, err := EncodeMessage(reg, &orderCreatedV1{ID: "o1", Total: 12.5})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"schema":"order.created","version":1,"data":{"id":"o1","total":12.5}}`, string(payload))
+
+	// Consumers on v2 upgrade v1 payloads
+	order, err := DecodeMessage[orderCreatedV2](reg, payload)
+	require.NoError(t, err)
+	assert.Equal(t, &orderCreatedV2{ID: "o1", TotalCents: 1250, Currency: "EUR"}, order)
+
+	// Consumers still on v1 can't read v2 payloads
+	payload, err = EncodeMessage(reg, order)
+	require.NoError(t, err)
+	_, err = DecodeMessage[orderCreatedV1](reg, payload)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeUnsupported))
+
+	// Payloads from publishers without envelopes decode directly
+	legacy, err := DecodeMessage[orderCreatedV1](reg, []byte(`{"id":"o2","total":3}`))
+	require.NoError(t, err)
+	assert.Equal(t, "o2", legacy.ID)
+}
+
+func TestMessageSchemaRegistration(t *testing.T) {
+	reg := newOrderSchemas(t)
+	assert.True(t, gpa.IsErrorType(RegisterMessageType[orderCreatedV1](reg, "order.created", 3), gpa.ErrorTypeDuplicate))
+	assert.True(t, gpa.IsErrorType(RegisterMessageType[TestValue](reg, "order.created", 2), gpa.ErrorTypeDuplicate))
+	assert.Error(t, RegisterMessageType[TestValue](reg, "", 1))
+
+	_, err := EncodeMessage(reg, &TestValue{})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+
+	require.NoError(t, RegisterMessageType[TestValue](reg, "test.value", 1))
+	payload, err := EncodeMessage(reg, &TestValue{ID: "x"})
+	require.NoError(t, err)
+	_, err = DecodeMessage[orderCreatedV2](reg, payload)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestPublishMessage(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	reg := repo.provider.MessageSchemas()
+	require.NoError(t, RegisterMessageType[orderCreatedV2](reg, "order.created", 2))
+
+	pubsub := repo.client.Subscribe(ctx, "orders.eu.created")
+	defer pubsub.Close()
+	_, err := pubsub.Receive(ctx)
+	require.NoError(t, err)
+
+	_, err = PublishMessage(ctx, repo.provider, "orders.eu.created", &orderCreatedV2{ID: "o3", TotalCents: 100})
+	require.NoError(t, err)
+	msg, err := pubsub.ReceiveMessage(ctx)
+	require.NoError(t, err)
+	order, err := DecodeMessage[orderCreatedV2](reg, []byte(msg.Payload))
+	require.NoError(t, err)
+	assert.Equal(t, "o3", order.ID)
+}
@@ -0,0 +1,468 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Metrics and Hot-Key Detection
+// =====================================
+
+// MetricsOptions configures metric collection
+type MetricsOptions struct {
+	// HotKeyCapacity bounds the number of keys tracked for hot-key detection (default 1000).
+	// When full, all key counts are halved and keys reaching zero are dropped, so counts
+	// are approximate while the ranking of hot keys is preserved.
+	HotKeyCapacity int
+}
+
+// KeyCount is an approximate access count of one key
+type KeyCount struct {
+	Key    string
+	Prefix string
+	Count  uint64
+}
+
+// PrefixCount is the access count of the keys owned by one repository prefix
+type PrefixCount struct {
+	// Prefix is the declared repository prefix; empty for keys no repository owns
+	Prefix string
+	// Entity is the entity type name of the repository
+	Entity string
+	Count  uint64
+}
+
+// Metrics collects per-key and per-repository access statistics from every command the
+// provider sends. Keys are attributed to repositories through the key schema registry.
+type Metrics struct {
+	provider *Provider
+	opts     MetricsOptions
+
+	mu       sync.Mutex
+	commands uint64
+	keys     map[string]uint64
+	prefixes map[string]uint64
+	entities map[string]string
+	exported map[string]uint64
+	latency  map[string]*LatencyHistogram
+	slos     map[string]*sloTracker
+
+	staleServes map[string]uint64
+
+	queues        map[string]*queueCounter
+	trackedQueues []QueueStatsSource
+
+	costs map[string]*CostUsage
+}
+
+// newMetrics creates an empty collector
+func newMetrics(p *Provider, opts MetricsOptions) *Metrics {
+	if opts.HotKeyCapacity <= 0 {
+		opts.HotKeyCapacity = 1000
+	}
+	return &Metrics{
+		provider: p,
+		opts:     opts,
+		keys:     make(map[string]uint64),
+		prefixes: make(map[string]uint64),
+		entities: make(map[string]string),
+		exported: make(map[string]uint64),
+		latency:  make(map[string]*LatencyHistogram),
+		slos:     make(map[string]*sloTracker),
+
+		staleServes: make(map[string]uint64),
+
+		queues: make(map[string]*queueCounter),
+
+		costs: make(map[string]*CostUsage),
+	}
+}
+
+// EnableMetrics starts collecting metrics with opts, replacing any running collector.
+// SLOs and tracked queues of the previous collector are kept.
+// Example: metrics := provider.EnableMetrics(gparedis.MetricsOptions{HotKeyCapacity: 5000})
+func (p *Provider) EnableMetrics(opts MetricsOptions) *Metrics {
+	m := newMetrics(p, opts)
+	if previous := p.metrics.Load(); previous != nil {
+		for _, status := range previous.SLOStatuses() {
+			m.SetSLO(status.Prefix, status.SLO)
+		}
+		previous.mu.Lock()
+		m.trackedQueues = append(m.trackedQueues, previous.trackedQueues...)
+		previous.mu.Unlock()
+	}
+	p.metrics.Store(m)
+	return m
+}
+
+// Metrics returns the running collector, enabling one with default options on first use
+func (p *Provider) Metrics() *Metrics {
+	if m := p.metrics.Load(); m != nil {
+		return m
+	}
+	p.metrics.CompareAndSwap(nil, newMetrics(p, MetricsOptions{}))
+	return p.metrics.Load()
+}
+
+// DisableMetrics stops metric collection
+func (p *Provider) DisableMetrics() {
+	p.metrics.Store(nil)
+}
+
+// observe records the keys touched by commands that took elapsed to complete. Each command's
+// latency is attributed to the repository owning its first key.
+func (m *Metrics) observe(cmds []redis.Cmder, elapsed time.Duration) {
+	now := time.Now()
+	var alerts []SLOAlert
+	m.mu.Lock()
+	for _, cmd := range cmds {
+		m.commands++
+		for i, key := range commandKeys(cmd) {
+			prefix := m.observeKey(key)
+			if i == 0 {
+				if alert, ok := m.observeLatency(prefix, elapsed, now); ok {
+					alerts = append(alerts, alert)
+				}
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for _, alert := range alerts {
+		if slo, ok := m.sloFor(alert.Prefix); ok && slo.OnAlert != nil {
+			slo.OnAlert(alert)
+		}
+	}
+}
+
+// sloFor returns the SLO set for prefix
+func (m *Metrics) sloFor(prefix string) (SLO, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tracker, ok := m.slos[prefix]
+	if !ok {
+		return SLO{}, false
+	}
+	return tracker.slo, true
+}
+
+// observeKey counts one access of key and returns the owning prefix; callers hold m.mu
+func (m *Metrics) observeKey(key string) string {
+	prefix := ""
+	if schema, ok := m.provider.KeySchemas().Lookup(key); ok {
+		prefix = schema.Prefix
+		m.entities[prefix] = schema.TypeName()
+	}
+	m.prefixes[prefix]++
+
+	if _, tracked := m.keys[key]; !tracked {
+		for len(m.keys) >= m.opts.HotKeyCapacity {
+			for k, n := range m.keys {
+				if n /= 2; n == 0 {
+					delete(m.keys, k)
+				} else {
+					m.keys[k] = n
+				}
+			}
+		}
+	}
+	m.keys[key]++
+	return prefix
+}
+
+// Commands returns the number of commands observed
+func (m *Metrics) Commands() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.commands
+}
+
+// HotKeys returns the n most accessed keys, hottest first
+func (m *Metrics) HotKeys(n int) []KeyCount {
+	m.mu.Lock()
+	counts := make([]KeyCount, 0, len(m.keys))
+	for key, count := range m.keys {
+		kc := KeyCount{Key: key, Count: count}
+		if schema, ok := m.provider.KeySchemas().Lookup(key); ok {
+			kc.Prefix = schema.Prefix
+		}
+		counts = append(counts, kc)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	if n >= 0 && n < len(counts) {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// PrefixCounts returns the access counts by repository prefix, busiest first
+func (m *Metrics) PrefixCounts() []PrefixCount {
+	m.mu.Lock()
+	counts := make([]PrefixCount, 0, len(m.prefixes))
+	for prefix, count := range m.prefixes {
+		counts = append(counts, PrefixCount{Prefix: prefix, Entity: m.entities[prefix], Count: count})
+	}
+	m.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Prefix < counts[j].Prefix
+	})
+	return counts
+}
+
+// Reset clears all collected statistics
+func (m *Metrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commands = 0
+	m.keys = make(map[string]uint64)
+	m.prefixes = make(map[string]uint64)
+	m.exported = make(map[string]uint64)
+	m.latency = make(map[string]*LatencyHistogram)
+	m.staleServes = make(map[string]uint64)
+	m.queues = make(map[string]*queueCounter)
+	m.costs = make(map[string]*CostUsage)
+	for prefix, tracker := range m.slos {
+		m.slos[prefix] = &sloTracker{slo: tracker.slo, windowStart: time.Now()}
+	}
+}
+
+// =====================================
+// Heatmap Export
+// =====================================
+
+// heatmapOtherField is the hash field counting keys no repository owns
+const heatmapOtherField = "(other)"
+
+// ExportHeatmap adds the per-prefix access counts since the previous export to the Redis hash
+// at hashKey (field = prefix), so several processes aggregate into one dashboard source.
+// The export's own commands are not counted.
+// Example: err := provider.Metrics().ExportHeatmap(ctx, "gparedis:heatmap")
+func (m *Metrics) ExportHeatmap(ctx context.Context, hashKey string) error {
+	m.mu.Lock()
+	deltas := make(map[string]uint64)
+	for prefix, count := range m.prefixes {
+		if delta := count - m.exported[prefix]; delta > 0 {
+			deltas[prefix] = delta
+		}
+	}
+	m.mu.Unlock()
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	_, err := m.provider.client.Pipelined(withoutMetrics(ctx), func(pipe redis.Pipeliner) error {
+		for prefix, delta := range deltas {
+			field := prefix
+			if field == "" {
+				field = heatmapOtherField
+			}
+			pipe.HIncrBy(ctx, hashKey, field, int64(delta))
+		}
+		return nil
+	})
+	if err != nil {
+		return convertRedisError(err)
+	}
+
+	m.mu.Lock()
+	for prefix, delta := range deltas {
+		m.exported[prefix] += delta
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// RunHeatmapExport calls ExportHeatmap every interval until ctx is cancelled.
+// Export errors are passed to onError (which may be nil) and don't stop the loop.
+func (m *Metrics) RunHeatmapExport(ctx context.Context, hashKey string, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := m.ExportHeatmap(ctx, hashKey); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// hotKeysExported is the number of hot keys included in the Prometheus output
+const hotKeysExported = 20
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# HELP gparedis_commands_total Commands sent to Redis.\n")
+	b.WriteString("# TYPE gparedis_commands_total counter\n")
+	fmt.Fprintf(&b, "gparedis_commands_total %d\n", m.Commands())
+
+	b.WriteString("# HELP gparedis_key_accesses_total Key accesses by repository.\n")
+	b.WriteString("# TYPE gparedis_key_accesses_total counter\n")
+	for _, pc := range m.PrefixCounts() {
+		fmt.Fprintf(&b, "gparedis_key_accesses_total{prefix=\"%s\",entity=\"%s\"} %d\n",
+			promLabel(pc.Prefix), promLabel(pc.Entity), pc.Count)
+	}
+
+	b.WriteString("# HELP gparedis_hot_key_accesses Approximate accesses of the hottest keys.\n")
+	b.WriteString("# TYPE gparedis_hot_key_accesses gauge\n")
+	for _, kc := range m.HotKeys(hotKeysExported) {
+		fmt.Fprintf(&b, "gparedis_hot_key_accesses{key=\"%s\",prefix=\"%s\"} %d\n",
+			promLabel(kc.Key), promLabel(kc.Prefix), kc.Count)
+	}
+	m.writeLatencyPrometheus(&b)
+	m.writeStalePrometheus(&b)
+	m.writeQueuePrometheus(&b)
+	m.writeCostPrometheus(&b)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Handler serves WritePrometheus output, for mounting at /metrics
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_ = m.WritePrometheus(w)
+	})
+}
+
+// promLabel escapes a Prometheus label value
+func promLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// =====================================
+// Command Key Extraction
+// =====================================
+
+// keylessCommands take no key arguments
+var keylessCommands = map[string]bool{
+	"ping": true, "echo": true, "select": true, "auth": true, "hello": true, "client": true,
+	"info": true, "config": true, "command": true, "time": true, "dbsize": true, "scan": true,
+	"keys": true, "randomkey": true, "flushdb": true, "flushall": true, "multi": true, "exec": true,
+	"discard": true, "unwatch": true, "script": true, "publish": true, "subscribe": true,
+	"psubscribe": true, "unsubscribe": true, "punsubscribe": true, "quit": true, "slowlog": true,
+	"memory": true, "debug": true, "wait": true, "readonly": true, "readwrite": true, "cluster": true,
+}
+
+// allKeyCommands take only keys as arguments
+var allKeyCommands = map[string]bool{
+	"mget": true, "del": true, "unlink": true, "exists": true, "touch": true, "watch": true,
+	"sinter": true, "sunion": true, "sdiff": true, "pfcount": true,
+}
+
+// commandKeys returns the keys a command touches, as far as they can be told from its arguments
+func commandKeys(cmd redis.Cmder) []string {
+	args := cmd.Args()
+	name := strings.ToLower(cmd.Name())
+	if len(args) < 2 || keylessCommands[name] {
+		return nil
+	}
+	switch {
+	case allKeyCommands[name]:
+		return stringArgs(args[1:], 1)
+	case name == "mset" || name == "msetnx":
+		return stringArgs(args[1:], 2)
+	case name == "eval" || name == "evalsha" || name == "eval_ro" || name == "evalsha_ro":
+		if len(args) < 3 {
+			return nil
+		}
+		n, ok := args[2].(int)
+		if !ok || 3+n > len(args) {
+			return nil
+		}
+		return stringArgs(args[3:3+n], 1)
+	default:
+		return stringArgs(args[1:2], 1)
+	}
+}
+
+// stringArgs converts every step-th argument to a string
+func stringArgs(args []interface{}, step int) []string {
+	keys := make([]string, 0, (len(args)+step-1)/step)
+	for i := 0; i < len(args); i += step {
+		keys = append(keys, fmt.Sprint(args[i]))
+	}
+	return keys
+}
+
+// =====================================
+// Metrics Hook
+// =====================================
+
+// noMetricsKey marks contexts whose commands are not counted
+type noMetricsKey struct{}
+
+// withoutMetrics excludes the commands issued with ctx from metrics
+func withoutMetrics(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noMetricsKey{}, true)
+}
+
+// metricsStartKey stores the time a measured command was sent
+type metricsStartKey struct{}
+
+// metricsHook feeds every command into the provider's metrics collector
+type metricsHook struct {
+	provider *Provider
+}
+
+func (h *metricsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return h.before(ctx), nil
+}
+
+func (h *metricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.after(ctx, []redis.Cmder{cmd})
+	return nil
+}
+
+func (h *metricsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return h.before(ctx), nil
+}
+
+func (h *metricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	h.after(ctx, cmds)
+	return nil
+}
+
+// before records the start time when metrics are collected
+func (h *metricsHook) before(ctx context.Context) context.Context {
+	if h.provider.metrics.Load() == nil || ctx.Value(noMetricsKey{}) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, metricsStartKey{}, time.Now())
+}
+
+// after passes the commands and their latency to the collector
+func (h *metricsHook) after(ctx context.Context, cmds []redis.Cmder) {
+	start, ok := ctx.Value(metricsStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	if m := h.provider.metrics.Load(); m != nil {
+		m.observe(cmds, time.Since(start))
+		m.observeCost(ctx, cmds)
+	}
+}
@@ -0,0 +1,90 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHeatmap(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	users, err := DeclareRepository[TestValue](repo.provider, "heat:user:")
+	require.NoError(t, err)
+	defer users.DeleteKey(ctx, "1")
+	defer users.DeleteKey(ctx, "2")
+	defer repo.client.Del(ctx, "heat:other", "heat:export")
+
+	metrics := repo.provider.EnableMetrics(MetricsOptions{})
+	defer repo.provider.DisableMetrics()
+
+	require.NoError(t, users.Set(ctx, "1", &TestValue{ID: "1"}))
+	require.NoError(t, users.Set(ctx, "2", &TestValue{ID: "2"}))
+	for i := 0; i < 3; i++ {
+		_, err := users.Get(ctx, "1")
+		require.NoError(t, err)
+	}
+	_, err = users.MGet(ctx, []string{"1", "2"})
+	require.NoError(t, err)
+	require.NoError(t, repo.client.Set(ctx, "heat:other", "x", 0).Err())
+
+	hot := metrics.HotKeys(1)
+	require.Len(t, hot, 1)
+	assert.Equal(t, "heat:user:1", hot[0].Key)
+	assert.Equal(t, "heat:user:", hot[0].Prefix)
+	assert.Equal(t, uint64(5), hot[0].Count)
+
+	counts := metrics.PrefixCounts()
+	require.Len(t, counts, 2)
+	assert.Equal(t, PrefixCount{Prefix: "heat:user:", Entity: "gparedis.TestValue", Count: 7}, counts[0])
+	assert.Equal(t, "", counts[1].Prefix)
+
+	// Export writes deltas, so a second export without new traffic adds nothing
+	require.NoError(t, metrics.ExportHeatmap(ctx, "heat:export"))
+	require.NoError(t, metrics.ExportHeatmap(ctx, "heat:export"))
+	exported, err := repo.client.HGetAll(ctx, "heat:export").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "7", exported["heat:user:"])
+	assert.Equal(t, "1", exported[heatmapOtherField])
+
+	var out bytes.Buffer
+	require.NoError(t, metrics.WritePrometheus(&out))
+	assert.Contains(t, out.String(), `gparedis_key_accesses_total{prefix="heat:user:",entity="gparedis.TestValue"} 7`)
+	assert.Contains(t, out.String(), `gparedis_hot_key_accesses{key="heat:user:1",prefix="heat:user:"} 5`)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), "# TYPE gparedis_commands_total counter")
+}
+
+func TestMetricsHotKeyCapacity(t *testing.T) {
+	m := newMetrics(&Provider{}, MetricsOptions{HotKeyCapacity: 3})
+	for i := 0; i < 10; i++ {
+		m.observeKey("hot")
+	}
+	m.observeKey("a")
+	m.observeKey("b")
+	m.observeKey("c")
+
+	assert.LessOrEqual(t, len(m.keys), 3)
+	assert.Equal(t, "hot", m.HotKeys(1)[0].Key)
+	assert.Equal(t, uint64(13), m.PrefixCounts()[0].Count)
+}
+
+func TestCommandKeys(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	assert.Equal(t, []string{"a", "b"}, commandKeys(repo.client.MGet(ctx, "a", "b")))
+	assert.Equal(t, []string{"a", "b"}, commandKeys(repo.client.MSet(ctx, "a", 1, "b", 2)))
+	assert.Equal(t, []string{"k"}, commandKeys(repo.client.Eval(ctx, "return 1", []string{"k"}, "arg")))
+	assert.Nil(t, commandKeys(repo.client.Ping(ctx)))
+	repo.client.Del(ctx, "a", "b")
+}
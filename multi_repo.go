@@ -0,0 +1,261 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Cross-Repository Transactions
+// =====================================
+
+// MultiTx collects writes from repositories of different entity types that share
+// a provider and executes them together in a single MULTI/EXEC block.
+type MultiTx struct {
+	ctx      context.Context
+	provider *Provider
+	pipe     redis.Pipeliner
+	keys     []string
+	after    []func(ctx context.Context)
+	// undo releases the unique values claimed by queued writes when the transaction doesn't commit
+	undo []func(ctx context.Context)
+	// audit records the committed writes in the audit logs of their repositories
+	audit []func(ctx context.Context) error
+}
+
+// MultiRepo runs fn and then executes every write queued on tx atomically.
+// If fn returns an error the queued writes are discarded and nothing is sent to Redis.
+// Writes to audited repositories are recorded once the transaction commits; a failure to
+// record them is returned even though the writes were applied.
+// In cluster mode all keys must hash to the same slot, including the change streams of
+// repositories that capture changes; use HashTag to co-locate them.
+// Example:
+//
+//	err := provider.MultiRepo(ctx, func(tx *gparedis.MultiTx) error {
+//		if err := userRepo.SetTx(tx, gparedis.HashTag(id), user); err != nil {
+//			return err
+//		}
+//		return sessionRepo.SetWithTTLTx(tx, gparedis.HashTag(id)+":web", session, time.Hour)
+//	})
+func (p *Provider) MultiRepo(ctx context.Context, fn func(tx *MultiTx) error) error {
+	tx := &MultiTx{ctx: ctx, provider: p, pipe: p.client.TxPipeline()}
+	defer tx.pipe.Close()
+
+	if err := fn(tx); err != nil {
+		tx.pipe.Discard()
+		tx.rollback(ctx)
+		return err
+	}
+	if len(tx.keys) == 0 {
+		return nil
+	}
+	if err := tx.checkSlots(); err != nil {
+		tx.pipe.Discard()
+		tx.rollback(ctx)
+		return err
+	}
+
+	if _, err := tx.pipe.Exec(ctx); err != nil {
+		tx.rollback(ctx)
+		return gpa.NewErrorWithCause(gpa.ErrorTypeTransaction, "multi-repository transaction failed", convertRedisError(err))
+	}
+
+	for _, fn := range tx.after {
+		fn(ctx)
+	}
+	for _, fn := range tx.audit {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollback releases the claims of a transaction that didn't commit; best effort, as the
+// claims of keys left unchanged are also taken over by the next writer once re-indexed
+func (tx *MultiTx) rollback(ctx context.Context) {
+	for _, fn := range tx.undo {
+		fn(ctx)
+	}
+}
+
+// Keys returns the full keys written by the transaction so far
+func (tx *MultiTx) Keys() []string {
+	return append([]string(nil), tx.keys...)
+}
+
+// checkSlots ensures all keys map to one hash slot when the provider runs in cluster mode
+func (tx *MultiTx) checkSlots() error {
+	if !tx.provider.clusterMode {
+		return nil
+	}
+	slots := GroupBySlot(tx.keys)
+	if len(slots) <= 1 {
+		return nil
+	}
+
+	groups := make([]string, 0, len(slots))
+	for slot, keys := range slots {
+		groups = append(groups, fmt.Sprintf("slot %d: %s", slot, strings.Join(keys, ", ")))
+	}
+	sort.Strings(groups)
+	return gpa.NewError(gpa.ErrorTypeInvalidArgument,
+		"transaction keys span multiple cluster slots, use HashTag to co-locate them ("+strings.Join(groups, "; ")+")")
+}
+
+// join validates that the repository can take part in tx
+func (r *Repository[T]) join(tx *MultiTx) error {
+	if tx == nil || tx.provider == nil {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "transaction is not initialized")
+	}
+	if r.client != tx.provider.client {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "repository does not share the transaction's provider connection")
+	}
+	return nil
+}
+
+// SetTx queues a Set of value at key on tx
+func (r *Repository[T]) SetTx(tx *MultiTx, key string, value *T) error {
+	return r.SetWithTTLTx(tx, key, value, 0)
+}
+
+// SetWithTTLTx queues a Set of value at key with an expiration on tx.
+// BeforeCreate hooks run and unique index values are claimed immediately, returning
+// ErrorTypeDuplicate when taken; AfterCreate hooks run after the transaction commits.
+func (r *Repository[T]) SetWithTTLTx(tx *MultiTx, key string, value *T, ttl time.Duration) error {
+	if err := r.join(tx); err != nil {
+		return err
+	}
+	if err := r.authorizeKeys(tx.ctx, AccessWrite, key); err != nil {
+		return err
+	}
+	if err := r.checkQuota(tx.ctx); err != nil {
+		return err
+	}
+
+	ctx := tx.ctx
+	if hook, ok := any(value).(gpa.BeforeCreateHook); ok {
+		if err := hook.BeforeCreate(ctx); err != nil {
+			return gpa.NewErrorWithCause(gpa.ErrorTypeValidation, "before create hook failed", err)
+		}
+	}
+
+	data, err := r.encode(value)
+	if err != nil {
+		return err
+	}
+	if err := r.claimUnique(ctx, map[string]*T{key: value}); err != nil {
+		return err
+	}
+	tx.undo = append(tx.undo, func(ctx context.Context) {
+		_ = r.releaseClaims(ctx, []string{key})
+	})
+
+	expiry := r.retentionTTL(key, ttl)
+	if r.opts.changeStream != "" {
+		keys, args := r.captureArgs(ChangeOpSet, []string{key}, [][]byte{data}, []time.Duration{expiry})
+		captureScript.Eval(ctx, tx.pipe, keys, args...)
+		tx.keys = append(tx.keys, keys...)
+	} else {
+		fullKey := r.buildKey(key)
+		tx.pipe.Set(ctx, fullKey, data, expiry)
+		tx.keys = append(tx.keys, fullKey)
+	}
+	tx.after = append(tx.after, func(ctx context.Context) {
+		// Best effort, like the hooks below: the write itself already committed
+		_ = r.afterSet(ctx, map[string]*T{key: value}, ttl)
+	})
+	tx.audit = append(tx.audit, func(ctx context.Context) error {
+		return r.recordWrite(ctx, "set", key)
+	})
+
+	if hook, ok := any(value).(gpa.AfterCreateHook); ok {
+		tx.after = append(tx.after, func(ctx context.Context) {
+			// Errors are ignored as in SetWithTTL
+			_ = hook.AfterCreate(ctx)
+		})
+	}
+	return nil
+}
+
+// DeleteKeyTx queues the removal of key on tx.
+// BeforeDelete hooks of the current entity run immediately; AfterDelete hooks run after the
+// transaction commits.
+func (r *Repository[T]) DeleteKeyTx(tx *MultiTx, key string) error {
+	if err := r.join(tx); err != nil {
+		return err
+	}
+	if err := r.authorizeKeys(tx.ctx, AccessDelete, key); err != nil {
+		return err
+	}
+
+	// Unlike DeleteKey, a missing key is still deleted: an earlier write of tx may create it
+	entity, _ := r.Get(tx.ctx, key)
+	if entity != nil {
+		if hook, ok := any(entity).(gpa.BeforeDeleteHook); ok {
+			if err := hook.BeforeDelete(tx.ctx); err != nil {
+				return gpa.NewErrorWithCause(gpa.ErrorTypeValidation, "before delete hook failed", err)
+			}
+		}
+	}
+	if r.opts.changeStream != "" {
+		keys, args := r.captureArgs(ChangeOpDelete, []string{key}, nil, nil)
+		captureScript.Eval(tx.ctx, tx.pipe, keys, args...)
+		tx.keys = append(tx.keys, keys...)
+	} else {
+		fullKey := r.buildKey(key)
+		tx.pipe.Del(tx.ctx, fullKey)
+		tx.keys = append(tx.keys, fullKey)
+	}
+	tx.after = append(tx.after, func(ctx context.Context) {
+		_ = r.afterDelete(ctx, []string{key})
+	})
+	tx.audit = append(tx.audit, func(ctx context.Context) error {
+		return r.recordWrite(ctx, "delete", key)
+	})
+
+	if hook, ok := any(entity).(gpa.AfterDeleteHook); ok && entity != nil {
+		tx.after = append(tx.after, func(ctx context.Context) {
+			// Errors are ignored as in DeleteKey
+			_ = hook.AfterDelete(ctx)
+		})
+	}
+	return nil
+}
+
+// IncrementTx queues an atomic increment of key by delta on tx
+func (r *Repository[T]) IncrementTx(tx *MultiTx, key string, delta int64) error {
+	if err := r.join(tx); err != nil {
+		return err
+	}
+	if err := r.authorizeKeys(tx.ctx, AccessWrite, key); err != nil {
+		return err
+	}
+	if err := r.checkQuota(tx.ctx); err != nil {
+		return err
+	}
+	if r.opts.changeStream != "" {
+		keys, args := r.incrementArgs(key, delta)
+		incrementScript.Eval(tx.ctx, tx.pipe, keys, args...)
+		tx.keys = append(tx.keys, keys...)
+	} else {
+		fullKey := r.buildKey(key)
+		tx.pipe.IncrBy(tx.ctx, fullKey, delta)
+		tx.keys = append(tx.keys, fullKey)
+	}
+	if r.near != nil {
+		tx.after = append(tx.after, func(ctx context.Context) {
+			r.near.Invalidate(key)
+		})
+	}
+	tx.audit = append(tx.audit, func(ctx context.Context) error {
+		return r.recordWrite(ctx, "increment", key)
+	})
+	return nil
+}
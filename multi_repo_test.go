@@ -0,0 +1,141 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiRepo(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	provider := repo.provider
+	userRepo := NewRepository[TypeSafeTestUser](provider, provider.client, "user:")
+	sessionRepo := NewRepository[TypeSafeTestSession](provider, provider.client, "session:")
+
+	err := provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		if err := userRepo.SetTx(tx, "1", &TypeSafeTestUser{ID: "1", Name: "Alice"}); err != nil {
+			return err
+		}
+		if err := userRepo.IncrementTx(tx, "logins:1", 1); err != nil {
+			return err
+		}
+		return sessionRepo.SetWithTTLTx(tx, "abc", &TypeSafeTestSession{ID: "abc", UserID: "1"}, time.Hour)
+	})
+	require.NoError(t, err)
+
+	user, err := userRepo.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", user.Name)
+
+	ttl, err := sessionRepo.GetTTL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+
+	// A failing callback discards every queued write
+	boom := errors.New("boom")
+	err = provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		if err := userRepo.DeleteKeyTx(tx, "1"); err != nil {
+			return err
+		}
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+
+	exists, err := userRepo.KeyExists(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestMultiRepoClusterSlots(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	provider := repo.provider
+	provider.clusterMode = true
+	defer func() { provider.clusterMode = false }()
+
+	err := provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		if err := repo.SetTx(tx, "a", &TestValue{ID: "a"}); err != nil {
+			return err
+		}
+		return repo.SetTx(tx, "b", &TestValue{ID: "b"})
+	})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+
+	err = provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		if err := repo.SetTx(tx, HashTag("7")+":a", &TestValue{ID: "a"}); err != nil {
+			return err
+		}
+		return repo.SetTx(tx, HashTag("7")+":b", &TestValue{ID: "b"})
+	})
+	assert.NoError(t, err)
+}
+
+// txDeletes counts the AfterDelete hooks run on txNote values
+var txDeletes atomic.Int32
+
+type txNote struct {
+	ID string `json:"id"`
+}
+
+func (n *txNote) AfterDelete(ctx context.Context) error {
+	txDeletes.Add(1)
+	return nil
+}
+
+func TestMultiRepoDeleteHooksAndNearCache(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	provider := repo.provider
+	notes := NewRepository[txNote](provider, provider.client, "txnote:")
+	locked := NewRepository[refLockedNote](provider, provider.client, "txlocked:")
+	counters := NewRepository[int64](provider, provider.client, "txcount:",
+		WithNearCache(NearCacheOptions{Capacity: 10, TTL: time.Minute}))
+	require.NoError(t, notes.Set(ctx, "1", &txNote{ID: "1"}))
+	require.NoError(t, locked.Set(ctx, "1", &refLockedNote{ID: "1"}))
+	require.NoError(t, provider.client.Set(ctx, "txcount:hits", 1, 0).Err())
+
+	// BeforeDelete runs when the delete is queued and can veto the transaction
+	err := provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		return locked.DeleteKeyTx(tx, "1")
+	})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeValidation))
+	exists, err := locked.KeyExists(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Increments invalidate near-cached copies
+	hits, err := counters.Get(ctx, "hits")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), *hits)
+	_, err = counters.Increment(ctx, "hits", 1)
+	require.NoError(t, err)
+	hits, err = counters.Get(ctx, "hits")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *hits)
+
+	// AfterDelete runs once the transaction commits
+	txDeletes.Store(0)
+	require.NoError(t, provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		if err := counters.IncrementTx(tx, "hits", 1); err != nil {
+			return err
+		}
+		return notes.DeleteKeyTx(tx, "1")
+	}))
+	assert.Equal(t, int32(1), txDeletes.Load())
+	hits, err = counters.Get(ctx, "hits")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), *hits)
+}
@@ -0,0 +1,320 @@
+package gparedis
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Near Cache and Serve-Stale Fallback
+// =====================================
+
+// NearCacheOptions configures a repository's in-process cache of recently read values
+type NearCacheOptions struct {
+	// Capacity is the maximum number of cached keys; the least recently used are evicted (default 1000)
+	Capacity int
+	// TTL is how long a copy is served without asking Redis (0 always asks Redis and keeps
+	// copies only as a fallback)
+	TTL time.Duration
+	// ServeStale serves a cached copy when Redis fails or times out, as long as the copy is
+	// younger than TTL + StaleGrace
+	ServeStale bool
+	// StaleGrace is how long past TTL a copy may still be served on error (default 5m)
+	StaleGrace time.Duration
+	// OnStale is called (outside any lock) whenever a stale copy is served in place of an error
+	OnStale func(key string, age time.Duration, cause error)
+	// SnapshotPath, when set, is loaded when the repository is created and written when the
+	// provider closes, so restarted instances start warm
+	SnapshotPath string
+}
+
+// NearCacheStats summarizes near cache activity
+type NearCacheStats struct {
+	Size        int
+	Hits        uint64
+	Misses      uint64
+	StaleServes uint64
+}
+
+// nearEntry is one cached value
+type nearEntry struct {
+	key    string
+	data   []byte
+	stored time.Time
+}
+
+// NearCache is an LRU of encoded values read through a repository. Writes and deletes
+// through the same repository invalidate their keys; changes made elsewhere become
+// visible once a copy is older than TTL.
+type NearCache struct {
+	opts NearCacheOptions
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	hits    uint64
+	misses  uint64
+	stale   uint64
+}
+
+// newNearCache creates an empty near cache
+func newNearCache(opts NearCacheOptions) *NearCache {
+	if opts.Capacity <= 0 {
+		opts.Capacity = 1000
+	}
+	if opts.StaleGrace <= 0 {
+		opts.StaleGrace = 5 * time.Minute
+	}
+	return &NearCache{opts: opts, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// WithNearCache keeps recently read values in process. Fresh copies (younger than TTL) are
+// served without a round trip; with ServeStale, Get and MGet fall back to older copies
+// when Redis returns an error or times out, counting each fallback in the provider's
+// metrics as gparedis_stale_serves_total.
+// Example: products := gparedis.NewRepository[Product](provider, client, "product:", gparedis.WithNearCache(gparedis.NearCacheOptions{TTL: time.Second, ServeStale: true}))
+func WithNearCache(opts NearCacheOptions) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.nearCache = &opts
+	}
+}
+
+// NearCache returns the repository's near cache, or nil when none is configured
+func (r *Repository[T]) NearCache() *NearCache {
+	return r.near
+}
+
+// lookup returns the copy of key and its age
+func (c *NearCache) lookup(key string) ([]byte, time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+	entry := elem.Value.(*nearEntry)
+	c.order.MoveToFront(elem)
+	return entry.data, time.Since(entry.stored), true
+}
+
+// fresh returns the copy of key if it may be served without asking Redis
+func (c *NearCache) fresh(key string) ([]byte, bool) {
+	data, age, ok := c.lookup(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok && c.opts.TTL > 0 && age < c.opts.TTL {
+		c.hits++
+		return data, true
+	}
+	c.misses++
+	return nil, false
+}
+
+// staleCopy returns the copy of key if it may be served in place of cause
+func (c *NearCache) staleCopy(key string, cause error) ([]byte, bool) {
+	if !c.opts.ServeStale || !degradedError(cause) {
+		return nil, false
+	}
+	data, age, ok := c.lookup(key)
+	if !ok || age >= c.opts.TTL+c.opts.StaleGrace {
+		return nil, false
+	}
+	c.mu.Lock()
+	c.stale++
+	c.mu.Unlock()
+	if c.opts.OnStale != nil {
+		c.opts.OnStale(key, age, cause)
+	}
+	return data, true
+}
+
+// store caches the encoded value of key
+func (c *NearCache) store(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &nearEntry{key: key, data: data, stored: time.Now()}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&nearEntry{key: key, data: data, stored: time.Now()})
+	for c.order.Len() > c.opts.Capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*nearEntry).key)
+	}
+}
+
+// Invalidate drops the copies of keys (relative to the repository prefix)
+func (c *NearCache) Invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Purge drops every copy
+func (c *NearCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// Len returns the number of cached keys
+func (c *NearCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats returns cache activity counters
+func (c *NearCache) Stats() NearCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return NearCacheStats{Size: c.order.Len(), Hits: c.hits, Misses: c.misses, StaleServes: c.stale}
+}
+
+// degradedError reports whether err means Redis couldn't answer, as opposed to a miss or a
+// rejected request
+func degradedError(err error) bool {
+	if err == nil || err == redis.Nil {
+		return false
+	}
+	if gpaErr, ok := err.(gpa.GPAError); ok {
+		switch gpaErr.Type {
+		case gpa.ErrorTypeNotFound, gpa.ErrorTypePermission, gpa.ErrorTypeInvalidArgument, gpa.ErrorTypeSerialization:
+			return false
+		}
+	}
+	return true
+}
+
+// nearGet reads key through the near cache, in the shape of a GET result
+func (r *Repository[T]) nearGet(ctx context.Context, key, fullKey string) *redis.StringCmd {
+	if r.near == nil || strongReads(ctx) {
+		return r.getValue(ctx, fullKey)
+	}
+	if data, ok := r.near.fresh(key); ok {
+		return redis.NewStringResult(string(data), nil)
+	}
+	result := r.getValue(ctx, fullKey)
+	switch err := result.Err(); {
+	case err == redis.Nil:
+		r.near.Invalidate(key)
+	case err != nil:
+		if data, ok := r.serveStale(key, convertRedisError(err)); ok {
+			return redis.NewStringResult(string(data), nil)
+		}
+	default:
+		r.near.store(key, []byte(result.Val()))
+	}
+	return result
+}
+
+// nearMGet reads keys through the near cache, in the shape of an MGET reply. When Redis
+// fails, the error is only replaced if every key not served fresh has a stale copy.
+func (r *Repository[T]) nearMGet(ctx context.Context, keys, fullKeys []string) ([]interface{}, error) {
+	if r.near == nil || strongReads(ctx) {
+		return r.mgetValues(ctx, fullKeys)
+	}
+	values := make([]interface{}, len(keys))
+	var missing []int
+	var missingKeys []string
+	for i, key := range keys {
+		if data, ok := r.near.fresh(key); ok {
+			values[i] = string(data)
+			continue
+		}
+		missing = append(missing, i)
+		missingKeys = append(missingKeys, fullKeys[i])
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	fetched, err := r.mgetValues(ctx, missingKeys)
+	if err != nil {
+		for _, i := range missing {
+			data, ok := r.serveStale(keys[i], err)
+			if !ok {
+				return nil, err
+			}
+			values[i] = string(data)
+		}
+		return values, nil
+	}
+	for j, i := range missing {
+		values[i] = fetched[j]
+		if data, ok := fetched[j].(string); ok {
+			r.near.store(keys[i], []byte(data))
+		} else {
+			r.near.Invalidate(keys[i])
+		}
+	}
+	return values, nil
+}
+
+// serveStale returns the cached copy of key in place of err, counting the fallback
+func (r *Repository[T]) serveStale(key string, err error) ([]byte, bool) {
+	if r.near == nil {
+		return nil, false
+	}
+	data, ok := r.near.staleCopy(key, err)
+	if ok {
+		if m := r.provider.metrics.Load(); m != nil {
+			m.recordStaleServe(r.keyPrefix)
+		}
+	}
+	return data, ok
+}
+
+// =====================================
+// Degradation Metrics
+// =====================================
+
+// recordStaleServe counts one stale copy served for the repository at prefix
+func (m *Metrics) recordStaleServe(prefix string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.staleServes[prefix]++
+}
+
+// StaleServes returns how many stale near cache copies were served in place of errors, by prefix
+func (m *Metrics) StaleServes() map[string]uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[string]uint64, len(m.staleServes))
+	for prefix, n := range m.staleServes {
+		counts[prefix] = n
+	}
+	return counts
+}
+
+// writeStalePrometheus appends the stale serve counters to a Prometheus exposition
+func (m *Metrics) writeStalePrometheus(b *strings.Builder) {
+	counts := m.StaleServes()
+	prefixes := make([]string, 0, len(counts))
+	for prefix := range counts {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	b.WriteString("# HELP gparedis_stale_serves_total Stale near cache copies served because Redis failed.\n")
+	b.WriteString("# TYPE gparedis_stale_serves_total counter\n")
+	for _, prefix := range prefixes {
+		fmt.Fprintf(b, "gparedis_stale_serves_total{prefix=\"%s\"} %d\n", promLabel(prefix), counts[prefix])
+	}
+}
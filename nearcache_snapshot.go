@@ -0,0 +1,155 @@
+package gparedis
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Near Cache Warm-Start Snapshots
+// =====================================
+
+// nearSnapshotEntry is one JSON line of a near cache snapshot
+type nearSnapshotEntry struct {
+	Key    string    `json:"key"`
+	Data   []byte    `json:"data"`
+	Stored time.Time `json:"stored"`
+}
+
+// Save writes the cached copies to w as JSON lines, most recently used first
+func (c *NearCache) Save(w io.Writer) error {
+	c.mu.Lock()
+	entries := make([]nearSnapshotEntry, 0, c.order.Len())
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*nearEntry)
+		entries = append(entries, nearSnapshotEntry{Key: entry.key, Data: entry.data, Stored: entry.stored})
+	}
+	c.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to write near cache snapshot", err)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to write near cache snapshot", err)
+	}
+	return nil
+}
+
+// Load adds the copies in a snapshot written by Save, keeping their original age, so
+// copies are only served while they would have been before the restart. Copies too old
+// to be served even as stale fallbacks are skipped. Returns the number of copies loaded.
+func (c *NearCache) Load(r io.Reader) (int, error) {
+	var entries []nearSnapshotEntry
+	dec := json.NewDecoder(r)
+	for {
+		var entry nearSnapshotEntry
+		if err := dec.Decode(&entry); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid near cache snapshot", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	maxAge := c.opts.TTL + c.opts.StaleGrace
+	loaded := 0
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Push least recently used first, so the snapshot's order is restored
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if time.Since(entry.Stored) >= maxAge {
+			continue
+		}
+		if _, exists := c.entries[entry.Key]; exists {
+			continue
+		}
+		c.entries[entry.Key] = c.order.PushFront(&nearEntry{key: entry.Key, data: entry.Data, stored: entry.Stored})
+		loaded++
+	}
+	// Copies already cached are older in LRU order, so they are evicted first
+	for c.order.Len() > c.opts.Capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*nearEntry).key)
+	}
+	return min(loaded, c.opts.Capacity), nil
+}
+
+// SaveFile writes a snapshot to path, replacing it atomically
+func (c *NearCache) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to create near cache snapshot", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := c.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to write near cache snapshot", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to replace near cache snapshot", err)
+	}
+	return nil
+}
+
+// LoadFile loads a snapshot from path; a missing file loads nothing
+func (c *NearCache) LoadFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to open near cache snapshot", err)
+	}
+	defer f.Close()
+	return c.Load(f)
+}
+
+// registerNearCache tracks a repository's near cache so the provider can snapshot it on Close
+func (p *Provider) registerNearCache(prefix string, c *NearCache) {
+	p.nearCachesMu.Lock()
+	defer p.nearCachesMu.Unlock()
+	if p.nearCaches == nil {
+		p.nearCaches = make(map[string]*NearCache)
+	}
+	p.nearCaches[prefix] = c
+}
+
+// SaveNearCaches snapshots every near cache configured with a SnapshotPath. Close calls it.
+func (p *Provider) SaveNearCaches() error {
+	p.nearCachesMu.Lock()
+	caches := make([]*NearCache, 0, len(p.nearCaches))
+	for _, c := range p.nearCaches {
+		caches = append(caches, c)
+	}
+	p.nearCachesMu.Unlock()
+
+	var firstErr error
+	for _, c := range caches {
+		if c.opts.SnapshotPath == "" {
+			continue
+		}
+		if err := c.SaveFile(c.opts.SnapshotPath); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,58 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearCacheSnapshotRoundTrip(t *testing.T) {
+	c := newNearCache(NearCacheOptions{Capacity: 2, TTL: time.Minute, StaleGrace: time.Minute})
+	c.store("a", []byte(`"a"`))
+	c.store("b", []byte(`"b"`))
+	c.store("old", []byte(`"old"`))
+	c.entries["old"].Value.(*nearEntry).stored = time.Now().Add(-time.Hour)
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Save(&buf))
+
+	restored := newNearCache(NearCacheOptions{Capacity: 2, TTL: time.Minute, StaleGrace: time.Minute})
+	n, err := restored.Load(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "copies too old to serve are skipped")
+	data, age, ok := restored.lookup("b")
+	require.True(t, ok)
+	assert.Equal(t, `"b"`, string(data))
+	assert.Greater(t, age, time.Duration(0), "copies keep their age")
+
+	_, err = restored.Load(bytes.NewBufferString("not json"))
+	assert.Error(t, err)
+}
+
+func TestNearCacheWarmStart(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.jsonl")
+	opts := WithNearCache(NearCacheOptions{TTL: time.Minute, SnapshotPath: path})
+	products := NewRepository[TestValue](repo.provider, repo.client, "warm:product:", opts)
+	require.NoError(t, products.Set(ctx, "a", &TestValue{ID: "a", Age: 1}))
+	defer products.MDelete(ctx, []string{"a"})
+	_, err := products.Get(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, repo.provider.SaveNearCaches())
+
+	// A new instance starts with the snapshot's copies
+	restarted := newRepository[TestValue](nil, repo.client, "warm:product:", []RepositoryOption{opts})
+	assert.Equal(t, 1, restarted.NearCache().Len())
+	require.NoError(t, repo.client.Set(ctx, "warm:product:a", `{"id":"a","age":2}`, 0).Err())
+	value, err := restarted.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, value.Age, "served from the warm copy")
+}
@@ -0,0 +1,87 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearCacheServesFreshCopies(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	products := NewRepository[TestValue](repo.provider, repo.client, "near:product:",
+		WithNearCache(NearCacheOptions{Capacity: 2, TTL: time.Minute}))
+	require.NoError(t, products.Set(ctx, "a", &TestValue{ID: "a", Age: 1}))
+	defer products.MDelete(ctx, []string{"a", "b", "c"})
+
+	_, err := products.Get(ctx, "a")
+	require.NoError(t, err)
+	// A write behind the repository's back is invisible until the copy expires
+	require.NoError(t, repo.client.Set(ctx, "near:product:a", `{"id":"a","age":2}`, 0).Err())
+	value, err := products.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, value.Age)
+	strong, err := products.Query(ctx, KeyPattern("a"), Consistency(ConsistencyStrong))
+	require.NoError(t, err)
+	require.Len(t, strong, 1)
+	assert.Equal(t, 2, strong[0].Age, "strong queries bypass the near cache")
+
+	// Writes through the repository invalidate the copy
+	require.NoError(t, products.Set(ctx, "a", &TestValue{ID: "a", Age: 3}))
+	found, err := products.MGet(ctx, []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, found["a"].Age)
+
+	stats := products.NearCache().Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+
+	// The least recently used copy is evicted at capacity
+	require.NoError(t, products.MSet(ctx, map[string]*TestValue{"b": {ID: "b"}, "c": {ID: "c"}}))
+	_, err = products.MGet(ctx, []string{"b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, products.NearCache().Len())
+	_, _, cached := products.NearCache().lookup("a")
+	assert.False(t, cached)
+}
+
+func TestNearCacheServeStaleOnError(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	metrics := repo.provider.EnableMetrics(MetricsOptions{})
+	client := redis.NewClient(&redis.Options{Addr: repo.client.Options().Addr})
+	var staleKeys []string
+	products := NewRepository[TestValue](repo.provider, client, "stale:product:",
+		WithNearCache(NearCacheOptions{ServeStale: true, OnStale: func(key string, age time.Duration, cause error) {
+			staleKeys = append(staleKeys, key)
+		}}))
+	require.NoError(t, products.MSet(ctx, map[string]*TestValue{"a": {ID: "a"}, "b": {ID: "b"}}))
+	defer repo.client.Del(ctx, "stale:product:a", "stale:product:b")
+	_, err := products.MGet(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+
+	// Simulate an outage
+	require.NoError(t, client.Close())
+	value, err := products.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", value.ID)
+	found, err := products.MGet(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, []string{"a", "a", "b"}, staleKeys)
+	assert.Equal(t, uint64(3), metrics.StaleServes()["stale:product:"])
+
+	// Keys without a copy still fail
+	_, err = products.Get(ctx, "c")
+	assert.Error(t, err)
+	_, err = products.MGet(ctx, []string{"a", "c"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package gparedis
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// OpenMetrics Endpoint
+// =====================================
+
+// openMetricsContentType is the content type of the OpenMetrics text format
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// WriteOpenMetrics writes the provider's metrics in the OpenMetrics text format: connection
+// pool stats of the primary and every replica, near cache hit ratios, and, when metrics
+// are enabled, everything WritePrometheus reports (commands, key accesses, latency, SLOs and
+// tracked queues)
+func (p *Provider) WriteOpenMetrics(w io.Writer) error {
+	var b strings.Builder
+	if m := p.metrics.Load(); m != nil {
+		var prom strings.Builder
+		if err := m.WritePrometheus(&prom); err != nil {
+			return err
+		}
+		b.WriteString(prometheusToOpenMetrics(prom.String()))
+	}
+	p.writePoolOpenMetrics(&b)
+	p.writeNearCacheOpenMetrics(&b)
+	b.WriteString("# EOF\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// OpenMetricsHandler serves WriteOpenMetrics output, so services without a metrics library
+// get observability by mounting one handler
+// Example: http.Handle("/metrics", provider.OpenMetricsHandler())
+func (p *Provider) OpenMetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", openMetricsContentType)
+		_ = p.WriteOpenMetrics(w)
+	})
+}
+
+// prometheusToOpenMetrics adapts Prometheus text output: OpenMetrics names counter
+// families without the _total suffix their samples carry
+func prometheusToOpenMetrics(text string) string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	counters := make(map[string]bool)
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) == 4 && fields[1] == "TYPE" && fields[3] == "counter" {
+			counters[fields[2]] = true
+		}
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		if fields := strings.SplitN(line, " ", 4); len(fields) >= 3 && fields[0] == "#" && counters[fields[2]] {
+			fields[2] = strings.TrimSuffix(fields[2], "_total")
+			line = strings.Join(fields, " ")
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// writePoolOpenMetrics appends the connection pool stats of every node
+func (p *Provider) writePoolOpenMetrics(b *strings.Builder) {
+	type nodePool struct {
+		node  string
+		stats *redis.PoolStats
+	}
+	var pools []nodePool
+	if p.client != nil {
+		pools = append(pools, nodePool{"primary", p.client.PoolStats()})
+	}
+	p.nodesMu.RLock()
+	for _, replica := range p.replicas {
+		pools = append(pools, nodePool{replica.addr, replica.client.PoolStats()})
+	}
+	p.nodesMu.RUnlock()
+
+	families := []struct {
+		name, help, kind string
+		value            func(*redis.PoolStats) uint32
+	}{
+		{"gparedis_pool_hits", "Connections reused from the pool.", "counter",
+			func(s *redis.PoolStats) uint32 { return s.Hits }},
+		{"gparedis_pool_misses", "Connections dialed because the pool had none idle.", "counter",
+			func(s *redis.PoolStats) uint32 { return s.Misses }},
+		{"gparedis_pool_timeouts", "Waits for a pool connection that timed out.", "counter",
+			func(s *redis.PoolStats) uint32 { return s.Timeouts }},
+		{"gparedis_pool_connections", "Open connections.", "gauge",
+			func(s *redis.PoolStats) uint32 { return s.TotalConns }},
+		{"gparedis_pool_idle_connections", "Idle connections.", "gauge",
+			func(s *redis.PoolStats) uint32 { return s.IdleConns }},
+		{"gparedis_pool_stale_connections", "Connections removed as stale.", "counter",
+			func(s *redis.PoolStats) uint32 { return s.StaleConns }},
+	}
+	for _, f := range families {
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		sample := f.name
+		if f.kind == "counter" {
+			sample += "_total"
+		}
+		for _, pool := range pools {
+			fmt.Fprintf(b, "%s{node=\"%s\"} %d\n", sample, promLabel(pool.node), f.value(pool.stats))
+		}
+	}
+}
+
+// writeNearCacheOpenMetrics appends the stats of every registered near cache
+func (p *Provider) writeNearCacheOpenMetrics(b *strings.Builder) {
+	p.nearCachesMu.Lock()
+	prefixes := make([]string, 0, len(p.nearCaches))
+	stats := make(map[string]NearCacheStats, len(p.nearCaches))
+	for prefix, c := range p.nearCaches {
+		prefixes = append(prefixes, prefix)
+		stats[prefix] = c.Stats()
+	}
+	p.nearCachesMu.Unlock()
+	sort.Strings(prefixes)
+
+	b.WriteString("# HELP gparedis_near_cache_hits Reads served by the near cache.\n# TYPE gparedis_near_cache_hits counter\n")
+	for _, prefix := range prefixes {
+		fmt.Fprintf(b, "gparedis_near_cache_hits_total{prefix=\"%s\"} %d\n", promLabel(prefix), stats[prefix].Hits)
+	}
+	b.WriteString("# HELP gparedis_near_cache_misses Reads the near cache couldn't serve.\n# TYPE gparedis_near_cache_misses counter\n")
+	for _, prefix := range prefixes {
+		fmt.Fprintf(b, "gparedis_near_cache_misses_total{prefix=\"%s\"} %d\n", promLabel(prefix), stats[prefix].Misses)
+	}
+	b.WriteString("# HELP gparedis_near_cache_hit_ratio Share of reads served by the near cache.\n# TYPE gparedis_near_cache_hit_ratio gauge\n")
+	for _, prefix := range prefixes {
+		s := stats[prefix]
+		ratio := 0.0
+		if total := s.Hits + s.Misses; total > 0 {
+			ratio = float64(s.Hits) / float64(total)
+		}
+		fmt.Fprintf(b, "gparedis_near_cache_hit_ratio{prefix=\"%s\"} %g\n", promLabel(prefix), ratio)
+	}
+	b.WriteString("# HELP gparedis_near_cache_entries Entries held by the near cache.\n# TYPE gparedis_near_cache_entries gauge\n")
+	for _, prefix := range prefixes {
+		fmt.Fprintf(b, "gparedis_near_cache_entries{prefix=\"%s\"} %d\n", promLabel(prefix), stats[prefix].Size)
+	}
+}
@@ -0,0 +1,50 @@
+package gparedis
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenMetricsHandler(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo.provider.EnableMetrics(MetricsOptions{})
+	defer repo.provider.DisableMetrics()
+	users := NewRepository[TestValue](repo.provider, repo.client, "om:user:", WithNearCache(NearCacheOptions{TTL: time.Minute}))
+	require.NoError(t, users.Set(ctx, "1", &TestValue{ID: "1"}))
+	defer repo.client.Del(ctx, "om:user:1")
+	for i := 0; i < 2; i++ {
+		_, err := users.Get(ctx, "1")
+		require.NoError(t, err)
+	}
+
+	rec := httptest.NewRecorder()
+	repo.provider.OpenMetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, openMetricsContentType, rec.Header().Get("Content-Type"))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	out := string(body)
+
+	assert.Contains(t, out, "# TYPE gparedis_commands counter\n")
+	assert.Contains(t, out, "gparedis_commands_total ")
+	assert.Contains(t, out, "# TYPE gparedis_command_duration_seconds histogram\n")
+	assert.Contains(t, out, `gparedis_pool_hits_total{node="primary"}`)
+	assert.Contains(t, out, `gparedis_near_cache_hit_ratio{prefix="om:user:"} 0.5`)
+	assert.NotContains(t, out, "_total counter", "counter families drop the _total suffix")
+	assert.True(t, strings.HasSuffix(out, "# EOF\n"))
+
+	repo.provider.DisableMetrics()
+	rec = httptest.NewRecorder()
+	repo.provider.OpenMetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.NotContains(t, rec.Body.String(), "gparedis_commands")
+	assert.Contains(t, rec.Body.String(), "gparedis_pool_connections")
+}
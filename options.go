@@ -0,0 +1,51 @@
+package gparedis
+
+// =====================================
+// Repository Options
+// =====================================
+
+// RepositoryOption configures optional repository behavior
+type RepositoryOption func(*repositoryOptions)
+
+// repositoryOptions holds the settings applied by RepositoryOption values
+type repositoryOptions struct {
+	allowFullScan bool
+
+	changeStream       string
+	changeStreamMaxLen int64
+
+	audit *AuditLog
+
+	authorizer Authorizer
+
+	slo *SLO
+
+	prefixHashTag  bool
+	segmentHashTag bool
+	hashTagSegment int
+
+	replicaReads bool
+
+	keyEncoding *KeyEncoding
+
+	strictKeys      bool
+	escapeSeparator bool
+
+	idle *IdlePolicy
+
+	nearCache *NearCacheOptions
+
+	canonicalJSON bool
+
+	quota *Quota
+
+	degradation *DegradationPolicy
+}
+
+// AllowFullScan permits operations that deserialize every value under the prefix,
+// such as Query with Where conditions and DeleteByCondition. Intended for admin and batch jobs.
+func AllowFullScan() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.allowFullScan = true
+	}
+}
@@ -0,0 +1,207 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Outbox Bridge to External Brokers
+// =====================================
+
+// OutboxMessage is one stream entry handed to a Publisher
+type OutboxMessage struct {
+	// ID is the stream entry ID; brokers can use it to deduplicate redeliveries
+	ID string
+	// Key is the ordering key: messages sharing it are published in stream order
+	Key string
+	// Value is the JSON encoded entry
+	Value []byte
+}
+
+// Publisher delivers outbox messages to an external broker such as Kafka or NATS. Publish
+// must only return nil once the broker accepted every message, in order.
+type Publisher interface {
+	Publish(ctx context.Context, msgs []OutboxMessage) error
+}
+
+// PublisherFunc adapts a function to the Publisher interface
+type PublisherFunc func(ctx context.Context, msgs []OutboxMessage) error
+
+// Publish calls f
+func (f PublisherFunc) Publish(ctx context.Context, msgs []OutboxMessage) error {
+	return f(ctx, msgs)
+}
+
+// OutboxBridgeOptions configures an OutboxBridge
+type OutboxBridgeOptions[T any] struct {
+	// Name identifies the bridge's checkpoint, so several bridges can relay one stream (default "default")
+	Name string
+	// KeyOf returns the ordering key of a value (default: the key of change events, else one
+	// key for all messages)
+	KeyOf func(value *T) string
+	// Lanes is the number of concurrent Publish calls; keys are spread over lanes by hash (default 1)
+	Lanes int
+	// BatchSize is the number of entries read per batch (default 100)
+	BatchSize int64
+	// Block is how long Run waits for new entries (default 5s)
+	Block time.Duration
+	// RetryDelay is how long Run waits after a failed batch (default 1s)
+	RetryDelay time.Duration
+	// OnError is called when Run fails to relay a batch; the batch is retried
+	OnError func(err error)
+}
+
+// OutboxBridge relays a stream, typically a repository's change stream, to an external broker.
+// Entries are read in batches after the last relayed ID, which is kept in a checkpoint key
+// next to the stream and advanced only once every message of the batch was published. A
+// failed batch is published again in full, so delivery is at least once. Run one bridge per
+// name; messages sharing a key keep their stream order.
+type OutboxBridge[T any] struct {
+	stream    *Stream[T]
+	publisher Publisher
+	opts      OutboxBridgeOptions[T]
+}
+
+// NewOutboxBridge creates a bridge relaying stream to publisher
+// Example: bridge := gparedis.NewOutboxBridge(users.Changes(), kafkaPublisher, gparedis.OutboxBridgeOptions[gparedis.ChangeEvent]{Lanes: 4})
+func NewOutboxBridge[T any](stream *Stream[T], publisher Publisher, opts OutboxBridgeOptions[T]) *OutboxBridge[T] {
+	if opts.Name == "" {
+		opts.Name = "default"
+	}
+	if opts.KeyOf == nil {
+		opts.KeyOf = func(value *T) string {
+			if event, ok := any(value).(*ChangeEvent); ok {
+				return event.Key
+			}
+			return ""
+		}
+	}
+	if opts.Lanes <= 0 {
+		opts.Lanes = 1
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.Block <= 0 {
+		opts.Block = 5 * time.Second
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+	return &OutboxBridge[T]{stream: stream, publisher: publisher, opts: opts}
+}
+
+// checkpointKey holds the ID of the last relayed entry
+func (b *OutboxBridge[T]) checkpointKey() string {
+	return b.stream.companionKey(":bridge:" + b.opts.Name)
+}
+
+// Checkpoint returns the ID of the last relayed entry, "0" before the first batch
+func (b *OutboxBridge[T]) Checkpoint(ctx context.Context) (string, error) {
+	id, err := b.stream.client.Get(ctx, b.checkpointKey()).Result()
+	if err == redis.Nil {
+		return "0", nil
+	}
+	if err != nil {
+		return "", convertRedisError(err)
+	}
+	return id, nil
+}
+
+// SetCheckpoint moves the checkpoint, to skip entries or to replay from an earlier ID
+func (b *OutboxBridge[T]) SetCheckpoint(ctx context.Context, id string) error {
+	return convertRedisError(b.stream.client.Set(ctx, b.checkpointKey(), id, 0).Err())
+}
+
+// RelayBatch publishes the entries available after the checkpoint, up to BatchSize, without
+// waiting, and returns how many were published
+func (b *OutboxBridge[T]) RelayBatch(ctx context.Context) (int, error) {
+	return b.relay(ctx, -1)
+}
+
+// Run relays entries until ctx is cancelled, retrying failed batches after RetryDelay
+func (b *OutboxBridge[T]) Run(ctx context.Context) error {
+	for {
+		if _, err := b.relay(ctx, b.opts.Block); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if b.opts.OnError != nil {
+				b.opts.OnError(err)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(b.opts.RetryDelay):
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// relay publishes one batch, waiting up to block for entries, and advances the checkpoint
+func (b *OutboxBridge[T]) relay(ctx context.Context, block time.Duration) (int, error) {
+	last, err := b.Checkpoint(ctx)
+	if err != nil {
+		return 0, err
+	}
+	msgs, err := b.stream.Read(ctx, last, b.opts.BatchSize, block)
+	if err != nil || len(msgs) == 0 {
+		return 0, err
+	}
+
+	lanes := make([][]OutboxMessage, b.opts.Lanes)
+	published := 0
+	for _, msg := range msgs {
+		if msg.Value == nil {
+			// Entry was trimmed before we got to it; nothing to publish
+			continue
+		}
+		data, err := json.Marshal(msg.Value)
+		if err != nil {
+			return 0, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize outbox message", err)
+		}
+		key := b.opts.KeyOf(msg.Value)
+		lane := 0
+		if b.opts.Lanes > 1 {
+			h := fnv.New32a()
+			h.Write([]byte(key))
+			lane = int(h.Sum32() % uint32(b.opts.Lanes))
+		}
+		lanes[lane] = append(lanes[lane], OutboxMessage{ID: msg.ID, Key: key, Value: data})
+		published++
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(lanes))
+	for i, lane := range lanes {
+		if len(lane) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, lane []OutboxMessage) {
+			defer wg.Done()
+			errs[i] = b.publisher.Publish(ctx, lane)
+		}(i, lane)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return 0, gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to publish outbox batch", err)
+		}
+	}
+
+	if err := b.SetCheckpoint(ctx, msgs[len(msgs)-1].ID); err != nil {
+		return published, err
+	}
+	return published, nil
+}
@@ -0,0 +1,79 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxBridge(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[TestValue](base.provider, base.client, "user:", CaptureChanges("cdc:user", 0))
+
+	var mu sync.Mutex
+	var fail bool
+	perKey := make(map[string][]int64)
+	publisher := PublisherFunc(func(ctx context.Context, msgs []OutboxMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return errors.New("broker unavailable")
+		}
+		for _, msg := range msgs {
+			var event ChangeEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				return err
+			}
+			assert.Equal(t, event.Key, msg.Key)
+			perKey[msg.Key] = append(perKey[msg.Key], event.Version)
+		}
+		return nil
+	})
+	bridge := NewOutboxBridge(repo.Changes(), publisher, OutboxBridgeOptions[ChangeEvent]{Lanes: 3})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.Set(ctx, "1", &TestValue{ID: "1"}))
+		require.NoError(t, repo.Set(ctx, "2", &TestValue{ID: "2"}))
+	}
+	require.NoError(t, repo.DeleteKey(ctx, "2"))
+
+	n, err := bridge.RelayBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+	assert.Equal(t, []int64{1, 2, 3}, perKey["1"])
+	assert.Equal(t, []int64{1, 2, 3, 4}, perKey["2"])
+
+	// Nothing new to relay
+	n, err = bridge.RelayBatch(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	// A failed batch leaves the checkpoint in place and is relayed again
+	checkpoint, err := bridge.Checkpoint(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Set(ctx, "3", &TestValue{ID: "3"}))
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	_, err = bridge.RelayBatch(ctx)
+	require.Error(t, err)
+	after, err := bridge.Checkpoint(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, checkpoint, after)
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	n, err = bridge.RelayBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []int64{1}, perKey["3"])
+}
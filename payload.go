@@ -0,0 +1,134 @@
+package gparedis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Outgoing Payload Limits
+// =====================================
+
+const (
+	// payloadGzipMarker prefixes gzip compressed payloads
+	payloadGzipMarker = "\x00gz:"
+	// payloadClaimMarker prefixes a reference to a payload stored in a key
+	payloadClaimMarker = "\x00claim:"
+	// claimCheckKeyPrefix is the key prefix of stored payloads
+	claimCheckKeyPrefix = "gparedis:claim:"
+)
+
+// PayloadOptions configures how Publish and stream appends encode payloads
+type PayloadOptions struct {
+	// CompressAbove gzips payloads larger than this many bytes (0 disables compression)
+	CompressAbove int
+	// MaxPayload is the largest payload sent after compression, in bytes (0 for no limit).
+	// Larger payloads fail with ErrorTypePayloadTooLarge unless ClaimCheck is set.
+	MaxPayload int
+	// ClaimCheck stores payloads over MaxPayload in a key and sends a reference to it instead
+	ClaimCheck bool
+	// ClaimCheckTTL is how long stored payloads are kept for consumers (default 1h)
+	ClaimCheckTTL time.Duration
+}
+
+// SetPayloadOptions configures compression and size limits of outgoing payloads; the zero
+// value sends payloads unchanged. Consumers decode payloads with DecodePayload, which Router
+// and Stream do automatically, so enable it only once consumers are upgraded.
+// Example: provider.SetPayloadOptions(gparedis.PayloadOptions{CompressAbove: 4 << 10, MaxPayload: 512 << 10, ClaimCheck: true})
+func (p *Provider) SetPayloadOptions(opts PayloadOptions) {
+	if opts == (PayloadOptions{}) {
+		p.payloadOpts.Store(nil)
+		return
+	}
+	if opts.ClaimCheckTTL <= 0 {
+		opts.ClaimCheckTTL = time.Hour
+	}
+	p.payloadOpts.Store(&opts)
+}
+
+// PayloadOptions returns the configured payload options
+func (p *Provider) PayloadOptions() PayloadOptions {
+	if opts := p.payloadOpts.Load(); opts != nil {
+		return *opts
+	}
+	return PayloadOptions{}
+}
+
+// encodePayload compresses payload and enforces the size limit, storing oversized payloads
+// in a key when claim checks are enabled
+func (p *Provider) encodePayload(ctx context.Context, payload []byte) ([]byte, error) {
+	opts := p.payloadOpts.Load()
+	if opts == nil {
+		return payload, nil
+	}
+	if opts.CompressAbove > 0 && len(payload) > opts.CompressAbove {
+		compressed, err := gzipPayload(payload)
+		if err != nil {
+			return nil, err
+		}
+		if len(compressed) < len(payload) {
+			payload = compressed
+		}
+	}
+	if opts.MaxPayload <= 0 || len(payload) <= opts.MaxPayload {
+		return payload, nil
+	}
+	if !opts.ClaimCheck {
+		return nil, gpa.NewError(ErrorTypePayloadTooLarge, fmt.Sprintf("payload of %d bytes exceeds the limit of %d bytes", len(payload), opts.MaxPayload))
+	}
+	// Subscribers and consumer groups are unknown here, so stored payloads live out their TTL
+	return NewClaimCheck(p, ClaimCheckOptions{TTL: opts.ClaimCheckTTL, KeepUntilExpiry: true}).store(ctx, payload)
+}
+
+// DecodePayload returns the original payload sent by Publish, a stream append or a
+// ClaimCheck: it claims stored payloads from their key and decompresses gzipped ones. Other
+// payloads are returned unchanged. A claim check whose payload was consumed or expired fails
+// with ErrorTypeNotFound.
+// Example: body, err := provider.DecodePayload(ctx, []byte(msg.Payload))
+func (p *Provider) DecodePayload(ctx context.Context, payload []byte) ([]byte, error) {
+	if key, ok := claimCheckKey(payload); ok {
+		stored, err := p.claimPayload(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		payload = stored
+	}
+	if compressed, ok := bytes.CutPrefix(payload, []byte(payloadGzipMarker)); ok {
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid compressed payload", err)
+		}
+		defer reader.Close()
+		decompressed, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid compressed payload", err)
+		}
+		return decompressed, nil
+	}
+	return payload, nil
+}
+
+// gzipPayload compresses payload behind the gzip marker
+func gzipPayload(payload []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(payloadGzipMarker)
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(payload); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to compress payload", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to compress payload", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// isEncodedPayload reports whether a payload may need DecodePayload
+func isEncodedPayload(payload string) bool {
+	return len(payload) > 0 && payload[0] == 0
+}
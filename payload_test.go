@@ -0,0 +1,99 @@
+package gparedis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishPayloadLimits(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	p := repo.provider
+	defer p.SetPayloadOptions(PayloadOptions{})
+
+	pubsub := repo.client.Subscribe(ctx, "payloads")
+	defer pubsub.Close()
+	_, err := pubsub.Receive(ctx)
+	require.NoError(t, err)
+	receive := func() []byte {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		require.NoError(t, err)
+		return []byte(msg.Payload)
+	}
+
+	body := []byte(strings.Repeat(`{"name":"payload"}`, 500))
+
+	// Compressed below the limit
+	p.SetPayloadOptions(PayloadOptions{CompressAbove: 1024, MaxPayload: 4096})
+	_, err = p.Publish(ctx, "payloads", body)
+	require.NoError(t, err)
+	raw := receive()
+	assert.Less(t, len(raw), 4096)
+	decoded, err := p.DecodePayload(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, body, decoded)
+
+	// Small payloads are sent unchanged
+	_, err = p.Publish(ctx, "payloads", []byte("small"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("small"), receive())
+
+	// Oversized payloads are rejected
+	p.SetPayloadOptions(PayloadOptions{MaxPayload: 1024})
+	_, err = p.Publish(ctx, "payloads", body)
+	assert.True(t, IsPayloadTooLargeError(err))
+
+	// ... or claim-checked
+	p.SetPayloadOptions(PayloadOptions{MaxPayload: 1024, ClaimCheck: true})
+	_, err = p.Publish(ctx, "payloads", body)
+	require.NoError(t, err)
+	raw = receive()
+	assert.True(t, strings.HasPrefix(string(raw), payloadClaimMarker+claimCheckKeyPrefix))
+	key := strings.TrimPrefix(string(raw), payloadClaimMarker)
+	ttl, err := repo.client.TTL(ctx, key).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl.Minutes(), 59.0)
+	decoded, err = p.DecodePayload(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, body, decoded)
+
+	require.NoError(t, repo.client.Del(ctx, key).Err())
+	_, err = p.DecodePayload(ctx, raw)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+}
+
+func TestStreamPayloadCompression(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	p := repo.provider
+	p.SetPayloadOptions(PayloadOptions{CompressAbove: 256})
+	defer p.SetPayloadOptions(PayloadOptions{})
+
+	stream := NewStream[TestValue](p, "test:payload:stream", StreamOptions{})
+	defer repo.client.Del(ctx, stream.Key())
+
+	value := &TestValue{ID: "1", Name: strings.Repeat("n", 2000), Age: 3}
+	_, err := stream.Add(ctx, value)
+	require.NoError(t, err)
+	_, err = stream.Add(ctx, &TestValue{ID: "2"})
+	require.NoError(t, err)
+
+	msgs, err := stream.Range(ctx, "-", "+", 0)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, value, msgs[0].Value)
+	assert.Equal(t, "2", msgs[1].Value.ID)
+
+	raw, err := repo.client.XRange(ctx, stream.Key(), "-", "+").Result()
+	require.NoError(t, err)
+	assert.Less(t, len(raw[0].Values[streamDataField].(string)), 500)
+}
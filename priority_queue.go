@@ -0,0 +1,242 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Priority Queue
+// =====================================
+
+// PriorityItem is an item popped from or peeked at in a PriorityQueue
+type PriorityItem[T any] struct {
+	ID       string
+	Priority float64
+	Value    *T
+}
+
+// PriorityQueue is a typed priority queue. Item IDs are ordered by priority in a sorted set
+// at key; payloads are stored as JSON in the hash key+":items".
+type PriorityQueue[T any] struct {
+	provider *Provider
+	client   *redis.Client
+	key      string
+}
+
+// NewPriorityQueue creates a priority queue stored at key
+// Example: jobs := gparedis.NewPriorityQueue[Job](provider, "jobs:pending")
+func NewPriorityQueue[T any](provider *Provider, key string) *PriorityQueue[T] {
+	return &PriorityQueue[T]{provider: provider, client: provider.client, key: key}
+}
+
+// Key returns the Redis key of the sorted set
+func (q *PriorityQueue[T]) Key() string {
+	return q.key
+}
+
+// itemsKey returns the hash holding the payloads
+func (q *PriorityQueue[T]) itemsKey() string {
+	return q.key + ":items"
+}
+
+// enqueuedKey returns the sorted set of enqueue times (unix ms), for QueueStats
+func (q *PriorityQueue[T]) enqueuedKey() string {
+	return q.key + ":enqueued"
+}
+
+// Push enqueues value with priority under a generated ID, which is returned
+func (q *PriorityQueue[T]) Push(ctx context.Context, value *T, priority float64) (string, error) {
+	id, err := newRandomID()
+	if err != nil {
+		return "", gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to generate queue item ID", err)
+	}
+	return id, q.PushWithID(ctx, id, value, priority)
+}
+
+// PushWithID enqueues value under id. Pushing an ID that is already queued replaces its
+// payload and priority, which makes retried producers idempotent.
+func (q *PriorityQueue[T]) PushWithID(ctx context.Context, id string, value *T, priority float64) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize queue item", err)
+	}
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.itemsKey(), id, data)
+		pipe.ZAdd(ctx, q.key, &redis.Z{Score: priority, Member: id})
+		pipe.ZAddNX(ctx, q.enqueuedKey(), &redis.Z{Score: float64(time.Now().UnixMilli()), Member: id})
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// popScript pops the lowest (ARGV[1] = "min") or highest item and its payload atomically.
+// KEYS[1]: sorted set, KEYS[2]: payload hash, KEYS[3]: enqueue times. Returns {id, score,
+// payload} or nil.
+var popScript = dryRunReply(redis.NewScript(`
+local popped
+if ARGV[1] == 'min' then
+	popped = redis.call('ZPOPMIN', KEYS[1])
+else
+	popped = redis.call('ZPOPMAX', KEYS[1])
+end
+if #popped == 0 then
+	return nil
+end
+local payload = redis.call('HGET', KEYS[2], popped[1])
+redis.call('HDEL', KEYS[2], popped[1])
+redis.call('ZREM', KEYS[3], popped[1])
+return {popped[1], popped[2], payload}
+`), redis.Nil)
+
+// PopHighest removes and returns the item with the highest priority, or nil when the queue is empty
+func (q *PriorityQueue[T]) PopHighest(ctx context.Context) (*PriorityItem[T], error) {
+	return q.pop(ctx, "max")
+}
+
+// PopLowest removes and returns the item with the lowest priority, or nil when the queue is empty
+func (q *PriorityQueue[T]) PopLowest(ctx context.Context) (*PriorityItem[T], error) {
+	return q.pop(ctx, "min")
+}
+
+// pop runs popScript for one end of the queue
+func (q *PriorityQueue[T]) pop(ctx context.Context, end string) (*PriorityItem[T], error) {
+	res, err := popScript.Run(ctx, q.client, []string{q.key, q.itemsKey(), q.enqueuedKey()}, end).Slice()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	q.provider.recordProcessed(queueKindPriority, q.key, "", 1)
+	id, _ := res[0].(string)
+	score, _ := res[1].(string)
+	payload, _ := res[2].(string)
+
+	priority, err := strconv.ParseFloat(score, 64)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeDatabase, "invalid queue item priority", err)
+	}
+	return q.decodeItem(redis.Z{Member: id, Score: priority}, payload)
+}
+
+// BlockPopLowest waits up to timeout (whole seconds, 0 waits forever) for an item and removes the one with
+// the lowest priority. Returns nil when the timeout passes without an item.
+func (q *PriorityQueue[T]) BlockPopLowest(ctx context.Context, timeout time.Duration) (*PriorityItem[T], error) {
+	return q.blockPop(ctx, q.client.BZPopMin(ctx, timeout, q.key))
+}
+
+// BlockPopHighest waits up to timeout (whole seconds, 0 waits forever) for an item and removes the one with
+// the highest priority. Returns nil when the timeout passes without an item.
+func (q *PriorityQueue[T]) BlockPopHighest(ctx context.Context, timeout time.Duration) (*PriorityItem[T], error) {
+	return q.blockPop(ctx, q.client.BZPopMax(ctx, timeout, q.key))
+}
+
+// blockPop fetches the payload of an ID popped by BZPOPMIN/BZPOPMAX. The pop itself is
+// atomic, so only this consumer owns the ID and its payload.
+func (q *PriorityQueue[T]) blockPop(ctx context.Context, cmd *redis.ZWithKeyCmd) (*PriorityItem[T], error) {
+	popped, err := cmd.Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+
+	var payload *redis.StringCmd
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		payload = pipe.HGet(ctx, q.itemsKey(), popped.Member.(string))
+		pipe.HDel(ctx, q.itemsKey(), popped.Member.(string))
+		pipe.ZRem(ctx, q.enqueuedKey(), popped.Member.(string))
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, convertRedisError(err)
+	}
+	q.provider.recordProcessed(queueKindPriority, q.key, "", 1)
+	return q.decodeItem(popped.Z, payload.Val())
+}
+
+// Peek returns up to n items without removing them, highest priority first when highest is set
+func (q *PriorityQueue[T]) Peek(ctx context.Context, n int64, highest bool) ([]*PriorityItem[T], error) {
+	if n <= 0 {
+		return []*PriorityItem[T]{}, nil
+	}
+	var members []redis.Z
+	var err error
+	if highest {
+		members, err = q.client.ZRevRangeWithScores(ctx, q.key, 0, n-1).Result()
+	} else {
+		members, err = q.client.ZRangeWithScores(ctx, q.key, 0, n-1).Result()
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	if len(members) == 0 {
+		return []*PriorityItem[T]{}, nil
+	}
+
+	ids := make([]string, len(members))
+	for i, z := range members {
+		ids[i] = z.Member.(string)
+	}
+	payloads, err := q.client.HMGet(ctx, q.itemsKey(), ids...).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+
+	items := make([]*PriorityItem[T], 0, len(members))
+	for i, z := range members {
+		payload, ok := payloads[i].(string)
+		if !ok {
+			// Popped concurrently
+			continue
+		}
+		item, err := q.decodeItem(z, payload)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Len returns the number of queued items
+func (q *PriorityQueue[T]) Len(ctx context.Context) (int64, error) {
+	n, err := q.client.ZCard(ctx, q.key).Result()
+	return n, convertRedisError(err)
+}
+
+// Remove deletes a queued item by ID and reports whether it was queued
+func (q *PriorityQueue[T]) Remove(ctx context.Context, id string) (bool, error) {
+	var removed *redis.IntCmd
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		removed = pipe.ZRem(ctx, q.key, id)
+		pipe.HDel(ctx, q.itemsKey(), id)
+		pipe.ZRem(ctx, q.enqueuedKey(), id)
+		return nil
+	})
+	if err != nil {
+		return false, convertRedisError(err)
+	}
+	return removed.Val() > 0, nil
+}
+
+// decodeItem builds a PriorityItem from a sorted set entry and its payload
+func (q *PriorityQueue[T]) decodeItem(z redis.Z, payload string) (*PriorityItem[T], error) {
+	item := &PriorityItem[T]{ID: z.Member.(string), Priority: z.Score}
+	if payload == "" {
+		return nil, gpa.NewError(gpa.ErrorTypeNotFound, "queue item "+item.ID+" has no payload")
+	}
+	var value T
+	if err := json.Unmarshal([]byte(payload), &value); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize queue item", err)
+	}
+	item.Value = &value
+	return item, nil
+}
@@ -0,0 +1,66 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	q := NewPriorityQueue[TestValue](repo.provider, "jobs")
+
+	_, err := q.Push(ctx, &TestValue{ID: "low"}, 1)
+	require.NoError(t, err)
+	_, err = q.Push(ctx, &TestValue{ID: "high"}, 10)
+	require.NoError(t, err)
+	require.NoError(t, q.PushWithID(ctx, "mid", &TestValue{ID: "mid"}, 5))
+	require.NoError(t, q.PushWithID(ctx, "mid", &TestValue{ID: "mid", Age: 2}, 6))
+
+	n, err := q.Len(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	peeked, err := q.Peek(ctx, 2, true)
+	require.NoError(t, err)
+	require.Len(t, peeked, 2)
+	assert.Equal(t, "high", peeked[0].Value.ID)
+	assert.Equal(t, 6.0, peeked[1].Priority)
+
+	item, err := q.PopHighest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "high", item.Value.ID)
+	assert.Equal(t, 10.0, item.Priority)
+
+	item, err = q.PopLowest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "low", item.Value.ID)
+
+	item, err = q.BlockPopLowest(ctx, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "mid", item.ID)
+	assert.Equal(t, 2, item.Value.Age)
+
+	item, err = q.PopHighest(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, item)
+
+	item, err = q.BlockPopHighest(ctx, time.Second)
+	require.NoError(t, err)
+	assert.Nil(t, item)
+
+	id, err := q.Push(ctx, &TestValue{ID: "gone"}, 1)
+	require.NoError(t, err)
+	removed, err := q.Remove(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	exists, err := repo.provider.client.Exists(ctx, "jobs:items").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), exists)
+}
@@ -0,0 +1,282 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Job Progress Tracking
+// =====================================
+
+// JobProgressState is a snapshot of a job's progress
+type JobProgressState struct {
+	Job   string
+	Stage string
+	// Percent is the completion, between 0 and 100
+	Percent   float64
+	StartedAt time.Time
+	UpdatedAt time.Time
+	// FinishedAt is set once the job finished, successfully or not
+	FinishedAt time.Time
+	// Error is the failure the job finished with
+	Error string
+	// Alive reports whether the job's heartbeat hasn't expired; a running job that is no
+	// longer alive was abandoned by its worker
+	Alive bool
+}
+
+// Done reports whether the job finished
+func (s *JobProgressState) Done() bool {
+	return !s.FinishedAt.IsZero()
+}
+
+// JobProgressOptions configures a JobProgress
+type JobProgressOptions struct {
+	// LogSize is the number of log lines kept (default 100)
+	LogSize int64
+	// HeartbeatTTL is how long a heartbeat keeps the job alive (default 30s)
+	HeartbeatTTL time.Duration
+	// Retention is how long the progress is kept after the last update (default 24h)
+	Retention time.Duration
+	// WatchInterval is how often Watch re-reads the progress, to notice expired heartbeats
+	// and updates missed while reconnecting (default 5s)
+	WatchInterval time.Duration
+}
+
+// JobProgress reports the progress of one long-running job so any instance can follow it.
+// The stage, percent and timestamps live in a hash at prefix+job, the tail of the job's log
+// in the capped list prefix+job+":log", and its heartbeat in prefix+job+":heartbeat", a key
+// expiring after HeartbeatTTL. Every write also publishes on prefix+job+":events" to wake
+// watchers.
+type JobProgress struct {
+	provider *Provider
+	client   *redis.Client
+	prefix   string
+	job      string
+	opts     JobProgressOptions
+}
+
+// NewJobProgress creates the progress tracker of job, keeping its state under prefix. The
+// worker and every watcher create their own with the same prefix and job.
+// Example: progress := gparedis.NewJobProgress(provider, "progress:", "import-42", gparedis.JobProgressOptions{})
+func NewJobProgress(provider *Provider, prefix, job string, opts JobProgressOptions) *JobProgress {
+	if opts.LogSize <= 0 {
+		opts.LogSize = 100
+	}
+	if opts.HeartbeatTTL <= 0 {
+		opts.HeartbeatTTL = 30 * time.Second
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = 24 * time.Hour
+	}
+	if opts.WatchInterval <= 0 {
+		opts.WatchInterval = 5 * time.Second
+	}
+	return &JobProgress{provider: provider, client: provider.client, prefix: prefix, job: job, opts: opts}
+}
+
+// stateKey is the hash of the job's progress
+func (p *JobProgress) stateKey() string {
+	return p.prefix + p.job
+}
+
+// logKey is the capped list of the job's latest log lines
+func (p *JobProgress) logKey() string {
+	return p.prefix + p.job + ":log"
+}
+
+// heartbeatKey expires when the job stops sending heartbeats
+func (p *JobProgress) heartbeatKey() string {
+	return p.prefix + p.job + ":heartbeat"
+}
+
+// channel is where every update is announced
+func (p *JobProgress) channel() string {
+	return p.prefix + p.job + ":events"
+}
+
+// Start resets the progress and marks the job as running in stage
+func (p *JobProgress) Start(ctx context.Context, stage string) error {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return p.write(ctx, func(pipe redis.Pipeliner) {
+		pipe.Del(ctx, p.stateKey(), p.logKey())
+		pipe.HSet(ctx, p.stateKey(), "stage", stage, "percent", "0", "started", now, "updated", now)
+		pipe.Set(ctx, p.heartbeatKey(), now, p.opts.HeartbeatTTL)
+	})
+}
+
+// Update records the job's stage and percent, and counts as a heartbeat. An empty stage
+// keeps the current one; percent is clamped to [0, 100].
+// Example: err := progress.Update(ctx, "importing", 100*float64(done)/float64(total))
+func (p *JobProgress) Update(ctx context.Context, stage string, percent float64) error {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	fields := []interface{}{"percent", strconv.FormatFloat(percent, 'f', -1, 64), "updated", now}
+	if stage != "" {
+		fields = append(fields, "stage", stage)
+	}
+	return p.write(ctx, func(pipe redis.Pipeliner) {
+		pipe.HSet(ctx, p.stateKey(), fields...)
+		pipe.Set(ctx, p.heartbeatKey(), now, p.opts.HeartbeatTTL)
+	})
+}
+
+// Log appends a line to the job's log, keeping the last LogSize lines
+func (p *JobProgress) Log(ctx context.Context, line string) error {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return p.write(ctx, func(pipe redis.Pipeliner) {
+		pipe.RPush(ctx, p.logKey(), line)
+		pipe.LTrim(ctx, p.logKey(), -p.opts.LogSize, -1)
+		pipe.HSet(ctx, p.stateKey(), "updated", now)
+	})
+}
+
+// Heartbeat keeps the job alive for another HeartbeatTTL without changing its progress.
+// Workers call it more often than HeartbeatTTL during steps that don't report progress.
+func (p *JobProgress) Heartbeat(ctx context.Context) error {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return convertRedisError(p.client.Set(ctx, p.heartbeatKey(), now, p.opts.HeartbeatTTL).Err())
+}
+
+// Finish marks the job as finished, failed when jobErr is set, and stops its heartbeat
+func (p *JobProgress) Finish(ctx context.Context, jobErr error) error {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	fields := []interface{}{"updated", now, "finished", now}
+	if jobErr != nil {
+		fields = append(fields, "error", jobErr.Error())
+	} else {
+		fields = append(fields, "percent", "100")
+	}
+	return p.write(ctx, func(pipe redis.Pipeliner) {
+		pipe.HSet(ctx, p.stateKey(), fields...)
+		pipe.Del(ctx, p.heartbeatKey())
+	})
+}
+
+// write applies a change in a transaction, renews the retention and wakes watchers
+func (p *JobProgress) write(ctx context.Context, change func(pipe redis.Pipeliner)) error {
+	_, err := p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		change(pipe)
+		pipe.Expire(ctx, p.stateKey(), p.opts.Retention)
+		pipe.Expire(ctx, p.logKey(), p.opts.Retention)
+		pipe.Publish(ctx, p.channel(), p.job)
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// Get returns the job's progress, or ErrorTypeNotFound when it never started or expired
+func (p *JobProgress) Get(ctx context.Context) (*JobProgressState, error) {
+	var fields *redis.StringStringMapCmd
+	var heartbeat *redis.IntCmd
+	_, err := p.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		fields = pipe.HGetAll(ctx, p.stateKey())
+		heartbeat = pipe.Exists(ctx, p.heartbeatKey())
+		return nil
+	})
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	raw := fields.Val()
+	if len(raw) == 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeNotFound, "job progress not found: "+p.job)
+	}
+
+	state := &JobProgressState{
+		Job:        p.job,
+		Stage:      raw["stage"],
+		Error:      raw["error"],
+		StartedAt:  parseProgressTime(raw["started"]),
+		UpdatedAt:  parseProgressTime(raw["updated"]),
+		FinishedAt: parseProgressTime(raw["finished"]),
+		Alive:      heartbeat.Val() > 0,
+	}
+	state.Percent, _ = strconv.ParseFloat(raw["percent"], 64)
+	return state, nil
+}
+
+// parseProgressTime parses unix milliseconds, returning the zero time for empty fields
+func parseProgressTime(value string) time.Time {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// Logs returns the tail of the job's log, oldest line first
+func (p *JobProgress) Logs(ctx context.Context) ([]string, error) {
+	lines, err := p.client.LRange(ctx, p.logKey(), 0, -1).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	return lines, nil
+}
+
+// Watch yields the job's progress whenever it changes, starting with its current state once
+// the job has started. Changes are announced over Pub/Sub and the state is re-read every
+// WatchInterval, so a worker whose heartbeat expires is reported with Alive unset. When
+// updates arrive in quick succession only the latest state may be seen. The channel is
+// closed after the finished state is yielded, or when ctx is cancelled.
+// Example: for state := range updates { fmt.Printf("%s %.0f%%\n", state.Stage, state.Percent) }
+func (p *JobProgress) Watch(ctx context.Context) (<-chan JobProgressState, error) {
+	pubsub := p.client.Subscribe(ctx, p.channel())
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to subscribe to "+p.channel(), err)
+	}
+
+	updates := make(chan JobProgressState)
+	go func() {
+		// Closing the subscription interrupts a pending receive
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+	go func() {
+		defer close(updates)
+		defer pubsub.Close()
+		var last *JobProgressState
+		for {
+			state, err := p.Get(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil && (last == nil || *state != *last) {
+				select {
+				case <-ctx.Done():
+					return
+				case updates <- *state:
+				}
+				if state.Done() {
+					return
+				}
+				last = state
+			}
+
+			_, err = pubsub.ReceiveTimeout(ctx, p.opts.WatchInterval)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				if netErr, ok := err.(interface{ Timeout() bool }); !ok || !netErr.Timeout() {
+					// The next Receive reconnects and resubscribes; the re-read catches up
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(time.Second):
+					}
+				}
+			}
+		}
+	}()
+	return updates, nil
+}
@@ -0,0 +1,85 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobProgress(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	progress := NewJobProgress(repo.provider, "progress:", "import", JobProgressOptions{LogSize: 3, HeartbeatTTL: 200 * time.Millisecond})
+	_, err := progress.Get(ctx)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+
+	require.NoError(t, progress.Start(ctx, "download"))
+	require.NoError(t, progress.Update(ctx, "", 150))
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, progress.Log(ctx, fmt.Sprintf("line %d", i)))
+	}
+	state, err := progress.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "download", state.Stage, "an empty stage keeps the current one")
+	assert.Equal(t, 100.0, state.Percent, "percent is clamped")
+	assert.True(t, state.Alive)
+	assert.False(t, state.Done())
+	logs, err := progress.Logs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 3", "line 4", "line 5"}, logs)
+
+	time.Sleep(300 * time.Millisecond)
+	state, err = progress.Get(ctx)
+	require.NoError(t, err)
+	assert.False(t, state.Alive, "the heartbeat expired")
+	require.NoError(t, progress.Heartbeat(ctx))
+	state, err = progress.Get(ctx)
+	require.NoError(t, err)
+	assert.True(t, state.Alive)
+
+	require.NoError(t, progress.Finish(ctx, errors.New("disk full")))
+	state, err = progress.Get(ctx)
+	require.NoError(t, err)
+	assert.True(t, state.Done())
+	assert.False(t, state.Alive)
+	assert.Equal(t, "disk full", state.Error)
+}
+
+func TestJobProgressWatch(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	worker := NewJobProgress(repo.provider, "progress:", "export", JobProgressOptions{})
+	watcher := NewJobProgress(repo.provider, "progress:", "export", JobProgressOptions{WatchInterval: 100 * time.Millisecond})
+
+	updates, err := watcher.Watch(ctx)
+	require.NoError(t, err)
+	go func() {
+		worker.Start(ctx, "scan")
+		time.Sleep(50 * time.Millisecond)
+		worker.Update(ctx, "write", 50)
+		time.Sleep(50 * time.Millisecond)
+		worker.Finish(ctx, nil)
+	}()
+
+	var seen []JobProgressState
+	for state := range updates {
+		seen = append(seen, state)
+	}
+	require.NotEmpty(t, seen, "the channel closes once the job finishes")
+	last := seen[len(seen)-1]
+	assert.True(t, last.Done())
+	assert.Equal(t, 100.0, last.Percent)
+	assert.Empty(t, last.Error)
+	assert.NoError(t, ctx.Err())
+}
@@ -0,0 +1,173 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Field Projections
+// =====================================
+
+// projectScript returns a JSON object holding only the top-level members of the stored
+// document whose quoted names are in ARGV, or false when the key is missing. Members are
+// copied as raw text, so numbers keep their exact digits.
+var projectScript = readOnlyScript(redis.NewScript(`
+local s = redis.call('GET', KEYS[1])
+if not s then
+	return false
+end
+local wanted = {}
+for i = 1, #ARGV do
+	wanted[ARGV[i]] = true
+end
+
+local function skip_ws(i)
+	while true do
+		local c = string.byte(s, i)
+		if c ~= 32 and c ~= 9 and c ~= 10 and c ~= 13 then
+			return i
+		end
+		i = i + 1
+	end
+end
+local function skip_string(i)
+	i = i + 1
+	while true do
+		local c = string.byte(s, i)
+		if c == nil then
+			return i
+		elseif c == 92 then
+			i = i + 2
+		elseif c == 34 then
+			return i + 1
+		else
+			i = i + 1
+		end
+	end
+end
+local function skip_value(i)
+	local c = string.byte(s, i)
+	if c == 34 then
+		return skip_string(i)
+	end
+	if c == 123 or c == 91 then
+		local depth = 0
+		while true do
+			c = string.byte(s, i)
+			if c == nil then
+				return i
+			elseif c == 34 then
+				i = skip_string(i)
+			else
+				if c == 123 or c == 91 then
+					depth = depth + 1
+				elseif c == 125 or c == 93 then
+					depth = depth - 1
+				end
+				i = i + 1
+				if depth == 0 then
+					return i
+				end
+			end
+		end
+	end
+	while true do
+		c = string.byte(s, i)
+		if c == nil or c == 44 or c == 125 or c == 93 or c == 32 or c == 9 or c == 10 or c == 13 then
+			return i
+		end
+		i = i + 1
+	end
+end
+
+local i = skip_ws(1)
+if string.byte(s, i) ~= 123 then
+	return redis.error_reply('NOTOBJECT')
+end
+i = i + 1
+local out = {}
+while true do
+	i = skip_ws(i)
+	local c = string.byte(s, i)
+	if c == nil or c == 125 then
+		break
+	end
+	local key_start = i
+	i = skip_string(i)
+	local key = string.sub(s, key_start, i - 1)
+	i = skip_ws(i) + 1
+	i = skip_ws(i)
+	local value_start = i
+	i = skip_value(i)
+	if wanted[key] then
+		out[#out + 1] = key .. ':' .. string.sub(s, value_start, i - 1)
+	end
+	i = skip_ws(i)
+	if string.byte(s, i) == 44 then
+		i = i + 1
+	end
+end
+return '{' .. table.concat(out, ',') .. '}'
+`))
+
+// GetFields returns a T with only the named top-level fields populated. The fields are
+// picked out of the stored document by a Lua script, so only they cross the network, which
+// pays off for multi-KB documents read for a couple of fields. Fields are matched by json
+// name or Go field name; unknown names fail with ErrorTypeInvalidArgument. Returns
+// ErrorTypeNotFound for missing keys.
+// Example: user, err := repo.GetFields(ctx, "user:1", "name", "email")
+func (r *Repository[T]) GetFields(ctx context.Context, key string, fields ...string) (*T, error) {
+	names, err := projectionNames[T](fields)
+	if err != nil {
+		return nil, err
+	}
+	fullKey := r.buildKey(key)
+	if err := r.authorize(ctx, AccessRead, fullKey); err != nil {
+		return nil, err
+	}
+
+	data, err := projectScript.Run(ctx, r.client, []string{fullKey}, names...).Text()
+	if err == redis.Nil {
+		return nil, gpa.NewError(gpa.ErrorTypeNotFound, "key not found: "+key)
+	}
+	if err != nil {
+		if strings.TrimPrefix(err.Error(), "ERR ") == "NOTOBJECT" {
+			return nil, gpa.NewError(gpa.ErrorTypeSerialization, "value of "+key+" is not a JSON object")
+		}
+		return nil, convertRedisError(err)
+	}
+	return r.decode([]byte(data))
+}
+
+// projectionNames resolves requested fields to the quoted JSON member names of T
+func projectionNames[T any](fields []string) ([]interface{}, error) {
+	if len(fields) == 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "no fields requested")
+	}
+	meta := entityMetaFor(reflect.TypeOf((*T)(nil)))
+	names := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		name := field
+		if len(meta.fields) > 0 {
+			name = ""
+			for _, f := range meta.fields {
+				if f.jsonName == field || f.info.Name == field {
+					name = f.jsonName
+					break
+				}
+			}
+			if name == "" {
+				return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unknown field "+field)
+			}
+		}
+		quoted, _ := json.Marshal(name)
+		names = append(names, string(quoted))
+	}
+	return names, nil
+}
@@ -0,0 +1,55 @@
+package gparedis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type projectionTestDoc struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Big     int64             `json:"big"`
+	Body    string            `json:"body"`
+	Nested  map[string]string `json:"nested"`
+	Ignored string            `json:"-"`
+}
+
+func TestGetFields(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	repo := NewRepository[projectionTestDoc](base.provider, base.client, "proj:")
+	doc := &projectionTestDoc{
+		ID:     "1",
+		Name:   `quote " and brace }`,
+		Big:    9007199254740993,
+		Body:   strings.Repeat("x", 2048),
+		Nested: map[string]string{"a": "[{"},
+	}
+	require.NoError(t, repo.Set(ctx, "1", doc))
+
+	got, err := repo.GetFields(ctx, "1", "name", "Big", "nested")
+	require.NoError(t, err)
+	assert.Equal(t, &projectionTestDoc{Name: doc.Name, Big: doc.Big, Nested: doc.Nested}, got)
+
+	_, err = repo.GetFields(ctx, "1", "nope")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+	_, err = repo.GetFields(ctx, "missing", "name")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+
+	// Map entities take member names as-is
+	maps := NewRepository[map[string]interface{}](base.provider, base.client, "proj:")
+	m, err := maps.GetFields(ctx, "1", "id", "absent")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "1"}, *m)
+
+	require.NoError(t, base.client.Set(ctx, "proj:list", "[1,2]", 0).Err())
+	_, err = repo.GetFields(ctx, "list", "id")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeSerialization))
+}
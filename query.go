@@ -0,0 +1,285 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Query Option Translation
+// =====================================
+
+// ConsistencyLevel describes the read consistency requested for a query
+type ConsistencyLevel string
+
+const (
+	// ConsistencyEventual allows reads to be served from replicas or local caches
+	ConsistencyEventual ConsistencyLevel = "eventual"
+	// ConsistencyStrong requires reads to be served by the primary, bypassing replica reads
+	// and the near cache
+	ConsistencyStrong ConsistencyLevel = "strong"
+)
+
+// KeyPatternOption restricts a query to keys matching a glob pattern (relative to the prefix)
+type KeyPatternOption struct {
+	Pattern string
+}
+
+// Apply implements gpa.QueryOption. The pattern has no SQL equivalent and is read by the Redis adapter directly.
+func (o KeyPatternOption) Apply(query *gpa.Query) {}
+
+// ConsistencyOption selects the read consistency of a query
+type ConsistencyOption struct {
+	Level ConsistencyLevel
+}
+
+// Apply implements gpa.QueryOption. The level is read by the Redis adapter directly.
+func (o ConsistencyOption) Apply(query *gpa.Query) {}
+
+// strongReadsKey marks a context whose reads bypass replicas and the near cache
+type strongReadsKey struct{}
+
+// withStrongReads returns a context whose repository reads are served by the primary
+func withStrongReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strongReadsKey{}, true)
+}
+
+// strongReads reports whether reads made with ctx must be served by the primary
+func strongReads(ctx context.Context) bool {
+	strong, _ := ctx.Value(strongReadsKey{}).(bool)
+	return strong
+}
+
+// KeyPattern creates a query option matching keys against a glob pattern
+// Example: users, err := repo.Query(ctx, gparedis.KeyPattern("user:*"), gpa.Limit(10))
+func KeyPattern(pattern string) gpa.QueryOption {
+	return KeyPatternOption{Pattern: pattern}
+}
+
+// Consistency creates a query option selecting the read consistency level
+func Consistency(level ConsistencyLevel) gpa.QueryOption {
+	return ConsistencyOption{Level: level}
+}
+
+// supportedQueryOptions lists the options honored by the Redis adapter, for error messages
+var supportedQueryOptions = []string{"Limit", "Offset", "KeyPattern", "Consistency", "Where/And/Or with AllowFullScan"}
+
+// kvQuery is the key-value interpretation of a set of gpa.QueryOption values
+type kvQuery struct {
+	pattern     string
+	limit       int
+	offset      int
+	consistency ConsistencyLevel
+	conditions  []gpa.Condition
+}
+
+// translateQueryOptions maps generic query options onto key-value semantics.
+// All options the adapter can't honor are reported together in a single error.
+func translateQueryOptions(opts []gpa.QueryOption) (*kvQuery, error) {
+	q := &kvQuery{pattern: "*", limit: -1, consistency: ConsistencyEventual}
+	var unsupported []string
+
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case nil:
+			continue
+		case gpa.LimitOption:
+			q.limit = o.Count
+		case gpa.OffsetOption:
+			q.offset = o.Count
+		case KeyPatternOption:
+			if o.Pattern != "" {
+				q.pattern = o.Pattern
+			}
+		case ConsistencyOption:
+			if o.Level != ConsistencyEventual && o.Level != ConsistencyStrong {
+				return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unknown consistency level: "+string(o.Level))
+			}
+			q.consistency = o.Level
+		case gpa.ConditionOption, gpa.CompositeConditionOption:
+			var applied gpa.Query
+			o.Apply(&applied)
+			q.conditions = append(q.conditions, applied.Conditions...)
+		default:
+			unsupported = append(unsupported, queryOptionName(opt))
+		}
+	}
+
+	if len(unsupported) > 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeUnsupported, fmt.Sprintf(
+			"query options not supported by Redis key-value store: %s (supported: %s)",
+			strings.Join(unsupported, ", "), strings.Join(supportedQueryOptions, ", ")))
+	}
+	if q.limit < -1 || q.offset < 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "limit and offset must not be negative")
+	}
+	return q, nil
+}
+
+// queryOptionName returns a readable name for a query option
+func queryOptionName(opt gpa.QueryOption) string {
+	switch opt.(type) {
+	case gpa.ConditionOption:
+		return "Where"
+	case gpa.CompositeConditionOption:
+		return "And/Or"
+	case gpa.OrderOption:
+		return "OrderBy"
+	case gpa.FieldsOption:
+		return "Fields"
+	case gpa.JoinOption:
+		return "Join"
+	case gpa.GroupByOption:
+		return "GroupBy"
+	case gpa.HavingOption:
+		return "Having"
+	case gpa.DistinctOption:
+		return "Distinct"
+	case gpa.LockOption:
+		return "Lock"
+	case gpa.PreloadOption:
+		return "Preload"
+	case gpa.SubQueryOption:
+		return "SubQuery"
+	default:
+		return fmt.Sprintf("%T", opt)
+	}
+}
+
+// matchingKeys returns all keys (without prefix) matching the query pattern, sorted
+func (r *Repository[T]) matchingKeys(ctx context.Context, q *kvQuery) ([]string, error) {
+	var keys []string
+	err := r.scanEach(ctx, q.pattern, func(fullKeys []string) error {
+		for _, key := range fullKeys {
+			keys = append(keys, r.trimKey(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// page applies offset and limit to a sorted key list
+func (q *kvQuery) page(keys []string) []string {
+	if q.offset >= len(keys) {
+		return nil
+	}
+	keys = keys[q.offset:]
+	if q.limit >= 0 && q.limit < len(keys) {
+		keys = keys[:q.limit]
+	}
+	return keys
+}
+
+// runQuery loads the entities selected by q, ordered by key
+func (r *Repository[T]) runQuery(ctx context.Context, q *kvQuery) ([]*T, error) {
+	if q.consistency == ConsistencyStrong {
+		ctx = withStrongReads(ctx)
+	}
+	keys, err := r.candidateKeys(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(q.conditions) > 0 {
+		stopAfter := -1
+		if q.limit >= 0 {
+			stopAfter = q.offset + q.limit
+		}
+		_, entities, err := r.filterKeys(ctx, keys, q.conditions, stopAfter)
+		if err != nil {
+			return nil, err
+		}
+		if q.offset >= len(entities) {
+			return []*T{}, nil
+		}
+		entities = entities[q.offset:]
+		if q.limit >= 0 && q.limit < len(entities) {
+			entities = entities[:q.limit]
+		}
+		return entities, nil
+	}
+
+	keys = q.page(keys)
+	if len(keys) == 0 {
+		return []*T{}, nil
+	}
+
+	values, err := r.MGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]*T, 0, len(values))
+	for _, key := range keys {
+		if value, ok := values[key]; ok {
+			entities = append(entities, value)
+		}
+	}
+	return entities, nil
+}
+
+// requireFullScan rejects operations that need AllowFullScan when it isn't enabled
+func (r *Repository[T]) requireFullScan() error {
+	if !r.opts.allowFullScan {
+		return gpa.NewError(gpa.ErrorTypeUnsupported,
+			"condition filtering requires scanning every value; create the repository with AllowFullScan() to enable it")
+	}
+	return nil
+}
+
+// filterKeys loads keys in batches and keeps the entities matching all conditions. Every
+// batch is authorized for reading first. Values that can't be deserialized as T are skipped. Stops once stopAfter matches
+// were found (-1 for no limit). Returns matching keys and entities in key order.
+func (r *Repository[T]) filterKeys(ctx context.Context, keys []string, conds []gpa.Condition, stopAfter int) ([]string, []*T, error) {
+	var matchedKeys []string
+	var matched []*T
+
+	for start := 0; start < len(keys); start += scanBatchSize {
+		end := start + scanBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+
+		fullKeys := r.buildKeys(batch)
+		if err := r.authorize(ctx, AccessRead, fullKeys...); err != nil {
+			return nil, nil, err
+		}
+		// Batches are split by slot in cluster mode and read where MGet reads
+		values, err := r.mgetValues(ctx, fullKeys)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				continue
+			}
+			entity, err := r.decode([]byte(data))
+			if err != nil {
+				continue
+			}
+			ok, err = matchesAll(entity, conds)
+			if err != nil {
+				return nil, nil, err
+			}
+			if ok {
+				matchedKeys = append(matchedKeys, batch[i])
+				matched = append(matched, entity)
+				if stopAfter >= 0 && len(matched) >= stopAfter {
+					return matchedKeys, matched, nil
+				}
+			}
+		}
+	}
+	return matchedKeys, matched, nil
+}
@@ -0,0 +1,181 @@
+package gparedis
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Index-Backed Query Planning
+// =====================================
+
+// QueryPlan describes how Query, QueryOne and Count evaluate conditions
+type QueryPlan struct {
+	// Index is the secondary index supplying the candidate keys; empty for a full scan
+	Index string
+	// Candidates is the number of keys in the index's tag set, or -1 for a full scan
+	Candidates int64
+}
+
+// ExplainQuery returns the plan Query would use for opts. A tag or composite index is used
+// when the conditions fix every one of its fields with top-level (or AND-nested) equalities;
+// among several, the one whose tag set holds the fewest keys wins. All conditions are still
+// evaluated on the candidates, so the index only narrows the scan. Queries with a KeyPattern
+// and queries no index covers scan every key and require AllowFullScan.
+// Example: plan, err := users.ExplainQuery(ctx, gpa.Where("tenant", gpa.OpEqual, "acme"), gpa.Where("status", gpa.OpEqual, "open"))
+func (r *Repository[T]) ExplainQuery(ctx context.Context, opts ...gpa.QueryOption) (QueryPlan, error) {
+	q, err := translateQueryOptions(opts)
+	if err != nil {
+		return QueryPlan{}, err
+	}
+	plan, _, err := r.planQuery(ctx, q)
+	return plan, err
+}
+
+// planQuery picks the most selective index covering the equalities of q and returns its tag set
+func (r *Repository[T]) planQuery(ctx context.Context, q *kvQuery) (QueryPlan, string, error) {
+	fullScan := QueryPlan{Candidates: -1}
+	if len(q.conditions) == 0 || q.pattern != "*" {
+		return fullScan, "", nil
+	}
+	equalities := make(map[string]interface{})
+	collectEqualities(q.conditions, equalities)
+	if len(equalities) == 0 {
+		return fullScan, "", nil
+	}
+
+	type candidate struct {
+		def  indexDef
+		set  string
+		size *redis.IntCmd
+	}
+	var candidates []*candidate
+	for _, def := range r.indexes() {
+		if def.ranged || def.text {
+			continue
+		}
+		values := make([]interface{}, 0, len(def.fields))
+		for _, f := range def.fields {
+			value, ok := equalityFor(equalities, f)
+			if !ok {
+				break
+			}
+			values = append(values, value)
+		}
+		if len(values) != len(def.fields) {
+			continue
+		}
+		set, ok, err := r.lookupTagSet(def.name, values)
+		if err != nil || !ok {
+			continue
+		}
+		candidates = append(candidates, &candidate{def: def, set: set})
+	}
+	if len(candidates) == 0 {
+		return fullScan, "", nil
+	}
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, c := range candidates {
+			c.size = pipe.SCard(ctx, c.set)
+		}
+		return nil
+	})
+	if err != nil {
+		return QueryPlan{}, "", convertRedisError(err)
+	}
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		// Ties go to the index fixing more fields, then to declaration order
+		if c.size.Val() < best.size.Val() || (c.size.Val() == best.size.Val() && len(c.def.fields) > len(best.def.fields)) {
+			best = c
+		}
+	}
+	return QueryPlan{Index: best.def.name, Candidates: best.size.Val()}, best.set, nil
+}
+
+// collectEqualities records the field = value conditions that every match must satisfy,
+// keyed by condition field name. OR and NOT groups and dotted paths are skipped.
+func collectEqualities(conds []gpa.Condition, equalities map[string]interface{}) {
+	for _, cond := range conds {
+		switch c := cond.(type) {
+		case gpa.CompositeCondition:
+			if c.Logic == gpa.LogicAnd {
+				collectEqualities(c.Conditions, equalities)
+			}
+			continue
+		case *gpa.CompositeCondition:
+			if c.Logic == gpa.LogicAnd {
+				collectEqualities(c.Conditions, equalities)
+			}
+			continue
+		case gpa.SubQueryCondition, *gpa.SubQueryCondition:
+			continue
+		}
+		if cond.Operator() != gpa.OpEqual || strings.Contains(cond.Field(), ".") || !indexableOperand(cond.Value()) {
+			continue
+		}
+		if _, seen := equalities[cond.Field()]; !seen {
+			equalities[cond.Field()] = cond.Value()
+		}
+	}
+}
+
+// indexableOperand reports whether an equality operand renders like the values it equals
+func indexableOperand(value interface{}) bool {
+	if _, ok := value.(time.Time); ok {
+		return true
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// equalityFor returns the equality fixing field f, resolving names like conditions do:
+// json name first, then case-insensitive Go name
+func equalityFor(equalities map[string]interface{}, f entityField) (interface{}, bool) {
+	if value, ok := equalities[f.jsonName]; ok {
+		return value, true
+	}
+	for name, value := range equalities {
+		if strings.EqualFold(name, f.info.Name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// candidateKeys returns the sorted keys whose values may satisfy q: the members of the
+// planned index's tag set, or every key matching the pattern when no index applies
+func (r *Repository[T]) candidateKeys(ctx context.Context, q *kvQuery) ([]string, error) {
+	if len(q.conditions) == 0 {
+		return r.matchingKeys(ctx, q)
+	}
+	plan, set, err := r.planQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Index == "" {
+		if err := r.requireFullScan(); err != nil {
+			return nil, err
+		}
+		return r.matchingKeys(ctx, q)
+	}
+	keys, err := r.client.SMembers(ctx, set).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
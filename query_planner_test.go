@@ -0,0 +1,94 @@
+package gparedis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type plannedTicket struct {
+	ID       string `json:"id"`
+	Tenant   string `json:"tenant" gpaindex:"tenant;tenant_status"`
+	Status   string `json:"status" gpaindex:"status;tenant_status"`
+	Priority int    `json:"priority"`
+}
+
+func TestCompositeIndexDeclarations(t *testing.T) {
+	meta := entityMetaFor(reflect.TypeOf(plannedTicket{}))
+	defs := indexDefs(meta)
+	require.Len(t, defs, 3)
+	assert.Equal(t, "tenant", defs[0].name)
+	assert.Equal(t, "tenant_status", defs[1].name)
+	require.Len(t, defs[1].fields, 2)
+	assert.Equal(t, "Tenant", defs[1].fields[0].info.Name)
+	assert.Equal(t, "Status", defs[1].fields[1].info.Name)
+	assert.Equal(t, "status", defs[2].name)
+	assert.Equal(t, gpa.IndexTypeComposite, meta.indexes[2].Type)
+
+	equalities := make(map[string]interface{})
+	collectEqualities([]gpa.Condition{
+		gpa.BasicCondition{FieldName: "Tenant", Op: gpa.OpEqual, Val: "acme"},
+		gpa.CompositeCondition{Logic: gpa.LogicAnd, Conditions: []gpa.Condition{
+			gpa.BasicCondition{FieldName: "status", Op: gpa.OpEqual, Val: "open"},
+		}},
+		gpa.CompositeCondition{Logic: gpa.LogicOr, Conditions: []gpa.Condition{
+			gpa.BasicCondition{FieldName: "priority", Op: gpa.OpEqual, Val: 1},
+		}},
+		gpa.BasicCondition{FieldName: "priority", Op: gpa.OpGreaterThan, Val: 2},
+	}, equalities)
+	assert.Equal(t, map[string]interface{}{"Tenant": "acme", "status": "open"}, equalities)
+}
+
+func TestQueryPlannerChoosesSelectiveIndex(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[plannedTicket](base.provider, base.client, "ticket:")
+	require.NoError(t, repo.MSet(ctx, map[string]*plannedTicket{
+		"1": {ID: "1", Tenant: "acme", Status: "open", Priority: 1},
+		"2": {ID: "2", Tenant: "acme", Status: "open", Priority: 3},
+		"3": {ID: "3", Tenant: "acme", Status: "closed", Priority: 3},
+		"4": {ID: "4", Tenant: "globex", Status: "open", Priority: 2},
+		"5": {ID: "5", Tenant: "globex", Status: "open", Priority: 2},
+		"6": {ID: "6", Tenant: "globex", Status: "open", Priority: 2},
+	}))
+
+	open, err := repo.FindByIndex(ctx, "tenant_status", "acme", "open")
+	require.NoError(t, err)
+	assert.Len(t, open, 2)
+
+	plan, err := repo.ExplainQuery(ctx, gpa.Where("tenant", gpa.OpEqual, "acme"), gpa.Where("status", gpa.OpEqual, "open"))
+	require.NoError(t, err)
+	assert.Equal(t, QueryPlan{Index: "tenant_status", Candidates: 2}, plan)
+
+	// The closed set is smaller than any set covering the tenant
+	plan, err = repo.ExplainQuery(ctx, gpa.Where("tenant", gpa.OpEqual, "globex"), gpa.Where("status", gpa.OpEqual, "closed"))
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_status", plan.Index)
+	assert.Equal(t, int64(0), plan.Candidates)
+	plan, err = repo.ExplainQuery(ctx, gpa.Where("status", gpa.OpEqual, "closed"), gpa.Where("priority", gpa.OpEqual, 3))
+	require.NoError(t, err)
+	assert.Equal(t, QueryPlan{Index: "status", Candidates: 1}, plan)
+
+	// Indexed queries don't need AllowFullScan; every condition still applies
+	tickets, err := repo.Query(ctx, gpa.Where("tenant", gpa.OpEqual, "acme"), gpa.Where("priority", gpa.OpGreaterThan, 2))
+	require.NoError(t, err)
+	require.Len(t, tickets, 2)
+	assert.Equal(t, "2", tickets[0].ID)
+	assert.Equal(t, "3", tickets[1].ID)
+	count, err := repo.Count(ctx, gpa.Where("tenant", gpa.OpEqual, "globex"), gpa.Where("status", gpa.OpEqual, "open"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	// Conditions no index covers fall back to a full scan
+	plan, err = repo.ExplainQuery(ctx, gpa.Where("priority", gpa.OpEqual, 2))
+	require.NoError(t, err)
+	assert.Equal(t, QueryPlan{Candidates: -1}, plan)
+	_, err = repo.Query(ctx, gpa.Where("priority", gpa.OpEqual, 2))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeUnsupported))
+}
@@ -0,0 +1,100 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateQueryOptions(t *testing.T) {
+	q, err := translateQueryOptions([]gpa.QueryOption{
+		gpa.Limit(10),
+		gpa.Offset(5),
+		KeyPattern("user:*"),
+		Consistency(ConsistencyStrong),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user:*", q.pattern)
+	assert.Equal(t, 10, q.limit)
+	assert.Equal(t, 5, q.offset)
+	assert.Equal(t, ConsistencyStrong, q.consistency)
+
+	_, err = translateQueryOptions([]gpa.QueryOption{Consistency("linearizable")})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+
+	q, err = translateQueryOptions(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "*", q.pattern)
+	assert.Equal(t, -1, q.limit)
+
+	_, err = translateQueryOptions([]gpa.QueryOption{
+		gpa.Distinct(),
+		gpa.Limit(1),
+		gpa.OrderBy("name", gpa.OrderAsc),
+	})
+	require.Error(t, err)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeUnsupported))
+	assert.Contains(t, err.Error(), "Distinct, OrderBy")
+	assert.Contains(t, err.Error(), "supported: Limit, Offset, KeyPattern, Consistency")
+
+	q, err = translateQueryOptions([]gpa.QueryOption{
+		gpa.Where("age", gpa.OpGreaterThan, 18),
+		gpa.Or(gpa.WhereCondition("name", gpa.OpEqual, "a"), gpa.WhereCondition("name", gpa.OpEqual, "b")),
+	})
+	require.NoError(t, err)
+	assert.Len(t, q.conditions, 2)
+
+	_, err = translateQueryOptions([]gpa.QueryOption{gpa.Offset(-1)})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestKVQueryPage(t *testing.T) {
+	keys := []string{"a", "b", "c", "d"}
+	assert.Equal(t, []string{"b", "c"}, (&kvQuery{limit: 2, offset: 1}).page(keys))
+	assert.Equal(t, keys, (&kvQuery{limit: -1}).page(keys))
+	assert.Empty(t, (&kvQuery{limit: -1, offset: 10}).page(keys))
+}
+
+func TestRepositoryQuery(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		require.NoError(t, repo.Set(ctx, key, &TestValue{ID: key, Age: i}))
+	}
+	require.NoError(t, repo.Set(ctx, "product:1", &TestValue{ID: "product:1"}))
+
+	users, err := repo.Query(ctx, KeyPattern("user:*"), gpa.Offset(1), gpa.Limit(2))
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "user:1", users[0].ID)
+	assert.Equal(t, "user:2", users[1].ID)
+
+	all, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 6)
+
+	count, err := repo.Count(ctx, KeyPattern("user:*"), gpa.Limit(1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+
+	exists, err := repo.Exists(ctx, KeyPattern("order:*"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	first, err := repo.QueryOne(ctx, KeyPattern("user:*"))
+	require.NoError(t, err)
+	assert.Equal(t, "user:0", first.ID)
+
+	_, err = repo.QueryOne(ctx, KeyPattern("order:*"))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+
+	_, err = repo.Query(ctx, gpa.OrderBy("age", gpa.OrderDesc))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeUnsupported))
+}
@@ -0,0 +1,309 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Queue Metrics
+// =====================================
+
+// Queue kinds reported in QueueStats.Kind
+const (
+	queueKindPriority  = "priority_queue"
+	queueKindStream    = "stream"
+	queueKindScheduler = "scheduler"
+	queueKindDebouncer = "debouncer"
+)
+
+// queueRateWindow is the number of one-second buckets processing rates are averaged over
+const queueRateWindow = 60
+
+// queueStatsTimeout bounds the Redis calls made when queue stats are exported
+const queueStatsTimeout = 2 * time.Second
+
+// QueueStats describes the backlog of a queue-like subsystem, for autoscaling decisions
+type QueueStats struct {
+	// Name is the queue's key (the key prefix for schedulers)
+	Name string
+	// Kind is "priority_queue", "stream", "scheduler" or "debouncer"
+	Kind string
+	// Group is the consumer group, for streams
+	Group string
+	// Depth is the number of items waiting: queued items, undelivered plus pending stream
+	// entries, due jobs or open debounce windows
+	Depth int64
+	// OldestAge is how long the oldest waiting item has waited (0 when nothing waits)
+	OldestAge time.Duration
+	// Processed counts the items this process handled while metrics were enabled
+	Processed uint64
+	// Rate is this process's processing rate per second over the last minute
+	Rate float64
+	// DeadLetters is the size of the dead-letter queue, where there is one
+	DeadLetters int64
+}
+
+// QueueStatsSource is implemented by every subsystem reporting QueueStats
+type QueueStatsSource interface {
+	QueueStats(ctx context.Context) (QueueStats, error)
+}
+
+// queueCounter counts processed items in one-second buckets
+type queueCounter struct {
+	total   uint64
+	buckets [queueRateWindow]uint64
+	seconds [queueRateWindow]int64
+}
+
+// add counts n items processed at now
+func (c *queueCounter) add(now time.Time, n uint64) {
+	s := now.Unix()
+	i := s % queueRateWindow
+	if c.seconds[i] != s {
+		c.seconds[i], c.buckets[i] = s, 0
+	}
+	c.buckets[i] += n
+	c.total += n
+}
+
+// rate returns the items processed per second over the window ending at now
+func (c *queueCounter) rate(now time.Time) float64 {
+	s := now.Unix()
+	var sum uint64
+	for i := range c.buckets {
+		if s-c.seconds[i] < queueRateWindow {
+			sum += c.buckets[i]
+		}
+	}
+	return float64(sum) / queueRateWindow
+}
+
+// queueID identifies a queue's counter
+func queueID(kind, name, group string) string {
+	return kind + "\x00" + name + "\x00" + group
+}
+
+// recordProcessed counts n items handled by a queue, when metrics are enabled
+func (p *Provider) recordProcessed(kind, name, group string, n int) {
+	if p == nil || n <= 0 {
+		return
+	}
+	if m := p.metrics.Load(); m != nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		c, ok := m.queues[queueID(kind, name, group)]
+		if !ok {
+			c = &queueCounter{}
+			m.queues[queueID(kind, name, group)] = c
+		}
+		c.add(time.Now(), uint64(n))
+	}
+}
+
+// fillProcessed adds this process's processing counters to stats
+func (p *Provider) fillProcessed(stats *QueueStats) {
+	if p == nil {
+		return
+	}
+	if m := p.metrics.Load(); m != nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if c, ok := m.queues[queueID(stats.Kind, stats.Name, stats.Group)]; ok {
+			stats.Processed, stats.Rate = c.total, c.rate(time.Now())
+		}
+	}
+}
+
+// TrackQueue includes a queue's stats in QueueStats and the Prometheus output
+// Example: provider.Metrics().TrackQueue(jobs)
+func (m *Metrics) TrackQueue(source QueueStatsSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trackedQueues = append(m.trackedQueues, source)
+}
+
+// QueueStats collects the stats of every tracked queue. Queues whose stats can't be read
+// are left out and their first error is returned alongside the rest.
+func (m *Metrics) QueueStats(ctx context.Context) ([]QueueStats, error) {
+	m.mu.Lock()
+	sources := append([]QueueStatsSource(nil), m.trackedQueues...)
+	m.mu.Unlock()
+
+	ctx = withoutMetrics(ctx)
+	all := make([]QueueStats, 0, len(sources))
+	var firstErr error
+	for _, source := range sources {
+		stats, err := source.QueueStats(ctx)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		all = append(all, stats)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Name != all[j].Name {
+			return all[i].Name < all[j].Name
+		}
+		return all[i].Group < all[j].Group
+	})
+	return all, firstErr
+}
+
+// writeQueuePrometheus appends the tracked queues' stats to a Prometheus exposition
+func (m *Metrics) writeQueuePrometheus(b *strings.Builder) {
+	ctx, cancel := context.WithTimeout(context.Background(), queueStatsTimeout)
+	defer cancel()
+	all, _ := m.QueueStats(ctx)
+
+	gauges := []struct {
+		name, help, kind string
+		value            func(QueueStats) string
+	}{
+		{"gparedis_queue_depth", "Items waiting in the queue.", "gauge",
+			func(s QueueStats) string { return fmt.Sprint(s.Depth) }},
+		{"gparedis_queue_oldest_age_seconds", "Age of the oldest waiting item.", "gauge",
+			func(s QueueStats) string { return fmt.Sprint(s.OldestAge.Seconds()) }},
+		{"gparedis_queue_processed_total", "Items processed by this process.", "counter",
+			func(s QueueStats) string { return fmt.Sprint(s.Processed) }},
+		{"gparedis_queue_processing_rate", "Items processed per second over the last minute.", "gauge",
+			func(s QueueStats) string { return fmt.Sprint(s.Rate) }},
+		{"gparedis_queue_dead_letters", "Items in the dead-letter queue.", "gauge",
+			func(s QueueStats) string { return fmt.Sprint(s.DeadLetters) }},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", g.name, g.help, g.name, g.kind)
+		for _, s := range all {
+			fmt.Fprintf(b, "%s{queue=\"%s\",kind=\"%s\",group=\"%s\"} %s\n",
+				g.name, promLabel(s.Name), s.Kind, promLabel(s.Group), g.value(s))
+		}
+	}
+}
+
+// oldestAge returns how long ago the unix millisecond timestamp ms was, or 0 in the future
+func oldestAge(ms float64) time.Duration {
+	if age := time.Since(time.UnixMilli(int64(ms))); age > 0 {
+		return age
+	}
+	return 0
+}
+
+// QueueStats reports the queue's depth and the age of its oldest item
+func (q *PriorityQueue[T]) QueueStats(ctx context.Context) (QueueStats, error) {
+	stats := QueueStats{Name: q.key, Kind: queueKindPriority}
+	var depth *redis.IntCmd
+	var oldest *redis.ZSliceCmd
+	_, err := q.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		depth = pipe.ZCard(ctx, q.key)
+		oldest = pipe.ZRangeWithScores(ctx, q.enqueuedKey(), 0, 0)
+		return nil
+	})
+	if err != nil {
+		return stats, convertRedisError(err)
+	}
+	stats.Depth = depth.Val()
+	if z := oldest.Val(); len(z) > 0 && stats.Depth > 0 {
+		stats.OldestAge = oldestAge(z[0].Score)
+	}
+	q.provider.fillProcessed(&stats)
+	return stats, nil
+}
+
+// QueueStats reports the backlog of group: entries not yet delivered plus delivered but
+// unacknowledged ones, the age of the oldest of them, and the dead-letter stream's length
+func (s *Stream[T]) QueueStats(ctx context.Context, group string) (QueueStats, error) {
+	stats := QueueStats{Name: s.key, Kind: queueKindStream, Group: group}
+	info, err := s.Group(ctx, group)
+	if err != nil {
+		return stats, err
+	}
+	stats.Depth = info.Lag + info.Pending
+
+	oldestID := ""
+	if info.Pending > 0 {
+		summary, err := s.client.XPending(ctx, s.key, group).Result()
+		if err != nil {
+			return stats, convertRedisError(err)
+		}
+		oldestID = summary.Lower
+	} else if info.Lag > 0 {
+		next, err := s.client.XRangeN(ctx, s.key, "("+info.LastDeliveredID, "+", 1).Result()
+		if err != nil {
+			return stats, convertRedisError(err)
+		}
+		if len(next) > 0 {
+			oldestID = next[0].ID
+		}
+	}
+	if oldestID != "" {
+		stats.OldestAge = oldestAge(float64(streamIDTime(oldestID).UnixMilli()))
+	}
+	if stats.DeadLetters, err = s.client.XLen(ctx, s.deadLetterKey(group)).Result(); err != nil {
+		return stats, convertRedisError(err)
+	}
+	s.provider.fillProcessed(&stats)
+	return stats, nil
+}
+
+// GroupQueue adapts the stats of one consumer group to QueueStatsSource, for TrackQueue
+func (s *Stream[T]) GroupQueue(group string) QueueStatsSource {
+	return streamGroupQueue[T]{stream: s, group: group}
+}
+
+// streamGroupQueue is a consumer group as a QueueStatsSource
+type streamGroupQueue[T any] struct {
+	stream *Stream[T]
+	group  string
+}
+
+// QueueStats reports the group's stats
+func (g streamGroupQueue[T]) QueueStats(ctx context.Context) (QueueStats, error) {
+	return g.stream.QueueStats(ctx, g.group)
+}
+
+// QueueStats reports the jobs that are due but haven't fired and how overdue the oldest is
+func (s *Scheduler) QueueStats(ctx context.Context) (QueueStats, error) {
+	return zsetDueStats(ctx, s.provider, s.client, QueueStats{Name: s.prefix, Kind: queueKindScheduler}, s.scheduleKey(), true)
+}
+
+// QueueStats reports the open windows and how overdue the oldest closed one is
+func (d *Debouncer) QueueStats(ctx context.Context) (QueueStats, error) {
+	return zsetDueStats(ctx, d.provider, d.client, QueueStats{Name: d.key, Kind: queueKindDebouncer}, d.key, false)
+}
+
+// zsetDueStats fills stats for a sorted set scored by due time. Depth counts the due
+// members when dueOnly is set and all members otherwise; OldestAge is how overdue the
+// earliest member is.
+func zsetDueStats(ctx context.Context, p *Provider, client *redis.Client, stats QueueStats, key string, dueOnly bool) (QueueStats, error) {
+	if client == nil {
+		return stats, gpa.NewError(gpa.ErrorTypeConnection, "no Redis client")
+	}
+	var depth *redis.IntCmd
+	var earliest *redis.ZSliceCmd
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if dueOnly {
+			depth = pipe.ZCount(ctx, key, "-inf", fmt.Sprint(time.Now().UnixMilli()))
+		} else {
+			depth = pipe.ZCard(ctx, key)
+		}
+		earliest = pipe.ZRangeWithScores(ctx, key, 0, 0)
+		return nil
+	})
+	if err != nil {
+		return stats, convertRedisError(err)
+	}
+	stats.Depth = depth.Val()
+	if z := earliest.Val(); len(z) > 0 {
+		stats.OldestAge = oldestAge(z[0].Score)
+	}
+	p.fillProcessed(&stats)
+	return stats, nil
+}
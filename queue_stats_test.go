@@ -0,0 +1,122 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityQueueStats(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	metrics := repo.provider.EnableMetrics(MetricsOptions{})
+	defer repo.provider.DisableMetrics()
+
+	jobs := NewPriorityQueue[TestValue](repo.provider, "qstats:jobs")
+	for i := 0; i < 3; i++ {
+		_, err := jobs.Push(ctx, &TestValue{Age: i}, float64(i))
+		require.NoError(t, err)
+	}
+	// Backdate the first item
+	first, err := jobs.Peek(ctx, 1, false)
+	require.NoError(t, err)
+	repo.client.ZAdd(ctx, "qstats:jobs:enqueued", &redis.Z{Score: float64(time.Now().Add(-time.Minute).UnixMilli()), Member: first[0].ID})
+
+	item, err := jobs.PopHighest(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, item)
+
+	stats, err := jobs.QueueStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "priority_queue", stats.Kind)
+	assert.Equal(t, int64(2), stats.Depth)
+	assert.InDelta(t, time.Minute.Seconds(), stats.OldestAge.Seconds(), 5)
+	assert.Equal(t, uint64(1), stats.Processed)
+	assert.InDelta(t, 1.0/60, stats.Rate, 0.001)
+
+	metrics.TrackQueue(jobs)
+	var out bytes.Buffer
+	require.NoError(t, metrics.WritePrometheus(&out))
+	assert.Contains(t, out.String(), `gparedis_queue_depth{queue="qstats:jobs",kind="priority_queue",group=""} 2`)
+	assert.Contains(t, out.String(), `gparedis_queue_processed_total{queue="qstats:jobs",kind="priority_queue",group=""} 1`)
+
+	metrics.Reset()
+	stats, err = jobs.QueueStats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Processed)
+	all, err := repo.provider.EnableMetrics(MetricsOptions{}).QueueStats(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 1, "tracked queues survive re-enabling metrics")
+}
+
+func TestStreamQueueStats(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo.provider.EnableMetrics(MetricsOptions{})
+	defer repo.provider.DisableMetrics()
+
+	stream := NewStream[streamTestEvent](repo.provider, "qstats:events", StreamOptions{})
+	require.NoError(t, stream.CreateGroup(ctx, "billing", "0"))
+	for _, status := range []string{"created", "paid", "shipped", "lost"} {
+		_, err := stream.Add(ctx, &streamTestEvent{OrderID: "o1", Status: status})
+		require.NoError(t, err)
+	}
+	msgs, err := stream.ReadGroup(ctx, "billing", "worker-1", 3, -1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	require.NoError(t, stream.Ack(ctx, "billing", msgs[0].ID))
+	moved, err := stream.DeadLetter(ctx, "billing", msgs[1].ID, msgs[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), moved, "only pending messages are dead-lettered")
+
+	stats, err := stream.QueueStats(ctx, "billing")
+	require.NoError(t, err)
+	assert.Equal(t, "billing", stats.Group)
+	assert.Equal(t, int64(2), stats.Depth, "one pending and one undelivered entry")
+	assert.Equal(t, int64(1), stats.DeadLetters)
+	assert.Equal(t, uint64(1), stats.Processed)
+
+	dead, err := stream.DeadLetters("billing").Range(ctx, "-", "+", 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "paid", dead[0].Value.Status)
+
+	repo.provider.Metrics().TrackQueue(stream.GroupQueue("billing"))
+	all, err := repo.provider.Metrics().QueueStats(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, int64(2), all[0].Depth)
+}
+
+func TestSchedulerAndDebouncerQueueStats(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	cron := NewScheduler(repo.provider, "qstats:cron:", SchedulerOptions{})
+	require.NoError(t, cron.Register(ctx, "sweep", "* * * * *", func(context.Context, JobRun) error { return nil }, JobOptions{}))
+	stats, err := cron.QueueStats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Depth)
+	repo.client.ZAdd(ctx, "qstats:cron:schedule", &redis.Z{Score: float64(time.Now().Add(-30 * time.Second).UnixMilli()), Member: "sweep"})
+	stats, err = cron.QueueStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Depth)
+	assert.InDelta(t, 30, stats.OldestAge.Seconds(), 5)
+
+	debouncer := NewDebouncer(repo.provider, "qstats:debounce", DebouncerOptions{Window: time.Minute})
+	require.NoError(t, debouncer.Trigger(ctx, "a"))
+	stats, err = debouncer.QueueStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Depth)
+	assert.Zero(t, stats.OldestAge, "the window is still open")
+}
@@ -0,0 +1,217 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Repository Quotas
+// =====================================
+
+// QuotaAction is what a write does when the repository is over its quota
+type QuotaAction int
+
+const (
+	// QuotaReject fails writes with ErrorTypeQuotaExceeded
+	QuotaReject QuotaAction = iota
+	// QuotaAlert lets writes through and only calls OnExceeded
+	QuotaAlert
+)
+
+// Quota limits the keyspace a repository may occupy, protecting shared clusters from a
+// runaway tenant. Usage is measured periodically rather than on every write, so the limits
+// are soft: a burst of writes can overshoot them until the next measurement.
+type Quota struct {
+	// MaxKeys caps the number of keys under the prefix (0 disables)
+	MaxKeys int64
+	// MaxBytes caps the estimated memory used by the keys under the prefix (0 disables)
+	MaxBytes int64
+	// Action chooses between rejecting writes and only alerting (default QuotaReject)
+	Action QuotaAction
+	// OnExceeded is called after each measurement that finds the repository over quota
+	OnExceeded func(usage QuotaUsage)
+	// CheckInterval is how long a measurement is trusted before writes trigger a new one (default 30s)
+	CheckInterval time.Duration
+	// SampleSize is how many keys have their memory measured; MaxBytes is checked against
+	// the sample average times the key count (default 100)
+	SampleSize int
+}
+
+// QuotaUsage is a measurement of the keyspace a repository occupies
+type QuotaUsage struct {
+	Prefix string
+	Keys   int64
+	// Bytes estimates memory usage from a sample of MEMORY USAGE readings
+	Bytes int64
+	// MeasuredAt is when the measurement started
+	MeasuredAt time.Time
+	// Exceeded reports whether Keys or Bytes is over the quota
+	Exceeded bool
+}
+
+// WithQuota enforces quota on the repository's writes
+// Example: tenants := gparedis.NewRepository[Doc](provider, client, "tenant:42:", gparedis.WithQuota(gparedis.Quota{MaxKeys: 100000, MaxBytes: 512 << 20}))
+func WithQuota(quota Quota) RepositoryOption {
+	return func(o *repositoryOptions) {
+		if quota.CheckInterval <= 0 {
+			quota.CheckInterval = 30 * time.Second
+		}
+		if quota.SampleSize <= 0 {
+			quota.SampleSize = 100
+		}
+		o.quota = &quota
+	}
+}
+
+// quotaState caches the last usage measurement of a repository
+type quotaState struct {
+	mu        sync.Mutex
+	usage     QuotaUsage
+	measured  bool
+	measuring bool
+}
+
+// QuotaUsage measures the repository's current usage and refreshes the measurement its
+// quota checks rely on
+func (r *Repository[T]) QuotaUsage(ctx context.Context) (QuotaUsage, error) {
+	if err := r.authorize(ctx, AccessScan, r.buildKey("*")); err != nil {
+		return QuotaUsage{}, err
+	}
+	return r.refreshQuota(ctx)
+}
+
+// refreshQuota measures usage and stores the measurement unless a newer one landed meanwhile
+func (r *Repository[T]) refreshQuota(ctx context.Context) (QuotaUsage, error) {
+	usage, err := r.measureQuota(ctx)
+	if err != nil {
+		return usage, err
+	}
+	if r.quota != nil {
+		r.quota.mu.Lock()
+		if !r.quota.measured || !usage.MeasuredAt.Before(r.quota.usage.MeasuredAt) {
+			r.quota.usage, r.quota.measured = usage, true
+		}
+		r.quota.mu.Unlock()
+		r.alertQuota(usage)
+	}
+	return usage, nil
+}
+
+// checkQuota fails writes that add data while the repository is over a rejecting quota.
+// Missing and stale measurements are taken in the background, bypassing the authorizer, so
+// writes are never blocked on a SCAN and the first writes pass before usage is known. They
+// run detached from the triggering write's context, bounded by CheckInterval.
+func (r *Repository[T]) checkQuota(ctx context.Context) error {
+	if r.quota == nil {
+		return nil
+	}
+	q := r.quota
+	q.mu.Lock()
+	if (!q.measured || time.Since(q.usage.MeasuredAt) >= r.opts.quota.CheckInterval) && !q.measuring {
+		q.measuring = true
+		go func() {
+			// Not derived from ctx: the measurement serves every later write, not this caller's
+			// deadline, trace or principal
+			ctx, cancel := context.WithTimeout(context.Background(), r.opts.quota.CheckInterval)
+			defer cancel()
+			// A failed measurement keeps the previous one until the next write retries
+			_, _ = r.refreshQuota(ctx)
+			q.mu.Lock()
+			q.measuring = false
+			q.mu.Unlock()
+		}()
+	}
+	usage, measured := q.usage, q.measured
+	q.mu.Unlock()
+
+	if !measured || !usage.Exceeded || r.opts.quota.Action == QuotaAlert {
+		return nil
+	}
+	return gpa.NewError(ErrorTypeQuotaExceeded, fmt.Sprintf("quota exceeded for %q: %d keys, ~%d bytes", r.keyPrefix, usage.Keys, usage.Bytes))
+}
+
+// alertQuota calls OnExceeded when usage is over quota
+func (r *Repository[T]) alertQuota(usage QuotaUsage) {
+	if usage.Exceeded && r.opts.quota.OnExceeded != nil {
+		r.opts.quota.OnExceeded(usage)
+	}
+}
+
+// measureQuota counts the keys under the prefix and estimates their memory from a sample
+func (r *Repository[T]) measureQuota(ctx context.Context) (QuotaUsage, error) {
+	sampleSize := 100
+	if r.opts.quota != nil {
+		sampleSize = r.opts.quota.SampleSize
+	}
+	// Writes landing during the scan count toward the next measurement
+	usage := QuotaUsage{Prefix: r.keyPrefix, MeasuredAt: time.Now()}
+	sample := make([]string, 0, sampleSize)
+	err := r.scanOwned(ctx, "*", func(fullKeys []string) error {
+		for _, key := range fullKeys {
+			usage.Keys++
+			if len(sample) < sampleSize {
+				sample = append(sample, key)
+			} else if j := rand.Int63n(usage.Keys); j < int64(sampleSize) {
+				sample[j] = key
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return usage, err
+	}
+
+	if len(sample) > 0 {
+		sizes := make([]*redis.IntCmd, len(sample))
+		_, err = r.reader(ctx).Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range sample {
+				sizes[i] = pipe.MemoryUsage(ctx, key)
+			}
+			return nil
+		})
+		if err != nil && err != redis.Nil {
+			// Servers without MEMORY USAGE fall back to the value lengths
+			sizes, err = r.valueSizes(ctx, sample)
+			if err != nil {
+				return usage, err
+			}
+		}
+		var total int64
+		for _, size := range sizes {
+			total += size.Val()
+		}
+		usage.Bytes = total * usage.Keys / int64(len(sample))
+	}
+
+	if q := r.opts.quota; q != nil {
+		usage.Exceeded = (q.MaxKeys > 0 && usage.Keys > q.MaxKeys) || (q.MaxBytes > 0 && usage.Bytes > q.MaxBytes)
+	}
+	return usage, nil
+}
+
+// valueSizes approximates the memory of string keys by the key and value lengths
+func (r *Repository[T]) valueSizes(ctx context.Context, fullKeys []string) ([]*redis.IntCmd, error) {
+	sizes := make([]*redis.IntCmd, len(fullKeys))
+	_, err := r.reader(ctx).Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range fullKeys {
+			sizes[i] = pipe.StrLen(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && !isWrongTypeError(err) {
+		return nil, convertRedisError(err)
+	}
+	for i, key := range fullKeys {
+		if sizes[i].Err() == nil {
+			sizes[i].SetVal(sizes[i].Val() + int64(len(key)))
+		}
+	}
+	return sizes, nil
+}
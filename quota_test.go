@@ -0,0 +1,109 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuota(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	var alerts []QuotaUsage
+	repo := NewRepository[TestValue](base.provider, base.client, "tenant:1:", WithQuota(Quota{
+		MaxKeys:       3,
+		CheckInterval: time.Hour,
+		OnExceeded: func(usage QuotaUsage) {
+			mu.Lock()
+			defer mu.Unlock()
+			alerts = append(alerts, usage)
+		},
+	}))
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, repo.Set(ctx, fmt.Sprintf("doc:%d", i), &TestValue{ID: "x"}))
+	}
+	// The first write measured in the background; wait for it so it can't alert below
+	assert.Eventually(t, func() bool {
+		repo.quota.mu.Lock()
+		defer repo.quota.mu.Unlock()
+		return repo.quota.measured && !repo.quota.measuring
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	alerts = nil
+	mu.Unlock()
+
+	usage, err := repo.QuotaUsage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), usage.Keys)
+	assert.Greater(t, usage.Bytes, int64(0))
+	assert.True(t, usage.Exceeded)
+	mu.Lock()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "tenant:1:", alerts[0].Prefix)
+	mu.Unlock()
+
+	// Writes are rejected while over quota, deletes still free space
+	err = repo.Set(ctx, "doc:4", &TestValue{ID: "x"})
+	require.Error(t, err)
+	assert.True(t, IsQuotaExceededError(err))
+	require.NoError(t, repo.DeleteKey(ctx, "doc:0"))
+	_, err = repo.QuotaUsage(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.Set(ctx, "doc:4", &TestValue{ID: "x"}))
+}
+
+func TestQuotaAlertOnly(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var alerts atomic.Int32
+	repo := NewRepository[TestValue](base.provider, base.client, "tenant:2:", WithQuota(Quota{
+		MaxBytes:      10,
+		Action:        QuotaAlert,
+		CheckInterval: time.Millisecond,
+		OnExceeded:    func(QuotaUsage) { alerts.Add(1) },
+	}))
+
+	// Writes trigger measurements in the background and are never rejected
+	assert.Eventually(t, func() bool {
+		require.NoError(t, repo.Set(ctx, "a", &TestValue{ID: "a", Name: "a long enough name"}))
+		return alerts.Load() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestQuotaWriteOnlyPrincipal(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[TestValue](base.provider, base.client, "tenant:3:", WithQuota(Quota{
+		MaxKeys:       1,
+		CheckInterval: time.Millisecond,
+	}), WithAuthorizer(func(ctx context.Context, op AccessOp, key string) error {
+		if op != AccessWrite {
+			return fmt.Errorf("%s denied", op)
+		}
+		return nil
+	}))
+
+	// Measurements don't go through the authorizer, so they still catch the overshoot
+	require.NoError(t, repo.Set(ctx, "a", &TestValue{ID: "a"}))
+	require.NoError(t, repo.Set(ctx, "b", &TestValue{ID: "b"}))
+	assert.Eventually(t, func() bool {
+		return IsQuotaExceededError(repo.Set(ctx, "c", &TestValue{ID: "c"}))
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err := repo.QuotaUsage(ctx)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypePermission), "on-demand measurements are scans of the caller")
+}
@@ -0,0 +1,186 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Range Indexes
+// =====================================
+
+// rangeEntry is the score of a key in the sorted set of a range index
+type rangeEntry struct {
+	set   string
+	score float64
+}
+
+// rangeSetKey returns the sorted set of a range index
+func (r *Repository[T]) rangeSetKey(index string) string {
+	return indexKeyPrefix + r.keyPrefix + "~range:" + index
+}
+
+// rangeScore converts a numeric value to its score. Times are scored by Unix milliseconds.
+// Returns false for nil, NaN and non-numeric values, which are not indexed.
+func rangeScore(v reflect.Value) (float64, bool) {
+	if !v.IsValid() {
+		return 0, false
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return float64(t.UnixMilli()), true
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		return f, !math.IsNaN(f)
+	}
+	return 0, false
+}
+
+// entityRanges returns the range set scores of entity under the range indexes of defs
+func (r *Repository[T]) entityRanges(entity reflect.Value, defs []indexDef) []rangeEntry {
+	for entity.Kind() == reflect.Ptr {
+		if entity.IsNil() {
+			return nil
+		}
+		entity = entity.Elem()
+	}
+	var entries []rangeEntry
+	for _, def := range defs {
+		if !def.ranged || len(def.fields) != 1 {
+			continue
+		}
+		field, err := entity.FieldByIndexErr(def.fields[0].index)
+		if err != nil {
+			continue
+		}
+		if score, ok := rangeScore(field); ok {
+			entries = append(entries, rangeEntry{set: r.rangeSetKey(def.name), score: score})
+		}
+	}
+	return entries
+}
+
+// RangeCondition bounds the values of a range index
+type RangeCondition struct {
+	min, max interface{}
+}
+
+// Between matches values from min to max inclusive
+// Example: adults, err := users.FindWhere(ctx, "age", gparedis.Between(18, 30), gparedis.WhereOptions{})
+func Between(min, max interface{}) RangeCondition {
+	return RangeCondition{min: min, max: max}
+}
+
+// AtLeast matches values greater than or equal to min
+func AtLeast(min interface{}) RangeCondition {
+	return RangeCondition{min: min}
+}
+
+// AtMost matches values less than or equal to max
+func AtMost(max interface{}) RangeCondition {
+	return RangeCondition{max: max}
+}
+
+// bounds renders the condition as ZRANGEBYSCORE bounds
+func (c RangeCondition) bounds() (string, string, error) {
+	bound := func(value interface{}, open string) (string, error) {
+		if value == nil {
+			return open, nil
+		}
+		score, ok := rangeScore(reflect.ValueOf(value))
+		if !ok {
+			return "", gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("range bound %v is not a number or time", value))
+		}
+		return strconv.FormatFloat(score, 'f', -1, 64), nil
+	}
+	min, err := bound(c.min, "-inf")
+	if err != nil {
+		return "", "", err
+	}
+	max, err := bound(c.max, "+inf")
+	if err != nil {
+		return "", "", err
+	}
+	return min, max, nil
+}
+
+// WhereOptions configures one range query
+type WhereOptions struct {
+	// Offset is the number of matching keys skipped
+	Offset int64
+	// Limit is the number of keys returned (default 100)
+	Limit int64
+	// Descending returns the highest values first
+	Descending bool
+}
+
+// KeysWhere returns one page of the keys whose range index value satisfies cond, ordered by
+// value then key, and the total number of matches
+// Example: keys, total, err := users.KeysWhere(ctx, "age", gparedis.Between(18, 30), gparedis.WhereOptions{Limit: 20})
+func (r *Repository[T]) KeysWhere(ctx context.Context, index string, cond RangeCondition, opts WhereOptions) ([]string, int64, error) {
+	def, err := r.findIndex(index)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !def.ranged {
+		return nil, 0, gpa.NewError(gpa.ErrorTypeInvalidArgument, "index "+index+" is not a range index")
+	}
+	if opts.Offset < 0 {
+		return nil, 0, gpa.NewError(gpa.ErrorTypeInvalidArgument, "negative range offset")
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 100
+	}
+	min, max, err := cond.bounds()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	set := r.rangeSetKey(index)
+	var total *redis.IntCmd
+	var keys *redis.StringSliceCmd
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		total = pipe.ZCount(ctx, set, min, max)
+		if opts.Descending {
+			keys = pipe.ZRevRangeByScore(ctx, set, &redis.ZRangeBy{Min: min, Max: max, Offset: opts.Offset, Count: opts.Limit})
+		} else {
+			keys = pipe.ZRangeByScore(ctx, set, &redis.ZRangeBy{Min: min, Max: max, Offset: opts.Offset, Count: opts.Limit})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, convertRedisError(err)
+	}
+	if err := r.authorizeKeys(ctx, AccessRead, keys.Val()...); err != nil {
+		return nil, 0, err
+	}
+	return keys.Val(), total.Val(), nil
+}
+
+// FindWhere returns one page of the entities whose range index value satisfies cond
+// Example: page, err := users.FindWhere(ctx, "age", gparedis.Between(18, 30), gparedis.WhereOptions{Offset: 20, Limit: 20})
+func (r *Repository[T]) FindWhere(ctx context.Context, index string, cond RangeCondition, opts WhereOptions) (*FilterPage[T], error) {
+	keys, total, err := r.KeysWhere(ctx, index, cond, opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadPage(ctx, keys, total)
+}
@@ -0,0 +1,100 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rangedMember struct {
+	ID       string    `json:"id"`
+	Age      int       `json:"age" gpaindex:"age,range"`
+	Score    *float64  `json:"score" gpaindex:"score,range"`
+	JoinedAt time.Time `json:"joined_at" gpaindex:"joined,range"`
+	Status   string    `json:"status" gpaindex:"status"`
+}
+
+func TestRangeIndexQueries(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[rangedMember](base.provider, base.client, "member:")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	high := 9.5
+	require.NoError(t, repo.MSet(ctx, map[string]*rangedMember{
+		"a": {ID: "a", Age: 17, JoinedAt: start, Status: "active"},
+		"b": {ID: "b", Age: 18, JoinedAt: start.Add(24 * time.Hour), Status: "active", Score: &high},
+		"c": {ID: "c", Age: 25, JoinedAt: start.Add(48 * time.Hour), Status: "active"},
+		"d": {ID: "d", Age: 30, JoinedAt: start.Add(72 * time.Hour), Status: "banned"},
+		"e": {ID: "e", Age: 31, JoinedAt: start.Add(96 * time.Hour), Status: "active"},
+	}))
+
+	page, err := repo.FindWhere(ctx, "age", Between(18, 30), WhereOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c", "d"}, page.Keys)
+	assert.Equal(t, int64(3), page.Total)
+	require.Len(t, page.Items, 3)
+	assert.Equal(t, 25, page.Items[1].Age)
+
+	// Pagination and ordering
+	keys, total, err := repo.KeysWhere(ctx, "age", AtLeast(18), WhereOptions{Offset: 1, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "d"}, keys)
+	assert.Equal(t, int64(4), total)
+	keys, _, err = repo.KeysWhere(ctx, "age", AtMost(25), WhereOptions{Descending: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b", "a"}, keys)
+
+	// Times are compared as instants; nil values are not indexed
+	keys, _, err = repo.KeysWhere(ctx, "joined", Between(start.Add(time.Hour), start.Add(48*time.Hour)), WhereOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, keys)
+	keys, total, err = repo.KeysWhere(ctx, "score", AtLeast(0), WhereOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, keys)
+	assert.Equal(t, int64(1), total)
+
+	// Updates move the key and deletes remove it
+	require.NoError(t, repo.Set(ctx, "a", &rangedMember{ID: "a", Age: 40, JoinedAt: start}))
+	require.NoError(t, repo.DeleteKey(ctx, "c"))
+	keys, _, err = repo.KeysWhere(ctx, "age", AtLeast(18), WhereOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "d", "e", "a"}, keys)
+
+	// Range and tag indexes are queried with their own methods
+	_, _, err = repo.KeysWhere(ctx, "status", Between(1, 2), WhereOptions{})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+	_, err = repo.KeysByIndex(ctx, "age", 18)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+	_, _, err = repo.KeysWhere(ctx, "age", Between("young", "old"), WhereOptions{})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestRepairRangeIndexes(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[rangedMember](base.provider, base.client, "member:")
+	require.NoError(t, repo.Set(ctx, "a", &rangedMember{ID: "a", Age: 20}))
+	require.NoError(t, repo.Set(ctx, "b", &rangedMember{ID: "b", Age: 30}))
+
+	// A write that bypassed the repository and a dangling member
+	require.NoError(t, base.client.Set(ctx, "member:a", `{"id":"a","age":50,"joined_at":"0001-01-01T00:00:00Z"}`, 0).Err())
+	require.NoError(t, base.client.ZAdd(ctx, repo.rangeSetKey("age"), &redis.Z{Member: "ghost", Score: 25}).Err())
+
+	report, err := repo.RepairIndexes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Reindexed)
+	assert.Equal(t, 1, report.Removed)
+
+	keys, _, err := repo.KeysWhere(ctx, "age", Between(0, 100), WhereOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "a"}, keys)
+}
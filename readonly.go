@@ -0,0 +1,147 @@
+package gparedis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Read-Only Mode
+// =====================================
+
+// writeCommands lists the commands that modify the dataset. Commands not listed here
+// (reads, PING, SCRIPT LOAD, PUBLISH, ...) are always allowed.
+var writeCommands = map[string]bool{
+	// strings
+	"set": true, "setnx": true, "setex": true, "psetex": true, "mset": true, "msetnx": true,
+	"getset": true, "getdel": true, "getex": true, "append": true, "setrange": true,
+	"incr": true, "incrby": true, "incrbyfloat": true, "decr": true, "decrby": true,
+	"setbit": true, "bitop": true, "bitfield": true,
+	// keyspace
+	"del": true, "unlink": true, "expire": true, "expireat": true, "pexpire": true, "pexpireat": true,
+	"persist": true, "rename": true, "renamenx": true, "move": true, "copy": true, "restore": true,
+	"migrate": true, "swapdb": true, "flushdb": true, "flushall": true,
+	// hashes
+	"hset": true, "hsetnx": true, "hmset": true, "hdel": true, "hincrby": true, "hincrbyfloat": true,
+	// lists
+	"lpush": true, "lpushx": true, "rpush": true, "rpushx": true, "lpop": true, "rpop": true,
+	"lset": true, "lrem": true, "ltrim": true, "linsert": true, "rpoplpush": true, "lmove": true,
+	"blpop": true, "brpop": true, "brpoplpush": true, "blmove": true, "lmpop": true, "blmpop": true,
+	// sets
+	"sadd": true, "srem": true, "spop": true, "smove": true,
+	"sinterstore": true, "sunionstore": true, "sdiffstore": true,
+	// sorted sets
+	"zadd": true, "zincrby": true, "zrem": true, "zremrangebyscore": true, "zremrangebyrank": true,
+	"zremrangebylex": true, "zpopmin": true, "zpopmax": true, "bzpopmin": true, "bzpopmax": true,
+	"zmpop": true, "bzmpop": true, "zunionstore": true, "zinterstore": true, "zdiffstore": true,
+	"zrangestore": true,
+	// streams
+	"xadd": true, "xdel": true, "xtrim": true, "xgroup": true, "xack": true, "xclaim": true,
+	"xautoclaim": true, "xreadgroup": true, "xsetid": true,
+	// hyperloglog and geo
+	"pfadd": true, "pfmerge": true, "geoadd": true, "geosearchstore": true,
+	// scripting: scripts may write; use the *_ro variants or readOnlyScript for read-only scripts
+	"eval": true, "evalsha": true, "fcall": true,
+}
+
+// readOnlyScripts holds the SHA-1 of the package's scripts that only read, filled at init by readOnlyScript
+var readOnlyScripts = map[string]bool{}
+
+// readOnlyScript marks script as read-only, so read-only and dry-run modes let it through.
+// Returns script, so it wraps the declaration.
+func readOnlyScript(script *redis.Script) *redis.Script {
+	readOnlyScripts[script.Hash()] = true
+	return script
+}
+
+// scriptHash returns the SHA-1 of the script an EVAL or EVALSHA runs, or "" for other commands
+func scriptHash(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return ""
+	}
+	switch strings.ToLower(cmd.Name()) {
+	case "evalsha":
+		hash, _ := args[1].(string)
+		return hash
+	case "eval":
+		src, _ := args[1].(string)
+		sum := sha1.Sum([]byte(src))
+		return hex.EncodeToString(sum[:])
+	}
+	return ""
+}
+
+// storeCommands write only when given a STORE/STOREDIST argument
+var storeCommands = map[string]bool{"sort": true, "georadius": true, "georadiusbymember": true}
+
+// isWriteCommand reports whether cmd modifies the dataset
+func isWriteCommand(cmd redis.Cmder) bool {
+	name := strings.ToLower(cmd.Name())
+	if writeCommands[name] {
+		return !readOnlyScripts[scriptHash(cmd)]
+	}
+	if storeCommands[name] {
+		for _, arg := range cmd.Args()[1:] {
+			if s, ok := arg.(string); ok && (strings.EqualFold(s, "store") || strings.EqualFold(s, "storedist")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetReadOnly switches read-only mode. While enabled, every mutating command issued through
+// the provider's client (repositories, scripts, pipelines, transactions) fails with
+// ErrorTypeReadOnly before reaching Redis.
+// Example: provider.SetReadOnly(true) // DR replica or analytics consumer
+func (p *Provider) SetReadOnly(enabled bool) {
+	p.readOnly.Store(enabled)
+}
+
+// ReadOnly reports whether read-only mode is enabled
+func (p *Provider) ReadOnly() bool {
+	return p.readOnly.Load()
+}
+
+// readOnlyError builds the error returned for a rejected command
+func readOnlyError(name string) error {
+	return gpa.NewError(ErrorTypeReadOnly, "provider is read-only: "+strings.ToUpper(name)+" rejected")
+}
+
+// readOnlyHook rejects mutating commands while the provider is read-only
+type readOnlyHook struct {
+	provider *Provider
+}
+
+func (h *readOnlyHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if h.provider.ReadOnly() && isWriteCommand(cmd) {
+		return ctx, readOnlyError(cmd.Name())
+	}
+	return ctx, nil
+}
+
+func (h *readOnlyHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *readOnlyHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if !h.provider.ReadOnly() {
+		return ctx, nil
+	}
+	for _, cmd := range cmds {
+		if isWriteCommand(cmd) {
+			return ctx, readOnlyError(cmd.Name())
+		}
+	}
+	return ctx, nil
+}
+
+func (h *readOnlyHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
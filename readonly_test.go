@@ -0,0 +1,64 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsWriteCommand(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, isWriteCommand(redis.NewStatusCmd(ctx, "set", "k", "v")))
+	assert.True(t, isWriteCommand(redis.NewIntCmd(ctx, "EVALSHA", "abc", 0)))
+	assert.True(t, isWriteCommand(redis.NewIntCmd(ctx, "sort", "k", "STORE", "dst")))
+	assert.False(t, isWriteCommand(redis.NewStringSliceCmd(ctx, "sort", "k", "alpha")))
+	assert.False(t, isWriteCommand(redis.NewStringCmd(ctx, "get", "k")))
+	assert.False(t, isWriteCommand(redis.NewCmd(ctx, "evalsha_ro", "abc", 0)))
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	provider := repo.provider
+	require.NoError(t, repo.Set(ctx, "user:1", &TestValue{ID: "1", Name: "Ada"}))
+
+	provider.SetReadOnly(true)
+	defer provider.SetReadOnly(false)
+	assert.True(t, provider.ReadOnly())
+
+	value, err := repo.Get(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", value.Name)
+
+	err = repo.Set(ctx, "user:2", &TestValue{ID: "2"})
+	assert.True(t, IsReadOnlyError(err), "unexpected error: %v", err)
+
+	_, err = repo.MDelete(ctx, []string{"user:1"})
+	assert.True(t, IsReadOnlyError(err), "unexpected error: %v", err)
+
+	err = provider.Set(ctx, "raw", "v", 0)
+	assert.True(t, IsReadOnlyError(err), "unexpected error: %v", err)
+	assert.False(t, gpa.IsNotFound(err))
+
+	_, err = provider.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "user:1")
+		pipe.Incr(ctx, "counter")
+		return nil
+	})
+	assert.True(t, IsReadOnlyError(err), "unexpected error: %v", err)
+
+	provider.SetReadOnly(false)
+	require.NoError(t, repo.Set(ctx, "user:2", &TestValue{ID: "2"}))
+}
+
+func TestReadOnlyOption(t *testing.T) {
+	p := &Provider{}
+	applyProviderOptions(p, map[string]interface{}{"read_only": true})
+	assert.True(t, p.ReadOnly())
+}
@@ -0,0 +1,302 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Realtime Groups
+// =====================================
+
+// RealtimeEventType identifies a group event
+type RealtimeEventType string
+
+const (
+	// RealtimeMessage is a payload broadcast to a group
+	RealtimeMessage RealtimeEventType = "message"
+	// RealtimeJoin announces a member joining a group
+	RealtimeJoin RealtimeEventType = "join"
+	// RealtimeLeave announces a member leaving a group, or timing out
+	RealtimeLeave RealtimeEventType = "leave"
+)
+
+// RealtimeEvent is delivered to the subscribers of a group
+type RealtimeEvent struct {
+	Type  RealtimeEventType `json:"type"`
+	Group string            `json:"group"`
+	// Member sent the message, or joined or left
+	Member  string `json:"member,omitempty"`
+	Payload []byte `json:"payload,omitempty"`
+}
+
+// RealtimeOptions configures a Realtime kit
+type RealtimeOptions struct {
+	// Prefix is the key and channel prefix (default "gparedis:rt:")
+	Prefix string
+	// PresenceTTL is how long a member stays present without a heartbeat (default 30s)
+	PresenceTTL time.Duration
+}
+
+// Realtime combines presence tracking with Pub/Sub groups, the backbone of chat rooms and
+// notification feeds served over WebSockets by several nodes: members join and leave
+// groups, heartbeats keep them present, and every node subscribes to the groups of its
+// local connections to fan out broadcasts, joins and leaves. Presence is stored in one
+// sorted set per group scored by the last heartbeat.
+type Realtime struct {
+	provider *Provider
+	opts     RealtimeOptions
+}
+
+// NewRealtime creates a realtime kit on provider
+// Example: rt := gparedis.NewRealtime(provider, gparedis.RealtimeOptions{PresenceTTL: time.Minute})
+func NewRealtime(provider *Provider, opts RealtimeOptions) *Realtime {
+	if opts.Prefix == "" {
+		opts.Prefix = "gparedis:rt:"
+	}
+	if opts.PresenceTTL <= 0 {
+		opts.PresenceTTL = 30 * time.Second
+	}
+	return &Realtime{provider: provider, opts: opts}
+}
+
+// membersKey is the sorted set of a group's members scored by their last heartbeat
+func (rt *Realtime) membersKey(group string) string {
+	return rt.opts.Prefix + "group:" + group + ":members"
+}
+
+// groupsKey is the set of groups a member joined
+func (rt *Realtime) groupsKey(member string) string {
+	return rt.opts.Prefix + "member:" + member + ":groups"
+}
+
+// channel is the Pub/Sub channel of a group
+func (rt *Realtime) channel(group string) string {
+	return rt.opts.Prefix + "group:" + group
+}
+
+// Join adds member to group and announces it to the group's subscribers
+// Example: err := rt.Join(ctx, "room:42", userID)
+func (rt *Realtime) Join(ctx context.Context, group, member string) error {
+	now := time.Now()
+	_, err := rt.provider.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, rt.membersKey(group), &redis.Z{Score: float64(now.UnixMilli()), Member: member})
+		pipe.SAdd(ctx, rt.groupsKey(member), group)
+		pipe.PExpire(ctx, rt.groupsKey(member), rt.opts.PresenceTTL)
+		return nil
+	})
+	if err != nil {
+		return convertRedisError(err)
+	}
+	return rt.publish(ctx, RealtimeEvent{Type: RealtimeJoin, Group: group, Member: member})
+}
+
+// Leave removes member from group and announces it; leaving a group the member isn't in
+// is a no-op
+func (rt *Realtime) Leave(ctx context.Context, group, member string) error {
+	var removed *redis.IntCmd
+	_, err := rt.provider.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		removed = pipe.ZRem(ctx, rt.membersKey(group), member)
+		pipe.SRem(ctx, rt.groupsKey(member), group)
+		return nil
+	})
+	if err != nil {
+		return convertRedisError(err)
+	}
+	if removed.Val() == 0 {
+		return nil
+	}
+	return rt.publish(ctx, RealtimeEvent{Type: RealtimeLeave, Group: group, Member: member})
+}
+
+// Disconnect removes member from every group it joined, such as when its last connection closed
+func (rt *Realtime) Disconnect(ctx context.Context, member string) error {
+	groups, err := rt.provider.client.SMembers(ctx, rt.groupsKey(member)).Result()
+	if err != nil {
+		return convertRedisError(err)
+	}
+	for _, group := range groups {
+		if err := rt.Leave(ctx, group, member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Heartbeat keeps member present in every group it joined; call it well within PresenceTTL
+func (rt *Realtime) Heartbeat(ctx context.Context, member string) error {
+	groups, err := rt.provider.client.SMembers(ctx, rt.groupsKey(member)).Result()
+	if err != nil {
+		return convertRedisError(err)
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	score := float64(time.Now().UnixMilli())
+	_, err = rt.provider.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, group := range groups {
+			// XX: a member swept from a group doesn't come back without joining
+			pipe.ZAddXX(ctx, rt.membersKey(group), &redis.Z{Score: score, Member: member})
+		}
+		pipe.PExpire(ctx, rt.groupsKey(member), rt.opts.PresenceTTL)
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// Members returns the members of group with a heartbeat within PresenceTTL, sorted by name
+func (rt *Realtime) Members(ctx context.Context, group string) ([]string, error) {
+	members, err := rt.provider.client.ZRangeByScore(ctx, rt.membersKey(group), &redis.ZRangeBy{
+		Min: rt.cutoff(),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// Count returns the number of present members of group
+func (rt *Realtime) Count(ctx context.Context, group string) (int64, error) {
+	n, err := rt.provider.client.ZCount(ctx, rt.membersKey(group), rt.cutoff(), "+inf").Result()
+	return n, convertRedisError(err)
+}
+
+// Sweep removes the members of group whose heartbeat is older than PresenceTTL, announces
+// their leave and returns them. Run it periodically from one node.
+func (rt *Realtime) Sweep(ctx context.Context, group string) ([]string, error) {
+	key := rt.membersKey(group)
+	cutoff := "(" + rt.cutoff()
+	stale, err := rt.provider.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: "-inf", Max: cutoff}).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	var swept []string
+	for _, member := range stale {
+		// Only the node whose ZREM succeeds announces the leave
+		removed, err := rt.provider.client.ZRem(ctx, key, member).Result()
+		if err != nil {
+			return swept, convertRedisError(err)
+		}
+		if removed == 0 {
+			continue
+		}
+		swept = append(swept, member)
+		if err := rt.publish(ctx, RealtimeEvent{Type: RealtimeLeave, Group: group, Member: member}); err != nil {
+			return swept, err
+		}
+	}
+	return swept, nil
+}
+
+// cutoff is the oldest heartbeat score of present members
+func (rt *Realtime) cutoff() string {
+	return strconv.FormatInt(time.Now().Add(-rt.opts.PresenceTTL).UnixMilli(), 10)
+}
+
+// Broadcast sends payload from member to the subscribers of group and returns how many
+// nodes received it
+// Example: n, err := rt.Broadcast(ctx, "room:42", userID, []byte(`{"text":"hi"}`))
+func (rt *Realtime) Broadcast(ctx context.Context, group, member string, payload []byte) (int64, error) {
+	data, err := json.Marshal(RealtimeEvent{Type: RealtimeMessage, Group: group, Member: member, Payload: payload})
+	if err != nil {
+		return 0, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to encode realtime event", err)
+	}
+	return rt.provider.Publish(ctx, rt.channel(group), data)
+}
+
+// publish sends a membership event to the group's subscribers
+func (rt *Realtime) publish(ctx context.Context, event RealtimeEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to encode realtime event", err)
+	}
+	_, err = rt.provider.Publish(ctx, rt.channel(event.Group), data)
+	return err
+}
+
+// RealtimeSubscription receives the events of a changing set of groups, typically those
+// joined by a node's local connections
+type RealtimeSubscription struct {
+	rt     *Realtime
+	pubsub *redis.PubSub
+	events chan RealtimeEvent
+}
+
+// Subscribe starts receiving the events of groups; more can be added with Add. Events
+// published while the subscription reconnects are lost. Close it when done.
+// Example: sub, err := rt.Subscribe(ctx, "room:42"); for event := range sub.Events() { ... }
+func (rt *Realtime) Subscribe(ctx context.Context, groups ...string) (*RealtimeSubscription, error) {
+	pubsub := rt.provider.client.Subscribe(ctx, rt.channels(groups)...)
+	if len(groups) > 0 {
+		if _, err := pubsub.Receive(ctx); err != nil {
+			pubsub.Close()
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to subscribe to realtime groups", err)
+		}
+	}
+	sub := &RealtimeSubscription{rt: rt, pubsub: pubsub, events: make(chan RealtimeEvent, 100)}
+	go sub.run(ctx)
+	return sub, nil
+}
+
+// channels returns the Pub/Sub channels of groups
+func (rt *Realtime) channels(groups []string) []string {
+	channels := make([]string, len(groups))
+	for i, group := range groups {
+		channels[i] = rt.channel(group)
+	}
+	return channels
+}
+
+// run decodes messages into events until the subscription is closed
+func (s *RealtimeSubscription) run(ctx context.Context) {
+	defer close(s.events)
+	for msg := range s.pubsub.Channel() {
+		payload := []byte(msg.Payload)
+		if isEncodedPayload(msg.Payload) {
+			decoded, err := s.rt.provider.DecodePayload(ctx, payload)
+			if err != nil {
+				continue
+			}
+			payload = decoded
+		}
+		var event RealtimeEvent
+		if err := json.Unmarshal(payload, &event); err != nil || !strings.HasPrefix(msg.Channel, s.rt.opts.Prefix) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Events returns the received events; the channel is closed by Close
+func (s *RealtimeSubscription) Events() <-chan RealtimeEvent {
+	return s.events
+}
+
+// Add starts receiving the events of groups. The subscription takes effect asynchronously,
+// so events published right after Add returns may be missed.
+func (s *RealtimeSubscription) Add(ctx context.Context, groups ...string) error {
+	return convertRedisError(s.pubsub.Subscribe(ctx, s.rt.channels(groups)...))
+}
+
+// Remove stops receiving the events of groups
+func (s *RealtimeSubscription) Remove(ctx context.Context, groups ...string) error {
+	return convertRedisError(s.pubsub.Unsubscribe(ctx, s.rt.channels(groups)...))
+}
+
+// Close ends the subscription
+func (s *RealtimeSubscription) Close() error {
+	return s.pubsub.Close()
+}
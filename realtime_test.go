@@ -0,0 +1,95 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealtimeGroups(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rt := NewRealtime(repo.provider, RealtimeOptions{Prefix: "test:rt:"})
+
+	sub, err := rt.Subscribe(ctx, "room:1")
+	require.NoError(t, err)
+	defer sub.Close()
+	next := func() RealtimeEvent {
+		select {
+		case event := <-sub.Events():
+			return event
+		case <-ctx.Done():
+			t.Fatal("no realtime event")
+			return RealtimeEvent{}
+		}
+	}
+
+	require.NoError(t, rt.Join(ctx, "room:1", "alice"))
+	assert.Equal(t, RealtimeEvent{Type: RealtimeJoin, Group: "room:1", Member: "alice"}, next())
+	require.NoError(t, rt.Join(ctx, "room:1", "bob"))
+	next()
+	require.NoError(t, rt.Join(ctx, "room:2", "alice"))
+
+	members, err := rt.Members(ctx, "room:1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, members)
+
+	n, err := rt.Broadcast(ctx, "room:1", "bob", []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, RealtimeEvent{Type: RealtimeMessage, Group: "room:1", Member: "bob", Payload: []byte("hello")}, next())
+
+	// Groups can be added to a running subscription
+	require.NoError(t, sub.Add(ctx, "room:2"))
+	require.Eventually(t, func() bool {
+		return repo.client.PubSubNumSub(ctx, rt.channel("room:2")).Val()[rt.channel("room:2")] == 1
+	}, time.Second, 10*time.Millisecond)
+	_, err = rt.Broadcast(ctx, "room:2", "alice", []byte("second room"))
+	require.NoError(t, err)
+	assert.Equal(t, "room:2", next().Group)
+
+	// Disconnecting leaves every group
+	require.NoError(t, rt.Disconnect(ctx, "alice"))
+	left := []RealtimeEvent{next(), next()}
+	assert.ElementsMatch(t, []string{"room:1", "room:2"}, []string{left[0].Group, left[1].Group})
+	assert.Equal(t, RealtimeLeave, left[0].Type)
+	count, err := rt.Count(ctx, "room:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	require.NoError(t, rt.Leave(ctx, "room:1", "bob"))
+	next()
+	keys, err := repo.client.Keys(ctx, "test:rt:*").Result()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestRealtimePresenceTimeout(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	rt := NewRealtime(repo.provider, RealtimeOptions{Prefix: "test:rt:", PresenceTTL: 200 * time.Millisecond})
+	defer repo.client.Del(ctx, rt.membersKey("lobby"), rt.groupsKey("alice"), rt.groupsKey("bob"))
+
+	require.NoError(t, rt.Join(ctx, "lobby", "alice"))
+	require.NoError(t, rt.Join(ctx, "lobby", "bob"))
+	time.Sleep(120 * time.Millisecond)
+	require.NoError(t, rt.Heartbeat(ctx, "alice"))
+	time.Sleep(120 * time.Millisecond)
+
+	members, err := rt.Members(ctx, "lobby")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, members)
+
+	swept, err := rt.Sweep(ctx, "lobby")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, swept)
+	assert.Equal(t, int64(1), repo.client.ZCard(ctx, rt.membersKey("lobby")).Val())
+}
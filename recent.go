@@ -0,0 +1,102 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Recently-Used Lists
+// =====================================
+
+// RecentListOptions configures a RecentList
+type RecentListOptions struct {
+	// MaxLen is the number of items kept per scope (default 50)
+	MaxLen int64
+	// TTL expires a scope's list after this long without Add (0 keeps it forever)
+	TTL time.Duration
+}
+
+// RecentList tracks the most recently used items per scope (typically a user ID), for
+// "recently viewed" features. Each scope is a sorted set of JSON encoded items scored by
+// last use, so adding an item again moves it to the front instead of duplicating it.
+type RecentList[T any] struct {
+	provider *Provider
+	client   *redis.Client
+	prefix   string
+	opts     RecentListOptions
+}
+
+// NewRecentList creates a recently-used list storing each scope at prefix+scope
+// Example: viewed := gparedis.NewRecentList[ProductRef](provider, "recent:viewed:", gparedis.RecentListOptions{MaxLen: 20})
+func NewRecentList[T any](provider *Provider, prefix string, opts RecentListOptions) *RecentList[T] {
+	if opts.MaxLen <= 0 {
+		opts.MaxLen = 50
+	}
+	return &RecentList[T]{provider: provider, client: provider.client, prefix: prefix, opts: opts}
+}
+
+// member encodes an item as its sorted set member
+func (l *RecentList[T]) member(value *T) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize recent item", err)
+	}
+	return string(data), nil
+}
+
+// Add marks value as used now in scope and trims the scope to MaxLen items
+func (l *RecentList[T]) Add(ctx context.Context, scope string, value *T) error {
+	member, err := l.member(value)
+	if err != nil {
+		return err
+	}
+	key := l.prefix + scope
+	_, err = l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, &redis.Z{Score: float64(time.Now().UnixMicro()), Member: member})
+		pipe.ZRemRangeByRank(ctx, key, 0, -l.opts.MaxLen-1)
+		if l.opts.TTL > 0 {
+			pipe.PExpire(ctx, key, l.opts.TTL)
+		}
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// GetRecent returns up to n items of scope, most recent first
+func (l *RecentList[T]) GetRecent(ctx context.Context, scope string, n int64) ([]*T, error) {
+	if n <= 0 {
+		return []*T{}, nil
+	}
+	members, err := l.client.ZRevRange(ctx, l.prefix+scope, 0, n-1).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	items := make([]*T, 0, len(members))
+	for _, member := range members {
+		var value T
+		if err := json.Unmarshal([]byte(member), &value); err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize recent item", err)
+		}
+		items = append(items, &value)
+	}
+	return items, nil
+}
+
+// Remove drops value from scope
+func (l *RecentList[T]) Remove(ctx context.Context, scope string, value *T) error {
+	member, err := l.member(value)
+	if err != nil {
+		return err
+	}
+	return convertRedisError(l.client.ZRem(ctx, l.prefix+scope, member).Err())
+}
+
+// Clear removes every item of scope
+func (l *RecentList[T]) Clear(ctx context.Context, scope string) error {
+	return convertRedisError(l.client.Del(ctx, l.prefix+scope).Err())
+}
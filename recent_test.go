@@ -0,0 +1,53 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentList(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	l := NewRecentList[TestValue](repo.provider, "recent:", RecentListOptions{MaxLen: 3, TTL: time.Hour})
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, l.Add(ctx, "user:1", &TestValue{ID: id}))
+		time.Sleep(time.Millisecond)
+	}
+	// Re-adding moves an item to the front
+	require.NoError(t, l.Add(ctx, "user:1", &TestValue{ID: "b"}))
+	require.NoError(t, l.Add(ctx, "user:2", &TestValue{ID: "z"}))
+
+	items, err := l.GetRecent(ctx, "user:1", 10)
+	require.NoError(t, err)
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	assert.Equal(t, []string{"b", "d", "c"}, ids)
+
+	items, err = l.GetRecent(ctx, "user:1", 1)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "b", items[0].ID)
+
+	require.NoError(t, l.Remove(ctx, "user:1", &TestValue{ID: "d"}))
+	items, err = l.GetRecent(ctx, "user:1", 10)
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	ttl, err := repo.provider.client.TTL(ctx, "recent:user:1").Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute)
+
+	require.NoError(t, l.Clear(ctx, "user:2"))
+	items, err = l.GetRecent(ctx, "user:2", 10)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
@@ -0,0 +1,425 @@
+package gparedis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Record and Replay
+// =====================================
+
+// recordedCommand is one line of a recording: a command and its raw RESP reply. Both are
+// kept as bytes (base64 in the JSON) since values and replies needn't be valid UTF-8.
+type recordedCommand struct {
+	Command [][]byte `json:"cmd"`
+	Reply   []byte   `json:"reply"`
+}
+
+// NewRecordingProvider connects like NewProvider and writes every command with its reply
+// to the file at path (JSON lines), for NewReplayProvider to serve back. The recording is
+// complete once the provider is closed. Pub/sub and blocking commands are not recorded
+// reliably.
+// Example: provider, err := gparedis.NewRecordingProvider(config, "testdata/checkout.rec")
+func NewRecordingProvider(config gpa.Config, path string) (*Provider, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to create recording "+path, err)
+	}
+	recorder := &commandRecorder{file: file, w: bufio.NewWriter(file)}
+	recorder.enc = json.NewEncoder(recorder.w)
+
+	provider, err := newProvider(config, func(opts *redis.Options) {
+		dialer := &net.Dialer{Timeout: opts.DialTimeout, KeepAlive: 5 * time.Minute}
+		tlsConfig := opts.TLSConfig
+		opts.Dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var conn net.Conn
+			var err error
+			if tlsConfig != nil {
+				conn, err = tls.DialWithDialer(dialer, network, addr, tlsConfig)
+			} else {
+				conn, err = dialer.DialContext(ctx, network, addr)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return &recordingConn{Conn: conn, recorder: recorder}, nil
+		}
+	})
+	if err != nil {
+		recorder.Close()
+		return nil, err
+	}
+	provider.recorder = recorder
+	return provider, nil
+}
+
+// NewReplayProvider returns a provider answering commands from a recording made with
+// NewRecordingProvider, without connecting to Redis. A command gets the replies recorded
+// for the identical command in order, repeating the last one once they run out; commands
+// that weren't recorded fail. Arguments must match exactly, so code under test should use
+// deterministic keys and values.
+// Example: provider, err := gparedis.NewReplayProvider(config, "testdata/checkout.rec")
+func NewReplayProvider(config gpa.Config, path string) (*Provider, error) {
+	replay, err := loadCommandReplay(path)
+	if err != nil {
+		return nil, err
+	}
+	return newProvider(config, func(opts *redis.Options) {
+		opts.Dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return &replayConn{replay: replay, ready: make(chan struct{}, 1)}, nil
+		}
+		// Nothing to retry against; a missing reply is a test failure, not a network blip
+		opts.MaxRetries = -1
+	})
+}
+
+// commandRecorder appends command/reply pairs to a recording file
+type commandRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+	err  error
+}
+
+// add writes one pair, keeping the first write error for Close
+func (r *commandRecorder) add(args []string, reply []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		rec := recordedCommand{Command: make([][]byte, len(args)), Reply: reply}
+		for i, arg := range args {
+			rec.Command[i] = []byte(arg)
+		}
+		r.err = r.enc.Encode(rec)
+	}
+}
+
+// Close flushes and closes the recording file
+func (r *commandRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to write recording", r.err)
+	}
+	return nil
+}
+
+// recordingConn tees the commands written and the replies read on a connection into a
+// recorder, pairing them in order
+type recordingConn struct {
+	net.Conn
+	recorder *commandRecorder
+
+	mu       sync.Mutex
+	written  []byte
+	replies  []byte
+	commands [][]string
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written = append(c.written, b[:n]...)
+	for {
+		size, args, ok := parseRESPCommand(c.written)
+		if !ok {
+			break
+		}
+		c.commands = append(c.commands, args)
+		c.written = c.written[size:]
+	}
+	return n, err
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replies = append(c.replies, b[:n]...)
+	for {
+		size, ok := respValueLen(c.replies)
+		if !ok {
+			break
+		}
+		// Replies without a command (pub/sub pushes) are dropped
+		if len(c.commands) > 0 {
+			c.recorder.add(c.commands[0], c.replies[:size])
+			c.commands = c.commands[1:]
+		}
+		c.replies = c.replies[size:]
+	}
+	return n, err
+}
+
+// commandReplay holds the recorded replies by command
+type commandReplay struct {
+	mu      sync.Mutex
+	replies map[string][]string
+	last    map[string]string
+}
+
+// loadCommandReplay reads a recording file
+func loadCommandReplay(path string) (*commandReplay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to open recording "+path, err)
+	}
+	defer file.Close()
+
+	replay := &commandReplay{replies: make(map[string][]string), last: make(map[string]string)}
+	dec := json.NewDecoder(file)
+	for {
+		var rec recordedCommand
+		if err := dec.Decode(&rec); err == io.EOF {
+			return replay, nil
+		} else if err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid recording "+path, err)
+		}
+		args := make([]string, len(rec.Command))
+		for i, arg := range rec.Command {
+			args[i] = string(arg)
+		}
+		key := replayKey(args)
+		replay.replies[key] = append(replay.replies[key], string(rec.Reply))
+	}
+}
+
+// replayKey identifies a command by its exact arguments, length-prefixed since binary
+// arguments may contain any byte
+func replayKey(args []string) string {
+	var b strings.Builder
+	for _, arg := range args {
+		b.WriteString(strconv.Itoa(len(arg)))
+		b.WriteByte(':')
+		b.WriteString(arg)
+	}
+	return b.String()
+}
+
+// reply returns the next recorded reply for a command
+func (r *commandReplay) reply(args []string) string {
+	key := replayKey(args)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if queue := r.replies[key]; len(queue) > 0 {
+		r.replies[key] = queue[1:]
+		r.last[key] = queue[0]
+		return queue[0]
+	}
+	if reply, ok := r.last[key]; ok {
+		return reply
+	}
+	return "-ERR gparedis replay: no recorded reply for " + strings.Join(args, " ") + "\r\n"
+}
+
+// replayConn is an in-memory connection answering each command written to it with the
+// recorded reply
+type replayConn struct {
+	replay *commandReplay
+	ready  chan struct{}
+
+	mu           sync.Mutex
+	pending      []byte
+	out          bytes.Buffer
+	readDeadline time.Time
+	closed       bool
+}
+
+func (c *replayConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	c.pending = append(c.pending, b...)
+	for {
+		size, args, ok := parseRESPCommand(c.pending)
+		if !ok {
+			break
+		}
+		c.out.WriteString(c.replay.reply(args))
+		c.pending = c.pending[size:]
+	}
+	c.signal()
+	return len(b), nil
+}
+
+func (c *replayConn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if c.out.Len() > 0 {
+			n, _ := c.out.Read(b)
+			c.mu.Unlock()
+			return n, nil
+		}
+		if c.closed {
+			c.mu.Unlock()
+			return 0, io.EOF
+		}
+		deadline := c.readDeadline
+		c.mu.Unlock()
+
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			wait := time.Until(deadline)
+			if wait <= 0 {
+				return 0, replayTimeoutError{}
+			}
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-c.ready:
+		case <-timeout:
+			return 0, replayTimeoutError{}
+		}
+	}
+}
+
+// signal wakes a waiting Read; callers hold c.mu
+func (c *replayConn) signal() {
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (c *replayConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.signal()
+	return nil
+}
+
+func (c *replayConn) LocalAddr() net.Addr  { return replayAddr{} }
+func (c *replayConn) RemoteAddr() net.Addr { return replayAddr{} }
+
+func (c *replayConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *replayConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	c.signal()
+	return nil
+}
+
+func (c *replayConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// replayAddr is the address of a replay connection
+type replayAddr struct{}
+
+func (replayAddr) Network() string { return "replay" }
+func (replayAddr) String() string  { return "replay" }
+
+// replayTimeoutError is returned by reads past the deadline
+type replayTimeoutError struct{}
+
+func (replayTimeoutError) Error() string   { return "gparedis replay: i/o timeout" }
+func (replayTimeoutError) Timeout() bool   { return true }
+func (replayTimeoutError) Temporary() bool { return true }
+
+// =====================================
+// RESP Framing
+// =====================================
+
+// respValueLen returns the length of the complete RESP value at the start of buf, or false
+// when more data is needed
+func respValueLen(buf []byte) (int, bool) {
+	end := bytes.Index(buf, []byte("\r\n"))
+	if end < 0 {
+		return 0, false
+	}
+	head := end + 2
+	switch buf[0] {
+	case '$', '!', '=':
+		n, err := strconv.Atoi(string(buf[1:end]))
+		if err != nil || n < 0 {
+			return head, true
+		}
+		if len(buf) < head+n+2 {
+			return 0, false
+		}
+		return head + n + 2, true
+	case '*', '~', '>', '%', '|':
+		n, err := strconv.Atoi(string(buf[1:end]))
+		if err != nil || n < 0 {
+			return head, true
+		}
+		if buf[0] == '%' || buf[0] == '|' {
+			n *= 2
+		}
+		// An attribute map precedes the value it annotates
+		if buf[0] == '|' {
+			n++
+		}
+		offset := head
+		for i := 0; i < n; i++ {
+			size, ok := respValueLen(buf[offset:])
+			if !ok {
+				return 0, false
+			}
+			offset += size
+		}
+		return offset, true
+	default:
+		return head, true
+	}
+}
+
+// parseRESPCommand decodes the complete command at the start of buf, returning its length
+// and arguments, or false when more data is needed
+func parseRESPCommand(buf []byte) (int, []string, bool) {
+	size, ok := respValueLen(buf)
+	if !ok {
+		return 0, nil, false
+	}
+	if buf[0] != '*' {
+		// Inline command
+		return size, strings.Fields(string(buf[:size])), true
+	}
+	end := bytes.Index(buf, []byte("\r\n"))
+	count, _ := strconv.Atoi(string(buf[1:end]))
+	args := make([]string, 0, count)
+	offset := end + 2
+	for i := 0; i < count && offset < size; i++ {
+		lineEnd := offset + bytes.Index(buf[offset:], []byte("\r\n"))
+		n, err := strconv.Atoi(string(buf[offset+1 : lineEnd]))
+		if err != nil || n < 0 {
+			args = append(args, "")
+			offset = lineEnd + 2
+			continue
+		}
+		args = append(args, string(buf[lineEnd+2:lineEnd+2+n]))
+		offset = lineEnd + 2 + n + 2
+	}
+	return size, args, true
+}
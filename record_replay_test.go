@@ -0,0 +1,123 @@
+package gparedis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	redisURL := os.Getenv("REDIS_TEST_URL")
+	if redisURL == "" {
+		redisURL = "redis://localhost:6379"
+	}
+	path := filepath.Join(t.TempDir(), "session.rec")
+	ctx := context.Background()
+
+	recording, err := NewRecordingProvider(gpa.Config{Driver: "redis", ConnectionURL: redisURL}, path)
+	if err != nil {
+		t.Skipf("Skipping Redis tests: %v", err)
+	}
+	users := NewRepository[TestValue](recording, recording.client, "replay:user:")
+	require.NoError(t, users.Set(ctx, "1", &TestValue{ID: "1", Name: "Ann"}))
+	require.NoError(t, users.Set(ctx, "2", &TestValue{ID: "2", Name: "Bob"}))
+	got, err := users.MGet(ctx, []string{"1", "2"})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	_, err = users.Get(ctx, "3")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+	recording.client.Del(ctx, "replay:user:1", "replay:user:2")
+	require.NoError(t, recording.Close())
+
+	// No Redis listens on the replay address
+	replay, err := NewReplayProvider(gpa.Config{Driver: "redis", ConnectionURL: "redis://127.0.0.1:1"}, path)
+	require.NoError(t, err)
+	defer replay.Close()
+	users = NewRepository[TestValue](replay, replay.client, "replay:user:")
+	got, err = users.MGet(ctx, []string{"1", "2"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", got["2"].Name)
+	user, err := users.Get(ctx, "3")
+	assert.Nil(t, user)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+	_, err = replay.client.Incr(ctx, "never-recorded").Result()
+	assert.ErrorContains(t, err, "no recorded reply")
+
+	var mget *redis.SliceCmd
+	_, err = replay.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "replay:user:3")
+		mget = pipe.MGet(ctx, "replay:user:1", "replay:user:2")
+		return nil
+	})
+	assert.Equal(t, redis.Nil, err, "the recorded nil reply comes back through pipelines")
+	assert.Len(t, mget.Val(), 2)
+}
+
+func TestRESPFraming(t *testing.T) {
+	n, ok := respValueLen([]byte("*2\r\n$3\r\nfoo\r\n:1\r\n+extra"))
+	require.True(t, ok)
+	assert.Equal(t, 17, n)
+	_, ok = respValueLen([]byte("$5\r\nab"))
+	assert.False(t, ok)
+	n, ok = respValueLen([]byte("$-1\r\n"))
+	require.True(t, ok)
+	assert.Equal(t, 5, n)
+
+	size, args, ok := parseRESPCommand([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$0\r\n\r\n"))
+	require.True(t, ok)
+	assert.Equal(t, 26, size)
+	assert.Equal(t, []string{"SET", "k", ""}, args)
+}
+
+func TestRecordingKeepsBinaryData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "binary.rec")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	recorder := &commandRecorder{file: file, w: bufio.NewWriter(file)}
+	recorder.enc = json.NewEncoder(recorder.w)
+
+	value := "\xff\xfe\x00\x80gzip"
+	recorder.add([]string{"set", "bin:1", value}, []byte("+OK\r\n"))
+	recorder.add([]string{"get", "bin:1"}, []byte("$8\r\n"+value+"\r\n"))
+	require.NoError(t, recorder.Close())
+
+	replay, err := loadCommandReplay(path)
+	require.NoError(t, err)
+	assert.Equal(t, "+OK\r\n", replay.reply([]string{"set", "bin:1", value}))
+	assert.Equal(t, "$8\r\n"+value+"\r\n", replay.reply([]string{"get", "bin:1"}))
+	assert.Contains(t, replay.reply([]string{"set", "bin:1", "\ufffd\ufffd\x00\ufffdgzip"}), "no recorded reply",
+		"invalid bytes aren't replaced on the way through the file")
+
+	// Against Redis, the bytes make the full round trip
+	redisURL := os.Getenv("REDIS_TEST_URL")
+	if redisURL == "" {
+		redisURL = "redis://localhost:6379"
+	}
+	ctx := context.Background()
+	path = filepath.Join(t.TempDir(), "session.rec")
+	recording, err := NewRecordingProvider(gpa.Config{Driver: "redis", ConnectionURL: redisURL}, path)
+	if err != nil {
+		t.Skipf("Skipping Redis tests: %v", err)
+	}
+	require.NoError(t, recording.client.Set(ctx, "replay:bin", value, 0).Err())
+	got, err := recording.client.Get(ctx, "replay:bin").Result()
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+	recording.client.Del(ctx, "replay:bin")
+	require.NoError(t, recording.Close())
+
+	replayed, err := NewReplayProvider(gpa.Config{Driver: "redis", ConnectionURL: "redis://127.0.0.1:1"}, path)
+	require.NoError(t, err)
+	defer replayed.Close()
+	got, err = replayed.client.Get(ctx, "replay:bin").Result()
+	require.NoError(t, err)
+	assert.Equal(t, value, got)
+}
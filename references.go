@@ -0,0 +1,248 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// References and Cascade Deletes
+// =====================================
+
+// Fields tagged `gpaindex:"name,ref=prefix"` hold the key of an entity stored under prefix,
+// like Session.UserID referencing a User under "user:". The field is indexed like any tag
+// index, so the tag set of a value lists the keys referencing that entity. Repositories
+// register their references on the provider, letting the referenced repository find its
+// dependents for DeleteCascade.
+
+// referenceSource is a repository with a reference index, registered on its provider
+type referenceSource struct {
+	// dependents returns the unprefixed keys referencing the target key
+	dependents func(ctx context.Context, targetKey string) ([]string, error)
+	// erase deletes unprefixed keys and their own dependents, returning the full keys removed
+	erase func(ctx context.Context, keys []string, visited map[string]bool) ([]string, error)
+}
+
+// registerReferenceSource makes a repository's reference index visible to the repositories under target
+func (p *Provider) registerReferenceSource(target, id string, source referenceSource) {
+	p.referencesMu.Lock()
+	defer p.referencesMu.Unlock()
+	if p.referenceSources == nil {
+		p.referenceSources = make(map[string]map[string]referenceSource)
+	}
+	if p.referenceSources[target] == nil {
+		p.referenceSources[target] = make(map[string]referenceSource)
+	}
+	p.referenceSources[target][id] = source
+}
+
+// referencesTo returns the sources referencing keys under target, ordered by registration id
+func (p *Provider) referencesTo(target string) []referenceSource {
+	p.referencesMu.RLock()
+	defer p.referencesMu.RUnlock()
+	ids := make([]string, 0, len(p.referenceSources[target]))
+	for id := range p.referenceSources[target] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	sources := make([]referenceSource, len(ids))
+	for i, id := range ids {
+		sources[i] = p.referenceSources[target][id]
+	}
+	return sources
+}
+
+// registerReferences exposes the repository's reference indexes to DeleteCascade
+func (r *Repository[T]) registerReferences() {
+	if r.provider == nil {
+		return
+	}
+	for _, def := range r.indexes() {
+		if def.ref == "" || len(def.fields) != 1 {
+			// References point at a single key; composite reference indexes are ignored
+			continue
+		}
+		index := def.name
+		r.provider.registerReferenceSource(def.ref, fmt.Sprintf("%s|%s|%s", r.keyPrefix, index, reflect.TypeOf((*T)(nil)).Elem()), referenceSource{
+			dependents: func(ctx context.Context, targetKey string) ([]string, error) {
+				return r.KeysByIndex(ctx, index, targetKey)
+			},
+			erase: r.deleteCascade,
+		})
+	}
+}
+
+// DeleteCascade deletes key together with every entity referencing it through the reference
+// indexes of this provider's repositories, recursively. Dependents are deleted before the
+// entities they reference, one at a time through their repositories, running BeforeDelete
+// and AfterDelete hooks like DeleteKey, so indexes, hooks and CDC stay consistent; reference
+// cycles are followed once. A failing BeforeDelete hook stops the cascade. Returns the full
+// keys that existed and were deleted, sorted.
+// Example: deleted, err := users.DeleteCascade(ctx, "42")
+func (r *Repository[T]) DeleteCascade(ctx context.Context, key string) ([]string, error) {
+	deleted, err := r.deleteCascade(ctx, []string{key}, make(map[string]bool))
+	sort.Strings(deleted)
+	return deleted, err
+}
+
+// deleteCascade deletes keys not visited yet after their dependents
+func (r *Repository[T]) deleteCascade(ctx context.Context, keys []string, visited map[string]bool) ([]string, error) {
+	var own []string
+	for _, key := range keys {
+		if fullKey := r.buildKey(key); !visited[fullKey] {
+			visited[fullKey] = true
+			own = append(own, key)
+		}
+	}
+	if len(own) == 0 {
+		return nil, nil
+	}
+
+	var deleted []string
+	if r.provider != nil {
+		for _, source := range r.provider.referencesTo(r.keyPrefix) {
+			for _, key := range own {
+				dependents, err := source.dependents(ctx, key)
+				if err != nil {
+					return deleted, err
+				}
+				removed, err := source.erase(ctx, dependents, visited)
+				deleted = append(deleted, removed...)
+				if err != nil {
+					return deleted, err
+				}
+			}
+		}
+	}
+	removed, err := r.deleteEntities(ctx, own)
+	return append(deleted, removed...), err
+}
+
+// deleteEntities deletes keys one at a time with their delete hooks, like DeleteKey, and
+// returns the full keys that existed. The degradation policy is bypassed, so deletions are
+// only reported once they happened.
+func (r *Repository[T]) deleteEntities(ctx context.Context, keys []string) ([]string, error) {
+	entities, err := r.MGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, key := range keys {
+		entity := entities[key]
+		if hook, ok := any(entity).(gpa.BeforeDeleteHook); ok && entity != nil {
+			if err := hook.BeforeDelete(ctx); err != nil {
+				return removed, gpa.GPAError{
+					Type:    gpa.ErrorTypeValidation,
+					Message: "before delete hook failed",
+					Cause:   err,
+				}
+			}
+		}
+		n, err := r.mdelete(ctx, []string{key})
+		if err != nil {
+			return removed, err
+		}
+		if n == 0 {
+			continue
+		}
+		removed = append(removed, r.buildKey(key))
+		if hook, ok := any(entity).(gpa.AfterDeleteHook); ok && entity != nil {
+			// As in DeleteKey, after delete hook errors don't fail the operation
+			_ = hook.AfterDelete(ctx)
+		}
+	}
+	return removed, nil
+}
+
+// DanglingReference is a reference to an entity that doesn't exist
+type DanglingReference struct {
+	// Key is the unprefixed key of the referencing entity
+	Key string
+	// Index is the reference index holding the reference
+	Index string
+	// Target is the full key of the missing entity
+	Target string
+}
+
+// ReferenceReport lists the findings of CheckReferences
+type ReferenceReport struct {
+	// Checked counts the distinct referenced keys checked
+	Checked int
+	// Dangling lists the references to missing entities, ordered by index, key and target
+	Dangling []DanglingReference
+}
+
+// CheckReferences reports references to entities that no longer exist, left by plain deletes
+// of referenced entities or by writes of references to entities never stored. Each distinct
+// referenced key is checked once through the tag sets of the reference indexes.
+// Example: report, err := sessions.CheckReferences(ctx)
+func (r *Repository[T]) CheckReferences(ctx context.Context) (ReferenceReport, error) {
+	report := ReferenceReport{Dangling: []DanglingReference{}}
+	if err := r.authorize(ctx, AccessScan, r.buildKey("*")); err != nil {
+		return report, err
+	}
+	for _, def := range r.indexes() {
+		if def.ref == "" || len(def.fields) != 1 {
+			continue
+		}
+		setPrefix := r.tagSetKey(def.name, "")
+		err := scanKeys(ctx, r.client, EscapeGlob(setPrefix)+"*", func(sets []string) error {
+			targets := make([]string, len(sets))
+			for i, set := range sets {
+				targets[i] = def.ref + strings.TrimPrefix(set, setPrefix)
+			}
+			// Checking a target's existence reads it
+			if err := r.authorize(ctx, AccessRead, targets...); err != nil {
+				return err
+			}
+			exists := make([]*redis.IntCmd, len(sets))
+			_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for i, target := range targets {
+					exists[i] = pipe.Exists(ctx, target)
+				}
+				return nil
+			})
+			if err != nil {
+				return convertRedisError(err)
+			}
+			report.Checked += len(sets)
+			for i, set := range sets {
+				if exists[i].Val() > 0 {
+					continue
+				}
+				members, err := r.client.SMembers(ctx, set).Result()
+				if err != nil {
+					return convertRedisError(err)
+				}
+				for _, member := range members {
+					report.Dangling = append(report.Dangling, DanglingReference{
+						Key:    member,
+						Index:  def.name,
+						Target: targets[i],
+					})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	sort.Slice(report.Dangling, func(i, j int) bool {
+		a, b := report.Dangling[i], report.Dangling[j]
+		if a.Index != b.Index {
+			return a.Index < b.Index
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Target < b.Target
+	})
+	return report, nil
+}
@@ -0,0 +1,111 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type refUser struct {
+	ID string `json:"id"`
+}
+
+type refSession struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id" gpaindex:"user,ref=refuser:"`
+}
+
+type refEvent struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id" gpaindex:"session,ref=refsession:"`
+}
+
+type refLockedNote struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id" gpaindex:"user,ref=reflockuser:"`
+}
+
+func (n *refLockedNote) BeforeDelete(ctx context.Context) error {
+	return errors.New("note is locked")
+}
+
+func TestReferenceTag(t *testing.T) {
+	defs := indexDefs(entityMetaFor(reflect.TypeOf(refSession{})))
+	require.Len(t, defs, 1)
+	assert.Equal(t, "user", defs[0].name)
+	assert.Equal(t, "refuser:", defs[0].ref)
+}
+
+func TestDeleteCascade(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	users := NewRepository[refUser](base.provider, base.client, "refuser:")
+	sessions := NewRepository[refSession](base.provider, base.client, "refsession:")
+	events := NewRepository[refEvent](base.provider, base.client, "refevent:")
+
+	require.NoError(t, users.MSet(ctx, map[string]*refUser{"1": {ID: "1"}, "2": {ID: "2"}}))
+	require.NoError(t, sessions.MSet(ctx, map[string]*refSession{
+		"a": {ID: "a", UserID: "1"},
+		"b": {ID: "b", UserID: "1"},
+		"c": {ID: "c", UserID: "2"},
+	}))
+	require.NoError(t, events.MSet(ctx, map[string]*refEvent{
+		"x": {ID: "x", SessionID: "a"},
+		"y": {ID: "y", SessionID: "c"},
+	}))
+
+	deleted, err := users.DeleteCascade(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"refevent:x", "refsession:a", "refsession:b", "refuser:1"}, deleted)
+	deleted, err = users.DeleteCascade(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, deleted, "only keys that existed are reported")
+
+	left, err := sessions.KeysByIndex(ctx, "user", "1")
+	require.NoError(t, err)
+	assert.Empty(t, left)
+	exists, err := events.KeyExists(ctx, "y")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// A plain delete leaves dangling references behind
+	require.NoError(t, users.DeleteKey(ctx, "2"))
+	require.NoError(t, sessions.Set(ctx, "d", &refSession{ID: "d", UserID: "9"}))
+	report, err := sessions.CheckReferences(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Checked)
+	assert.Equal(t, []DanglingReference{
+		{Key: "c", Index: "user", Target: "refuser:2"},
+		{Key: "d", Index: "user", Target: "refuser:9"},
+	}, report.Dangling)
+
+	report, err = events.CheckReferences(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, report.Dangling)
+}
+
+func TestDeleteCascadeRunsHooks(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	users := NewRepository[refUser](base.provider, base.client, "reflockuser:")
+	notes := NewRepository[refLockedNote](base.provider, base.client, "reflocknote:")
+	require.NoError(t, users.Set(ctx, "1", &refUser{ID: "1"}))
+	require.NoError(t, notes.Set(ctx, "n", &refLockedNote{ID: "n", UserID: "1"}))
+	defer base.client.Del(ctx, "reflockuser:1", "reflocknote:n")
+
+	deleted, err := users.DeleteCascade(ctx, "1")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeValidation), "BeforeDelete stops the cascade")
+	assert.Empty(t, deleted)
+	exists, err := users.KeyExists(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
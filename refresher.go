@@ -0,0 +1,181 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Keep-Warm Refresher
+// =====================================
+
+// RefreshLoader loads the current value for a key kept warm by a Refresher
+type RefreshLoader[T any] func(ctx context.Context, key string) (*T, error)
+
+// RefresherOptions configures a Refresher
+type RefresherOptions struct {
+	// Lead is how long before expiry a key is refreshed (default 10% of its TTL)
+	Lead time.Duration
+	// Interval is how often Run checks for due keys (default 1s)
+	Interval time.Duration
+	// ScheduleKey is the sorted set tracking expirations (default "gparedis:refresh:<prefix>")
+	ScheduleKey string
+	// OnError is called when a loader or write fails; the key is retried on the next tick
+	OnError func(key string, err error)
+}
+
+// refreshEntry is a registered critical key
+type refreshEntry[T any] struct {
+	ttl    time.Duration
+	loader RefreshLoader[T]
+}
+
+// Refresher re-runs registered loaders shortly before their keys expire, so
+// always-hot entries never go cold. Expirations are tracked in a sorted set
+// (score = expiry in unix milliseconds) shared by all processes; a process only
+// refreshes keys it has a loader for, and claims each due key before reloading it.
+type Refresher[T any] struct {
+	repo *Repository[T]
+	opts RefresherOptions
+
+	mu      sync.RWMutex
+	entries map[string]refreshEntry[T]
+}
+
+// NewRefresher creates a refresher writing through repo
+// Example: refresher := gparedis.NewRefresher(configRepo, gparedis.RefresherOptions{Lead: 30 * time.Second})
+func NewRefresher[T any](repo *Repository[T], opts RefresherOptions) *Refresher[T] {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.ScheduleKey == "" {
+		opts.ScheduleKey = "gparedis:refresh:" + repo.keyPrefix
+	}
+	return &Refresher[T]{repo: repo, opts: opts, entries: make(map[string]refreshEntry[T])}
+}
+
+// Register keeps key warm: the value is loaded and written now with ttl, and reloaded before each expiry
+func (f *Refresher[T]) Register(ctx context.Context, key string, ttl time.Duration, loader RefreshLoader[T]) error {
+	if ttl <= 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "refresh TTL must be positive")
+	}
+	f.mu.Lock()
+	f.entries[key] = refreshEntry[T]{ttl: ttl, loader: loader}
+	f.mu.Unlock()
+
+	return f.refresh(ctx, key)
+}
+
+// Unregister stops refreshing key; the current value expires normally
+func (f *Refresher[T]) Unregister(ctx context.Context, key string) error {
+	f.mu.Lock()
+	delete(f.entries, key)
+	f.mu.Unlock()
+	return convertRedisError(f.repo.client.ZRem(ctx, f.opts.ScheduleKey, key).Err())
+}
+
+// RefreshDue reloads every registered key expiring within its lead time and returns how many were refreshed
+func (f *Refresher[T]) RefreshDue(ctx context.Context) (int, error) {
+	horizon := time.Now().Add(f.maxLead())
+	due, err := f.repo.client.ZRangeByScoreWithScores(ctx, f.opts.ScheduleKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(horizon.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return 0, convertRedisError(err)
+	}
+
+	refreshed := 0
+	for _, z := range due {
+		key := z.Member.(string)
+		f.mu.RLock()
+		entry, ok := f.entries[key]
+		f.mu.RUnlock()
+		if !ok || time.UnixMilli(int64(z.Score)).After(time.Now().Add(f.lead(entry.ttl))) {
+			continue
+		}
+
+		// Claim the key so concurrent refreshers don't reload it twice
+		claimed, err := f.repo.client.ZRem(ctx, f.opts.ScheduleKey, key).Result()
+		if err != nil {
+			return refreshed, convertRedisError(err)
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		if err := f.refresh(ctx, key); err != nil {
+			// Put the old expiry back so the next tick retries
+			f.repo.client.ZAdd(ctx, f.opts.ScheduleKey, &redis.Z{Score: z.Score, Member: key})
+			if f.opts.OnError != nil {
+				f.opts.OnError(key, err)
+			}
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// Run calls RefreshDue every Interval until ctx is cancelled
+func (f *Refresher[T]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(f.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := f.RefreshDue(ctx); err != nil && f.opts.OnError != nil {
+				f.opts.OnError("", err)
+			}
+		}
+	}
+}
+
+// refresh loads key, writes it with its TTL and schedules the next refresh
+func (f *Refresher[T]) refresh(ctx context.Context, key string) error {
+	f.mu.RLock()
+	entry, ok := f.entries[key]
+	f.mu.RUnlock()
+	if !ok {
+		return gpa.NewError(gpa.ErrorTypeNotFound, "no refresh loader registered for key: "+key)
+	}
+
+	value, err := entry.loader(ctx, key)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "refresh loader failed", err)
+	}
+	if err := f.repo.SetWithTTL(ctx, key, value, entry.ttl); err != nil {
+		return err
+	}
+
+	expiry := time.Now().Add(entry.ttl).UnixMilli()
+	return convertRedisError(f.repo.client.ZAdd(ctx, f.opts.ScheduleKey, &redis.Z{Score: float64(expiry), Member: key}).Err())
+}
+
+// lead returns how long before expiry a key with ttl is refreshed
+func (f *Refresher[T]) lead(ttl time.Duration) time.Duration {
+	if f.opts.Lead > 0 {
+		return f.opts.Lead
+	}
+	return ttl / 10
+}
+
+// maxLead returns the largest lead of all registered keys, bounding the due-key query
+func (f *Refresher[T]) maxLead() time.Duration {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var max time.Duration
+	for _, entry := range f.entries {
+		if lead := f.lead(entry.ttl); lead > max {
+			max = lead
+		}
+	}
+	return max
+}
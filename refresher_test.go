@@ -0,0 +1,70 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefresher(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var loads atomic.Int32
+	loader := func(ctx context.Context, key string) (*TestValue, error) {
+		n := loads.Add(1)
+		return &TestValue{ID: key, Age: int(n)}, nil
+	}
+
+	var failures []string
+	refresher := NewRefresher(repo, RefresherOptions{
+		Lead:    800 * time.Millisecond,
+		OnError: func(key string, err error) { failures = append(failures, key) },
+	})
+	require.NoError(t, refresher.Register(ctx, "config:flags", time.Second, loader))
+	assert.Equal(t, int32(1), loads.Load())
+
+	value, err := repo.Get(ctx, "config:flags")
+	require.NoError(t, err)
+	assert.Equal(t, 1, value.Age)
+
+	// Not yet within the lead window
+	n, err := refresher.RefreshDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	time.Sleep(300 * time.Millisecond)
+	n, err = refresher.RefreshDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	value, err = repo.Get(ctx, "config:flags")
+	require.NoError(t, err)
+	assert.Equal(t, 2, value.Age)
+
+	// Failing loaders are retried on the next tick
+	require.NoError(t, refresher.Register(ctx, "config:broken", time.Second, func(ctx context.Context, key string) (*TestValue, error) {
+		if loads.Load() > 2 {
+			return nil, errors.New("source down")
+		}
+		return loader(ctx, key)
+	}))
+	time.Sleep(300 * time.Millisecond)
+	_, err = refresher.RefreshDue(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, failures, "config:broken")
+	scheduled, err := repo.client.ZScore(ctx, refresher.opts.ScheduleKey, "config:broken").Result()
+	require.NoError(t, err)
+	assert.Greater(t, scheduled, float64(0))
+
+	require.NoError(t, refresher.Unregister(ctx, "config:broken"))
+	members, err := repo.client.ZRange(ctx, refresher.opts.ScheduleKey, 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config:flags"}, members)
+}
@@ -0,0 +1,384 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Prefix Rekeying
+// =====================================
+
+// RekeyOptions controls how RekeyPrefix moves keys
+type RekeyOptions struct {
+	// BatchSize is the SCAN COUNT hint and the number of keys moved per pipeline (default 100)
+	BatchSize int64
+	// Copy uses COPY instead of RENAME so values are duplicated before the source is unlinked
+	Copy bool
+	// KeepSource leaves the original keys in place when Copy is set (dual-read migrations)
+	KeepSource bool
+	// Overwrite replaces keys that already exist under the new prefix instead of skipping them
+	Overwrite bool
+	// OnProgress is called after every batch with the cumulative progress
+	OnProgress func(RekeyProgress)
+	// Adaptive, when set, replaces BatchSize with a size tuned from each pipeline's round trip
+	Adaptive *AdaptiveController
+}
+
+// RekeyProgress reports the cumulative state of a RekeyPrefix run
+type RekeyProgress struct {
+	Scanned int64
+	Moved   int64
+	Skipped int64
+	// Failed counts keys whose move failed; Failures holds their errors
+	Failed   int64
+	Failures []RekeyFailure
+	Batches  int64
+}
+
+// RekeyFailure is a key RekeyPrefix failed to move
+type RekeyFailure struct {
+	Key string
+	Err error
+}
+
+// RekeyPrefix moves every key starting with oldPrefix to the same key under newPrefix.
+// Keys are processed in SCAN batches so the server is never blocked for long, and each key
+// is moved atomically with RENAMENX (or COPY + UNLINK in copy mode). Keys whose target already
+// exists, or that vanish mid-batch, are skipped unless Overwrite is set. Keys that fail to move
+// are reported in Failures and the run continues; it then returns an error once done.
+// The bookkeeping of moved keys follows them: repositories owning the old keys drop their
+// index, retention and idle deadline entries, and repositories owning the new keys rebuild
+// them from the moved values, keeping the retention write times. Unique values stay taken
+// through the rebuilt tag sets.
+// Example: progress, err := provider.RekeyPrefix(ctx, "u:", "user:", gparedis.RekeyOptions{})
+func (p *Provider) RekeyPrefix(ctx context.Context, oldPrefix, newPrefix string, opts RekeyOptions) (RekeyProgress, error) {
+	var progress RekeyProgress
+	if oldPrefix == "" || oldPrefix == newPrefix {
+		return progress, gpa.NewError(gpa.ErrorTypeInvalidArgument, "old prefix must be non-empty and differ from new prefix")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = scanBatchSize
+	}
+
+	// When the new prefix extends the old one, already moved keys would match the scan again
+	nested := strings.HasPrefix(newPrefix, oldPrefix)
+
+	var cursor uint64
+	for {
+		batchSize := opts.BatchSize
+		if opts.Adaptive != nil {
+			batchSize = opts.Adaptive.BatchSize()
+		}
+		keys, next, err := p.client.Scan(ctx, cursor, EscapeGlob(oldPrefix)+"*", batchSize).Result()
+		if err != nil {
+			return progress, convertRedisError(err)
+		}
+
+		batch := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if nested && strings.HasPrefix(key, newPrefix) {
+				continue
+			}
+			batch = append(batch, key)
+		}
+
+		if len(batch) > 0 {
+			start := time.Now()
+			moved, failures, err := p.rekeyBatch(ctx, batch, oldPrefix, newPrefix, opts)
+			if opts.Adaptive != nil {
+				opts.Adaptive.Observe(int64(len(keys)), time.Since(start), err)
+			}
+			if err != nil {
+				return progress, err
+			}
+			if err := p.rekeyBookkeeping(ctx, moved, oldPrefix, newPrefix, opts); err != nil {
+				return progress, err
+			}
+			progress.Scanned += int64(len(batch))
+			progress.Moved += int64(len(moved))
+			progress.Failed += int64(len(failures))
+			progress.Skipped += int64(len(batch)-len(moved)) - int64(len(failures))
+			progress.Failures = append(progress.Failures, failures...)
+			progress.Batches++
+			if opts.OnProgress != nil {
+				opts.OnProgress(progress)
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	if progress.Failed > 0 {
+		first := progress.Failures[0]
+		return progress, gpa.NewErrorWithCause(gpa.ErrorTypeDatabase,
+			fmt.Sprintf("failed to move %d keys (first: %s)", progress.Failed, first.Key), first.Err)
+	}
+	return progress, nil
+}
+
+// rekeyBatch moves a single batch of keys in one pipeline and returns the old keys that were
+// moved and the keys that failed
+func (p *Provider) rekeyBatch(ctx context.Context, keys []string, oldPrefix, newPrefix string, opts RekeyOptions) ([]string, []RekeyFailure, error) {
+	cmds := make([]redis.Cmder, len(keys))
+	_, err := p.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			target := newPrefix + strings.TrimPrefix(key, oldPrefix)
+			switch {
+			case opts.Copy:
+				cmds[i] = pipe.Copy(ctx, key, target, p.db(), opts.Overwrite)
+			case opts.Overwrite:
+				cmds[i] = pipe.Rename(ctx, key, target)
+			default:
+				cmds[i] = pipe.RenameNX(ctx, key, target)
+			}
+		}
+		return nil
+	})
+	// Replies of individual keys are checked below; anything else failed the whole batch
+	var replyErr redis.Error
+	if err != nil && err != redis.Nil && !errors.As(err, &replyErr) {
+		return nil, nil, convertRedisError(err)
+	}
+
+	var moved, unlink []string
+	var failures []RekeyFailure
+	for i, cmd := range cmds {
+		if err := cmd.Err(); err != nil {
+			// A key that expired mid-batch has nothing left to move
+			if !isPerKeyError(err) {
+				failures = append(failures, RekeyFailure{Key: keys[i], Err: convertRedisError(err)})
+			}
+			continue
+		}
+		switch c := cmd.(type) {
+		case *redis.IntCmd:
+			if c.Val() == 1 {
+				moved = append(moved, keys[i])
+				unlink = append(unlink, keys[i])
+			}
+		case *redis.BoolCmd:
+			if c.Val() {
+				moved = append(moved, keys[i])
+			}
+		case *redis.StatusCmd:
+			moved = append(moved, keys[i])
+		}
+	}
+
+	if opts.Copy && !opts.KeepSource && len(unlink) > 0 {
+		if err := p.client.Unlink(ctx, unlink...).Err(); err != nil {
+			return moved, failures, convertRedisError(err)
+		}
+	}
+	return moved, failures, nil
+}
+
+// rekeySource is a repository whose bookkeeping follows keys RekeyPrefix moves, registered on
+// its provider
+type rekeySource struct {
+	// owns reports whether a full key under the repository prefix belongs to the repository
+	owns func(fullKey string) bool
+	// detach drops the index, unique claim, retention and idle deadline entries of full keys
+	// moved away to targets
+	detach func(ctx context.Context, fullKeys, targets []string) error
+	// attach claims the unique values of full keys moved in and rebuilds their entries from
+	// the stored values
+	attach func(ctx context.Context, fullKeys []string) error
+}
+
+// registerRekeySource makes a repository maintain the bookkeeping of keys moved under prefix.
+// The last repository registered for a prefix wins.
+func (p *Provider) registerRekeySource(prefix string, source rekeySource) {
+	p.rekeyMu.Lock()
+	defer p.rekeyMu.Unlock()
+	if p.rekeySources == nil {
+		p.rekeySources = make(map[string]rekeySource)
+	}
+	p.rekeySources[prefix] = source
+}
+
+// rekeyOwners groups full keys by the repository owning them: the longest registered prefix
+// of the key. Keys no repository owns are left out.
+func (p *Provider) rekeyOwners(fullKeys []string) map[string][]string {
+	p.rekeyMu.RLock()
+	defer p.rekeyMu.RUnlock()
+	owned := make(map[string][]string)
+	for _, key := range fullKeys {
+		best, found := "", false
+		for prefix, source := range p.rekeySources {
+			if strings.HasPrefix(key, prefix) && (!found || len(prefix) > len(best)) && source.owns(key) {
+				best, found = prefix, true
+			}
+		}
+		if found {
+			owned[best] = append(owned[best], key)
+		}
+	}
+	return owned
+}
+
+// rekeySource returns the source registered for prefix
+func (p *Provider) rekeySource(prefix string) rekeySource {
+	p.rekeyMu.RLock()
+	defer p.rekeyMu.RUnlock()
+	return p.rekeySources[prefix]
+}
+
+// rekeyBookkeeping moves the index, retention and idle deadline entries of moved keys to
+// their new keys
+func (p *Provider) rekeyBookkeeping(ctx context.Context, moved []string, oldPrefix, newPrefix string, opts RekeyOptions) error {
+	if len(moved) == 0 {
+		return nil
+	}
+	targets := make([]string, len(moved))
+	for i, key := range moved {
+		targets[i] = newPrefix + strings.TrimPrefix(key, oldPrefix)
+	}
+
+	// Read the write times first: detaching the old keys drops them
+	writtenAt, err := p.retentionScores(ctx, moved)
+	if err != nil {
+		return err
+	}
+	if !opts.Copy || !opts.KeepSource {
+		targetOf := make(map[string]string, len(moved))
+		for i, key := range moved {
+			targetOf[key] = targets[i]
+		}
+		owned := p.rekeyOwners(moved)
+		for _, owner := range sortedKeys(owned) {
+			to := make([]string, len(owned[owner]))
+			for i, key := range owned[owner] {
+				to[i] = targetOf[key]
+			}
+			if err := p.rekeySource(owner).detach(ctx, owned[owner], to); err != nil {
+				return err
+			}
+		}
+		if err := p.untrackRetention(ctx, moved); err != nil {
+			return err
+		}
+	}
+	owned := p.rekeyOwners(targets)
+	for _, owner := range sortedKeys(owned) {
+		if err := p.rekeySource(owner).attach(ctx, owned[owner]); err != nil {
+			return err
+		}
+	}
+
+	// Keep the write times the moved keys had, so MaxAge still counts from the last write
+	byIndex := make(map[string][]*redis.Z)
+	for i, target := range targets {
+		score, ok := writtenAt[moved[i]]
+		if !ok {
+			continue
+		}
+		if prefix, policy, ok := p.retentionFor(target); ok && policy.MaxAge > 0 {
+			index := retentionIndexKey(prefix)
+			byIndex[index] = append(byIndex[index], &redis.Z{Score: score, Member: target})
+		}
+	}
+	if len(byIndex) == 0 {
+		return nil
+	}
+	_, err = p.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for index, members := range byIndex {
+			pipe.ZAdd(ctx, index, members...)
+		}
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// registerRekey lets RekeyPrefix maintain the repository's bookkeeping of moved keys
+func (r *Repository[T]) registerRekey() {
+	if r.provider == nil {
+		return
+	}
+	r.provider.registerRekeySource(r.keyPrefix, rekeySource{
+		owns: r.ownsKey,
+		detach: func(ctx context.Context, fullKeys, targets []string) error {
+			keys := r.trimKeys(fullKeys)
+			if r.hasUniqueIndexes() {
+				// The values now live at the targets; read them there to find the claims
+				entities, _, err := r.loadMoved(ctx, keys, targets)
+				if err != nil {
+					return err
+				}
+				if err := r.dropClaims(ctx, entities); err != nil {
+					return err
+				}
+			}
+			return r.afterDelete(ctx, keys)
+		},
+		attach: func(ctx context.Context, fullKeys []string) error {
+			entities, missing, err := r.loadMoved(ctx, r.trimKeys(fullKeys), fullKeys)
+			if err != nil {
+				return err
+			}
+			if err := r.afterDelete(ctx, missing); err != nil {
+				return err
+			}
+			// Values already held by another key (duplicates across the prefixes) stay unclaimed
+			if err := r.claimUnique(ctx, entities); err != nil && !gpa.IsErrorType(err, gpa.ErrorTypeDuplicate) {
+				return err
+			}
+			return r.afterSet(ctx, entities, 0)
+		},
+	})
+}
+
+// loadMoved decodes the entities stored at fullKeys, keyed by keys. Keys whose value is gone
+// are returned as missing; values that aren't entities (counters, other types) are left out.
+func (r *Repository[T]) loadMoved(ctx context.Context, keys, fullKeys []string) (map[string]*T, []string, error) {
+	values, err := mgetGroups(ctx, r.client, fullKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	entities := make(map[string]*T, len(keys))
+	var missing []string
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			missing = append(missing, keys[i])
+			continue
+		}
+		if entity, err := r.decode([]byte(data)); err == nil {
+			entities[keys[i]] = entity
+		}
+	}
+	return entities, missing, nil
+}
+
+// db returns the database number the provider is connected to
+func (p *Provider) db() int {
+	return p.client.Options().DB
+}
+
+// isPerKeyError reports whether a pipeline error only concerns a single key
+func isPerKeyError(err error) bool {
+	return strings.HasPrefix(err.Error(), "ERR no such key")
+}
+
+// EscapeGlob escapes glob metacharacters so s is matched literally by SCAN/KEYS, e.g. when
+// building a pattern from user input: repo.Keys(ctx, gparedis.EscapeGlob(userID)+":*")
+func EscapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,176 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRekeyPrefix(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 25; i++ {
+		key := fmt.Sprintf("u:%d", i)
+		require.NoError(t, repo.Set(ctx, key, &TestValue{ID: key, Age: i}))
+	}
+	// A conflicting key that must not be overwritten
+	require.NoError(t, repo.Set(ctx, "user:0", &TestValue{ID: "existing"}))
+
+	var reports []RekeyProgress
+	progress, err := repo.provider.RekeyPrefix(ctx, "u:", "user:", RekeyOptions{
+		BatchSize:  10,
+		OnProgress: func(p RekeyProgress) { reports = append(reports, p) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(25), progress.Scanned)
+	assert.Equal(t, int64(24), progress.Moved)
+	assert.Equal(t, int64(1), progress.Skipped)
+	assert.NotEmpty(t, reports)
+
+	existing, err := repo.Get(ctx, "user:0")
+	require.NoError(t, err)
+	assert.Equal(t, "existing", existing.ID)
+
+	moved, err := repo.Get(ctx, "user:7")
+	require.NoError(t, err)
+	assert.Equal(t, 7, moved.Age)
+
+	exists, err := repo.KeyExists(ctx, "u:7")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestRekeyPrefixCopyNested(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("cfg:%d", i)
+		require.NoError(t, repo.Set(ctx, key, &TestValue{ID: key}))
+	}
+
+	progress, err := repo.provider.RekeyPrefix(ctx, "cfg:", "cfg:v2:", RekeyOptions{Copy: true, KeepSource: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), progress.Moved)
+
+	keys, err := repo.Keys(ctx, "cfg:*")
+	require.NoError(t, err)
+	assert.Len(t, keys, 10)
+}
+
+func TestRekeyPrefixInvalid(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	_, err := repo.provider.RekeyPrefix(context.Background(), "", "new:", RekeyOptions{})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestEscapeGlob(t *testing.T) {
+	assert.Equal(t, `a\*b\?c\[d\]`, EscapeGlob("a*b?c[d]"))
+	assert.Equal(t, "plain:", EscapeGlob("plain:"))
+}
+
+func TestRekeyPrefixMovesBookkeeping(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	p := base.provider
+	require.NoError(t, p.SetRetentionPolicy("rk:", RetentionPolicy{MaxAge: time.Hour}))
+	oldUsers := NewRepository[indexedUser](p, base.client, "rk:old:")
+	newUsers := NewRepository[indexedUser](p, base.client, "rk:new:")
+	require.NoError(t, oldUsers.Set(ctx, "1", &indexedUser{ID: "1", Email: "ada@example.com", Status: "active"}))
+	require.NoError(t, oldUsers.Set(ctx, "2", &indexedUser{ID: "2", Email: "alan@example.com", Status: "active"}))
+	writtenAt, err := base.client.ZScore(ctx, retentionIndexKey("rk:"), "rk:old:1").Result()
+	require.NoError(t, err)
+
+	progress, err := p.RekeyPrefix(ctx, "rk:old:", "rk:new:", RekeyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), progress.Moved)
+
+	// Indexes and unique values follow the keys
+	keys, err := newUsers.KeysByIndex(ctx, "status", "active")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, keys)
+	key, ok, err := newUsers.KeyByUnique(ctx, "email", "ada@example.com")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", key)
+	err = newUsers.Set(ctx, "3", &indexedUser{ID: "3", Email: "ada@example.com"})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+
+	// Nothing is left under the old prefix
+	keys, err = oldUsers.KeysByIndex(ctx, "status", "active")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	left, err := base.client.Keys(ctx, indexKeyPrefix+"rk:old:*").Result()
+	require.NoError(t, err)
+	assert.Empty(t, left)
+	owner, err := base.client.HGet(ctx, newUsers.uniqueHashKey("email"), "ada@example.com").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "1", owner)
+
+	// Retention write times move along
+	_, err = base.client.ZScore(ctx, retentionIndexKey("rk:"), "rk:old:1").Result()
+	assert.Error(t, err)
+	moved, err := base.client.ZScore(ctx, retentionIndexKey("rk:"), "rk:new:1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, writtenAt, moved)
+}
+
+// failRenameHook makes the rename of one key fail with an error reply
+type failRenameHook struct{ key string }
+
+func (failRenameHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (failRenameHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h failRenameHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		if args := cmd.Args(); cmd.Name() == "renamenx" && args[1] == h.key {
+			args[0] = "renamenx-broken"
+		}
+	}
+	return ctx, nil
+}
+
+func (failRenameHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestRekeyPrefixReportsFailures(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("rkf:%d", i)
+		require.NoError(t, repo.Set(ctx, key, &TestValue{ID: key}))
+	}
+	client := redis.NewClient(repo.client.Options())
+	defer client.Close()
+	client.AddHook(failRenameHook{key: "rkf:1"})
+	p := &Provider{client: client}
+
+	progress, err := p.RekeyPrefix(ctx, "rkf:", "rkf2:", RekeyOptions{})
+	require.Error(t, err)
+	assert.Equal(t, int64(2), progress.Moved)
+	assert.Equal(t, int64(0), progress.Skipped)
+	assert.Equal(t, int64(1), progress.Failed)
+	require.Len(t, progress.Failures, 1)
+	assert.Equal(t, "rkf:1", progress.Failures[0].Key)
+}
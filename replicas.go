@@ -0,0 +1,291 @@
+package gparedis
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Node Health Probing and Replica Reads
+// =====================================
+
+// Node roles reported by NodeHealth
+const (
+	NodeRolePrimary = "primary"
+	NodeRoleReplica = "replica"
+)
+
+// ProbeOptions configures node health probing
+type ProbeOptions struct {
+	// Interval between probe rounds (default 1s)
+	Interval time.Duration
+	// Timeout of each PING (default 500ms); timeouts count as errors
+	Timeout time.Duration
+	// Samples is the number of recent probes kept per node (default 100)
+	Samples int
+	// MaxErrorRate marks nodes whose recent probes failed more often as unhealthy (default 0.2)
+	MaxErrorRate float64
+	// MaxP99 marks nodes whose recent p99 latency is higher as unhealthy (0 disables)
+	MaxP99 time.Duration
+}
+
+// withDefaults fills in unset fields
+func (o ProbeOptions) withDefaults() ProbeOptions {
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 500 * time.Millisecond
+	}
+	if o.Samples <= 0 {
+		o.Samples = 100
+	}
+	if o.MaxErrorRate <= 0 {
+		o.MaxErrorRate = 0.2
+	}
+	return o
+}
+
+// NodeHealth is the probed state of one node
+type NodeHealth struct {
+	Addr string
+	Role string
+	// Healthy is false until the node has been probed successfully
+	Healthy   bool
+	Probes    int
+	P99       time.Duration
+	ErrorRate float64
+	LastError string
+	LastProbe time.Time
+}
+
+// probeSample is the outcome of one PING
+type probeSample struct {
+	latency time.Duration
+	failed  bool
+}
+
+// nodeStats keeps the recent probe samples of a node in a ring buffer
+type nodeStats struct {
+	mu        sync.Mutex
+	samples   []probeSample
+	next      int
+	lastError string
+	lastProbe time.Time
+}
+
+// record adds a probe outcome, keeping at most size samples
+func (s *nodeStats) record(sample probeSample, err error, size int, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.samples) < size {
+		s.samples = append(s.samples, sample)
+	} else {
+		s.samples[s.next%len(s.samples)] = sample
+	}
+	s.next++
+	s.lastProbe = now
+	if err != nil {
+		s.lastError = err.Error()
+	}
+}
+
+// health summarizes the samples under opts
+func (s *nodeStats) health(addr, role string, opts ProbeOptions) NodeHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := NodeHealth{Addr: addr, Role: role, Probes: len(s.samples), LastError: s.lastError, LastProbe: s.lastProbe}
+	if len(s.samples) == 0 {
+		return h
+	}
+
+	var latencies []time.Duration
+	failures := 0
+	for _, sample := range s.samples {
+		if sample.failed {
+			failures++
+			continue
+		}
+		latencies = append(latencies, sample.latency)
+	}
+	h.ErrorRate = float64(failures) / float64(len(s.samples))
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		h.P99 = latencies[(len(latencies)*99+99)/100-1]
+	}
+	h.Healthy = len(latencies) > 0 && h.ErrorRate <= opts.MaxErrorRate && (opts.MaxP99 <= 0 || h.P99 <= opts.MaxP99)
+	return h
+}
+
+// replicaNode is a read replica and its probe statistics
+type replicaNode struct {
+	addr   string
+	client *redis.Client
+	stats  nodeStats
+}
+
+// AddReplica connects a read replica at addr using the primary's connection settings.
+// Replicas serve reads of repositories created with WithReplicaReads once the health
+// prober has found them healthy.
+// Example: err := provider.AddReplica("redis-replica-1:6379")
+func (p *Provider) AddReplica(addr string) error {
+	opts := *p.client.Options()
+	opts.Addr = addr
+	client := redis.NewClient(&opts)
+	p.addClientHooks(client)
+
+	p.nodesMu.Lock()
+	defer p.nodesMu.Unlock()
+	for _, node := range p.replicas {
+		if node.addr == addr {
+			client.Close()
+			return gpa.NewError(gpa.ErrorTypeDuplicate, "replica "+addr+" is already registered")
+		}
+	}
+	p.replicas = append(p.replicas, &replicaNode{addr: addr, client: client})
+	return nil
+}
+
+// Replicas returns the addresses of the registered replicas
+func (p *Provider) Replicas() []string {
+	p.nodesMu.RLock()
+	defer p.nodesMu.RUnlock()
+	addrs := make([]string, len(p.replicas))
+	for i, node := range p.replicas {
+		addrs[i] = node.addr
+	}
+	return addrs
+}
+
+// closeReplicas closes every replica client
+func (p *Provider) closeReplicas() error {
+	p.nodesMu.Lock()
+	defer p.nodesMu.Unlock()
+	var firstErr error
+	for _, node := range p.replicas {
+		if err := node.client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	p.replicas = nil
+	p.readReplica.Store(nil)
+	return firstErr
+}
+
+// SetProbeOptions sets the thresholds used by ProbeNodes and RunHealthProbe
+func (p *Provider) SetProbeOptions(opts ProbeOptions) {
+	p.nodesMu.Lock()
+	defer p.nodesMu.Unlock()
+	p.probeOpts = opts.withDefaults()
+	p.selectReadReplicaLocked()
+}
+
+// probeOptions returns the configured probe options
+func (p *Provider) probeOptions() ProbeOptions {
+	p.nodesMu.RLock()
+	defer p.nodesMu.RUnlock()
+	return p.probeOpts.withDefaults()
+}
+
+// ProbeNodes pings the primary and every replica once, concurrently
+func (p *Provider) ProbeNodes(ctx context.Context) {
+	opts := p.probeOptions()
+	p.nodesMu.RLock()
+	replicas := append([]*replicaNode(nil), p.replicas...)
+	p.nodesMu.RUnlock()
+
+	var wg sync.WaitGroup
+	probe := func(client *redis.Client, stats *nodeStats) {
+		defer wg.Done()
+		pingCtx, cancel := context.WithTimeout(withoutMetrics(ctx), opts.Timeout)
+		defer cancel()
+		start := time.Now()
+		err := client.Ping(pingCtx).Err()
+		stats.record(probeSample{latency: time.Since(start), failed: err != nil}, err, opts.Samples, time.Now())
+	}
+	wg.Add(1 + len(replicas))
+	go probe(p.client, &p.primaryStats)
+	for _, node := range replicas {
+		go probe(node.client, &node.stats)
+	}
+	wg.Wait()
+
+	p.nodesMu.Lock()
+	p.selectReadReplicaLocked()
+	p.nodesMu.Unlock()
+}
+
+// RunHealthProbe probes all nodes every opts.Interval until ctx is cancelled. The results
+// drive replica selection for WithReplicaReads repositories: unhealthy and slow replicas
+// are avoided automatically.
+// Example: go provider.RunHealthProbe(ctx, gparedis.ProbeOptions{MaxP99: 20 * time.Millisecond})
+func (p *Provider) RunHealthProbe(ctx context.Context, opts ProbeOptions) error {
+	p.SetProbeOptions(opts)
+	opts = p.probeOptions()
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		p.ProbeNodes(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// NodeHealth returns the probed state of the primary followed by every replica
+func (p *Provider) NodeHealth() []NodeHealth {
+	opts := p.probeOptions()
+	health := []NodeHealth{p.primaryStats.health(p.client.Options().Addr, NodeRolePrimary, opts)}
+	p.nodesMu.RLock()
+	defer p.nodesMu.RUnlock()
+	for _, node := range p.replicas {
+		health = append(health, node.stats.health(node.addr, NodeRoleReplica, opts))
+	}
+	return health
+}
+
+// selectReadReplicaLocked picks the healthy replica with the lowest p99 latency for reads;
+// callers hold nodesMu
+func (p *Provider) selectReadReplicaLocked() {
+	opts := p.probeOpts.withDefaults()
+	var best *redis.Client
+	var bestP99 time.Duration
+	for _, node := range p.replicas {
+		h := node.stats.health(node.addr, NodeRoleReplica, opts)
+		if h.Healthy && (best == nil || h.P99 < bestP99) {
+			best, bestP99 = node.client, h.P99
+		}
+	}
+	p.readReplica.Store(best)
+}
+
+// readClient returns the selected replica, or the primary when no replica is healthy
+func (p *Provider) readClient() *redis.Client {
+	if replica := p.readReplica.Load(); replica != nil {
+		return replica
+	}
+	return p.client
+}
+
+// WithReplicaReads serves the repository's Get, MGet and KeyExists from the healthiest
+// replica, accepting replication lag. Writes and all other operations use the primary.
+func WithReplicaReads() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.replicaReads = true
+	}
+}
+
+// reader returns the client the repository reads from; the primary for strong reads
+func (r *Repository[T]) reader(ctx context.Context) *redis.Client {
+	if !r.opts.replicaReads || r.provider == nil || r.client != r.provider.client || strongReads(ctx) {
+		return r.client
+	}
+	return r.provider.readClient()
+}
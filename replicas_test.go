@@ -0,0 +1,80 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaReads(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+	defer repo.provider.closeReplicas()
+
+	ctx := context.Background()
+	p := repo.provider
+	require.NoError(t, p.AddReplica("127.0.0.1:1"))
+	require.NoError(t, p.AddReplica("127.0.0.1:6379"))
+	err := p.AddReplica("127.0.0.1:6379")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+	assert.Equal(t, []string{"127.0.0.1:1", "127.0.0.1:6379"}, p.Replicas())
+
+	// Unprobed replicas are not used
+	assert.Same(t, p.client, p.readClient())
+
+	p.SetProbeOptions(ProbeOptions{Timeout: 200 * time.Millisecond})
+	p.ProbeNodes(ctx)
+
+	health := p.NodeHealth()
+	require.Len(t, health, 3)
+	assert.Equal(t, NodeRolePrimary, health[0].Role)
+	assert.True(t, health[0].Healthy)
+	assert.False(t, health[1].Healthy)
+	assert.Equal(t, 1.0, health[1].ErrorRate)
+	assert.NotEmpty(t, health[1].LastError)
+	assert.True(t, health[2].Healthy)
+	assert.Equal(t, 1, health[2].Probes)
+
+	replica := p.replicas[1].client
+	assert.Same(t, replica, p.readClient())
+
+	users := NewRepository[TestValue](p, repo.client, "replica:user:", WithReplicaReads())
+	require.NoError(t, users.Set(ctx, "1", &TestValue{ID: "1"}))
+	defer users.DeleteKey(ctx, "1")
+	assert.Same(t, replica, users.reader(ctx))
+	assert.Same(t, repo.client, users.reader(withStrongReads(ctx)), "strong reads go to the primary")
+	value, err := users.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", value.ID)
+
+	// Repositories without the option keep reading from the primary
+	assert.Same(t, repo.client, repo.reader(ctx))
+
+	// A latency ceiling no replica meets sends reads back to the primary
+	p.SetProbeOptions(ProbeOptions{MaxP99: time.Nanosecond})
+	assert.Same(t, p.client, p.readClient())
+}
+
+func TestNodeStatsHealth(t *testing.T) {
+	var stats nodeStats
+	opts := ProbeOptions{Samples: 4}.withDefaults()
+	now := time.Now()
+	for _, ms := range []int{5, 1, 3, 2, 4} {
+		stats.record(probeSample{latency: time.Duration(ms) * time.Millisecond}, nil, opts.Samples, now)
+	}
+	h := stats.health("a", NodeRoleReplica, opts)
+	assert.Equal(t, 4, h.Probes, "the oldest sample was replaced")
+	assert.Equal(t, 4*time.Millisecond, h.P99)
+	assert.True(t, h.Healthy)
+
+	stats.record(probeSample{failed: true}, errors.New("timeout"), opts.Samples, now)
+	h = stats.health("a", NodeRoleReplica, opts)
+	assert.Equal(t, 0.25, h.ErrorRate)
+	assert.False(t, h.Healthy)
+	assert.Equal(t, "timeout", h.LastError)
+}
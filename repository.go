@@ -5,6 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -21,26 +23,98 @@ type Repository[T any] struct {
 	provider  *Provider
 	client    *redis.Client
 	keyPrefix string
+	opts      repositoryOptions
+	near      *NearCache
+	quota     *quotaState
+	degraded  *degradedWrites[T]
 }
 
 // NewRepository creates a new generic Redis repository for type T.
+// Non-empty prefixes are declared in the provider's key schema registry; in strict
+// mode a colliding prefix panics, use DeclareRepository to handle the error instead.
 // Example: userRepo := NewRepository[User](provider, client, "user:")
-func NewRepository[T any](provider *Provider, client *redis.Client, keyPrefix string) *Repository[T] {
-	return &Repository[T]{
+func NewRepository[T any](provider *Provider, client *redis.Client, keyPrefix string, opts ...RepositoryOption) *Repository[T] {
+	keyPrefix = taggedPrefix(keyPrefix, opts)
+	if provider != nil && keyPrefix != "" {
+		if err := provider.KeySchemas().Declare(keyPrefix, reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+			panic(err)
+		}
+	}
+	return newRepository[T](provider, client, keyPrefix, opts)
+}
+
+// newRepository creates a repository without touching the key schema registry
+func newRepository[T any](provider *Provider, client *redis.Client, keyPrefix string, opts []RepositoryOption) *Repository[T] {
+	r := &Repository[T]{
 		provider:  provider,
 		client:    client,
 		keyPrefix: keyPrefix,
 	}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	if p := r.opts.degradation; p != nil {
+		r.degraded = &degradedWrites[T]{writes: make(map[string]pendingWrite[T])}
+		if p.mode(OperationRead) == DegradeServeStale {
+			r.opts.nearCache = p.staleReads(r.opts.nearCache)
+		}
+	}
+	if r.opts.nearCache != nil {
+		r.near = newNearCache(*r.opts.nearCache)
+		if path := r.opts.nearCache.SnapshotPath; path != "" {
+			// A missing or unreadable snapshot only means a cold start
+			_, _ = r.near.LoadFile(path)
+		}
+		if provider != nil {
+			provider.registerNearCache(keyPrefix, r.near)
+		}
+	}
+	if r.opts.quota != nil {
+		r.quota = &quotaState{}
+	}
+	r.registerSubjectIndexes()
+	r.registerReferences()
+	r.registerRetention()
+	r.registerRekey()
+	r.registerSLO()
+	return r
 }
 
 // buildKey creates a full key with the prefix
 func (r *Repository[T]) buildKey(key string) string {
+	if r.opts.escapeSeparator {
+		key = separatorEscaper.Replace(key)
+	}
+	if r.opts.keyEncoding != nil {
+		key = r.opts.keyEncoding.encodeKey(key)
+	}
+	if r.opts.segmentHashTag {
+		key = tagSegment(key, r.opts.hashTagSegment)
+	}
 	if r.keyPrefix == "" {
 		return key
 	}
 	return r.keyPrefix + key
 }
 
+// trimKey removes the repository prefix from a full key
+func (r *Repository[T]) trimKey(fullKey string) string {
+	key := fullKey
+	if r.keyPrefix != "" {
+		key = strings.TrimPrefix(fullKey, r.keyPrefix)
+	}
+	if r.opts.segmentHashTag {
+		key = untagSegment(key, r.opts.hashTagSegment)
+	}
+	if r.opts.keyEncoding != nil {
+		key = decodeKey(key)
+	}
+	if r.opts.escapeSeparator {
+		key = separatorUnescaper.Replace(key)
+	}
+	return key
+}
+
 // =====================================
 // BasicKeyValueRepositoryG Implementation
 // =====================================
@@ -49,7 +123,10 @@ func (r *Repository[T]) buildKey(key string) string {
 // Returns the value directly without requiring a destination parameter.
 func (r *Repository[T]) Get(ctx context.Context, key string) (*T, error) {
 	fullKey := r.buildKey(key)
-	result := r.client.Get(ctx, fullKey)
+	if err := r.authorize(ctx, AccessRead, fullKey); err != nil {
+		return nil, err
+	}
+	result := r.nearGet(ctx, key, fullKey)
 	if err := result.Err(); err != nil {
 		if err == redis.Nil {
 			return nil, gpa.GPAError{
@@ -65,24 +142,20 @@ func (r *Repository[T]) Get(ctx context.Context, key string) (*T, error) {
 		return nil, convertRedisError(err)
 	}
 
-	var entity T
-	if err := json.Unmarshal(data, &entity); err != nil {
-		return nil, gpa.GPAError{
-			Type:    gpa.ErrorTypeSerialization,
-			Message: "failed to deserialize value",
-			Cause:   err,
-		}
+	entity, err := r.decode(data)
+	if err != nil {
+		return nil, err
 	}
 
 	// Execute after find hook
-	if hook, ok := any(&entity).(gpa.AfterFindHook); ok {
+	if hook, ok := any(entity).(gpa.AfterFindHook); ok {
 		if err := hook.AfterFind(ctx); err != nil {
 			// Log error but don't fail the operation
 			// log.Printf("after find hook failed: %v", err)
 		}
 	}
 
-	return &entity, nil
+	return entity, nil
 }
 
 // Set stores a value with compile-time type safety.
@@ -93,6 +166,17 @@ func (r *Repository[T]) Set(ctx context.Context, key string, value *T) error {
 
 // DeleteKey removes a key-value pair.
 func (r *Repository[T]) DeleteKey(ctx context.Context, key string) error {
+	return r.degradeWrite(ctx, OperationDelete, []string{key}, func() error {
+		return r.deleteKey(ctx, key)
+	}, func() map[string]pendingWrite[T] { return pendingDeletes[T]([]string{key}) })
+}
+
+// deleteKey removes key, without the degradation policy
+func (r *Repository[T]) deleteKey(ctx context.Context, key string) error {
+	if err := r.authorizeKeys(ctx, AccessDelete, key); err != nil {
+		return err
+	}
+
 	// First, try to get the entity to run hooks on it
 	entity, err := r.Get(ctx, key)
 	if err != nil {
@@ -118,9 +202,21 @@ func (r *Repository[T]) DeleteKey(ctx context.Context, key string) error {
 		}
 	}
 
-	fullKey := r.buildKey(key)
-	result := r.client.Del(ctx, fullKey)
-	if err := convertRedisError(result.Err()); err != nil {
+	if r.opts.changeStream != "" {
+		if _, err := r.captureWrite(ctx, ChangeOpDelete, []string{key}, nil, nil); err != nil {
+			return err
+		}
+	} else {
+		fullKey := r.buildKey(key)
+		result := r.client.Del(ctx, fullKey)
+		if err := convertRedisError(result.Err()); err != nil {
+			return err
+		}
+	}
+	if err := r.afterDelete(ctx, []string{key}); err != nil {
+		return err
+	}
+	if err := r.recordWrite(ctx, "delete", key); err != nil {
 		return err
 	}
 
@@ -140,7 +236,10 @@ func (r *Repository[T]) DeleteKey(ctx context.Context, key string) error {
 // KeyExists checks if a key exists in the store.
 func (r *Repository[T]) KeyExists(ctx context.Context, key string) (bool, error) {
 	fullKey := r.buildKey(key)
-	result := r.client.Exists(ctx, fullKey)
+	if err := r.authorize(ctx, AccessRead, fullKey); err != nil {
+		return false, err
+	}
+	result := r.reader(ctx).Exists(ctx, fullKey)
 	if err := result.Err(); err != nil {
 		return false, convertRedisError(err)
 	}
@@ -163,13 +262,14 @@ func (r *Repository[T]) MGet(ctx context.Context, keys []string) (map[string]*T,
 	for i, key := range keys {
 		fullKeys[i] = r.buildKey(key)
 	}
-
-	result := r.client.MGet(ctx, fullKeys...)
-	if err := result.Err(); err != nil {
-		return nil, convertRedisError(err)
+	if err := r.authorize(ctx, AccessRead, fullKeys...); err != nil {
+		return nil, err
 	}
 
-	values := result.Val()
+	values, err := r.nearMGet(ctx, keys, fullKeys)
+	if err != nil {
+		return nil, err
+	}
 	entities := make(map[string]*T)
 
 	for i, value := range values {
@@ -183,60 +283,195 @@ func (r *Repository[T]) MGet(ctx context.Context, keys []string) (map[string]*T,
 			return nil, gpa.NewError(gpa.ErrorTypeSerialization, "unexpected value type from Redis")
 		}
 
-		var entity T
-		if err := json.Unmarshal([]byte(data), &entity); err != nil {
-			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize value", err)
+		entity, err := r.decode([]byte(data))
+		if err != nil {
+			return nil, err
 		}
 
-		entities[keys[i]] = &entity
+		entities[keys[i]] = entity
 	}
 
 	return entities, nil
 }
 
+// getValue reads one full key from Redis
+func (r *Repository[T]) getValue(ctx context.Context, fullKey string) *redis.StringCmd {
+	if r.opts.idle != nil {
+		return r.getIdle(ctx, fullKey)
+	}
+	return r.reader(ctx).Get(ctx, fullKey)
+}
+
+// mgetValues reads full keys from Redis in the shape of an MGET reply
+func (r *Repository[T]) mgetValues(ctx context.Context, fullKeys []string) ([]interface{}, error) {
+	if r.opts.idle != nil {
+		return r.touchIdle(ctx, fullKeys)
+	}
+	if r.splitsBySlot(fullKeys) {
+		return r.mgetBySlot(ctx, fullKeys)
+	}
+	result := r.reader(ctx).MGet(ctx, fullKeys...)
+	if err := result.Err(); err != nil {
+		return nil, convertRedisError(err)
+	}
+	return result.Val(), nil
+}
+
 // MSet stores multiple key-value pairs with compile-time type safety.
+// Returns ErrorTypeDuplicate without writing anything when a unique index value is taken.
 func (r *Repository[T]) MSet(ctx context.Context, pairs map[string]*T) error {
+	return r.degradeWrite(ctx, OperationWrite, sortedKeys(pairs), func() error {
+		return r.mset(ctx, pairs)
+	}, func() map[string]pendingWrite[T] { return pendingSets(pairs, 0) })
+}
+
+// mset stores pairs, without the degradation policy
+func (r *Repository[T]) mset(ctx context.Context, pairs map[string]*T) error {
 	if len(pairs) == 0 {
 		return nil
 	}
+	keys := sortedKeys(pairs)
+	if err := r.authorizeKeys(ctx, AccessWrite, keys...); err != nil {
+		return err
+	}
+	if err := r.checkQuota(ctx); err != nil {
+		return err
+	}
+	if err := r.claimUnique(ctx, pairs); err != nil {
+		return err
+	}
+
+	var err error
+	if r.opts.changeStream != "" || r.hasRetention(keys) {
+		err = r.msetWithTTLs(ctx, pairs)
+	} else {
+		err = r.msetPlain(ctx, pairs)
+	}
+	if err != nil {
+		return r.abandonClaims(ctx, keys, err)
+	}
+	if err := r.afterSet(ctx, pairs, 0); err != nil {
+		return err
+	}
+	return r.recordWrite(ctx, "mset", keys...)
+}
 
+// msetPlain writes pairs with MSET, one per slot in cluster mode
+func (r *Repository[T]) msetPlain(ctx context.Context, pairs map[string]*T) error {
 	// Convert to Redis format
 	redisPairs := make([]interface{}, 0, len(pairs)*2)
+	fullKeys := make([]string, 0, len(pairs))
+	payloads := make(map[string][]byte, len(pairs))
 	for key, value := range pairs {
 		fullKey := r.buildKey(key)
 		
-		data, err := json.Marshal(value)
+		data, err := r.encode(value)
 		if err != nil {
-			return gpa.GPAError{
-				Type:    gpa.ErrorTypeSerialization,
-				Message: "failed to serialize value",
-				Cause:   err,
-			}
+			return err
 		}
 
 		redisPairs = append(redisPairs, fullKey, data)
+		fullKeys = append(fullKeys, fullKey)
+		payloads[fullKey] = data
 	}
 
-	result := r.client.MSet(ctx, redisPairs...)
-	return convertRedisError(result.Err())
+	if r.splitsBySlot(fullKeys) {
+		return r.msetBySlot(ctx, fullKeys, payloads)
+	}
+	return convertRedisError(r.client.MSet(ctx, redisPairs...).Err())
+}
+
+// msetWithTTLs writes pairs atomically with per-key retention TTLs, capturing changes when enabled
+func (r *Repository[T]) msetWithTTLs(ctx context.Context, pairs map[string]*T) error {
+	keys := sortedKeys(pairs)
+	payloads := make([][]byte, len(keys))
+	ttls := make([]time.Duration, len(keys))
+	for i, key := range keys {
+		data, err := r.encode(pairs[key])
+		if err != nil {
+			return err
+		}
+		payloads[i] = data
+		ttls[i] = r.retentionTTL(key, 0)
+	}
+
+	if r.opts.changeStream != "" {
+		_, err := r.captureWrite(ctx, ChangeOpSet, keys, payloads, ttls)
+		return err
+	}
+	if fullKeys := r.buildKeys(keys); r.splitsBySlot(fullKeys) {
+		// MULTI can't span slots in cluster mode, so each slot's keys are written separately
+		return r.setBySlot(ctx, fullKeys, payloads, ttls)
+	}
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			pipe.Set(ctx, r.buildKey(key), payloads[i], ttls[i])
+		}
+		return nil
+	})
+	return convertRedisError(err)
 }
 
 // MDelete removes multiple keys in a single operation.
+// Returns 0 deleted keys when the degradation policy skips or defers the delete.
 func (r *Repository[T]) MDelete(ctx context.Context, keys []string) (int64, error) {
+	var deleted int64
+	err := r.degradeWrite(ctx, OperationDelete, keys, func() error {
+		var err error
+		deleted, err = r.mdelete(ctx, keys)
+		return err
+	}, func() map[string]pendingWrite[T] { return pendingDeletes[T](keys) })
+	return deleted, err
+}
+
+// mdelete removes keys, without the degradation policy
+func (r *Repository[T]) mdelete(ctx context.Context, keys []string) (int64, error) {
 	if len(keys) == 0 {
 		return 0, nil
 	}
-
-	fullKeys := make([]string, len(keys))
-	for i, key := range keys {
-		fullKeys[i] = r.buildKey(key)
+	if err := r.authorizeKeys(ctx, AccessDelete, keys...); err != nil {
+		return 0, err
 	}
+	return r.removeKeys(ctx, keys, "delete")
+}
 
-	result := r.client.Del(ctx, fullKeys...)
-	if err := result.Err(); err != nil {
-		return 0, convertRedisError(err)
+// removeKeys deletes keys with the bookkeeping of a delete (change capture, indexes, near
+// cache, deadlines and the audit entry op), without the access check
+func (r *Repository[T]) removeKeys(ctx context.Context, keys []string, op string) (int64, error) {
+	var deleted int64
+	if r.opts.changeStream != "" {
+		n, err := r.captureWrite(ctx, ChangeOpDelete, keys, nil, nil)
+		if err != nil {
+			return 0, err
+		}
+		deleted = n
+	} else {
+		fullKeys := make([]string, len(keys))
+		for i, key := range keys {
+			fullKeys[i] = r.buildKey(key)
+		}
+
+		if r.splitsBySlot(fullKeys) {
+			n, err := r.delBySlot(ctx, fullKeys)
+			if err != nil {
+				return 0, err
+			}
+			deleted = n
+		} else {
+			result := r.client.Del(ctx, fullKeys...)
+			if err := result.Err(); err != nil {
+				return 0, convertRedisError(err)
+			}
+			deleted = result.Val()
+		}
 	}
-	return result.Val(), nil
+	if err := r.afterDelete(ctx, keys); err != nil {
+		return deleted, err
+	}
+	if err := r.recordWrite(ctx, op, keys...); err != nil {
+		return deleted, err
+	}
+	return deleted, nil
 }
 
 // =====================================
@@ -244,7 +479,22 @@ func (r *Repository[T]) MDelete(ctx context.Context, keys []string) (int64, erro
 // =====================================
 
 // SetWithTTL stores a value with an expiration time and compile-time type safety.
+// Returns ErrorTypeDuplicate when another key holds one of its unique index values.
 func (r *Repository[T]) SetWithTTL(ctx context.Context, key string, value *T, ttl time.Duration) error {
+	return r.degradeWrite(ctx, OperationWrite, []string{key}, func() error {
+		return r.setWithTTL(ctx, key, value, ttl)
+	}, func() map[string]pendingWrite[T] { return pendingSets(map[string]*T{key: value}, ttl) })
+}
+
+// setWithTTL stores value at key, without the degradation policy
+func (r *Repository[T]) setWithTTL(ctx context.Context, key string, value *T, ttl time.Duration) error {
+	if err := r.authorizeKeys(ctx, AccessWrite, key); err != nil {
+		return err
+	}
+	if err := r.checkQuota(ctx); err != nil {
+		return err
+	}
+
 	// Execute before create hook
 	if hook, ok := any(value).(gpa.BeforeCreateHook); ok {
 		if err := hook.BeforeCreate(ctx); err != nil {
@@ -255,19 +505,28 @@ func (r *Repository[T]) SetWithTTL(ctx context.Context, key string, value *T, tt
 			}
 		}
 	}
-
 	fullKey := r.buildKey(key)
 	
-	data, err := json.Marshal(value)
+	data, err := r.encode(value)
 	if err != nil {
-		return gpa.GPAError{
-			Type:    gpa.ErrorTypeSerialization,
-			Message: "failed to serialize value",
-			Cause:   err,
-		}
+		return err
+	}
+	if err := r.claimUnique(ctx, map[string]*T{key: value}); err != nil {
+		return err
 	}
 
-	if err := convertRedisError(r.client.Set(ctx, fullKey, data, ttl).Err()); err != nil {
+	expiry := r.retentionTTL(key, ttl)
+	if r.opts.changeStream != "" {
+		if _, err := r.captureWrite(ctx, ChangeOpSet, []string{key}, [][]byte{data}, []time.Duration{expiry}); err != nil {
+			return r.abandonClaims(ctx, []string{key}, err)
+		}
+	} else if err := convertRedisError(r.client.Set(ctx, fullKey, data, expiry).Err()); err != nil {
+		return r.abandonClaims(ctx, []string{key}, err)
+	}
+	if err := r.afterSet(ctx, map[string]*T{key: value}, ttl); err != nil {
+		return err
+	}
+	if err := r.recordWrite(ctx, "set", key); err != nil {
 		return err
 	}
 
@@ -285,13 +544,25 @@ func (r *Repository[T]) SetWithTTL(ctx context.Context, key string, value *T, tt
 // Expire sets or updates the TTL for an existing key.
 func (r *Repository[T]) Expire(ctx context.Context, key string, ttl time.Duration) error {
 	fullKey := r.buildKey(key)
+	if err := r.authorize(ctx, AccessWrite, fullKey); err != nil {
+		return err
+	}
 	result := r.client.Expire(ctx, fullKey, ttl)
-	return convertRedisError(result.Err())
+	if err := result.Err(); err != nil {
+		return convertRedisError(err)
+	}
+	if !result.Val() {
+		return nil
+	}
+	return r.recordWrite(ctx, "expire", key)
 }
 
 // TTL returns the remaining time until the key expires.
 func (r *Repository[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
 	fullKey := r.buildKey(key)
+	if err := r.authorize(ctx, AccessRead, fullKey); err != nil {
+		return 0, err
+	}
 	result := r.client.TTL(ctx, fullKey)
 	if err := result.Err(); err != nil {
 		return 0, convertRedisError(err)
@@ -307,6 +578,9 @@ func (r *Repository[T]) GetTTL(ctx context.Context, key string) (time.Duration,
 // SetTTL sets or updates the TTL for an existing key.
 func (r *Repository[T]) SetTTL(ctx context.Context, key string, ttl time.Duration) error {
 	fullKey := r.buildKey(key)
+	if err := r.authorize(ctx, AccessWrite, fullKey); err != nil {
+		return err
+	}
 	result := r.client.Expire(ctx, fullKey, ttl)
 	if err := result.Err(); err != nil {
 		return convertRedisError(err)
@@ -314,12 +588,15 @@ func (r *Repository[T]) SetTTL(ctx context.Context, key string, ttl time.Duratio
 	if !result.Val() {
 		return gpa.NewError(gpa.ErrorTypeNotFound, "key not found")
 	}
-	return nil
+	return r.recordWrite(ctx, "expire", key)
 }
 
 // RemoveTTL removes the TTL from a key, making it persistent.
 func (r *Repository[T]) RemoveTTL(ctx context.Context, key string) error {
 	fullKey := r.buildKey(key)
+	if err := r.authorize(ctx, AccessWrite, fullKey); err != nil {
+		return err
+	}
 	result := r.client.Persist(ctx, fullKey)
 	if err := result.Err(); err != nil {
 		return convertRedisError(err)
@@ -327,7 +604,7 @@ func (r *Repository[T]) RemoveTTL(ctx context.Context, key string) error {
 	if !result.Val() {
 		return gpa.NewError(gpa.ErrorTypeNotFound, "key not found")
 	}
-	return nil
+	return r.recordWrite(ctx, "persist", key)
 }
 
 // =====================================
@@ -337,11 +614,34 @@ func (r *Repository[T]) RemoveTTL(ctx context.Context, key string) error {
 // Increment atomically adds delta to a numeric value.
 func (r *Repository[T]) Increment(ctx context.Context, key string, delta int64) (int64, error) {
 	fullKey := r.buildKey(key)
-	result := r.client.IncrBy(ctx, fullKey, delta)
-	if err := result.Err(); err != nil {
-		return 0, convertRedisError(err)
+	if err := r.authorize(ctx, AccessWrite, fullKey); err != nil {
+		return 0, err
 	}
-	return result.Val(), nil
+	if err := r.checkQuota(ctx); err != nil {
+		return 0, err
+	}
+	var value int64
+	if r.opts.changeStream != "" {
+		keys, args := r.incrementArgs(key, delta)
+		n, err := incrementScript.Run(ctx, r.client, keys, args...).Int64()
+		if err != nil {
+			return 0, convertRedisError(err)
+		}
+		value = n
+	} else {
+		n, err := r.client.IncrBy(ctx, fullKey, delta).Result()
+		if err != nil {
+			return 0, convertRedisError(err)
+		}
+		value = n
+	}
+	if r.near != nil {
+		r.near.Invalidate(key)
+	}
+	if err := r.recordWrite(ctx, "increment", key); err != nil {
+		return value, err
+	}
+	return value, nil
 }
 
 // Decrement atomically subtracts delta from a numeric value.
@@ -356,20 +656,18 @@ func (r *Repository[T]) Decrement(ctx context.Context, key string, delta int64)
 // Keys returns all keys matching the given pattern.
 func (r *Repository[T]) Keys(ctx context.Context, pattern string) ([]string, error) {
 	fullPattern := r.buildKey(pattern)
+	if err := r.authorize(ctx, AccessScan, fullPattern); err != nil {
+		return nil, err
+	}
 	result := r.client.Keys(ctx, fullPattern)
 	if err := result.Err(); err != nil {
 		return nil, convertRedisError(err)
 	}
 
-	keys := result.Val()
+	keys := r.ownedKeys(result.Val())
 	// Remove prefix from returned keys
-	if r.keyPrefix != "" {
-		prefixLen := len(r.keyPrefix)
-		for i, key := range keys {
-			if len(key) > prefixLen && key[:prefixLen] == r.keyPrefix {
-				keys[i] = key[prefixLen:]
-			}
-		}
+	for i, key := range keys {
+		keys[i] = r.trimKey(key)
 	}
 
 	return keys, nil
@@ -378,21 +676,20 @@ func (r *Repository[T]) Keys(ctx context.Context, pattern string) ([]string, err
 // Scan iterates through keys matching a pattern using cursor-based pagination.
 func (r *Repository[T]) Scan(ctx context.Context, cursor uint64, pattern string, count int64) ([]string, uint64, error) {
 	fullPattern := r.buildKey(pattern)
+	if err := r.authorize(ctx, AccessScan, fullPattern); err != nil {
+		return nil, 0, err
+	}
 	result := r.client.Scan(ctx, cursor, fullPattern, count)
 	if err := result.Err(); err != nil {
 		return nil, 0, convertRedisError(err)
 	}
 
 	keys, newCursor := result.Val()
-	
+	keys = r.ownedKeys(keys)
+
 	// Remove prefix from returned keys
-	if r.keyPrefix != "" {
-		prefixLen := len(r.keyPrefix)
-		for i, key := range keys {
-			if len(key) > prefixLen && key[:prefixLen] == r.keyPrefix {
-				keys[i] = key[prefixLen:]
-			}
-		}
+	for i, key := range keys {
+		keys[i] = r.trimKey(key)
 	}
 
 	return keys, newCursor, nil
@@ -424,9 +721,10 @@ func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (*T, error
 	return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "FindByID operation not supported for Redis key-value store - use Get instead")
 }
 
-// FindAll is not applicable for Redis key-value store
+// FindAll returns all values matching the query options, ordered by key.
+// Supports Limit, Offset, KeyPattern and Consistency options.
 func (r *Repository[T]) FindAll(ctx context.Context, opts ...gpa.QueryOption) ([]*T, error) {
-	return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "FindAll operation not supported for Redis key-value store")
+	return r.Query(ctx, opts...)
 }
 
 // Update is not applicable for Redis key-value store - use Set instead
@@ -444,29 +742,103 @@ func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
 	return gpa.NewError(gpa.ErrorTypeUnsupported, "Delete operation not supported for Redis key-value store - use DeleteKey instead")
 }
 
-// DeleteByCondition is not applicable for Redis key-value store
+// DeleteByCondition removes every value under the prefix matching condition.
+// Values are deserialized and evaluated in memory during a SCAN, so the repository
+// must be created with AllowFullScan(). Delete hooks run for each removed entity.
 func (r *Repository[T]) DeleteByCondition(ctx context.Context, condition gpa.Condition) error {
-	return gpa.NewError(gpa.ErrorTypeUnsupported, "DeleteByCondition operation not supported for Redis key-value store")
+	if err := r.requireFullScan(); err != nil {
+		return err
+	}
+
+	keys, err := r.matchingKeys(ctx, &kvQuery{pattern: "*"})
+	if err != nil {
+		return err
+	}
+	keys, entities, err := r.filterKeys(ctx, keys, []gpa.Condition{condition}, -1)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+
+	for _, entity := range entities {
+		if hook, ok := any(entity).(gpa.BeforeDeleteHook); ok {
+			if err := hook.BeforeDelete(ctx); err != nil {
+				return gpa.NewErrorWithCause(gpa.ErrorTypeValidation, "before delete hook failed", err)
+			}
+		}
+	}
+
+	if _, err := r.MDelete(ctx, keys); err != nil {
+		return err
+	}
+
+	for _, entity := range entities {
+		if hook, ok := any(entity).(gpa.AfterDeleteHook); ok {
+			// Errors are ignored as in DeleteKey
+			_ = hook.AfterDelete(ctx)
+		}
+	}
+	return nil
 }
 
-// Query is not applicable for Redis key-value store
+// Query returns values matching the query options, ordered by key.
+// Supports Limit, Offset, KeyPattern and Consistency options, plus Where/And/Or conditions
+// evaluated in memory on the keys of a covering secondary index (see ExplainQuery) or, when
+// the repository allows full scans, on every key. Any other option
+// produces a single ErrorTypeUnsupported error listing what can't be honored.
 func (r *Repository[T]) Query(ctx context.Context, opts ...gpa.QueryOption) ([]*T, error) {
-	return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "Query operation not supported for Redis key-value store")
+	q, err := translateQueryOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.runQuery(ctx, q)
 }
 
-// QueryOne is not applicable for Redis key-value store
+// QueryOne returns the first value matching the query options.
+// Returns ErrorTypeNotFound if nothing matches.
 func (r *Repository[T]) QueryOne(ctx context.Context, opts ...gpa.QueryOption) (*T, error) {
-	return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "QueryOne operation not supported for Redis key-value store")
+	q, err := translateQueryOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	q.limit = 1
+	entities, err := r.runQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeNotFound, "no value matches the query")
+	}
+	return entities[0], nil
 }
 
-// Count is not applicable for Redis key-value store
+// Count returns the number of values matching the query options.
+// Limit and Offset don't affect the count.
 func (r *Repository[T]) Count(ctx context.Context, opts ...gpa.QueryOption) (int64, error) {
-	return 0, gpa.NewError(gpa.ErrorTypeUnsupported, "Count operation not supported for Redis key-value store")
+	q, err := translateQueryOptions(opts)
+	if err != nil {
+		return 0, err
+	}
+	keys, err := r.candidateKeys(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	if len(q.conditions) > 0 {
+		keys, _, err = r.filterKeys(ctx, keys, q.conditions, -1)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(keys)), nil
 }
 
-// Exists is not applicable for Redis key-value store - use KeyExists instead
+// Exists checks if any key matches the query options.
+// Use KeyExists to check a single key.
 func (r *Repository[T]) Exists(ctx context.Context, opts ...gpa.QueryOption) (bool, error) {
-	return false, gpa.NewError(gpa.ErrorTypeUnsupported, "Exists operation not supported for Redis key-value store - use KeyExists instead")
+	count, err := r.Count(ctx, opts...)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
 }
 
 // Transaction is not applicable for Redis key-value store
@@ -484,15 +856,26 @@ func (r *Repository[T]) RawExec(ctx context.Context, query string, args []interf
 	return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "RawExec operation not supported for Redis key-value store")
 }
 
-// GetEntityInfo returns basic entity information for Redis
+// GetEntityInfo returns entity metadata derived from T's struct fields and tags.
+// Entities without an identifiable primary key field report the Redis key as primary key.
 func (r *Repository[T]) GetEntityInfo() (*gpa.EntityInfo, error) {
-	var zero T
+	meta := entityMetaFor(reflect.TypeOf((*T)(nil)))
+
+	fields := make([]gpa.FieldInfo, len(meta.fields))
+	for i, f := range meta.fields {
+		fields[i] = f.info
+	}
+	primaryKey := append([]string{}, meta.primaryKey...)
+	if len(primaryKey) == 0 {
+		primaryKey = []string{"key"}
+	}
+
 	return &gpa.EntityInfo{
-		Name:       fmt.Sprintf("%T", zero),
+		Name:       meta.name,
 		TableName:  r.keyPrefix,
-		PrimaryKey: []string{"key"},
-		Fields:     []gpa.FieldInfo{},
-		Indexes:    []gpa.IndexInfo{},
+		PrimaryKey: primaryKey,
+		Fields:     fields,
+		Indexes:    append([]gpa.IndexInfo{}, meta.indexes...),
 		Relations:  []gpa.RelationInfo{},
 	}, nil
 }
@@ -501,6 +884,94 @@ func (r *Repository[T]) GetEntityInfo() (*gpa.EntityInfo, error) {
 // Helper Functions
 // =====================================
 
+// afterSet runs the bookkeeping every successful write needs: retention and idle deadline
+// tracking, near cache invalidation and secondary indexes. ttl is the TTL the caller
+// requested (0 for none), bounding how far idle reads may extend the entries.
+func (r *Repository[T]) afterSet(ctx context.Context, values map[string]*T, ttl time.Duration) error {
+	if err := r.trackWrites(ctx, sortedKeys(values)...); err != nil {
+		return err
+	}
+	if err := r.trackDeadlines(ctx, sortedKeys(values), ttl); err != nil {
+		return err
+	}
+	if r.near != nil {
+		r.near.Invalidate(sortedKeys(values)...)
+	}
+	return r.indexEntities(ctx, values)
+}
+
+// afterDelete removes deleted keys from secondary indexes, the retention and idle deadline
+// indexes and the near cache
+func (r *Repository[T]) afterDelete(ctx context.Context, keys []string) error {
+	if err := r.untrackWrites(ctx, keys); err != nil {
+		return err
+	}
+	if err := r.untrackDeadlines(ctx, keys); err != nil {
+		return err
+	}
+	if r.near != nil {
+		r.near.Invalidate(keys...)
+	}
+	return r.unindexKeys(ctx, keys)
+}
+
+// encode serializes a value for storage
+func (r *Repository[T]) encode(value *T) ([]byte, error) {
+	if r.opts.canonicalJSON {
+		return CanonicalJSON(value)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize value", err)
+	}
+	return data, nil
+}
+
+// decode deserializes a stored value
+func (r *Repository[T]) decode(data []byte) (*T, error) {
+	var entity T
+	if err := json.Unmarshal(data, &entity); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize value", err)
+	}
+	return &entity, nil
+}
+
+// scanBatchSize is the SCAN COUNT hint used when walking the keyspace
+const scanBatchSize = 100
+
+// scanEach walks all keys matching pattern (relative to the prefix) and calls fn
+// with each batch of full keys returned by SCAN. Iteration stops on the first error.
+func (r *Repository[T]) scanEach(ctx context.Context, pattern string, fn func(fullKeys []string) error) error {
+	if pattern == "" {
+		pattern = "*"
+	}
+	if err := r.authorize(ctx, AccessScan, r.buildKey(pattern)); err != nil {
+		return err
+	}
+	return r.scanOwned(ctx, pattern, fn)
+}
+
+// scanOwned is scanEach without the access check, for internal bookkeeping scans
+func (r *Repository[T]) scanOwned(ctx context.Context, pattern string, fn func(fullKeys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, r.buildKey(pattern), scanBatchSize).Result()
+		if err != nil {
+			return convertRedisError(err)
+		}
+		keys = r.ownedKeys(keys)
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
 // convertRedisError converts Redis errors to GPA errors
 func convertRedisError(err error) error {
 	if err == nil {
@@ -518,8 +989,8 @@ func convertRedisError(err error) error {
 
 // NewAdvancedKVRepository creates a new type-safe advanced Redis repository.
 // This repository implements all KV capabilities with compile-time type safety.
-func NewAdvancedKVRepository[T any](provider *Provider, client *redis.Client, keyPrefix string) gpa.AdvancedKeyValueRepository[T] {
-	return NewRepository[T](provider, client, keyPrefix)
+func NewAdvancedKVRepository[T any](provider *Provider, client *redis.Client, keyPrefix string, opts ...RepositoryOption) gpa.AdvancedKeyValueRepository[T] {
+	return NewRepository[T](provider, client, keyPrefix, opts...)
 }
 
 // Compile-time interface checks for generic repository
@@ -768,8 +768,12 @@ func TestRepositoryGetEntityInfo(t *testing.T) {
 		t.Errorf("Expected entity name 'gparedis.TestValue', got '%s'", info.Name)
 	}
 
-	// For Redis KV store, fields are not populated since it's schema-less
-	// This is expected behavior
+	if len(info.Fields) != 3 {
+		t.Errorf("Expected 3 fields, got %d", len(info.Fields))
+	}
+	if len(info.PrimaryKey) != 1 || info.PrimaryKey[0] != "ID" {
+		t.Errorf("Expected primary key 'ID', got %v", info.PrimaryKey)
+	}
 }
 
 func TestConvertRedisError(t *testing.T) {
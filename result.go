@@ -0,0 +1,47 @@
+package gparedis
+
+import (
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// gpa.Result Implementation
+// =====================================
+
+// RedisResult implements gpa.Result for Redis commands.
+// It captures the number of keys affected, the raw command reply and how long the command took.
+type RedisResult struct {
+	affected int64
+	reply    interface{}
+	duration time.Duration
+}
+
+// NewRedisResult creates a result from an already computed affected count and reply
+func NewRedisResult(affected int64, reply interface{}, duration time.Duration) *RedisResult {
+	return &RedisResult{affected: affected, reply: reply, duration: duration}
+}
+
+// LastInsertId is not meaningful for Redis and always returns ErrorTypeUnsupported
+func (r *RedisResult) LastInsertId() (int64, error) {
+	return 0, gpa.NewError(gpa.ErrorTypeUnsupported, "LastInsertId is not supported by Redis")
+}
+
+// RowsAffected returns the number of keys affected by the command
+func (r *RedisResult) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
+
+// Reply returns the raw command reply
+func (r *RedisResult) Reply() interface{} {
+	return r.reply
+}
+
+// Duration returns how long the command took
+func (r *RedisResult) Duration() time.Duration {
+	return r.duration
+}
+
+// Compile-time interface check
+var _ gpa.Result = (*RedisResult)(nil)
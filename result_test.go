@@ -0,0 +1,22 @@
+package gparedis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisResult(t *testing.T) {
+	result := NewRedisResult(3, int64(3), time.Millisecond)
+
+	affected, err := result.RowsAffected()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), affected)
+	assert.Equal(t, int64(3), result.Reply())
+	assert.Equal(t, time.Millisecond, result.Duration())
+
+	_, err = result.LastInsertId()
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeUnsupported))
+}
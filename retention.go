@@ -0,0 +1,414 @@
+package gparedis
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Data Retention Policies
+// =====================================
+
+// RetentionPolicy declares how long data under a key prefix may live
+type RetentionPolicy struct {
+	// MaxTTL caps the TTL of every write; writes without a TTL get MaxTTL (0 disables)
+	MaxTTL time.Duration
+	// MaxAge deletes keys last written longer ago than this during sweeps (0 disables)
+	MaxAge time.Duration
+}
+
+// RetentionReport summarizes a retention sweep
+type RetentionReport struct {
+	Deleted   map[string]int64 // keys deleted for exceeding MaxAge, by policy prefix
+	TTLCapped map[string]int64 // existing keys whose TTL was lowered to MaxTTL, by policy prefix
+}
+
+// retentionIndexKey is the sorted set recording write times (unix ms) of keys under a policy prefix
+func retentionIndexKey(prefix string) string {
+	return "gparedis:retention:" + prefix
+}
+
+// SetRetentionPolicy declares the retention policy for keys starting with prefix.
+// Policies apply to writes made through repositories of this provider; the most specific
+// (longest) matching prefix wins.
+// Example: provider.SetRetentionPolicy("session:", gparedis.RetentionPolicy{MaxTTL: 24 * time.Hour, MaxAge: 30 * 24 * time.Hour})
+func (p *Provider) SetRetentionPolicy(prefix string, policy RetentionPolicy) error {
+	if prefix == "" {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "retention policy prefix cannot be empty")
+	}
+	if policy.MaxTTL < 0 || policy.MaxAge < 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "retention durations must not be negative")
+	}
+	p.retentionMu.Lock()
+	defer p.retentionMu.Unlock()
+	if p.retention == nil {
+		p.retention = make(map[string]RetentionPolicy)
+	}
+	p.retention[prefix] = policy
+	return nil
+}
+
+// RemoveRetentionPolicy removes the policy for prefix
+func (p *Provider) RemoveRetentionPolicy(prefix string) {
+	p.retentionMu.Lock()
+	defer p.retentionMu.Unlock()
+	delete(p.retention, prefix)
+}
+
+// RetentionPolicies returns a copy of all declared policies keyed by prefix
+func (p *Provider) RetentionPolicies() map[string]RetentionPolicy {
+	p.retentionMu.RLock()
+	defer p.retentionMu.RUnlock()
+	policies := make(map[string]RetentionPolicy, len(p.retention))
+	for prefix, policy := range p.retention {
+		policies[prefix] = policy
+	}
+	return policies
+}
+
+// retentionFor returns the policy governing fullKey and its prefix
+func (p *Provider) retentionFor(fullKey string) (string, RetentionPolicy, bool) {
+	if p == nil {
+		return "", RetentionPolicy{}, false
+	}
+	p.retentionMu.RLock()
+	defer p.retentionMu.RUnlock()
+
+	best, found := "", false
+	for prefix := range p.retention {
+		if strings.HasPrefix(fullKey, prefix) && len(prefix) > len(best) {
+			best, found = prefix, true
+		}
+	}
+	return best, p.retention[best], found
+}
+
+// SweepRetention enforces all policies once: keys written longer ago than MaxAge are deleted,
+// and existing keys under a MaxTTL policy with no or a longer TTL get their TTL lowered.
+// The TTL pass scans the whole prefix, so run sweeps from a background job.
+func (p *Provider) SweepRetention(ctx context.Context) (RetentionReport, error) {
+	report := RetentionReport{Deleted: make(map[string]int64), TTLCapped: make(map[string]int64)}
+	policies := p.RetentionPolicies()
+
+	prefixes := make([]string, 0, len(policies))
+	for prefix := range policies {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	for _, prefix := range prefixes {
+		policy := policies[prefix]
+		if policy.MaxAge > 0 {
+			n, err := p.sweepExpired(ctx, prefix, policy.MaxAge)
+			report.Deleted[prefix] = n
+			if err != nil {
+				return report, err
+			}
+		}
+		if policy.MaxTTL > 0 {
+			n, err := p.capTTLs(ctx, prefix, policy.MaxTTL)
+			report.TTLCapped[prefix] = n
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// RunRetention calls SweepRetention every interval until ctx is cancelled.
+// Sweep errors are passed to onError (which may be nil) and don't stop the loop.
+func (p *Provider) RunRetention(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := p.SweepRetention(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// sweepExpired deletes keys whose recorded write time is older than maxAge. Keys are deleted
+// through the repository owning them, so indexes, unique claims, the near cache, change
+// capture and the audit log see the deletion; keys of no repository are deleted directly.
+func (p *Provider) sweepExpired(ctx context.Context, prefix string, maxAge time.Duration) (int64, error) {
+	index := retentionIndexKey(prefix)
+	cutoff := strconv.FormatInt(time.Now().Add(-maxAge).UnixMilli(), 10)
+	var deleted int64
+
+	for {
+		keys, err := p.client.ZRangeByScore(ctx, index, &redis.ZRangeBy{
+			Min: "-inf", Max: cutoff, Count: scanBatchSize,
+		}).Result()
+		if err != nil {
+			return deleted, convertRedisError(err)
+		}
+		if len(keys) == 0 {
+			return deleted, nil
+		}
+
+		owned := make(map[string][]string)
+		var orphans []string
+		for _, key := range keys {
+			if owner, ok := p.retentionSourceFor(key); ok {
+				owned[owner] = append(owned[owner], key)
+			} else {
+				orphans = append(orphans, key)
+			}
+		}
+		for _, owner := range sortedKeys(owned) {
+			n, err := p.retentionSource(owner).expire(ctx, owned[owner])
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+
+		members := make([]interface{}, len(keys))
+		for i, key := range keys {
+			members[i] = key
+		}
+		var del *redis.IntCmd
+		_, err = p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if len(orphans) > 0 {
+				del = pipe.Del(ctx, orphans...)
+			}
+			// Owned keys were untracked by their repository unless its policy changed meanwhile
+			pipe.ZRem(ctx, index, members...)
+			return nil
+		})
+		if err != nil {
+			return deleted, convertRedisError(err)
+		}
+		if del != nil {
+			deleted += del.Val()
+		}
+	}
+}
+
+// capTTLs lowers the TTL of keys under prefix that have none or one longer than maxTTL.
+// Keys governed by a longer, more specific prefix are left to that prefix's policy.
+func (p *Provider) capTTLs(ctx context.Context, prefix string, maxTTL time.Duration) (int64, error) {
+	var capped int64
+	var cursor uint64
+	for {
+		scanned, next, err := p.client.Scan(ctx, cursor, EscapeGlob(prefix)+"*", scanBatchSize).Result()
+		if err != nil {
+			return capped, convertRedisError(err)
+		}
+		keys := scanned[:0]
+		for _, key := range scanned {
+			if governing, _, _ := p.retentionFor(key); governing == prefix {
+				keys = append(keys, key)
+			}
+		}
+
+		if len(keys) > 0 {
+			ttls := make([]*redis.DurationCmd, len(keys))
+			_, err := p.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for i, key := range keys {
+					ttls[i] = pipe.PTTL(ctx, key)
+				}
+				return nil
+			})
+			if err != nil {
+				return capped, convertRedisError(err)
+			}
+
+			var over []string
+			for i, cmd := range ttls {
+				// -1 means no expiration, -2 means the key vanished meanwhile
+				if ttl := cmd.Val(); ttl == -1 || ttl > maxTTL {
+					over = append(over, keys[i])
+				}
+			}
+			if len(over) > 0 {
+				_, err := p.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+					for _, key := range over {
+						pipe.PExpire(ctx, key, maxTTL)
+					}
+					return nil
+				})
+				if err != nil {
+					return capped, convertRedisError(err)
+				}
+				capped += int64(len(over))
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return capped, nil
+		}
+	}
+}
+
+// retentionTTL applies the MaxTTL of the policy governing key, and the repository's idle
+// policy, to a requested TTL
+func (r *Repository[T]) retentionTTL(key string, ttl time.Duration) time.Duration {
+	_, policy, ok := r.provider.retentionFor(r.buildKey(key))
+	if ok && policy.MaxTTL > 0 && (ttl <= 0 || ttl > policy.MaxTTL) {
+		ttl = policy.MaxTTL
+	}
+	return r.idleTTL(ttl)
+}
+
+// hasRetention reports whether any key is governed by a retention or idle policy
+func (r *Repository[T]) hasRetention(keys []string) bool {
+	if r.opts.idle != nil && len(keys) > 0 {
+		return true
+	}
+	for _, key := range keys {
+		if _, _, ok := r.provider.retentionFor(r.buildKey(key)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// untrackWrites removes deleted keys from the write time indexes of their MaxAge policies
+func (r *Repository[T]) untrackWrites(ctx context.Context, keys []string) error {
+	return r.provider.untrackRetention(ctx, r.buildKeys(keys))
+}
+
+// untrackRetention removes full keys from the write time indexes of their MaxAge policies
+func (p *Provider) untrackRetention(ctx context.Context, fullKeys []string) error {
+	byIndex := make(map[string][]interface{})
+	for _, fullKey := range fullKeys {
+		prefix, policy, ok := p.retentionFor(fullKey)
+		if !ok || policy.MaxAge <= 0 {
+			continue
+		}
+		index := retentionIndexKey(prefix)
+		byIndex[index] = append(byIndex[index], fullKey)
+	}
+	if len(byIndex) == 0 {
+		return nil
+	}
+
+	_, err := p.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for index, members := range byIndex {
+			pipe.ZRem(ctx, index, members...)
+		}
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// retentionScores returns the recorded write times (unix ms) of full keys governed by a
+// MaxAge policy; keys without one are left out
+func (p *Provider) retentionScores(ctx context.Context, fullKeys []string) (map[string]float64, error) {
+	cmds := make(map[string]*redis.FloatCmd)
+	_, err := p.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, fullKey := range fullKeys {
+			if prefix, policy, ok := p.retentionFor(fullKey); ok && policy.MaxAge > 0 {
+				cmds[fullKey] = pipe.ZScore(ctx, retentionIndexKey(prefix), fullKey)
+			}
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, convertRedisError(err)
+	}
+	scores := make(map[string]float64, len(cmds))
+	for fullKey, cmd := range cmds {
+		if score, err := cmd.Result(); err == nil {
+			scores[fullKey] = score
+		}
+	}
+	return scores, nil
+}
+
+// retentionSource is a repository whose keys retention sweeps delete, registered on its provider
+type retentionSource struct {
+	// expire deletes full keys through the repository, returning the number removed
+	expire func(ctx context.Context, fullKeys []string) (int64, error)
+	// owns reports whether a full key under the repository prefix belongs to the repository
+	owns func(fullKey string) bool
+}
+
+// registerRetentionSource makes a repository delete the keys under prefix that sweeps expire.
+// The last repository registered for a prefix wins.
+func (p *Provider) registerRetentionSource(prefix string, source retentionSource) {
+	p.retentionMu.Lock()
+	defer p.retentionMu.Unlock()
+	if p.retentionSources == nil {
+		p.retentionSources = make(map[string]retentionSource)
+	}
+	p.retentionSources[prefix] = source
+}
+
+// retentionSource returns the source registered for prefix
+func (p *Provider) retentionSource(prefix string) retentionSource {
+	p.retentionMu.RLock()
+	defer p.retentionMu.RUnlock()
+	return p.retentionSources[prefix]
+}
+
+// retentionSourceFor returns the prefix of the repository owning fullKey: the longest
+// registered prefix of the key
+func (p *Provider) retentionSourceFor(fullKey string) (string, bool) {
+	p.retentionMu.RLock()
+	defer p.retentionMu.RUnlock()
+	best, found := "", false
+	for prefix, source := range p.retentionSources {
+		if strings.HasPrefix(fullKey, prefix) && (!found || len(prefix) > len(best)) && source.owns(fullKey) {
+			best, found = prefix, true
+		}
+	}
+	return best, found
+}
+
+// registerRetention lets retention sweeps delete the repository's keys through it
+func (r *Repository[T]) registerRetention() {
+	if r.provider == nil {
+		return
+	}
+	r.provider.registerRetentionSource(r.keyPrefix, retentionSource{
+		expire: func(ctx context.Context, fullKeys []string) (int64, error) {
+			keys := make([]string, len(fullKeys))
+			for i, fullKey := range fullKeys {
+				keys[i] = r.trimKey(fullKey)
+			}
+			return r.removeKeys(ctx, keys, "retention_delete")
+		},
+		owns: r.ownsKey,
+	})
+}
+
+// trackWrites records the write time of keys governed by a MaxAge policy
+func (r *Repository[T]) trackWrites(ctx context.Context, keys ...string) error {
+	now := float64(time.Now().UnixMilli())
+	byIndex := make(map[string][]*redis.Z)
+	for _, key := range keys {
+		fullKey := r.buildKey(key)
+		prefix, policy, ok := r.provider.retentionFor(fullKey)
+		if !ok || policy.MaxAge <= 0 {
+			continue
+		}
+		index := retentionIndexKey(prefix)
+		byIndex[index] = append(byIndex[index], &redis.Z{Score: now, Member: fullKey})
+	}
+	if len(byIndex) == 0 {
+		return nil
+	}
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for index, members := range byIndex {
+			pipe.ZAdd(ctx, index, members...)
+		}
+		return nil
+	})
+	return convertRedisError(err)
+}
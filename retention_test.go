@@ -0,0 +1,120 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionPolicyLookup(t *testing.T) {
+	p := &Provider{}
+	require.NoError(t, p.SetRetentionPolicy("session:", RetentionPolicy{MaxTTL: time.Hour}))
+	require.NoError(t, p.SetRetentionPolicy("session:admin:", RetentionPolicy{MaxTTL: time.Minute}))
+	assert.True(t, gpa.IsErrorType(p.SetRetentionPolicy("", RetentionPolicy{}), gpa.ErrorTypeInvalidArgument))
+	assert.True(t, gpa.IsErrorType(p.SetRetentionPolicy("x:", RetentionPolicy{MaxAge: -1}), gpa.ErrorTypeInvalidArgument))
+
+	prefix, policy, ok := p.retentionFor("session:admin:1")
+	require.True(t, ok)
+	assert.Equal(t, "session:admin:", prefix)
+	assert.Equal(t, time.Minute, policy.MaxTTL)
+
+	_, _, ok = p.retentionFor("user:1")
+	assert.False(t, ok)
+
+	repo := NewRepository[TestValue](p, nil, "session:")
+	assert.Equal(t, time.Hour, repo.retentionTTL("1", 0))
+	assert.Equal(t, time.Hour, repo.retentionTTL("1", 2*time.Hour))
+	assert.Equal(t, time.Second, repo.retentionTTL("1", time.Second))
+	assert.Equal(t, time.Minute, repo.retentionTTL("admin:1", 0))
+
+	p.RemoveRetentionPolicy("session:admin:")
+	assert.Len(t, p.RetentionPolicies(), 1)
+}
+
+func TestRetentionEnforcement(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	p := base.provider
+	defer p.RemoveRetentionPolicy("session:")
+	defer p.RemoveRetentionPolicy("session:keep:")
+
+	// Written before the policy exists: untracked and without TTL
+	require.NoError(t, base.client.Set(ctx, "session:legacy", "{}", 0).Err())
+	// Governed by a more specific policy without MaxTTL
+	require.NoError(t, base.client.Set(ctx, "session:keep:x", "{}", 0).Err())
+	defer base.client.Del(ctx, "session:keep:x")
+	require.NoError(t, p.SetRetentionPolicy("session:keep:", RetentionPolicy{}))
+
+	require.NoError(t, p.SetRetentionPolicy("session:", RetentionPolicy{MaxTTL: time.Hour, MaxAge: 200 * time.Millisecond}))
+	sessions := NewRepository[TestValue](p, base.client, "session:")
+
+	require.NoError(t, sessions.Set(ctx, "a", &TestValue{ID: "a"}))
+	require.NoError(t, sessions.MSet(ctx, map[string]*TestValue{"b": {ID: "b"}, "c": {ID: "c"}}))
+
+	for _, key := range []string{"a", "b", "c"} {
+		ttl, err := sessions.TTL(ctx, key)
+		require.NoError(t, err)
+		assert.InDelta(t, float64(time.Hour), float64(ttl), float64(time.Minute), key)
+	}
+
+	report, err := p.SweepRetention(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), report.Deleted["session:"])
+	assert.Equal(t, int64(1), report.TTLCapped["session:"])
+	ttl, err := base.client.PTTL(ctx, "session:keep:x").Result()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), ttl, "the longest prefix wins")
+
+	time.Sleep(250 * time.Millisecond)
+	require.NoError(t, sessions.Set(ctx, "fresh", &TestValue{ID: "fresh"}))
+
+	report, err = p.SweepRetention(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), report.Deleted["session:"])
+
+	keys, err := sessions.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"fresh", "keep:x", "legacy"}, keys)
+}
+
+func TestRetentionSweepBookkeeping(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	p := base.provider
+	require.NoError(t, p.SetRetentionPolicy("swept:", RetentionPolicy{MaxAge: 100 * time.Millisecond}))
+	defer p.RemoveRetentionPolicy("swept:")
+	users := NewRepository[indexedUser](p, base.client, "swept:", CaptureChanges("cdc:swept", 0))
+	defer base.client.Del(ctx, "cdc:swept", "cdc:swept:versions", retentionIndexKey("swept:"))
+
+	// Deletes untrack keys from the write time index
+	require.NoError(t, users.Set(ctx, "1", &indexedUser{ID: "1", Email: "a@x.io", Status: "active"}))
+	require.NoError(t, users.Set(ctx, "2", &indexedUser{ID: "2", Email: "b@x.io"}))
+	require.NoError(t, users.DeleteKey(ctx, "2"))
+	tracked, err := base.client.ZRange(ctx, retentionIndexKey("swept:"), 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"swept:1"}, tracked)
+
+	time.Sleep(150 * time.Millisecond)
+	report, err := p.SweepRetention(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Deleted["swept:"])
+
+	// Swept keys leave their indexes, release their unique values and are captured
+	keys, err := users.KeysByIndex(ctx, "status", "active")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	require.NoError(t, users.Set(ctx, "3", &indexedUser{ID: "3", Email: "a@x.io"}))
+	events, err := users.Changes().Range(ctx, "-", "+", 0)
+	require.NoError(t, err)
+	require.Len(t, events, 5)
+	assert.Equal(t, &ChangeEvent{Key: "1", Op: ChangeOpDelete, Version: 2, Timestamp: events[3].Value.Timestamp}, events[3].Value)
+	require.NoError(t, users.DeleteKey(ctx, "3"))
+}
@@ -0,0 +1,254 @@
+package gparedis
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Pub/Sub Topic Router
+// =====================================
+
+// Publish sends payload to the subscribers of topic and returns how many received it. The
+// payload is compressed and size-limited according to SetPayloadOptions.
+// Example: n, err := provider.Publish(ctx, "orders.eu.created", payload)
+func (p *Provider) Publish(ctx context.Context, topic string, payload []byte) (int64, error) {
+	payload, err := p.encodePayload(ctx, payload)
+	if err != nil {
+		return 0, err
+	}
+	n, err := p.client.Publish(ctx, topic, payload).Result()
+	return n, convertRedisError(err)
+}
+
+// TopicMessage is a published message matched by a route
+type TopicMessage struct {
+	Topic string
+	// Route is the pattern the topic matched
+	Route string
+	// Params holds the segments matched by {name} placeholders
+	Params map[string]string
+	// Wildcards holds the segments matched by * and the rest of the topic matched by #, in order
+	Wildcards []string
+	Payload   []byte
+}
+
+// TopicHandler processes a routed message
+type TopicHandler func(ctx context.Context, msg TopicMessage) error
+
+// RouterOptions configures a Router
+type RouterOptions struct {
+	// Concurrency is the number of handlers running at once; delivery waits for a free slot (default 16)
+	Concurrency int
+	// OnError is called when a handler fails
+	OnError func(msg TopicMessage, err error)
+}
+
+// RouteStats counts the messages of one route
+type RouteStats struct {
+	Route    string
+	Received uint64
+	Failed   uint64
+	// Latency is the total time spent in the handler
+	Latency time.Duration
+}
+
+// route is a registered pattern and its handler
+type route struct {
+	pattern  string
+	segments []string
+	handler  TopicHandler
+	stats    RouteStats
+}
+
+// Router dispatches Pub/Sub messages to handlers by dot-separated topic patterns, such as
+// "orders.*.created" or "orders.{region}.created". A * or {name} segment matches exactly
+// one topic segment and a trailing # matches one or more. Each route subscribes with
+// PSUBSCRIBE; a message is dispatched to every route it matches.
+type Router struct {
+	provider *Provider
+	opts     RouterOptions
+
+	mu     sync.Mutex
+	routes []*route
+}
+
+// NewRouter creates a router receiving through provider
+// Example: router := gparedis.NewRouter(provider, gparedis.RouterOptions{Concurrency: 8})
+func NewRouter(provider *Provider, opts RouterOptions) *Router {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 16
+	}
+	return &Router{provider: provider, opts: opts}
+}
+
+// Handle registers handler for topics matching pattern. Routes must be registered before Run.
+// Example: router.Handle("orders.{region}.created", func(ctx context.Context, msg gparedis.TopicMessage) error { ... })
+func (r *Router) Handle(pattern string, handler TopicHandler) error {
+	segments := strings.Split(pattern, ".")
+	for i, segment := range segments {
+		switch {
+		case segment == "":
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "empty segment in topic pattern "+pattern)
+		case segment == "#" && i != len(segments)-1:
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "# must be the last segment of topic pattern "+pattern)
+		case segment != "*" && segment != "#" && !isTopicParam(segment) && strings.ContainsAny(segment, "*?[]{}\\#"):
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid segment "+segment+" in topic pattern "+pattern)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, &route{pattern: pattern, segments: segments, handler: handler, stats: RouteStats{Route: pattern}})
+	return nil
+}
+
+// isTopicParam reports whether a segment is a {name} placeholder
+func isTopicParam(segment string) bool {
+	return len(segment) > 2 && segment[0] == '{' && segment[len(segment)-1] == '}'
+}
+
+// glob returns the PSUBSCRIBE pattern covering the route; matches are confirmed by match
+func (rt *route) glob() string {
+	parts := make([]string, len(rt.segments))
+	for i, segment := range rt.segments {
+		if segment == "*" || segment == "#" || isTopicParam(segment) {
+			parts[i] = "*"
+		} else {
+			parts[i] = segment
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// match checks a topic against the route and extracts its parameters
+func (rt *route) match(topic string) (TopicMessage, bool) {
+	parts := strings.Split(topic, ".")
+	msg := TopicMessage{Topic: topic, Route: rt.pattern}
+	for i, segment := range rt.segments {
+		if segment == "#" {
+			if i >= len(parts) {
+				return msg, false
+			}
+			msg.Wildcards = append(msg.Wildcards, strings.Join(parts[i:], "."))
+			return msg, true
+		}
+		if i >= len(parts) {
+			return msg, false
+		}
+		switch {
+		case segment == "*":
+			msg.Wildcards = append(msg.Wildcards, parts[i])
+		case isTopicParam(segment):
+			if msg.Params == nil {
+				msg.Params = make(map[string]string)
+			}
+			msg.Params[segment[1:len(segment)-1]] = parts[i]
+		case segment != parts[i]:
+			return msg, false
+		}
+	}
+	return msg, len(parts) == len(rt.segments)
+}
+
+// Run subscribes to every route and dispatches messages until ctx is cancelled, then waits
+// for running handlers. Messages published while the subscription reconnects are lost.
+func (r *Router) Run(ctx context.Context) error {
+	r.mu.Lock()
+	routes := append([]*route(nil), r.routes...)
+	r.mu.Unlock()
+	if len(routes) == 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "router has no routes")
+	}
+	globs := make([]string, 0, len(routes))
+	seen := make(map[string]bool)
+	for _, rt := range routes {
+		if glob := rt.glob(); !seen[glob] {
+			seen[glob] = true
+			globs = append(globs, glob)
+		}
+	}
+
+	pubsub := r.provider.client.PSubscribe(ctx, globs...)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to subscribe to topic routes", err)
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	slots := make(chan struct{}, r.opts.Concurrency)
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case received, ok := <-messages:
+			if !ok {
+				return ctx.Err()
+			}
+			// A topic matching several globs arrives once per glob; dispatch it only for
+			// the routes of the glob it arrived through
+			for _, rt := range routes {
+				if rt.glob() != received.Pattern {
+					continue
+				}
+				msg, ok := rt.match(received.Channel)
+				if !ok {
+					continue
+				}
+				msg.Payload = []byte(received.Payload)
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				wg.Add(1)
+				go func(rt *route, msg TopicMessage) {
+					defer wg.Done()
+					defer func() { <-slots }()
+					r.dispatch(ctx, rt, msg)
+				}(rt, msg)
+			}
+		}
+	}
+}
+
+// dispatch decodes the payload, runs a handler and records its outcome
+func (r *Router) dispatch(ctx context.Context, rt *route, msg TopicMessage) {
+	start := time.Now()
+	payload, err := r.provider.DecodePayload(ctx, msg.Payload)
+	if err == nil {
+		msg.Payload = payload
+		err = rt.handler(ctx, msg)
+	}
+	elapsed := time.Since(start)
+
+	r.mu.Lock()
+	rt.stats.Received++
+	rt.stats.Latency += elapsed
+	if err != nil {
+		rt.stats.Failed++
+	}
+	r.mu.Unlock()
+
+	if err != nil && r.opts.OnError != nil {
+		r.opts.OnError(msg, err)
+	}
+}
+
+// Stats returns the counters of every route, sorted by pattern
+func (r *Router) Stats() []RouteStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := make([]RouteStats, len(r.routes))
+	for i, rt := range r.routes {
+		stats[i] = rt.stats
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Route < stats[j].Route })
+	return stats
+}
@@ -0,0 +1,105 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterMatch(t *testing.T) {
+	rt := &route{pattern: "orders.{region}.*.#", segments: []string{"orders", "{region}", "*", "#"}}
+	msg, ok := rt.match("orders.eu.created.v2.test")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"region": "eu"}, msg.Params)
+	assert.Equal(t, []string{"created", "v2.test"}, msg.Wildcards)
+	_, ok = rt.match("orders.eu.created")
+	assert.False(t, ok)
+	_, ok = rt.match("invoices.eu.created.v2")
+	assert.False(t, ok)
+	assert.Equal(t, "orders.*.*.*", rt.glob())
+
+	router := NewRouter(nil, RouterOptions{})
+	assert.Error(t, router.Handle("orders.#.created", nil))
+	assert.Error(t, router.Handle("orders..created", nil))
+	assert.Error(t, router.Handle("orders.eu?.created", nil))
+}
+
+func TestRouterDispatch(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		created  []TopicMessage
+		all      []string
+		failures []string
+	)
+	router := NewRouter(repo.provider, RouterOptions{
+		Concurrency: 2,
+		OnError:     func(msg TopicMessage, err error) { mu.Lock(); failures = append(failures, msg.Topic); mu.Unlock() },
+	})
+	require.NoError(t, router.Handle("orders.{region}.created", func(ctx context.Context, msg TopicMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		created = append(created, msg)
+		return nil
+	}))
+	require.NoError(t, router.Handle("orders.*.created", func(ctx context.Context, msg TopicMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		all = append(all, msg.Topic)
+		return nil
+	}))
+	require.NoError(t, router.Handle("invoices.#", func(ctx context.Context, msg TopicMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		all = append(all, msg.Topic)
+		if msg.Topic == "invoices.us.cancelled" {
+			return errors.New("rejected")
+		}
+		return nil
+	}))
+
+	done := make(chan error, 1)
+	go func() { done <- router.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		n, err := repo.client.PubSubNumPat(ctx).Result()
+		return err == nil && n >= 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	for _, topic := range []string{"orders.eu.created", "invoices.us.cancelled", "orders.eu.created.extra", "invoices.eu.issued.v2"} {
+		_, err := repo.provider.Publish(ctx, topic, []byte(`{"id":1}`))
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(all) == 3 && len(created) == 1 && len(failures) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, "eu", created[0].Params["region"])
+	assert.Equal(t, `{"id":1}`, string(created[0].Payload))
+	assert.ElementsMatch(t, []string{"orders.eu.created", "invoices.us.cancelled", "invoices.eu.issued.v2"}, all)
+	assert.Equal(t, []string{"invoices.us.cancelled"}, failures)
+	mu.Unlock()
+
+	stats := router.Stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, "invoices.#", stats[0].Route)
+	assert.Equal(t, uint64(2), stats[0].Received)
+	assert.Equal(t, uint64(1), stats[0].Failed)
+	assert.Equal(t, uint64(1), stats[1].Received)
+	assert.Equal(t, uint64(1), stats[2].Received)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,231 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Request/Response RPC
+// =====================================
+
+// RPCOptions configures an RPC endpoint
+type RPCOptions struct {
+	// Prefix is the key prefix of request queues and reply keys (default "gparedis:rpc:")
+	Prefix string
+	// Timeout bounds calls whose context has no deadline (default 5s)
+	Timeout time.Duration
+	// Workers is the number of requests Serve handles at once (default 4)
+	Workers int
+	// PoolSize is the number of dedicated connections for waiting callers and workers (default 10)
+	PoolSize int
+	// OnError is called when Serve fails to receive or answer a request
+	OnError func(err error)
+}
+
+// RPCRequest is a call received by a handler
+type RPCRequest struct {
+	ID      string
+	Method  string
+	Payload []byte
+}
+
+// RPCHandler answers a call; a returned error is reported to the caller as ErrorTypeRemote
+type RPCHandler func(ctx context.Context, req RPCRequest) ([]byte, error)
+
+// rpcEnvelope is a queued call
+type rpcEnvelope struct {
+	ID      string `json:"id"`
+	Method  string `json:"method"`
+	ReplyTo string `json:"reply_to"`
+	// Deadline is when the caller stops waiting, in Unix milliseconds
+	Deadline int64  `json:"deadline"`
+	Payload  []byte `json:"payload,omitempty"`
+}
+
+// rpcReply is the answer pushed to a call's reply key
+type rpcReply struct {
+	Payload []byte `json:"payload,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RPC makes lightweight service-to-service calls over Redis lists: Call pushes a request
+// with a correlation ID onto the service's queue and waits on a reply key unique to the
+// call; Serve pops requests, runs the handler of their method and pushes the reply. Any
+// number of servers may serve a service; each request is handled once. Requests whose
+// caller gave up are skipped, and unclaimed replies expire.
+type RPC struct {
+	provider *Provider
+	service  string
+	opts     RPCOptions
+	blocking *BlockingClient
+
+	mu       sync.RWMutex
+	handlers map[string]RPCHandler
+}
+
+// NewRPC creates an endpoint for calling or serving service. Close it when done.
+// Example: billing := gparedis.NewRPC(provider, "billing", gparedis.RPCOptions{Timeout: 2 * time.Second})
+func NewRPC(provider *Provider, service string, opts RPCOptions) *RPC {
+	if opts.Prefix == "" {
+		opts.Prefix = "gparedis:rpc:"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	return &RPC{
+		provider: provider,
+		service:  service,
+		opts:     opts,
+		blocking: provider.NewBlockingClient(BlockingOptions{PoolSize: opts.PoolSize}),
+		handlers: make(map[string]RPCHandler),
+	}
+}
+
+// Close closes the dedicated connections
+func (r *RPC) Close() error {
+	return r.blocking.Close()
+}
+
+// queueKey is the list holding the service's pending requests
+func (r *RPC) queueKey() string {
+	return r.opts.Prefix + r.service + ":requests"
+}
+
+// Handle registers the handler of method; handlers must be registered before Serve
+// Example: billing.Handle("charge", func(ctx context.Context, req gparedis.RPCRequest) ([]byte, error) { ... })
+func (r *RPC) Handle(method string, handler RPCHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[method] = handler
+}
+
+// Call sends payload to method and waits for the reply until ctx ends, or for Timeout when
+// ctx has no deadline. Handler errors fail with ErrorTypeRemote and calls left unanswered
+// with ErrorTypeTimeout.
+// Example: receipt, err := billing.Call(ctx, "charge", order)
+func (r *RPC) Call(ctx context.Context, method string, payload []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.opts.Timeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+	id, err := newRandomID()
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to generate RPC correlation ID", err)
+	}
+	replyTo := r.opts.Prefix + r.service + ":reply:" + id
+	request, err := json.Marshal(rpcEnvelope{ID: id, Method: method, ReplyTo: replyTo, Deadline: deadline.UnixMilli(), Payload: payload})
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to encode RPC request", err)
+	}
+	if err := r.provider.client.LPush(ctx, r.queueKey(), request).Err(); err != nil {
+		return nil, convertRedisError(err)
+	}
+
+	_, raw, err := r.blocking.BLPop(ctx, replyTo)
+	if err != nil {
+		return nil, err
+	}
+	var reply rpcReply
+	if err := json.Unmarshal([]byte(raw), &reply); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to decode RPC reply", err)
+	}
+	if reply.Error != "" {
+		return nil, gpa.NewError(ErrorTypeRemote, r.service+"."+method+": "+reply.Error)
+	}
+	return reply.Payload, nil
+}
+
+// Serve handles requests with Workers concurrent handlers until ctx is cancelled, then
+// waits for running handlers. A request of an unregistered method is answered with an error.
+func (r *RPC) Serve(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < r.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// work receives and answers requests until ctx ends
+func (r *RPC) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		// Requests are pushed on the left and served from the right, in arrival order
+		_, raw, err := r.blocking.BRPop(ctx, r.queueKey())
+		if err != nil {
+			if ctx.Err() == nil {
+				r.report(err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+			}
+			continue
+		}
+		var request rpcEnvelope
+		if err := json.Unmarshal([]byte(raw), &request); err != nil {
+			r.report(gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to decode RPC request", err))
+			continue
+		}
+		if err := r.answer(ctx, request); err != nil {
+			r.report(err)
+		}
+	}
+}
+
+// answer runs the handler of a request within the caller's deadline and pushes the reply
+func (r *RPC) answer(ctx context.Context, request rpcEnvelope) error {
+	deadline := time.UnixMilli(request.Deadline)
+	if time.Now().After(deadline) {
+		// The caller gave up
+		return nil
+	}
+	r.mu.RLock()
+	handler, ok := r.handlers[request.Method]
+	r.mu.RUnlock()
+
+	var reply rpcReply
+	if !ok {
+		reply.Error = "unknown method " + request.Method
+	} else {
+		handlerCtx, cancel := context.WithDeadline(ctx, deadline)
+		payload, err := handler(handlerCtx, RPCRequest{ID: request.ID, Method: request.Method, Payload: request.Payload})
+		cancel()
+		if err != nil {
+			reply.Error = err.Error()
+		} else {
+			reply.Payload = payload
+		}
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to encode RPC reply", err)
+	}
+	// The reply outlives the caller's deadline briefly, then expires if nobody took it
+	pipe := r.provider.client.TxPipeline()
+	pipe.RPush(ctx, request.ReplyTo, data)
+	pipe.PExpireAt(ctx, request.ReplyTo, deadline.Add(time.Second))
+	_, err = pipe.Exec(ctx)
+	return convertRedisError(err)
+}
+
+// report passes a serving error to OnError
+func (r *RPC) report(err error) {
+	if r.opts.OnError != nil {
+		r.opts.OnError(err)
+	}
+}
@@ -0,0 +1,97 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCCallServe(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	server := NewRPC(repo.provider, "test-echo", RPCOptions{Workers: 2})
+	defer server.Close()
+	server.Handle("upper", func(ctx context.Context, req RPCRequest) ([]byte, error) {
+		return []byte(strings.ToUpper(string(req.Payload))), nil
+	})
+	server.Handle("fail", func(ctx context.Context, req RPCRequest) ([]byte, error) {
+		return nil, errors.New("card declined")
+	})
+
+	serveCtx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(serveCtx) }()
+
+	client := NewRPC(repo.provider, "test-echo", RPCOptions{Timeout: 2 * time.Second})
+	defer client.Close()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for _, word := range []string{"alpha", "beta", "gamma"} {
+		wg.Add(1)
+		go func(word string) {
+			defer wg.Done()
+			reply, err := client.Call(ctx, "upper", []byte(word))
+			assert.NoError(t, err)
+			assert.Equal(t, strings.ToUpper(word), string(reply))
+		}(word)
+	}
+	wg.Wait()
+
+	_, err := client.Call(ctx, "fail", nil)
+	assert.True(t, IsRemoteError(err))
+	assert.Contains(t, err.Error(), "card declined")
+
+	_, err = client.Call(ctx, "missing", nil)
+	assert.True(t, IsRemoteError(err))
+	assert.Contains(t, err.Error(), "unknown method")
+
+	stop()
+	select {
+	case err := <-served:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Serve didn't return after cancellation")
+	}
+
+	// Replies are consumed or expire
+	keys, err := repo.client.Keys(ctx, "gparedis:rpc:test-echo:reply:*").Result()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestRPCTimeout(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	client := NewRPC(repo.provider, "test-unserved", RPCOptions{Timeout: 300 * time.Millisecond})
+	defer client.Close()
+	ctx := context.Background()
+	defer repo.client.Del(ctx, client.queueKey())
+
+	_, err := client.Call(ctx, "anything", []byte("x"))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeTimeout))
+
+	// A server starting late skips the expired request
+	time.Sleep(50 * time.Millisecond)
+	server := NewRPC(repo.provider, "test-unserved", RPCOptions{Workers: 1})
+	defer server.Close()
+	handled := false
+	server.Handle("anything", func(ctx context.Context, req RPCRequest) ([]byte, error) {
+		handled = true
+		return nil, nil
+	})
+	serveCtx, stop := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer stop()
+	server.Serve(serveCtx)
+	assert.False(t, handled)
+	assert.Equal(t, int64(0), repo.client.LLen(ctx, client.queueKey()).Val())
+}
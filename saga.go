@@ -0,0 +1,218 @@
+package gparedis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Saga Compensation
+// =====================================
+
+// CompensationFunc undoes a completed saga step using the payload recorded for it
+type CompensationFunc func(ctx context.Context, payload json.RawMessage) error
+
+// compensationRecord is the durable form of a compensation stored in the saga log
+type compensationRecord struct {
+	Action  string          `json:"action"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// SagaCoordinator runs sagas spanning Redis and other stores.
+// Every completed step records a named compensation in a Redis list; when a saga fails
+// the compensations are replayed in reverse order. Sagas abandoned by a crashed process
+// can be compensated later with Recover.
+type SagaCoordinator struct {
+	provider *Provider
+	prefix   string
+
+	mu      sync.RWMutex
+	actions map[string]CompensationFunc
+}
+
+// Saga is a running saga handed to the callback of SagaCoordinator.Run
+type Saga struct {
+	ID          string
+	coordinator *SagaCoordinator
+}
+
+// NewSagaCoordinator creates a coordinator storing saga logs under prefix
+// Example: coord := gparedis.NewSagaCoordinator(provider, "saga:")
+func NewSagaCoordinator(provider *Provider, prefix string) *SagaCoordinator {
+	return &SagaCoordinator{
+		provider: provider,
+		prefix:   prefix,
+		actions:  make(map[string]CompensationFunc),
+	}
+}
+
+// RegisterCompensation registers the handler used to replay compensations named action.
+// Handlers must be registered before running or recovering sagas that reference them, and
+// must be idempotent: a handler interrupted by a crash runs again on Recover.
+func (c *SagaCoordinator) RegisterCompensation(action string, fn CompensationFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions[action] = fn
+}
+
+// Run executes fn as a saga. If fn returns an error, every compensation recorded so far is
+// replayed (most recent first) and the original error is returned. If a compensation itself
+// fails, the remaining log is kept for Recover and a transaction error wrapping both is returned.
+func (c *SagaCoordinator) Run(ctx context.Context, fn func(s *Saga) error) error {
+	id, err := newRandomID()
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to generate saga id", err)
+	}
+	saga := &Saga{ID: id, coordinator: c}
+
+	err = c.provider.client.ZAdd(ctx, c.activeKey(), &redis.Z{
+		Score:  float64(time.Now().UnixMilli()),
+		Member: id,
+	}).Err()
+	if err != nil {
+		return convertRedisError(err)
+	}
+
+	if runErr := fn(saga); runErr != nil {
+		if compErr := c.compensate(ctx, id); compErr != nil {
+			return gpa.NewErrorWithCause(gpa.ErrorTypeTransaction,
+				fmt.Sprintf("saga %s failed and compensation is incomplete: %v", id, compErr), runErr)
+		}
+		return runErr
+	}
+
+	return c.finish(ctx, id)
+}
+
+// Compensate records the compensation for a step that has just completed.
+// payload is JSON-encoded and handed back to the registered handler on replay.
+func (s *Saga) Compensate(ctx context.Context, action string, payload interface{}) error {
+	c := s.coordinator
+	c.mu.RLock()
+	_, ok := c.actions[action]
+	c.mu.RUnlock()
+	if !ok {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "unknown compensation action: "+action)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize compensation payload", err)
+	}
+	record, err := json.Marshal(compensationRecord{Action: action, Payload: data})
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize compensation", err)
+	}
+	return convertRedisError(c.provider.client.RPush(ctx, c.logKey(s.ID), record).Err())
+}
+
+// Pending returns the IDs of sagas that started before olderThan ago and never finished
+func (c *SagaCoordinator) Pending(ctx context.Context, olderThan time.Duration) ([]string, error) {
+	max := strconv.FormatInt(time.Now().Add(-olderThan).UnixMilli(), 10)
+	ids, err := c.provider.client.ZRangeByScore(ctx, c.activeKey(), &redis.ZRangeBy{Min: "-inf", Max: max}).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	return ids, nil
+}
+
+// Recover replays the compensations of sagas abandoned for longer than olderThan,
+// e.g. because the process running them crashed. Returns the IDs that were fully compensated.
+func (c *SagaCoordinator) Recover(ctx context.Context, olderThan time.Duration) ([]string, error) {
+	ids, err := c.Pending(ctx, olderThan)
+	if err != nil {
+		return nil, err
+	}
+
+	recovered := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if err := c.compensate(ctx, id); err != nil {
+			return recovered, err
+		}
+		recovered = append(recovered, id)
+	}
+	return recovered, nil
+}
+
+// popCompensationScript removes the last compensation of a saga log if it is still ARGV[1].
+// KEYS[1]: log. Returns 1 when removed.
+var popCompensationScript = redis.NewScript(`
+if redis.call('LINDEX', KEYS[1], -1) == ARGV[1] then
+	redis.call('RPOP', KEYS[1])
+	return 1
+end
+return 0
+`)
+
+// compensate replays compensations for a saga, most recent first, until its log is empty.
+// A compensation is only removed from the log once its handler succeeded, so a crash
+// midway leaves it for Recover; handlers must therefore be idempotent.
+func (c *SagaCoordinator) compensate(ctx context.Context, id string) error {
+	logKey := c.logKey(id)
+	for {
+		data, err := c.provider.client.LIndex(ctx, logKey, -1).Result()
+		if err == redis.Nil {
+			return c.finish(ctx, id)
+		}
+		if err != nil {
+			return convertRedisError(err)
+		}
+
+		var record compensationRecord
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to decode compensation", err)
+		}
+
+		c.mu.RLock()
+		fn, ok := c.actions[record.Action]
+		c.mu.RUnlock()
+		if !ok {
+			// The record stays in the log so a later Recover can retry it
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "no handler registered for compensation: "+record.Action)
+		}
+		if err := fn(ctx, record.Payload); err != nil {
+			return err
+		}
+		if err := popCompensationScript.Run(ctx, c.provider.client, []string{logKey}, data).Err(); err != nil {
+			return convertRedisError(err)
+		}
+	}
+}
+
+// finish removes all bookkeeping for a saga
+func (c *SagaCoordinator) finish(ctx context.Context, id string) error {
+	_, err := c.provider.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, c.logKey(id))
+		pipe.ZRem(ctx, c.activeKey(), id)
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// activeKey is the sorted set of running sagas scored by start time
+func (c *SagaCoordinator) activeKey() string {
+	return c.prefix + "active"
+}
+
+// logKey is the list holding a saga's compensations
+func (c *SagaCoordinator) logKey(id string) string {
+	return c.prefix + "log:" + id
+}
+
+// newRandomID returns a random 24 hex character identifier
+func newRandomID() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,110 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSagaCompensatesInReverseOrder(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coord := NewSagaCoordinator(repo.provider, "saga:")
+
+	var undone []string
+	coord.RegisterCompensation("undo", func(ctx context.Context, payload json.RawMessage) error {
+		var step string
+		if err := json.Unmarshal(payload, &step); err != nil {
+			return err
+		}
+		undone = append(undone, step)
+		return nil
+	})
+
+	failure := errors.New("payment declined")
+	err := coord.Run(ctx, func(s *Saga) error {
+		require.NoError(t, s.Compensate(ctx, "undo", "reserve-stock"))
+		require.NoError(t, s.Compensate(ctx, "undo", "create-order"))
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []string{"create-order", "reserve-stock"}, undone)
+
+	pending, err := coord.Pending(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestSagaSuccessClearsLog(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coord := NewSagaCoordinator(repo.provider, "saga:")
+	coord.RegisterCompensation("undo", func(ctx context.Context, payload json.RawMessage) error {
+		t.Fatal("compensation must not run for successful sagas")
+		return nil
+	})
+
+	var id string
+	err := coord.Run(ctx, func(s *Saga) error {
+		id = s.ID
+		return s.Compensate(ctx, "undo", map[string]string{"id": "1"})
+	})
+	require.NoError(t, err)
+
+	exists, err := repo.client.Exists(ctx, coord.logKey(id)).Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists)
+
+	err = coord.Run(ctx, func(s *Saga) error {
+		return s.Compensate(ctx, "missing", nil)
+	})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestSagaRecover(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coord := NewSagaCoordinator(repo.provider, "saga:")
+
+	attempts := 0
+	var logged []int64
+	coord.RegisterCompensation("flaky", func(ctx context.Context, payload json.RawMessage) error {
+		attempts++
+		keys, err := repo.client.Keys(ctx, "saga:log:*").Result()
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		logged = append(logged, repo.client.LLen(ctx, keys[0]).Val())
+		if attempts == 1 {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	})
+
+	err := coord.Run(ctx, func(s *Saga) error {
+		require.NoError(t, s.Compensate(ctx, "flaky", 1))
+		return errors.New("step failed")
+	})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeTransaction))
+
+	pending, err := coord.Pending(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+
+	recovered, err := coord.Recover(ctx, 0*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, pending, recovered)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []int64{1, 1}, logged, "a compensation stays logged while its handler runs")
+}
@@ -0,0 +1,91 @@
+package gparedis
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Random Sampling
+// =====================================
+
+// RandomKey returns a random key matching pattern.
+// Uses RANDOMKEY when the repository is unprefixed and the pattern matches everything,
+// otherwise falls back to SCAN-based sampling.
+// Returns ErrorTypeNotFound if no key matches.
+// Example: key, err := repo.RandomKey(ctx, "user:*")
+func (r *Repository[T]) RandomKey(ctx context.Context, pattern string) (string, error) {
+	if r.keyPrefix == "" && (pattern == "" || pattern == "*") {
+		if err := r.authorize(ctx, AccessScan, "*"); err != nil {
+			return "", err
+		}
+		key, err := r.client.RandomKey(ctx).Result()
+		if err != nil {
+			return "", convertRedisError(err)
+		}
+		return key, nil
+	}
+
+	keys, err := r.Sample(ctx, 1, pattern)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", gpa.NewError(gpa.ErrorTypeNotFound, "no keys match pattern: "+pattern)
+	}
+	return keys[0], nil
+}
+
+// Sample returns up to n keys matching pattern, chosen uniformly at random.
+// Walks the keyspace with SCAN and keeps a reservoir sample, so every matching
+// key has the same chance of being selected. Returned keys have the prefix removed.
+// Example: keys, err := repo.Sample(ctx, 50, "*")
+func (r *Repository[T]) Sample(ctx context.Context, n int, pattern string) ([]string, error) {
+	if n <= 0 {
+		return []string{}, nil
+	}
+	reservoir := make([]string, 0, n)
+	seen := 0
+	err := r.scanEach(ctx, pattern, func(keys []string) error {
+		for _, key := range keys {
+			seen++
+			if len(reservoir) < n {
+				reservoir = append(reservoir, r.trimKey(key))
+			} else if j := rand.Intn(seen); j < n {
+				reservoir[j] = r.trimKey(key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservoir, nil
+}
+
+// SampleMembers returns up to n random members of the set stored at key using SRANDMEMBER.
+func (r *Repository[T]) SampleMembers(ctx context.Context, key string, n int64) ([]string, error) {
+	if err := r.authorizeKeys(ctx, AccessRead, key); err != nil {
+		return nil, err
+	}
+	result := r.client.SRandMemberN(ctx, r.buildKey(key), n)
+	if err := result.Err(); err != nil {
+		return nil, convertRedisError(err)
+	}
+	return result.Val(), nil
+}
+
+// SampleFields returns up to n random field names of the hash stored at key using HRANDFIELD.
+func (r *Repository[T]) SampleFields(ctx context.Context, key string, n int) ([]string, error) {
+	if err := r.authorizeKeys(ctx, AccessRead, key); err != nil {
+		return nil, err
+	}
+	result := r.client.HRandField(ctx, r.buildKey(key), n, false)
+	if err := result.Err(); err != nil {
+		return nil, convertRedisError(err)
+	}
+	return result.Val(), nil
+}
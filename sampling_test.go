@@ -0,0 +1,80 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositorySample(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		require.NoError(t, repo.Set(ctx, key, &TestValue{ID: key, Name: "User", Age: i}))
+	}
+	require.NoError(t, repo.Set(ctx, "product:1", &TestValue{ID: "product:1", Name: "Widget"}))
+
+	keys, err := repo.Sample(ctx, 5, "user:*")
+	require.NoError(t, err)
+	assert.Len(t, keys, 5)
+	for _, key := range keys {
+		assert.Contains(t, key, "user:")
+	}
+
+	// Asking for more than exists returns everything that matches
+	keys, err = repo.Sample(ctx, 100, "user:*")
+	require.NoError(t, err)
+	assert.Len(t, keys, 20)
+
+	keys, err = repo.Sample(ctx, 0, "*")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestRepositoryRandomKey(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	_, err := repo.RandomKey(ctx, "*")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+
+	require.NoError(t, repo.Set(ctx, "user:1", &TestValue{ID: "user:1"}))
+	require.NoError(t, repo.Set(ctx, "product:1", &TestValue{ID: "product:1"}))
+
+	key, err := repo.RandomKey(ctx, "user:*")
+	require.NoError(t, err)
+	assert.Equal(t, "user:1", key)
+
+	key, err = repo.RandomKey(ctx, "")
+	require.NoError(t, err)
+	assert.Contains(t, []string{"user:1", "product:1"}, key)
+
+	_, err = repo.RandomKey(ctx, "order:*")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+}
+
+func TestRepositorySampleMembers(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, repo.client.SAdd(ctx, "tags", "a", "b", "c").Err())
+	require.NoError(t, repo.client.HSet(ctx, "profile", "name", "x", "email", "y").Err())
+
+	members, err := repo.SampleMembers(ctx, "tags", 2)
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	fields, err := repo.SampleFields(ctx, "profile", 5)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"name", "email"}, fields)
+}
@@ -0,0 +1,308 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Recurring Job Scheduler
+// =====================================
+
+// MissedRunPolicy decides what happens to runs that should have fired while no scheduler
+// was running (deploys, outages)
+type MissedRunPolicy int
+
+const (
+	// MissedRunSkip drops runs later than Grace and waits for the next occurrence
+	MissedRunSkip MissedRunPolicy = iota
+	// MissedRunOnce fires a single catch-up run for everything that was missed
+	MissedRunOnce
+	// MissedRunAll fires every missed occurrence, oldest first, up to MaxCatchUp
+	MissedRunAll
+)
+
+// JobRun describes one firing of a recurring job
+type JobRun struct {
+	Name string
+	// ScheduledAt is the occurrence being run
+	ScheduledAt time.Time
+	// CatchUp is set for runs firing later than the job's Grace
+	CatchUp bool
+}
+
+// JobFunc runs one occurrence of a recurring job
+type JobFunc func(ctx context.Context, run JobRun) error
+
+// JobOptions configures a recurring job
+type JobOptions struct {
+	// Missed is the catch-up policy for missed runs (default MissedRunSkip)
+	Missed MissedRunPolicy
+	// Grace is how late a run may fire and still count as on time (default 1m)
+	Grace time.Duration
+	// MaxCatchUp caps the runs fired by MissedRunAll in one go (default 100, most recent kept)
+	MaxCatchUp int
+	// LockTTL bounds how long one instance holds the job while firing it (default 5m)
+	LockTTL time.Duration
+}
+
+// SchedulerOptions configures a Scheduler
+type SchedulerOptions struct {
+	// Interval is how often Run looks for due jobs (default 1s)
+	Interval time.Duration
+	// Location is the time zone cron expressions are evaluated in (default UTC). Every
+	// instance sharing the scheduler must use the same location.
+	Location *time.Location
+	// OnError is called when a job fails or its bookkeeping can't be updated
+	OnError func(name string, err error)
+}
+
+// scheduledJob is a registered recurring job
+type scheduledJob struct {
+	schedule *CronSchedule
+	fn       JobFunc
+	opts     JobOptions
+}
+
+// Scheduler fires recurring jobs on cron schedules across any number of instances. The
+// next run of every job lives in a sorted set (score = unix milliseconds) shared by all
+// instances; a due job is fired by whichever instance takes its lock first, so each
+// occurrence runs once. Runs are at most once: the schedule advances before the job runs.
+type Scheduler struct {
+	provider *Provider
+	client   *redis.Client
+	prefix   string
+	opts     SchedulerOptions
+
+	mu   sync.RWMutex
+	jobs map[string]*scheduledJob
+}
+
+// releaseLockScript deletes a lock only while it still holds the caller's token
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// NewScheduler creates a scheduler keeping its state under prefix
+// Example: cron := gparedis.NewScheduler(provider, "cron:", gparedis.SchedulerOptions{})
+func NewScheduler(provider *Provider, prefix string, opts SchedulerOptions) *Scheduler {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	return &Scheduler{provider: provider, client: provider.client, prefix: prefix, opts: opts, jobs: make(map[string]*scheduledJob)}
+}
+
+// Register adds a recurring job firing on the cron expression spec. The job's next run is
+// kept across restarts, so runs missed while no instance was up are caught up according to
+// opts.Missed; registering a different spec reschedules the job from now.
+// Example: err := cron.Register(ctx, "nightly-report", "0 2 * * *", sendReport, gparedis.JobOptions{Missed: gparedis.MissedRunOnce})
+func (s *Scheduler) Register(ctx context.Context, name, spec string, fn JobFunc, opts JobOptions) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	if opts.Grace <= 0 {
+		opts.Grace = time.Minute
+	}
+	if opts.MaxCatchUp <= 0 {
+		opts.MaxCatchUp = scanBatchSize
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = 5 * time.Minute
+	}
+
+	previous, err := s.client.HGet(ctx, s.specsKey(), name).Result()
+	if err != nil && err != redis.Nil {
+		return convertRedisError(err)
+	}
+	next := schedule.Next(time.Now().In(s.opts.Location))
+	if next.IsZero() {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "cron expression never fires: "+spec)
+	}
+	z := &redis.Z{Score: float64(next.UnixMilli()), Member: name}
+	pipe := s.client.TxPipeline()
+	if previous == spec {
+		pipe.ZAddNX(ctx, s.scheduleKey(), z)
+	} else {
+		pipe.ZAdd(ctx, s.scheduleKey(), z)
+		pipe.HSet(ctx, s.specsKey(), name, spec)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return convertRedisError(err)
+	}
+
+	s.mu.Lock()
+	s.jobs[name] = &scheduledJob{schedule: schedule, fn: fn, opts: opts}
+	s.mu.Unlock()
+	return nil
+}
+
+// Unregister removes a job from this instance and from the shared schedule
+func (s *Scheduler) Unregister(ctx context.Context, name string) error {
+	s.mu.Lock()
+	delete(s.jobs, name)
+	s.mu.Unlock()
+	pipe := s.client.TxPipeline()
+	pipe.ZRem(ctx, s.scheduleKey(), name)
+	pipe.HDel(ctx, s.specsKey(), name)
+	_, err := pipe.Exec(ctx)
+	return convertRedisError(err)
+}
+
+// NextRun returns when a job fires next, or ErrorTypeNotFound for unknown jobs
+func (s *Scheduler) NextRun(ctx context.Context, name string) (time.Time, error) {
+	score, err := s.client.ZScore(ctx, s.scheduleKey(), name).Result()
+	if err == redis.Nil {
+		return time.Time{}, gpa.NewError(gpa.ErrorTypeNotFound, "recurring job not found: "+name)
+	}
+	if err != nil {
+		return time.Time{}, convertRedisError(err)
+	}
+	return time.UnixMilli(int64(score)).In(s.opts.Location), nil
+}
+
+// RunDue fires every due job registered on this instance and returns how many runs fired.
+// Jobs locked by another instance are left to it.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	due, err := s.client.ZRangeByScore(ctx, s.scheduleKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return 0, convertRedisError(err)
+	}
+	fired := 0
+	for _, name := range due {
+		s.mu.RLock()
+		job, ok := s.jobs[name]
+		s.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		n, err := s.fire(ctx, name, job)
+		fired += n
+		if err != nil && s.opts.OnError != nil {
+			s.opts.OnError(name, err)
+		}
+	}
+	return fired, nil
+}
+
+// Run calls RunDue every Interval until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := s.RunDue(ctx); err != nil && s.opts.OnError != nil {
+				s.opts.OnError("", err)
+			}
+		}
+	}
+}
+
+// fire takes the job's lock, advances its schedule and runs the occurrences the missed-run
+// policy selects. Job errors are reported to OnError; the returned error is for bookkeeping.
+func (s *Scheduler) fire(ctx context.Context, name string, job *scheduledJob) (int, error) {
+	token, err := newRandomID()
+	if err != nil {
+		return 0, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to generate lock token", err)
+	}
+	lockKey := s.lockKey(name)
+	locked, err := s.client.SetNX(ctx, lockKey, token, job.opts.LockTTL).Result()
+	if err != nil || !locked {
+		return 0, convertRedisError(err)
+	}
+	defer releaseLockScript.Run(context.Background(), s.client, []string{lockKey}, token)
+
+	// Re-read under the lock: another instance may have fired the job since RunDue looked
+	score, err := s.client.ZScore(ctx, s.scheduleKey(), name).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, convertRedisError(err)
+	}
+	now := time.Now().In(s.opts.Location)
+	scheduled := time.UnixMilli(int64(score)).In(s.opts.Location)
+	if scheduled.After(now) {
+		return 0, nil
+	}
+
+	runs := s.dueRuns(name, job, scheduled, now)
+	next := job.schedule.Next(now)
+	if next.IsZero() {
+		err = s.client.ZRem(ctx, s.scheduleKey(), name).Err()
+	} else {
+		err = s.client.ZAdd(ctx, s.scheduleKey(), &redis.Z{Score: float64(next.UnixMilli()), Member: name}).Err()
+	}
+	if err != nil {
+		return 0, convertRedisError(err)
+	}
+
+	for _, run := range runs {
+		if err := job.fn(ctx, run); err != nil && s.opts.OnError != nil {
+			s.opts.OnError(name, err)
+		}
+	}
+	s.provider.recordProcessed(queueKindScheduler, s.prefix, "", len(runs))
+	return len(runs), nil
+}
+
+// dueRuns lists the occurrences from scheduled up to now and applies the missed-run policy
+func (s *Scheduler) dueRuns(name string, job *scheduledJob, scheduled, now time.Time) []JobRun {
+	var occurrences []time.Time
+	for t := scheduled; !t.IsZero() && !t.After(now); t = job.schedule.Next(t) {
+		occurrences = append(occurrences, t)
+		if len(occurrences) > job.opts.MaxCatchUp {
+			occurrences = occurrences[1:]
+		}
+	}
+	run := func(t time.Time) JobRun {
+		return JobRun{Name: name, ScheduledAt: t, CatchUp: now.Sub(t) > job.opts.Grace}
+	}
+
+	var runs []JobRun
+	switch job.opts.Missed {
+	case MissedRunAll:
+		for _, t := range occurrences {
+			runs = append(runs, run(t))
+		}
+	case MissedRunOnce:
+		runs = append(runs, run(occurrences[len(occurrences)-1]))
+	default:
+		if latest := occurrences[len(occurrences)-1]; now.Sub(latest) <= job.opts.Grace {
+			runs = append(runs, run(latest))
+		}
+	}
+	return runs
+}
+
+// scheduleKey is the sorted set of next runs
+func (s *Scheduler) scheduleKey() string {
+	return s.prefix + "schedule"
+}
+
+// specsKey is the hash of registered cron expressions
+func (s *Scheduler) specsKey() string {
+	return s.prefix + "specs"
+}
+
+// lockKey is the lock held while firing a job
+func (s *Scheduler) lockKey(name string) string {
+	return s.prefix + "lock:" + name
+}
@@ -0,0 +1,113 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerCatchUp(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var failures []string
+	cron := NewScheduler(repo.provider, "cron:", SchedulerOptions{
+		OnError: func(name string, err error) { failures = append(failures, name) },
+	})
+	runs := map[string][]JobRun{}
+	record := func(ctx context.Context, run JobRun) error {
+		runs[run.Name] = append(runs[run.Name], run)
+		return nil
+	}
+	require.NoError(t, cron.Register(ctx, "skip", "* * * * *", record, JobOptions{}))
+	require.NoError(t, cron.Register(ctx, "once", "* * * * *", record, JobOptions{Missed: MissedRunOnce}))
+	require.NoError(t, cron.Register(ctx, "all", "* * * * *", record, JobOptions{Missed: MissedRunAll, Grace: time.Second}))
+	require.NoError(t, cron.Register(ctx, "failing", "* * * * *", func(context.Context, JobRun) error {
+		return errors.New("boom")
+	}, JobOptions{}))
+
+	next, err := cron.NextRun(ctx, "skip")
+	require.NoError(t, err)
+	assert.True(t, next.After(time.Now()))
+	fired, err := cron.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, fired, "nothing is due yet")
+
+	// Pretend every instance was down for the last three minutes
+	missed := time.Now().Truncate(time.Minute).Add(-3 * time.Minute)
+	for _, name := range []string{"skip", "once", "all", "failing"} {
+		repo.client.ZAdd(ctx, "cron:schedule", &redis.Z{Score: float64(missed.UnixMilli()), Member: name})
+	}
+	fired, err = cron.RunDue(ctx)
+	require.NoError(t, err)
+
+	assert.Len(t, runs["skip"], 1, "the on-time occurrence fires, missed ones are dropped")
+	assert.False(t, runs["skip"][0].CatchUp)
+	require.Len(t, runs["once"], 1)
+	require.GreaterOrEqual(t, len(runs["all"]), 4, "every missed minute fires")
+	assert.True(t, missed.Equal(runs["all"][0].ScheduledAt))
+	assert.True(t, runs["all"][0].CatchUp)
+	assert.Equal(t, []string{"failing"}, failures)
+	assert.Equal(t, 3+len(runs["all"]), fired)
+
+	next, err = cron.NextRun(ctx, "all")
+	require.NoError(t, err)
+	assert.True(t, next.After(time.Now()), "the schedule advances past now")
+
+	// Re-registering the same spec keeps the schedule, a new spec reschedules
+	repo.client.ZAdd(ctx, "cron:schedule", &redis.Z{Score: float64(missed.UnixMilli()), Member: "once"})
+	require.NoError(t, cron.Register(ctx, "once", "* * * * *", record, JobOptions{Missed: MissedRunOnce}))
+	next, err = cron.NextRun(ctx, "once")
+	require.NoError(t, err)
+	assert.Equal(t, missed.UnixMilli(), next.UnixMilli())
+	require.NoError(t, cron.Register(ctx, "once", "@hourly", record, JobOptions{}))
+	next, err = cron.NextRun(ctx, "once")
+	require.NoError(t, err)
+	assert.Zero(t, next.Minute())
+
+	require.NoError(t, cron.Unregister(ctx, "once"))
+	_, err = cron.NextRun(ctx, "once")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+	assert.Error(t, cron.Register(ctx, "bad", "not cron", record, JobOptions{}))
+}
+
+func TestSchedulerSingleInstanceFires(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	fired := 0
+	job := func(context.Context, JobRun) error {
+		fired++
+		return nil
+	}
+	a := NewScheduler(repo.provider, "cronlock:", SchedulerOptions{})
+	b := NewScheduler(repo.provider, "cronlock:", SchedulerOptions{})
+	require.NoError(t, a.Register(ctx, "sweep", "* * * * *", job, JobOptions{}))
+	require.NoError(t, b.Register(ctx, "sweep", "* * * * *", job, JobOptions{}))
+	due := float64(time.Now().Add(-time.Second).UnixMilli())
+	repo.client.ZAdd(ctx, "cronlock:schedule", &redis.Z{Score: due, Member: "sweep"})
+
+	// Another instance holds the job
+	repo.client.Set(ctx, "cronlock:lock:sweep", "other", time.Minute)
+	n, err := a.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	repo.client.Del(ctx, "cronlock:lock:sweep")
+
+	n, err = a.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = b.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n, "the occurrence already fired on the other instance")
+	assert.Equal(t, 1, fired)
+	assert.Zero(t, repo.client.Exists(ctx, "cronlock:lock:sweep").Val(), "the lock is released")
+}
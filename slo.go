@@ -0,0 +1,313 @@
+package gparedis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// =====================================
+// Latency Histograms and SLO Tracking
+// =====================================
+
+// latencyBuckets are the upper bounds of the command latency histogram
+var latencyBuckets = []time.Duration{
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond, 10 * time.Millisecond,
+	25 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond,
+	500 * time.Millisecond, time.Second, 2500 * time.Millisecond,
+}
+
+// LatencyHistogram is the command latency distribution of one repository prefix
+type LatencyHistogram struct {
+	// Buckets are the upper bounds; Counts[i] is the number of commands at or below Buckets[i]
+	// (cumulative, as in Prometheus), and Count includes commands above the last bucket
+	Buckets []time.Duration
+	Counts  []uint64
+	Count   uint64
+	Sum     time.Duration
+}
+
+// observe adds one latency sample
+func (h *LatencyHistogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Buckets = latencyBuckets
+		h.Counts = make([]uint64, len(latencyBuckets))
+	}
+	for i, bound := range h.Buckets {
+		if d <= bound {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += d
+}
+
+// clone returns a copy safe to hand out
+func (h *LatencyHistogram) clone() LatencyHistogram {
+	c := *h
+	c.Counts = append([]uint64(nil), h.Counts...)
+	return c
+}
+
+// SLO is a latency objective for the commands of one repository
+type SLO struct {
+	// Objective is the latency each command should stay within
+	Objective time.Duration
+	// Target is the fraction of commands expected to meet the objective (default 0.99)
+	Target float64
+	// Window is the period over which burn rates are evaluated (default 1m)
+	Window time.Duration
+	// AlertBurnRate triggers OnAlert when the violation ratio divided by the error budget
+	// (1 - Target) reaches it within a window (default 1, i.e. the budget is being spent exactly)
+	AlertBurnRate float64
+	// MinSamples is the number of commands a window needs before it can alert (default 20)
+	MinSamples int
+	// OnAlert is called at most once per window when the burn rate reaches AlertBurnRate
+	OnAlert func(SLOAlert)
+}
+
+// withDefaults fills in unset fields
+func (s SLO) withDefaults() SLO {
+	if s.Target <= 0 || s.Target >= 1 {
+		s.Target = 0.99
+	}
+	if s.Window <= 0 {
+		s.Window = time.Minute
+	}
+	if s.AlertBurnRate <= 0 {
+		s.AlertBurnRate = 1
+	}
+	if s.MinSamples <= 0 {
+		s.MinSamples = 20
+	}
+	return s
+}
+
+// SLOAlert reports a window in which a repository burned its error budget too fast
+type SLOAlert struct {
+	Prefix     string
+	Objective  time.Duration
+	Target     float64
+	Commands   uint64
+	Violations uint64
+	BurnRate   float64
+	WindowFrom time.Time
+}
+
+// String describes the alert
+func (a SLOAlert) String() string {
+	return fmt.Sprintf("SLO burn for %q: %d of %d commands exceeded %s since %s (burn rate %.1f, target %.2f%%)",
+		a.Prefix, a.Violations, a.Commands, a.Objective, a.WindowFrom.Format(time.RFC3339), a.BurnRate, a.Target*100)
+}
+
+// SLOStatus is the state of one repository's SLO
+type SLOStatus struct {
+	Prefix string
+	SLO    SLO
+	// Commands and Violations count all commands since the SLO was set
+	Commands   uint64
+	Violations uint64
+	// WindowCommands and WindowViolations cover the current window
+	WindowCommands   uint64
+	WindowViolations uint64
+	// BurnRate is the current window's violation ratio divided by the error budget
+	BurnRate float64
+}
+
+// ViolationRatio returns the fraction of all commands that missed the objective
+func (s SLOStatus) ViolationRatio() float64 {
+	if s.Commands == 0 {
+		return 0
+	}
+	return float64(s.Violations) / float64(s.Commands)
+}
+
+// sloTracker accumulates the violations of one SLO
+type sloTracker struct {
+	slo        SLO
+	commands   uint64
+	violations uint64
+
+	windowStart      time.Time
+	windowCommands   uint64
+	windowViolations uint64
+	alerted          bool
+}
+
+// burnRate returns the current window's burn rate
+func (t *sloTracker) burnRate() float64 {
+	if t.windowCommands == 0 {
+		return 0
+	}
+	return float64(t.windowViolations) / float64(t.windowCommands) / (1 - t.slo.Target)
+}
+
+// record adds one sample and returns an alert when the window starts burning too fast
+func (t *sloTracker) record(prefix string, d time.Duration, now time.Time) (SLOAlert, bool) {
+	if now.Sub(t.windowStart) >= t.slo.Window {
+		t.windowStart = now
+		t.windowCommands, t.windowViolations, t.alerted = 0, 0, false
+	}
+	t.commands++
+	t.windowCommands++
+	if d > t.slo.Objective {
+		t.violations++
+		t.windowViolations++
+	}
+	if t.alerted || t.slo.OnAlert == nil || t.windowCommands < uint64(t.slo.MinSamples) {
+		return SLOAlert{}, false
+	}
+	burn := t.burnRate()
+	if burn < t.slo.AlertBurnRate {
+		return SLOAlert{}, false
+	}
+	t.alerted = true
+	return SLOAlert{
+		Prefix:     prefix,
+		Objective:  t.slo.Objective,
+		Target:     t.slo.Target,
+		Commands:   t.windowCommands,
+		Violations: t.windowViolations,
+		BurnRate:   burn,
+		WindowFrom: t.windowStart,
+	}, true
+}
+
+// status returns the tracker's current state
+func (t *sloTracker) status(prefix string) SLOStatus {
+	return SLOStatus{
+		Prefix:           prefix,
+		SLO:              t.slo,
+		Commands:         t.commands,
+		Violations:       t.violations,
+		WindowCommands:   t.windowCommands,
+		WindowViolations: t.windowViolations,
+		BurnRate:         t.burnRate(),
+	}
+}
+
+// SetSLO sets the latency objective of the repository with the given prefix, replacing any
+// previous one and its counters. A zero Objective removes the SLO.
+// Example: provider.Metrics().SetSLO("user:", gparedis.SLO{Objective: 5 * time.Millisecond, Target: 0.999})
+func (m *Metrics) SetSLO(prefix string, slo SLO) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if slo.Objective <= 0 {
+		delete(m.slos, prefix)
+		return
+	}
+	m.slos[prefix] = &sloTracker{slo: slo.withDefaults(), windowStart: time.Now()}
+}
+
+// SLOStatus returns the state of the SLO set for prefix
+func (m *Metrics) SLOStatus(prefix string) (SLOStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tracker, ok := m.slos[prefix]
+	if !ok {
+		return SLOStatus{}, false
+	}
+	return tracker.status(prefix), true
+}
+
+// SLOStatuses returns the state of every SLO, ordered by prefix
+func (m *Metrics) SLOStatuses() []SLOStatus {
+	m.mu.Lock()
+	statuses := make([]SLOStatus, 0, len(m.slos))
+	for prefix, tracker := range m.slos {
+		statuses = append(statuses, tracker.status(prefix))
+	}
+	m.mu.Unlock()
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Prefix < statuses[j].Prefix })
+	return statuses
+}
+
+// Latency returns the command latency histogram of the repository with the given prefix
+func (m *Metrics) Latency(prefix string) (LatencyHistogram, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.latency[prefix]
+	if !ok {
+		return LatencyHistogram{}, false
+	}
+	return h.clone(), true
+}
+
+// observeLatency records the latency of a command attributed to prefix; callers hold m.mu
+func (m *Metrics) observeLatency(prefix string, d time.Duration, now time.Time) (SLOAlert, bool) {
+	h, ok := m.latency[prefix]
+	if !ok {
+		h = &LatencyHistogram{}
+		m.latency[prefix] = h
+	}
+	h.observe(d)
+	if tracker, ok := m.slos[prefix]; ok {
+		return tracker.record(prefix, d, now)
+	}
+	return SLOAlert{}, false
+}
+
+// writeLatencyPrometheus appends the latency histograms and SLO counters to b
+func (m *Metrics) writeLatencyPrometheus(b *strings.Builder) {
+	m.mu.Lock()
+	prefixes := make([]string, 0, len(m.latency))
+	histograms := make(map[string]LatencyHistogram, len(m.latency))
+	for prefix, h := range m.latency {
+		prefixes = append(prefixes, prefix)
+		histograms[prefix] = h.clone()
+	}
+	m.mu.Unlock()
+	sort.Strings(prefixes)
+
+	b.WriteString("# HELP gparedis_command_duration_seconds Command latency by repository.\n")
+	b.WriteString("# TYPE gparedis_command_duration_seconds histogram\n")
+	for _, prefix := range prefixes {
+		h := histograms[prefix]
+		label := promLabel(prefix)
+		for i, bound := range h.Buckets {
+			fmt.Fprintf(b, "gparedis_command_duration_seconds_bucket{prefix=\"%s\",le=\"%g\"} %d\n",
+				label, bound.Seconds(), h.Counts[i])
+		}
+		fmt.Fprintf(b, "gparedis_command_duration_seconds_bucket{prefix=\"%s\",le=\"+Inf\"} %d\n", label, h.Count)
+		fmt.Fprintf(b, "gparedis_command_duration_seconds_sum{prefix=\"%s\"} %g\n", label, h.Sum.Seconds())
+		fmt.Fprintf(b, "gparedis_command_duration_seconds_count{prefix=\"%s\"} %d\n", label, h.Count)
+	}
+
+	statuses := m.SLOStatuses()
+	if len(statuses) == 0 {
+		return
+	}
+	b.WriteString("# HELP gparedis_slo_objective_seconds Latency objective by repository.\n")
+	b.WriteString("# TYPE gparedis_slo_objective_seconds gauge\n")
+	for _, s := range statuses {
+		fmt.Fprintf(b, "gparedis_slo_objective_seconds{prefix=\"%s\"} %g\n", promLabel(s.Prefix), s.SLO.Objective.Seconds())
+	}
+	b.WriteString("# HELP gparedis_slo_violations_total Commands exceeding the latency objective.\n")
+	b.WriteString("# TYPE gparedis_slo_violations_total counter\n")
+	for _, s := range statuses {
+		fmt.Fprintf(b, "gparedis_slo_violations_total{prefix=\"%s\"} %d\n", promLabel(s.Prefix), s.Violations)
+	}
+	b.WriteString("# HELP gparedis_slo_burn_rate Error budget burn rate in the current window.\n")
+	b.WriteString("# TYPE gparedis_slo_burn_rate gauge\n")
+	for _, s := range statuses {
+		fmt.Fprintf(b, "gparedis_slo_burn_rate{prefix=\"%s\"} %g\n", promLabel(s.Prefix), s.BurnRate)
+	}
+}
+
+// WithSLO sets a latency objective for the repository's commands in the provider's metrics,
+// enabling metric collection if needed
+// Example: users := gparedis.NewRepository[User](provider, client, "user:", gparedis.WithSLO(gparedis.SLO{Objective: 5 * time.Millisecond}))
+func WithSLO(slo SLO) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.slo = &slo
+	}
+}
+
+// registerSLO installs the repository's SLO, if any
+func (r *Repository[T]) registerSLO() {
+	if r.provider == nil || r.opts.slo == nil {
+		return
+	}
+	r.provider.Metrics().SetSLO(r.keyPrefix, *r.opts.slo)
+}
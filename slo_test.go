@@ -0,0 +1,89 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLOTracking(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+	defer repo.provider.DisableMetrics()
+
+	ctx := context.Background()
+	var alerts []SLOAlert
+	users := NewRepository[TestValue](repo.provider, repo.client, "slo:user:", WithSLO(SLO{
+		// Every command misses a 1ns objective
+		Objective:  time.Nanosecond,
+		MinSamples: 5,
+		OnAlert:    func(a SLOAlert) { alerts = append(alerts, a) },
+	}))
+	defer users.DeleteKey(ctx, "1")
+
+	require.NoError(t, users.Set(ctx, "1", &TestValue{ID: "1"}))
+	for i := 0; i < 9; i++ {
+		_, err := users.Get(ctx, "1")
+		require.NoError(t, err)
+	}
+
+	status, ok := repo.provider.Metrics().SLOStatus("slo:user:")
+	require.True(t, ok)
+	assert.Equal(t, uint64(10), status.Commands)
+	assert.Equal(t, uint64(10), status.Violations)
+	assert.Equal(t, 1.0, status.ViolationRatio())
+	assert.InDelta(t, 100, status.BurnRate, 0.001)
+	assert.Equal(t, 0.99, status.SLO.Target)
+
+	// One alert per window
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "slo:user:", alerts[0].Prefix)
+	assert.Equal(t, uint64(5), alerts[0].Commands)
+
+	h, ok := repo.provider.Metrics().Latency("slo:user:")
+	require.True(t, ok)
+	assert.Equal(t, uint64(10), h.Count)
+	assert.Len(t, h.Counts, len(latencyBuckets))
+
+	var out bytes.Buffer
+	require.NoError(t, repo.provider.Metrics().WritePrometheus(&out))
+	assert.Contains(t, out.String(), `gparedis_command_duration_seconds_count{prefix="slo:user:"} 10`)
+	assert.Contains(t, out.String(), `gparedis_slo_violations_total{prefix="slo:user:"} 10`)
+	assert.Contains(t, out.String(), `gparedis_slo_objective_seconds{prefix="slo:user:"} 1e-09`)
+
+	// A generous objective records no violations
+	repo.provider.Metrics().SetSLO("slo:user:", SLO{Objective: time.Minute})
+	_, err := users.Get(ctx, "1")
+	require.NoError(t, err)
+	status, _ = repo.provider.Metrics().SLOStatus("slo:user:")
+	assert.Equal(t, uint64(1), status.Commands)
+	assert.Zero(t, status.Violations)
+
+	repo.provider.Metrics().SetSLO("slo:user:", SLO{})
+	_, ok = repo.provider.Metrics().SLOStatus("slo:user:")
+	assert.False(t, ok)
+}
+
+func TestSLOTrackerWindow(t *testing.T) {
+	tracker := &sloTracker{slo: SLO{Objective: time.Millisecond, MinSamples: 2, Window: time.Minute,
+		OnAlert: func(SLOAlert) {}}.withDefaults()}
+	start := time.Now()
+	tracker.windowStart = start
+
+	_, alerted := tracker.record("p", 2*time.Millisecond, start)
+	assert.False(t, alerted, "below MinSamples")
+	_, alerted = tracker.record("p", 2*time.Millisecond, start)
+	assert.True(t, alerted)
+	_, alerted = tracker.record("p", 2*time.Millisecond, start)
+	assert.False(t, alerted, "already alerted in this window")
+
+	later := start.Add(2 * time.Minute)
+	tracker.record("p", 0, later)
+	assert.Equal(t, uint64(1), tracker.windowCommands)
+	assert.Equal(t, uint64(4), tracker.commands)
+	assert.Equal(t, uint64(3), tracker.violations)
+}
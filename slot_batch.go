@@ -0,0 +1,162 @@
+package gparedis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Slot-Aware Batch Splitting
+// =====================================
+
+// slotBatchParallelism bounds the pipelines a split batch runs concurrently
+const slotBatchParallelism = 4
+
+// buildKeys returns the full keys of repository-relative keys
+func (r *Repository[T]) buildKeys(keys []string) []string {
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = r.buildKey(key)
+	}
+	return fullKeys
+}
+
+// trimKeys strips the prefix from full keys
+func (r *Repository[T]) trimKeys(fullKeys []string) []string {
+	keys := make([]string, len(fullKeys))
+	for i, fullKey := range fullKeys {
+		keys[i] = r.trimKey(fullKey)
+	}
+	return keys
+}
+
+// splitsBySlot reports whether a multi-key command over fullKeys has to be split into
+// single-slot commands: the provider runs in cluster mode and the keys span several slots
+func (r *Repository[T]) splitsBySlot(fullKeys []string) bool {
+	return r.provider != nil && r.provider.clusterMode && !SameSlot(fullKeys...)
+}
+
+// slotGroups groups fullKeys by hash slot, in order of first appearance
+func slotGroups(fullKeys []string) [][]string {
+	index := make(map[int]int)
+	var groups [][]string
+	for _, key := range fullKeys {
+		slot := KeySlot(key)
+		i, ok := index[slot]
+		if !ok {
+			i = len(groups)
+			index[slot] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
+
+// runSlotPipelines calls queue once per group and sends the queued commands in up to
+// slotBatchParallelism concurrent pipelines. The commands are not atomic across groups.
+func runSlotPipelines(ctx context.Context, client *redis.Client, groups [][]string, queue func(pipe redis.Pipeliner, group int)) error {
+	workers := slotBatchParallelism
+	if len(groups) < workers {
+		workers = len(groups)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			_, errs[w] = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for g := w; g < len(groups); g += workers {
+					queue(pipe, g)
+				}
+				return nil
+			})
+		}(w)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return convertRedisError(err)
+		}
+	}
+	return nil
+}
+
+// mgetBySlot runs one MGET per slot and returns the values in the order of fullKeys
+func (r *Repository[T]) mgetBySlot(ctx context.Context, fullKeys []string) ([]interface{}, error) {
+	return mgetGroups(ctx, r.reader(ctx), fullKeys)
+}
+
+// mgetGroups runs one MGET per slot on client and returns the values in the order of fullKeys
+func mgetGroups(ctx context.Context, client *redis.Client, fullKeys []string) ([]interface{}, error) {
+	groups := slotGroups(fullKeys)
+	cmds := make([]*redis.SliceCmd, len(groups))
+	err := runSlotPipelines(ctx, client, groups, func(pipe redis.Pipeliner, g int) {
+		cmds[g] = pipe.MGet(ctx, groups[g]...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]interface{}, len(fullKeys))
+	for g, group := range groups {
+		for i, value := range cmds[g].Val() {
+			byKey[group[i]] = value
+		}
+	}
+	values := make([]interface{}, len(fullKeys))
+	for i, key := range fullKeys {
+		values[i] = byKey[key]
+	}
+	return values, nil
+}
+
+// msetBySlot runs one MSET per slot for the full key/payload pairs
+func (r *Repository[T]) msetBySlot(ctx context.Context, fullKeys []string, payloads map[string][]byte) error {
+	groups := slotGroups(fullKeys)
+	return runSlotPipelines(ctx, r.client, groups, func(pipe redis.Pipeliner, g int) {
+		pairs := make([]interface{}, 0, len(groups[g])*2)
+		for _, key := range groups[g] {
+			pairs = append(pairs, key, payloads[key])
+		}
+		pipe.MSet(ctx, pairs...)
+	})
+}
+
+// setBySlot writes payloads[i] to fullKeys[i] with ttls[i], in one pipeline per slot group
+func (r *Repository[T]) setBySlot(ctx context.Context, fullKeys []string, payloads [][]byte, ttls []time.Duration) error {
+	position := make(map[string]int, len(fullKeys))
+	for i, key := range fullKeys {
+		position[key] = i
+	}
+	groups := slotGroups(fullKeys)
+	return runSlotPipelines(ctx, r.client, groups, func(pipe redis.Pipeliner, g int) {
+		for _, key := range groups[g] {
+			i := position[key]
+			pipe.Set(ctx, key, payloads[i], ttls[i])
+		}
+	})
+}
+
+// delBySlot runs one DEL per slot and returns the total number of keys removed
+func (r *Repository[T]) delBySlot(ctx context.Context, fullKeys []string) (int64, error) {
+	groups := slotGroups(fullKeys)
+	cmds := make([]*redis.IntCmd, len(groups))
+	err := runSlotPipelines(ctx, r.client, groups, func(pipe redis.Pipeliner, g int) {
+		cmds[g] = pipe.Del(ctx, groups[g]...)
+	})
+	if err != nil {
+		return 0, err
+	}
+	var deleted int64
+	for _, cmd := range cmds {
+		deleted += cmd.Val()
+	}
+	return deleted, nil
+}
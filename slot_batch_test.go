@@ -0,0 +1,103 @@
+package gparedis
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturedCmdsKey marks contexts whose commands slotCaptureHook records
+type capturedCmdsKey struct{}
+
+// capturedCmds collects the commands of a test context
+type capturedCmds struct {
+	mu   sync.Mutex
+	cmds []redis.Cmder
+}
+
+// slotCaptureHook records commands issued with a capturedCmdsKey context
+type slotCaptureHook struct{}
+
+func (slotCaptureHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (slotCaptureHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return slotCaptureHook{}.AfterProcessPipeline(ctx, []redis.Cmder{cmd})
+}
+
+func (slotCaptureHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (slotCaptureHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	if c, ok := ctx.Value(capturedCmdsKey{}).(*capturedCmds); ok {
+		c.mu.Lock()
+		c.cmds = append(c.cmds, cmds...)
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+func TestSlotAwareBatches(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	repo.client.AddHook(slotCaptureHook{})
+	repo.provider.clusterMode = true
+	defer func() { repo.provider.clusterMode = false }()
+
+	captured := &capturedCmds{}
+	ctx := context.WithValue(context.Background(), capturedCmdsKey{}, captured)
+	keys := []string{"foo", "bar", "{foo}:2", "baz", "qux"}
+	require.False(t, SameSlot(keys...))
+
+	pairs := make(map[string]*TestValue)
+	for _, key := range keys {
+		pairs[key] = &TestValue{ID: key}
+	}
+	require.NoError(t, repo.MSet(ctx, pairs))
+
+	found, err := repo.MGet(ctx, append(keys, "missing"))
+	require.NoError(t, err)
+	require.Len(t, found, len(keys))
+	for _, key := range keys {
+		assert.Equal(t, key, found[key].ID)
+	}
+
+	// Full-scan queries load their candidates the same way
+	matchedKeys, _, err := repo.filterKeys(ctx, append(keys, "missing"), nil, -1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, keys, matchedKeys)
+
+	deleted, err := repo.MDelete(ctx, append(keys, "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(keys)), deleted)
+
+	// Every multi-key command stayed within one slot
+	counts := make(map[string]int)
+	for _, cmd := range captured.cmds {
+		name := cmd.Name()
+		counts[name]++
+		cmdKeys := commandKeys(cmd)
+		assert.True(t, SameSlot(cmdKeys...), "%s spans slots: %v", name, cmdKeys)
+	}
+	assert.Equal(t, len(slotGroups(keys)), counts["mset"])
+	assert.Equal(t, 2*len(slotGroups(append(keys, "missing"))), counts["mget"])
+	assert.Equal(t, len(slotGroups(append(keys, "missing"))), counts["del"])
+
+	// Single-slot batches are sent as one command
+	captured.cmds = nil
+	_, err = repo.MGet(ctx, []string{"{foo}:1", "{foo}:2"})
+	require.NoError(t, err)
+	assert.Len(t, captured.cmds, 1)
+}
+
+func TestSlotGroups(t *testing.T) {
+	groups := slotGroups([]string{"foo", "bar", "{foo}:x"})
+	assert.Equal(t, [][]string{{"foo", "{foo}:x"}, {"bar"}}, groups)
+}
@@ -0,0 +1,283 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Workflow State Machines
+// =====================================
+
+// StateMachineOptions configures a StateMachine
+type StateMachineOptions struct {
+	// Initial is the state entities are created in (required)
+	Initial string
+	// Transitions maps each state to the states it may move to (required)
+	Transitions map[string][]string
+	// HistoryMaxLen caps each entity's transition history, approximately (default 1000)
+	HistoryMaxLen int64
+}
+
+// StateEntity is an entity with its workflow state
+type StateEntity[T any] struct {
+	ID      string
+	State   string
+	Value   *T
+	Version int64
+	// UpdatedAt is the time of the last transition
+	UpdatedAt time.Time
+}
+
+// StateTransition is one entry of an entity's transition history
+type StateTransition struct {
+	// ID is the history stream entry ID
+	ID string
+	// From is empty for the entry recorded on creation
+	From    string
+	To      string
+	Version int64
+	At      time.Time
+}
+
+// StateMachine stores entities together with their workflow state. Transitions are
+// validated against the allowed-transition table inside a Lua script, so concurrent
+// writers can't move an entity along an illegal edge; every transition is appended to a
+// per-entity history stream in the same script.
+type StateMachine[T any] struct {
+	provider *Provider
+	client   *redis.Client
+	prefix   string
+	opts     StateMachineOptions
+	// sources maps each state to the states allowed to move into it
+	sources map[string][]string
+}
+
+// stateCreateScript creates an entity in its initial state. KEYS[1] is the state hash,
+// KEYS[2] the history stream; ARGV is state, data, now (ms), history max length.
+var stateCreateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return redis.error_reply('DUPLICATE')
+end
+redis.call('HSET', KEYS[1], 'state', ARGV[1], 'data', ARGV[2], 'version', 1, 'updated', ARGV[3])
+redis.call('XADD', KEYS[2], 'MAXLEN', '~', ARGV[4], '*', 'from', '', 'to', ARGV[1], 'version', 1)
+return redis.call('HGETALL', KEYS[1])
+`)
+
+// stateTransitionScript moves an entity to ARGV[1] if its current state is one of ARGV[5..].
+// ARGV[2] replaces the stored data unless empty; ARGV[3] is now (ms), ARGV[4] the history
+// max length.
+var stateTransitionScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'state')
+if not current then
+	return redis.error_reply('NOTFOUND')
+end
+local allowed = false
+for i = 5, #ARGV do
+	if ARGV[i] == current then
+		allowed = true
+		break
+	end
+end
+if not allowed then
+	return redis.error_reply('CONFLICT ' .. current)
+end
+local version = redis.call('HINCRBY', KEYS[1], 'version', 1)
+redis.call('HSET', KEYS[1], 'state', ARGV[1], 'updated', ARGV[3])
+if ARGV[2] ~= '' then
+	redis.call('HSET', KEYS[1], 'data', ARGV[2])
+end
+redis.call('XADD', KEYS[2], 'MAXLEN', '~', ARGV[4], '*', 'from', current, 'to', ARGV[1], 'version', version)
+return redis.call('HGETALL', KEYS[1])
+`)
+
+// NewStateMachine creates a state machine storing entities under prefix
+// Example:
+//
+//	orders, err := gparedis.NewStateMachine[Order](provider, "order:", gparedis.StateMachineOptions{
+//		Initial: "created",
+//		Transitions: map[string][]string{
+//			"created": {"paid", "cancelled"},
+//			"paid":    {"shipped", "refunded"},
+//		},
+//	})
+func NewStateMachine[T any](provider *Provider, prefix string, opts StateMachineOptions) (*StateMachine[T], error) {
+	if opts.Initial == "" {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "state machine needs an initial state")
+	}
+	if len(opts.Transitions) == 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "state machine needs transitions")
+	}
+	if opts.HistoryMaxLen <= 0 {
+		opts.HistoryMaxLen = 1000
+	}
+	sources := make(map[string][]string)
+	for from, targets := range opts.Transitions {
+		for _, to := range targets {
+			sources[to] = append(sources[to], from)
+		}
+	}
+	for _, from := range sources {
+		sort.Strings(from)
+	}
+	return &StateMachine[T]{provider: provider, client: provider.client, prefix: prefix, opts: opts, sources: sources}, nil
+}
+
+// CanTransition reports whether the table allows moving from one state to another
+func (m *StateMachine[T]) CanTransition(from, to string) bool {
+	for _, target := range m.opts.Transitions[from] {
+		if target == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Create stores value in the initial state, or fails with ErrorTypeDuplicate
+func (m *StateMachine[T]) Create(ctx context.Context, id string, value *T) (*StateEntity[T], error) {
+	data, err := m.encode(value)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := stateCreateScript.Run(ctx, m.client, m.keys(id), m.opts.Initial, data, time.Now().UnixMilli(), m.opts.HistoryMaxLen).Result()
+	if err != nil {
+		return nil, m.scriptError(id, "", err)
+	}
+	return m.decodeEntity(id, reply)
+}
+
+// Get returns an entity with its state, or ErrorTypeNotFound
+func (m *StateMachine[T]) Get(ctx context.Context, id string) (*StateEntity[T], error) {
+	fields, err := m.client.HGetAll(ctx, m.stateKey(id)).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	if len(fields) == 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeNotFound, "state machine entity not found: "+id)
+	}
+	reply := make([]interface{}, 0, len(fields)*2)
+	for field, value := range fields {
+		reply = append(reply, field, value)
+	}
+	return m.decodeEntity(id, reply)
+}
+
+// Transition moves an entity to state to, replacing its value unless value is nil. It fails
+// with ErrorTypeConflict when the entity's current state may not move to to, and with
+// ErrorTypeNotFound when the entity doesn't exist.
+// Example: order, err := orders.Transition(ctx, "o-1", "paid", nil)
+func (m *StateMachine[T]) Transition(ctx context.Context, id, to string, value *T) (*StateEntity[T], error) {
+	from, ok := m.sources[to]
+	if !ok {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "no transition leads to state "+to)
+	}
+	data := ""
+	if value != nil {
+		encoded, err := m.encode(value)
+		if err != nil {
+			return nil, err
+		}
+		data = encoded
+	}
+	args := make([]interface{}, 0, len(from)+4)
+	args = append(args, to, data, time.Now().UnixMilli(), m.opts.HistoryMaxLen)
+	for _, state := range from {
+		args = append(args, state)
+	}
+	reply, err := stateTransitionScript.Run(ctx, m.client, m.keys(id), args...).Result()
+	if err != nil {
+		return nil, m.scriptError(id, to, err)
+	}
+	return m.decodeEntity(id, reply)
+}
+
+// History returns up to count transitions of an entity, oldest first (count <= 0 returns all)
+func (m *StateMachine[T]) History(ctx context.Context, id string, count int64) ([]StateTransition, error) {
+	var msgs []redis.XMessage
+	var err error
+	if count > 0 {
+		msgs, err = m.client.XRangeN(ctx, m.historyKey(id), "-", "+", count).Result()
+	} else {
+		msgs, err = m.client.XRange(ctx, m.historyKey(id), "-", "+").Result()
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	transitions := make([]StateTransition, 0, len(msgs))
+	for _, msg := range msgs {
+		transitions = append(transitions, StateTransition{
+			ID:      msg.ID,
+			From:    replyString(msg.Values["from"]),
+			To:      replyString(msg.Values["to"]),
+			Version: replyInt(msg.Values["version"], 0),
+			At:      streamIDTime(msg.ID),
+		})
+	}
+	return transitions, nil
+}
+
+// Delete removes an entity and its history
+func (m *StateMachine[T]) Delete(ctx context.Context, id string) error {
+	return convertRedisError(m.client.Del(ctx, m.keys(id)...).Err())
+}
+
+// scriptError maps the errors raised by the state scripts to adapter errors
+func (m *StateMachine[T]) scriptError(id, to string, err error) error {
+	// Servers prefix single-word error replies with "ERR"
+	msg := strings.TrimPrefix(err.Error(), "ERR ")
+	switch {
+	case msg == "DUPLICATE":
+		return gpa.NewError(gpa.ErrorTypeDuplicate, "state machine entity already exists: "+id)
+	case msg == "NOTFOUND":
+		return gpa.NewError(gpa.ErrorTypeNotFound, "state machine entity not found: "+id)
+	case strings.HasPrefix(msg, "CONFLICT "):
+		return gpa.NewError(ErrorTypeConflict, "illegal transition of "+id+" from "+strings.TrimPrefix(msg, "CONFLICT ")+" to "+to)
+	}
+	return convertRedisError(err)
+}
+
+// decodeEntity parses the HGETALL reply of a state hash
+func (m *StateMachine[T]) decodeEntity(id string, reply interface{}) (*StateEntity[T], error) {
+	fields := replyFields(reply)
+	var value T
+	if err := json.Unmarshal([]byte(replyString(fields["data"])), &value); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize state machine entity", err)
+	}
+	return &StateEntity[T]{
+		ID:        id,
+		State:     replyString(fields["state"]),
+		Value:     &value,
+		Version:   replyInt(fields["version"], 0),
+		UpdatedAt: time.UnixMilli(replyInt(fields["updated"], 0)),
+	}, nil
+}
+
+// encode serializes an entity value
+func (m *StateMachine[T]) encode(value *T) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize state machine entity", err)
+	}
+	return string(data), nil
+}
+
+// keys returns the state hash and history stream of an entity; both share a hash slot
+func (m *StateMachine[T]) keys(id string) []string {
+	return []string{m.stateKey(id), m.historyKey(id)}
+}
+
+// stateKey is the hash holding an entity's state, value and version
+func (m *StateMachine[T]) stateKey(id string) string {
+	return m.prefix + HashTag(id)
+}
+
+// historyKey is the stream of an entity's transitions
+func (m *StateMachine[T]) historyKey(id string) string {
+	return m.prefix + HashTag(id) + ":history"
+}
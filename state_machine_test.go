@@ -0,0 +1,74 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateMachine(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	orders, err := NewStateMachine[TestValue](repo.provider, "sm:order:", StateMachineOptions{
+		Initial: "created",
+		Transitions: map[string][]string{
+			"created": {"paid", "cancelled"},
+			"paid":    {"shipped", "refunded"},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, orders.CanTransition("created", "paid"))
+	assert.False(t, orders.CanTransition("created", "shipped"))
+
+	created, err := orders.Create(ctx, "o1", &TestValue{ID: "o1", Name: "Ann"})
+	require.NoError(t, err)
+	assert.Equal(t, "created", created.State)
+	assert.Equal(t, int64(1), created.Version)
+	_, err = orders.Create(ctx, "o1", &TestValue{ID: "o1"})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+
+	_, err = orders.Transition(ctx, "o1", "shipped", nil)
+	assert.True(t, IsConflictError(err), "created can't move to shipped")
+
+	paid, err := orders.Transition(ctx, "o1", "paid", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "paid", paid.State)
+	assert.Equal(t, "Ann", paid.Value.Name, "a nil value keeps the stored one")
+	shipped, err := orders.Transition(ctx, "o1", "shipped", &TestValue{ID: "o1", Name: "Ann", Age: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), shipped.Version)
+
+	got, err := orders.Get(ctx, "o1")
+	require.NoError(t, err)
+	assert.Equal(t, "shipped", got.State)
+	assert.Equal(t, 3, got.Value.Age)
+
+	history, err := orders.History(ctx, "o1", 0)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "", history[0].From)
+	assert.Equal(t, "created", history[0].To)
+	assert.Equal(t, "paid", history[2].From)
+	assert.Equal(t, "shipped", history[2].To)
+	assert.Equal(t, int64(3), history[2].Version)
+
+	_, err = orders.Transition(ctx, "missing", "paid", nil)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+	_, err = orders.Transition(ctx, "o1", "created", nil)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument), "no edge leads back to created")
+
+	require.NoError(t, orders.Delete(ctx, "o1"))
+	_, err = orders.Get(ctx, "o1")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+	history, err = orders.History(ctx, "o1", 0)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	_, err = NewStateMachine[TestValue](repo.provider, "sm:", StateMachineOptions{})
+	assert.Error(t, err)
+}
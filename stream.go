@@ -0,0 +1,225 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Typed Redis Streams
+// =====================================
+
+// streamDataField is the stream entry field holding the JSON encoded value
+const streamDataField = "data"
+
+// StreamOptions configures a Stream
+type StreamOptions struct {
+	// MaxLen caps the stream length on every Add (0 for unbounded)
+	MaxLen int64
+	// Approx trims with "~" which is much cheaper but may keep a few extra entries
+	Approx bool
+}
+
+// StreamMessage is a decoded stream entry
+type StreamMessage[T any] struct {
+	ID    string
+	Value *T
+}
+
+// Time returns the timestamp embedded in the message ID
+func (m StreamMessage[T]) Time() time.Time {
+	return streamIDTime(m.ID)
+}
+
+// Stream is a typed wrapper around a Redis stream storing JSON encoded values
+type Stream[T any] struct {
+	provider *Provider
+	client   *redis.Client
+	key      string
+	opts     StreamOptions
+}
+
+// NewStream creates a typed stream stored at key
+// Example: events := gparedis.NewStream[OrderEvent](provider, "orders:events", gparedis.StreamOptions{MaxLen: 100000, Approx: true})
+func NewStream[T any](provider *Provider, key string, opts StreamOptions) *Stream[T] {
+	return &Stream[T]{provider: provider, client: provider.client, key: key, opts: opts}
+}
+
+// Key returns the Redis key of the stream
+func (s *Stream[T]) Key() string {
+	return s.key
+}
+
+// Add appends value to the stream and returns the generated message ID. The encoded value is
+// compressed and size-limited according to the provider's SetPayloadOptions.
+func (s *Stream[T]) Add(ctx context.Context, value *T) (string, error) {
+	args, err := s.addArgs(ctx, value)
+	if err != nil {
+		return "", err
+	}
+	id, err := s.client.XAdd(ctx, args).Result()
+	if err != nil {
+		return "", convertRedisError(err)
+	}
+	return id, nil
+}
+
+// addArgs builds the XADD arguments for value, shared with pipelined writers
+func (s *Stream[T]) addArgs(ctx context.Context, value *T) (*redis.XAddArgs, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize stream message", err)
+	}
+	if data, err = s.provider.encodePayload(ctx, data); err != nil {
+		return nil, err
+	}
+	return &redis.XAddArgs{
+		Stream: s.key,
+		MaxLen: s.opts.MaxLen,
+		Approx: s.opts.Approx,
+		Values: []interface{}{streamDataField, data},
+	}, nil
+}
+
+// Len returns the number of entries in the stream
+func (s *Stream[T]) Len(ctx context.Context) (int64, error) {
+	n, err := s.client.XLen(ctx, s.key).Result()
+	return n, convertRedisError(err)
+}
+
+// Range returns up to count messages with IDs between start and end inclusive ("-" and "+" for the ends).
+// A count of 0 returns all matching messages.
+func (s *Stream[T]) Range(ctx context.Context, start, end string, count int64) ([]StreamMessage[T], error) {
+	var msgs []redis.XMessage
+	var err error
+	if count > 0 {
+		msgs, err = s.client.XRangeN(ctx, s.key, start, end, count).Result()
+	} else {
+		msgs, err = s.client.XRange(ctx, s.key, start, end).Result()
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	return decodeStreamMessages[T](ctx, s.provider, msgs)
+}
+
+// Read returns up to count messages after lastID ("0" for the beginning), waiting up to block
+// for new messages when none are available (negative block returns immediately).
+func (s *Stream[T]) Read(ctx context.Context, lastID string, count int64, block time.Duration) ([]StreamMessage[T], error) {
+	streams, err := s.client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{s.key, lastID},
+		Count:   count,
+		Block:   block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	return decodeStreamMessages[T](ctx, s.provider, streams[0].Messages)
+}
+
+// CreateGroup creates a consumer group starting at startID ("0" for all history, "$" for new messages).
+// The stream is created if missing; an already existing group is not an error.
+func (s *Stream[T]) CreateGroup(ctx context.Context, group, startID string) error {
+	err := s.client.XGroupCreateMkStream(ctx, s.key, group, startID).Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return convertRedisError(err)
+}
+
+// ReadGroup reads up to count new messages for consumer in group, waiting up to block
+// (negative block returns immediately). Messages must be acknowledged with Ack.
+func (s *Stream[T]) ReadGroup(ctx context.Context, group, consumer string, count int64, block time.Duration) ([]StreamMessage[T], error) {
+	return s.readGroup(ctx, group, consumer, ">", count, block)
+}
+
+// Pending returns messages delivered to consumer but not yet acknowledged
+func (s *Stream[T]) Pending(ctx context.Context, group, consumer string, count int64) ([]StreamMessage[T], error) {
+	return s.readGroup(ctx, group, consumer, "0", count, -1)
+}
+
+// readGroup runs XREADGROUP from the given ID
+func (s *Stream[T]) readGroup(ctx context.Context, group, consumer, id string, count int64, block time.Duration) ([]StreamMessage[T], error) {
+	streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  []string{s.key, id},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	return decodeStreamMessages[T](ctx, s.provider, streams[0].Messages)
+}
+
+// Ack acknowledges processed messages for group
+func (s *Stream[T]) Ack(ctx context.Context, group string, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	n, err := s.client.XAck(ctx, s.key, group, ids...).Result()
+	s.provider.recordProcessed(queueKindStream, s.key, group, int(n))
+	return convertRedisError(err)
+}
+
+// Trim caps the stream to maxLen entries and returns the number of entries removed
+func (s *Stream[T]) Trim(ctx context.Context, maxLen int64) (int64, error) {
+	n, err := s.client.XTrimMaxLen(ctx, s.key, maxLen).Result()
+	return n, convertRedisError(err)
+}
+
+// decodeStreamMessages decodes the JSON payload of raw stream entries, resolving compressed
+// and claim-checked payloads
+func decodeStreamMessages[T any](ctx context.Context, p *Provider, msgs []redis.XMessage) ([]StreamMessage[T], error) {
+	result := make([]StreamMessage[T], 0, len(msgs))
+	for _, msg := range msgs {
+		// Entries deleted while pending are returned without values
+		raw, ok := msg.Values[streamDataField].(string)
+		if !ok {
+			result = append(result, StreamMessage[T]{ID: msg.ID})
+			continue
+		}
+		data := []byte(raw)
+		if isEncodedPayload(raw) {
+			decoded, err := p.DecodePayload(ctx, data)
+			if err != nil {
+				return nil, err
+			}
+			data = decoded
+		}
+		var value T
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize stream message "+msg.ID, err)
+		}
+		result = append(result, StreamMessage[T]{ID: msg.ID, Value: &value})
+	}
+	return result, nil
+}
+
+// streamIDTime extracts the millisecond timestamp from a stream ID
+func streamIDTime(id string) time.Time {
+	ms := id
+	if i := strings.IndexByte(id, '-'); i >= 0 {
+		ms = id[:i]
+	}
+	var n int64
+	for _, c := range ms {
+		if c < '0' || c > '9' {
+			return time.Time{}
+		}
+		n = n*10 + int64(c-'0')
+	}
+	return time.UnixMilli(n)
+}
@@ -0,0 +1,128 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Deduplicated Stream Processing
+// =====================================
+
+// DedupOptions configures a DedupProcessor
+type DedupOptions struct {
+	// TTL is how long processed message IDs are remembered (default 24h). Redeliveries
+	// later than this are processed again.
+	TTL time.Duration
+}
+
+// DedupProcessor processes a consumer group's messages at most once per message ID as far
+// as the dedup store can tell: IDs of successfully handled messages are recorded together
+// with the acknowledgement, so messages redelivered after a crash or a failed Ack are
+// detected and skipped. A crash between the handler returning and the record being written
+// still redelivers, so handlers should tolerate the rare duplicate.
+type DedupProcessor[T any] struct {
+	stream *Stream[T]
+	group  string
+	opts   DedupOptions
+}
+
+// dedupMarkScript records message IDs as processed, acknowledges them and prunes expired
+// records, atomically. KEYS[1] is the stream, KEYS[2] the dedup sorted set; ARGV is group,
+// now (ms), cutoff (ms), then the IDs.
+var dedupMarkScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', '(' .. ARGV[3])
+for i = 4, #ARGV do
+	redis.call('ZADD', KEYS[2], ARGV[2], ARGV[i])
+	redis.call('XACK', KEYS[1], ARGV[1], ARGV[i])
+end
+return #ARGV - 3
+`)
+
+// Deduplicated returns a processor for group that skips messages it already handled
+// Example: billing := events.Deduplicated("billing", gparedis.DedupOptions{TTL: 48 * time.Hour})
+func (s *Stream[T]) Deduplicated(group string, opts DedupOptions) *DedupProcessor[T] {
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	return &DedupProcessor[T]{stream: s, group: group, opts: opts}
+}
+
+// dedupKey is the sorted set of processed message IDs, scored by processing time
+func (d *DedupProcessor[T]) dedupKey() string {
+	return d.stream.companionKey(":dedup:" + d.group)
+}
+
+// Seen reports whether the message with id was processed within the TTL
+func (d *DedupProcessor[T]) Seen(ctx context.Context, id string) (bool, error) {
+	score, err := d.stream.client.ZScore(ctx, d.dedupKey(), id).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, convertRedisError(err)
+	}
+	return time.Since(time.UnixMilli(int64(score))) < d.opts.TTL, nil
+}
+
+// Process runs fn for msg unless it was already processed, then records and acknowledges
+// it. Messages seen before are acknowledged without calling fn and reported as not
+// processed. When fn fails the message stays pending for redelivery.
+func (d *DedupProcessor[T]) Process(ctx context.Context, msg StreamMessage[T], fn func(ctx context.Context, msg StreamMessage[T]) error) (bool, error) {
+	seen, err := d.Seen(ctx, msg.ID)
+	if err != nil {
+		return false, err
+	}
+	if seen {
+		return false, d.stream.Ack(ctx, d.group, msg.ID)
+	}
+	if err := fn(ctx, msg); err != nil {
+		return false, err
+	}
+	return true, d.mark(ctx, msg.ID)
+}
+
+// Consume reads up to count messages for consumer (its own unacknowledged messages first,
+// then new ones, waiting up to block) and processes each with Process. Returns how many
+// were handled and skipped as duplicates; it stops at the first handler error.
+func (d *DedupProcessor[T]) Consume(ctx context.Context, consumer string, count int64, block time.Duration, fn func(ctx context.Context, msg StreamMessage[T]) error) (handled, skipped int, err error) {
+	msgs, err := d.stream.Pending(ctx, d.group, consumer, count)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(msgs) == 0 {
+		if msgs, err = d.stream.ReadGroup(ctx, d.group, consumer, count, block); err != nil {
+			return 0, 0, err
+		}
+	}
+	for _, msg := range msgs {
+		processed, err := d.Process(ctx, msg, fn)
+		if err != nil {
+			return handled, skipped, err
+		}
+		if processed {
+			handled++
+		} else {
+			skipped++
+		}
+	}
+	return handled, skipped, nil
+}
+
+// mark records ids as processed and acknowledges them
+func (d *DedupProcessor[T]) mark(ctx context.Context, ids ...string) error {
+	now := time.Now()
+	args := make([]interface{}, 0, len(ids)+3)
+	args = append(args, d.group, strconv.FormatInt(now.UnixMilli(), 10), strconv.FormatInt(now.Add(-d.opts.TTL).UnixMilli(), 10))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	err := dedupMarkScript.Run(ctx, d.stream.client, []string{d.stream.key, d.dedupKey()}, args...).Err()
+	if err == nil {
+		d.stream.provider.recordProcessed(queueKindStream, d.stream.key, d.group, len(ids))
+	}
+	return convertRedisError(err)
+}
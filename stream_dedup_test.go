@@ -0,0 +1,64 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamDedupProcessor(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	stream := NewStream[streamTestEvent](repo.provider, "dedup:events", StreamOptions{})
+	require.NoError(t, stream.CreateGroup(ctx, "billing", "0"))
+	for _, status := range []string{"created", "paid"} {
+		_, err := stream.Add(ctx, &streamTestEvent{OrderID: "o1", Status: status})
+		require.NoError(t, err)
+	}
+	billing := stream.Deduplicated("billing", DedupOptions{})
+
+	var seen []string
+	handle := func(ctx context.Context, msg StreamMessage[streamTestEvent]) error {
+		seen = append(seen, msg.Value.Status)
+		return nil
+	}
+	msgs, err := stream.ReadGroup(ctx, "billing", "worker-1", 2, -1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	processed, err := billing.Process(ctx, msgs[0], handle)
+	require.NoError(t, err)
+	assert.True(t, processed)
+	processed, err = billing.Process(ctx, msgs[0], handle)
+	require.NoError(t, err)
+	assert.False(t, processed, "a redelivered message is skipped")
+	assert.Equal(t, []string{"created"}, seen)
+
+	_, err = billing.Process(ctx, msgs[1], func(context.Context, StreamMessage[streamTestEvent]) error {
+		return errors.New("boom")
+	})
+	assert.Error(t, err)
+	ok, err := billing.Seen(ctx, msgs[1].ID)
+	require.NoError(t, err)
+	assert.False(t, ok, "failed messages aren't recorded")
+
+	handled, skipped, err := billing.Consume(ctx, "worker-1", 10, -1, handle)
+	require.NoError(t, err)
+	assert.Equal(t, 1, handled, "the failed message is still pending and retried")
+	assert.Equal(t, 0, skipped)
+	assert.Equal(t, []string{"created", "paid"}, seen)
+
+	pending, err := stream.Pending(ctx, "billing", "worker-1", 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	other := stream.Deduplicated("audit", DedupOptions{})
+	ok, err = other.Seen(ctx, msgs[0].ID)
+	require.NoError(t, err)
+	assert.False(t, ok, "groups are deduplicated independently")
+}
@@ -0,0 +1,329 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Consumer Groups: Receipts, Lag and Rewind
+// =====================================
+
+// DeliveryReceipt records that a group processed a message
+type DeliveryReceipt struct {
+	ID       string
+	Group    string
+	Consumer string
+	// Deliveries is how often the message was delivered before it was acknowledged
+	Deliveries int64
+	AckedAt    time.Time
+}
+
+// receiptRecord is the stored form of a DeliveryReceipt, written by ackReceiptScript
+type receiptRecord struct {
+	Consumer   string `json:"consumer"`
+	Deliveries int64  `json:"deliveries"`
+	Acked      int64  `json:"acked"`
+}
+
+// StreamGroupInfo describes a consumer group, parsed from XINFO GROUPS
+type StreamGroupInfo struct {
+	Name            string
+	Consumers       int64
+	Pending         int64
+	LastDeliveredID string
+	// EntriesRead is the logical read counter of the group (Redis 7+, -1 when unknown)
+	EntriesRead int64
+	// Lag is the number of entries not yet delivered to the group
+	Lag int64
+}
+
+// StreamConsumerInfo describes a group member, parsed from XINFO CONSUMERS
+type StreamConsumerInfo struct {
+	Name    string
+	Pending int64
+	// Idle is the time since the consumer last interacted with the group
+	Idle time.Duration
+}
+
+// ackReceiptScript acknowledges pending messages and records a receipt for each, atomically.
+// KEYS[1] is the stream, KEYS[2] the receipts hash; ARGV is group, now (ms), then the IDs.
+var ackReceiptScript = redis.NewScript(`
+local acked = 0
+for i = 3, #ARGV do
+	local id = ARGV[i]
+	local pending = redis.call('XPENDING', KEYS[1], ARGV[1], id, id, 1)
+	if #pending > 0 then
+		acked = acked + redis.call('XACK', KEYS[1], ARGV[1], id)
+		redis.call('HSET', KEYS[2], id, cjson.encode({consumer = pending[1][2], deliveries = pending[1][4], acked = tonumber(ARGV[2])}))
+	end
+end
+return acked
+`)
+
+// companionKey returns a key sharing the stream's hash slot, so it can be written in the
+// same script as the stream itself
+func (s *Stream[T]) companionKey(suffix string) string {
+	if HashTagOf(s.key) != s.key {
+		return s.key + suffix
+	}
+	return "{" + s.key + "}" + suffix
+}
+
+// receiptsKey is the hash of delivery receipts for group
+func (s *Stream[T]) receiptsKey(group string) string {
+	return s.companionKey(":receipts:" + group)
+}
+
+// deadLetterKey is the stream holding the dead letters of group
+func (s *Stream[T]) deadLetterKey(group string) string {
+	return s.companionKey(":dead:" + group)
+}
+
+// deadLetterScript moves pending messages to the dead-letter stream and acknowledges them,
+// atomically. KEYS[1] is the stream, KEYS[2] the dead-letter stream; ARGV is group, then IDs.
+var deadLetterScript = redis.NewScript(`
+local moved = 0
+for i = 2, #ARGV do
+	local entry = redis.call('XRANGE', KEYS[1], ARGV[i], ARGV[i])
+	if #entry > 0 and redis.call('XACK', KEYS[1], ARGV[1], ARGV[i]) == 1 then
+		redis.call('XADD', KEYS[2], '*', 'source', ARGV[i], unpack(entry[1][2]))
+		moved = moved + 1
+	end
+end
+return moved
+`)
+
+// DeadLetter moves messages group can't process to the group's dead-letter stream and
+// acknowledges them. Returns how many pending messages were moved.
+// Example: _, err := events.DeadLetter(ctx, "billing", msg.ID)
+func (s *Stream[T]) DeadLetter(ctx context.Context, group string, ids ...string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, group)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	n, err := deadLetterScript.Run(ctx, s.client, []string{s.key, s.deadLetterKey(group)}, args...).Int64()
+	return n, convertRedisError(err)
+}
+
+// DeadLetters returns the dead-letter stream of group, to inspect or replay dead letters
+func (s *Stream[T]) DeadLetters(group string) *Stream[T] {
+	return &Stream[T]{provider: s.provider, client: s.client, key: s.deadLetterKey(group)}
+}
+
+// AckWithReceipt acknowledges messages like Ack and records a DeliveryReceipt for each one
+// that was pending, so producers can confirm processing with Receipt. Returns the number
+// of messages acknowledged.
+func (s *Stream[T]) AckWithReceipt(ctx context.Context, group string, ids ...string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, 0, len(ids)+2)
+	args = append(args, group, time.Now().UnixMilli())
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	n, err := ackReceiptScript.Run(ctx, s.client, []string{s.key, s.receiptsKey(group)}, args...).Int64()
+	s.provider.recordProcessed(queueKindStream, s.key, group, int(n))
+	return n, convertRedisError(err)
+}
+
+// Receipt returns the delivery receipt of message id in group, or ErrorTypeNotFound when
+// the message hasn't been acknowledged with AckWithReceipt (or its receipt was pruned)
+func (s *Stream[T]) Receipt(ctx context.Context, group, id string) (*DeliveryReceipt, error) {
+	raw, err := s.client.HGet(ctx, s.receiptsKey(group), id).Result()
+	if err == redis.Nil {
+		return nil, gpa.NewError(gpa.ErrorTypeNotFound, "no delivery receipt for "+id+" in group "+group)
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	var record receiptRecord
+	if err := json.Unmarshal([]byte(raw), &record); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid delivery receipt for "+id, err)
+	}
+	return &DeliveryReceipt{
+		ID:         id,
+		Group:      group,
+		Consumer:   record.Consumer,
+		Deliveries: record.Deliveries,
+		AckedAt:    time.UnixMilli(record.Acked),
+	}, nil
+}
+
+// PruneReceipts removes receipts of messages created before cutoff and returns how many
+// were removed. Run it periodically; receipts are otherwise kept forever.
+func (s *Stream[T]) PruneReceipts(ctx context.Context, group string, cutoff time.Time) (int64, error) {
+	key := s.receiptsKey(group)
+	var removed int64
+	var cursor uint64
+	for {
+		fields, next, err := s.client.HScan(ctx, key, cursor, "", scanBatchSize).Result()
+		if err != nil {
+			return removed, convertRedisError(err)
+		}
+		var stale []string
+		for i := 0; i < len(fields); i += 2 {
+			if streamIDTime(fields[i]).Before(cutoff) {
+				stale = append(stale, fields[i])
+			}
+		}
+		if len(stale) > 0 {
+			n, err := s.client.HDel(ctx, key, stale...).Result()
+			removed += n
+			if err != nil {
+				return removed, convertRedisError(err)
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			return removed, nil
+		}
+	}
+}
+
+// Groups returns the consumer groups of the stream with their lag. When the server doesn't
+// report a reliable lag (before Redis 7, or without an entries-read counter), it is counted
+// with XRANGE, which is O(lag).
+func (s *Stream[T]) Groups(ctx context.Context) ([]StreamGroupInfo, error) {
+	reply, err := s.client.Do(ctx, "XINFO", "GROUPS", s.key).Slice()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	groups := make([]StreamGroupInfo, 0, len(reply))
+	for _, entry := range reply {
+		fields := replyFields(entry)
+		group := StreamGroupInfo{
+			Name:            replyString(fields["name"]),
+			Consumers:       replyInt(fields["consumers"], 0),
+			Pending:         replyInt(fields["pending"], 0),
+			LastDeliveredID: replyString(fields["last-delivered-id"]),
+			EntriesRead:     replyInt(fields["entries-read"], -1),
+			Lag:             replyInt(fields["lag"], -1),
+		}
+		if group.Lag < 0 || group.EntriesRead < 0 {
+			if group.Lag, err = s.countAfter(ctx, group.LastDeliveredID); err != nil {
+				return nil, err
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// Group returns one consumer group, or ErrorTypeNotFound
+func (s *Stream[T]) Group(ctx context.Context, name string) (*StreamGroupInfo, error) {
+	groups, err := s.Groups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].Name == name {
+			return &groups[i], nil
+		}
+	}
+	return nil, gpa.NewError(gpa.ErrorTypeNotFound, "consumer group not found: "+name)
+}
+
+// Consumers returns the members of group, parsed from XINFO CONSUMERS
+func (s *Stream[T]) Consumers(ctx context.Context, group string) ([]StreamConsumerInfo, error) {
+	reply, err := s.client.Do(ctx, "XINFO", "CONSUMERS", s.key, group).Slice()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	consumers := make([]StreamConsumerInfo, 0, len(reply))
+	for _, entry := range reply {
+		fields := replyFields(entry)
+		consumers = append(consumers, StreamConsumerInfo{
+			Name:    replyString(fields["name"]),
+			Pending: replyInt(fields["pending"], 0),
+			Idle:    time.Duration(replyInt(fields["idle"], 0)) * time.Millisecond,
+		})
+	}
+	return consumers, nil
+}
+
+// countAfter counts the entries with IDs greater than id
+func (s *Stream[T]) countAfter(ctx context.Context, id string) (int64, error) {
+	var count int64
+	start := "(" + id
+	for {
+		msgs, err := s.client.XRangeN(ctx, s.key, start, "+", scanBatchSize).Result()
+		if err != nil {
+			return count, convertRedisError(err)
+		}
+		count += int64(len(msgs))
+		if len(msgs) < scanBatchSize {
+			return count, nil
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+}
+
+// SetGroupID moves the last delivered ID of group, so the next ReadGroup continues after
+// id ("0" redelivers the whole stream, "$" skips to new messages). Pending messages are kept.
+func (s *Stream[T]) SetGroupID(ctx context.Context, group, id string) error {
+	return convertRedisError(s.client.XGroupSetID(ctx, s.key, group, id).Err())
+}
+
+// RewindGroup makes group receive again every message added at or after t
+// Example: err := events.RewindGroup(ctx, "billing", time.Now().Add(-time.Hour))
+func (s *Stream[T]) RewindGroup(ctx context.Context, group string, t time.Time) error {
+	return s.SetGroupID(ctx, group, rewindID(t))
+}
+
+// rewindID returns the greatest stream ID before t
+func rewindID(t time.Time) string {
+	ms := t.UnixMilli()
+	if ms <= 0 {
+		return "0-0"
+	}
+	return fmt.Sprintf("%d-%d", ms-1, uint64(math.MaxUint64))
+}
+
+// replyFields turns a flat RESP2 map reply into a map
+func replyFields(entry interface{}) map[string]interface{} {
+	values, _ := entry.([]interface{})
+	fields := make(map[string]interface{}, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		fields[replyString(values[i])] = values[i+1]
+	}
+	return fields
+}
+
+// replyString converts a reply value to a string
+func replyString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return ""
+	}
+}
+
+// replyInt converts a reply value to an integer, returning fallback for nil or non-numeric values
+func replyInt(v interface{}, fallback int64) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case string:
+		if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
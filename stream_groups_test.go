@@ -0,0 +1,100 @@
+package gparedis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamDeliveryReceipts(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	stream := NewStream[streamTestEvent](repo.provider, "receipts:events", StreamOptions{})
+	require.NoError(t, stream.CreateGroup(ctx, "billing", "0"))
+	var ids []string
+	for _, status := range []string{"created", "paid", "shipped"} {
+		id, err := stream.Add(ctx, &streamTestEvent{OrderID: "o1", Status: status})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	msgs, err := stream.ReadGroup(ctx, "billing", "worker-1", 2, -1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	acked, err := stream.AckWithReceipt(ctx, "billing", ids[0], ids[2])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), acked, "only pending messages are acknowledged")
+
+	receipt, err := stream.Receipt(ctx, "billing", ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, "worker-1", receipt.Consumer)
+	assert.Equal(t, int64(1), receipt.Deliveries)
+	assert.Equal(t, "billing", receipt.Group)
+	assert.WithinDuration(t, time.Now(), receipt.AckedAt, time.Minute)
+
+	_, err = stream.Receipt(ctx, "billing", ids[1])
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+
+	pruned, err := stream.PruneReceipts(ctx, "billing", streamIDTime(ids[0]).Add(time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+	_, err = stream.Receipt(ctx, "billing", ids[0])
+	assert.Error(t, err)
+}
+
+func TestStreamGroupInfo(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	stream := NewStream[streamTestEvent](repo.provider, "lag:events", StreamOptions{})
+	require.NoError(t, stream.CreateGroup(ctx, "billing", "0"))
+	for i := 0; i < 5; i++ {
+		_, err := stream.Add(ctx, &streamTestEvent{OrderID: "o1"})
+		require.NoError(t, err)
+	}
+	msgs, err := stream.ReadGroup(ctx, "billing", "worker-1", 2, -1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	group, err := stream.Group(ctx, "billing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), group.Consumers)
+	assert.Equal(t, int64(2), group.Pending)
+	assert.Equal(t, msgs[1].ID, group.LastDeliveredID)
+	assert.Equal(t, int64(3), group.Lag)
+
+	_, err = stream.Group(ctx, "missing")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeNotFound))
+
+	consumers, err := stream.Consumers(ctx, "billing")
+	require.NoError(t, err)
+	require.Len(t, consumers, 1)
+	assert.Equal(t, "worker-1", consumers[0].Name)
+	assert.Equal(t, int64(2), consumers[0].Pending)
+}
+
+func TestStreamRewind(t *testing.T) {
+	at := time.UnixMilli(1700000000000)
+	assert.Equal(t, "1699999999999-18446744073709551615", rewindID(at))
+	assert.Equal(t, "0-0", rewindID(time.UnixMilli(0)))
+
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	stream := NewStream[streamTestEvent](repo.provider, "rewind:events", StreamOptions{})
+	require.NoError(t, stream.CreateGroup(ctx, "billing", "$"))
+	err := stream.RewindGroup(ctx, "billing", time.Now().Add(-time.Hour))
+	if err != nil && strings.Contains(err.Error(), "not supported") {
+		t.Skip("XGROUP SETID not supported by the test server")
+	}
+	require.NoError(t, err)
+}
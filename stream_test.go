@@ -0,0 +1,71 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamTestEvent struct {
+	OrderID string `json:"order_id"`
+	Status  string `json:"status"`
+	Amount  int64  `json:"amount"`
+}
+
+func TestStream(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	stream := NewStream[streamTestEvent](repo.provider, "orders:events", StreamOptions{MaxLen: 3})
+
+	var ids []string
+	for _, status := range []string{"created", "paid", "shipped", "delivered"} {
+		id, err := stream.Add(ctx, &streamTestEvent{OrderID: "o1", Status: status})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	n, err := stream.Len(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n, "MaxLen trims on add")
+
+	all, err := stream.Range(ctx, "-", "+", 0)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, "paid", all[0].Value.Status)
+	assert.WithinDuration(t, time.Now(), all[0].Time(), time.Minute)
+
+	after, err := stream.Read(ctx, ids[2], 10, -1)
+	require.NoError(t, err)
+	require.Len(t, after, 1)
+	assert.Equal(t, "delivered", after[0].Value.Status)
+
+	require.NoError(t, stream.CreateGroup(ctx, "billing", "0"))
+	require.NoError(t, stream.CreateGroup(ctx, "billing", "0"))
+
+	msgs, err := stream.ReadGroup(ctx, "billing", "worker-1", 2, -1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	pending, err := stream.Pending(ctx, "billing", "worker-1", 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	require.NoError(t, stream.Ack(ctx, "billing", msgs[0].ID, msgs[1].ID))
+	pending, err = stream.Pending(ctx, "billing", "worker-1", 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	removed, err := stream.Trim(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+}
+
+func TestStreamIDTime(t *testing.T) {
+	assert.Equal(t, time.UnixMilli(1700000000000), streamIDTime("1700000000000-3"))
+	assert.True(t, streamIDTime("bogus").IsZero())
+}
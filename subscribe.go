@@ -0,0 +1,172 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Key Subscriptions
+// =====================================
+
+// keyspaceEventFlags are the notify-keyspace-events classes key subscriptions rely on:
+// keyspace channels (K), generic (g), string ($), expired (x) and evicted (e) events
+const keyspaceEventFlags = "Kg$xe"
+
+// KeyChange is one observed state of a subscribed key
+type KeyChange[T any] struct {
+	Key string
+	// Value is the new value, nil when the key was deleted or expired
+	Value   *T
+	Deleted bool
+	// Err reports a value that couldn't be decoded
+	Err error
+}
+
+// SubscribeOptions configures a key subscription
+type SubscribeOptions struct {
+	// ResyncInterval re-reads the key when no notification arrived for this long, catching
+	// changes whose notifications were lost (default 30s)
+	ResyncInterval time.Duration
+	// ReconnectBackoff is the pause after a connection error before resubscribing (default 1s)
+	ReconnectBackoff time.Duration
+}
+
+// EnableKeyspaceNotifications adds the notification classes key subscriptions need to the
+// server's notify-keyspace-events setting, keeping the classes already enabled
+func (p *Provider) EnableKeyspaceNotifications(ctx context.Context) error {
+	current, err := p.client.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return convertRedisError(err)
+	}
+	flags := ""
+	if len(current) == 2 {
+		flags, _ = current[1].(string)
+	}
+	for _, flag := range keyspaceEventFlags {
+		// "A" is an alias for every event class, including g, $, x and e
+		if !strings.ContainsRune(flags, flag) && !(flag != 'K' && strings.ContainsRune(flags, 'A')) {
+			flags += string(flag)
+		}
+	}
+	return convertRedisError(p.client.ConfigSet(ctx, "notify-keyspace-events", flags).Err())
+}
+
+// Subscribe yields the value of key whenever it changes, starting with its current state.
+// See SubscribeWith.
+// Example: changes, err := configs.Subscribe(ctx, "feature-flags")
+func (r *Repository[T]) Subscribe(ctx context.Context, key string) (<-chan KeyChange[T], error) {
+	return r.SubscribeWith(ctx, key, SubscribeOptions{})
+}
+
+// SubscribeWith yields the value of key whenever it changes, starting with its current
+// state. Changes are detected through keyspace notifications (see
+// EnableKeyspaceNotifications) followed by a read, so only the latest value is seen when
+// writes arrive in quick succession; identical consecutive values are not repeated. After
+// reconnects and quiet periods the key is re-read, so lost notifications are caught up.
+// The channel is closed when ctx is cancelled.
+func (r *Repository[T]) SubscribeWith(ctx context.Context, key string, opts SubscribeOptions) (<-chan KeyChange[T], error) {
+	if opts.ResyncInterval <= 0 {
+		opts.ResyncInterval = 30 * time.Second
+	}
+	if opts.ReconnectBackoff <= 0 {
+		opts.ReconnectBackoff = time.Second
+	}
+	fullKey := r.buildKey(key)
+	if err := r.authorize(ctx, AccessRead, fullKey); err != nil {
+		return nil, err
+	}
+
+	channel := "__keyspace@" + strconv.Itoa(r.client.Options().DB) + "__:" + fullKey
+	pubsub := r.client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to subscribe to "+channel, err)
+	}
+
+	changes := make(chan KeyChange[T])
+	w := &keyWatcher[T]{repo: r, key: key, fullKey: fullKey, changes: changes}
+	go func() {
+		// Closing the subscription interrupts a pending receive
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+	go func() {
+		defer close(changes)
+		if !w.sync(ctx) {
+			return
+		}
+		for {
+			msg, err := pubsub.ReceiveTimeout(ctx, opts.ResyncInterval)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				if netErr, ok := err.(interface{ Timeout() bool }); !ok || !netErr.Timeout() {
+					// The next Receive reconnects and resubscribes; catch up on what was missed
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(opts.ReconnectBackoff):
+					}
+				}
+			} else if _, ok := msg.(*redis.Pong); ok {
+				continue
+			}
+			if !w.sync(ctx) {
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// keyWatcher tracks the last state yielded for a subscribed key
+type keyWatcher[T any] struct {
+	repo    *Repository[T]
+	key     string
+	fullKey string
+	changes chan<- KeyChange[T]
+
+	synced  bool
+	exists  bool
+	payload []byte
+}
+
+// sync reads the key and yields it when it differs from the last yielded state. Read
+// errors are skipped, to be retried on the next notification or resync. Returns false
+// once ctx is done.
+func (w *keyWatcher[T]) sync(ctx context.Context) bool {
+	r := w.repo
+	if r.near != nil {
+		r.near.Invalidate(w.key)
+	}
+	// Read the primary directly: replicas may lag behind the notification, and an idle
+	// policy GETEX would keep the key alive just because it is watched
+	data, err := r.client.Get(ctx, w.fullKey).Bytes()
+	if err != nil && err != redis.Nil {
+		return ctx.Err() == nil
+	}
+	exists := err == nil
+	if w.synced && exists == w.exists && bytes.Equal(data, w.payload) {
+		return true
+	}
+
+	change := KeyChange[T]{Key: w.key, Deleted: !exists}
+	if exists {
+		change.Value, change.Err = r.decode(data)
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case w.changes <- change:
+	}
+	w.synced, w.exists, w.payload = true, exists, data
+	return true
+}
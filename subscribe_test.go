@@ -0,0 +1,81 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextChange waits for the next change, failing the test after timeout
+func nextChange[T any](t *testing.T, changes <-chan KeyChange[T], timeout time.Duration) KeyChange[T] {
+	t.Helper()
+	select {
+	case change, ok := <-changes:
+		require.True(t, ok, "subscription closed")
+		return change
+	case <-time.After(timeout):
+		t.Fatal("no change received")
+		return KeyChange[T]{}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	configs := NewRepository[TestValue](repo.provider, repo.client, "watch:config:")
+	changes, err := configs.Subscribe(ctx, "flags")
+	require.NoError(t, err)
+	channel := "__keyspace@0__:watch:config:flags"
+
+	initial := nextChange(t, changes, time.Second)
+	assert.True(t, initial.Deleted)
+
+	require.NoError(t, configs.Set(ctx, "flags", &TestValue{ID: "v1"}))
+	require.NoError(t, repo.client.Publish(ctx, channel, "set").Err())
+	change := nextChange(t, changes, time.Second)
+	require.NoError(t, change.Err)
+	assert.Equal(t, "flags", change.Key)
+	assert.Equal(t, "v1", change.Value.ID)
+
+	// An unchanged value isn't repeated
+	require.NoError(t, repo.client.Publish(ctx, channel, "expire").Err())
+	select {
+	case change := <-changes:
+		t.Fatalf("unexpected change %+v", change)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	_, err = configs.MDelete(ctx, []string{"flags"})
+	require.NoError(t, err)
+	require.NoError(t, repo.client.Publish(ctx, channel, "del").Err())
+	assert.True(t, nextChange(t, changes, time.Second).Deleted)
+
+	cancel()
+	for range changes {
+	}
+}
+
+func TestSubscribeResync(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	configs := NewRepository[TestValue](repo.provider, repo.client, "resync:config:")
+	require.NoError(t, configs.Set(ctx, "flags", &TestValue{ID: "v1"}))
+	defer configs.MDelete(ctx, []string{"flags"})
+
+	changes, err := configs.SubscribeWith(ctx, "flags", SubscribeOptions{ResyncInterval: 50 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, "v1", nextChange(t, changes, time.Second).Value.ID)
+
+	// No notification is sent: the change is picked up by the periodic resync
+	require.NoError(t, configs.Set(ctx, "flags", &TestValue{ID: "v2"}))
+	assert.Equal(t, "v2", nextChange(t, changes, time.Second).Value.ID)
+}
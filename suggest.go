@@ -0,0 +1,237 @@
+package gparedis
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Autocomplete Suggestions
+// =====================================
+
+// SuggesterOptions configures a Suggester
+type SuggesterOptions struct {
+	// SearchModule stores the suggestions in an FT.SUGADD dictionary, which requires the
+	// search module; otherwise two sorted sets are used
+	SearchModule bool
+	// MaxCandidates bounds how many prefix matches the sorted set implementation ranks by
+	// score per query (default 200)
+	MaxCandidates int
+}
+
+// Suggestion is a completion with its score
+type Suggestion struct {
+	Term  string
+	Score float64
+}
+
+// Suggester completes prefixes typed by users with known terms, highest score first. Without
+// the search module, terms are kept in a lexicographically ordered sorted set of lowercased
+// terms, for case-insensitive prefix ranges, and a sorted set of scores.
+type Suggester struct {
+	provider *Provider
+	client   *redis.Client
+	key      string
+	opts     SuggesterOptions
+}
+
+// NewSuggester creates a suggester stored at key
+// Example: cities := gparedis.NewSuggester(provider, "suggest:cities", gparedis.SuggesterOptions{})
+func NewSuggester(provider *Provider, key string, opts SuggesterOptions) *Suggester {
+	if opts.MaxCandidates <= 0 {
+		opts.MaxCandidates = 200
+	}
+	return &Suggester{provider: provider, client: provider.client, key: key, opts: opts}
+}
+
+// lexKey is the sorted set of "lowercased term\x00term" members with equal scores
+func (s *Suggester) lexKey() string {
+	return s.key + ":lex"
+}
+
+// scoreKey is the sorted set of terms scored by weight
+func (s *Suggester) scoreKey() string {
+	return s.key + ":scores"
+}
+
+// lexMember encodes a term for the lexicographic set
+func lexMember(term string) string {
+	return strings.ToLower(term) + "\x00" + term
+}
+
+// Add stores term with score, replacing its previous score
+// Example: err := cities.Add(ctx, "Amsterdam", 10)
+func (s *Suggester) Add(ctx context.Context, term string, score float64) error {
+	return s.add(ctx, term, score, false)
+}
+
+// Boost adds by to the score of term, adding the term when it is unknown, such as when a
+// suggestion is picked
+func (s *Suggester) Boost(ctx context.Context, term string, by float64) error {
+	return s.add(ctx, term, by, true)
+}
+
+// add stores or increments the score of term
+func (s *Suggester) add(ctx context.Context, term string, score float64, incr bool) error {
+	if term == "" {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "empty suggestion")
+	}
+	if s.opts.SearchModule {
+		args := []interface{}{"FT.SUGADD", s.key, term, score}
+		if incr {
+			args = append(args, "INCR")
+		}
+		return convertRedisError(s.client.Do(ctx, args...).Err())
+	}
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, s.lexKey(), &redis.Z{Member: lexMember(term)})
+		if incr {
+			pipe.ZIncrBy(ctx, s.scoreKey(), score, term)
+		} else {
+			pipe.ZAdd(ctx, s.scoreKey(), &redis.Z{Score: score, Member: term})
+		}
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// Remove forgets term
+func (s *Suggester) Remove(ctx context.Context, term string) error {
+	if s.opts.SearchModule {
+		return convertRedisError(s.client.Do(ctx, "FT.SUGDEL", s.key, term).Err())
+	}
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, s.lexKey(), lexMember(term))
+		pipe.ZRem(ctx, s.scoreKey(), term)
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// Suggest returns up to n terms starting with prefix (case-insensitively), highest score first
+// Example: suggestions, err := cities.Suggest(ctx, "ams", 5)
+func (s *Suggester) Suggest(ctx context.Context, prefix string, n int) ([]Suggestion, error) {
+	return s.suggest(ctx, prefix, n, false)
+}
+
+// SuggestFuzzy is Suggest tolerating one typo (a wrong, missing or extra character) in the
+// prefix. Without the search module the first character must match.
+func (s *Suggester) SuggestFuzzy(ctx context.Context, prefix string, n int) ([]Suggestion, error) {
+	return s.suggest(ctx, prefix, n, true)
+}
+
+// suggest runs a prefix query
+func (s *Suggester) suggest(ctx context.Context, prefix string, n int, fuzzy bool) ([]Suggestion, error) {
+	if n <= 0 || prefix == "" {
+		return []Suggestion{}, nil
+	}
+	if s.opts.SearchModule {
+		return s.suggestModule(ctx, prefix, n, fuzzy)
+	}
+
+	lowered := strings.ToLower(prefix)
+	rangePrefix := lowered
+	if fuzzy {
+		_, size := utf8.DecodeRuneInString(lowered)
+		rangePrefix = lowered[:size]
+	}
+	members, err := s.client.ZRangeByLex(ctx, s.lexKey(), &redis.ZRangeBy{
+		Min:   "[" + rangePrefix,
+		Max:   "[" + rangePrefix + "\xff",
+		Count: int64(s.opts.MaxCandidates),
+	}).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+
+	var terms []string
+	for _, member := range members {
+		i := strings.IndexByte(member, 0)
+		if i < 0 {
+			continue
+		}
+		if fuzzy && !fuzzyPrefixMatch(member[:i], lowered) {
+			continue
+		}
+		terms = append(terms, member[i+1:])
+	}
+	if len(terms) == 0 {
+		return []Suggestion{}, nil
+	}
+
+	scores := make([]*redis.FloatCmd, len(terms))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, term := range terms {
+			scores[i] = pipe.ZScore(ctx, s.scoreKey(), term)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, convertRedisError(err)
+	}
+	suggestions := make([]Suggestion, len(terms))
+	for i, term := range terms {
+		suggestions[i] = Suggestion{Term: term, Score: scores[i].Val()}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Score > suggestions[j].Score })
+	if len(suggestions) > n {
+		suggestions = suggestions[:n]
+	}
+	return suggestions, nil
+}
+
+// suggestModule runs FT.SUGGET
+func (s *Suggester) suggestModule(ctx context.Context, prefix string, n int, fuzzy bool) ([]Suggestion, error) {
+	args := []interface{}{"FT.SUGGET", s.key, prefix}
+	if fuzzy {
+		args = append(args, "FUZZY")
+	}
+	args = append(args, "WITHSCORES", "MAX", n)
+	values, err := s.client.Do(ctx, args...).StringSlice()
+	if err == redis.Nil {
+		return []Suggestion{}, nil
+	}
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	suggestions := make([]Suggestion, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		score, _ := strconv.ParseFloat(values[i+1], 64)
+		suggestions = append(suggestions, Suggestion{Term: values[i], Score: score})
+	}
+	return suggestions, nil
+}
+
+// fuzzyPrefixMatch reports whether some prefix of term is within one edit of prefix
+func fuzzyPrefixMatch(term, prefix string) bool {
+	t, p := []rune(term), []rune(prefix)
+	// Edit distances between prefix and every prefix of term, one row per rune of prefix
+	row := make([]int, len(t)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(p); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if p[i-1] == t[j-1] {
+				cost = 0
+			}
+			current := min(row[j]+1, row[j-1]+1, prev+cost)
+			prev, row[j] = row[j], current
+		}
+	}
+	for _, d := range row {
+		if d <= 1 {
+			return true
+		}
+	}
+	return false
+}
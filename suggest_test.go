@@ -0,0 +1,67 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggester(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	cities := NewSuggester(repo.provider, "test:suggest:cities", SuggesterOptions{})
+	for term, score := range map[string]float64{"Amsterdam": 10, "Amstelveen": 2, "Ambato": 5, "Berlin": 8} {
+		require.NoError(t, cities.Add(ctx, term, score))
+	}
+
+	terms := func(suggestions []Suggestion) []string {
+		result := make([]string, len(suggestions))
+		for i, s := range suggestions {
+			result[i] = s.Term
+		}
+		return result
+	}
+
+	suggestions, err := cities.Suggest(ctx, "AMS", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Amsterdam", "Amstelveen"}, terms(suggestions))
+	assert.Equal(t, 10.0, suggestions[0].Score)
+
+	suggestions, err = cities.Suggest(ctx, "am", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Amsterdam", "Ambato"}, terms(suggestions))
+
+	// Boosting reorders suggestions
+	require.NoError(t, cities.Boost(ctx, "Amstelveen", 20))
+	suggestions, err = cities.Suggest(ctx, "ams", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Amstelveen"}, terms(suggestions))
+
+	// One typo is tolerated by fuzzy queries
+	suggestions, err = cities.Suggest(ctx, "amsd", 5)
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+	suggestions, err = cities.SuggestFuzzy(ctx, "amsd", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Amstelveen", "Amsterdam"}, terms(suggestions))
+	suggestions, err = cities.SuggestFuzzy(ctx, "bexlin", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Berlin"}, terms(suggestions))
+
+	require.NoError(t, cities.Remove(ctx, "Berlin"))
+	suggestions, err = cities.Suggest(ctx, "b", 5)
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+}
+
+func TestFuzzyPrefixMatch(t *testing.T) {
+	assert.True(t, fuzzyPrefixMatch("amsterdam", "amst"))
+	assert.True(t, fuzzyPrefixMatch("amsterdam", "amsr"))
+	assert.True(t, fuzzyPrefixMatch("amsterdam", "amt"))
+	assert.True(t, fuzzyPrefixMatch("amsterdam", "amxst"))
+	assert.False(t, fuzzyPrefixMatch("amsterdam", "axxt"))
+}
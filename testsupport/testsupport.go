@@ -0,0 +1,223 @@
+// Package testsupport starts throwaway Redis containers through testcontainers-go and
+// returns ready gparedis providers, so integration tests don't depend on a locally
+// installed Redis.
+//
+// It is a separate module so the main module doesn't pull in the Docker client:
+//
+//	go get github.com/lemmego/gparedis/testsupport
+//
+// Tests are skipped, not failed, when no Docker daemon is reachable:
+//
+//	func TestCheckout(t *testing.T) {
+//		provider := testsupport.StartT(t, testsupport.Options{})
+//		repo := gparedis.NewRepository[Order](provider, provider.Client().(*redis.Client), "orders:")
+//		...
+//	}
+package testsupport
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/lemmego/gparedis"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Mode selects the kind of Redis started
+type Mode int
+
+const (
+	// ModeSingle starts a plain single-node Redis
+	ModeSingle Mode = iota
+	// ModeCluster starts a single-node Redis Cluster owning every hash slot, and enables
+	// cluster_mode on the provider so multi-key commands are checked for slot conflicts
+	ModeCluster
+	// ModeStack starts Redis Stack, which bundles the search, JSON, time series and bloom
+	// modules
+	ModeStack
+)
+
+// Default images per mode
+const (
+	DefaultImage      = "redis:7-alpine"
+	DefaultStackImage = "redis/redis-stack-server:latest"
+)
+
+// Options configures a Redis container
+type Options struct {
+	// Mode is the kind of Redis to start (default ModeSingle)
+	Mode Mode
+	// Image overrides the container image (default DefaultImage, or DefaultStackImage for
+	// ModeStack)
+	Image string
+	// RedisOptions are passed to the provider as config.Options["redis"]
+	RedisOptions map[string]interface{}
+	// StartupTimeout bounds how long to wait for Redis to accept connections (default 60s)
+	StartupTimeout time.Duration
+}
+
+// Redis is a running Redis container with a provider connected to it
+type Redis struct {
+	Container testcontainers.Container
+	Provider  *gparedis.Provider
+	// URL is the redis:// URL of the container, for clients other than the provider
+	URL string
+}
+
+// Start runs a Redis container and connects a provider to it. Call Terminate when done.
+// Example: r, err := testsupport.Start(ctx, testsupport.Options{Mode: testsupport.ModeCluster})
+func Start(ctx context.Context, opts Options) (*Redis, error) {
+	if opts.Image == "" {
+		opts.Image = DefaultImage
+		if opts.Mode == ModeStack {
+			opts.Image = DefaultStackImage
+		}
+	}
+	if opts.StartupTimeout <= 0 {
+		opts.StartupTimeout = time.Minute
+	}
+
+	customizers := []testcontainers.ContainerCustomizer{
+		testcontainers.WithExposedPorts("6379/tcp"),
+		testcontainers.WithWaitStrategyAndDeadline(opts.StartupTimeout,
+			wait.ForLog("Ready to accept connections"),
+			wait.ForListeningPort("6379/tcp"),
+		),
+	}
+	if opts.Mode == ModeCluster {
+		customizers = append(customizers, testcontainers.WithCmd("redis-server", "--cluster-enabled", "yes"))
+	}
+	container, err := testcontainers.Run(ctx, opts.Image, customizers...)
+	if err != nil {
+		if container != nil {
+			_ = testcontainers.TerminateContainer(container)
+		}
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to start Redis container "+opts.Image, err)
+	}
+	r := &Redis{Container: container}
+
+	if opts.Mode == ModeCluster {
+		if err := r.initCluster(ctx, opts.StartupTimeout); err != nil {
+			_ = r.Terminate(ctx)
+			return nil, err
+		}
+	}
+
+	endpoint, err := container.PortEndpoint(ctx, "6379/tcp", "redis")
+	if err != nil {
+		_ = r.Terminate(ctx)
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to resolve Redis container address", err)
+	}
+	r.URL = endpoint
+
+	redisOptions := make(map[string]interface{}, len(opts.RedisOptions)+1)
+	for k, v := range opts.RedisOptions {
+		redisOptions[k] = v
+	}
+	if opts.Mode == ModeCluster {
+		redisOptions["cluster_mode"] = true
+	}
+	provider, err := gparedis.NewProvider(gpa.Config{
+		Driver:        "redis",
+		ConnectionURL: endpoint,
+		Options:       map[string]interface{}{"redis": redisOptions},
+	})
+	if err != nil {
+		_ = r.Terminate(ctx)
+		return nil, err
+	}
+	r.Provider = provider
+	return r, nil
+}
+
+// StartT starts a Redis container for a test and returns its provider. The test is skipped
+// when Docker isn't available and fails when the container can't be started; the provider
+// and container are cleaned up when the test finishes.
+// Example: provider := testsupport.StartT(t, testsupport.Options{Mode: testsupport.ModeStack})
+func StartT(t testing.TB, opts Options) *gparedis.Provider {
+	t.Helper()
+	if err := dockerAvailable(); err != nil {
+		t.Skipf("testsupport: Docker is not available: %v", err)
+	}
+	r, err := Start(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("testsupport: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := r.Terminate(context.Background()); err != nil {
+			t.Errorf("testsupport: %v", err)
+		}
+	})
+	return r.Provider
+}
+
+// Terminate closes the provider and removes the container
+func (r *Redis) Terminate(ctx context.Context) error {
+	if r.Provider != nil {
+		_ = r.Provider.Close()
+	}
+	if err := testcontainers.TerminateContainer(r.Container); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to remove Redis container", err)
+	}
+	return nil
+}
+
+// initCluster assigns every hash slot to the container's node and waits for the cluster
+// to report ok
+func (r *Redis) initCluster(ctx context.Context, timeout time.Duration) error {
+	if _, err := r.exec(ctx, "redis-cli", "CLUSTER", "ADDSLOTSRANGE", "0", "16383"); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		info, err := r.exec(ctx, "redis-cli", "CLUSTER", "INFO")
+		if err != nil {
+			return err
+		}
+		if strings.Contains(info, "cluster_state:ok") {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return gpa.NewError(gpa.ErrorTypeTimeout, "Redis cluster didn't become ready")
+		}
+		select {
+		case <-ctx.Done():
+			return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "Redis cluster didn't become ready", ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// exec runs a command in the container and returns its output
+func (r *Redis) exec(ctx context.Context, cmd ...string) (string, error) {
+	code, reader, err := r.Container.Exec(ctx, cmd)
+	if err != nil {
+		return "", gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to run "+strings.Join(cmd, " "), err)
+	}
+	output, _ := io.ReadAll(reader)
+	if code != 0 {
+		return "", gpa.NewError(gpa.ErrorTypeConnection, strings.Join(cmd, " ")+" failed: "+string(output))
+	}
+	return string(output), nil
+}
+
+// dockerAvailable reports why containers can't be started, if they can't
+func dockerAvailable() (err error) {
+	// The Docker provider panics on some misconfigured environments
+	defer func() {
+		if r := recover(); r != nil {
+			err = gpa.NewError(gpa.ErrorTypeConnection, "docker provider panicked")
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+	return provider.Health(context.Background())
+}
@@ -0,0 +1,46 @@
+package testsupport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gparedis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestStartSingle(t *testing.T) {
+	provider := StartT(t, Options{})
+	repo := gparedis.NewRepository[user](provider, provider.Client().(*redis.Client), "users:")
+
+	ctx := context.Background()
+	require.NoError(t, repo.Set(ctx, "1", &user{ID: "1", Name: "Ann"}))
+	got, err := repo.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Ann", got.Name)
+}
+
+func TestStartCluster(t *testing.T) {
+	provider := StartT(t, Options{Mode: ModeCluster})
+	repo := gparedis.NewRepository[user](provider, provider.Client().(*redis.Client), "users:")
+
+	ctx := context.Background()
+	require.NoError(t, repo.Set(ctx, "1", &user{ID: "1", Name: "Ann"}))
+	info, err := provider.Client().(*redis.Client).ClusterInfo(ctx).Result()
+	require.NoError(t, err)
+	assert.Contains(t, info, "cluster_state:ok")
+}
+
+func TestStartInvalidImage(t *testing.T) {
+	if err := dockerAvailable(); err != nil {
+		t.Skipf("Docker is not available: %v", err)
+	}
+	_, err := Start(context.Background(), Options{Image: "gparedis-testsupport/does-not-exist:none"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package gparedis
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Full-Text Search Fallback
+// =====================================
+
+// Fields tagged `gpaindex:"name,text"` are indexed per term: the field value is lowercased
+// and split into words, and each distinct word owns a tag set of the keys containing it.
+// Search intersects the sets of the query's words, so it finds entities containing every
+// word. It is a fallback for small datasets on servers without RediSearch: no stemming,
+// ranking or phrase matching.
+
+// textStopWords are common English words that are not indexed
+var textStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "to": true, "with": true,
+}
+
+// tokenize returns the distinct indexable terms of text, sorted
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(words))
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if len([]rune(word)) < 2 || textStopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// SearchKeys returns the keys whose text index contains every word of query, sorted. A
+// query without indexable words matches nothing.
+// Example: keys, err := articles.SearchKeys(ctx, "body", "redis streams")
+func (r *Repository[T]) SearchKeys(ctx context.Context, index, query string) ([]string, error) {
+	def, err := r.findIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	if !def.text {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "index "+index+" is not a text index")
+	}
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return []string{}, nil
+	}
+	sets := make([]string, len(terms))
+	for i, term := range terms {
+		sets[i] = r.tagSetKey(index, term)
+	}
+	keys, err := r.client.SInter(ctx, sets...).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	sort.Strings(keys)
+	if err := r.authorizeKeys(ctx, AccessRead, keys...); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Search returns the entities whose text index contains every word of query, ordered by key
+// Example: found, err := articles.Search(ctx, "body", "redis streams")
+func (r *Repository[T]) Search(ctx context.Context, index, query string) ([]*T, error) {
+	keys, err := r.SearchKeys(ctx, index, query)
+	if err != nil {
+		return nil, err
+	}
+	return r.findKeys(ctx, keys)
+}
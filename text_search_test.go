@@ -0,0 +1,71 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type searchableArticle struct {
+	ID     string `json:"id"`
+	Title  string `json:"title" gpaindex:"text,text"`
+	Body   string `json:"body" gpaindex:"text"`
+	Status string `json:"status" gpaindex:"status"`
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"caching", "redis", "streams", "v7"}, tokenize("The Redis streams, and caching (Redis v7)!"))
+	assert.Empty(t, tokenize("a to the ."))
+}
+
+func TestTextSearch(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[searchableArticle](base.provider, base.client, "article:")
+
+	require.NoError(t, repo.MSet(ctx, map[string]*searchableArticle{
+		"1": {ID: "1", Title: "Redis Streams", Body: "Consumer groups explained", Status: "published"},
+		"2": {ID: "2", Title: "Caching with Redis", Body: "Near caches and invalidation", Status: "draft"},
+		"3": {ID: "3", Title: "Go generics", Body: "Type parameters for repositories", Status: "published"},
+	}))
+
+	keys, err := repo.SearchKeys(ctx, "text", "redis")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, keys)
+
+	// Every word must match, across the fields of the index
+	found, err := repo.Search(ctx, "text", "REDIS caches")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "2", found[0].ID)
+
+	keys, err = repo.SearchKeys(ctx, "text", "the")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// Updates and deletes maintain the terms
+	require.NoError(t, repo.Set(ctx, "1", &searchableArticle{ID: "1", Title: "Kafka streams"}))
+	keys, err = repo.SearchKeys(ctx, "text", "redis")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, keys)
+	require.NoError(t, repo.DeleteKey(ctx, "2"))
+	keys, err = repo.SearchKeys(ctx, "text", "redis")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = repo.SearchKeys(ctx, "status", "published")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+	_, err = repo.KeysByIndex(ctx, "text", "redis", "x")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+
+	// Repair recognizes term sets
+	report, err := repo.RepairIndexes(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, report.Reindexed)
+	assert.Zero(t, report.Removed)
+}
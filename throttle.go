@@ -0,0 +1,168 @@
+package gparedis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Client-Side Throttling
+// =====================================
+
+// ThrottleOptions limits the command throughput of one provider
+type ThrottleOptions struct {
+	// CommandsPerSecond caps the command rate; pipelines count one per command (0 disables)
+	CommandsPerSecond float64
+	// Burst is the number of commands that may run back to back (default: one second's worth)
+	Burst int
+	// MaxConcurrentPipelines caps pipelines and transactions in flight (0 disables)
+	MaxConcurrentPipelines int
+}
+
+// throttle is a token bucket plus a pipeline semaphore. Callers may take more tokens than
+// are available; the debt is paid by waiting, so large pipelines are delayed, not rejected.
+type throttle struct {
+	opts      ThrottleOptions
+	mu        sync.Mutex
+	tokens    float64
+	last      time.Time
+	pipelines chan struct{}
+}
+
+// newThrottle creates a throttle with a full bucket
+func newThrottle(opts ThrottleOptions) *throttle {
+	if opts.Burst <= 0 {
+		opts.Burst = int(opts.CommandsPerSecond)
+		if opts.Burst < 1 {
+			opts.Burst = 1
+		}
+	}
+	t := &throttle{opts: opts, tokens: float64(opts.Burst), last: time.Now()}
+	if opts.MaxConcurrentPipelines > 0 {
+		t.pipelines = make(chan struct{}, opts.MaxConcurrentPipelines)
+	}
+	return t
+}
+
+// wait takes n tokens, sleeping until the bucket covers them or ctx is done
+func (t *throttle) wait(ctx context.Context, n int) error {
+	if t.opts.CommandsPerSecond <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	now := time.Now()
+	t.tokens += now.Sub(t.last).Seconds() * t.opts.CommandsPerSecond
+	if burst := float64(t.opts.Burst); t.tokens > burst {
+		t.tokens = burst
+	}
+	t.last = now
+	t.tokens -= float64(n)
+	deficit := -t.tokens
+	t.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(deficit / t.opts.CommandsPerSecond * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		t.tokens += float64(n)
+		t.mu.Unlock()
+		return throttleError(ctx.Err())
+	}
+}
+
+// acquirePipeline takes a pipeline slot
+func (t *throttle) acquirePipeline(ctx context.Context) (bool, error) {
+	if t.pipelines == nil {
+		return false, nil
+	}
+	select {
+	case t.pipelines <- struct{}{}:
+		return true, nil
+	case <-ctx.Done():
+		return false, throttleError(ctx.Err())
+	}
+}
+
+// releasePipeline returns a pipeline slot
+func (t *throttle) releasePipeline() {
+	<-t.pipelines
+}
+
+// throttleError reports a context that ended while waiting for the throttle
+func throttleError(err error) error {
+	return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "context done while waiting for client-side throttle", err)
+}
+
+// SetThrottle limits the commands this provider sends to Redis, so a runaway batch job can't
+// saturate a shared server. Commands wait for capacity; waiting ends with ErrorTypeTimeout when
+// the context is done. A zero ThrottleOptions removes the limit.
+// Example: provider.SetThrottle(gparedis.ThrottleOptions{CommandsPerSecond: 5000, MaxConcurrentPipelines: 4})
+func (p *Provider) SetThrottle(opts ThrottleOptions) {
+	if opts.CommandsPerSecond <= 0 && opts.MaxConcurrentPipelines <= 0 {
+		p.throttle.Store(nil)
+		return
+	}
+	p.throttle.Store(newThrottle(opts))
+}
+
+// Throttle returns the active throttle settings; ok is false when unthrottled
+func (p *Provider) Throttle() (opts ThrottleOptions, ok bool) {
+	if t := p.throttle.Load(); t != nil {
+		return t.opts, true
+	}
+	return ThrottleOptions{}, false
+}
+
+// throttleHook applies the provider throttle to every command and pipeline
+type throttleHook struct {
+	provider *Provider
+}
+
+// throttleSlotKey marks pipelines holding a pipeline slot of the given throttle
+type throttleSlotKey struct{}
+
+func (h *throttleHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if t := h.provider.throttle.Load(); t != nil {
+		return ctx, t.wait(ctx, 1)
+	}
+	return ctx, nil
+}
+
+func (h *throttleHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *throttleHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	t := h.provider.throttle.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	acquired, err := t.acquirePipeline(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	if acquired {
+		ctx = context.WithValue(ctx, throttleSlotKey{}, t)
+	}
+	if err := t.wait(ctx, len(cmds)); err != nil {
+		return ctx, err
+	}
+	return ctx, nil
+}
+
+func (h *throttleHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	if t, ok := ctx.Value(throttleSlotKey{}).(*throttle); ok {
+		t.releasePipeline()
+	}
+	return nil
+}
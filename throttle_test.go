@@ -0,0 +1,113 @@
+package gparedis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottleTokenBucket(t *testing.T) {
+	th := newThrottle(ThrottleOptions{CommandsPerSecond: 100, Burst: 5})
+	ctx := context.Background()
+
+	start := time.Now()
+	require.NoError(t, th.wait(ctx, 5))
+	assert.Less(t, time.Since(start), 20*time.Millisecond)
+
+	// 10 commands in debt at 100/s is ~100ms
+	require.NoError(t, th.wait(ctx, 10))
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err := th.wait(cancelled, 100)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeTimeout))
+}
+
+func TestProviderThrottle(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	provider := repo.provider
+	provider.SetThrottle(ThrottleOptions{CommandsPerSecond: 50, Burst: 1, MaxConcurrentPipelines: 1})
+	defer provider.SetThrottle(ThrottleOptions{})
+
+	opts, ok := provider.Throttle()
+	require.True(t, ok)
+	assert.Equal(t, 1, opts.MaxConcurrentPipelines)
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		require.NoError(t, provider.client.Ping(ctx).Err())
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+
+	// Pipelines never overlap with one slot
+	provider.SetThrottle(ThrottleOptions{MaxConcurrentPipelines: 1})
+	var inFlight, maxInFlight atomic.Int32
+	provider.client.AddHook(&pipelineProbe{inFlight: &inFlight, max: &maxInFlight})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := provider.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Ping(ctx)
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(1))
+
+	provider.SetThrottle(ThrottleOptions{})
+	_, ok = provider.Throttle()
+	assert.False(t, ok)
+}
+
+func TestThrottleOptions(t *testing.T) {
+	p := &Provider{}
+	applyProviderOptions(p, map[string]interface{}{"max_commands_per_second": 1000, "max_concurrent_pipelines": 2})
+	opts, ok := p.Throttle()
+	require.True(t, ok)
+	assert.Equal(t, ThrottleOptions{CommandsPerSecond: 1000, Burst: 1000, MaxConcurrentPipelines: 2}, opts)
+}
+
+// pipelineProbe counts pipelines executing concurrently
+type pipelineProbe struct {
+	inFlight, max *atomic.Int32
+}
+
+func (p *pipelineProbe) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (p *pipelineProbe) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (p *pipelineProbe) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	n := p.inFlight.Add(1)
+	for {
+		m := p.max.Load()
+		if n <= m || p.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return ctx, nil
+}
+
+func (p *pipelineProbe) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	p.inFlight.Add(-1)
+	return nil
+}
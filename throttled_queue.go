@@ -0,0 +1,276 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Throttled Outbound Queues
+// =====================================
+
+// RateLimit is a token bucket: PerSecond tokens are added every second, up to Burst
+type RateLimit struct {
+	// PerSecond is the sustained rate (0 disables the limit)
+	PerSecond float64
+	// Burst is the number of messages that may go back to back (default: one second's worth)
+	Burst int
+}
+
+// normalize fills in the default burst
+func (l RateLimit) normalize() RateLimit {
+	if l.PerSecond > 0 && l.Burst <= 0 {
+		l.Burst = int(l.PerSecond)
+		if l.Burst < 1 {
+			l.Burst = 1
+		}
+	}
+	return l
+}
+
+// ThrottledQueueOptions configures a ThrottledQueue
+type ThrottledQueueOptions struct {
+	// Providers limits each named provider, like {"twilio": {PerSecond: 10}}
+	Providers map[string]RateLimit
+	// DefaultProvider limits providers missing from Providers (zero: unlimited)
+	DefaultProvider RateLimit
+	// PerRecipient limits each recipient, whatever the provider (zero: unlimited)
+	PerRecipient RateLimit
+}
+
+// OutboundMessage is a message queued for a provider and a recipient
+type OutboundMessage[T any] struct {
+	ID        string
+	Provider  string
+	Recipient string
+	Value     *T
+}
+
+// outboundEnvelope is the stored form of a message; the dequeue script reads its routing fields
+type outboundEnvelope struct {
+	Provider  string          `json:"provider"`
+	Recipient string          `json:"recipient"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// ThrottledQueue is a delayed queue of outbound messages, such as emails or SMS, released no
+// faster than the rate limits of their provider and recipient allow. Message IDs wait in a
+// sorted set at key scored by when they may be sent (unix milliseconds) and payloads live in
+// the hash key+":items". Token buckets are shared by every process using the queue, so a
+// fleet of senders respects a provider's limit as a whole.
+type ThrottledQueue[T any] struct {
+	provider *Provider
+	client   *redis.Client
+	key      string
+	opts     ThrottledQueueOptions
+}
+
+// NewThrottledQueue creates a throttled queue stored at key
+// Example: sms := gparedis.NewThrottledQueue[SMS](provider, "outbound:sms", gparedis.ThrottledQueueOptions{Providers: map[string]gparedis.RateLimit{"twilio": {PerSecond: 10}}, PerRecipient: gparedis.RateLimit{PerSecond: 1.0 / 60}})
+func NewThrottledQueue[T any](provider *Provider, key string, opts ThrottledQueueOptions) *ThrottledQueue[T] {
+	providers := make(map[string]RateLimit, len(opts.Providers))
+	for name, limit := range opts.Providers {
+		providers[name] = limit.normalize()
+	}
+	opts.Providers = providers
+	opts.DefaultProvider = opts.DefaultProvider.normalize()
+	opts.PerRecipient = opts.PerRecipient.normalize()
+	return &ThrottledQueue[T]{provider: provider, client: provider.client, key: key, opts: opts}
+}
+
+// Key returns the Redis key of the sorted set
+func (q *ThrottledQueue[T]) Key() string {
+	return q.key
+}
+
+// itemsKey returns the hash holding the payloads
+func (q *ThrottledQueue[T]) itemsKey() string {
+	return q.key + ":items"
+}
+
+// bucketPrefix prefixes the token bucket hashes
+func (q *ThrottledQueue[T]) bucketPrefix() string {
+	return q.key + ":bucket:"
+}
+
+// Enqueue queues value for recipient through provider, to be sent as soon as the limits allow
+func (q *ThrottledQueue[T]) Enqueue(ctx context.Context, provider, recipient string, value *T) (string, error) {
+	return q.EnqueueAt(ctx, provider, recipient, value, time.Now())
+}
+
+// EnqueueAt queues value to be sent no earlier than at, for scheduled messages
+func (q *ThrottledQueue[T]) EnqueueAt(ctx context.Context, provider, recipient string, value *T, at time.Time) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize outbound message", err)
+	}
+	data, err := json.Marshal(outboundEnvelope{Provider: provider, Recipient: recipient, Value: raw})
+	if err != nil {
+		return "", gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize outbound message", err)
+	}
+	id, err := newRandomID()
+	if err != nil {
+		return "", gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to generate outbound message ID", err)
+	}
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.itemsKey(), id, data)
+		pipe.ZAdd(ctx, q.key, &redis.Z{Score: float64(at.UnixMilli()), Member: id})
+		return nil
+	})
+	if err != nil {
+		return "", convertRedisError(err)
+	}
+	return id, nil
+}
+
+// dequeueThrottledScript pops due messages whose provider and recipient buckets hold a
+// token, and defers the others until their buckets refill.
+// KEYS[1]: sorted set, KEYS[2]: payload hash. ARGV[1]: now (ms), ARGV[2]: batch size,
+// ARGV[3]: scan limit, ARGV[4]: bucket prefix, ARGV[5..6]: recipient rate and burst,
+// ARGV[7..8]: default provider rate and burst, ARGV[9..]: provider, rate, burst triples.
+// Returns {id, payload, ...}.
+var dequeueThrottledScript = dryRunReply(redis.NewScript(`
+local now = tonumber(ARGV[1])
+local limits = {}
+for i = 9, #ARGV, 3 do
+	limits[ARGV[i]] = {tonumber(ARGV[i + 1]), tonumber(ARGV[i + 2])}
+end
+local buckets = {}
+local function bucket(key, rate, burst)
+	if rate <= 0 then
+		return nil
+	end
+	local b = buckets[key]
+	if not b then
+		local state = redis.call('HMGET', key, 'tokens', 'ts')
+		local tokens = tonumber(state[1]) or burst
+		local elapsed = math.max(0, now - (tonumber(state[2]) or now))
+		b = {tokens = math.min(burst, tokens + elapsed * rate / 1000), rate = rate, burst = burst}
+		buckets[key] = b
+	end
+	return b
+end
+-- wait returns the milliseconds until b holds a token
+local function wait(b)
+	if not b or b.tokens >= 1 then
+		return 0
+	end
+	return math.ceil((1 - b.tokens) * 1000 / b.rate)
+end
+
+local out = {}
+local taken = 0
+for _, id in ipairs(redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now, 'LIMIT', 0, ARGV[3])) do
+	if taken >= tonumber(ARGV[2]) then
+		break
+	end
+	local payload = redis.call('HGET', KEYS[2], id)
+	if not payload then
+		redis.call('ZREM', KEYS[1], id)
+	else
+		local msg = cjson.decode(payload)
+		local limit = limits[msg.provider] or {tonumber(ARGV[7]), tonumber(ARGV[8])}
+		local p = bucket(ARGV[4] .. 'provider:' .. msg.provider, limit[1], limit[2])
+		local r = nil
+		if msg.recipient ~= '' then
+			r = bucket(ARGV[4] .. 'recipient:' .. msg.recipient, tonumber(ARGV[5]), tonumber(ARGV[6]))
+		end
+		local delay = math.max(wait(p), wait(r))
+		if delay > 0 then
+			redis.call('ZADD', KEYS[1], now + delay, id)
+		else
+			if p then p.tokens = p.tokens - 1 end
+			if r then r.tokens = r.tokens - 1 end
+			redis.call('ZREM', KEYS[1], id)
+			redis.call('HDEL', KEYS[2], id)
+			out[#out + 1] = id
+			out[#out + 1] = payload
+			taken = taken + 1
+		end
+	end
+end
+for key, b in pairs(buckets) do
+	redis.call('HSET', key, 'tokens', tostring(b.tokens), 'ts', now)
+	redis.call('PEXPIRE', key, math.ceil(b.burst * 1000 / b.rate) + 1000)
+end
+return out
+`), []interface{}{})
+
+// DequeueBatch removes and returns up to n messages that are due and within their limits,
+// oldest first. Due messages held back by a limit are deferred until their bucket refills,
+// so they don't block messages for other providers and recipients; deferred messages of
+// the same bucket may come out in any order. Returns an empty batch when nothing can be
+// sent yet; NextReady tells when to try again.
+// Example: batch, err := sms.DequeueBatch(ctx, 50)
+func (q *ThrottledQueue[T]) DequeueBatch(ctx context.Context, n int64) ([]*OutboundMessage[T], error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	scan := 4 * n
+	if scan < scanBatchSize {
+		scan = scanBatchSize
+	}
+	args := []interface{}{
+		time.Now().UnixMilli(), n, scan, q.bucketPrefix(),
+		q.opts.PerRecipient.PerSecond, q.opts.PerRecipient.Burst,
+		q.opts.DefaultProvider.PerSecond, q.opts.DefaultProvider.Burst,
+	}
+	for _, name := range sortedKeys(q.opts.Providers) {
+		limit := q.opts.Providers[name]
+		args = append(args, name, limit.PerSecond, limit.Burst)
+	}
+	res, err := dequeueThrottledScript.Run(ctx, q.client, []string{q.key, q.itemsKey()}, args...).StringSlice()
+	if err != nil && err != redis.Nil {
+		return nil, convertRedisError(err)
+	}
+
+	batch := make([]*OutboundMessage[T], 0, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		var envelope outboundEnvelope
+		if err := json.Unmarshal([]byte(res[i+1]), &envelope); err != nil {
+			return batch, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize outbound message", err)
+		}
+		var value T
+		if err := json.Unmarshal(envelope.Value, &value); err != nil {
+			return batch, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize outbound message", err)
+		}
+		batch = append(batch, &OutboundMessage[T]{ID: res[i], Provider: envelope.Provider, Recipient: envelope.Recipient, Value: &value})
+	}
+	return batch, nil
+}
+
+// NextReady returns when the earliest queued message may be sent; false when the queue is empty
+func (q *ThrottledQueue[T]) NextReady(ctx context.Context) (time.Time, bool, error) {
+	first, err := q.client.ZRangeWithScores(ctx, q.key, 0, 0).Result()
+	if err != nil {
+		return time.Time{}, false, convertRedisError(err)
+	}
+	if len(first) == 0 {
+		return time.Time{}, false, nil
+	}
+	return time.UnixMilli(int64(first[0].Score)), true, nil
+}
+
+// Len returns the number of queued messages, due or not
+func (q *ThrottledQueue[T]) Len(ctx context.Context) (int64, error) {
+	n, err := q.client.ZCard(ctx, q.key).Result()
+	return n, convertRedisError(err)
+}
+
+// Remove drops a queued message by ID and reports whether it was queued
+func (q *ThrottledQueue[T]) Remove(ctx context.Context, id string) (bool, error) {
+	var removed *redis.IntCmd
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		removed = pipe.ZRem(ctx, q.key, id)
+		pipe.HDel(ctx, q.itemsKey(), id)
+		return nil
+	})
+	if err != nil {
+		return false, convertRedisError(err)
+	}
+	return removed.Val() > 0, nil
+}
@@ -0,0 +1,81 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type outboundSMS struct {
+	Body string `json:"body"`
+}
+
+func TestThrottledQueue(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	q := NewThrottledQueue[outboundSMS](repo.provider, "outbound", ThrottledQueueOptions{
+		Providers:    map[string]RateLimit{"sms": {PerSecond: 1, Burst: 2}},
+		PerRecipient: RateLimit{PerSecond: 1},
+	})
+
+	// Explicit times keep the oldest-first order deterministic
+	start := time.Now().Add(-time.Minute)
+	enqueue := func(provider, recipient string, offset time.Duration) {
+		_, err := q.EnqueueAt(ctx, provider, recipient, &outboundSMS{Body: "hi " + recipient}, start.Add(offset))
+		require.NoError(t, err)
+	}
+	enqueue("sms", "alice", 0)
+	enqueue("sms", "alice", time.Second)
+	enqueue("sms", "bob", 2*time.Second)
+	enqueue("sms", "carol", 3*time.Second)
+	enqueue("email", "dave", 4*time.Second)
+	_, err := q.EnqueueAt(ctx, "email", "erin", &outboundSMS{}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	batch, err := q.DequeueBatch(ctx, 10)
+	require.NoError(t, err)
+	var sent []string
+	for _, msg := range batch {
+		sent = append(sent, msg.Provider+":"+msg.Recipient)
+	}
+	// alice's second message waits for her bucket, carol's for the provider's burst; email is
+	// unlimited but erin's message isn't due
+	assert.Equal(t, []string{"sms:alice", "sms:bob", "email:dave"}, sent)
+	assert.Equal(t, "hi alice", batch[0].Value.Body)
+
+	n, err := q.Len(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	next, ok, err := q.NextReady(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Second), next, time.Second, "deferred messages wait for a token")
+
+	batch, err = q.DequeueBatch(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, batch, "nothing can be sent until the buckets refill")
+
+	// One provider token per second: the two deferred messages leave one after the other
+	time.Sleep(1100 * time.Millisecond)
+	batch, err = q.DequeueBatch(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, batch, 1)
+	time.Sleep(1100 * time.Millisecond)
+	batch, err = q.DequeueBatch(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, batch, 1)
+
+	id, err := q.Enqueue(ctx, "sms", "frank", &outboundSMS{})
+	require.NoError(t, err)
+	removed, err := q.Remove(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	n, err = q.Len(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n, "only the scheduled message is left")
+}
@@ -0,0 +1,181 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Per-Class Command Timeouts
+// =====================================
+
+// CommandClass groups commands with similar latency expectations
+type CommandClass string
+
+const (
+	// CommandClassFast covers single-key and small multi-key commands
+	CommandClassFast CommandClass = "fast"
+	// CommandClassSlow covers keyspace walks, scripts, whole-collection reads, bulk
+	// multi-key commands and pipelines
+	CommandClassSlow CommandClass = "slow"
+	// CommandClassBlocking covers commands that wait server-side (BLPOP, XREAD BLOCK, ...);
+	// they are never given a class timeout
+	CommandClassBlocking CommandClass = "blocking"
+)
+
+// bulkKeyThreshold is the number of keys above which a multi-key command counts as slow
+const bulkKeyThreshold = 16
+
+// slowCommands always count as slow
+var slowCommands = map[string]bool{
+	"keys": true, "scan": true, "sscan": true, "hscan": true, "zscan": true,
+	"eval": true, "evalsha": true, "eval_ro": true, "evalsha_ro": true, "fcall": true,
+	"sort": true, "smembers": true, "hgetall": true, "hkeys": true, "hvals": true,
+	"lrange": true, "zrange": true, "zrangebyscore": true, "zrevrange": true, "zrevrangebyscore": true,
+	"sunion": true, "sinter": true, "sdiff": true, "sunionstore": true, "sinterstore": true, "sdiffstore": true,
+	"zunionstore": true, "zinterstore": true, "xrange": true, "xrevrange": true,
+	"flushdb": true, "flushall": true, "dbsize": true, "info": true, "memory": true, "debug": true,
+	"copy": true, "rename": true, "object": true,
+}
+
+// blockingCommands wait server-side and are left to their own timeouts
+var blockingCommands = map[string]bool{
+	"blpop": true, "brpop": true, "brpoplpush": true, "blmove": true, "blmpop": true,
+	"bzpopmin": true, "bzpopmax": true, "bzmpop": true, "wait": true,
+	"subscribe": true, "psubscribe": true,
+}
+
+// ClassifyCommand returns the timeout class of a command
+func ClassifyCommand(cmd redis.Cmder) CommandClass {
+	name := strings.ToLower(cmd.Name())
+	switch {
+	case blockingCommands[name]:
+		return CommandClassBlocking
+	case name == "xread" || name == "xreadgroup":
+		for _, arg := range cmd.Args() {
+			if s, ok := arg.(string); ok && strings.EqualFold(s, "block") {
+				return CommandClassBlocking
+			}
+		}
+		return CommandClassFast
+	case slowCommands[name]:
+		return CommandClassSlow
+	case len(commandKeys(cmd)) > bulkKeyThreshold:
+		return CommandClassSlow
+	}
+	return CommandClassFast
+}
+
+// CommandTimeouts sets separate deadlines for command classes, so bulk jobs don't force
+// inflated timeouts on latency-critical reads. A zero value leaves the class to the
+// connection's read/write timeouts.
+type CommandTimeouts struct {
+	Fast time.Duration
+	// Slow also applies to pipelines and transactions as a whole
+	Slow time.Duration
+}
+
+// forClass returns the timeout of a class
+func (t CommandTimeouts) forClass(class CommandClass) time.Duration {
+	switch class {
+	case CommandClassFast:
+		return t.Fast
+	case CommandClassSlow:
+		return t.Slow
+	}
+	return 0
+}
+
+// SetCommandTimeouts applies per-class deadlines to every command. The deadline is the
+// earlier of the class timeout and the caller's context deadline; commands that miss it fail
+// with ErrorTypeTimeout. The connection ReadTimeout/WriteTimeout must be at least the slow
+// timeout (the fast_command_timeout and slow_command_timeout options raise them as needed).
+// Example: provider.SetCommandTimeouts(gparedis.CommandTimeouts{Fast: 50 * time.Millisecond, Slow: 5 * time.Second})
+func (p *Provider) SetCommandTimeouts(t CommandTimeouts) {
+	if t == (CommandTimeouts{}) {
+		p.commandTimeouts.Store(nil)
+		return
+	}
+	p.commandTimeouts.Store(&t)
+}
+
+// CommandTimeouts returns the configured per-class timeouts
+func (p *Provider) CommandTimeouts() CommandTimeouts {
+	if t := p.commandTimeouts.Load(); t != nil {
+		return *t
+	}
+	return CommandTimeouts{}
+}
+
+// commandDeadline is the class deadline attached to a command's context
+type commandDeadline struct {
+	parent  context.Context
+	ctx     context.Context
+	cancel  context.CancelFunc
+	class   CommandClass
+	timeout time.Duration
+}
+
+// commandDeadlineKey stores the commandDeadline in a context
+type commandDeadlineKey struct{}
+
+// timeoutHook applies the provider's per-class timeouts
+type timeoutHook struct {
+	provider *Provider
+}
+
+func (h *timeoutHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return h.before(ctx, ClassifyCommand(cmd)), nil
+}
+
+func (h *timeoutHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	timeoutAfter(ctx, []redis.Cmder{cmd})
+	return nil
+}
+
+func (h *timeoutHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return h.before(ctx, CommandClassSlow), nil
+}
+
+func (h *timeoutHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	timeoutAfter(ctx, cmds)
+	return nil
+}
+
+// before derives a context bounded by the class timeout
+func (h *timeoutHook) before(ctx context.Context, class CommandClass) context.Context {
+	t := h.provider.commandTimeouts.Load()
+	if t == nil {
+		return ctx
+	}
+	timeout := t.forClass(class)
+	if timeout <= 0 {
+		return ctx
+	}
+	deadline := &commandDeadline{parent: ctx, class: class, timeout: timeout}
+	deadline.ctx, deadline.cancel = context.WithTimeout(ctx, timeout)
+	return context.WithValue(deadline.ctx, commandDeadlineKey{}, deadline)
+}
+
+// timeoutAfter releases the class deadline and reports commands that missed it as timeouts
+func timeoutAfter(ctx context.Context, cmds []redis.Cmder) {
+	deadline, ok := ctx.Value(commandDeadlineKey{}).(*commandDeadline)
+	if !ok {
+		return
+	}
+	defer deadline.cancel()
+	if deadline.ctx.Err() != context.DeadlineExceeded || deadline.parent.Err() != nil {
+		return
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			cmd.SetErr(gpa.NewErrorWithCause(gpa.ErrorTypeTimeout,
+				fmt.Sprintf("%s exceeded the %s command timeout of %s", strings.ToUpper(cmd.Name()), deadline.class, deadline.timeout), err))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package gparedis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyCommand(t *testing.T) {
+	ctx := context.Background()
+	keys := make([]string, bulkKeyThreshold+1)
+	for i := range keys {
+		keys[i] = fmt.Sprint("k", i)
+	}
+
+	assert.Equal(t, CommandClassFast, ClassifyCommand(redis.NewStringCmd(ctx, "get", "k")))
+	assert.Equal(t, CommandClassFast, ClassifyCommand(redis.NewSliceCmd(ctx, "mget", "a", "b")))
+	assert.Equal(t, CommandClassSlow, ClassifyCommand(redis.NewScanCmd(ctx, nil, "scan", 0)))
+	assert.Equal(t, CommandClassSlow, ClassifyCommand(redis.NewStringSliceCmd(ctx, "KEYS", "*")))
+	args := []interface{}{"del"}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	assert.Equal(t, CommandClassSlow, ClassifyCommand(redis.NewIntCmd(ctx, args...)))
+	assert.Equal(t, CommandClassBlocking, ClassifyCommand(redis.NewStringSliceCmd(ctx, "blpop", "q", 0)))
+	assert.Equal(t, CommandClassBlocking, ClassifyCommand(redis.NewXStreamSliceCmd(ctx, "xread", "block", 0, "streams", "s", "$")))
+	assert.Equal(t, CommandClassFast, ClassifyCommand(redis.NewXStreamSliceCmd(ctx, "xread", "streams", "s", "0")))
+}
+
+func TestCommandTimeouts(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, repo.Set(ctx, "timeouts:1", &TestValue{ID: "1"}))
+	defer repo.DeleteKey(ctx, "timeouts:1")
+
+	// A fast budget no command can meet fails single-key reads, while slow commands run
+	repo.provider.SetCommandTimeouts(CommandTimeouts{Fast: time.Nanosecond, Slow: 5 * time.Second})
+	defer repo.provider.SetCommandTimeouts(CommandTimeouts{})
+	assert.Equal(t, time.Nanosecond, repo.provider.CommandTimeouts().Fast)
+
+	_, err := repo.Get(ctx, "timeouts:1")
+	require.Error(t, err)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeTimeout), "unexpected error: %v", err)
+	assert.Contains(t, err.Error(), "fast command timeout")
+
+	keys, err := repo.Keys(ctx, "timeouts:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"timeouts:1"}, keys)
+
+	_, err = repo.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "timeouts:1")
+		return nil
+	})
+	require.NoError(t, err)
+
+	repo.provider.SetCommandTimeouts(CommandTimeouts{})
+	_, err = repo.Get(ctx, "timeouts:1")
+	require.NoError(t, err)
+}
+
+func TestRaiseTimeout(t *testing.T) {
+	timeout := time.Duration(0)
+	raiseTimeout(&timeout, 5*time.Second)
+	assert.Equal(t, 5*time.Second, timeout)
+
+	timeout = 0
+	raiseTimeout(&timeout, time.Second)
+	assert.Equal(t, time.Duration(0), timeout, "the 3s default already covers it")
+
+	timeout = -1
+	raiseTimeout(&timeout, time.Minute)
+	assert.Equal(t, time.Duration(-1), timeout)
+}
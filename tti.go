@@ -0,0 +1,202 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// =====================================
+// Time-to-Idle Expiry
+// =====================================
+
+// IdlePolicy expires entries that haven't been read for Idle, like expireAfterAccess in
+// common cache libraries, optionally bounded by MaxLifetime (expireAfterWrite)
+type IdlePolicy struct {
+	// Idle is how long an entry survives without being read or written
+	Idle time.Duration
+	// MaxLifetime caps how long an entry lives after its last write, however often it's read (0 disables)
+	MaxLifetime time.Duration
+}
+
+// ttiIndexKey is the sorted set recording the lifetime deadlines (unix ms) of keys under a prefix
+func ttiIndexKey(prefix string) string {
+	return "gparedis:tti:" + prefix
+}
+
+// WithTimeToIdle applies an idle expiry policy: every write sets the TTL to Idle (or less,
+// when a shorter TTL, retention MaxTTL or MaxLifetime applies), and Get and MGet read with
+// GETEX, extending the TTL to Idle again. Reads never extend an entry past its write-time
+// cap (the requested TTL, retention MaxTTL or MaxLifetime, whichever is shortest): deadlines
+// are kept in a sorted set, and entries found past theirs are deleted and reported as
+// missing. Reads go to the primary, since GETEX is a write.
+// Example: sessions := gparedis.NewRepository[Session](provider, client, "session:", gparedis.WithTimeToIdle(gparedis.IdlePolicy{Idle: 30 * time.Minute, MaxLifetime: 12 * time.Hour}))
+func WithTimeToIdle(policy IdlePolicy) RepositoryOption {
+	return func(o *repositoryOptions) {
+		if policy.Idle <= 0 {
+			return
+		}
+		o.idle = &policy
+	}
+}
+
+// idleTTL caps a write TTL by the idle policy
+func (r *Repository[T]) idleTTL(ttl time.Duration) time.Duration {
+	policy := r.opts.idle
+	if policy == nil {
+		return ttl
+	}
+	if ttl <= 0 || ttl > policy.Idle {
+		ttl = policy.Idle
+	}
+	if policy.MaxLifetime > 0 && ttl > policy.MaxLifetime {
+		ttl = policy.MaxLifetime
+	}
+	return ttl
+}
+
+// lifetimeCap returns how long a write of key with the requested ttl may live however often
+// it's read: the shortest of ttl, the retention MaxTTL and MaxLifetime (0 when uncapped)
+func (r *Repository[T]) lifetimeCap(key string, ttl time.Duration) time.Duration {
+	limit := ttl
+	if limit < 0 {
+		limit = 0
+	}
+	if _, policy, ok := r.provider.retentionFor(r.buildKey(key)); ok && policy.MaxTTL > 0 && (limit == 0 || policy.MaxTTL < limit) {
+		limit = policy.MaxTTL
+	}
+	if max := r.opts.idle.MaxLifetime; max > 0 && (limit == 0 || max < limit) {
+		limit = max
+	}
+	return limit
+}
+
+// trackDeadlines records the lifetime deadline of freshly written keys, capped by the
+// requested ttl, retention MaxTTL and MaxLifetime, and prunes past ones. Keys written
+// without any cap drop their previous deadline.
+func (r *Repository[T]) trackDeadlines(ctx context.Context, keys []string, ttl time.Duration) error {
+	if r.opts.idle == nil || len(keys) == 0 {
+		return nil
+	}
+	now := time.Now()
+	var members []*redis.Z
+	var uncapped []interface{}
+	for _, key := range keys {
+		limit := r.lifetimeCap(key, ttl)
+		if limit <= 0 {
+			uncapped = append(uncapped, r.buildKey(key))
+			continue
+		}
+		members = append(members, &redis.Z{Score: float64(now.Add(limit).UnixMilli()), Member: r.buildKey(key)})
+	}
+
+	index := ttiIndexKey(r.keyPrefix)
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(members) > 0 {
+			pipe.ZAdd(ctx, index, members...)
+		}
+		if len(uncapped) > 0 {
+			pipe.ZRem(ctx, index, uncapped...)
+		}
+		pipe.ZRemRangeByScore(ctx, index, "-inf", "("+strconv.FormatInt(now.UnixMilli(), 10))
+		return nil
+	})
+	return convertRedisError(err)
+}
+
+// untrackDeadlines removes deleted keys from the deadline index
+func (r *Repository[T]) untrackDeadlines(ctx context.Context, keys []string) error {
+	if r.opts.idle == nil || len(keys) == 0 {
+		return nil
+	}
+	members := make([]interface{}, len(keys))
+	for i, key := range keys {
+		members[i] = r.buildKey(key)
+	}
+	return convertRedisError(r.client.ZRem(ctx, ttiIndexKey(r.keyPrefix), members...).Err())
+}
+
+// getIdle reads one key under the idle policy, in the shape of a GET result
+func (r *Repository[T]) getIdle(ctx context.Context, fullKey string) *redis.StringCmd {
+	values, err := r.touchIdle(ctx, []string{fullKey})
+	if err != nil {
+		return redis.NewStringResult("", err)
+	}
+	if values[0] == nil {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(values[0].(string), nil)
+}
+
+// touchIdle reads keys with GETEX, extending their TTL to Idle without passing their
+// lifetime deadline. Keys found past their deadline are deleted like any other removal.
+// Returns values in the shape of an MGET reply (nil for missing keys).
+func (r *Repository[T]) touchIdle(ctx context.Context, fullKeys []string) ([]interface{}, error) {
+	policy := r.opts.idle
+	index := ttiIndexKey(r.keyPrefix)
+
+	// Read the deadlines first, so the GETEX below never extends a key past its own
+	deadlines := make([]*redis.FloatCmd, len(fullKeys))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, fullKey := range fullKeys {
+			deadlines[i] = pipe.ZScore(ctx, index, fullKey)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, convertRedisError(err)
+	}
+
+	now := time.Now()
+	gets := make([]*redis.Cmd, len(fullKeys))
+	var expired []string
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, fullKey := range fullKeys {
+			expiry := now.Add(policy.Idle)
+			// Keys without a recorded deadline were written uncapped or before the policy
+			if ms, err := deadlines[i].Result(); err == nil {
+				deadline := time.UnixMilli(int64(ms))
+				if !deadline.After(now) {
+					expired = append(expired, fullKey)
+					continue
+				}
+				if deadline.Before(expiry) {
+					expiry = deadline
+				}
+			}
+			gets[i] = pipe.Do(ctx, "getex", fullKey, "pxat", expiry.UnixMilli())
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, convertRedisError(err)
+	}
+
+	values := make([]interface{}, len(fullKeys))
+	for i, cmd := range gets {
+		if cmd == nil {
+			continue
+		}
+		data, err := cmd.Text()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, convertRedisError(err)
+		}
+		values[i] = data
+	}
+
+	if len(expired) > 0 {
+		keys := make([]string, len(expired))
+		for i, fullKey := range expired {
+			keys[i] = r.trimKey(fullKey)
+		}
+		if _, err := r.removeKeys(ctx, keys, "lifetime_delete"); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,100 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeToIdle(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sessions := NewRepository[TestValue](repo.provider, repo.client, "tti:session:",
+		WithTimeToIdle(IdlePolicy{Idle: 400 * time.Millisecond}))
+	require.NoError(t, sessions.SetWithTTL(ctx, "a", &TestValue{ID: "a"}, time.Hour))
+	require.NoError(t, sessions.MSet(ctx, map[string]*TestValue{"b": {ID: "b"}}))
+	defer sessions.MDelete(ctx, []string{"a", "b"})
+
+	for _, key := range []string{"tti:session:a", "tti:session:b"} {
+		ttl, err := repo.client.PTTL(ctx, key).Result()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, ttl, 400*time.Millisecond, key)
+		assert.Greater(t, ttl, time.Duration(0), key)
+	}
+
+	// Reading "a" keeps it alive; "b" idles out
+	for i := 0; i < 4; i++ {
+		time.Sleep(150 * time.Millisecond)
+		_, err := sessions.Get(ctx, "a")
+		require.NoError(t, err, "read %d", i)
+	}
+	found, err := sessions.MGet(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Contains(t, found, "a")
+	assert.NotContains(t, found, "b")
+}
+
+func TestTimeToIdleMaxLifetime(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sessions := NewRepository[TestValue](repo.provider, repo.client, "tti:capped:",
+		WithTimeToIdle(IdlePolicy{Idle: 400 * time.Millisecond, MaxLifetime: 500 * time.Millisecond}))
+	require.NoError(t, sessions.Set(ctx, "a", &TestValue{ID: "a"}))
+	defer repo.client.Del(ctx, "tti:capped:a", ttiIndexKey("tti:capped:"))
+
+	time.Sleep(300 * time.Millisecond)
+	_, err := sessions.Get(ctx, "a")
+	require.NoError(t, err)
+	ttl, err := repo.client.PTTL(ctx, "tti:capped:a").Result()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, ttl, 250*time.Millisecond, "reads don't extend past the lifetime deadline")
+
+	time.Sleep(300 * time.Millisecond)
+	_, err = sessions.Get(ctx, "a")
+	assert.Error(t, err)
+
+	// Deletes drop the deadline entry
+	require.NoError(t, sessions.Set(ctx, "b", &TestValue{ID: "b"}))
+	_, err = sessions.MDelete(ctx, []string{"b"})
+	require.NoError(t, err)
+	_, err = repo.client.ZScore(ctx, ttiIndexKey("tti:capped:"), "tti:capped:b").Result()
+	assert.Error(t, err)
+}
+
+func TestTimeToIdleKeepsWriteTTL(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sessions := NewRepository[TestValue](repo.provider, repo.client, "tti:short:",
+		WithTimeToIdle(IdlePolicy{Idle: time.Hour}))
+	require.NoError(t, sessions.SetWithTTL(ctx, "a", &TestValue{ID: "a"}, 300*time.Millisecond))
+	defer repo.client.Del(ctx, "tti:short:a", ttiIndexKey("tti:short:"))
+
+	_, err := sessions.Get(ctx, "a")
+	require.NoError(t, err)
+	ttl, err := repo.client.PTTL(ctx, "tti:short:a").Result()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, ttl, 300*time.Millisecond, "reads don't extend past the written TTL")
+
+	time.Sleep(400 * time.Millisecond)
+	_, err = sessions.Get(ctx, "a")
+	assert.Error(t, err)
+
+	// Rewriting without a TTL drops the cap
+	require.NoError(t, sessions.Set(ctx, "a", &TestValue{ID: "a"}))
+	_, err = repo.client.ZScore(ctx, ttiIndexKey("tti:short:"), "tti:short:a").Result()
+	assert.Error(t, err)
+	_, err = sessions.Get(ctx, "a")
+	require.NoError(t, err)
+	ttl, err = repo.client.PTTL(ctx, "tti:short:a").Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute)
+}
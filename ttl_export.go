@@ -0,0 +1,213 @@
+package gparedis
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// TTL Metadata Export / Import
+// =====================================
+
+// TTLFormat selects the encoding used by ExportTTLs and ImportTTLs
+type TTLFormat string
+
+const (
+	// TTLFormatCSV writes a header row followed by key,ttl_ms,expires_at_ms rows
+	TTLFormatCSV TTLFormat = "csv"
+	// TTLFormatJSON writes one JSON object per line (JSON Lines)
+	TTLFormatJSON TTLFormat = "json"
+)
+
+// TTLRecord describes the expiration state of a single key.
+// TTL is -1 for keys without an expiration; ExpiresAt is zero in that case.
+type TTLRecord struct {
+	Key       string `json:"key"`
+	TTL       int64  `json:"ttl_ms"`
+	ExpiresAt int64  `json:"expires_at_ms,omitempty"`
+}
+
+var ttlCSVHeader = []string{"key", "ttl_ms", "expires_at_ms"}
+
+// ExportTTLs writes the remaining TTL of every key matching pattern to w.
+// Keys are written without the repository prefix. Returns the number of records written.
+// Example: n, err := repo.ExportTTLs(ctx, "session:*", file, gparedis.TTLFormatCSV)
+func (r *Repository[T]) ExportTTLs(ctx context.Context, pattern string, w io.Writer, format TTLFormat) (int, error) {
+	enc, err := newTTLEncoder(w, format)
+	if err != nil {
+		return 0, err
+	}
+	written := 0
+	err = r.scanEach(ctx, pattern, func(keys []string) error {
+		if err := r.authorize(ctx, AccessRead, keys...); err != nil {
+			return err
+		}
+		now := time.Now()
+		cmds := make([]*redis.DurationCmd, len(keys))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = pipe.PTTL(ctx, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return convertRedisError(err)
+		}
+
+		for i, key := range keys {
+			ttl := cmds[i].Val()
+			if ttl == -2 {
+				// Key expired or was removed after SCAN returned it
+				continue
+			}
+			record := TTLRecord{Key: r.trimKey(key), TTL: -1}
+			if ttl > 0 {
+				record.TTL = ttl.Milliseconds()
+				record.ExpiresAt = now.Add(ttl).UnixMilli()
+			}
+			if err := enc.encode(record); err != nil {
+				return err
+			}
+			written++
+		}
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+
+	return written, enc.flush()
+}
+
+// ImportTTLs reads records produced by ExportTTLs and reapplies them.
+// Records with an absolute expiry are restored with PEXPIREAT so time spent between
+// export and import is accounted for; records without a TTL are made persistent.
+// Keys that don't exist are skipped. Returns the number of keys updated.
+func (r *Repository[T]) ImportTTLs(ctx context.Context, rd io.Reader, format TTLFormat) (int, error) {
+	records, err := decodeTTLRecords(rd, format)
+	if err != nil {
+		return 0, err
+	}
+	for _, record := range records {
+		if err := r.authorizeKeys(ctx, AccessWrite, record.Key); err != nil {
+			return 0, err
+		}
+	}
+
+	cmds := make([]*redis.BoolCmd, len(records))
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, record := range records {
+			fullKey := r.buildKey(record.Key)
+			switch {
+			case record.ExpiresAt > 0:
+				cmds[i] = pipe.PExpireAt(ctx, fullKey, time.UnixMilli(record.ExpiresAt))
+			case record.TTL > 0:
+				cmds[i] = pipe.PExpire(ctx, fullKey, time.Duration(record.TTL)*time.Millisecond)
+			default:
+				cmds[i] = pipe.Persist(ctx, fullKey)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, convertRedisError(err)
+	}
+
+	var updated []string
+	for i, cmd := range cmds {
+		if cmd.Val() {
+			updated = append(updated, records[i].Key)
+		}
+	}
+	if err := r.recordWrite(ctx, "import_ttl", updated...); err != nil {
+		return len(updated), err
+	}
+	return len(updated), nil
+}
+
+// ttlEncoder writes TTL records in a specific format
+type ttlEncoder struct {
+	encode func(TTLRecord) error
+	flush  func() error
+}
+
+// newTTLEncoder creates an encoder for the given format
+func newTTLEncoder(w io.Writer, format TTLFormat) (*ttlEncoder, error) {
+	switch format {
+	case TTLFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(ttlCSVHeader); err != nil {
+			return nil, err
+		}
+		return &ttlEncoder{
+			encode: func(rec TTLRecord) error {
+				return cw.Write([]string{rec.Key, strconv.FormatInt(rec.TTL, 10), strconv.FormatInt(rec.ExpiresAt, 10)})
+			},
+			flush: func() error {
+				cw.Flush()
+				return cw.Error()
+			},
+		}, nil
+	case TTLFormatJSON:
+		bw := bufio.NewWriter(w)
+		je := json.NewEncoder(bw)
+		return &ttlEncoder{
+			encode: func(rec TTLRecord) error { return je.Encode(rec) },
+			flush:  bw.Flush,
+		}, nil
+	default:
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("unsupported TTL format: %s", format))
+	}
+}
+
+// decodeTTLRecords reads all TTL records from rd
+func decodeTTLRecords(rd io.Reader, format TTLFormat) ([]TTLRecord, error) {
+	var records []TTLRecord
+	switch format {
+	case TTLFormatCSV:
+		rows, err := csv.NewReader(rd).ReadAll()
+		if err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to read TTL CSV", err)
+		}
+		for i, row := range rows {
+			if i == 0 && len(row) > 0 && row[0] == ttlCSVHeader[0] {
+				continue
+			}
+			if len(row) != len(ttlCSVHeader) {
+				return nil, gpa.NewError(gpa.ErrorTypeSerialization, fmt.Sprintf("invalid TTL CSV row %d", i+1))
+			}
+			ttl, err := strconv.ParseInt(row[1], 10, 64)
+			if err != nil {
+				return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, fmt.Sprintf("invalid ttl on row %d", i+1), err)
+			}
+			expiresAt, err := strconv.ParseInt(row[2], 10, 64)
+			if err != nil {
+				return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, fmt.Sprintf("invalid expiry on row %d", i+1), err)
+			}
+			records = append(records, TTLRecord{Key: row[0], TTL: ttl, ExpiresAt: expiresAt})
+		}
+	case TTLFormatJSON:
+		dec := json.NewDecoder(rd)
+		for {
+			var rec TTLRecord
+			if err := dec.Decode(&rec); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to decode TTL record", err)
+			}
+			records = append(records, rec)
+		}
+	default:
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("unsupported TTL format: %s", format))
+	}
+	return records, nil
+}
@@ -0,0 +1,67 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImportTTLs(t *testing.T) {
+	for _, format := range []TTLFormat{TTLFormatCSV, TTLFormatJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			repo, cleanup := setupTestRepository(t)
+			defer cleanup()
+
+			ctx := context.Background()
+			require.NoError(t, repo.SetWithTTL(ctx, "session:1", &TestValue{ID: "1"}, time.Hour))
+			require.NoError(t, repo.SetWithTTL(ctx, "session:2", &TestValue{ID: "2"}, 30*time.Minute))
+			require.NoError(t, repo.Set(ctx, "session:3", &TestValue{ID: "3"}))
+			require.NoError(t, repo.Set(ctx, "user:1", &TestValue{ID: "u1"}))
+
+			var buf bytes.Buffer
+			n, err := repo.ExportTTLs(ctx, "session:*", &buf, format)
+			require.NoError(t, err)
+			assert.Equal(t, 3, n)
+
+			// Simulate a restore that lost all expirations
+			for _, key := range []string{"session:1", "session:2"} {
+				require.NoError(t, repo.RemoveTTL(ctx, key))
+			}
+
+			updated, err := repo.ImportTTLs(ctx, &buf, format)
+			require.NoError(t, err)
+			assert.Equal(t, 2, updated)
+
+			ttl, err := repo.GetTTL(ctx, "session:1")
+			require.NoError(t, err)
+			assert.Greater(t, ttl, 59*time.Minute)
+
+			ttl, err = repo.GetTTL(ctx, "session:2")
+			require.NoError(t, err)
+			assert.Greater(t, ttl, 29*time.Minute)
+			assert.LessOrEqual(t, ttl, 30*time.Minute)
+		})
+	}
+}
+
+func TestDecodeTTLRecords(t *testing.T) {
+	records, err := decodeTTLRecords(strings.NewReader("key,ttl_ms,expires_at_ms\na,1000,0\nb,-1,0\n"), TTLFormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, []TTLRecord{{Key: "a", TTL: 1000}, {Key: "b", TTL: -1}}, records)
+
+	records, err = decodeTTLRecords(strings.NewReader(`{"key":"a","ttl_ms":5,"expires_at_ms":10}`+"\n"), TTLFormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, []TTLRecord{{Key: "a", TTL: 5, ExpiresAt: 10}}, records)
+
+	_, err = decodeTTLRecords(strings.NewReader("a,notanumber,0\n"), TTLFormatCSV)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeSerialization))
+
+	_, err = decodeTTLRecords(strings.NewReader(""), TTLFormat("xml"))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
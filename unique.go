@@ -0,0 +1,266 @@
+package gparedis
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Unique Constraints
+// =====================================
+
+// Values of indexes tagged unique are claimed in a hash per index mapping each value to the
+// key holding it. A claim only counts while its key is still a member of the value's tag set
+// and still exists, so claims left behind by updates, deletes and expiry are taken over by the
+// next writer. Claiming also adds the tag set entry, before the value is written, so
+// concurrent writers of the same value can't both pass the check.
+
+// uniqueHashKey returns the hash of the claims of a unique index
+func (r *Repository[T]) uniqueHashKey(index string) string {
+	return indexKeyPrefix + r.keyPrefix + "~unique:" + index
+}
+
+// claimUniqueScript checks and claims unique values for a batch of keys. A value is taken
+// when another live key of its tag set holds it: the claim owner, or a key written by a
+// path that doesn't claim. Tag set members whose full key is known are live while it exists;
+// members added since the caller read the tag sets count as live.
+// KEYS: groups of hash, tag set, reverse set, then the full keys of known members.
+// ARGV: number of groups, groups of value, key, then the known members in KEYS order.
+// Returns 0 when every value was claimed, else the 1-based number of the conflicting group.
+var claimUniqueScript = dryRunReply(redis.NewScript(`
+local groups = tonumber(ARGV[1])
+local full = {}
+for i = 3 * groups + 1, #KEYS do
+	full[ARGV[i - groups + 1]] = KEYS[i]
+end
+local function live(member)
+	return not full[member] or redis.call('EXISTS', full[member]) == 1
+end
+local claimed = {}
+for g = 1, groups do
+	local hash, set = KEYS[g * 3 - 2], KEYS[g * 3 - 1]
+	local value, key = ARGV[g * 2], ARGV[g * 2 + 1]
+	local id = hash .. '\0' .. value
+	if claimed[id] and claimed[id] ~= key then
+		return g
+	end
+	claimed[id] = key
+	for _, member in ipairs(redis.call('SMEMBERS', set)) do
+		if member ~= key and live(member) then
+			return g
+		end
+	end
+end
+for g = 1, groups do
+	local key = ARGV[g * 2 + 1]
+	redis.call('HSET', KEYS[g * 3 - 2], ARGV[g * 2], key)
+	redis.call('SADD', KEYS[g * 3 - 1], key)
+	redis.call('SADD', KEYS[g * 3], KEYS[g * 3 - 1])
+end
+return 0
+`), int64(0))
+
+// uniqueClaim is one unique value a key is about to hold
+type uniqueClaim struct {
+	index string
+	value string
+	key   string
+}
+
+// claimUnique atomically claims the unique index values of values before they are written,
+// returning ErrorTypeDuplicate when another key holds one of them
+func (r *Repository[T]) claimUnique(ctx context.Context, values map[string]*T) error {
+	claims := r.uniqueClaims(values)
+	if len(claims) == 0 {
+		return nil
+	}
+
+	// The script can't build full keys, so pass those of the current tag set members
+	members := make([]*redis.StringSliceCmd, len(claims))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, c := range claims {
+			members[i] = pipe.SMembers(ctx, r.tagSetKey(c.index, c.value))
+		}
+		return nil
+	})
+	if err != nil {
+		return r.indexError(err)
+	}
+	known := make(map[string]bool)
+	var others []string
+	for _, cmd := range members {
+		for _, member := range cmd.Val() {
+			if !known[member] {
+				known[member] = true
+				others = append(others, member)
+			}
+		}
+	}
+
+	keys := make([]string, 0, 3*len(claims)+len(others))
+	args := make([]interface{}, 0, 1+2*len(claims)+len(others))
+	args = append(args, len(claims))
+	for _, c := range claims {
+		keys = append(keys, r.uniqueHashKey(c.index), r.tagSetKey(c.index, c.value), r.reverseIndexKey(c.key))
+		args = append(args, c.value, c.key)
+	}
+	for _, member := range others {
+		keys = append(keys, r.buildKey(member))
+		args = append(args, member)
+	}
+	conflict, err := claimUniqueScript.Run(ctx, r.client, keys, args...).Int()
+	if err != nil {
+		return r.indexError(err)
+	}
+	if conflict > 0 {
+		c := claims[conflict-1]
+		return gpa.NewError(gpa.ErrorTypeDuplicate, "value "+c.value+" of unique index "+c.index+" is already taken")
+	}
+	return nil
+}
+
+// uniqueClaims returns the unique index values held by values, by index then key
+func (r *Repository[T]) uniqueClaims(values map[string]*T) []uniqueClaim {
+	var claims []uniqueClaim
+	for _, def := range r.indexes() {
+		if !def.unique || def.ranged || def.text {
+			continue
+		}
+		for _, key := range sortedKeys(values) {
+			if value, ok := indexValue(reflect.ValueOf(values[key]), def); ok {
+				claims = append(claims, uniqueClaim{index: def.name, value: value, key: key})
+			}
+		}
+	}
+	return claims
+}
+
+// dropClaimScript removes the claim of value ARGV[1] from the hash KEYS[1] while key ARGV[2] owns it
+var dropClaimScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) == ARGV[2] then
+	return redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// dropClaims removes the claims keys hold on the unique values of values, for keys that
+// leave the repository without their values being deleted
+func (r *Repository[T]) dropClaims(ctx context.Context, values map[string]*T) error {
+	claims := r.uniqueClaims(values)
+	if len(claims) == 0 {
+		return nil
+	}
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, c := range claims {
+			dropClaimScript.Eval(ctx, pipe, []string{r.uniqueHashKey(c.index)}, c.value, c.key)
+		}
+		return nil
+	})
+	return r.indexError(err)
+}
+
+// hasUniqueIndexes reports whether writes of T claim unique values
+func (r *Repository[T]) hasUniqueIndexes() bool {
+	for _, def := range r.indexes() {
+		if def.unique && !def.ranged && !def.text {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseClaims undoes the claims made for keys that a conditional write left unchanged, by
+// re-indexing them from the values they still hold (or unindexing them when missing)
+func (r *Repository[T]) releaseClaims(ctx context.Context, keys []string) error {
+	if len(keys) == 0 || !r.hasUniqueIndexes() {
+		return nil
+	}
+	values, err := mgetGroups(ctx, r.client, r.buildKeys(keys))
+	if err != nil {
+		return err
+	}
+	current := make(map[string]*T, len(keys))
+	var missing []string
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			missing = append(missing, keys[i])
+			continue
+		}
+		entity, err := r.decode([]byte(data))
+		if err != nil {
+			return err
+		}
+		current[keys[i]] = entity
+	}
+	if err := r.indexEntities(ctx, current); err != nil {
+		return err
+	}
+	return r.unindexKeys(ctx, missing)
+}
+
+// abandonClaims releases the claims of keys whose write failed and returns the write error.
+// A failed release is dropped: the claims of keys that don't exist free themselves.
+func (r *Repository[T]) abandonClaims(ctx context.Context, keys []string, err error) error {
+	_ = r.releaseClaims(ctx, keys)
+	return err
+}
+
+// KeyByUnique returns the key holding the given value of a unique index; false when no key
+// holds it. Composite indexes take one value per field in declaration order.
+// Example: key, ok, err := users.KeyByUnique(ctx, "email", "ada@example.com")
+func (r *Repository[T]) KeyByUnique(ctx context.Context, index string, values ...interface{}) (string, bool, error) {
+	def, err := r.findIndex(index)
+	if err != nil {
+		return "", false, err
+	}
+	if !def.unique {
+		return "", false, gpa.NewError(gpa.ErrorTypeInvalidArgument, "index "+index+" is not unique")
+	}
+	set, ok, err := r.lookupTagSet(index, values)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	// The tag set name ends with the normalized value
+	value := set[len(r.tagSetKey(index, "")):]
+
+	owner, err := r.client.HGet(ctx, r.uniqueHashKey(index), value).Result()
+	if err != nil && err != redis.Nil {
+		return "", false, convertRedisError(err)
+	}
+	// Keys written by paths that don't claim are still found through the tag set
+	members, err := r.client.SMembers(ctx, set).Result()
+	if err != nil {
+		return "", false, convertRedisError(err)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if (members[i] == owner) != (members[j] == owner) {
+			return members[i] == owner
+		}
+		return members[i] < members[j]
+	})
+	// Members that expired or were deleted without unindexing don't hold the value
+	exists := make([]*redis.IntCmd, len(members))
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, member := range members {
+			exists[i] = pipe.Exists(ctx, r.buildKey(member))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", false, convertRedisError(err)
+	}
+	for i, member := range members {
+		if exists[i].Val() == 1 {
+			if err := r.authorizeKeys(ctx, AccessRead, member); err != nil {
+				return "", false, err
+			}
+			return member, true, nil
+		}
+	}
+	return "", false, nil
+}
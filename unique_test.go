@@ -0,0 +1,144 @@
+package gparedis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUniqueIndexEnforcement(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[indexedUser](base.provider, base.client, "user:")
+	require.NoError(t, repo.Set(ctx, "1", &indexedUser{ID: "1", Email: "a@x.io", Status: "active"}))
+
+	// Rewriting the same key keeps its claim
+	require.NoError(t, repo.Set(ctx, "1", &indexedUser{ID: "1", Email: "a@x.io", Status: "banned"}))
+
+	err := repo.Set(ctx, "2", &indexedUser{ID: "2", Email: "a@x.io"})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+	exists, err := repo.KeyExists(ctx, "2")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Batches are rejected as a whole, including duplicates within the batch
+	err = repo.MSet(ctx, map[string]*indexedUser{
+		"2": {ID: "2", Email: "b@x.io"},
+		"3": {ID: "3", Email: "b@x.io"},
+	})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+	key, ok, err := repo.KeyByUnique(ctx, "email", "b@x.io")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, key)
+
+	// Values released by updates and deletes can be claimed again
+	require.NoError(t, repo.Set(ctx, "1", &indexedUser{ID: "1", Email: "new@x.io"}))
+	require.NoError(t, repo.Set(ctx, "2", &indexedUser{ID: "2", Email: "a@x.io"}))
+	require.NoError(t, repo.DeleteKey(ctx, "2"))
+	require.NoError(t, repo.Set(ctx, "3", &indexedUser{ID: "3", Email: "a@x.io"}))
+
+	key, ok, err = repo.KeyByUnique(ctx, "email", "a@x.io")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "3", key)
+
+	// Values written without a claim still count
+	require.NoError(t, base.client.Set(ctx, "user:4", `{"id":"4","email":"raw@x.io"}`, 0).Err())
+	_, err = repo.BuildIndexes(ctx, IndexBuildOptions{})
+	require.NoError(t, err)
+	err = repo.Set(ctx, "5", &indexedUser{ID: "5", Email: "raw@x.io"})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+
+	_, _, err = repo.KeyByUnique(ctx, "status", "active")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument))
+}
+
+func TestUniqueClaimsOnEveryWritePath(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[indexedUser](base.provider, base.client, "uniq:user:")
+	require.NoError(t, repo.Set(ctx, "1", &indexedUser{ID: "1", Email: "a@x.io"}))
+	defer repo.MDelete(ctx, []string{"1", "2", "3", "4"})
+	taken := &indexedUser{ID: "2", Email: "a@x.io"}
+
+	_, err := repo.MSetNX(ctx, map[string]*indexedUser{"2": taken})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+	_, err = repo.MCompareAndSwap(ctx, nil, map[string]*indexedUser{"2": taken}, 0)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+	_, err = repo.SetIfUnchanged(ctx, "2", taken, "")
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+	err = base.provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		return repo.SetTx(tx, "2", taken)
+	})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+	_, err = NewImporter(repo, ImporterOptions[indexedUser]{Key: func(u *indexedUser) string { return u.ID }}).
+		Import(ctx, strings.NewReader(`{"id":"2","email":"a@x.io"}`+"\n"))
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+	loaded, err := repo.MGetOrLoad(ctx, []string{"2"}, func(missing []string) (map[string]*indexedUser, error) {
+		return map[string]*indexedUser{"2": taken}, nil
+	}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "a@x.io", loaded["2"].Email)
+	exists, err := repo.KeyExists(ctx, "2")
+	require.NoError(t, err)
+	assert.False(t, exists, "conflicting loaded values aren't cached")
+
+	// Conditional writes that don't apply release their claims
+	require.NoError(t, repo.Set(ctx, "3", &indexedUser{ID: "3", Email: "c@x.io"}))
+	written, err := repo.MSetNX(ctx, map[string]*indexedUser{"3": {ID: "3", Email: "d@x.io"}, "4": {ID: "4", Email: "e@x.io"}})
+	require.NoError(t, err)
+	assert.False(t, written)
+	_, err = repo.SetIfUnchanged(ctx, "3", &indexedUser{ID: "3", Email: "d@x.io"}, "stale")
+	assert.True(t, IsConflictError(err))
+	err = base.provider.MultiRepo(ctx, func(tx *MultiTx) error {
+		require.NoError(t, repo.SetTx(tx, "4", &indexedUser{ID: "4", Email: "d@x.io"}))
+		return errors.New("abort")
+	})
+	require.Error(t, err)
+	for email, want := range map[string]string{"c@x.io": "3", "d@x.io": "", "e@x.io": ""} {
+		key, _, err := repo.KeyByUnique(ctx, "email", email)
+		require.NoError(t, err)
+		assert.Equal(t, want, key, email)
+	}
+	require.NoError(t, repo.Set(ctx, "2", &indexedUser{ID: "2", Email: "d@x.io"}))
+}
+
+func TestUniqueClaimsOfVanishedKeys(t *testing.T) {
+	base, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewRepository[indexedUser](base.provider, base.client, "uniq:ttl:")
+	defer repo.MDelete(ctx, []string{"1", "2", "3", "4"})
+
+	require.NoError(t, repo.SetWithTTL(ctx, "1", &indexedUser{ID: "1", Email: "a@x.io"}, 50*time.Millisecond))
+	err := repo.Set(ctx, "2", &indexedUser{ID: "2", Email: "a@x.io"})
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeDuplicate))
+
+	// Expired keys stay in their tag sets but no longer hold their values
+	time.Sleep(100 * time.Millisecond)
+	key, ok, err := repo.KeyByUnique(ctx, "email", "a@x.io")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, key)
+	require.NoError(t, repo.Set(ctx, "2", &indexedUser{ID: "2", Email: "a@x.io"}))
+	key, _, err = repo.KeyByUnique(ctx, "email", "a@x.io")
+	require.NoError(t, err)
+	assert.Equal(t, "2", key)
+
+	// So do keys removed behind the repository's back
+	require.NoError(t, repo.Set(ctx, "3", &indexedUser{ID: "3", Email: "b@x.io"}))
+	require.NoError(t, base.client.Del(ctx, "uniq:ttl:3").Err())
+	require.NoError(t, repo.Set(ctx, "4", &indexedUser{ID: "4", Email: "b@x.io"}))
+}
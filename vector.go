@@ -0,0 +1,368 @@
+package gparedis
+
+import (
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Vector Similarity Search
+// =====================================
+
+// Hash fields of stored vectors
+const (
+	vectorField     = "vec"
+	vectorDataField = "data"
+	vectorDistField = "__dist"
+)
+
+// VectorMetric is the distance metric of a vector index
+type VectorMetric string
+
+const (
+	// VectorCosine is the cosine distance, 1 - cosine similarity
+	VectorCosine VectorMetric = "COSINE"
+	// VectorL2 is the squared Euclidean distance
+	VectorL2 VectorMetric = "L2"
+	// VectorInnerProduct is 1 - the inner product, for normalized embeddings
+	VectorInnerProduct VectorMetric = "IP"
+)
+
+// VectorOptions configures a VectorRepository
+type VectorOptions struct {
+	// Dim is the number of dimensions of every vector (required)
+	Dim int
+	// Metric is the distance metric (default VectorCosine)
+	Metric VectorMetric
+	// Algorithm is the index algorithm, "HNSW" (approximate) or "FLAT" (exact) (default "HNSW")
+	Algorithm string
+	// Index is the name of the search index (default "idx:" + prefix)
+	Index string
+	// FallbackScan answers KNN by scanning every vector client-side when the search module
+	// is missing, for small sets and tests. Without it KNN fails with ErrorTypeUnsupported.
+	FallbackScan bool
+}
+
+// VectorMatch is a result of a KNN query
+type VectorMatch[T any] struct {
+	Key   string
+	Value *T
+	// Distance to the query vector under the index metric; lower is closer
+	Distance float64
+}
+
+// VectorRepository stores embeddings as FLOAT32 vectors with JSON metadata in hashes under a
+// prefix, and answers k-nearest-neighbour queries with FT.SEARCH when the search module
+// (Redis Stack) is available, so retrieval-augmented lookups can run next to the data.
+type VectorRepository[T any] struct {
+	provider *Provider
+	client   *redis.Client
+	prefix   string
+	opts     VectorOptions
+
+	searchMu      sync.Mutex
+	searchChecked bool
+	search        bool
+}
+
+// NewVectorRepository creates a vector repository storing each entry at prefix+key
+// Example: docs, err := gparedis.NewVectorRepository[Chunk](provider, "chunk:", gparedis.VectorOptions{Dim: 768})
+func NewVectorRepository[T any](provider *Provider, prefix string, opts VectorOptions) (*VectorRepository[T], error) {
+	if opts.Dim <= 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "vector dimension must be positive")
+	}
+	if opts.Metric == "" {
+		opts.Metric = VectorCosine
+	}
+	if opts.Algorithm == "" {
+		opts.Algorithm = "HNSW"
+	}
+	if opts.Index == "" {
+		opts.Index = "idx:" + prefix
+	}
+	return &VectorRepository[T]{provider: provider, client: provider.client, prefix: prefix, opts: opts}, nil
+}
+
+// searchAvailable reports whether the server has the search module. The answer is cached
+// once the server gave one.
+func (v *VectorRepository[T]) searchAvailable(ctx context.Context) (bool, error) {
+	v.searchMu.Lock()
+	defer v.searchMu.Unlock()
+	if !v.searchChecked {
+		err := v.client.Do(ctx, "FT._LIST").Err()
+		switch {
+		case err == nil:
+			v.search = true
+		case strings.Contains(strings.ToLower(err.Error()), "unknown command"):
+			v.search = false
+		default:
+			return false, convertRedisError(err)
+		}
+		v.searchChecked = true
+	}
+	return v.search, nil
+}
+
+// EnsureIndex creates the search index over the prefix if it doesn't exist. Without the
+// search module it fails with ErrorTypeUnsupported, unless FallbackScan is set.
+func (v *VectorRepository[T]) EnsureIndex(ctx context.Context) error {
+	available, err := v.searchAvailable(ctx)
+	if err != nil {
+		return err
+	}
+	if !available {
+		if v.opts.FallbackScan {
+			return nil
+		}
+		return gpa.NewError(gpa.ErrorTypeUnsupported, "vector search requires the search module")
+	}
+	err = v.client.Do(ctx, "FT.CREATE", v.opts.Index, "ON", "HASH", "PREFIX", 1, v.prefix,
+		"SCHEMA", vectorField, "VECTOR", v.opts.Algorithm, 6,
+		"TYPE", "FLOAT32", "DIM", v.opts.Dim, "DISTANCE_METRIC", string(v.opts.Metric)).Err()
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "already exists") {
+		return nil
+	}
+	return convertRedisError(err)
+}
+
+// DropIndex drops the search index, keeping the stored vectors
+func (v *VectorRepository[T]) DropIndex(ctx context.Context) error {
+	return convertRedisError(v.client.Do(ctx, "FT.DROPINDEX", v.opts.Index).Err())
+}
+
+// Upsert stores vector and value at key, replacing an existing entry
+// Example: err := docs.Upsert(ctx, chunk.ID, embedding, chunk)
+func (v *VectorRepository[T]) Upsert(ctx context.Context, key string, vector []float32, value *T) error {
+	if len(vector) != v.opts.Dim {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("vector has %d dimensions, expected %d", len(vector), v.opts.Dim))
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize vector metadata", err)
+	}
+	return convertRedisError(v.client.HSet(ctx, v.prefix+key, vectorField, encodeVector(vector), vectorDataField, data).Err())
+}
+
+// Get returns the vector and value stored at key
+func (v *VectorRepository[T]) Get(ctx context.Context, key string) ([]float32, *T, error) {
+	fields, err := v.client.HMGet(ctx, v.prefix+key, vectorField, vectorDataField).Result()
+	if err != nil {
+		return nil, nil, convertRedisError(err)
+	}
+	rawVector, ok := fields[0].(string)
+	rawData, _ := fields[1].(string)
+	if !ok {
+		return nil, nil, gpa.NewError(gpa.ErrorTypeNotFound, "vector "+key+" not found")
+	}
+	value, err := decodeVectorValue[T](key, rawData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodeVector(rawVector), value, nil
+}
+
+// Delete removes entries
+func (v *VectorRepository[T]) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = v.prefix + key
+	}
+	return convertRedisError(v.client.Del(ctx, fullKeys...).Err())
+}
+
+// KNN returns the k entries nearest to vector, closest first
+// Example: matches, err := docs.KNN(ctx, queryEmbedding, 5)
+func (v *VectorRepository[T]) KNN(ctx context.Context, vector []float32, k int) ([]VectorMatch[T], error) {
+	if len(vector) != v.opts.Dim {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("vector has %d dimensions, expected %d", len(vector), v.opts.Dim))
+	}
+	if k <= 0 {
+		return []VectorMatch[T]{}, nil
+	}
+	available, err := v.searchAvailable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !available {
+		if !v.opts.FallbackScan {
+			return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "vector search requires the search module")
+		}
+		return v.scanKNN(ctx, vector, k)
+	}
+
+	reply, err := v.client.Do(ctx, "FT.SEARCH", v.opts.Index,
+		fmt.Sprintf("*=>[KNN %d @%s $vec AS %s]", k, vectorField, vectorDistField),
+		"PARAMS", 2, "vec", encodeVector(vector),
+		"SORTBY", vectorDistField, "RETURN", 2, vectorDistField, vectorDataField,
+		"LIMIT", 0, k, "DIALECT", 2).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	return v.parseSearchReply(reply)
+}
+
+// parseSearchReply decodes an FT.SEARCH reply: the total, then each key and its fields
+func (v *VectorRepository[T]) parseSearchReply(reply interface{}) ([]VectorMatch[T], error) {
+	items, ok := reply.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeDatabase, "unexpected FT.SEARCH reply")
+	}
+	matches := make([]VectorMatch[T], 0, (len(items)-1)/2)
+	for i := 1; i+1 < len(items); i += 2 {
+		fullKey, _ := items[i].(string)
+		fields, _ := items[i+1].([]interface{})
+		match := VectorMatch[T]{Key: strings.TrimPrefix(fullKey, v.prefix)}
+		var rawData string
+		for j := 0; j+1 < len(fields); j += 2 {
+			value, _ := fields[j+1].(string)
+			switch fields[j] {
+			case vectorDistField:
+				match.Distance, _ = strconv.ParseFloat(value, 64)
+			case vectorDataField:
+				rawData = value
+			}
+		}
+		value, err := decodeVectorValue[T](match.Key, rawData)
+		if err != nil {
+			return nil, err
+		}
+		match.Value = value
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// scanKNN finds the nearest entries by scanning every vector under the prefix
+func (v *VectorRepository[T]) scanKNN(ctx context.Context, vector []float32, k int) ([]VectorMatch[T], error) {
+	nearest := &vectorHeap{}
+	err := scanKeys(ctx, v.client, EscapeGlob(v.prefix)+"*", func(keys []string) error {
+		cmds := make([]*redis.SliceCmd, len(keys))
+		_, err := v.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = pipe.HMGet(ctx, key, vectorField, vectorDataField)
+			}
+			return nil
+		})
+		if err != nil {
+			return convertRedisError(err)
+		}
+		for i, cmd := range cmds {
+			fields := cmd.Val()
+			rawVector, ok := fields[0].(string)
+			if !ok || len(rawVector) != 4*v.opts.Dim {
+				continue
+			}
+			rawData, _ := fields[1].(string)
+			candidate := vectorCandidate{key: keys[i], data: rawData, distance: vectorDistance(v.opts.Metric, vector, decodeVector(rawVector))}
+			if nearest.Len() < k {
+				heap.Push(nearest, candidate)
+			} else if candidate.distance < (*nearest)[0].distance {
+				(*nearest)[0] = candidate
+				heap.Fix(nearest, 0)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]VectorMatch[T], nearest.Len())
+	for i := len(matches) - 1; i >= 0; i-- {
+		candidate := heap.Pop(nearest).(vectorCandidate)
+		key := strings.TrimPrefix(candidate.key, v.prefix)
+		value, err := decodeVectorValue[T](key, candidate.data)
+		if err != nil {
+			return nil, err
+		}
+		matches[i] = VectorMatch[T]{Key: key, Value: value, Distance: candidate.distance}
+	}
+	return matches, nil
+}
+
+// vectorCandidate is an entry considered by a scan
+type vectorCandidate struct {
+	key      string
+	data     string
+	distance float64
+}
+
+// vectorHeap is a max-heap of candidates by distance, keeping the k nearest
+type vectorHeap []vectorCandidate
+
+func (h vectorHeap) Len() int            { return len(h) }
+func (h vectorHeap) Less(i, j int) bool  { return h[i].distance > h[j].distance }
+func (h vectorHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *vectorHeap) Push(x interface{}) { *h = append(*h, x.(vectorCandidate)) }
+func (h *vectorHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// vectorDistance computes the distance between a and b as the search module does
+func vectorDistance(metric VectorMetric, a, b []float32) float64 {
+	var dot, normA, normB, l2 float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		normA += x * x
+		normB += y * y
+		l2 += (x - y) * (x - y)
+	}
+	switch metric {
+	case VectorL2:
+		return l2
+	case VectorInnerProduct:
+		return 1 - dot
+	default:
+		if normA == 0 || normB == 0 {
+			return 1
+		}
+		return 1 - dot/math.Sqrt(normA*normB)
+	}
+}
+
+// encodeVector encodes a vector as little-endian FLOAT32 bytes
+func encodeVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, x := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+// decodeVector decodes little-endian FLOAT32 bytes
+func decodeVector(raw string) []float32 {
+	vector := make([]float32, len(raw)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32([]byte(raw[4*i : 4*i+4])))
+	}
+	return vector
+}
+
+// decodeVectorValue decodes the JSON metadata of an entry
+func decodeVectorValue[T any](key, raw string) (*T, error) {
+	var value T
+	if raw == "" {
+		return &value, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to deserialize vector metadata "+key, err)
+	}
+	return &value, nil
+}
@@ -0,0 +1,83 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type vectorDoc struct {
+	Title string `json:"title"`
+}
+
+func TestVectorRepositoryFallback(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	docs, err := NewVectorRepository[vectorDoc](repo.provider, "test:vec:", VectorOptions{Dim: 3, FallbackScan: true})
+	require.NoError(t, err)
+	if available, err := docs.searchAvailable(ctx); err != nil || available {
+		t.Skip("server has the search module; the scan fallback isn't used")
+	}
+	require.NoError(t, docs.EnsureIndex(ctx))
+
+	require.NoError(t, docs.Upsert(ctx, "x", []float32{1, 0, 0}, &vectorDoc{Title: "x axis"}))
+	require.NoError(t, docs.Upsert(ctx, "y", []float32{0, 1, 0}, &vectorDoc{Title: "y axis"}))
+	require.NoError(t, docs.Upsert(ctx, "xy", []float32{1, 1, 0}, &vectorDoc{Title: "diagonal"}))
+	assert.True(t, gpa.IsErrorType(docs.Upsert(ctx, "bad", []float32{1}, &vectorDoc{}), gpa.ErrorTypeInvalidArgument))
+
+	vector, doc, err := docs.Get(ctx, "xy")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{1, 1, 0}, vector)
+	assert.Equal(t, "diagonal", doc.Title)
+
+	matches, err := docs.KNN(ctx, []float32{0.9, 0.2, 0}, 2)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "x", matches[0].Key)
+	assert.Equal(t, "xy", matches[1].Key)
+	assert.Equal(t, "x axis", matches[0].Value.Title)
+	assert.Less(t, matches[0].Distance, matches[1].Distance)
+
+	require.NoError(t, docs.Delete(ctx, "x"))
+	matches, err = docs.KNN(ctx, []float32{0.9, 0.2, 0}, 5)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "xy", matches[0].Key)
+
+	// Without the fallback, missing search support is reported
+	strict, err := NewVectorRepository[vectorDoc](repo.provider, "test:vec:", VectorOptions{Dim: 3})
+	require.NoError(t, err)
+	_, err = strict.KNN(ctx, []float32{1, 0, 0}, 1)
+	assert.True(t, gpa.IsErrorType(err, gpa.ErrorTypeUnsupported))
+}
+
+func TestVectorSearchReply(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	docs, err := NewVectorRepository[vectorDoc](repo.provider, "doc:", VectorOptions{Dim: 2})
+	require.NoError(t, err)
+	matches, err := docs.parseSearchReply([]interface{}{
+		int64(2),
+		"doc:a", []interface{}{"__dist", "0.125", "data", `{"title":"A"}`},
+		"doc:b", []interface{}{"__dist", "0.5", "data", `{"title":"B"}`},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []VectorMatch[vectorDoc]{
+		{Key: "a", Value: &vectorDoc{Title: "A"}, Distance: 0.125},
+		{Key: "b", Value: &vectorDoc{Title: "B"}, Distance: 0.5},
+	}, matches)
+}
+
+func TestVectorDistance(t *testing.T) {
+	a, b := []float32{1, 0}, []float32{0, 2}
+	assert.InDelta(t, 1.0, vectorDistance(VectorCosine, a, b), 1e-9)
+	assert.InDelta(t, 5.0, vectorDistance(VectorL2, a, b), 1e-9)
+	assert.InDelta(t, 1.0, vectorDistance(VectorInnerProduct, a, b), 1e-9)
+	assert.Equal(t, a, decodeVector(string(encodeVector(a))))
+}
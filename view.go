@@ -0,0 +1,230 @@
+package gparedis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Materialized Views
+// =====================================
+
+// Projection applies one change event to derived keys. All commands must be queued
+// on pipe; they are executed atomically together with the dedup marker and the ack.
+type Projection[T any] func(ctx context.Context, pipe redis.Pipeliner, msg StreamMessage[T]) error
+
+// ViewOptions configures a MaterializedView
+type ViewOptions[T any] struct {
+	// Consumer names this process within the view's consumer group (default "default")
+	Consumer string
+	// BatchSize is the number of events read per round trip (default 100)
+	BatchSize int64
+	// Block is how long Run waits for new events (default 1s)
+	Block time.Duration
+	// DedupTTL is how long applied dedup IDs are remembered (default 24h)
+	DedupTTL time.Duration
+	// DedupID extracts a producer-side idempotency key; defaults to the stream message ID
+	DedupID func(msg StreamMessage[T]) string
+}
+
+// MaterializedView consumes a Stream[T] of change events and keeps derived keys current.
+// Each event is applied exactly once: projections, the dedup marker and the XACK run in a
+// single MULTI/EXEC guarded by WATCH on the dedup set, so redeliveries and duplicate
+// events are skipped.
+type MaterializedView[T any] struct {
+	stream      *Stream[T]
+	name        string
+	group       string
+	dedupKey    string
+	opts        ViewOptions[T]
+	projections []Projection[T]
+}
+
+// NewMaterializedView creates a view named name over stream. The view uses a consumer group of the same name.
+// Example: view := gparedis.NewMaterializedView(orderEvents, "orders-by-status", gparedis.ViewOptions[OrderEvent]{})
+func NewMaterializedView[T any](stream *Stream[T], name string, opts ViewOptions[T]) *MaterializedView[T] {
+	if opts.Consumer == "" {
+		opts.Consumer = "default"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.Block <= 0 {
+		opts.Block = time.Second
+	}
+	if opts.DedupTTL <= 0 {
+		opts.DedupTTL = 24 * time.Hour
+	}
+	if opts.DedupID == nil {
+		opts.DedupID = func(msg StreamMessage[T]) string { return msg.ID }
+	}
+	return &MaterializedView[T]{
+		stream:   stream,
+		name:     name,
+		group:    "view:" + name,
+		dedupKey: "gparedis:view:" + name + ":applied",
+		opts:     opts,
+	}
+}
+
+// Project adds a projection applied to every event
+func (v *MaterializedView[T]) Project(p Projection[T]) *MaterializedView[T] {
+	v.projections = append(v.projections, p)
+	return v
+}
+
+// ProcessBatch applies pending and new events once and returns how many were applied
+func (v *MaterializedView[T]) ProcessBatch(ctx context.Context) (int, error) {
+	return v.process(ctx, -1)
+}
+
+// Run applies events until ctx is cancelled
+func (v *MaterializedView[T]) Run(ctx context.Context) error {
+	for {
+		if _, err := v.process(ctx, v.opts.Block); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// process reads a batch (redelivering this consumer's pending events first) and applies it
+func (v *MaterializedView[T]) process(ctx context.Context, block time.Duration) (int, error) {
+	if err := v.stream.CreateGroup(ctx, v.group, "0"); err != nil {
+		return 0, err
+	}
+
+	msgs, err := v.stream.Pending(ctx, v.group, v.opts.Consumer, v.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		msgs, err = v.stream.ReadGroup(ctx, v.group, v.opts.Consumer, v.opts.BatchSize, block)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	applied := 0
+	for _, msg := range msgs {
+		ok, err := v.apply(ctx, msg)
+		if err != nil {
+			return applied, err
+		}
+		if ok {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// apply runs the projections for msg unless its dedup ID was already applied
+func (v *MaterializedView[T]) apply(ctx context.Context, msg StreamMessage[T]) (bool, error) {
+	client := v.stream.client
+	if msg.Value == nil {
+		// Entry was trimmed before we got to it; nothing to apply
+		return false, v.stream.Ack(ctx, v.group, msg.ID)
+	}
+	dedupID := v.opts.DedupID(msg)
+	applied := false
+
+	err := client.Watch(ctx, func(tx *redis.Tx) error {
+		_, err := tx.ZScore(ctx, v.dedupKey, dedupID).Result()
+		if err == nil {
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.XAck(ctx, v.stream.key, v.group, msg.ID)
+				return nil
+			})
+			return err
+		}
+		if err != redis.Nil {
+			return err
+		}
+
+		now := time.Now()
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, project := range v.projections {
+				if err := project(ctx, pipe, msg); err != nil {
+					return err
+				}
+			}
+			pipe.ZAdd(ctx, v.dedupKey, &redis.Z{Score: float64(now.UnixMilli()), Member: dedupID})
+			pipe.ZRemRangeByScore(ctx, v.dedupKey, "-inf", "("+strconv.FormatInt(now.Add(-v.opts.DedupTTL).UnixMilli(), 10))
+			pipe.XAck(ctx, v.stream.key, v.group, msg.ID)
+			return nil
+		})
+		if err == nil {
+			applied = true
+		}
+		return err
+	}, v.dedupKey)
+
+	if err == redis.TxFailedErr {
+		// Another consumer touched the dedup set; the message stays pending and is retried
+		return false, nil
+	}
+	if err != nil {
+		return false, convertRedisError(err)
+	}
+	return applied, nil
+}
+
+// =====================================
+// Built-in Projections
+// =====================================
+
+// CountProjection increments the counter returned by key by delta(event). Empty keys are skipped.
+// Example: view.Project(gparedis.CountProjection(func(e *OrderEvent) string { return "orders:count:" + e.Status }, nil))
+func CountProjection[T any](key func(*T) string, delta func(*T) int64) Projection[T] {
+	return func(ctx context.Context, pipe redis.Pipeliner, msg StreamMessage[T]) error {
+		k := key(msg.Value)
+		if k == "" {
+			return nil
+		}
+		d := int64(1)
+		if delta != nil {
+			d = delta(msg.Value)
+		}
+		pipe.IncrBy(ctx, k, d)
+		return nil
+	}
+}
+
+// SortedSetProjection sets member's score in the sorted set returned by key
+func SortedSetProjection[T any](key func(*T) string, member func(*T) string, score func(*T) float64) Projection[T] {
+	return func(ctx context.Context, pipe redis.Pipeliner, msg StreamMessage[T]) error {
+		k := key(msg.Value)
+		if k == "" {
+			return nil
+		}
+		pipe.ZAdd(ctx, k, &redis.Z{Score: score(msg.Value), Member: member(msg.Value)})
+		return nil
+	}
+}
+
+// LatestNProjection keeps the n most recent events (JSON encoded, newest first) in the list returned by key
+func LatestNProjection[T any](key func(*T) string, n int64) Projection[T] {
+	return func(ctx context.Context, pipe redis.Pipeliner, msg StreamMessage[T]) error {
+		k := key(msg.Value)
+		if k == "" {
+			return nil
+		}
+		data, err := json.Marshal(msg.Value)
+		if err != nil {
+			return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize view entry", err)
+		}
+		pipe.LPush(ctx, k, data)
+		pipe.LTrim(ctx, k, 0, n-1)
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+package gparedis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaterializedView(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	stream := NewStream[streamTestEvent](repo.provider, "orders:events", StreamOptions{})
+	view := NewMaterializedView(stream, "orders", ViewOptions[streamTestEvent]{
+		DedupID: func(msg StreamMessage[streamTestEvent]) string { return msg.Value.OrderID + ":" + msg.Value.Status },
+	}).
+		Project(CountProjection(func(e *streamTestEvent) string { return "orders:count:" + e.Status }, nil)).
+		Project(SortedSetProjection(
+			func(e *streamTestEvent) string { return "orders:by_amount" },
+			func(e *streamTestEvent) string { return e.OrderID },
+			func(e *streamTestEvent) float64 { return float64(e.Amount) })).
+		Project(LatestNProjection(func(e *streamTestEvent) string { return "orders:latest" }, 2))
+
+	events := []streamTestEvent{
+		{OrderID: "o1", Status: "paid", Amount: 30},
+		{OrderID: "o2", Status: "paid", Amount: 10},
+		{OrderID: "o1", Status: "paid", Amount: 30}, // duplicate delivery from the producer
+		{OrderID: "o3", Status: "refunded", Amount: 20},
+	}
+	for i := range events {
+		_, err := stream.Add(ctx, &events[i])
+		require.NoError(t, err)
+	}
+
+	applied, err := view.ProcessBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, applied)
+
+	// Everything was acknowledged, nothing is applied twice
+	applied, err = view.ProcessBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	paid, err := repo.client.Get(ctx, "orders:count:paid").Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), paid)
+
+	ranked, err := repo.client.ZRange(ctx, "orders:by_amount", 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"o2", "o3", "o1"}, ranked)
+
+	latest, err := repo.client.LLen(ctx, "orders:latest").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), latest)
+
+	// A redelivered event that was already applied is only acknowledged
+	msgs, err := stream.Range(ctx, "-", "+", 1)
+	require.NoError(t, err)
+	ok, err := view.apply(ctx, msgs[0])
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
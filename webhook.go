@@ -0,0 +1,497 @@
+package gparedis
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Webhook Dispatcher
+// =====================================
+
+// Headers set on every webhook request
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookEndpoint is a registered receiver of webhook events
+type WebhookEndpoint struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Secret signs the payloads sent to the endpoint
+	Secret string `json:"secret"`
+	// Events lists the events the endpoint subscribes to (empty: every event)
+	Events []string `json:"events,omitempty"`
+}
+
+// subscribes reports whether the endpoint receives event
+func (e *WebhookEndpoint) subscribes(event string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range e.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookOutcome is the result of one delivery attempt
+type WebhookOutcome string
+
+const (
+	// WebhookDelivered means the endpoint answered with a 2xx status
+	WebhookDelivered WebhookOutcome = "delivered"
+	// WebhookRetrying means the attempt failed and another one is scheduled
+	WebhookRetrying WebhookOutcome = "retrying"
+	// WebhookFailed means the last allowed attempt failed and the delivery was dropped
+	WebhookFailed WebhookOutcome = "failed"
+)
+
+// WebhookAttempt is one entry of an endpoint's delivery history
+type WebhookAttempt struct {
+	DeliveryID string         `json:"delivery_id"`
+	Event      string         `json:"event"`
+	Attempt    int            `json:"attempt"`
+	Outcome    WebhookOutcome `json:"outcome"`
+	// StatusCode is the response status (0 when no response was received)
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	At         time.Time     `json:"at"`
+}
+
+// webhookDelivery is a pending delivery of one event to one endpoint
+type webhookDelivery struct {
+	ID        string          `json:"id"`
+	Endpoint  string          `json:"endpoint"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// WebhookDispatcherOptions configures a WebhookDispatcher
+type WebhookDispatcherOptions struct {
+	// Client sends the requests (default: a client with a 10s timeout)
+	Client *http.Client
+	// MaxAttempts is the number of attempts before a delivery is dropped (default 8)
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry; it doubles with every attempt (default 10s)
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between attempts (default 1h)
+	MaxDelay time.Duration
+	// Lease is how long a claimed delivery is hidden from other dispatchers; a dispatcher
+	// dying mid-attempt leaves it to be retried once the lease ends (default 1m)
+	Lease time.Duration
+	// Interval is how often Run looks for due deliveries (default 1s)
+	Interval time.Duration
+	// BatchSize caps the deliveries claimed per poll (default 100)
+	BatchSize int64
+	// HistorySize is the number of attempts kept per endpoint (default 100)
+	HistorySize int64
+	// OnError is called when an attempt fails or the dispatcher's bookkeeping can't be updated
+	OnError func(endpoint string, err error)
+}
+
+// WebhookDispatcher delivers events to HTTP endpoints. Endpoints and pending deliveries live
+// in Redis, so any number of processes can share a dispatcher. Deliveries wait in a sorted
+// set scored by their next attempt (unix milliseconds), like the Scheduler's runs; failed
+// attempts are pushed back with exponential backoff until MaxAttempts. Payloads are signed
+// with the endpoint's secret and every attempt is recorded in the endpoint's history.
+// Delivery is at least once: receivers should deduplicate on the delivery header.
+type WebhookDispatcher struct {
+	provider *Provider
+	client   *redis.Client
+	prefix   string
+	opts     WebhookDispatcherOptions
+}
+
+// NewWebhookDispatcher creates a dispatcher keeping its state under prefix
+// Example: hooks := gparedis.NewWebhookDispatcher(provider, "webhooks:", gparedis.WebhookDispatcherOptions{})
+func NewWebhookDispatcher(provider *Provider, prefix string, opts WebhookDispatcherOptions) *WebhookDispatcher {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 10 * time.Second
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = time.Hour
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = time.Minute
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = scanBatchSize
+	}
+	if opts.HistorySize <= 0 {
+		opts.HistorySize = 100
+	}
+	return &WebhookDispatcher{provider: provider, client: provider.client, prefix: prefix, opts: opts}
+}
+
+// AddEndpoint registers an endpoint, replacing the one with the same ID
+func (d *WebhookDispatcher) AddEndpoint(ctx context.Context, endpoint WebhookEndpoint) error {
+	if endpoint.ID == "" || endpoint.URL == "" {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "webhook endpoint needs an ID and a URL")
+	}
+	data, err := json.Marshal(endpoint)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize webhook endpoint", err)
+	}
+	return convertRedisError(d.client.HSet(ctx, d.endpointsKey(), endpoint.ID, data).Err())
+}
+
+// RemoveEndpoint unregisters an endpoint and drops its history. Its pending deliveries are
+// discarded when they come due.
+func (d *WebhookDispatcher) RemoveEndpoint(ctx context.Context, id string) error {
+	pipe := d.client.TxPipeline()
+	pipe.HDel(ctx, d.endpointsKey(), id)
+	pipe.Del(ctx, d.historyKey(id))
+	_, err := pipe.Exec(ctx)
+	return convertRedisError(err)
+}
+
+// Endpoints returns the registered endpoints
+func (d *WebhookDispatcher) Endpoints(ctx context.Context) ([]WebhookEndpoint, error) {
+	raw, err := d.client.HGetAll(ctx, d.endpointsKey()).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	endpoints := make([]WebhookEndpoint, 0, len(raw))
+	for _, id := range sortedKeys(raw) {
+		var endpoint WebhookEndpoint
+		if err := json.Unmarshal([]byte(raw[id]), &endpoint); err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid webhook endpoint "+id, err)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+// Dispatch queues event for every endpoint subscribed to it and returns the delivery IDs.
+// The payload is sent as JSON; the first attempt is made on the next poll.
+// Example: ids, err := hooks.Dispatch(ctx, "order.paid", order)
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, event string, payload interface{}) ([]string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize webhook payload", err)
+	}
+	endpoints, err := d.Endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var ids []string
+	pipe := d.client.TxPipeline()
+	for _, endpoint := range endpoints {
+		if !endpoint.subscribes(event) {
+			continue
+		}
+		id, err := newRandomID()
+		if err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to generate delivery ID", err)
+		}
+		data, err := json.Marshal(webhookDelivery{ID: id, Endpoint: endpoint.ID, Event: event, Payload: body, CreatedAt: now})
+		if err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize webhook delivery", err)
+		}
+		pipe.HSet(ctx, d.deliveriesKey(), id, data)
+		pipe.ZAdd(ctx, d.dueKey(), &redis.Z{Score: float64(now.UnixMilli()), Member: id})
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, convertRedisError(err)
+	}
+	return ids, nil
+}
+
+// Pending returns the number of deliveries waiting for an attempt
+func (d *WebhookDispatcher) Pending(ctx context.Context) (int64, error) {
+	n, err := d.client.ZCard(ctx, d.dueKey()).Result()
+	return n, convertRedisError(err)
+}
+
+// History returns the latest attempts made to an endpoint, newest first
+func (d *WebhookDispatcher) History(ctx context.Context, endpoint string, limit int64) ([]WebhookAttempt, error) {
+	if limit <= 0 {
+		limit = d.opts.HistorySize
+	}
+	raw, err := d.client.LRange(ctx, d.historyKey(endpoint), 0, limit-1).Result()
+	if err != nil {
+		return nil, convertRedisError(err)
+	}
+	attempts := make([]WebhookAttempt, 0, len(raw))
+	for _, entry := range raw {
+		var attempt WebhookAttempt
+		if err := json.Unmarshal([]byte(entry), &attempt); err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid webhook history entry", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, nil
+}
+
+// leaseDueScript pushes up to ARGV[2] members scored at or below ARGV[1] to ARGV[3] and
+// returns them, so other dispatchers skip them until the lease ends
+var leaseDueScript = dryRunReply(redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, member in ipairs(due) do
+	redis.call('ZADD', KEYS[1], ARGV[3], member)
+end
+return due
+`), []interface{}{})
+
+// DeliverDue attempts every due delivery and returns how many were delivered
+func (d *WebhookDispatcher) DeliverDue(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		now := time.Now()
+		ids, err := leaseDueScript.Run(ctx, d.client, []string{d.dueKey()},
+			now.UnixMilli(), d.opts.BatchSize, now.Add(d.opts.Lease).UnixMilli()).StringSlice()
+		if err != nil && err != redis.Nil {
+			return delivered, convertRedisError(err)
+		}
+		for _, id := range ids {
+			ok, err := d.deliver(ctx, id)
+			if err != nil && d.opts.OnError != nil {
+				d.opts.OnError("", err)
+			}
+			if ok {
+				delivered++
+			}
+		}
+		if int64(len(ids)) < d.opts.BatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// Run calls DeliverDue every Interval until ctx is cancelled
+func (d *WebhookDispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := d.DeliverDue(ctx); err != nil && d.opts.OnError != nil {
+				d.opts.OnError("", err)
+			}
+		}
+	}
+}
+
+// deliver makes one attempt of a leased delivery and records its outcome. Attempt failures
+// are reported to OnError; the returned error is for bookkeeping.
+func (d *WebhookDispatcher) deliver(ctx context.Context, id string) (bool, error) {
+	raw, err := d.client.HGet(ctx, d.deliveriesKey(), id).Bytes()
+	if err == redis.Nil {
+		return false, convertRedisError(d.client.ZRem(ctx, d.dueKey(), id).Err())
+	}
+	if err != nil {
+		return false, convertRedisError(err)
+	}
+	var delivery webhookDelivery
+	if err := json.Unmarshal(raw, &delivery); err != nil {
+		return false, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid webhook delivery "+id, err)
+	}
+	rawEndpoint, err := d.client.HGet(ctx, d.endpointsKey(), delivery.Endpoint).Bytes()
+	if err == redis.Nil {
+		// The endpoint was removed; nobody is left to deliver to
+		return false, d.drop(ctx, id, delivery.Endpoint, nil)
+	}
+	if err != nil {
+		return false, convertRedisError(err)
+	}
+	var endpoint WebhookEndpoint
+	if err := json.Unmarshal(rawEndpoint, &endpoint); err != nil {
+		return false, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "invalid webhook endpoint "+delivery.Endpoint, err)
+	}
+
+	delivery.Attempts++
+	start := time.Now()
+	status, sendErr := d.send(ctx, &endpoint, &delivery)
+	attempt := WebhookAttempt{
+		DeliveryID: id,
+		Event:      delivery.Event,
+		Attempt:    delivery.Attempts,
+		StatusCode: status,
+		Duration:   time.Since(start),
+		At:         start,
+	}
+	switch {
+	case sendErr == nil:
+		attempt.Outcome = WebhookDelivered
+		return true, d.drop(ctx, id, endpoint.ID, &attempt)
+	case delivery.Attempts >= d.opts.MaxAttempts:
+		attempt.Outcome = WebhookFailed
+	default:
+		attempt.Outcome = WebhookRetrying
+	}
+	attempt.Error = sendErr.Error()
+	if d.opts.OnError != nil {
+		d.opts.OnError(endpoint.ID, sendErr)
+	}
+	if attempt.Outcome == WebhookFailed {
+		return false, d.drop(ctx, id, endpoint.ID, &attempt)
+	}
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return false, gpa.NewErrorWithCause(gpa.ErrorTypeSerialization, "failed to serialize webhook delivery", err)
+	}
+	next := time.Now().Add(d.backoff(delivery.Attempts))
+	pipe := d.client.TxPipeline()
+	pipe.HSet(ctx, d.deliveriesKey(), id, data)
+	pipe.ZAdd(ctx, d.dueKey(), &redis.Z{Score: float64(next.UnixMilli()), Member: id})
+	d.record(ctx, pipe, endpoint.ID, &attempt)
+	_, err = pipe.Exec(ctx)
+	return false, convertRedisError(err)
+}
+
+// send posts the signed payload and returns the response status
+func (d *WebhookDispatcher) send(ctx context.Context, endpoint *WebhookEndpoint, delivery *webhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(endpoint.Secret, time.Now(), delivery.Payload))
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// drop removes a delivery, recording its last attempt to endpoint when there was one
+func (d *WebhookDispatcher) drop(ctx context.Context, id, endpoint string, attempt *WebhookAttempt) error {
+	pipe := d.client.TxPipeline()
+	pipe.HDel(ctx, d.deliveriesKey(), id)
+	pipe.ZRem(ctx, d.dueKey(), id)
+	if attempt != nil {
+		d.record(ctx, pipe, endpoint, attempt)
+	}
+	_, err := pipe.Exec(ctx)
+	return convertRedisError(err)
+}
+
+// record appends an attempt to the endpoint's capped history
+func (d *WebhookDispatcher) record(ctx context.Context, pipe redis.Pipeliner, endpoint string, attempt *WebhookAttempt) {
+	data, _ := json.Marshal(attempt)
+	pipe.LPush(ctx, d.historyKey(endpoint), data)
+	pipe.LTrim(ctx, d.historyKey(endpoint), 0, d.opts.HistorySize-1)
+}
+
+// backoff returns the wait after the given number of failed attempts
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.BaseDelay
+	for i := 1; i < attempts && delay < d.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.opts.MaxDelay {
+		delay = d.opts.MaxDelay
+	}
+	return delay
+}
+
+// endpointsKey is the hash of registered endpoints
+func (d *WebhookDispatcher) endpointsKey() string {
+	return d.prefix + "endpoints"
+}
+
+// deliveriesKey is the hash of pending deliveries
+func (d *WebhookDispatcher) deliveriesKey() string {
+	return d.prefix + "deliveries"
+}
+
+// dueKey is the sorted set of next attempts
+func (d *WebhookDispatcher) dueKey() string {
+	return d.prefix + "due"
+}
+
+// historyKey is the capped list of an endpoint's attempts
+func (d *WebhookDispatcher) historyKey(endpoint string) string {
+	return d.prefix + "history:" + endpoint
+}
+
+// SignWebhook returns the signature header value for a payload sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<payload>">"
+func SignWebhook(secret string, t time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + webhookMAC(secret, timestamp, payload)
+}
+
+// webhookMAC computes the hex HMAC of a timestamped payload
+func webhookMAC(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks a signature header against the payload received, for receivers.
+// Signatures older than tolerance are rejected to limit replays (0 disables the check).
+// Example: ok := gparedis.VerifyWebhook(secret, r.Header.Get(gparedis.WebhookSignatureHeader), body, 5*time.Minute)
+func VerifyWebhook(secret, header string, payload []byte, tolerance time.Duration) bool {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(part, "=")
+		switch name {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return false
+	}
+	if tolerance > 0 {
+		if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+			return false
+		}
+	}
+	expected, _ := hex.DecodeString(webhookMAC(secret, timestamp, payload))
+	actual, err := hex.DecodeString(signature)
+	return err == nil && hmac.Equal(expected, actual)
+}
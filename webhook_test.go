@@ -0,0 +1,138 @@
+package gparedis
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDispatcherRetries(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	var received []string
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if !VerifyWebhook("s3cret", r.Header.Get(WebhookSignatureHeader), body, time.Minute) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, r.Header.Get(WebhookEventHeader)+" "+string(body))
+	}))
+	defer server.Close()
+
+	var failures []string
+	hooks := NewWebhookDispatcher(repo.provider, "hooks:", WebhookDispatcherOptions{
+		BaseDelay: 50 * time.Millisecond,
+		OnError:   func(endpoint string, err error) { failures = append(failures, endpoint) },
+	})
+	require.NoError(t, hooks.AddEndpoint(ctx, WebhookEndpoint{ID: "shop", URL: server.URL, Secret: "s3cret", Events: []string{"order.paid"}}))
+	require.NoError(t, hooks.AddEndpoint(ctx, WebhookEndpoint{ID: "down", URL: "http://127.0.0.1:1", Secret: "x"}))
+	assert.Error(t, hooks.AddEndpoint(ctx, WebhookEndpoint{ID: "nourl"}))
+
+	ids, err := hooks.Dispatch(ctx, "order.paid", map[string]int{"total": 42})
+	require.NoError(t, err)
+	assert.Len(t, ids, 2, "both endpoints subscribe to the event")
+	ids, err = hooks.Dispatch(ctx, "order.refunded", nil)
+	require.NoError(t, err)
+	assert.Len(t, ids, 1, "only the catch-all endpoint subscribes")
+
+	delivered, err := hooks.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, delivered, "the first attempts fail")
+	assert.ElementsMatch(t, []string{"shop", "down", "down"}, failures)
+	delivered, err = hooks.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, delivered, "retries wait for the backoff")
+
+	time.Sleep(100 * time.Millisecond)
+	delivered, err = hooks.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []string{`order.paid {"total":42}`}, received)
+
+	history, err := hooks.History(ctx, "shop", 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, WebhookDelivered, history[0].Outcome)
+	assert.Equal(t, 2, history[0].Attempt)
+	assert.Equal(t, WebhookRetrying, history[1].Outcome)
+	assert.Equal(t, http.StatusServiceUnavailable, history[1].StatusCode)
+
+	// Removing an endpoint discards its pending deliveries
+	pending, err := hooks.Pending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pending)
+	require.NoError(t, hooks.RemoveEndpoint(ctx, "down"))
+	time.Sleep(200 * time.Millisecond)
+	_, err = hooks.DeliverDue(ctx)
+	require.NoError(t, err)
+	pending, err = hooks.Pending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+	endpoints, err := hooks.Endpoints(ctx)
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "shop", endpoints[0].ID)
+}
+
+func TestWebhookDispatcherGivesUp(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	hooks := NewWebhookDispatcher(repo.provider, "hooks:", WebhookDispatcherOptions{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	require.NoError(t, hooks.AddEndpoint(ctx, WebhookEndpoint{ID: "broken", URL: server.URL}))
+	_, err := hooks.Dispatch(ctx, "ping", "hello")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := hooks.DeliverDue(ctx)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	history, err := hooks.History(ctx, "broken", 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2, "no attempt is made after MaxAttempts")
+	assert.Equal(t, WebhookFailed, history[0].Outcome)
+	pending, err := hooks.Pending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+}
+
+func TestWebhookSignature(t *testing.T) {
+	payload := []byte(`{"id":1}`)
+	now := time.Now()
+	header := SignWebhook("key", now, payload)
+
+	assert.True(t, VerifyWebhook("key", header, payload, time.Minute))
+	assert.False(t, VerifyWebhook("other", header, payload, time.Minute))
+	assert.False(t, VerifyWebhook("key", header, []byte(`{"id":2}`), time.Minute))
+	assert.False(t, VerifyWebhook("key", "garbage", payload, 0))
+
+	old := SignWebhook("key", now.Add(-time.Hour), payload)
+	assert.False(t, VerifyWebhook("key", old, payload, time.Minute), "stale signatures are replays")
+	assert.True(t, VerifyWebhook("key", old, payload, 0))
+}
@@ -0,0 +1,121 @@
+package gparedis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lemmego/gpa"
+)
+
+// =====================================
+// Sliding-Window Counters
+// =====================================
+
+// WindowCounterOptions configures a WindowCounter
+type WindowCounterOptions struct {
+	// Resolution is the bucket width; counts are exact to one bucket (default 1s)
+	Resolution time.Duration
+	// Retention is the longest window Count can answer; older buckets are pruned (default 1h)
+	Retention time.Duration
+}
+
+// WindowCounter counts events per key over sliding windows, for request-per-minute and
+// abuse-detection counters. Each key is a hash of bucket start (unix ms) to count, so memory
+// is bounded by Retention/Resolution fields per key regardless of traffic.
+type WindowCounter struct {
+	provider *Provider
+	client   *redis.Client
+	prefix   string
+	opts     WindowCounterOptions
+}
+
+// NewWindowCounter creates a counter storing its hashes under prefix
+// Example: rpm := gparedis.NewWindowCounter(provider, "rate:", gparedis.WindowCounterOptions{Resolution: time.Second, Retention: time.Hour})
+func NewWindowCounter(provider *Provider, prefix string, opts WindowCounterOptions) *WindowCounter {
+	if opts.Resolution <= 0 {
+		opts.Resolution = time.Second
+	}
+	if opts.Retention < opts.Resolution {
+		opts.Retention = time.Hour
+	}
+	return &WindowCounter{provider: provider, client: provider.client, prefix: prefix, opts: opts}
+}
+
+// windowIncrScript adds ARGV[2] to bucket ARGV[1]. When a new bucket is created, buckets
+// older than ARGV[3] are pruned; the hash expires ARGV[4] ms after the last increment.
+var windowIncrScript = redis.NewScript(`
+local count = redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2])
+if count == tonumber(ARGV[2]) then
+	local cutoff = tonumber(ARGV[3])
+	local stale = {}
+	for _, bucket in ipairs(redis.call('HKEYS', KEYS[1])) do
+		if tonumber(bucket) < cutoff then
+			table.insert(stale, bucket)
+		end
+	end
+	if #stale > 0 then
+		redis.call('HDEL', KEYS[1], unpack(stale))
+	end
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return count
+`)
+
+// windowCountScript sums the buckets starting at or after ARGV[1]
+var windowCountScript = readOnlyScript(redis.NewScript(`
+local from = tonumber(ARGV[1])
+local total = 0
+local buckets = redis.call('HGETALL', KEYS[1])
+for i = 1, #buckets, 2 do
+	if tonumber(buckets[i]) >= from then
+		total = total + tonumber(buckets[i + 1])
+	end
+end
+return total
+`))
+
+// bucket returns the start of the bucket containing t, in unix milliseconds
+func (c *WindowCounter) bucket(t time.Time) int64 {
+	res := c.opts.Resolution.Milliseconds()
+	if res <= 0 {
+		res = 1
+	}
+	return t.UnixMilli() / res * res
+}
+
+// Incr records one event for key
+func (c *WindowCounter) Incr(ctx context.Context, key string) error {
+	return c.IncrBy(ctx, key, 1)
+}
+
+// IncrBy records n events for key
+func (c *WindowCounter) IncrBy(ctx context.Context, key string, n int64) error {
+	if n <= 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "window counter increment must be positive")
+	}
+	now := time.Now()
+	cutoff := c.bucket(now.Add(-c.opts.Retention))
+	ttl := (c.opts.Retention + c.opts.Resolution).Milliseconds()
+	return convertRedisError(windowIncrScript.Run(ctx, c.client, []string{c.prefix + key},
+		c.bucket(now), n, cutoff, ttl).Err())
+}
+
+// Count returns the events recorded for key in the last window, including the current bucket.
+// The window start is rounded down to a bucket boundary.
+func (c *WindowCounter) Count(ctx context.Context, key string, window time.Duration) (int64, error) {
+	if window <= 0 || window > c.opts.Retention {
+		return 0, gpa.NewError(gpa.ErrorTypeInvalidArgument, "window must be positive and within the counter's retention")
+	}
+	from := c.bucket(time.Now().Add(-window))
+	total, err := windowCountScript.Run(ctx, c.client, []string{c.prefix + key}, from).Int64()
+	if err != nil {
+		return 0, convertRedisError(err)
+	}
+	return total, nil
+}
+
+// Reset clears all buckets of key
+func (c *WindowCounter) Reset(ctx context.Context, key string) error {
+	return convertRedisError(c.client.Del(ctx, c.prefix+key).Err())
+}
@@ -0,0 +1,83 @@
+package gparedis
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowCounter(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	c := NewWindowCounter(repo.provider, "rate:", WindowCounterOptions{Resolution: 20 * time.Millisecond, Retention: 200 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.Incr(ctx, "ip:1"))
+	}
+	require.NoError(t, c.IncrBy(ctx, "ip:2", 5))
+
+	count, err := c.Count(ctx, "ip:1", 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, c.Incr(ctx, "ip:1"))
+
+	count, err = c.Count(ctx, "ip:1", 20*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "older buckets fall out of short windows")
+
+	count, err = c.Count(ctx, "ip:1", 200*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	count, err = c.Count(ctx, "ip:2", 200*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+
+	_, err = c.Count(ctx, "ip:1", time.Hour)
+	assert.Error(t, err)
+	assert.Error(t, c.IncrBy(ctx, "ip:1", 0))
+
+	require.NoError(t, c.Reset(ctx, "ip:2"))
+	count, err = c.Count(ctx, "ip:2", 200*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestWindowCounterPrunesBuckets(t *testing.T) {
+	repo, cleanup := setupTestRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	c := NewWindowCounter(repo.provider, "rate:", WindowCounterOptions{Resolution: 20 * time.Millisecond, Retention: 100 * time.Millisecond})
+	defer c.Reset(ctx, "k")
+
+	// Each increment extends the key's TTL (120ms), so only the bucket pruning removes the first bucket
+	require.NoError(t, c.Incr(ctx, "k"))
+	first, err := repo.provider.client.HKeys(ctx, "rate:k").Result()
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, c.Incr(ctx, "k"))
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, c.Incr(ctx, "k"))
+
+	buckets, err := repo.provider.client.HKeys(ctx, "rate:k").Result()
+	require.NoError(t, err)
+	assert.NotContains(t, buckets, first[0])
+	require.NotEmpty(t, buckets)
+	start, err := strconv.ParseInt(buckets[0], 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, c.bucket(time.UnixMilli(start)), start)
+
+	ttl, err := repo.provider.client.PTTL(ctx, "rate:k").Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+}